@@ -0,0 +1,165 @@
+// Package audit looks through the variables of an organization and its
+// repositories for organization variables shadowed by repository variables,
+// values repeated across repositories, and variables not updated for a long
+// time.
+package audit
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// Options tune the findings of Run
+type Options struct {
+	// MinRepos is the number of repositories a name and value must be found
+	// in to be reported as a duplicate
+	MinRepos int
+	// StaleAfter is the age past which a variable is reported as stale;
+	// zero leaves stale variables out
+	StaleAfter time.Duration
+	// Now is the time ages are measured from
+	Now time.Time
+}
+
+// Report holds the findings of an audit. The lists are empty rather than
+// nil, so that they encode as [] in JSON.
+type Report struct {
+	Org          string      `json:"org"`
+	Repositories int         `json:"repositories"`
+	Shadowed     []Shadowed  `json:"shadowed"`
+	Duplicates   []Duplicate `json:"duplicates"`
+	Stale        []Stale     `json:"stale"`
+}
+
+// Shadowed is a repository variable with the name of an organization
+// variable, which it overrides in that repository's workflows. SameValue
+// marks a repository variable that could be deleted without any change.
+type Shadowed struct {
+	Name          string `json:"name"`
+	Repository    string `json:"repository"`
+	OrgVisibility string `json:"org_visibility"`
+	SameValue     bool   `json:"same_value"`
+}
+
+// Duplicate is a name and value set in several repositories, a candidate for
+// an organization variable. OrgVariable is set when the organization already
+// has a variable of that name.
+type Duplicate struct {
+	Name         string   `json:"name"`
+	Value        *string  `json:"value,omitempty"`
+	Repositories []string `json:"repositories"`
+	OrgVariable  bool     `json:"org_variable"`
+}
+
+// Stale is a variable last updated more than Options.StaleAfter ago. Scope
+// is "org:ORG" or "OWNER/REPO", as in the list command.
+type Stale struct {
+	Scope     string `json:"scope"`
+	Name      string `json:"name"`
+	UpdatedAt string `json:"updated_at"`
+	AgeDays   int    `json:"age_days"`
+}
+
+// Run audits the variables of org and of its repositories, given by
+// repository name. Names are compared case-insensitively, as GitHub does;
+// values are compared exactly.
+func Run(org string, orgVars []types.Variable, repoVars map[string][]types.Variable, opts Options) *Report {
+	r := &Report{
+		Org:          org,
+		Repositories: len(repoVars),
+		Shadowed:     []Shadowed{},
+		Duplicates:   []Duplicate{},
+		Stale:        []Stale{},
+	}
+
+	repos := make([]string, 0, len(repoVars))
+	for repo := range repoVars {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	orgByName := make(map[string]types.Variable, len(orgVars))
+	for _, v := range orgVars {
+		orgByName[strings.ToUpper(v.Name)] = v
+	}
+
+	type key struct{ name, value string }
+	groups := map[key]*Duplicate{}
+	var order []key
+	for _, repo := range repos {
+		for _, v := range repoVars[repo] {
+			upper := strings.ToUpper(v.Name)
+			if o, ok := orgByName[upper]; ok {
+				r.Shadowed = append(r.Shadowed, Shadowed{
+					Name:          v.Name,
+					Repository:    repo,
+					OrgVisibility: o.Visibility,
+					SameValue:     o.Value == v.Value,
+				})
+			}
+
+			k := key{upper, v.Value}
+			d, ok := groups[k]
+			if !ok {
+				value := v.Value
+				_, inOrg := orgByName[upper]
+				d = &Duplicate{Name: v.Name, Value: &value, OrgVariable: inOrg}
+				groups[k] = d
+				order = append(order, k)
+			}
+			d.Repositories = append(d.Repositories, repo)
+		}
+	}
+	sort.SliceStable(r.Shadowed, func(i, j int) bool {
+		return strings.ToUpper(r.Shadowed[i].Name) < strings.ToUpper(r.Shadowed[j].Name)
+	})
+
+	for _, k := range order {
+		if d := groups[k]; len(d.Repositories) >= opts.MinRepos {
+			r.Duplicates = append(r.Duplicates, *d)
+		}
+	}
+	// The most repeated values are the best candidates, so they come first
+	sort.SliceStable(r.Duplicates, func(i, j int) bool {
+		a, b := r.Duplicates[i], r.Duplicates[j]
+		if len(a.Repositories) != len(b.Repositories) {
+			return len(a.Repositories) > len(b.Repositories)
+		}
+		return strings.ToUpper(a.Name) < strings.ToUpper(b.Name)
+	})
+
+	if opts.StaleAfter > 0 {
+		r.Stale = appendStale(r.Stale, types.DesiredScope{Org: org}.Label(), orgVars, opts)
+		for _, repo := range repos {
+			r.Stale = appendStale(r.Stale, types.DesiredScope{Owner: org, Repo: repo}.Label(), repoVars[repo], opts)
+		}
+		// Oldest first
+		sort.SliceStable(r.Stale, func(i, j int) bool { return r.Stale[i].AgeDays > r.Stale[j].AgeDays })
+	}
+	return r
+}
+
+// appendStale appends the variables of scope updated more than
+// opts.StaleAfter ago. Variables without a readable update time are skipped.
+func appendStale(stale []Stale, scope string, vars []types.Variable, opts Options) []Stale {
+	for _, v := range vars {
+		updated, err := time.Parse(time.RFC3339, v.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		age := opts.Now.Sub(updated)
+		if age <= opts.StaleAfter {
+			continue
+		}
+		stale = append(stale, Stale{
+			Scope:     scope,
+			Name:      v.Name,
+			UpdatedAt: v.UpdatedAt,
+			AgeDays:   int(age.Hours() / 24),
+		})
+	}
+	return stale
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+var now = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// fixture covers every finding: API_URL shadows an organization variable
+// with another value in app and the same value in web, REGION is repeated in
+// three repositories and LOG_LEVEL in two, and OLD_FLAG and LEGACY_HOST have
+// not been updated for years.
+var (
+	orgVars = []types.Variable{
+		{Name: "API_URL", Value: "https://api.acme.dev", Visibility: "all", UpdatedAt: "2026-02-01T00:00:00Z"},
+		{Name: "OLD_FLAG", Value: "1", Visibility: "private", UpdatedAt: "2022-03-01T00:00:00Z"},
+	}
+	repoVars = map[string][]types.Variable{
+		"app": {
+			{Name: "API_URL", Value: "https://staging.acme.dev", UpdatedAt: "2026-01-15T00:00:00Z"},
+			{Name: "REGION", Value: "eu-west-1", UpdatedAt: "2025-12-01T00:00:00Z"},
+			{Name: "LOG_LEVEL", Value: "debug", UpdatedAt: "2025-12-01T00:00:00Z"},
+		},
+		"web": {
+			{Name: "api_url", Value: "https://api.acme.dev", UpdatedAt: "2026-01-15T00:00:00Z"},
+			{Name: "REGION", Value: "eu-west-1", UpdatedAt: "2025-12-01T00:00:00Z"},
+			{Name: "LOG_LEVEL", Value: "debug", UpdatedAt: "2025-12-01T00:00:00Z"},
+			{Name: "LEGACY_HOST", Value: "old.acme.dev", UpdatedAt: "2023-03-01T00:00:00Z"},
+		},
+		"worker": {
+			{Name: "REGION", Value: "eu-west-1", UpdatedAt: "2025-12-01T00:00:00Z"},
+			{Name: "LOG_LEVEL", Value: "info"},
+		},
+	}
+)
+
+func TestRun(t *testing.T) {
+	r := Run("acme", orgVars, repoVars, Options{MinRepos: 2, StaleAfter: 365 * 24 * time.Hour, Now: now})
+
+	if r.Org != "acme" || r.Repositories != 3 {
+		t.Errorf("Run() = org %q, %d repositories", r.Org, r.Repositories)
+	}
+
+	wantShadowed := []Shadowed{
+		{Name: "API_URL", Repository: "app", OrgVisibility: "all", SameValue: false},
+		{Name: "api_url", Repository: "web", OrgVisibility: "all", SameValue: true},
+	}
+	if !reflect.DeepEqual(r.Shadowed, wantShadowed) {
+		t.Errorf("Shadowed = %+v, want %+v", r.Shadowed, wantShadowed)
+	}
+
+	if len(r.Duplicates) != 2 {
+		t.Fatalf("Duplicates = %+v, want REGION and LOG_LEVEL", r.Duplicates)
+	}
+	region, logLevel := r.Duplicates[0], r.Duplicates[1]
+	if region.Name != "REGION" || *region.Value != "eu-west-1" ||
+		!reflect.DeepEqual(region.Repositories, []string{"app", "web", "worker"}) || region.OrgVariable {
+		t.Errorf("Duplicates[0] = %+v", region)
+	}
+	if logLevel.Name != "LOG_LEVEL" || *logLevel.Value != "debug" ||
+		!reflect.DeepEqual(logLevel.Repositories, []string{"app", "web"}) {
+		t.Errorf("Duplicates[1] = %+v", logLevel)
+	}
+
+	wantStale := []Stale{
+		{Scope: "org:acme", Name: "OLD_FLAG", UpdatedAt: "2022-03-01T00:00:00Z", AgeDays: 1461},
+		{Scope: "acme/web", Name: "LEGACY_HOST", UpdatedAt: "2023-03-01T00:00:00Z", AgeDays: 1096},
+	}
+	if !reflect.DeepEqual(r.Stale, wantStale) {
+		t.Errorf("Stale = %+v, want %+v", r.Stale, wantStale)
+	}
+}
+
+func TestRun_Options(t *testing.T) {
+	r := Run("acme", orgVars, repoVars, Options{MinRepos: 3, Now: now})
+	if len(r.Duplicates) != 1 || r.Duplicates[0].Name != "REGION" {
+		t.Errorf("Duplicates with MinRepos 3 = %+v, want only REGION", r.Duplicates)
+	}
+	if len(r.Stale) != 0 {
+		t.Errorf("Stale without StaleAfter = %+v, want none", r.Stale)
+	}
+}
+
+func TestRun_OrgOnly(t *testing.T) {
+	r := Run("acme", orgVars, nil, Options{MinRepos: 2, StaleAfter: 30 * 24 * time.Hour, Now: now})
+	if r.Shadowed == nil || r.Duplicates == nil || len(r.Shadowed) != 0 || len(r.Duplicates) != 0 {
+		t.Errorf("Run() without repositories = %+v, want empty lists", r)
+	}
+	if len(r.Stale) != 1 || r.Stale[0].Name != "OLD_FLAG" {
+		t.Errorf("Stale = %+v, want OLD_FLAG", r.Stale)
+	}
+}
@@ -0,0 +1,341 @@
+// Package batch loads the YAML files of the batch command, which list
+// several migrations to run in one invocation, and writes the combined
+// report of such a run.
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/report"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the batch file format version
+const Version = 1
+
+// File is a list of migrations run one after the other, or a few at a time
+type File struct {
+	Version    int     `yaml:"version"`
+	Migrations []Entry `yaml:"migrations"`
+}
+
+// Entry is one migration of a batch. Source and Target are an organization,
+// or an OWNER/REPO repository, as the mode requires. The options apply to
+// this migration only.
+type Entry struct {
+	Name   string              `yaml:"name,omitempty"`
+	Mode   types.MigrationMode `yaml:"mode"`
+	Source string              `yaml:"source"`
+	Target string              `yaml:"target"`
+
+	SkipEnvs         bool                   `yaml:"skip_envs,omitempty"`
+	Envs             []string               `yaml:"envs,omitempty,flow"`
+	ExcludeEnvs      []string               `yaml:"exclude_envs,omitempty,flow"`
+	Vars             []string               `yaml:"vars,omitempty,flow"`
+	Include          []string               `yaml:"include,omitempty,flow"`
+	Exclude          []string               `yaml:"exclude,omitempty,flow"`
+	OnConflict       types.ConflictStrategy `yaml:"on_conflict,omitempty"`
+	AlwaysWrite      bool                   `yaml:"always_write,omitempty"`
+	Visibility       string                 `yaml:"visibility,omitempty"`
+	TargetVisibility string                 `yaml:"target_visibility,omitempty"`
+
+	// line is where the entry starts in the loaded file
+	line int
+}
+
+// Load reads and validates the batch file at path. Errors name the file and
+// the entry they concern.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading batch file: %w", err)
+	}
+
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Parse decodes and validates batch data. Unknown keys are rejected.
+func Parse(data []byte) (*File, error) {
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("batch file is empty")
+		}
+		return nil, fmt.Errorf("invalid batch file: %s", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	// Decoding into a struct loses the positions, so they are read from
+	// the node tree for the validation messages.
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid batch file: %w", err)
+	}
+	f.recordLines(&root)
+
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// recordLines copies the line of every entry from the node tree of the same
+// document
+func (f *File) recordLines(root *yaml.Node) {
+	n := root
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != "migrations" || n.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		for j, en := range n.Content[i+1].Content {
+			if j < len(f.Migrations) {
+				f.Migrations[j].line = en.Line
+			}
+		}
+	}
+}
+
+// Validate checks the version and every entry. All invalid entries are
+// reported, one per line, each with its number and line in the file.
+func (f *File) Validate() error {
+	if f.Version != Version {
+		return fmt.Errorf("unsupported batch file version %d (expected %d)", f.Version, Version)
+	}
+	if len(f.Migrations) == 0 {
+		return fmt.Errorf("batch file lists no migrations")
+	}
+
+	var errs []string
+	names := make(map[string]int, len(f.Migrations))
+	for i, e := range f.Migrations {
+		if err := e.validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", e.position(i), err))
+			continue
+		}
+		if e.Name == "" {
+			continue
+		}
+		if first, dup := names[e.Name]; dup {
+			errs = append(errs, fmt.Sprintf("%s: name %q is already used by entry %d", e.position(i), e.Name, first))
+			continue
+		}
+		names[e.Name] = i + 1
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errors.New(errs[0])
+	}
+	return fmt.Errorf("%d invalid entries:\n  %s", len(errs), strings.Join(errs, "\n  "))
+}
+
+// position names the entry at index i in messages, e.g. "entry 3 (line 14)"
+func (e Entry) position(i int) string {
+	if e.line == 0 {
+		return fmt.Sprintf("entry %d", i+1)
+	}
+	return fmt.Sprintf("entry %d (line %d)", i+1, e.line)
+}
+
+// validate checks the mode, the form of the source and target, and the
+// options of the entry
+func (e Entry) validate() error {
+	var sourceRepo, targetRepo bool
+	switch e.Mode {
+	case "":
+		return fmt.Errorf("mode is required")
+	case types.ModeRepoToRepo:
+		sourceRepo, targetRepo = true, true
+	case types.ModeOrgToOrg:
+	case types.ModeOrgToRepo:
+		targetRepo = true
+	case types.ModeRepoToOrg:
+		sourceRepo = true
+	default:
+		return fmt.Errorf("unsupported mode %q: must be repo-to-repo, org-to-org, org-to-repo, or repo-to-org", e.Mode)
+	}
+	if err := checkEndpoint("source", e.Source, sourceRepo, e.Mode); err != nil {
+		return err
+	}
+	if err := checkEndpoint("target", e.Target, targetRepo, e.Mode); err != nil {
+		return err
+	}
+	if e.OnConflict == types.ConflictPrompt {
+		return fmt.Errorf("on_conflict prompt is not supported in a batch")
+	}
+	return config.Validate(e.Config(types.MigrationConfig{}))
+}
+
+// checkEndpoint checks that the source or target (side) of an entry is an
+// OWNER/REPO repository when repo is set, and an organization otherwise
+func checkEndpoint(side, value string, repo bool, mode types.MigrationMode) error {
+	owner, name, found := strings.Cut(value, "/")
+	switch {
+	case value == "":
+		return fmt.Errorf("%s is required", side)
+	case repo && (!found || owner == "" || name == "" || strings.Contains(name, "/")):
+		return fmt.Errorf("%s %q must be OWNER/REPO in %s mode", side, value, mode)
+	case !repo && found:
+		return fmt.Errorf("%s %q must be an organization in %s mode", side, value, mode)
+	}
+	return nil
+}
+
+// Label names the entry in the summary: its name, or its source and target
+func (e Entry) Label() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.Source + " → " + e.Target
+}
+
+// Config returns the configuration of the entry's migration: base, which
+// holds the options shared by the whole batch, with the mode, endpoints, and
+// options of the entry. An on_conflict of the entry replaces the strategy
+// of base.
+func (e Entry) Config(base types.MigrationConfig) *types.MigrationConfig {
+	cfg := base
+	cfg.Mode = e.Mode
+
+	sourceOwner, sourceRepo, _ := strings.Cut(e.Source, "/")
+	targetOwner, targetRepo, _ := strings.Cut(e.Target, "/")
+	cfg.SourceOrg = sourceOwner
+	cfg.TargetOrg = targetOwner
+	if sourceRepo != "" {
+		cfg.SourceOwner = sourceOwner
+		cfg.SourceRepo = sourceRepo
+	}
+	if targetRepo != "" {
+		cfg.TargetOwner = targetOwner
+		cfg.TargetRepo = targetRepo
+	}
+
+	cfg.SkipEnvs = e.SkipEnvs
+	cfg.Envs = e.Envs
+	cfg.ExcludeEnvs = e.ExcludeEnvs
+	cfg.Vars = e.Vars
+	cfg.Include = e.Include
+	cfg.Exclude = e.Exclude
+	cfg.Visibility = e.Visibility
+	cfg.TargetVisibility = e.TargetVisibility
+	if e.OnConflict != "" {
+		cfg.OnConflict = e.OnConflict
+		cfg.SkipOverwrite = false
+	}
+	if e.AlwaysWrite {
+		cfg.AlwaysWrite = true
+	}
+	return &cfg
+}
+
+// ReportSchemaVersion is the combined report format version written by
+// SaveReport
+const ReportSchemaVersion = 1
+
+// Status is the outcome of one entry of a batch run
+type Status string
+
+const (
+	// StatusSucceeded is an entry that migrated without errors
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed is an entry that could not start, failed, or completed
+	// with errors
+	StatusFailed Status = "failed"
+	// StatusNotRun is an entry left out after --fail-fast or an interrupt
+	StatusNotRun Status = "not_run"
+)
+
+// Report is the combined record of a batch run
+type Report struct {
+	SchemaVersion int       `json:"schema_version"`
+	File          string    `json:"file"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+
+	Summary ReportSummary `json:"summary"`
+	Entries []EntryReport `json:"entries"`
+}
+
+// ReportSummary counts the entries of a batch run by status
+type ReportSummary struct {
+	Entries   int `json:"entries"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	NotRun    int `json:"not_run"`
+}
+
+// EntryReport is the outcome of one entry. Report is the record of its
+// migration, as written by --report-file for a single run; it is missing for
+// entries that did not start.
+type EntryReport struct {
+	Index  int                 `json:"index"`
+	Name   string              `json:"name,omitempty"`
+	Mode   types.MigrationMode `json:"mode"`
+	Source string              `json:"source"`
+	Target string              `json:"target"`
+	Status Status              `json:"status"`
+	Error  string              `json:"error,omitempty"`
+	Report *report.Report      `json:"report,omitempty"`
+}
+
+// Label names the entry in the summary, as Entry.Label does
+func (e EntryReport) Label() string {
+	return Entry{Name: e.Name, Source: e.Source, Target: e.Target}.Label()
+}
+
+// NewReport returns the combined report of the entries of a run of the batch
+// file at path, counting them by status
+func NewReport(path string, entries []EntryReport, startedAt, finishedAt time.Time) *Report {
+	r := &Report{
+		SchemaVersion: ReportSchemaVersion,
+		File:          path,
+		StartedAt:     startedAt.UTC(),
+		FinishedAt:    finishedAt.UTC(),
+		Summary:       ReportSummary{Entries: len(entries)},
+		Entries:       entries,
+	}
+	for _, e := range entries {
+		switch e.Status {
+		case StatusSucceeded:
+			r.Summary.Succeeded++
+		case StatusFailed:
+			r.Summary.Failed++
+		case StatusNotRun:
+			r.Summary.NotRun++
+		}
+	}
+	return r
+}
+
+// SaveReport writes r as indented JSON to path
+func SaveReport(path string, r *Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
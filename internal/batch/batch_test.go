@@ -0,0 +1,155 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+const sample = `version: 1
+migrations:
+  - name: app
+    mode: repo-to-repo
+    source: acme/app
+    target: acme-new/app
+    skip_envs: true
+  - mode: org-to-org
+    source: acme
+    target: acme-new
+    on_conflict: skip
+    include: ["DEPLOY_*"]
+  - mode: repo-to-org
+    source: acme/app
+    target: acme-new
+    target_visibility: private
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if len(f.Migrations) != 3 {
+		t.Fatalf("Parse() returned %d migrations, want 3", len(f.Migrations))
+	}
+
+	cfg := f.Migrations[0].Config(types.MigrationConfig{DryRun: true})
+	if cfg.Mode != types.ModeRepoToRepo || cfg.SourceOwner != "acme" || cfg.SourceRepo != "app" ||
+		cfg.TargetOwner != "acme-new" || cfg.TargetRepo != "app" || !cfg.SkipEnvs || !cfg.DryRun {
+		t.Errorf("Config() of entry 1 = %+v", cfg)
+	}
+	cfg = f.Migrations[1].Config(types.MigrationConfig{SkipOverwrite: true})
+	if cfg.SourceOrg != "acme" || cfg.TargetOrg != "acme-new" || cfg.OnConflict != types.ConflictSkip || cfg.SkipOverwrite {
+		t.Errorf("Config() of entry 2 = %+v", cfg)
+	}
+	if got := f.Migrations[1].Label(); got != "acme → acme-new" {
+		t.Errorf("Label() = %q", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr []string
+	}{
+		{"empty", "", []string{"batch file is empty"}},
+		{"malformed", "version: 1\nmigrations: [\n", []string{"invalid batch file"}},
+		{"unknown key", "version: 1\nmigrations:\n  - mode: org-to-org\n    source: a\n    target: b\n    force: true\n", []string{"field force not found"}},
+		{"version", "version: 2\nmigrations: []\n", []string{"unsupported batch file version 2"}},
+		{"no migrations", "version: 1\nmigrations: []\n", []string{"lists no migrations"}},
+		{
+			name: "every invalid entry",
+			data: `version: 1
+migrations:
+  - mode: org-to-org
+    source: acme
+    target: acme-new
+  - mode: repo-to-repo
+    source: acme
+    target: acme-new/app
+  - mode: fan-out
+    source: acme
+    target: acme-new
+  - mode: org-to-repo
+    source: acme
+  - mode: org-to-org
+    source: acme
+    target: acme-new
+    envs: [prod]
+  - mode: org-to-org
+    source: acme
+    target: acme-new
+    on_conflict: prompt
+`,
+			wantErr: []string{
+				`entry 2 (line 6): source "acme" must be OWNER/REPO in repo-to-repo mode`,
+				`entry 3 (line 9): unsupported mode "fan-out"`,
+				"entry 4 (line 12): target is required",
+				"entry 5 (line 14): environment selection is only supported in repo-to-repo mode",
+				"entry 6 (line 18): on_conflict prompt is not supported in a batch",
+			},
+		},
+		{
+			name:    "duplicate name",
+			data:    "version: 1\nmigrations:\n  - name: a\n    mode: org-to-org\n    source: x\n    target: y\n  - name: a\n    mode: org-to-org\n    source: y\n    target: z\n",
+			wantErr: []string{`entry 2 (line 7): name "a" is already used by entry 1`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil {
+				t.Fatal("Parse(): expected error, got nil")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Parse() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_NamesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wave.yaml")
+	if err := os.WriteFile(path, []byte("version: 1\nmigrations:\n  - mode: org-to-org\n    source: acme\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), path+": entry 1 (line 3): target is required") {
+		t.Errorf("Load() error = %v, want the file and the entry", err)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := NewReport("wave.yaml", []EntryReport{
+		{Index: 1, Status: StatusSucceeded},
+		{Index: 2, Status: StatusFailed, Error: "boom"},
+		{Index: 3, Status: StatusNotRun},
+		{Index: 4, Status: StatusSucceeded},
+	}, started, started.Add(time.Minute))
+
+	want := ReportSummary{Entries: 4, Succeeded: 2, Failed: 1, NotRun: 1}
+	if r.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", r.Summary, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := SaveReport(path, r); err != nil {
+		t.Fatalf("SaveReport() unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"status": "not_run"`) || !strings.Contains(string(data), `"error": "boom"`) {
+		t.Errorf("SaveReport() wrote:\n%s", data)
+	}
+}
@@ -2,9 +2,10 @@ package client
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
+	"net/http"
 	"strings"
 	"time"
 
@@ -20,11 +21,13 @@ const minRemainingRequests = 10
 type Client struct {
 	restClient *api.RESTClient
 	sleepFn    func(time.Duration)
+	counter    *requestCounter
 }
 
 // New creates a new GitHub API client using default authentication
 func New() (*Client, error) {
-	restClient, err := api.DefaultRESTClient()
+	counter := newRequestCounter(nil)
+	restClient, err := api.NewRESTClient(api.ClientOptions{Transport: counter})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GitHub API client: %w", err)
 	}
@@ -32,6 +35,7 @@ func New() (*Client, error) {
 	return &Client{
 		restClient: restClient,
 		sleepFn:    time.Sleep,
+		counter:    counter,
 	}, nil
 }
 
@@ -45,6 +49,9 @@ func NewWithToken(token string) (*Client, error) {
 		AuthToken: token,
 	}
 
+	counter := newRequestCounter(opts.Transport)
+	opts.Transport = counter
+
 	restClient, err := api.NewRESTClient(opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GitHub API client with token: %w", err)
@@ -53,6 +60,7 @@ func NewWithToken(token string) (*Client, error) {
 	return &Client{
 		restClient: restClient,
 		sleepFn:    time.Sleep,
+		counter:    counter,
 	}, nil
 }
 
@@ -70,6 +78,9 @@ func NewWithTokenAndHost(token, host string) (*Client, error) {
 		Host:      host,
 	}
 
+	counter := newRequestCounter(opts.Transport)
+	opts.Transport = counter
+
 	restClient, err := api.NewRESTClient(opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GitHub API client with token: %w", err)
@@ -78,6 +89,7 @@ func NewWithTokenAndHost(token, host string) (*Client, error) {
 	return &Client{
 		restClient: restClient,
 		sleepFn:    time.Sleep,
+		counter:    counter,
 	}, nil
 }
 
@@ -90,6 +102,9 @@ func NewWithHost(host string) (*Client, error) {
 		Host: host,
 	}
 
+	counter := newRequestCounter(opts.Transport)
+	opts.Transport = counter
+
 	restClient, err := api.NewRESTClient(opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GitHub API client for host %s: %w", host, err)
@@ -98,6 +113,36 @@ func NewWithHost(host string) (*Client, error) {
 	return &Client{
 		restClient: restClient,
 		sleepFn:    time.Sleep,
+		counter:    counter,
+	}, nil
+}
+
+// NewWithTransport creates a new GitHub API client that sends every request
+// through the given transport instead of the network. Rate limit waits are
+// skipped. This is used by tests to stand in for the GitHub API.
+func NewWithTransport(token, host string, transport http.RoundTripper) (*Client, error) {
+	if token == "" {
+		return nil, fmt.Errorf("token cannot be empty")
+	}
+
+	opts := api.ClientOptions{
+		AuthToken: token,
+		Host:      host,
+		Transport: transport,
+	}
+
+	counter := newRequestCounter(opts.Transport)
+	opts.Transport = counter
+
+	restClient, err := api.NewRESTClient(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub API client with transport: %w", err)
+	}
+
+	return &Client{
+		restClient: restClient,
+		sleepFn:    func(time.Duration) {},
+		counter:    counter,
 	}, nil
 }
 
@@ -146,6 +191,22 @@ func (c *Client) ListEnvVariables(owner, repo, env string) ([]types.Variable, er
 	return response.Variables, nil
 }
 
+// CountEnvVariables returns the number of variables of a repository
+// environment, reading the total of a one-item page instead of listing them
+func (c *Client) CountEnvVariables(owner, repo, env string) (int, error) {
+	var response struct {
+		TotalCount int `json:"total_count"`
+	}
+
+	path := fmt.Sprintf("repos/%s/%s/environments/%s/variables?per_page=1", owner, repo, env)
+	err := c.restClient.Get(path, &response)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count environment variables: %w", err)
+	}
+
+	return response.TotalCount, nil
+}
+
 // GetRepoVariable gets a specific variable from a repository
 func (c *Client) GetRepoVariable(owner, repo, name string) (*types.Variable, error) {
 	var variable types.Variable
@@ -335,6 +396,33 @@ func (c *Client) UpdateEnvVariable(owner, repo, env string, variable types.Varia
 	return nil
 }
 
+// DeleteRepoVariable deletes a variable from a repository
+func (c *Client) DeleteRepoVariable(owner, repo, name string) error {
+	path := fmt.Sprintf("repos/%s/%s/actions/variables/%s", owner, repo, name)
+	if err := c.restClient.Delete(path, nil); err != nil {
+		return fmt.Errorf("failed to delete repository variable: %w", err)
+	}
+	return nil
+}
+
+// DeleteOrgVariable deletes a variable from an organization
+func (c *Client) DeleteOrgVariable(org, name string) error {
+	path := fmt.Sprintf("orgs/%s/actions/variables/%s", org, name)
+	if err := c.restClient.Delete(path, nil); err != nil {
+		return fmt.Errorf("failed to delete organization variable: %w", err)
+	}
+	return nil
+}
+
+// DeleteEnvVariable deletes a variable from an environment
+func (c *Client) DeleteEnvVariable(owner, repo, env, name string) error {
+	path := fmt.Sprintf("repos/%s/%s/environments/%s/variables/%s", owner, repo, env, name)
+	if err := c.restClient.Delete(path, nil); err != nil {
+		return fmt.Errorf("failed to delete environment variable: %w", err)
+	}
+	return nil
+}
+
 // ListOrgVariableSelectedRepos returns the repositories selected for an
 // organization variable that has "selected" visibility.
 func (c *Client) ListOrgVariableSelectedRepos(org, varName string) ([]types.Repository, error) {
@@ -364,6 +452,37 @@ func (c *Client) GetRepo(owner, name string) (*types.Repository, error) {
 	return &repo, nil
 }
 
+// GetOrg retrieves an organization by name
+func (c *Client) GetOrg(org string) (*types.Organization, error) {
+	var o types.Organization
+
+	path := fmt.Sprintf("orgs/%s", org)
+	if err := c.restClient.Get(path, &o); err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+// ListOrgRepos lists every repository in an organization, following
+// pagination
+func (c *Client) ListOrgRepos(org string) ([]types.Repository, error) {
+	const perPage = 100
+
+	var repos []types.Repository
+	for page := 1; ; page++ {
+		var batch []types.Repository
+		path := fmt.Sprintf("orgs/%s/repos?per_page=%d&page=%d", org, perPage, page)
+		if err := c.restClient.Get(path, &batch); err != nil {
+			return nil, fmt.Errorf("failed to list organization repositories: %w", err)
+		}
+		repos = append(repos, batch...)
+		if len(batch) < perPage {
+			return repos, nil
+		}
+	}
+}
+
 // ListEnvironments lists all environments for a repository
 func (c *Client) ListEnvironments(owner, repo string) ([]types.Environment, error) {
 	var response struct {
@@ -406,31 +525,228 @@ func (c *Client) CreateEnvironment(owner, repo, envName string) error {
 	return nil
 }
 
-// GetTokenScopes returns the OAuth scopes associated with the token by inspecting
-// the X-OAuth-Scopes response header. Returns nil if the header is absent (e.g.
-// fine-grained PATs or GITHUB_TOKEN from Actions), indicating scope validation
-// should be skipped.
-func (c *Client) GetTokenScopes() ([]string, error) {
+// UpdateEnvironment creates or updates an environment in a repository with
+// the given protection settings
+func (c *Client) UpdateEnvironment(owner, repo, envName string, settings types.EnvironmentSettings) error {
+	path := fmt.Sprintf("repos/%s/%s/environments/%s", owner, repo, envName)
+
+	bodyBytes, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	err = c.restClient.Put(path, bytes.NewReader(bodyBytes), nil)
+	if err != nil {
+		return fmt.Errorf("failed to update environment: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserID returns the ID of the user with the given login
+func (c *Client) GetUserID(login string) (int64, error) {
+	var user struct {
+		ID int64 `json:"id"`
+	}
+
+	path := fmt.Sprintf("users/%s", login)
+	if err := c.restClient.Get(path, &user); err != nil {
+		return 0, err
+	}
+
+	return user.ID, nil
+}
+
+// GetTeamID returns the ID of the team with the given slug in an
+// organization
+func (c *Client) GetTeamID(org, slug string) (int64, error) {
+	var team struct {
+		ID int64 `json:"id"`
+	}
+
+	path := fmt.Sprintf("orgs/%s/teams/%s", org, slug)
+	if err := c.restClient.Get(path, &team); err != nil {
+		return 0, err
+	}
+
+	return team.ID, nil
+}
+
+// ListDeploymentBranchPolicies lists the custom deployment branch policies
+// of an environment, following pagination
+func (c *Client) ListDeploymentBranchPolicies(owner, repo, envName string) ([]types.BranchPolicy, error) {
+	const perPage = 100
+
+	var policies []types.BranchPolicy
+	for page := 1; ; page++ {
+		var response struct {
+			TotalCount     int                  `json:"total_count"`
+			BranchPolicies []types.BranchPolicy `json:"branch_policies"`
+		}
+		path := fmt.Sprintf("repos/%s/%s/environments/%s/deployment-branch-policies?per_page=%d&page=%d", owner, repo, envName, perPage, page)
+		if err := c.restClient.Get(path, &response); err != nil {
+			return nil, fmt.Errorf("failed to list deployment branch policies: %w", err)
+		}
+		policies = append(policies, response.BranchPolicies...)
+		if len(response.BranchPolicies) < perPage {
+			return policies, nil
+		}
+	}
+}
+
+// CreateDeploymentBranchPolicy adds a custom deployment branch policy to an
+// environment
+func (c *Client) CreateDeploymentBranchPolicy(owner, repo, envName string, policy types.BranchPolicy) error {
+	path := fmt.Sprintf("repos/%s/%s/environments/%s/deployment-branch-policies", owner, repo, envName)
+
+	bodyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	err = c.restClient.Post(path, bytes.NewReader(bodyBytes), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create deployment branch policy: %w", err)
+	}
+
+	return nil
+}
+
+// ListProtectedBranches lists the names of the protected branches of a
+// repository, following pagination
+func (c *Client) ListProtectedBranches(owner, repo string) ([]string, error) {
+	const perPage = 100
+
+	var names []string
+	for page := 1; ; page++ {
+		var batch []struct {
+			Name string `json:"name"`
+		}
+		path := fmt.Sprintf("repos/%s/%s/branches?protected=true&per_page=%d&page=%d", owner, repo, perPage, page)
+		if err := c.restClient.Get(path, &batch); err != nil {
+			return nil, fmt.Errorf("failed to list protected branches: %w", err)
+		}
+		for _, b := range batch {
+			names = append(names, b.Name)
+		}
+		if len(batch) < perPage {
+			return names, nil
+		}
+	}
+}
+
+// workflowsDir is where GitHub Actions reads a repository's workflow files
+const workflowsDir = ".github/workflows"
+
+// ListWorkflowFiles fetches the workflow files (.yml and .yaml) of a
+// repository's default branch through the contents API. A repository
+// without a workflows directory has none.
+func (c *Client) ListWorkflowFiles(owner, repo string) ([]types.WorkflowFile, error) {
+	var entries []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+
+	path := fmt.Sprintf("repos/%s/%s/contents/%s", owner, repo, workflowsDir)
+	if err := c.restClient.Get(path, &entries); err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list workflow files: %w", err)
+	}
+
+	var files []types.WorkflowFile
+	for _, e := range entries {
+		if e.Type != "file" || (!strings.HasSuffix(e.Path, ".yml") && !strings.HasSuffix(e.Path, ".yaml")) {
+			continue
+		}
+		var file struct {
+			Content  string `json:"content"`
+			Encoding string `json:"encoding"`
+		}
+		if err := c.restClient.Get(fmt.Sprintf("repos/%s/%s/contents/%s", owner, repo, e.Path), &file); err != nil {
+			return nil, fmt.Errorf("failed to fetch workflow file %s: %w", e.Path, err)
+		}
+		if file.Encoding != "base64" {
+			return nil, fmt.Errorf("workflow file %s has unsupported encoding %q", e.Path, file.Encoding)
+		}
+		// The contents API wraps the base64 content in lines
+		content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode workflow file %s: %w", e.Path, err)
+		}
+		files = append(files, types.WorkflowFile{Path: e.Path, Content: string(content)})
+	}
+	return files, nil
+}
+
+// TokenInfo describes the token of a client as the user endpoint reports it
+type TokenInfo struct {
+	Login string
+	Name  string
+	Email string
+	// Scopes are the OAuth scopes of the token, nil when the token does not
+	// expose them (e.g. fine-grained PATs or GITHUB_TOKEN from Actions)
+	Scopes []string
+	// Expiration is when the token expires, zero when it does not or the
+	// host does not say
+	Expiration time.Time
+}
+
+// expirationLayouts are the layouts of the
+// GitHub-Authentication-Token-Expiration header
+var expirationLayouts = []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"}
+
+// GetTokenInfo returns the user the token authenticates as, its OAuth scopes
+// from the X-OAuth-Scopes header, and its expiration from the
+// GitHub-Authentication-Token-Expiration header
+func (c *Client) GetTokenInfo() (*TokenInfo, error) {
 	resp, err := c.restClient.Request("GET", "user", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve token scopes: %w", err)
+		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
-	_, _ = io.Copy(io.Discard, resp.Body)
 
-	scopesHeader := resp.Header.Get("X-OAuth-Scopes")
-	if scopesHeader == "" {
-		return nil, nil
+	var user struct {
+		Login string `json:"login"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
 	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode the user: %w", err)
+	}
+	info := &TokenInfo{Login: user.Login, Name: user.Name, Email: user.Email}
 
-	parts := strings.Split(scopesHeader, ",")
-	scopes := make([]string, 0, len(parts))
-	for _, s := range parts {
-		if trimmed := strings.TrimSpace(s); trimmed != "" {
-			scopes = append(scopes, trimmed)
+	if scopesHeader := resp.Header.Get("X-OAuth-Scopes"); scopesHeader != "" {
+		parts := strings.Split(scopesHeader, ",")
+		info.Scopes = make([]string, 0, len(parts))
+		for _, s := range parts {
+			if trimmed := strings.TrimSpace(s); trimmed != "" {
+				info.Scopes = append(info.Scopes, trimmed)
+			}
 		}
 	}
-	return scopes, nil
+	if expiration := resp.Header.Get("GitHub-Authentication-Token-Expiration"); expiration != "" {
+		for _, layout := range expirationLayouts {
+			if t, err := time.Parse(layout, expiration); err == nil {
+				info.Expiration = t
+				break
+			}
+		}
+	}
+	return info, nil
+}
+
+// GetTokenScopes returns the OAuth scopes associated with the token by inspecting
+// the X-OAuth-Scopes response header. Returns nil if the header is absent (e.g.
+// fine-grained PATs or GITHUB_TOKEN from Actions), indicating scope validation
+// should be skipped.
+func (c *Client) GetTokenScopes() ([]string, error) {
+	info, err := c.GetTokenInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve token scopes: %w", err)
+	}
+	return info.Scopes, nil
 }
 
 // GetUser retrieves the authenticated user information
@@ -448,13 +764,22 @@ func (c *Client) GetUser() (string, error) {
 
 // GetRateLimit retrieves the current GitHub API rate limit status.
 func (c *Client) GetRateLimit() (*types.RateLimitInfo, error) {
+	limits, err := c.GetRateLimits()
+	if err != nil {
+		return nil, err
+	}
+	core := limits["core"]
+	return &core, nil
+}
+
+// GetRateLimits retrieves the rate limit status of every API resource the
+// host reports, keyed by resource name (core, graphql, search, ...)
+func (c *Client) GetRateLimits() (map[string]types.RateLimitInfo, error) {
 	var response struct {
-		Resources struct {
-			Core struct {
-				Limit     int   `json:"limit"`
-				Remaining int   `json:"remaining"`
-				Reset     int64 `json:"reset"`
-			} `json:"core"`
+		Resources map[string]struct {
+			Limit     int   `json:"limit"`
+			Remaining int   `json:"remaining"`
+			Reset     int64 `json:"reset"`
 		} `json:"resources"`
 	}
 
@@ -462,11 +787,15 @@ func (c *Client) GetRateLimit() (*types.RateLimitInfo, error) {
 		return nil, fmt.Errorf("failed to get rate limit: %w", err)
 	}
 
-	return &types.RateLimitInfo{
-		Limit:     response.Resources.Core.Limit,
-		Remaining: response.Resources.Core.Remaining,
-		ResetTime: time.Unix(response.Resources.Core.Reset, 0),
-	}, nil
+	limits := make(map[string]types.RateLimitInfo, len(response.Resources))
+	for name, r := range response.Resources {
+		limits[name] = types.RateLimitInfo{
+			Limit:     r.Limit,
+			Remaining: r.Remaining,
+			ResetTime: time.Unix(r.Reset, 0),
+		}
+	}
+	return limits, nil
 }
 
 // WaitForRateLimit checks the current rate limit and pauses if remaining calls are critically low.
@@ -1,12 +1,20 @@
 package client
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/renan-alm/gh-vars-migrator/internal/types"
 )
 
@@ -413,6 +421,69 @@ func TestGetRepo_PathConstruction(t *testing.T) {
 	}
 }
 
+// pagedRepos serves GET orgs/<org>/repos from a fixed list of repositories,
+// honouring the per_page and page query parameters
+type pagedRepos struct {
+	repos []types.Repository
+	pages []string
+}
+
+func (p *pagedRepos) RoundTrip(req *http.Request) (*http.Response, error) {
+	q := req.URL.Query()
+	p.pages = append(p.pages, q.Get("page"))
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	page, _ := strconv.Atoi(q.Get("page"))
+
+	start := min((page-1)*perPage, len(p.repos))
+	end := min(start+perPage, len(p.repos))
+	body, _ := json.Marshal(p.repos[start:end])
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// TestListOrgRepos_Pagination verifies that every page is fetched and that
+// listing stops at the first short page
+func TestListOrgRepos_Pagination(t *testing.T) {
+	tests := []struct {
+		name      string
+		count     int
+		wantPages int
+	}{
+		{name: "empty", count: 0, wantPages: 1},
+		{name: "single page", count: 3, wantPages: 1},
+		{name: "exactly one full page", count: 100, wantPages: 2},
+		{name: "several pages", count: 250, wantPages: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &pagedRepos{}
+			for i := 0; i < tt.count; i++ {
+				fake.repos = append(fake.repos, types.Repository{ID: int64(i + 1), Name: fmt.Sprintf("repo-%d", i)})
+			}
+			c, err := NewWithTransport("test-token", "github.com", fake)
+			if err != nil {
+				t.Fatalf("NewWithTransport() unexpected error: %v", err)
+			}
+
+			repos, err := c.ListOrgRepos("acme")
+			if err != nil {
+				t.Fatalf("ListOrgRepos() unexpected error: %v", err)
+			}
+			if len(repos) != tt.count {
+				t.Errorf("ListOrgRepos() returned %d repositories, want %d", len(repos), tt.count)
+			}
+			if len(fake.pages) != tt.wantPages {
+				t.Errorf("ListOrgRepos() fetched pages %v, want %d page(s)", fake.pages, tt.wantPages)
+			}
+		})
+	}
+}
+
 // TestNewWithToken_EmptyToken verifies that NewWithToken rejects empty tokens
 func TestNewWithToken_EmptyToken(t *testing.T) {
 	_, err := NewWithToken("")
@@ -531,3 +602,450 @@ func TestWaitForRateLimit_AlreadyReset(t *testing.T) {
 		t.Error("Expected no sleep when reset time has already passed, but sleepFn was called")
 	}
 }
+
+// roundTripFunc adapts a function to an http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestAPICalls(t *testing.T) {
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"variables":[]}`)),
+			Request:    req,
+		}, nil
+	})
+	c, err := NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("NewWithTransport() unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = c.ListRepoVariables("o", "r")
+		}()
+	}
+	wg.Wait()
+	_ = c.CreateRepoVariable("o", "r", types.Variable{Name: "A", Value: "1"})
+
+	calls := c.APICalls()
+	if calls["GET"] != 20 || calls["POST"] != 1 || calls.Total() != 21 {
+		t.Errorf("APICalls() = %v, want 20 GET and 1 POST", calls)
+	}
+
+	// The returned counts are a copy.
+	calls["GET"] = 0
+	if c.APICalls()["GET"] != 20 {
+		t.Error("APICalls() must return a copy of the counters")
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unauthorized", err: &api.HTTPError{StatusCode: 401, Message: "Bad credentials"}, want: true},
+		{name: "forbidden", err: &api.HTTPError{StatusCode: 403, Message: "Resource not accessible by personal access token"}, want: true},
+		{name: "wrapped", err: fmt.Errorf("failed to list source organization variables: %w", &api.HTTPError{StatusCode: 401}), want: true},
+		{name: "rate limited", err: &api.HTTPError{StatusCode: 403, Message: "API rate limit exceeded for user ID 1."}, want: false},
+		{name: "not found", err: &api.HTTPError{StatusCode: 404, Message: "Not Found"}, want: false},
+		{name: "other error", err: fmt.Errorf("connection refused"), want: false},
+		{name: "nil", err: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAuthError(tt.err); got != tt.want {
+				t.Errorf("IsAuthError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// workflowContents serves the contents API for a repository's workflow
+// files, keyed by path; without files the directory is missing
+type workflowContents struct {
+	files map[string]string
+}
+
+func (w *workflowContents) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := strings.TrimPrefix(req.URL.Path, "/repos/acme/app/contents/")
+	status, body := http.StatusOK, []byte(`{"message":"Not Found"}`)
+
+	switch {
+	case len(w.files) == 0:
+		status = http.StatusNotFound
+	case path == ".github/workflows":
+		entries := []map[string]string{{"path": ".github/workflows/nested", "type": "dir"}}
+		for p := range w.files {
+			entries = append(entries, map[string]string{"path": p, "type": "file"})
+		}
+		body, _ = json.Marshal(entries)
+	default:
+		content, ok := w.files[path]
+		if !ok {
+			status = http.StatusNotFound
+			break
+		}
+		// The API breaks the encoded content into lines of 60 characters
+		encoded := base64.StdEncoding.EncodeToString([]byte(content))
+		var wrapped strings.Builder
+		for len(encoded) > 60 {
+			wrapped.WriteString(encoded[:60] + "\n")
+			encoded = encoded[60:]
+		}
+		wrapped.WriteString(encoded)
+		body, _ = json.Marshal(map[string]string{"content": wrapped.String(), "encoding": "base64"})
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestListWorkflowFiles(t *testing.T) {
+	long := "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo ${{ vars.REGION }}\n"
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  map[string]string
+	}{
+		{name: "no workflows directory", files: nil, want: map[string]string{}},
+		{
+			name: "yml and yaml files",
+			files: map[string]string{
+				".github/workflows/ci.yml":      long,
+				".github/workflows/deploy.yaml": "on: push\n",
+				".github/workflows/README.md":   "docs",
+			},
+			want: map[string]string{
+				".github/workflows/ci.yml":      long,
+				".github/workflows/deploy.yaml": "on: push\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewWithTransport("test-token", "github.com", &workflowContents{files: tt.files})
+			if err != nil {
+				t.Fatalf("NewWithTransport() unexpected error: %v", err)
+			}
+			files, err := c.ListWorkflowFiles("acme", "app")
+			if err != nil {
+				t.Fatalf("ListWorkflowFiles() unexpected error: %v", err)
+			}
+			got := map[string]string{}
+			for _, f := range files {
+				got[f.Path] = f.Content
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ListWorkflowFiles() returned %v, want %v", got, tt.want)
+			}
+			for path, content := range tt.want {
+				if got[path] != content {
+					t.Errorf("Content of %s = %q, want %q", path, got[path], content)
+				}
+			}
+		})
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	if !IsNotFound(fmt.Errorf("wrapped: %w", &api.HTTPError{StatusCode: 404})) {
+		t.Error("A wrapped 404 response is a not-found error")
+	}
+	if IsNotFound(&api.HTTPError{StatusCode: 403}) || IsNotFound(fmt.Errorf("connection refused")) {
+		t.Error("Only 404 responses are not-found errors")
+	}
+}
+
+func TestGetEnvironment_ProtectionRules(t *testing.T) {
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"id": 1, "name": "prod", "protection_rules": [
+			{"id": 3, "type": "wait_timer", "wait_timer": 30},
+			{"id": 4, "type": "required_reviewers", "prevent_self_review": true, "reviewers": [
+				{"type": "User", "reviewer": {"id": 11, "login": "octocat"}},
+				{"type": "Team", "reviewer": {"id": 22, "slug": "release"}}
+			]},
+			{"id": 5, "type": "branch_policy"}
+		]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+	c, err := NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("NewWithTransport() unexpected error: %v", err)
+	}
+
+	env, err := c.GetEnvironment("acme", "app", "prod")
+	if err != nil {
+		t.Fatalf("GetEnvironment() unexpected error: %v", err)
+	}
+	if len(env.ProtectionRules) != 3 {
+		t.Fatalf("Expected 3 protection rules, got %+v", env.ProtectionRules)
+	}
+	if r := env.ProtectionRules[0]; r.Type != types.RuleWaitTimer || r.WaitTimer != 30 {
+		t.Errorf("Unexpected wait timer rule: %+v", r)
+	}
+	r := env.ProtectionRules[1]
+	if r.Type != types.RuleRequiredReviewers || !r.PreventSelfReview || len(r.Reviewers) != 2 {
+		t.Fatalf("Unexpected reviewers rule: %+v", r)
+	}
+	if u := r.Reviewers[0]; u.Type != types.ReviewerUser || u.Reviewer.Login != "octocat" {
+		t.Errorf("Unexpected user reviewer: %+v", u)
+	}
+	if team := r.Reviewers[1]; team.Type != types.ReviewerTeam || team.Reviewer.Slug != "release" {
+		t.Errorf("Unexpected team reviewer: %+v", team)
+	}
+}
+
+func TestUpdateEnvironment_RequestBody(t *testing.T) {
+	var method, path string
+	var body map[string]any
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		method, path = req.Method, req.URL.Path
+		data, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(data, &body)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{}`)),
+			Request:    req,
+		}, nil
+	})
+	c, err := NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("NewWithTransport() unexpected error: %v", err)
+	}
+
+	settings := types.EnvironmentSettings{
+		WaitTimer:         15,
+		PreventSelfReview: true,
+		Reviewers:         []types.ReviewerRef{{Type: types.ReviewerTeam, ID: 7}},
+	}
+	if err := c.UpdateEnvironment("acme", "app", "prod", settings); err != nil {
+		t.Fatalf("UpdateEnvironment() unexpected error: %v", err)
+	}
+
+	if method != http.MethodPut || path != "/repos/acme/app/environments/prod" {
+		t.Errorf("Request = %s %s, want PUT /repos/acme/app/environments/prod", method, path)
+	}
+	want := map[string]any{
+		"wait_timer":          15.0,
+		"prevent_self_review": true,
+		"reviewers":           []any{map[string]any{"type": "Team", "id": 7.0}},
+		// nil allows every branch and must be sent explicitly
+		"deployment_branch_policy": nil,
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("Body = %v, want %v", body, want)
+	}
+}
+
+func TestDeploymentBranchPolicies(t *testing.T) {
+	var requests []string
+	var posted map[string]any
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery)
+		body := `{}`
+		switch {
+		case req.Method == http.MethodPost:
+			data, _ := io.ReadAll(req.Body)
+			_ = json.Unmarshal(data, &posted)
+		case strings.HasSuffix(req.URL.Path, "/deployment-branch-policies"):
+			body = `{"total_count": 2, "branch_policies": [{"id": 1, "name": "release/*", "type": "branch"}, {"id": 2, "name": "v*", "type": "tag"}]}`
+		case strings.HasSuffix(req.URL.Path, "/branches"):
+			body = `[{"name": "main", "protected": true}]`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+	c, err := NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("NewWithTransport() unexpected error: %v", err)
+	}
+
+	policies, err := c.ListDeploymentBranchPolicies("acme", "app", "prod")
+	if err != nil {
+		t.Fatalf("ListDeploymentBranchPolicies() unexpected error: %v", err)
+	}
+	want := []types.BranchPolicy{{Name: "release/*", Type: "branch"}, {Name: "v*", Type: "tag"}}
+	if !reflect.DeepEqual(policies, want) {
+		t.Errorf("ListDeploymentBranchPolicies() = %+v, want %+v", policies, want)
+	}
+
+	if err := c.CreateDeploymentBranchPolicy("acme", "app", "prod", types.BranchPolicy{Name: "main"}); err != nil {
+		t.Fatalf("CreateDeploymentBranchPolicy() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(posted, map[string]any{"name": "main"}) {
+		t.Errorf("Policy body = %v, want only the name when the type is empty", posted)
+	}
+
+	branches, err := c.ListProtectedBranches("acme", "app")
+	if err != nil {
+		t.Fatalf("ListProtectedBranches() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(branches, []string{"main"}) {
+		t.Errorf("ListProtectedBranches() = %v, want [main]", branches)
+	}
+
+	wantRequests := []string{
+		"GET /repos/acme/app/environments/prod/deployment-branch-policies?per_page=100&page=1",
+		"POST /repos/acme/app/environments/prod/deployment-branch-policies?",
+		"GET /repos/acme/app/branches?protected=true&per_page=100&page=1",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("Requests = %v, want %v", requests, wantRequests)
+	}
+}
+
+func TestCountEnvVariables(t *testing.T) {
+	var requested string
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.RequestURI()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"total_count": 42, "variables": [{"name": "A"}]}`)),
+			Request:    req,
+		}, nil
+	})
+	c, err := NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("NewWithTransport() unexpected error: %v", err)
+	}
+
+	count, err := c.CountEnvVariables("acme", "app", "prod")
+	if err != nil {
+		t.Fatalf("CountEnvVariables() unexpected error: %v", err)
+	}
+	if count != 42 {
+		t.Errorf("CountEnvVariables() = %d, want the total count 42", count)
+	}
+	if requested != "/repos/acme/app/environments/prod/variables?per_page=1" {
+		t.Errorf("Requested %s, want a one-item page", requested)
+	}
+}
+
+func TestGetRateLimits(t *testing.T) {
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body: io.NopCloser(strings.NewReader(`{"resources": {
+				"core": {"limit": 5000, "remaining": 4990, "reset": 1700000000},
+				"graphql": {"limit": 5000, "remaining": 5000, "reset": 1700003600}}}`)),
+			Request: req,
+		}, nil
+	})
+	c, err := NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("NewWithTransport() unexpected error: %v", err)
+	}
+
+	limits, err := c.GetRateLimits()
+	if err != nil {
+		t.Fatalf("GetRateLimits() unexpected error: %v", err)
+	}
+	want := map[string]types.RateLimitInfo{
+		"core":    {Limit: 5000, Remaining: 4990, ResetTime: time.Unix(1700000000, 0)},
+		"graphql": {Limit: 5000, Remaining: 5000, ResetTime: time.Unix(1700003600, 0)},
+	}
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("GetRateLimits() = %+v, want %+v", limits, want)
+	}
+
+	core, err := c.GetRateLimit()
+	if err != nil {
+		t.Fatalf("GetRateLimit() unexpected error: %v", err)
+	}
+	if *core != want["core"] {
+		t.Errorf("GetRateLimit() = %+v, want the core limit", core)
+	}
+}
+
+// TestGetTokenInfo verifies that the user, the OAuth scopes, and the
+// expiration are read from the user endpoint and its headers
+func TestGetTokenInfo(t *testing.T) {
+	tests := []struct {
+		name           string
+		header         map[string]string
+		wantScopes     []string
+		wantExpiration time.Time
+	}{
+		{
+			name: "classic PAT",
+			header: map[string]string{
+				"X-OAuth-Scopes":                         "repo, admin:org",
+				"GitHub-Authentication-Token-Expiration": "2026-11-01 12:30:00 UTC",
+			},
+			wantScopes:     []string{"repo", "admin:org"},
+			wantExpiration: time.Date(2026, 11, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name:           "numeric zone",
+			header:         map[string]string{"GitHub-Authentication-Token-Expiration": "2026-11-01 05:30:00 -0700"},
+			wantExpiration: time.Date(2026, 11, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{name: "fine-grained PAT without expiration"},
+		{
+			name:   "unparsable expiration",
+			header: map[string]string{"GitHub-Authentication-Token-Expiration": "soon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				header := http.Header{"Content-Type": []string{"application/json"}}
+				for k, v := range tt.header {
+					header.Set(k, v)
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     header,
+					Body:       io.NopCloser(strings.NewReader(`{"login":"octocat","name":"Mona","email":"mona@example.com"}`)),
+					Request:    req,
+				}, nil
+			})
+			c, err := NewWithTransport("test-token", "github.com", fake)
+			if err != nil {
+				t.Fatalf("NewWithTransport() unexpected error: %v", err)
+			}
+
+			info, err := c.GetTokenInfo()
+			if err != nil {
+				t.Fatalf("GetTokenInfo() unexpected error: %v", err)
+			}
+			if info.Login != "octocat" || info.Name != "Mona" || info.Email != "mona@example.com" {
+				t.Errorf("GetTokenInfo() user = %q, %q, %q", info.Login, info.Name, info.Email)
+			}
+			if !reflect.DeepEqual(info.Scopes, tt.wantScopes) {
+				t.Errorf("GetTokenInfo() scopes = %#v, want %#v", info.Scopes, tt.wantScopes)
+			}
+			if !info.Expiration.Equal(tt.wantExpiration) {
+				t.Errorf("GetTokenInfo() expiration = %v, want %v", info.Expiration, tt.wantExpiration)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// IsNotFound reports whether err, or an error it wraps, is a GitHub API
+// 404 response
+func IsNotFound(err error) bool {
+	var httpErr *api.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
+
+// IsAuthError reports whether err, or an error it wraps, is a GitHub API
+// response rejecting the credentials (401) or the permissions (403) of the
+// token. A 403 caused by rate limiting is not an authentication error.
+func IsAuthError(err error) bool {
+	var httpErr *api.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	switch httpErr.StatusCode {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusForbidden:
+		return !strings.Contains(strings.ToLower(httpErr.Message), "rate limit")
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// requestCounter is an http.RoundTripper that counts the requests sent
+// through it by HTTP method. It is safe for concurrent use.
+type requestCounter struct {
+	next http.RoundTripper
+
+	mu     sync.Mutex
+	counts types.APICalls
+}
+
+// newRequestCounter wraps next, or http.DefaultTransport when next is nil
+func newRequestCounter(next http.RoundTripper) *requestCounter {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &requestCounter{next: next, counts: types.APICalls{}}
+}
+
+// RoundTrip counts the request and passes it on
+func (rc *requestCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	rc.mu.Lock()
+	rc.counts[req.Method]++
+	rc.mu.Unlock()
+	return rc.next.RoundTrip(req)
+}
+
+// snapshot returns a copy of the counts
+func (rc *requestCounter) snapshot() types.APICalls {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	out := make(types.APICalls, len(rc.counts))
+	for method, n := range rc.counts {
+		out[method] = n
+	}
+	return out
+}
+
+// APICalls returns the number of API requests the client has made so far,
+// by HTTP method
+func (c *Client) APICalls() types.APICalls {
+	if c.counter == nil {
+		return types.APICalls{}
+	}
+	return c.counter.snapshot()
+}
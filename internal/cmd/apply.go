@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/manifest"
+	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/plan"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// applyCmd applies a plan written by a dry run with --plan-out, or a
+// desired-state manifest
+var applyCmd = &cobra.Command{
+	Use:   "apply (--plan FILE | --manifest FILE)",
+	Short: "Apply a plan written by a dry run with --plan-out, or a manifest",
+	Long: `Apply exactly the creates and updates recorded in a plan file written by a
+dry run with --plan-out.
+
+Pass the same migration flags as the dry run: the plan records the source,
+the target, and a fingerprint of the name, value, environment, and visibility
+options, and is rejected when they differ. Variables outside the plan are left
+alone. Before each write the source value is compared with the hash in the
+plan, and a variable changed or removed since is failed as drifted.
+
+With --manifest, apply a YAML manifest that declares the variables of
+organizations, repositories, and environments instead. Every declared scope is
+compared with the target: missing variables are created, differing ones are
+updated under --on-conflict, and with --prune, variables the manifest does not
+declare are deleted. Applying the same manifest again changes nothing. Only
+the target credentials and hostname are used; --dry-run, --diff,
+--report-file, and the hooks work as for a migration. export --manifest
+writes a manifest from the current variables.`,
+	Example: `  # Review a plan, then apply it
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --dry-run --plan-out plan.json
+  gh vars-migrator apply --plan plan.json --source-org myorg --target-org targetorg --org-to-org
+
+  # Preview, then converge the target on a manifest, deleting undeclared variables
+  gh vars-migrator apply --manifest vars.yaml --prune --diff
+  gh vars-migrator apply --manifest vars.yaml --prune`,
+	PreRunE:       validateApplyFlags,
+	RunE:          runMigration,
+	SilenceErrors: true,
+}
+
+var (
+	planFile     string
+	manifestFile string
+	prune        bool
+)
+
+// applyPlan holds the plan loaded from --plan during flag validation
+var applyPlan *plan.Plan
+
+// applyManifest holds the manifest loaded from --manifest during flag
+// validation
+var applyManifest *manifest.Manifest
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	supportOutput(applyCmd, output.JSON, output.CSV)
+	applyCmd.Flags().StringVar(&planFile, "plan", "", "Plan file written by a dry run with --plan-out")
+	applyCmd.Flags().StringVar(&manifestFile, "manifest", "", "YAML manifest of the desired variables to converge the target on")
+	applyCmd.Flags().BoolVar(&prune, "prune", false, "With --manifest, delete variables of the declared scopes that the manifest does not declare")
+	// The migration flags are added by the root command once it has
+	// registered them.
+}
+
+// validateApplyFlags loads the plan and validates the migration flags the
+// plan is applied with
+func validateApplyFlags(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	applyPlan, applyManifest = nil, nil
+	switch {
+	case planFile != "" && manifestFile != "":
+		return fmt.Errorf("--plan and --manifest cannot be combined")
+	case prune && manifestFile == "":
+		return fmt.Errorf("--prune requires --manifest")
+	case manifestFile != "":
+		return validateManifestFlags(cmd)
+	case planFile == "":
+		return fmt.Errorf("--plan or --manifest flag is required")
+	}
+	switch {
+	case planOut != "":
+		return fmt.Errorf("--plan-out cannot be used with apply")
+	case diffMode:
+		return fmt.Errorf("apply cannot be combined with --diff")
+	case rollbackFile != "":
+		return fmt.Errorf("apply cannot be combined with --rollback")
+	case retryFailed != "":
+		return fmt.Errorf("apply cannot be combined with --retry-failed")
+	}
+
+	p, err := plan.Load(planFile)
+	if err != nil {
+		return fmt.Errorf("--plan: %w", err)
+	}
+	applyPlan = p
+	return validateFlags(cmd, args)
+}
+
+// manifestFlags are the flags apply --manifest accepts besides its own: the
+// target connection and the options that decide how the run writes, stops,
+// and reports. Everything about a source or the migration's selection and
+// transformations does not apply.
+var manifestFlags = map[string]bool{
+	"manifest": true, "prune": true,
+	"verbose": true, "quiet": true, "no-color": true, "no-annotations": true, "env-file": true, "output": true,
+	"target-pat": true, "target-pat-file": true, "target-hostname": true, "no-keyring": true,
+	"dry-run": true, "diff": true, "show-values": true, "exit-code-on-diff": true,
+	"on-conflict": true, "skip-overwrite": true, "interactive": true, "always-write": true,
+	"fail-fast": true, "max-errors": true, "max-api-calls": true,
+	"report-file": true, "last-report-file": true, "github-summary": true, "report-include-values": true,
+	"pre-hook": true, "post-hook": true, "hooks-in-dry-run": true,
+}
+
+// validateManifestFlags loads the manifest and checks the flags it is
+// applied with
+func validateManifestFlags(cmd *cobra.Command) error {
+	if err := rejectFlags(cmd, "--manifest", func(name string) bool { return manifestFlags[name] }); err != nil {
+		return err
+	}
+
+	targetHostname = normalizeHostname(targetHostname)
+	if err := validateRunOptions(); err != nil {
+		return err
+	}
+	if err := validateConflictOptions(); err != nil {
+		return err
+	}
+
+	m, err := manifest.Load(manifestFile)
+	if err != nil {
+		return fmt.Errorf("--manifest: %w", err)
+	}
+	applyManifest = m
+	return nil
+}
+
+// rejectFlags fails when a flag that allowed does not accept was set on the
+// command line; with names what the flags cannot be combined with
+func rejectFlags(cmd *cobra.Command, with string, allowed func(name string) bool) error {
+	var rejected []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if !allowed(f.Name) {
+			rejected = append(rejected, "--"+f.Name)
+		}
+	})
+	if len(rejected) > 0 {
+		return fmt.Errorf("%s cannot be combined with %s", strings.Join(rejected, ", "), with)
+	}
+	return nil
+}
+
+// runManifest converges the target on the --manifest manifest. Only the
+// target credentials and hostname are used.
+func runManifest(cmd *cobra.Command) error {
+	targetClient, err := targetOnlyClient("manifest")
+	if err != nil {
+		return authError(err)
+	}
+	if err := validateManifestScopes(targetClient, applyManifest); err != nil {
+		return authError(err)
+	}
+
+	cfg := &types.MigrationConfig{
+		Mode:          types.ModeManifest,
+		Manifest:      manifestFile,
+		Desired:       applyManifest.Desired(),
+		Prune:         prune,
+		DryRun:        dryRun,
+		SkipOverwrite: skipOverwrite,
+		OnConflict:    types.ConflictStrategy(onConflict),
+		Interactive:   interactive,
+		ShowValues:    showValues,
+		FailFast:      failFast,
+		MaxErrors:     maxErrors,
+		MaxAPICalls:   maxAPICalls,
+		AlwaysWrite:   alwaysWrite,
+	}
+
+	logger.Info("Manifest:        %s (%d scope(s))  ← %s", manifestFile, len(cfg.Desired), flagSource(cmd, "manifest", ""))
+	if prune {
+		logger.Info("Prune:           true  ← %s", flagSource(cmd, "prune", ""))
+	}
+
+	// The manifest is the source, so the target client serves both roles
+	m, err := migrator.New(cfg, targetClient, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrator: %w", err)
+	}
+	if diffMode {
+		return runDiff(m)
+	}
+	return runMigrator(cfg, m)
+}
+
+// validateManifestScopes checks the token scopes needed by the kinds of
+// scope the manifest declares
+func validateManifestScopes(c *client.Client, m *manifest.Manifest) error {
+	var orgs, repos bool
+	for _, s := range m.Scopes {
+		if s.Org != "" {
+			orgs = true
+		} else {
+			repos = true
+		}
+	}
+	if orgs {
+		if err := client.ValidateOrgScopes(c, "target"); err != nil {
+			return err
+		}
+	}
+	if repos {
+		return client.ValidateRepoScopes(c, "target")
+	}
+	return nil
+}
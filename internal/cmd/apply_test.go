@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestValidateApplyFlags(t *testing.T) {
+	origSourceOrg, origTargetOrg, origOrgToOrg := sourceOrg, targetOrg, orgToOrg
+	origPlanFile, origApplyPlan, origPlanOut := planFile, applyPlan, planOut
+	defer func() {
+		sourceOrg, targetOrg, orgToOrg = origSourceOrg, origTargetOrg, origOrgToOrg
+		planFile, applyPlan, planOut = origPlanFile, origApplyPlan, origPlanOut
+	}()
+
+	dir := t.TempDir()
+	validFile := filepath.Join(dir, "plan.json")
+	if err := os.WriteFile(validFile, []byte(`{"version": 1, "mode": "org-to-org", "source": "source-org", "target": "target-org", "fingerprint": "sha256:x", "actions": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldFile := filepath.Join(dir, "old.json")
+	if err := os.WriteFile(oldFile, []byte(`{"version": 99}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		planFile string
+		planOut  string
+		wantErr  string
+	}{
+		{name: "valid plan", planFile: validFile},
+		{name: "missing plan flag", wantErr: "--plan or --manifest flag is required"},
+		{name: "missing file", planFile: filepath.Join(dir, "missing.json"), wantErr: "reading plan"},
+		{name: "unsupported version", planFile: oldFile, wantErr: "unsupported plan version 99"},
+		{name: "plan out", planFile: validFile, planOut: "next.json", wantErr: "--plan-out cannot be used with apply"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg, orgToOrg = "source-org", "target-org", true
+			planFile, applyPlan, planOut = tt.planFile, nil, tt.planOut
+
+			err := validateApplyFlags(applyCmd, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateApplyFlags() unexpected error: %v", err)
+				}
+				if applyPlan == nil || applyPlan.Source != "source-org" {
+					t.Errorf("Expected the plan to be loaded, got %+v", applyPlan)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateApplyFlags() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateApplyFlags_Manifest(t *testing.T) {
+	origPlanFile, origManifestFile, origPrune := planFile, manifestFile, prune
+	origApplyPlan, origApplyManifest, origDryRun, origExitCodeOnDiff := applyPlan, applyManifest, dryRun, exitCodeOnDiff
+	defer func() {
+		planFile, manifestFile, prune = origPlanFile, origManifestFile, origPrune
+		applyPlan, applyManifest, dryRun, exitCodeOnDiff = origApplyPlan, origApplyManifest, origDryRun, origExitCodeOnDiff
+	}()
+
+	dir := t.TempDir()
+	validFile := filepath.Join(dir, "vars.yaml")
+	if err := os.WriteFile(validFile, []byte("version: 1\nscopes:\n  - repo: acme/app\n    variables:\n      - name: A\n        value: a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	badFile := filepath.Join(dir, "bad.yaml")
+	if err := os.WriteFile(badFile, []byte("version: 1\nscopes:\n  - repo: acme/app\n    variables:\n      - name: A-B\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		planFile     string
+		manifestFile string
+		prune        bool
+		exitCode     bool
+		flag         string
+		wantErr      string
+	}{
+		{name: "valid manifest", manifestFile: validFile, prune: true},
+		{name: "plan and manifest", planFile: "plan.json", manifestFile: validFile, wantErr: "--plan and --manifest cannot be combined"},
+		{name: "prune without manifest", planFile: "plan.json", prune: true, wantErr: "--prune requires --manifest"},
+		{name: "invalid manifest", manifestFile: badFile, wantErr: "bad.yaml: line 5: acme/app: variable A-B"},
+		{name: "run options checked", manifestFile: validFile, exitCode: true, wantErr: "--exit-code-on-diff requires --dry-run"},
+		{name: "source flag", manifestFile: validFile, flag: "source-org", wantErr: "--source-org cannot be combined with --manifest"},
+		{name: "allowed flag", manifestFile: validFile, flag: "target-hostname"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planFile, manifestFile, prune = tt.planFile, tt.manifestFile, tt.prune
+			dryRun, exitCodeOnDiff = false, tt.exitCode
+
+			// A throwaway command, so that setting a flag leaves applyCmd alone
+			cmd := &cobra.Command{Use: "apply"}
+			if tt.flag != "" {
+				cmd.Flags().String(tt.flag, "", "")
+				if err := cmd.Flags().Set(tt.flag, "x"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := validateApplyFlags(cmd, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateApplyFlags() unexpected error: %v", err)
+				}
+				if applyManifest == nil || len(applyManifest.Scopes) != 1 {
+					t.Errorf("Expected the manifest to be loaded, got %+v", applyManifest)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateApplyFlags() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
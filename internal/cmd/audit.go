@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/audit"
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// auditCmd reports shadowed, duplicated, and stale variables of an
+// organization and its repositories
+var auditCmd = &cobra.Command{
+	Use:   "audit --org ORG [--repos REPO,... | --all-repos]",
+	Short: "Find shadowed, duplicated, and stale variables before consolidating",
+	Long: `Read the variables of an organization and, with --repos or --all-repos, of
+its repositories, and report:
+
+  - shadowed variables: repository variables with the name of an organization
+    variable, which they override; those with the same value can be deleted
+  - duplicates: the same name and value in at least --min-repos repositories,
+    candidates for an organization variable
+  - stale variables: variables not updated for more than --stale-days days
+    (0 leaves them out)
+
+--all-repos reads every non-archived repository of the organization. Nothing
+is written.
+
+--output json writes a JSON object of {org, repositories, shadowed,
+duplicates, stale} to standard output instead of the tables, and sends every
+other message to standard error. Duplicated values are left out unless
+--show-values is set. Authentication works as for list.`,
+	Example: `  # Audit an organization and all its repositories
+  gh vars-migrator audit --org renan-org --all-repos
+
+  # Values repeated in at least 5 repositories, as JSON
+  gh vars-migrator audit --org renan-org --all-repos --min-repos 5 --output json
+
+  # Organization variables not updated for two years
+  gh vars-migrator audit --org renan-org --stale-days 730`,
+	Args:    cobra.NoArgs,
+	RunE:    runAudit,
+	PreRunE: validateAuditFlags,
+}
+
+var (
+	auditOrg        string
+	auditRepos      []string
+	auditAllRepos   bool
+	auditMinRepos   int
+	auditStaleDays  int
+	auditShowValues bool
+	auditPAT        string
+	auditHostname   string
+)
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.Flags().StringVarP(&auditOrg, "org", "o", "", "Organization to audit (required)")
+	auditCmd.Flags().StringSliceVar(&auditRepos, "repos", nil, "Repositories of --org to audit; comma-separated or repeatable")
+	auditCmd.Flags().BoolVar(&auditAllRepos, "all-repos", false, "Audit every non-archived repository of --org")
+	auditCmd.Flags().IntVar(&auditMinRepos, "min-repos", 2, "Number of repositories a name and value must be found in to be a duplicate")
+	auditCmd.Flags().IntVar(&auditStaleDays, "stale-days", 365, "Report variables not updated for more than this many days; 0 disables")
+	auditCmd.Flags().BoolVar(&auditShowValues, "show-values", false, "Include the values of duplicates in --output json")
+	auditCmd.Flags().StringVar(&auditPAT, "pat", "", "Personal access token; overrides GITHUB_TOKEN")
+	auditCmd.Flags().StringVar(&auditHostname, "hostname", "", "GitHub hostname for GitHub Enterprise Server (default: github.com)")
+	supportOutput(auditCmd, output.JSON)
+}
+
+// validateAuditFlags checks the audit flags and normalizes --repos
+func validateAuditFlags(cmd *cobra.Command, args []string) error {
+	switch {
+	case auditOrg == "":
+		return fmt.Errorf("--org flag is required")
+	case len(auditRepos) > 0 && auditAllRepos:
+		return fmt.Errorf("--repos and --all-repos cannot be combined")
+	case auditMinRepos < 2:
+		return fmt.Errorf("invalid --min-repos %d: must be at least 2", auditMinRepos)
+	case auditStaleDays < 0:
+		return fmt.Errorf("invalid --stale-days %d: must not be negative", auditStaleDays)
+	case auditShowValues && outputFormat != output.JSON:
+		return fmt.Errorf("--show-values requires --output json")
+	}
+
+	if len(auditRepos) > 0 {
+		repos, err := config.NormalizeRepoNames(auditRepos, auditOrg)
+		if err != nil {
+			return fmt.Errorf("invalid --repos: %w", err)
+		}
+		auditRepos = repos
+	}
+
+	cmd.SilenceUsage = true
+	auditHostname = normalizeHostname(auditHostname)
+	return nil
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	c, err := patClient(auditPAT, auditHostname, "audit")
+	if err != nil {
+		return authError(err)
+	}
+	return auditVariables(c, cmd.OutOrStdout(), time.Now())
+}
+
+// auditVariables reads the variables of the audited organization and
+// repositories and writes the findings, with ages measured from now, to w
+func auditVariables(c *client.Client, w io.Writer, now time.Time) error {
+	logger.Info("Auditing variables of organization: %s", auditOrg)
+	orgVars, err := c.ListOrgVariables(auditOrg)
+	if err != nil {
+		return err
+	}
+
+	repos, err := auditRepositories(c)
+	if err != nil {
+		return err
+	}
+	if len(repos) == 0 {
+		logger.Info("Pass --repos or --all-repos to look for shadowed and duplicated variables")
+	}
+	repoVars := make(map[string][]types.Variable, len(repos))
+	for _, repo := range repos {
+		vars, err := c.ListRepoVariables(auditOrg, repo)
+		if err != nil {
+			return fmt.Errorf("repository %s/%s: %w", auditOrg, repo, err)
+		}
+		repoVars[repo] = vars
+	}
+	logger.Plain("")
+
+	r := audit.Run(auditOrg, orgVars, repoVars, audit.Options{
+		MinRepos:   auditMinRepos,
+		StaleAfter: time.Duration(auditStaleDays) * 24 * time.Hour,
+		Now:        now,
+	})
+
+	if outputFormat == output.JSON {
+		if !auditShowValues {
+			for i := range r.Duplicates {
+				r.Duplicates[i].Value = nil
+			}
+		}
+		return output.WriteJSON(w, r)
+	}
+
+	writeAuditReport(w, r)
+	logger.Success("Audited %d organization variable(s) and %d repository(ies): %d shadowed, %d duplicated, %d stale",
+		len(orgVars), len(repos), len(r.Shadowed), len(r.Duplicates), len(r.Stale))
+	return nil
+}
+
+// auditRepositories returns the repositories to audit: --repos, or every
+// non-archived repository of the organization with --all-repos
+func auditRepositories(c *client.Client) ([]string, error) {
+	if !auditAllRepos {
+		return auditRepos, nil
+	}
+
+	repos, err := c.ListOrgRepos(auditOrg)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(repos))
+	archived := 0
+	for _, r := range repos {
+		if r.Archived {
+			archived++
+			continue
+		}
+		names = append(names, r.Name)
+	}
+	if archived > 0 {
+		logger.Info("Skipping %d archived repository(ies) in %s", archived, auditOrg)
+	}
+	logger.Info("Reading the variables of %d repository(ies)", len(names))
+	return names, nil
+}
+
+// writeAuditReport writes one section per finding, each with its table or a
+// line saying there is nothing to report. Repositories are only audited for
+// shadowing and duplicates when some were read.
+func writeAuditReport(w io.Writer, r *audit.Report) {
+	if r.Repositories > 0 {
+		if len(r.Shadowed) == 0 {
+			fmt.Fprint(w, "Shadowed organization variables: none\n\n")
+		} else {
+			fmt.Fprintf(w, "Shadowed organization variables (%d):\n", len(r.Shadowed))
+			fmt.Fprintf(w, "%-30s %-30s %-15s %s\n", "NAME", "REPOSITORY", "ORG VISIBILITY", "SAME VALUE")
+			fmt.Fprintf(w, "%-30s %-30s %-15s %s\n", "----", "----------", "--------------", "----------")
+			for _, s := range r.Shadowed {
+				fmt.Fprintf(w, "%-30s %-30s %-15s %s\n", s.Name, s.Repository, orDash(s.OrgVisibility), yesNo(s.SameValue))
+			}
+			fmt.Fprintln(w)
+		}
+
+		if len(r.Duplicates) == 0 {
+			fmt.Fprint(w, "Duplicated values: none\n\n")
+		} else {
+			fmt.Fprintf(w, "Duplicated values (%d):\n", len(r.Duplicates))
+			fmt.Fprintf(w, "%-30s %-12s %s\n", "NAME", "ORG VARIABLE", "REPOSITORIES")
+			fmt.Fprintf(w, "%-30s %-12s %s\n", "----", "------------", "------------")
+			for _, d := range r.Duplicates {
+				fmt.Fprintf(w, "%-30s %-12s %d (%s)\n", d.Name, yesNo(d.OrgVariable), len(d.Repositories), strings.Join(d.Repositories, ", "))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	if auditStaleDays == 0 {
+		return
+	}
+	if len(r.Stale) == 0 {
+		fmt.Fprintf(w, "Variables not updated for %d days: none\n\n", auditStaleDays)
+		return
+	}
+	fmt.Fprintf(w, "Variables not updated for %d days (%d):\n", auditStaleDays, len(r.Stale))
+	fmt.Fprintf(w, "%-30s %-30s %-21s %s\n", "SCOPE", "NAME", "UPDATED AT", "AGE (DAYS)")
+	fmt.Fprintf(w, "%-30s %-30s %-21s %s\n", "-----", "----", "----------", "----------")
+	for _, s := range r.Stale {
+		fmt.Fprintf(w, "%-30s %-30s %-21s %d\n", s.Scope, s.Name, s.UpdatedAt, s.AgeDays)
+	}
+	fmt.Fprintln(w)
+}
+
+// yesNo returns "yes" or "no"
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
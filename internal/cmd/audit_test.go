@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// TestAuditVariables tests the audit tables and JSON document from fixture
+// variables covering shadowed, duplicated, and stale variables
+func TestAuditVariables(t *testing.T) {
+	origOrg, origRepos, origAllRepos := auditOrg, auditRepos, auditAllRepos
+	origMinRepos, origStaleDays, origOutput, origShowValues := auditMinRepos, auditStaleDays, outputFormat, auditShowValues
+	defer func() {
+		auditOrg, auditRepos, auditAllRepos = origOrg, origRepos, origAllRepos
+		auditMinRepos, auditStaleDays, outputFormat, auditShowValues = origMinRepos, origStaleDays, origOutput, origShowValues
+	}()
+	auditOrg, auditRepos, auditAllRepos = "acme", nil, true
+	auditMinRepos, auditStaleDays = 2, 365
+
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	c := fakeAPIClient(t, map[string]fakeResponse{
+		"orgs/acme/actions/variables": {http.StatusOK, `{"variables":[
+			{"name":"API_URL","value":"https://api.acme.dev","visibility":"all","updated_at":"2026-02-01T00:00:00Z"},
+			{"name":"OLD_FLAG","value":"1","visibility":"private","updated_at":"2022-03-01T00:00:00Z"}]}`},
+		"orgs/acme/repos": {http.StatusOK, `[{"name":"app"},{"name":"web"},{"name":"attic","archived":true}]`},
+		"repos/acme/app/actions/variables": {http.StatusOK, `{"variables":[
+			{"name":"API_URL","value":"https://api.acme.dev","updated_at":"2026-01-15T00:00:00Z"},
+			{"name":"REGION","value":"eu-west-1","updated_at":"2025-12-01T00:00:00Z"}]}`},
+		"repos/acme/web/actions/variables": {http.StatusOK, `{"variables":[
+			{"name":"REGION","value":"eu-west-1","updated_at":"2023-03-01T00:00:00Z"}]}`},
+	})
+
+	t.Run("table", func(t *testing.T) {
+		outputFormat, auditShowValues = output.Table, false
+		var b strings.Builder
+		if err := auditVariables(c, &b, now); err != nil {
+			t.Fatalf("auditVariables() unexpected error: %v", err)
+		}
+		for _, want := range []string{
+			"Shadowed organization variables (1):\n",
+			"API_URL                        app                            all             yes\n",
+			"Duplicated values (1):\n",
+			"REGION                         no           2 (app, web)\n",
+			"Variables not updated for 365 days (2):\n",
+			"org:acme                       OLD_FLAG                       2022-03-01T00:00:00Z  1461\n",
+			"acme/web                       REGION                         2023-03-01T00:00:00Z  1096\n",
+		} {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("auditVariables() wrote\n%s\nwant it to contain %q", b.String(), want)
+			}
+		}
+		if strings.Contains(b.String(), "attic") {
+			t.Errorf("auditVariables() read the archived repository:\n%s", b.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		outputFormat, auditShowValues = output.JSON, false
+		var b strings.Builder
+		if err := auditVariables(c, &b, now); err != nil {
+			t.Fatalf("auditVariables() unexpected error: %v", err)
+		}
+		var got struct {
+			Repositories int `json:"repositories"`
+			Shadowed     []struct {
+				Name      string `json:"name"`
+				SameValue bool   `json:"same_value"`
+			} `json:"shadowed"`
+			Duplicates []map[string]any `json:"duplicates"`
+			Stale      []struct {
+				Name string `json:"name"`
+			} `json:"stale"`
+		}
+		if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+			t.Fatalf("auditVariables() wrote invalid JSON: %v\n%s", err, b.String())
+		}
+		if got.Repositories != 2 || len(got.Shadowed) != 1 || !got.Shadowed[0].SameValue || len(got.Duplicates) != 1 || len(got.Stale) != 2 {
+			t.Errorf("auditVariables() wrote %s", b.String())
+		}
+		if _, ok := got.Duplicates[0]["value"]; ok {
+			t.Errorf("duplicate value written without --show-values: %v", got.Duplicates[0])
+		}
+	})
+}
+
+func TestValidateAuditFlags(t *testing.T) {
+	origOrg, origRepos, origAllRepos := auditOrg, auditRepos, auditAllRepos
+	origMinRepos, origStaleDays, origOutput, origShowValues := auditMinRepos, auditStaleDays, outputFormat, auditShowValues
+	defer func() {
+		auditOrg, auditRepos, auditAllRepos = origOrg, origRepos, origAllRepos
+		auditMinRepos, auditStaleDays, outputFormat, auditShowValues = origMinRepos, origStaleDays, origOutput, origShowValues
+	}()
+
+	tests := []struct {
+		name       string
+		org        string
+		repos      []string
+		allRepos   bool
+		minRepos   int
+		staleDays  int
+		output     string
+		showValues bool
+		wantErr    string
+	}{
+		{name: "org only", org: "acme"},
+		{name: "repos", org: "acme", repos: []string{"acme/app", "web"}},
+		{name: "missing org", wantErr: "--org flag is required"},
+		{name: "repos and all-repos", org: "acme", repos: []string{"app"}, allRepos: true, wantErr: "cannot be combined"},
+		{name: "repo of another owner", org: "acme", repos: []string{"other/app"}, wantErr: "invalid --repos"},
+		{name: "min-repos", org: "acme", minRepos: 1, wantErr: "invalid --min-repos 1"},
+		{name: "stale-days", org: "acme", staleDays: -1, wantErr: "invalid --stale-days -1"},
+		{name: "show-values without json", org: "acme", showValues: true, wantErr: "--show-values requires --output json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditOrg, auditRepos, auditAllRepos = tt.org, tt.repos, tt.allRepos
+			auditMinRepos, auditStaleDays, outputFormat, auditShowValues = 2, tt.staleDays, output.Table, tt.showValues
+			if tt.minRepos != 0 {
+				auditMinRepos = tt.minRepos
+			}
+			if tt.output != "" {
+				outputFormat = tt.output
+			}
+
+			err := validateAuditFlags(&cobra.Command{Use: "audit"}, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateAuditFlags() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateAuditFlags() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	auditOrg, auditRepos, auditAllRepos, auditStaleDays, auditShowValues = "acme", []string{"acme/app", "APP", "web"}, false, 0, false
+	if err := validateAuditFlags(&cobra.Command{Use: "audit"}, nil); err != nil {
+		t.Fatalf("validateAuditFlags() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(auditRepos, []string{"app", "web"}) {
+		t.Errorf("--repos normalized to %v, want [app web]", auditRepos)
+	}
+}
@@ -1,62 +1,281 @@
 package cmd
 
 import (
-	"github.com/cli/go-gh/v2/pkg/api"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/keyring"
 	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
 	"github.com/spf13/cobra"
 )
 
 // authCmd represents the auth command
 var authCmd = &cobra.Command{
 	Use:   "auth",
-	Short: "Check GitHub CLI authentication status",
-	Long:  `Verify that you are properly authenticated with the GitHub CLI and have access to the required organizations.`,
+	Short: "Check authentication and inspect a token",
+	Long: `Verify that a token authenticates, show what it can do, and check that it has
+access to the required organizations.
+
+The token is --pat, GITHUB_TOKEN, a token stored with auth store for the
+host, or the GitHub CLI login to --hostname, in that order. auth prints the
+user it authenticates as, which of those it is, its OAuth scopes (fine-grained
+PATs and app tokens do not expose any), its expiration date when the host
+reports one, and the core requests it has left.
+
+Given the source and target flags of a migration (--source/--target,
+--source-org/--target-org, --source-repo/--target-repo, their tokens and
+hostnames, or --profile) instead, auth checks both sides together the way
+the migration would: the credential each side uses, that it authenticates,
+that it has the scopes of the mode the flags imply, and that it can access
+its organization, or repository when the mode migrates one. It prints a
+table with a column per side and exits non-zero when either side fails.
+
+The store, remove, and status subcommands manage personal access tokens kept
+in the OS keyring (the macOS keychain, the Secret Service through secret-tool
+on Linux, or the Windows Credential Manager), one per side and host. A
+migration uses the stored token of a side that has no --source-pat /
+--target-pat or GITHUB_TOKEN, before the GitHub CLI login, unless
+--no-keyring is set.`,
 	Example: `  # Check authentication status
   gh vars-migrator auth
 
   # Check access to specific organizations
-  gh vars-migrator auth --check-org renan-org --check-org demo-org-renan`,
-	RunE: runAuthCheck,
+  gh vars-migrator auth --check-org renan-org --check-org demo-org-renan
+
+  # Inspect a token for a GitHub Enterprise Server host
+  gh vars-migrator auth --pat "$GHES_TOKEN" --hostname github.example.com
+
+  # Check the credentials of both sides of an org-to-org migration
+  gh vars-migrator auth --source-org acme --target-org acme-new --org-to-org --target-hostname acme.ghe.com
+
+  # Keep the target token for a GHE.com host in the OS keyring
+  gh vars-migrator auth store --side target --hostname acme.ghe.com`,
+	PreRunE: validateAuthFlags,
+	RunE:    runAuthCheck,
 }
 
-var checkOrgs []string
+// authStoreCmd saves a prompted token in the OS keyring
+var authStoreCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Store a personal access token in the OS keyring",
+	Long: `Ask for the personal access token of --side on --hostname, check that it
+authenticates, and store it in the OS keyring, replacing any token stored
+for them before. The token is read from the terminal without echo, so it
+never appears on the command line or in the shell history.`,
+	Example: `  gh vars-migrator auth store --side source
+  gh vars-migrator auth store --side target --hostname acme.ghe.com`,
+	Args:    cobra.NoArgs,
+	PreRunE: validateAuthSide,
+	RunE:    runAuthStore,
+}
+
+// authRemoveCmd deletes a stored token
+var authRemoveCmd = &cobra.Command{
+	Use:     "remove",
+	Short:   "Remove a personal access token from the OS keyring",
+	Example: `  gh vars-migrator auth remove --side source`,
+	Args:    cobra.NoArgs,
+	PreRunE: validateAuthSide,
+	RunE:    runAuthRemove,
+}
+
+// authStatusCmd lists the stored tokens, masked
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List the personal access tokens stored in the OS keyring",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthStatus,
+}
+
+var (
+	checkOrgs         []string
+	authPAT           string
+	authCheckHostname string
+	authSide          string
+	authHostname      string
+)
 
 func init() {
 	rootCmd.AddCommand(authCmd)
 	authCmd.Flags().StringSliceVar(&checkOrgs, "check-org", []string{}, "Organization(s) to check access for")
+	authCmd.Flags().StringVar(&authPAT, "pat", "", "Personal access token to inspect (default: GITHUB_TOKEN, then a token stored with auth store, then the GitHub CLI authentication)")
+	authCmd.Flags().StringVar(&authCheckHostname, "hostname", "", "GitHub hostname to authenticate against (default: github.com)")
+
+	authCmd.AddCommand(authStoreCmd, authRemoveCmd, authStatusCmd)
+	for _, c := range []*cobra.Command{authStoreCmd, authRemoveCmd} {
+		c.Flags().StringVar(&authSide, "side", "", "Side the token is for: source or target (required)")
+		c.Flags().StringVar(&authHostname, "hostname", "", "GitHub hostname the token is for (default github.com)")
+	}
 }
 
-func runAuthCheck(cmd *cobra.Command, args []string) error {
-	logger.Info("Checking GitHub CLI authentication...")
-	logger.Plain("")
+// openKeyring returns the OS keyring; tests replace it
+var openKeyring = keyring.Default
+
+// keyringLabel names a token stored with auth store in credential messages
+const keyringLabel = "OS keyring"
 
-	// Check basic authentication
-	client, err := api.DefaultRESTClient()
+// keyringHost names hostname in the keyring, where github.com stands for
+// the default host
+func keyringHost(hostname string) string {
+	return hostLabel(normalizeHostname(hostname))
+}
+
+// keyringLookup looks up the stored tokens of a run, warning once when the
+// keyring is not available
+type keyringLookup struct {
+	store  *keyring.Store
+	warned bool
+}
+
+func newKeyringLookup() *keyringLookup {
+	return &keyringLookup{store: keyring.NewStore(openKeyring())}
+}
+
+// token returns the token stored for side on hostname, or "" when there is
+// none or the keyring cannot be read
+func (k *keyringLookup) token(side, hostname string) string {
+	token, err := k.store.Token(side, keyringHost(hostname))
+	switch {
+	case err == nil:
+		return token
+	case errors.Is(err, keyring.ErrNotFound):
+	case !k.warned:
+		logger.Warning("Stored tokens are not used: %v", err)
+		k.warned = true
+	}
+	return ""
+}
+
+// validateAuthSide checks --side of auth store and auth remove
+func validateAuthSide(cmd *cobra.Command, args []string) error {
+	if authSide != "source" && authSide != "target" {
+		return fmt.Errorf("invalid --side %q: must be source or target", authSide)
+	}
+	cmd.SilenceUsage = true
+	return nil
+}
+
+func runAuthStore(cmd *cobra.Command, args []string) error {
+	p := newTokenPrompt()
+	if p == nil {
+		return errors.New("auth store reads the token from a terminal; run it in one")
+	}
+	host := keyringHost(authHostname)
+	token, err := p.ask(authSide, "", normalizeHostname(authHostname))
 	if err != nil {
-		logger.Error("Failed to create GitHub API client: %v", err)
-		logger.Plain("\nTo authenticate, run: gh auth login")
 		return err
 	}
+	if err := keyring.NewStore(openKeyring()).Save(authSide, host, token); err != nil {
+		return fmt.Errorf("failed to store the %s token: %w", authSide, err)
+	}
+	logger.Success("Stored the %s token for %s in the OS keyring", authSide, host)
+	return nil
+}
 
-	var user struct {
-		Login string `json:"login"`
-		Name  string `json:"name"`
-		Email string `json:"email"`
+func runAuthRemove(cmd *cobra.Command, args []string) error {
+	host := keyringHost(authHostname)
+	err := keyring.NewStore(openKeyring()).Remove(authSide, host)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("no %s token for %s is stored in the OS keyring", authSide, host)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove the %s token: %w", authSide, err)
 	}
+	logger.Success("Removed the %s token for %s from the OS keyring", authSide, host)
+	return nil
+}
 
-	if err := client.Get("user", &user); err != nil {
-		logger.Error("Authentication failed: %v", err)
-		logger.Plain("\nTo authenticate, run: gh auth login")
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	entries, err := keyring.NewStore(openKeyring()).Entries()
+	if err != nil {
+		return fmt.Errorf("failed to read the stored tokens: %w", err)
+	}
+	if len(entries) == 0 {
+		logger.Info("No tokens are stored in the OS keyring")
+		return nil
+	}
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "%-8s %-30s %s\n", "SIDE", "HOST", "TOKEN")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%-8s %-30s %s\n", e.Side, e.Host, maskToken(e.Token))
+	}
+	return nil
+}
+
+// authFlags are the flags auth accepts besides its own: the endpoints and
+// credentials of both sides of a migration or a profile holding them, and
+// its mode
+var authFlags = map[string]bool{
+	"check-org": true, "pat": true, "hostname": true,
+	"source": true, "source-org": true, "source-repo": true, "source-pat": true, "source-pat-file": true, "source-hostname": true,
+	"target": true, "target-org": true, "target-repo": true, "target-pat": true, "target-pat-file": true, "target-hostname": true,
+	"no-keyring": true, "org-to-org": true, "org-to-repo": true, "repo-to-org": true, "fan-out": true, "deep": true,
+	"config": true, "profile": true,
+	"verbose": true, "quiet": true, "no-color": true, "no-annotations": true, "env-file": true,
+}
+
+// authSideFlags are the flags that make auth check both sides of a
+// migration instead of inspecting one token
+var authSideFlags = []string{
+	"source", "source-org", "source-repo", "source-pat", "source-pat-file", "source-hostname",
+	"target", "target-org", "target-repo", "target-pat", "target-pat-file", "target-hostname",
+	"profile",
+}
+
+// authBothSides is set when auth checks both sides of a migration
+var authBothSides bool
+
+// validateAuthFlags checks the flags of auth and decides whether it
+// inspects one token or checks both sides of a migration
+func validateAuthFlags(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if err := rejectFlags(cmd, "auth", func(name string) bool { return authFlags[name] }); err != nil {
+		return err
+	}
+	authBothSides = slices.ContainsFunc(authSideFlags, cmd.Flags().Changed)
+	if !authBothSides {
+		return nil
+	}
+	for _, flag := range []string{"pat", "hostname", "check-org"} {
+		if cmd.Flags().Changed(flag) {
+			return fmt.Errorf("--%s inspects a single token and cannot be combined with the source and target flags", flag)
+		}
+	}
+	if err := applyRefs(cmd); err != nil {
 		return err
 	}
+	sourceHostname = normalizeHostname(sourceHostname)
+	targetHostname = normalizeHostname(targetHostname)
+	return nil
+}
 
-	logger.Success("✓ Authenticated successfully")
-	logger.Plain("  User:  %s", user.Login)
-	if user.Name != "" {
-		logger.Plain("  Name:  %s", user.Name)
+func runAuthCheck(cmd *cobra.Command, args []string) error {
+	if authBothSides {
+		return runAuthSides(cmd)
 	}
-	if user.Email != "" {
-		logger.Plain("  Email: %s", user.Email)
+	hostname := normalizeHostname(authCheckHostname)
+	host := hostLabel(hostname)
+	token, label := authCredential(hostname)
+	logger.Info("Checking the authentication of %s on %s...", label, host)
+	logger.Plain("")
+
+	c, err := createClientWithToken(token, hostname, "auth")
+	if err != nil {
+		logger.Error("Failed to create GitHub API client: %v", err)
+		logger.Plain("\nTo authenticate, run: gh auth login --hostname %s", host)
+		return err
+	}
+	if err := inspectToken(c, label, host, time.Now()); err != nil {
+		logger.Plain("\nTo authenticate, run: gh auth login --hostname %s", host)
+		return err
 	}
 
 	// Check organization access if specified
@@ -66,7 +285,7 @@ func runAuthCheck(cmd *cobra.Command, args []string) error {
 
 		allOK := true
 		for _, org := range checkOrgs {
-			if err := CheckOrgAccess(org); err != nil {
+			if _, err := c.GetOrg(org); err != nil {
 				logger.Error("✗ Cannot access organization '%s': %v", org, err)
 				allOK = false
 			} else {
@@ -85,3 +304,193 @@ func runAuthCheck(cmd *cobra.Command, args []string) error {
 	logger.Success("Authentication check passed!")
 	return nil
 }
+
+// runAuthSides checks both sides of the migration the flags describe as the
+// migration would: their tokens authenticate, have the scopes of its mode,
+// and can access their organization or repository
+func runAuthSides(cmd *cobra.Command) error {
+	sourceToken, targetToken, err := resolveTokens()
+	if err != nil {
+		return authError(err)
+	}
+	sourceClient, targetClient, err := createClients(sourceToken, targetToken)
+	if err != nil {
+		return authError(err)
+	}
+	mode := detectMigrationMode()
+	logger.Info("Checking the source and target credentials of a %s migration...", mode)
+	return reportAuthSides(cmd.OutOrStdout(), authSideChecks("source", sourceClient, mode), authSideChecks("target", targetClient, mode))
+}
+
+// authSideChecks runs the checks of side, whose client is c, for mode. The
+// scopes and access are skipped when the token does not authenticate, and
+// the access when the side names no organization.
+func authSideChecks(side string, c *client.Client, mode types.MigrationMode) []preflightCheck {
+	pat, patName, hostname, org, repo := sourcePAT, sourcePATName, sourceHostname, sourceOrg, sourceRepo
+	if side == "target" {
+		pat, patName, hostname, org, repo = targetPAT, targetPATName, targetHostname, targetOrg, targetRepo
+	}
+	if mode == types.ModeOrgToOrg || mode == types.ModeFanOut || (side == "source" && mode == types.ModeOrgToRepo) || (side == "target" && mode == types.ModeRepoToOrg) {
+		repo = ""
+	}
+	host := hostLabel(hostname)
+	checks := []preflightCheck{{name: "Credential", detail: credentialLabel(pat, os.Getenv("GITHUB_TOKEN"), patName, hostname)}}
+
+	user, err := validateSideAuth(side, c)
+	checks = append(checks, preflightCheck{name: "Authentication", detail: "as " + user, err: authErrorOrNil(err)})
+	if err != nil {
+		return append(checks, preflightCheck{name: "Token scopes", skipped: true}, preflightCheck{name: "Access", skipped: true})
+	}
+
+	err = validateSidePermissions(side, c, mode)
+	checks = append(checks, preflightCheck{name: "Token scopes", detail: fmt.Sprintf("allow %s", mode), err: authErrorOrNil(err)})
+
+	access := preflightCheck{name: "Access", skipped: org == ""}
+	if org != "" {
+		e := endpoint{side: side, client: c, host: host}
+		if repo != "" {
+			access.detail = fmt.Sprintf("repository %s/%s", org, repo)
+			access.err = e.checkRepo(org, repo, side == "target")
+		} else {
+			access.detail = "organization " + org
+			access.err = e.checkOrg(org)
+		}
+	}
+	return append(checks, access)
+}
+
+// authErrorOrNil marks err, when set, as an authentication failure
+func authErrorOrNil(err error) error {
+	if err == nil {
+		return nil
+	}
+	return authError(err)
+}
+
+// reportAuthSides writes the checks of both sides to w as a table with a
+// column per side, followed by the full errors, and fails when a check of
+// either side failed
+func reportAuthSides(w io.Writer, source, target []preflightCheck) error {
+	cell := func(c preflightCheck) string {
+		switch {
+		case c.skipped:
+			return "- skipped"
+		case c.err != nil:
+			first, _, _ := strings.Cut(c.err.Error(), "\n")
+			return "✗ " + first
+		case c.name == "Credential":
+			return c.detail
+		}
+		return "✓ " + c.detail
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSOURCE\tTARGET")
+	for i := range source {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", source[i].name, cell(source[i]), cell(target[i]))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var firstErr error
+	var failed []string
+	for _, side := range []struct {
+		name   string
+		checks []preflightCheck
+	}{{"source", source}, {"target", target}} {
+		sideFailed := false
+		for _, c := range side.checks {
+			if c.err == nil {
+				continue
+			}
+			if firstErr == nil {
+				firstErr = c.err
+			}
+			if !sideFailed {
+				failed = append(failed, side.name)
+				sideFailed = true
+			}
+			logger.Plain("")
+			logger.Error("%s %s: %v", strings.ToUpper(side.name[:1])+side.name[1:], strings.ToLower(c.name), c.err)
+		}
+	}
+	logger.Plain("")
+	if firstErr != nil {
+		return &exitError{code: exitCode(firstErr), err: fmt.Errorf("the %s credentials failed the check", strings.Join(failed, " and "))}
+	}
+	logger.Success("The source and target credentials passed every check")
+	return nil
+}
+
+// authCredential returns the token auth inspects on hostname and where it
+// comes from: --pat, GITHUB_TOKEN, a token stored with auth store for either
+// side, or, with an empty token, the GitHub CLI login to the host
+func authCredential(hostname string) (token, label string) {
+	if authPAT != "" {
+		return authPAT, "--pat"
+	}
+	if githubToken := os.Getenv("GITHUB_TOKEN"); githubToken != "" {
+		return githubToken, "GITHUB_TOKEN"
+	}
+	if !noKeyring {
+		k := newKeyringLookup()
+		for _, side := range []string{"source", "target"} {
+			if token := k.token(side, hostname); token != "" {
+				return token, keyringLabel + " (" + side + ")"
+			}
+		}
+	}
+	return "", credentialLabel("", "", "", hostname)
+}
+
+// inspectToken prints who the token of c, named label, authenticates as on
+// host, its scopes and expiration as of now, and its core rate limit
+func inspectToken(c *client.Client, label, host string, now time.Time) error {
+	info, err := c.GetTokenInfo()
+	if err != nil {
+		logger.Error("Authentication failed: %v", err)
+		return err
+	}
+
+	logger.Success("✓ Authenticated successfully")
+	logger.Plain("  User:        %s", info.Login)
+	if info.Name != "" {
+		logger.Plain("  Name:        %s", info.Name)
+	}
+	if info.Email != "" {
+		logger.Plain("  Email:       %s", info.Email)
+	}
+	logger.Plain("  Credential:  %s", label)
+	logger.Plain("  Host:        %s", host)
+
+	scopes := "fine-grained PAT — scopes not exposed"
+	if info.Scopes != nil {
+		scopes = strings.Join(info.Scopes, ", ")
+	}
+	logger.Plain("  Scopes:      %s", scopes)
+
+	expires := "no expiration reported"
+	if !info.Expiration.IsZero() {
+		expires = info.Expiration.UTC().Format("2006-01-02 15:04 MST")
+		if left := info.Expiration.Sub(now); left > 0 {
+			expires += fmt.Sprintf(" (in %d days)", int(left.Hours()/24))
+		} else {
+			expires += " (expired)"
+		}
+	}
+	logger.Plain("  Expires:     %s", expires)
+
+	limits, err := c.GetRateLimits()
+	switch {
+	case client.IsNotFound(err):
+		logger.Plain("  Rate limit:  disabled on %s", host)
+	case err != nil:
+		logger.Plain("  Rate limit:  could not be read: %v", err)
+	default:
+		core := limits["core"]
+		logger.Plain("  Rate limit:  %d of %d core requests left, reset at %s",
+			core.Remaining, core.Limit, core.ResetTime.Local().Format("15:04"))
+	}
+	return nil
+}
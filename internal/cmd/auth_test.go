@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/keyring"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// unavailableKeyring is an OS keyring that cannot be reached
+type unavailableKeyring struct{}
+
+func (unavailableKeyring) Get(service, account string) (string, error) {
+	return "", fmt.Errorf("%w: secret-tool is not installed", keyring.ErrUnavailable)
+}
+func (unavailableKeyring) Set(service, account, secret string) error { return keyring.ErrUnavailable }
+func (unavailableKeyring) Delete(service, account string) error      { return keyring.ErrUnavailable }
+
+// memoryKeyring returns an in-memory keyring holding the tokens of
+// side:host accounts
+func memoryKeyring(t *testing.T, tokens map[string]string) *keyring.Memory {
+	t.Helper()
+	m := keyring.NewMemory()
+	store := keyring.NewStore(m)
+	for account, token := range tokens {
+		side, host, _ := strings.Cut(account, ":")
+		if err := store.Save(side, host, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+// TestResolveTokens_Keyring tests that the tokens stored with auth store are
+// used after the PATs and GITHUB_TOKEN, per side and host
+func TestResolveTokens_Keyring(t *testing.T) {
+	origSourcePAT, origTargetPAT := sourcePAT, targetPAT
+	origSourceHostname, origTargetHostname := sourceHostname, targetHostname
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origPrompt, origKeyring, origNoKeyring := newTokenPrompt, openKeyring, noKeyring
+	defer func() {
+		sourcePAT, targetPAT = origSourcePAT, origTargetPAT
+		sourceHostname, targetHostname = origSourceHostname, origTargetHostname
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		newTokenPrompt, openKeyring, noKeyring = origPrompt, origKeyring, origNoKeyring
+	}()
+	newTokenPrompt = func() *tokenPrompt { return nil }
+
+	tests := []struct {
+		name           string
+		githubToken    string
+		sourcePAT      string
+		targetHostname string
+		stored         map[string]string
+		unavailable    bool
+		noKeyring      bool
+		wantSource     string
+		wantTarget     string
+		wantLog        string
+		wantErr        string
+	}{
+		{
+			name:       "both sides stored",
+			stored:     map[string]string{"source:github.com": "ghp_source", "target:github.com": "ghp_target"},
+			wantSource: "ghp_source", wantTarget: "ghp_target",
+			wantLog: "OS keyring used for Target Org corp",
+		},
+		{
+			name:       "PAT wins over the stored token",
+			sourcePAT:  "source-pat",
+			stored:     map[string]string{"source:github.com": "ghp_source", "target:github.com": "ghp_target"},
+			wantSource: "source-pat", wantTarget: "ghp_target",
+			wantLog: "SOURCE_PAT used for Source Org acme",
+		},
+		{
+			name:        "GITHUB_TOKEN wins over the stored tokens",
+			githubToken: "env-token",
+			stored:      map[string]string{"source:github.com": "ghp_source", "target:github.com": "ghp_target"},
+			wantSource:  "env-token", wantTarget: "env-token",
+		},
+		{
+			name:       "one side stored, the other logged in with the GitHub CLI",
+			stored:     map[string]string{"target:github.com": "ghp_target"},
+			wantTarget: "ghp_target",
+			wantLog:    "GitHub CLI (github.com) used for Source Org acme",
+		},
+		{
+			name:           "tokens are stored per host",
+			targetHostname: "ghes.corp.example",
+			stored:         map[string]string{"source:github.com": "ghp_source", "target:github.com": "ghp_target", "target:ghes.corp.example": "ghp_ghes"},
+			wantSource:     "ghp_source", wantTarget: "ghp_ghes",
+		},
+		{
+			name:      "--no-keyring",
+			noKeyring: true,
+			stored:    map[string]string{"source:github.com": "ghp_source", "target:github.com": "ghp_target"},
+			wantLog:   "GitHub CLI (github.com) used for Target Org corp",
+		},
+		{
+			name:        "unavailable keyring",
+			unavailable: true,
+			wantLog:     "Stored tokens are not used: the OS keyring is not available: secret-tool is not installed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_TOKEN", tt.githubToken)
+			sourcePAT, targetPAT = tt.sourcePAT, ""
+			sourceHostname, targetHostname = "", tt.targetHostname
+			sourceOrg, targetOrg = "acme", "corp"
+			noKeyring = tt.noKeyring
+			ring := keyring.Keyring(memoryKeyring(t, tt.stored))
+			if tt.unavailable {
+				ring = unavailableKeyring{}
+			}
+			openKeyring = func() keyring.Keyring { return ring }
+
+			var sourceToken, targetToken string
+			var err error
+			stdout, stderr := captureStdio(t, func() { sourceToken, targetToken, err = resolveTokens() })
+			if err != nil {
+				t.Fatalf("resolveTokens() unexpected error: %v", err)
+			}
+			if sourceToken != tt.wantSource || targetToken != tt.wantTarget {
+				t.Errorf("resolveTokens() = %q, %q; want %q, %q", sourceToken, targetToken, tt.wantSource, tt.wantTarget)
+			}
+			if !strings.Contains(stdout+stderr, tt.wantLog) {
+				t.Errorf("Expected %q in the output, got:\n%s%s", tt.wantLog, stdout, stderr)
+			}
+			if strings.Count(stdout+stderr, "Stored tokens are not used") > 1 {
+				t.Errorf("The unavailable keyring was warned about more than once:\n%s%s", stdout, stderr)
+			}
+			if strings.Contains(stdout+stderr, "ghp_") {
+				t.Errorf("A stored token was logged:\n%s%s", stdout, stderr)
+			}
+		})
+	}
+}
+
+// TestAuthKeyringCommands tests auth store, status, and remove against an
+// in-memory keyring
+func TestAuthKeyringCommands(t *testing.T) {
+	origSide, origHostname := authSide, authHostname
+	origPrompt, origKeyring := newTokenPrompt, openKeyring
+	defer func() {
+		authSide, authHostname = origSide, origHostname
+		newTokenPrompt, openKeyring = origPrompt, origKeyring
+	}()
+	ring := keyring.NewMemory()
+	openKeyring = func() keyring.Keyring { return ring }
+	store := keyring.NewStore(ring)
+
+	// store needs a terminal to read the token from
+	newTokenPrompt = func() *tokenPrompt { return nil }
+	authSide, authHostname = "source", ""
+	if err := runAuthStore(authStoreCmd, nil); err == nil || !strings.Contains(err.Error(), "terminal") {
+		t.Fatalf("auth store without a terminal: err = %v", err)
+	}
+
+	var prompt strings.Builder
+	inputs := []string{"ghp_wrong", "ghp_good", "ghp_good"}
+	newTokenPrompt = func() *tokenPrompt {
+		p := fakeTokenPrompt(t, &prompt, nil, inputs[0])
+		inputs = inputs[1:]
+		return p
+	}
+	var err error
+	captureStdio(t, func() { err = runAuthStore(authStoreCmd, nil) })
+	if err == nil || !strings.Contains(err.Error(), "does not authenticate") {
+		t.Fatalf("auth store of an invalid token: err = %v", err)
+	}
+	if _, err := store.Token("source", "github.com"); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("An invalid token was stored: err = %v", err)
+	}
+
+	captureStdio(t, func() { err = runAuthStore(authStoreCmd, nil) })
+	if err != nil {
+		t.Fatalf("auth store: %v", err)
+	}
+	authSide, authHostname = "target", "https://GHES.corp.example/"
+	captureStdio(t, func() { err = runAuthStore(authStoreCmd, nil) })
+	if err != nil {
+		t.Fatalf("auth store --hostname: %v", err)
+	}
+	if got, err := store.Token("target", "ghes.corp.example"); err != nil || got != "ghp_good" {
+		t.Errorf("Stored target token = %q, %v", got, err)
+	}
+	if prompt.String() != "Enter PAT for source: \nEnter PAT for source: \nEnter PAT for target: \n" {
+		t.Errorf("Prompts = %q", prompt.String())
+	}
+
+	var out strings.Builder
+	authStatusCmd.SetOut(&out)
+	defer authStatusCmd.SetOut(nil)
+	if err := runAuthStatus(authStatusCmd, nil); err != nil {
+		t.Fatalf("auth status: %v", err)
+	}
+	want := "SIDE     HOST                           TOKEN\n" +
+		"target   ghes.corp.example              ghp_**** (8 chars)\n" +
+		"source   github.com                     ghp_**** (8 chars)\n"
+	if out.String() != want {
+		t.Errorf("auth status =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	authSide, authHostname = "source", ""
+	captureStdio(t, func() { err = runAuthRemove(authRemoveCmd, nil) })
+	if err != nil {
+		t.Fatalf("auth remove: %v", err)
+	}
+	if err := runAuthRemove(authRemoveCmd, nil); err == nil || err.Error() != "no source token for github.com is stored in the OS keyring" {
+		t.Errorf("second auth remove: err = %v", err)
+	}
+	if entries, _ := store.Entries(); len(entries) != 1 || entries[0].Side != "target" {
+		t.Errorf("Entries after remove = %v", entries)
+	}
+
+	openKeyring = func() keyring.Keyring { return unavailableKeyring{} }
+	if err := runAuthStatus(authStatusCmd, nil); !errors.Is(err, keyring.ErrUnavailable) {
+		t.Errorf("auth status with an unavailable keyring: err = %v", err)
+	}
+}
+
+// TestAuthCredential tests where auth takes the token it inspects from
+func TestAuthCredential(t *testing.T) {
+	origPAT, origKeyring, origNoKeyring := authPAT, openKeyring, noKeyring
+	defer func() { authPAT, openKeyring, noKeyring = origPAT, origKeyring, origNoKeyring }()
+	stored := map[string]string{"target:github.com": "ghp_stored", "source:ghes.corp.example": "ghp_ghes"}
+
+	tests := []struct {
+		name        string
+		pat         string
+		githubToken string
+		hostname    string
+		noKeyring   bool
+		wantToken   string
+		wantLabel   string
+	}{
+		{name: "flag", pat: "ghp_flag", githubToken: "ghp_env", wantToken: "ghp_flag", wantLabel: "--pat"},
+		{name: "env", githubToken: "ghp_env", wantToken: "ghp_env", wantLabel: "GITHUB_TOKEN"},
+		{name: "keyring", wantToken: "ghp_stored", wantLabel: "OS keyring (target)"},
+		{name: "keyring of a custom host", hostname: "ghes.corp.example", wantToken: "ghp_ghes", wantLabel: "OS keyring (source)"},
+		{name: "GitHub CLI", noKeyring: true, wantLabel: "GitHub CLI (github.com)"},
+		{name: "GitHub CLI of a custom host", hostname: "other.example", wantLabel: "GitHub CLI (other.example)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_TOKEN", tt.githubToken)
+			authPAT, noKeyring = tt.pat, tt.noKeyring
+			ring := memoryKeyring(t, stored)
+			openKeyring = func() keyring.Keyring { return ring }
+
+			token, label := authCredential(tt.hostname)
+			if token != tt.wantToken || label != tt.wantLabel {
+				t.Errorf("authCredential(%q) = %q, %q; want %q, %q", tt.hostname, token, label, tt.wantToken, tt.wantLabel)
+			}
+		})
+	}
+}
+
+// TestInspectToken tests what auth prints about classic, fine-grained, and
+// expired tokens, and about hosts without rate limiting
+func TestInspectToken(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	user := fakeResponse{http.StatusOK, `{"login":"octocat","name":"Mona"}`}
+	rateLimit := fakeResponse{http.StatusOK, `{"resources":{"core":{"limit":5000,"remaining":4990,"reset":1792152000}}}`}
+
+	tests := []struct {
+		name      string
+		header    map[string]string
+		responses map[string]fakeResponse
+		label     string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name: "classic PAT with an expiration",
+			header: map[string]string{
+				"X-OAuth-Scopes":                         "repo, admin:org",
+				"GitHub-Authentication-Token-Expiration": "2026-11-01 12:30:00 UTC",
+			},
+			responses: map[string]fakeResponse{"user": user, "rate_limit": rateLimit},
+			label:     "--pat",
+			want: []string{
+				"User:        octocat", "Name:        Mona", "Credential:  --pat", "Host:        github.com",
+				"Scopes:      repo, admin:org", "Expires:     2026-11-01 12:30 UTC (in 16 days)",
+				"Rate limit:  4990 of 5000 core requests left, reset at",
+			},
+		},
+		{
+			name:      "fine-grained PAT from the keyring",
+			responses: map[string]fakeResponse{"user": user, "rate_limit": rateLimit},
+			label:     "OS keyring (source)",
+			want: []string{
+				"Credential:  OS keyring (source)", "Scopes:      fine-grained PAT — scopes not exposed",
+				"Expires:     no expiration reported",
+			},
+		},
+		{
+			name:      "expired token on a host without rate limiting",
+			header:    map[string]string{"GitHub-Authentication-Token-Expiration": "2026-10-01 00:00:00 UTC"},
+			responses: map[string]fakeResponse{"user": user},
+			label:     "GitHub CLI (github.com)",
+			want: []string{
+				"Credential:  GitHub CLI (github.com)", "Expires:     2026-10-01 00:00 UTC (expired)",
+				"Rate limit:  disabled on github.com",
+			},
+		},
+		{
+			name:      "bad credentials",
+			responses: map[string]fakeResponse{"user": {http.StatusUnauthorized, `{"message":"Bad credentials"}`}},
+			label:     "GITHUB_TOKEN",
+			want:      []string{"Authentication failed"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				resp, ok := tt.responses[strings.TrimPrefix(req.URL.Path, "/")]
+				if !ok {
+					resp = fakeResponse{http.StatusNotFound, `{"message":"Not Found"}`}
+				}
+				header := http.Header{"Content-Type": []string{"application/json"}}
+				for k, v := range tt.header {
+					header.Set(k, v)
+				}
+				return &http.Response{StatusCode: resp.status, Header: header, Body: io.NopCloser(strings.NewReader(resp.body)), Request: req}, nil
+			})
+			c, err := client.NewWithTransport("test-token", "github.com", fake)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stdout, stderr := captureStdio(t, func() { err = inspectToken(c, tt.label, "github.com", now) })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("inspectToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(stdout+stderr, want) {
+					t.Errorf("Expected %q in the output, got:\n%s%s", want, stdout, stderr)
+				}
+			}
+		})
+	}
+}
+
+// TestAuthSides checks both sides of an org-to-org migration whose source
+// token authenticates but lacks admin:org
+func TestAuthSides(t *testing.T) {
+	origSourceOrg, origTargetOrg, origSourceRepo, origTargetRepo := sourceOrg, targetOrg, sourceRepo, targetRepo
+	origSourcePAT, origTargetPAT, origSourcePATName, origTargetPATName := sourcePAT, targetPAT, sourcePATName, targetPATName
+	origDeep := deep
+	defer func() {
+		sourceOrg, targetOrg, sourceRepo, targetRepo = origSourceOrg, origTargetOrg, origSourceRepo, origTargetRepo
+		sourcePAT, targetPAT, sourcePATName, targetPATName = origSourcePAT, origTargetPAT, origSourcePATName, origTargetPATName
+		deep = origDeep
+	}()
+	sourceOrg, targetOrg, sourceRepo, targetRepo = "acme", "other", "", ""
+	sourcePAT, targetPAT, sourcePATName, targetPATName = "source-token", "target-token", "SOURCE_PAT", "TARGET_PAT"
+	deep = false
+
+	source := preflightClient(t, map[string]fakeResponse{
+		"user":      {http.StatusOK, `{"login":"alice"}`},
+		"orgs/acme": {http.StatusOK, `{"login":"acme"}`},
+	}, "repo")
+	target := preflightClient(t, map[string]fakeResponse{
+		"user":       {http.StatusOK, `{"login":"bob"}`},
+		"orgs/other": {http.StatusOK, `{"login":"other"}`},
+	}, "admin:org, repo")
+
+	var err error
+	stdout, stderr := captureStdio(t, func() {
+		err = reportAuthSides(os.Stdout, authSideChecks("source", source, types.ModeOrgToOrg), authSideChecks("target", target, types.ModeOrgToOrg))
+	})
+	if code := exitCode(err); code != exitCodeAuth {
+		t.Errorf("exitCode() = %d, want %d (err: %v)", code, exitCodeAuth, err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "the source credentials failed") {
+		t.Errorf("Expected the source to fail, got %v", err)
+	}
+	for _, want := range []string{
+		"CHECK           SOURCE",
+		"Credential      SOURCE_PAT",
+		"Authentication  ✓ as alice",
+		"✓ as bob",
+		`Token scopes    ✗ source token is missing required scope "admin:org"`,
+		"✓ allow org-to-org",
+		"Access          ✓ organization acme",
+		"✓ organization other",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the table, got:\n%s", want, stdout)
+		}
+	}
+	if !strings.Contains(stderr, "Source token scopes") {
+		t.Errorf("Expected the source error to be listed, got:\n%s", stderr)
+	}
+}
+
+// TestValidateAuthSide tests the --side check of auth store and remove
+func TestValidateAuthSide(t *testing.T) {
+	origSide := authSide
+	defer func() { authSide = origSide }()
+	for side, ok := range map[string]bool{"source": true, "target": true, "": false, "both": false} {
+		authSide = side
+		if err := validateAuthSide(&cobra.Command{Use: "x"}, nil); (err == nil) != ok {
+			t.Errorf("validateAuthSide(%q) error = %v", side, err)
+		}
+	}
+}
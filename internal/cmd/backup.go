@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/dump"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/spf13/cobra"
+)
+
+// backupCmd writes the variables of an organization, and with --deep those
+// of its repositories, to a JSON file that restore reads back
+var backupCmd = &cobra.Command{
+	Use:   "backup --org ORG --output FILE [--deep]",
+	Short: "Back up the variables of an organization to a JSON file",
+	Long: `Back up the Actions variables of an organization to a JSON file, with their
+values, visibility, and, for variables visible to selected repositories, the
+names of those repositories.
+
+--deep adds the variables of every repository of the organization and of
+their environments. Archived repositories are left out.
+
+The file is written in the versioned JSON format of export --include-values,
+with owner-only permissions since it holds values. restore writes it back,
+and import reads it as well.
+
+The token is taken from --pat, then GITHUB_TOKEN, then the GitHub CLI
+authentication. The --output flag of backup names the file to write, in
+place of the global --output format.`,
+	Example: `  # Back up the organization variables
+  gh vars-migrator backup --org myorg --output myorg.json
+
+  # Back up every repository and environment as well
+  gh vars-migrator backup --org myorg --deep --output myorg-full.json`,
+	PreRunE:       validateBackupFlags,
+	RunE:          runBackup,
+	SilenceErrors: true,
+}
+
+var (
+	backupOrg      string
+	backupOutput   string
+	backupDeep     bool
+	backupPAT      string
+	backupHostname string
+)
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.Flags().StringVarP(&backupOrg, "org", "o", "", "Organization to back up (required)")
+	backupCmd.Flags().StringVar(&backupOutput, "output", "", "File to write (required)")
+	backupCmd.Flags().BoolVar(&backupDeep, "deep", false, "Also back up the variables of every repository and its environments")
+	backupCmd.Flags().StringVar(&backupPAT, "pat", "", "Personal access token (default: GITHUB_TOKEN, then the GitHub CLI authentication)")
+	backupCmd.Flags().StringVar(&backupHostname, "hostname", "", "GitHub hostname for GitHub Enterprise Server (default: github.com)")
+}
+
+// validateBackupFlags checks the required backup flags
+func validateBackupFlags(cmd *cobra.Command, args []string) error {
+	switch {
+	case backupOrg == "":
+		return fmt.Errorf("--org flag is required")
+	case backupOutput == "":
+		return fmt.Errorf("--output flag is required")
+	}
+	cmd.SilenceUsage = true
+	backupHostname = normalizeHostname(backupHostname)
+	return nil
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	c, err := patClient(backupPAT, backupHostname, "backup")
+	if err != nil {
+		return authError(err)
+	}
+
+	d, err := migrator.BackupDump(c, backupOrg, backupDeep)
+	if err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	if err := dump.Save(backupOutput, d, dump.FormatJSON); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	if backupDeep {
+		logger.Success("Backed up %d variable(s) of %s and %d repository(ies) to %s", d.Count(), backupOrg, len(d.Repositories), backupOutput)
+	} else {
+		logger.Success("Backed up %d variable(s) of %s to %s", d.Count(), backupOrg, backupOutput)
+	}
+	return nil
+}
@@ -0,0 +1,427 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/batch"
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/report"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// batchCmd runs the migrations listed in a batch file
+var batchCmd = &cobra.Command{
+	Use:   "batch --file FILE",
+	Short: "Run the migrations listed in a YAML file",
+	Long: `Run every migration listed in a YAML batch file, one after the other or
+--parallel N at a time, then print a summary table with one row per entry:
+
+  version: 1
+  migrations:
+    - name: app
+      mode: repo-to-repo
+      source: acme/app
+      target: acme-new/app
+      skip_envs: true
+    - mode: org-to-org
+      source: acme
+      target: acme-new
+      on_conflict: skip
+
+mode is repo-to-repo, org-to-org, org-to-repo, or repo-to-org; source and
+target are an organization or an OWNER/REPO repository, as the mode requires.
+An entry may also set envs, exclude_envs, vars, include, and exclude (lists),
+on_conflict, always_write, visibility (org-to-org), and target_visibility
+(repo-to-org). The whole file is checked before anything runs, and every
+invalid entry is reported with its number and line.
+
+All entries share the credentials and hosts of the source and target, and
+the --dry-run, --on-conflict, --skip-overwrite, --always-write,
+--show-values, --skip-limit-checks, and --strict-names options; an entry's
+on_conflict replaces --on-conflict. An entry that fails does not stop the
+others unless --fail-fast is set, which also stops each migration at its
+first error. Messages of entries run in parallel are interleaved.
+
+--report-file writes one JSON report holding the report of every entry. The
+command exits 3 when any entry failed.`,
+	Example: `  # Preview a migration wave
+  gh vars-migrator batch --file wave-1.yaml --dry-run
+
+  # Run it four migrations at a time with a combined report
+  gh vars-migrator batch --file wave-1.yaml --parallel 4 --report-file wave-1.json`,
+	PreRunE:       validateBatchFlags,
+	RunE:          runBatch,
+	SilenceErrors: true,
+}
+
+var (
+	batchFilePath string
+	batchParallel int
+)
+
+// batchPlan is the batch file loaded during validation
+var batchPlan *batch.File
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	supportOutput(batchCmd, output.JSON, output.CSV)
+	batchCmd.Flags().StringVar(&batchFilePath, "file", "", "YAML file listing the migrations (required)")
+	batchCmd.Flags().IntVar(&batchParallel, "parallel", 1, "Number of migrations run at the same time")
+	// The migration flags are added by the root command once it has
+	// registered them.
+}
+
+// batchFlags are the flags batch accepts besides its own: the credentials
+// and hosts of both sides, and the options shared by every entry
+var batchFlags = map[string]bool{
+	"file": true, "parallel": true,
+	"verbose": true, "quiet": true, "no-color": true, "no-annotations": true, "env-file": true, "output": true,
+	"source-pat": true, "target-pat": true, "source-pat-file": true, "target-pat-file": true, "source-hostname": true, "target-hostname": true, "no-keyring": true,
+	"config": true, "profile": true,
+	"dry-run": true, "on-conflict": true, "skip-overwrite": true, "always-write": true,
+	"show-values": true, "skip-limit-checks": true, "strict-names": true, "fail-fast": true,
+	"report-file": true, "report-include-values": true,
+}
+
+// validateBatchFlags checks the flags and loads the batch file
+func validateBatchFlags(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if err := rejectFlags(cmd, "batch", func(name string) bool { return batchFlags[name] }); err != nil {
+		return err
+	}
+	if batchFilePath == "" {
+		return fmt.Errorf("--file flag is required")
+	}
+	if batchParallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+	if types.ConflictStrategy(onConflict) == types.ConflictPrompt {
+		return fmt.Errorf("--on-conflict=prompt cannot be combined with batch")
+	}
+	if err := config.ValidateConflictStrategy(types.ConflictStrategy(onConflict), skipOverwrite); err != nil {
+		return err
+	}
+
+	f, err := batch.Load(batchFilePath)
+	if err != nil {
+		return err
+	}
+	batchPlan = f
+	sourceHostname = normalizeHostname(sourceHostname)
+	targetHostname = normalizeHostname(targetHostname)
+	return nil
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	sourceToken, targetToken, err := resolveTokens()
+	if err != nil {
+		return authError(err)
+	}
+	sourceClient, targetClient, err := createClients(sourceToken, targetToken)
+	if err != nil {
+		return authError(err)
+	}
+	if err := validateAuth(sourceClient, targetClient); err != nil {
+		return authError(err)
+	}
+
+	r := &batchRun{
+		entries:  batchPlan.Migrations,
+		base:     batchBaseConfig(),
+		parallel: batchParallel,
+		// Every entry gets its own clients, so that its report counts its
+		// own API requests
+		clients: func() (*client.Client, *client.Client, error) {
+			return createClients(sourceToken, targetToken)
+		},
+	}
+	started := time.Now()
+	stop := r.stopOnInterrupt()
+	results := r.run()
+	stop()
+
+	rep := batch.NewReport(batchFilePath, results, started, time.Now())
+	if err := writeBatchSummary(cmd.OutOrStdout(), rep); err != nil {
+		return err
+	}
+	if reportFile != "" {
+		if err := batch.SaveReport(reportFile, rep); err != nil {
+			logger.Error("Failed to save report: %v", err)
+			return fmt.Errorf("report failed: %w", err)
+		}
+		logger.Success("Saved batch report to %s", reportFile)
+	}
+	return r.exitError(results)
+}
+
+// batchBaseConfig returns the options every entry of the batch shares
+func batchBaseConfig() types.MigrationConfig {
+	return types.MigrationConfig{
+		DryRun:          dryRun,
+		SkipOverwrite:   skipOverwrite,
+		OnConflict:      types.ConflictStrategy(onConflict),
+		AlwaysWrite:     alwaysWrite,
+		ShowValues:      showValues,
+		FailFast:        failFast,
+		SkipLimitChecks: skipLimitChecks,
+		StrictNames:     strictNames,
+	}
+}
+
+// batchRun runs the entries of a batch file
+type batchRun struct {
+	entries  []batch.Entry
+	base     types.MigrationConfig
+	parallel int
+	// clients creates the source and target clients of one entry
+	clients func() (*client.Client, *client.Client, error)
+
+	mu sync.Mutex
+	// running holds the migrators of the entries in progress, by index
+	running     map[int]*migrator.Migrator
+	failed      bool
+	interrupted bool
+}
+
+// run runs the entries with up to parallel of them at a time and returns
+// their outcomes in file order. No entry is started after an interrupt, or
+// after a failure with --fail-fast; those entries are left not run.
+func (r *batchRun) run() []batch.EntryReport {
+	results := make([]batch.EntryReport, len(r.entries))
+	for i, e := range r.entries {
+		results[i] = batch.EntryReport{Index: i + 1, Name: e.Name, Mode: e.Mode, Source: e.Source, Target: e.Target, Status: batch.StatusNotRun}
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(r.parallel, len(r.entries)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if r.stopped() {
+					continue
+				}
+				r.runEntry(i, &results[i])
+			}
+		}()
+	}
+	for i := range r.entries {
+		if r.stopped() {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// runEntry runs the migration of entry i and records its outcome in res
+func (r *batchRun) runEntry(i int, res *batch.EntryReport) {
+	e := r.entries[i]
+	cfg := e.Config(r.base)
+	logger.Info("Batch entry %d of %d: %s (%s)", i+1, len(r.entries), e.Label(), e.Mode)
+
+	started := time.Now()
+	fail := func(err error) {
+		res.Status = batch.StatusFailed
+		res.Error = err.Error()
+		logger.Error("Batch entry %d (%s) failed: %v", i+1, e.Label(), err)
+		r.mu.Lock()
+		r.failed = true
+		r.mu.Unlock()
+	}
+
+	sourceClient, targetClient, err := r.clients()
+	if err != nil {
+		fail(err)
+		return
+	}
+	if err := validatePermissions(sourceClient, targetClient, cfg.Mode); err != nil {
+		fail(err)
+		return
+	}
+	if err := checkEndpoints(sourceClient, targetClient, cfg); err != nil {
+		fail(err)
+		return
+	}
+	m, err := migrator.New(cfg, sourceClient, targetClient)
+	if err != nil {
+		fail(fmt.Errorf("failed to initialize migrator: %w", err))
+		return
+	}
+	if !r.track(i, m) {
+		return
+	}
+	result, err := m.Run()
+	r.untrack(i)
+
+	res.Report = report.New(cfg, started, reportIncludeValues)
+	res.Report.Finish(result, err, time.Now())
+	if err != nil {
+		fail(fmt.Errorf("migration failed: %w", err))
+		return
+	}
+	if err := migrationExitError(result); err != nil {
+		fail(err)
+		return
+	}
+	res.Status = batch.StatusSucceeded
+}
+
+// stopped reports whether no further entry may start
+func (r *batchRun) stopped() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.interrupted || (r.base.FailFast && r.failed)
+}
+
+// track records the migrator of entry i as running, unless the batch was
+// interrupted while the entry was being prepared
+func (r *batchRun) track(i int, m *migrator.Migrator) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.interrupted {
+		return false
+	}
+	if r.running == nil {
+		r.running = map[int]*migrator.Migrator{}
+	}
+	r.running[i] = m
+	return true
+}
+
+// untrack forgets the migrator of entry i once it has finished
+func (r *batchRun) untrack(i int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, i)
+}
+
+// interrupt stops the running migrations before their next variable and
+// keeps the remaining entries from starting
+func (r *batchRun) interrupt() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interrupted = true
+	for _, m := range r.running {
+		m.Interrupt()
+	}
+}
+
+// stopOnInterrupt interrupts the batch when the process is interrupted or
+// terminated, so that the running migrations print their partial summary
+// and the combined report is written. A second signal exits right away. The
+// returned function stops watching for signals.
+func (r *batchRun) stopOnInterrupt() func() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		var sig os.Signal
+		select {
+		case sig = <-signals:
+		case <-done:
+			return
+		}
+		logger.Warning("Received %v; stopping the running migrations after the variable in progress (press Ctrl+C again to exit now)", sig)
+		r.interrupt()
+
+		select {
+		case <-signals:
+			os.Exit(exitCodeInterrupted)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// exitError maps the outcomes of the entries to the command's exit
+// behavior: exitCodeAborted when the batch was interrupted, exitCodePartial
+// when any entry failed
+func (r *batchRun) exitError(results []batch.EntryReport) error {
+	var failed, notRun int
+	for _, res := range results {
+		switch res.Status {
+		case batch.StatusFailed:
+			failed++
+		case batch.StatusNotRun:
+			notRun++
+		}
+	}
+
+	r.mu.Lock()
+	interrupted := r.interrupted
+	r.mu.Unlock()
+	if interrupted {
+		return &exitError{code: exitCodeAborted, err: fmt.Errorf("batch interrupted; %d of %d entries not run", notRun, len(results))}
+	}
+	if failed > 0 {
+		err := fmt.Errorf("%d of %d batch entries failed", failed, len(results))
+		if notRun > 0 {
+			err = fmt.Errorf("%d of %d batch entries failed; %d not run after --fail-fast", failed, len(results), notRun)
+		}
+		return &exitError{code: exitCodePartial, err: err}
+	}
+	logger.Success("Batch completed: %d migration(s) succeeded", len(results))
+	return nil
+}
+
+// writeBatchSummary writes one row per entry with its status and counts,
+// followed by the error of every failed entry, or with --output json the
+// combined report and with --output csv one row per entry
+func writeBatchSummary(w io.Writer, rep *batch.Report) error {
+	switch outputFormat {
+	case output.JSON:
+		return output.WriteJSON(w, rep)
+	case output.CSV:
+		rows := make([][]string, 0, len(rep.Entries))
+		for _, res := range rep.Entries {
+			row := []string{strconv.Itoa(res.Index), res.Name, string(res.Mode), res.Source, res.Target, string(res.Status)}
+			if res.Report == nil {
+				row = append(row, "", "", "", "", "")
+			} else {
+				s := res.Report.Summary
+				row = append(row, strconv.Itoa(s.Created), strconv.Itoa(s.Updated), strconv.Itoa(s.Unchanged), strconv.Itoa(s.Skipped), strconv.Itoa(s.Errors))
+			}
+			rows = append(rows, append(row, res.Error))
+		}
+		return output.WriteCSV(w, []string{"entry", "name", "mode", "source", "target", "status", "created", "updated", "unchanged", "skipped", "errors", "error"}, rows)
+	}
+
+	fmt.Fprintf(w, "\n%-5s %-40s %-13s %-10s %7s %7s %9s %7s %6s\n", "ENTRY", "NAME", "MODE", "STATUS", "CREATED", "UPDATED", "UNCHANGED", "SKIPPED", "ERRORS")
+	fmt.Fprintf(w, "%-5s %-40s %-13s %-10s %7s %7s %9s %7s %6s\n", "-----", "----", "----", "------", "-------", "-------", "---------", "-------", "------")
+	for _, res := range rep.Entries {
+		name := res.Label()
+		if res.Report == nil {
+			fmt.Fprintf(w, "%-5d %-40s %-13s %-10s %7s %7s %9s %7s %6s\n", res.Index, name, res.Mode, res.Status, "-", "-", "-", "-", "-")
+			continue
+		}
+		s := res.Report.Summary
+		fmt.Fprintf(w, "%-5d %-40s %-13s %-10s %7d %7d %9d %7d %6d\n", res.Index, name, res.Mode, res.Status, s.Created, s.Updated, s.Unchanged, s.Skipped, s.Errors)
+	}
+	for _, res := range rep.Entries {
+		if res.Error != "" {
+			fmt.Fprintf(w, "\nEntry %d failed: %s\n", res.Index, res.Error)
+		}
+	}
+	return nil
+}
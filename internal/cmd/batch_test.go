@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/batch"
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// TestBatchRun runs a batch whose second entry fails, sequentially, in
+// parallel, and with --fail-fast
+func TestBatchRun(t *testing.T) {
+	f, err := batch.Parse([]byte(`version: 1
+migrations:
+  - name: org
+    mode: org-to-org
+    source: acme
+    target: acme-new
+  - mode: repo-to-repo
+    source: acme/missing
+    target: acme-new/app
+  - name: app
+    mode: repo-to-repo
+    source: acme/app
+    target: acme-new/app
+    skip_envs: true
+`))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	repo := fakeResponse{http.StatusOK, `{"name":"app","permissions":{"admin":false,"push":true,"pull":true}}`}
+	responses := map[string]fakeResponse{
+		"user":                                   {http.StatusOK, `{"login":"alice"}`},
+		"orgs/acme":                              {http.StatusOK, `{"login":"acme"}`},
+		"orgs/acme-new":                          {http.StatusOK, `{"login":"acme-new"}`},
+		"orgs/acme/actions/variables":            {http.StatusOK, `{"total_count":2,"variables":[{"name":"REGION","value":"eu","visibility":"all"},{"name":"TIER","value":"gold","visibility":"private"}]}`},
+		"orgs/acme-new/actions/variables":        {http.StatusOK, `{"total_count":1,"variables":[{"name":"REGION","value":"eu","visibility":"all"}]}`},
+		"orgs/acme-new/actions/variables/REGION": {http.StatusOK, `{"name":"REGION","value":"eu","visibility":"all"}`},
+		"repos/acme/app":                         repo,
+		"repos/acme-new/app":                     repo,
+		"repos/acme/app/actions/variables":       {http.StatusOK, `{"total_count":1,"variables":[{"name":"API_URL","value":"https://api"}]}`},
+		"repos/acme-new/app/actions/variables":   {http.StatusOK, `{"total_count":0,"variables":[]}`},
+		"repos/acme-new/app/environments":        {http.StatusOK, `{"total_count":0,"environments":[]}`},
+	}
+
+	tests := []struct {
+		name     string
+		parallel int
+		failFast bool
+		want     []batch.Status
+		wantErr  string
+	}{
+		{"sequential", 1, false, []batch.Status{batch.StatusSucceeded, batch.StatusFailed, batch.StatusSucceeded}, "1 of 3 batch entries failed"},
+		{"parallel", 3, false, []batch.Status{batch.StatusSucceeded, batch.StatusFailed, batch.StatusSucceeded}, "1 of 3 batch entries failed"},
+		{"fail fast", 1, true, []batch.Status{batch.StatusSucceeded, batch.StatusFailed, batch.StatusNotRun}, "1 of 3 batch entries failed; 1 not run after --fail-fast"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &batchRun{
+				entries:  f.Migrations,
+				base:     types.MigrationConfig{DryRun: true, FailFast: tt.failFast},
+				parallel: tt.parallel,
+				clients: func() (*client.Client, *client.Client, error) {
+					return fakeAPIClient(t, responses), fakeAPIClient(t, responses), nil
+				},
+			}
+			results := r.run()
+
+			for i, want := range tt.want {
+				if results[i].Status != want {
+					t.Errorf("entry %d status = %s (%s), want %s", i+1, results[i].Status, results[i].Error, want)
+				}
+			}
+			if !strings.Contains(results[1].Error, "source repository acme/missing not found") {
+				t.Errorf("entry 2 error = %q, want the missing repository", results[1].Error)
+			}
+			if rep := results[0].Report; rep == nil || rep.Summary.Created != 1 || rep.Summary.Unchanged != 1 {
+				t.Errorf("entry 1 report = %+v, want 1 created and 1 unchanged", rep)
+			}
+			if results[1].Report != nil {
+				t.Errorf("entry 2 has a report, want none for an entry that did not start")
+			}
+
+			err := r.exitError(results)
+			if err == nil || err.Error() != tt.wantErr || exitCode(err) != exitCodePartial {
+				t.Errorf("exitError() = %v (exit %d), want %q (exit %d)", err, exitCode(err), tt.wantErr, exitCodePartial)
+			}
+
+			var b strings.Builder
+			if err := writeBatchSummary(&b, batch.NewReport("wave.yaml", results, time.Now(), time.Now())); err != nil {
+				t.Fatalf("writeBatchSummary() unexpected error: %v", err)
+			}
+			for _, want := range []string{"org ", "acme/missing → acme-new/app", "app ", "Entry 2 failed: source repository acme/missing"} {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("summary does not contain %q:\n%s", want, b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateBatchFlags(t *testing.T) {
+	origFile, origParallel, origConflict, origPlan := batchFilePath, batchParallel, onConflict, batchPlan
+	defer func() {
+		batchFilePath, batchParallel, onConflict, batchPlan = origFile, origParallel, origConflict, origPlan
+	}()
+
+	path := filepath.Join(t.TempDir(), "wave.yaml")
+	if err := os.WriteFile(path, []byte("version: 1\nmigrations:\n  - mode: org-to-org\n    source: acme\n    target: acme-new\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		parallel int
+		conflict string
+		flag     string
+		wantErr  string
+	}{
+		{name: "valid", file: path, parallel: 2},
+		{name: "no file", parallel: 1, wantErr: "--file flag is required"},
+		{name: "parallel", file: path, parallel: 0, wantErr: "--parallel must be at least 1"},
+		{name: "prompt", file: path, parallel: 1, conflict: "prompt", wantErr: "--on-conflict=prompt cannot be combined with batch"},
+		{name: "rejected flag", file: path, parallel: 1, flag: "deep", wantErr: "--deep cannot be combined with batch"},
+		{name: "invalid file", file: filepath.Join(t.TempDir(), "missing.yaml"), parallel: 1, wantErr: "reading batch file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batchFilePath, batchParallel, onConflict, batchPlan = tt.file, tt.parallel, tt.conflict, nil
+			cmd := &cobra.Command{Use: "x"}
+			if tt.flag != "" {
+				cmd.Flags().Bool(tt.flag, false, "")
+				if err := cmd.Flags().Set(tt.flag, "true"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := validateBatchFlags(cmd, nil)
+			if tt.wantErr == "" {
+				if err != nil || batchPlan == nil {
+					t.Errorf("validateBatchFlags() = %v, want the file loaded", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateBatchFlags() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
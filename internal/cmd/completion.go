@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// completionCmd writes a shell completion script
+var completionCmd = &cobra.Command{
+	Use:   "completion (bash|zsh|fish|powershell)",
+	Short: "Generate a shell completion script",
+	Long: `Write a completion script for bash, zsh, fish, or PowerShell to standard
+output. The script completes the commands and flags of the gh-vars-migrator
+executable, the values of flags that take one of a fixed set (such as
+--on-conflict, --visibility, and --output), and the environment names of
+--env once --repo is given.
+
+Load it in the current shell, or save it where the shell loads completions
+from:
+
+  bash:        source <(gh-vars-migrator completion bash)
+  zsh:         gh-vars-migrator completion zsh > "${fpath[1]}/_gh-vars-migrator"
+  fish:        gh-vars-migrator completion fish > ~/.config/fish/completions/gh-vars-migrator.fish
+  PowerShell:  gh-vars-migrator completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	w := cmd.OutOrStdout()
+	switch args[0] {
+	case "bash":
+		return cmd.Root().GenBashCompletionV2(w, true)
+	case "zsh":
+		return cmd.Root().GenZshCompletion(w)
+	case "fish":
+		return cmd.Root().GenFishCompletion(w, true)
+	case "powershell":
+		return cmd.Root().GenPowerShellCompletionWithDesc(w)
+	}
+	return fmt.Errorf("unsupported shell %q", args[0])
+}
+
+// registerCompletion registers the completion of a flag of cmd; the flag
+// must already be defined
+func registerCompletion(cmd *cobra.Command, flag string, f cobra.CompletionFunc) {
+	if err := cmd.RegisterFlagCompletionFunc(flag, f); err != nil {
+		panic(err)
+	}
+}
+
+// fixedCompletion completes a flag with one of choices
+func fixedCompletion(choices ...string) cobra.CompletionFunc {
+	return cobra.FixedCompletions(choices, cobra.ShellCompDirectiveNoFileComp)
+}
+
+// environmentCompletion completes --env with the environments of the
+// repository given by the owner and repo flags, read with the pat flag (or
+// GITHUB_TOKEN, or the GitHub CLI authentication) from the hostname flag.
+// Nothing is completed until --repo is given or when the lookup fails.
+func environmentCompletion(owner, repo, pat, hostname *string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if *repo == "" {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		o, r, err := resolveRepoFlag(*owner, *repo)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		token := os.Getenv("GITHUB_TOKEN")
+		if *pat != "" {
+			token = *pat
+		}
+		c, err := createClientWithToken(token, normalizeHostname(*hostname), "completion")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		envs, err := c.ListEnvironments(o, r)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		names := make([]string, 0, len(envs))
+		for _, env := range envs {
+			names = append(names, env.Name)
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/dump"
+	"github.com/spf13/cobra"
+)
+
+func TestRunCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		t.Run(shell, func(t *testing.T) {
+			var b strings.Builder
+			completionCmd.SetOut(&b)
+			defer completionCmd.SetOut(nil)
+
+			if err := runCompletion(completionCmd, []string{shell}); err != nil {
+				t.Fatalf("runCompletion(%s) unexpected error: %v", shell, err)
+			}
+			if !strings.Contains(b.String(), "gh-vars-migrator") {
+				t.Errorf("The %s script does not mention the executable:\n%.200s", shell, b.String())
+			}
+		})
+	}
+
+	if err := completionCmd.Args(completionCmd, []string{"tcsh"}); err == nil {
+		t.Error("An unsupported shell should be rejected")
+	}
+}
+
+func TestFlagCompletions(t *testing.T) {
+	tests := []struct {
+		cmd  *cobra.Command
+		flag string
+		want []string
+	}{
+		{rootCmd, "on-conflict", []string{"skip", "overwrite", "fail", "prompt"}},
+		{rootCmd, "visibility", []string{"all", "private", "selected"}},
+		{rootCmd, "target-visibility", []string{"all", "private"}},
+		{rootCmd, "selected-fallback", []string{"empty", "private", "all", "skip"}},
+		{cpCmd, "on-conflict", []string{"skip", "overwrite", "fail", "prompt"}},
+		{listCmd, "output", []string{"table", "json", "csv"}},
+		{envsCmd, "output", []string{"table", "json", "csv"}},
+		{ratelimitCmd, "output", []string{"table", "json", "csv"}},
+		{exportCmd, "format", []string{"json", "yaml", "env", "csv", "terraform"}},
+		{importCmd, "format", dump.ImportFormats},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd.Name()+" --"+tt.flag, func(t *testing.T) {
+			complete, ok := tt.cmd.GetFlagCompletionFunc(tt.flag)
+			if !ok {
+				t.Fatalf("--%s has no completion", tt.flag)
+			}
+			got, directive := complete(tt.cmd, nil, "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Completions = %v, want %v", got, tt.want)
+			}
+			if directive != cobra.ShellCompDirectiveNoFileComp {
+				t.Errorf("Directive = %v, want no file completion", directive)
+			}
+		})
+	}
+}
+
+// TestEnvironmentCompletion_NoRepo tests that --env completes nothing, and
+// makes no request, before --repo is given
+func TestEnvironmentCompletion_NoRepo(t *testing.T) {
+	var owner, repo, pat, hostname string
+	got, directive := environmentCompletion(&owner, &repo, &pat, &hostname)(listCmd, nil, "")
+	if len(got) != 0 || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("environmentCompletion() = %v, %v; want nothing", got, directive)
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// cpCmd copies one variable from a source scope to a target scope
+var cpCmd = &cobra.Command{
+	Use:   "cp SOURCE TARGET --name NAME [--new-name NAME]",
+	Short: "Copy a single variable between organizations, repositories, and environments",
+	Long: `Copy one variable from the SOURCE scope to the TARGET scope. Each scope is
+given as org:ORG, repo:OWNER/REPO, or env:OWNER/REPO/ENV.
+
+The variable keeps its name unless --new-name is set. An organization
+variable copied to another organization keeps its visibility, and its
+selected repositories are matched by name in the target organization; a
+repository or environment variable copied to an organization is visible to
+all of its repositories. A missing target environment is created.
+
+An existing target variable is handled by --on-conflict (or --skip-overwrite)
+as in a migration, and one that already matches is not written. The source is
+read with the source credentials and hostname and the target written with the
+target ones; --dry-run, --report-file, and the hooks work as for a migration.`,
+	Example: `  # Copy an organization variable into a repository
+  gh vars-migrator cp org:acme repo:acme/api --name API_URL
+
+  # Copy a repository variable into an environment under another name
+  gh vars-migrator cp repo:acme/api env:acme/api/production --name URL --new-name API_URL
+
+  # Preview copying a variable to another organization, keeping an existing one
+  gh vars-migrator cp org:acme org:acme-next --name LOG_LEVEL --on-conflict skip --dry-run`,
+	PreRunE:       validateCpFlags,
+	RunE:          runCp,
+	SilenceErrors: true,
+}
+
+var (
+	cpName    string
+	cpNewName string
+)
+
+// cpScopes holds the source and target scopes parsed from the arguments
+// during flag validation
+var cpScopes [2]types.DesiredScope
+
+func init() {
+	rootCmd.AddCommand(cpCmd)
+	supportOutput(cpCmd, output.JSON, output.CSV)
+	cpCmd.Flags().StringVar(&cpName, "name", "", "Name of the variable to copy (required)")
+	cpCmd.Flags().StringVar(&cpNewName, "new-name", "", "Name of the copy (default: the source name)")
+	// The migration flags are added by the root command once it has
+	// registered them.
+}
+
+// cpFlags are the flags cp accepts besides its own: the credentials and
+// hosts of both sides or a profile holding them, and the options that decide
+// how the copy is written and reported
+var cpFlags = map[string]bool{
+	"name": true, "new-name": true,
+	"verbose": true, "quiet": true, "no-color": true, "no-annotations": true, "env-file": true, "output": true,
+	"source-pat": true, "target-pat": true, "source-pat-file": true, "target-pat-file": true, "source-hostname": true, "target-hostname": true, "no-keyring": true,
+	"config": true, "profile": true,
+	"dry-run": true, "on-conflict": true, "skip-overwrite": true, "interactive": true,
+	"show-values": true, "always-write": true, "report-file": true, "last-report-file": true, "github-summary": true,
+	"pre-hook": true, "post-hook": true, "hooks-in-dry-run": true,
+}
+
+// validateCpFlags parses the SOURCE and TARGET scopes and checks the names
+// and flags of the copy
+func validateCpFlags(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("cp takes a SOURCE and a TARGET scope, e.g. cp org:acme repo:acme/api --name API_URL")
+	}
+	if err := config.ValidateVariableName("--name", cpName); err != nil {
+		return err
+	}
+	if cpNewName != "" {
+		if err := config.ValidateVariableName("--new-name", cpNewName); err != nil {
+			return err
+		}
+	}
+
+	for i, side := range []string{"source", "target"} {
+		scope, err := config.ParseScope(args[i])
+		if err != nil {
+			return fmt.Errorf("%s: %w", side, err)
+		}
+		cpScopes[i] = scope
+	}
+	sameName := cpNewName == "" || strings.EqualFold(cpNewName, cpName)
+	if strings.EqualFold(cpScopes[0].Label(), cpScopes[1].Label()) && sameName {
+		return fmt.Errorf("source and target are the same scope; set --new-name to copy %s within %s", cpName, args[0])
+	}
+	cmd.SilenceUsage = true
+
+	if err := rejectFlags(cmd, "cp", func(name string) bool { return cpFlags[name] }); err != nil {
+		return err
+	}
+
+	sourceHostname = normalizeHostname(sourceHostname)
+	targetHostname = normalizeHostname(targetHostname)
+	return validateConflictOptions()
+}
+
+// runCp copies the variable with the source and target credentials
+func runCp(cmd *cobra.Command, args []string) error {
+	from, to := cpScopes[0], cpScopes[1]
+
+	sourceClient, err := sourceOnlyClient("cp")
+	if err != nil {
+		return authError(err)
+	}
+	targetClient, err := targetOnlyClient("cp")
+	if err != nil {
+		return authError(err)
+	}
+	if to.Org != "" {
+		err = client.ValidateOrgScopes(targetClient, "target")
+	} else {
+		err = client.ValidateRepoScopes(targetClient, "target")
+	}
+	if err != nil {
+		return authError(err)
+	}
+
+	cfg := &types.MigrationConfig{
+		Mode:          types.ModeCopy,
+		CopyFrom:      from,
+		CopyName:      cpName,
+		CopyAs:        cpNewName,
+		Desired:       []types.DesiredScope{to},
+		DryRun:        dryRun,
+		SkipOverwrite: skipOverwrite,
+		OnConflict:    types.ConflictStrategy(onConflict),
+		Interactive:   interactive,
+		ShowValues:    showValues,
+		AlwaysWrite:   alwaysWrite,
+	}
+
+	logger.Info("Copy:            %s  %s → %s", cpName, from.Label(), to.Label())
+	if cpNewName != "" {
+		logger.Info("New Name:        %s", cpNewName)
+	}
+
+	m, err := migrator.New(cfg, sourceClient, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrator: %w", err)
+	}
+	return runMigrator(cfg, m)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+func TestValidateCpFlags(t *testing.T) {
+	origName, origNewName, origScopes, origOnConflict := cpName, cpNewName, cpScopes, onConflict
+	defer func() { cpName, cpNewName, cpScopes, onConflict = origName, origNewName, origScopes, origOnConflict }()
+
+	tests := []struct {
+		name     string
+		args     []string
+		varName  string
+		newName  string
+		flag     string
+		wantFrom string
+		wantTo   string
+		wantErr  string
+	}{
+		{name: "org to repo", args: []string{"org:acme", "repo:acme/api"}, varName: "API_URL", wantFrom: "org:acme", wantTo: "acme/api"},
+		{name: "repo to env", args: []string{"repo:acme/api", "env:acme/api/prod"}, varName: "URL", wantFrom: "acme/api", wantTo: "acme/api:env:prod"},
+		{name: "same scope under a new name", args: []string{"repo:acme/api", "repo:acme/api"}, varName: "URL", newName: "API_URL", wantFrom: "acme/api", wantTo: "acme/api"},
+		{name: "write flag", args: []string{"org:acme", "org:other"}, varName: "A", flag: "dry-run", wantFrom: "org:acme", wantTo: "org:other"},
+		{name: "one scope", args: []string{"org:acme"}, varName: "A", wantErr: "cp takes a SOURCE and a TARGET scope"},
+		{name: "missing name", args: []string{"org:acme", "org:other"}, wantErr: "--name cannot be empty"},
+		{name: "invalid new name", args: []string{"org:acme", "org:other"}, varName: "A", newName: "B-2", wantErr: `--new-name "B-2" contains invalid character`},
+		{name: "invalid source", args: []string{"acme", "org:other"}, varName: "A", wantErr: `source: invalid scope "acme"`},
+		{name: "invalid target", args: []string{"org:acme", "env:acme/api"}, varName: "A", wantErr: "target: invalid scope"},
+		{name: "same scope", args: []string{"repo:acme/api", "repo:ACME/api"}, varName: "A", newName: "a", wantErr: "source and target are the same scope"},
+		{name: "migration flag", args: []string{"org:acme", "org:other"}, varName: "A", flag: "include", wantErr: "--include cannot be combined with cp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpName, cpNewName, cpScopes, onConflict = tt.varName, tt.newName, [2]types.DesiredScope{}, ""
+
+			// A throwaway command, so that setting a flag leaves cpCmd alone
+			cmd := &cobra.Command{Use: "cp"}
+			if tt.flag != "" {
+				cmd.Flags().String(tt.flag, "", "")
+				if err := cmd.Flags().Set(tt.flag, "x"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := validateCpFlags(cmd, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("validateCpFlags() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateCpFlags() unexpected error: %v", err)
+			}
+			if cpScopes[0].Label() != tt.wantFrom || cpScopes[1].Label() != tt.wantTo {
+				t.Errorf("Scopes = %s → %s, want %s → %s", cpScopes[0].Label(), cpScopes[1].Label(), tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/term"
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// deleteCmd deletes the selected variables of an organization, repository,
+// or environment
+var deleteCmd = &cobra.Command{
+	Use:   "delete --org ORG [--repo REPO [--env ENV]] (--vars NAMES | --include GLOB | --exclude GLOB | --all)",
+	Short: "Delete the selected variables of an organization, repository, or environment",
+	Long: `Delete Actions variables of an organization, of one of its repositories
+(--repo), or of an environment of that repository (--env).
+
+The variables to delete are selected with --vars, --include, --exclude, and
+--filter-regex, which work as for a migration; --all selects every variable.
+The selection is listed first, and nothing is deleted until the scope name
+(e.g. myorg/app) is typed to confirm. --yes skips the confirmation, and is
+required when standard input is not a terminal. With --dry-run the selection
+is only listed.
+
+Every deletion is reported, and the command exits 3 when any of them failed.
+A deletion refused for lack of permission stops the command at once with exit
+2, reporting what was deleted before it and what was not attempted.
+Only the target credentials and hostname are used; --report-file and the hooks
+work as for a migration.`,
+	Example: `  # List what would be deleted
+  gh vars-migrator delete --org myorg --repo app --include 'TMP_*' --dry-run
+
+  # Delete two variables of an environment without a prompt
+  gh vars-migrator delete --org myorg --repo app --env staging --vars OLD_URL,OLD_KEY --yes`,
+	PreRunE:       validateDeleteFlags,
+	RunE:          runDelete,
+	SilenceErrors: true,
+}
+
+var (
+	deleteOrg  string
+	deleteRepo string
+	deleteEnv  string
+	deleteAll  bool
+	deleteYes  bool
+)
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+	supportOutput(deleteCmd, output.JSON, output.CSV)
+	deleteCmd.Flags().StringVarP(&deleteOrg, "org", "o", "", "Organization to delete from (required)")
+	deleteCmd.Flags().StringVar(&deleteRepo, "repo", "", "Delete from this repository of the organization instead")
+	deleteCmd.Flags().StringVar(&deleteEnv, "env", "", "Delete from this environment of --repo")
+	deleteCmd.Flags().BoolVar(&deleteAll, "all", false, "Select every variable of the scope")
+	deleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Delete without asking to type the scope name")
+	// The migration flags are added by the root command once it has
+	// registered them.
+}
+
+// deleteFlags are the flags delete accepts besides its own: the target
+// connection, the name filters, and the options that decide how the run
+// stops and reports
+var deleteFlags = map[string]bool{
+	"org": true, "repo": true, "env": true, "all": true, "yes": true,
+	"verbose": true, "quiet": true, "no-color": true, "no-annotations": true, "env-file": true, "output": true,
+	"target-pat": true, "target-pat-file": true, "target-hostname": true, "no-keyring": true,
+	"vars": true, "include": true, "exclude": true, "filter-regex": true,
+	"dry-run": true, "interactive": true,
+	"fail-fast": true, "max-errors": true, "max-api-calls": true,
+	"report-file": true, "last-report-file": true, "github-summary": true,
+	"pre-hook": true, "post-hook": true, "hooks-in-dry-run": true,
+}
+
+// validateDeleteFlags checks the target, the selection, and how the
+// deletion is confirmed
+func validateDeleteFlags(cmd *cobra.Command, args []string) error {
+	switch {
+	case deleteOrg == "":
+		return fmt.Errorf("--org flag is required")
+	case deleteEnv != "" && deleteRepo == "":
+		return fmt.Errorf("--env requires --repo")
+	}
+	cmd.SilenceUsage = true
+
+	if err := rejectFlags(cmd, "delete", func(name string) bool { return deleteFlags[name] }); err != nil {
+		return err
+	}
+
+	selection := len(varNames) > 0 || len(includePatterns) > 0 || len(excludePatterns) > 0 || filterRegex != ""
+	switch {
+	case deleteAll && selection:
+		return fmt.Errorf("--all cannot be combined with --vars, --include, --exclude, or --filter-regex")
+	case !deleteAll && !selection:
+		return fmt.Errorf("select the variables to delete with --vars, --include, --exclude, or --filter-regex, or pass --all")
+	case !deleteYes && !dryRun && !term.IsTerminal(os.Stdin):
+		return fmt.Errorf("delete asks to type the scope name on a terminal; pass --yes to delete without it")
+	}
+
+	targetHostname = normalizeHostname(targetHostname)
+	if err := validateRunOptions(); err != nil {
+		return err
+	}
+	if err := validateConflictOptions(); err != nil {
+		return err
+	}
+	if err := config.ValidatePatterns(includePatterns, excludePatterns); err != nil {
+		return err
+	}
+	return config.ValidateFilterRegex(filterRegex)
+}
+
+// runDelete deletes the selected variables. Only the target credentials
+// and hostname are used.
+func runDelete(cmd *cobra.Command, args []string) error {
+	targetClient, err := targetOnlyClient("delete")
+	if err != nil {
+		return authError(err)
+	}
+	scope := types.DesiredScope{Org: deleteOrg}
+	if deleteRepo != "" {
+		scope = types.DesiredScope{Owner: deleteOrg, Repo: deleteRepo, Environment: deleteEnv}
+		err = client.ValidateRepoScopes(targetClient, "target")
+	} else {
+		err = client.ValidateOrgScopes(targetClient, "target")
+	}
+	if err != nil {
+		return authError(err)
+	}
+
+	cfg := &types.MigrationConfig{
+		Mode:        types.ModeDelete,
+		Desired:     []types.DesiredScope{scope},
+		Yes:         deleteYes,
+		DryRun:      dryRun,
+		Interactive: interactive,
+		FailFast:    failFast,
+		MaxErrors:   maxErrors,
+		MaxAPICalls: maxAPICalls,
+		Vars:        varNames,
+		Include:     includePatterns,
+		Exclude:     excludePatterns,
+		FilterRegex: filterRegex,
+	}
+
+	logger.Info("Delete from:     %s", scope.Label())
+	if len(varNames) > 0 {
+		logger.Info("Vars:            %s  ← %s", strings.Join(varNames, ", "), flagSource(cmd, "vars", "VARS"))
+	}
+	if len(includePatterns) > 0 {
+		logger.Info("Include:         %s  ← %s", strings.Join(includePatterns, ", "), flagSource(cmd, "include", "INCLUDE_VARS"))
+	}
+	if len(excludePatterns) > 0 {
+		logger.Info("Exclude:         %s  ← %s", strings.Join(excludePatterns, ", "), flagSource(cmd, "exclude", "EXCLUDE_VARS"))
+	}
+	if filterRegex != "" {
+		logger.Info("Filter Regex:    %s  ← %s", filterRegex, flagSource(cmd, "filter-regex", "FILTER_REGEX"))
+	}
+
+	// There is no source, so the target client serves both roles
+	m, err := migrator.New(cfg, targetClient, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrator: %w", err)
+	}
+	return runMigrator(cfg, m)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestValidateDeleteFlags(t *testing.T) {
+	origOrg, origRepo, origEnv, origAll, origYes := deleteOrg, deleteRepo, deleteEnv, deleteAll, deleteYes
+	origVars, origInclude, origDryRun := varNames, includePatterns, dryRun
+	defer func() {
+		deleteOrg, deleteRepo, deleteEnv, deleteAll, deleteYes = origOrg, origRepo, origEnv, origAll, origYes
+		varNames, includePatterns, dryRun = origVars, origInclude, origDryRun
+	}()
+
+	tests := []struct {
+		name    string
+		org     string
+		repo    string
+		env     string
+		all     bool
+		yes     bool
+		dryRun  bool
+		vars    []string
+		include []string
+		flag    string
+		wantErr string
+	}{
+		{name: "organization variables", org: "acme", vars: []string{"A"}, yes: true},
+		{name: "environment pattern", org: "acme", repo: "app", env: "prod", include: []string{"TMP_*"}, yes: true},
+		{name: "all in a dry run", org: "acme", repo: "app", all: true, dryRun: true},
+		{name: "filter flag", org: "acme", all: true, yes: true, flag: "max-errors"},
+		{name: "missing org", vars: []string{"A"}, yes: true, wantErr: "--org flag is required"},
+		{name: "env without repo", org: "acme", env: "prod", vars: []string{"A"}, yes: true, wantErr: "--env requires --repo"},
+		{name: "no selection", org: "acme", yes: true, wantErr: "select the variables to delete"},
+		{name: "all with a selection", org: "acme", all: true, vars: []string{"A"}, yes: true, wantErr: "--all cannot be combined"},
+		{name: "no terminal without yes", org: "acme", vars: []string{"A"}, wantErr: "pass --yes"},
+		{name: "invalid include", org: "acme", include: []string{"["}, yes: true, wantErr: `invalid include pattern "["`},
+		{name: "source flag", org: "acme", all: true, yes: true, flag: "source-org", wantErr: "--source-org cannot be combined with delete"},
+		{name: "write flag", org: "acme", all: true, yes: true, flag: "on-conflict", wantErr: "--on-conflict cannot be combined with delete"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleteOrg, deleteRepo, deleteEnv, deleteAll, deleteYes = tt.org, tt.repo, tt.env, tt.all, tt.yes
+			varNames, includePatterns, dryRun = tt.vars, tt.include, tt.dryRun
+
+			// A throwaway command, so that setting a flag leaves deleteCmd alone
+			cmd := &cobra.Command{Use: "delete"}
+			if tt.flag != "" {
+				cmd.Flags().String(tt.flag, "", "")
+				if err := cmd.Flags().Set(tt.flag, "x"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := validateDeleteFlags(cmd, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateDeleteFlags() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateDeleteFlags() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
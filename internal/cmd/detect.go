@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/repository"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// gitRemotes returns the output of git remote -v in the working directory;
+// tests replace it
+var gitRemotes = func() (string, error) {
+	out, err := exec.Command("git", "remote", "-v").Output()
+	if err != nil {
+		return "", errors.New("the working directory is not a git repository")
+	}
+	return string(out), nil
+}
+
+// detectedRemote is a GitHub repository a git remote points to
+type detectedRemote struct {
+	name string
+	repo repository.Repository
+}
+
+func (r detectedRemote) String() string {
+	return r.name + ": " + r.repo.Host + "/" + r.repo.Owner + "/" + r.repo.Name
+}
+
+// detectRepository finds the repository the working directory is a clone
+// of: the GH_REPO repository when set, like the GitHub CLI, otherwise the
+// one the fetch URLs of the git remotes in remotes, the output of git
+// remote -v, point to. Remotes pointing to different repositories are
+// ambiguous, and remotes that are not SSH or HTTPS URLs are left out.
+func detectRepository(getenv func(string) string, remotes string) (detectedRemote, error) {
+	if v := getenv("GH_REPO"); v != "" {
+		r, err := repository.Parse(v)
+		if err != nil {
+			return detectedRemote{}, fmt.Errorf("GH_REPO: %w", err)
+		}
+		return detectedRemote{name: "GH_REPO", repo: r}, nil
+	}
+
+	var found []detectedRemote
+	seen := false
+	for _, line := range strings.Split(remotes, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[2] != "(fetch)" {
+			continue
+		}
+		seen = true
+		url := fields[1]
+		if !strings.Contains(url, "://") && !strings.HasPrefix(url, "git@") {
+			continue
+		}
+		r, err := repository.Parse(url)
+		if err != nil {
+			continue
+		}
+		remote := detectedRemote{name: fields[0], repo: r}
+		if len(found) > 0 && !sameRepository(found[0].repo, r) {
+			all := make([]string, 0, len(found)+1)
+			for _, f := range append(found, remote) {
+				all = append(all, f.String())
+			}
+			return detectedRemote{}, fmt.Errorf("the git remotes point to different repositories (%s)", strings.Join(all, ", "))
+		}
+		found = append(found, remote)
+	}
+
+	switch {
+	case !seen:
+		return detectedRemote{}, errors.New("the git repository has no remotes")
+	case len(found) == 0:
+		return detectedRemote{}, errors.New("none of the git remotes is a GitHub repository")
+	}
+	return found[0], nil
+}
+
+// sameRepository reports whether a and b name the same repository; GitHub
+// names are case-insensitive
+func sameRepository(a, b repository.Repository) bool {
+	return strings.EqualFold(a.Host, b.Host) && strings.EqualFold(a.Owner, b.Owner) && strings.EqualFold(a.Name, b.Name)
+}
+
+// detectSource fills --source-org, and --source-repo for the modes that
+// migrate from a repository, from the repository the working directory is
+// a clone of when neither is set and --no-detect is not. Detection that
+// fails or is ambiguous asks for the flags instead. A source on another
+// host than github.com also sets --source-hostname, which must otherwise
+// match it.
+func detectSource() error {
+	detectedFlags = map[string]string{}
+	if sourceOrg != "" || sourceRepo != "" || noDetect {
+		return nil
+	}
+
+	remote, err := func() (detectedRemote, error) {
+		out, err := gitRemotes()
+		if err != nil {
+			return detectedRemote{}, err
+		}
+		return detectRepository(os.Getenv, out)
+	}()
+	if err == nil && sourceHostname != "" && !sameHost(sourceHostname, remote.repo.Host) {
+		err = fmt.Errorf("%s is not on --source-hostname %s", remote, sourceHostname)
+	}
+	if err != nil {
+		return fmt.Errorf("--source-org or --source flag is required; the source could not be detected from the working directory: %w", err)
+	}
+
+	sourceOrg = remote.repo.Owner
+	detectedFlags["source-org"] = remote.name
+	if mode := detectMigrationMode(); mode == types.ModeRepoToRepo || mode == types.ModeRepoToOrg {
+		sourceRepo = remote.repo.Name
+		detectedFlags["source-repo"] = remote.name
+		logger.Info("Detected the source repository %s/%s from %s", sourceOrg, sourceRepo, remoteLabel(remote.name))
+	} else {
+		logger.Info("Detected the source organization %s from %s", sourceOrg, remoteLabel(remote.name))
+	}
+	if sourceHostname == "" && remote.repo.Host != "github.com" {
+		sourceHostname = remote.repo.Host
+		detectedFlags["source-hostname"] = remote.name
+	}
+	return nil
+}
+
+// remoteLabel describes where a detected source came from
+func remoteLabel(name string) string {
+	if name == "GH_REPO" {
+		return "GH_REPO"
+	}
+	return "git remote " + name
+}
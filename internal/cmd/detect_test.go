@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDetectRepository(t *testing.T) {
+	tests := []struct {
+		name       string
+		remotes    string
+		ghRepo     string
+		wantRemote string
+		want       string
+		wantErr    string
+	}{
+		{
+			name:       "https",
+			remotes:    "origin\thttps://github.com/acme/app.git (fetch)\norigin\thttps://github.com/acme/app.git (push)\n",
+			wantRemote: "origin", want: "github.com/acme/app",
+		},
+		{
+			name:       "https without .git",
+			remotes:    "origin\thttps://github.com/acme/app (fetch)\n",
+			wantRemote: "origin", want: "github.com/acme/app",
+		},
+		{
+			name:       "scp-like ssh",
+			remotes:    "origin\tgit@github.com:acme/app.git (fetch)\norigin\tgit@github.com:acme/app.git (push)\n",
+			wantRemote: "origin", want: "github.com/acme/app",
+		},
+		{
+			name:       "ssh URL with a port",
+			remotes:    "origin\tssh://git@github.example.com:2222/acme/app.git (fetch)\n",
+			wantRemote: "origin", want: "github.example.com/acme/app",
+		},
+		{
+			name:       "remotes of the same repository",
+			remotes:    "mirror\thttps://github.com/Acme/App.git (fetch)\norigin\tgit@github.com:acme/app.git (fetch)\n",
+			wantRemote: "mirror", want: "github.com/Acme/App",
+		},
+		{
+			name:       "local remotes are left out",
+			remotes:    "backup\t/srv/git/app.git (fetch)\norigin\tgit@github.com:acme/app.git (fetch)\n",
+			wantRemote: "origin", want: "github.com/acme/app",
+		},
+		{
+			name:       "GH_REPO wins",
+			remotes:    "origin\tgit@github.com:acme/app.git (fetch)\n",
+			ghRepo:     "github.example.com/corp/api",
+			wantRemote: "GH_REPO", want: "github.example.com/corp/api",
+		},
+		{
+			name:    "ambiguous",
+			remotes: "origin\tgit@github.com:me/app.git (fetch)\nupstream\thttps://github.com/acme/app.git (fetch)\n",
+			wantErr: "the git remotes point to different repositories (origin: github.com/me/app, upstream: github.com/acme/app)",
+		},
+		{name: "no remotes", remotes: "", wantErr: "the git repository has no remotes"},
+		{name: "no GitHub remote", remotes: "backup\t/srv/git/app.git (fetch)\n", wantErr: "none of the git remotes is a GitHub repository"},
+		{name: "malformed GH_REPO", ghRepo: "acme", wantErr: "GH_REPO: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string {
+				if key == "GH_REPO" {
+					return tt.ghRepo
+				}
+				return ""
+			}
+			got, err := detectRepository(getenv, tt.remotes)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("detectRepository() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("detectRepository() unexpected error: %v", err)
+			}
+			if repo := got.repo.Host + "/" + got.repo.Owner + "/" + got.repo.Name; got.name != tt.wantRemote || repo != tt.want {
+				t.Errorf("detectRepository() = %s %s, want %s %s", got.name, repo, tt.wantRemote, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateFlags_DetectSource tests that the source is detected from the
+// git remote when --source-org and --source-repo are not set
+func TestValidateFlags_DetectSource(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origSourceHostname, origOrgToOrg := sourceHostname, orgToOrg
+	origNoDetect, origDetectedFlags, origGitRemotes := noDetect, detectedFlags, gitRemotes
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		sourceHostname, orgToOrg = origSourceHostname, origOrgToOrg
+		noDetect, detectedFlags, gitRemotes = origNoDetect, origDetectedFlags, origGitRemotes
+	}()
+	t.Setenv("GH_REPO", "")
+
+	const origin = "origin\tgit@github.example.com:acme/app.git (fetch)\n"
+	tests := []struct {
+		name         string
+		remotes      string
+		gitErr       error
+		sourceRepo   string
+		hostname     string
+		orgToOrg     bool
+		noDetect     bool
+		want         [3]string // source org, repo, and hostname
+		wantProvider string
+		wantErr      string
+	}{
+		{name: "repository", remotes: origin, want: [3]string{"acme", "app", "github.example.com"}, wantProvider: "git remote origin"},
+		{name: "organization", remotes: origin, orgToOrg: true, want: [3]string{"acme", "", "github.example.com"}, wantProvider: "git remote origin"},
+		{name: "matching hostname", remotes: origin, hostname: "github.example.com", want: [3]string{"acme", "app", "github.example.com"}},
+		{name: "flags win", remotes: origin, sourceRepo: "api", wantErr: "--source-org or --source flag is required"},
+		{name: "other hostname", remotes: origin, hostname: "github.com", wantErr: "origin: github.example.com/acme/app is not on --source-hostname github.com"},
+		{name: "ambiguous", remotes: origin + "upstream\tgit@github.com:corp/app.git (fetch)\n", wantErr: "the source could not be detected from the working directory: the git remotes point to different repositories"},
+		{name: "not a git repository", gitErr: errors.New("the working directory is not a git repository"), wantErr: "the working directory is not a git repository"},
+		{name: "no-detect", remotes: origin, noDetect: true, wantErr: "--source-org or --source flag is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, sourceRepo, sourceHostname = "", tt.sourceRepo, tt.hostname
+			targetOrg, targetRepo = "target-org", "app"
+			orgToOrg, noDetect = tt.orgToOrg, tt.noDetect
+			if tt.orgToOrg {
+				targetRepo = ""
+			}
+			gitRemotes = func() (string, error) { return tt.remotes, tt.gitErr }
+
+			var err error
+			captureStdio(t, func() { err = validateFlags(rootCmd, nil) })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validateFlags() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateFlags() unexpected error: %v", err)
+			}
+			if got := [3]string{sourceOrg, sourceRepo, sourceHostname}; got != tt.want {
+				t.Errorf("Detected %q, want %q", got, tt.want)
+			}
+			if tt.wantProvider != "" {
+				if got := flagSource(rootCmd, "source-org", "SOURCE_ORG"); got != tt.wantProvider {
+					t.Errorf("flagSource(source-org) = %q, want %q", got, tt.wantProvider)
+				}
+			}
+		})
+	}
+}
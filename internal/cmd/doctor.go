@@ -0,0 +1,409 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd diagnoses the authentication and connectivity of the machine it
+// runs on
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose authentication and connectivity problems",
+	Long: `Check, in order, what a migration from this machine depends on, and print a
+pass, warn, or fail line per check followed by a verdict and hints:
+
+  - the GitHub CLI login of each host
+  - the token variables GITHUB_TOKEN, GH_TOKEN, SOURCE_PAT, and TARGET_PAT,
+    shown masked
+  - that the API of github.com and of the --source-hostname and
+    --target-hostname hosts resolves and completes a TLS handshake
+  - that the credential of each side authenticates, and the core rate limit
+    it has left
+  - the OAuth scopes of each side's token
+
+No migration flag is needed: the credentials are resolved as a migration
+would resolve them, from SOURCE_PAT and TARGET_PAT, then GITHUB_TOKEN, then
+the GitHub CLI login, including those set by --env-file. Nothing is written.
+The command exits non-zero when any check fails.`,
+	Example: `  # Diagnose before opening a support request
+  gh vars-migrator doctor
+
+  # Include a GitHub Enterprise Server source
+  gh vars-migrator doctor --source-hostname github.example.com`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+var (
+	doctorSourceHostname string
+	doctorTargetHostname string
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringVar(&doctorSourceHostname, "source-hostname", os.Getenv("SOURCE_HOSTNAME"), "GitHub hostname of the source (default: github.com) (env: SOURCE_HOSTNAME)")
+	doctorCmd.Flags().StringVar(&doctorTargetHostname, "target-hostname", os.Getenv("TARGET_HOSTNAME"), "GitHub hostname of the target (default: github.com) (env: TARGET_HOSTNAME)")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	d := newDoctor()
+	d.run(normalizeHostname(doctorSourceHostname), normalizeHostname(doctorTargetHostname))
+	return d.report(cmd.OutOrStdout())
+}
+
+// Statuses of a doctor check
+const (
+	doctorPass = "pass"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+	doctorSkip = "skip"
+)
+
+// doctorCheck is one line of the doctor output. Hint says how to fix a
+// warning or failure.
+type doctorCheck struct {
+	name   string
+	status string
+	detail string
+	hint   string
+}
+
+// doctor runs the checks of the doctor command. Its functions stand in for
+// the environment, the GitHub CLI, and the network in tests.
+type doctor struct {
+	getenv func(string) string
+	// ghToken returns the GitHub CLI token of a host and where it was found
+	ghToken    func(host string) (string, string)
+	lookupHost func(host string) ([]string, error)
+	// dialTLS completes a TLS handshake with host and returns the version
+	dialTLS   func(host string) (string, error)
+	newClient func(token, hostname, side string) (*client.Client, error)
+
+	checks []doctorCheck
+}
+
+// doctorTimeout bounds the TLS handshake of each host
+const doctorTimeout = 10 * time.Second
+
+func newDoctor() *doctor {
+	return &doctor{
+		getenv:     os.Getenv,
+		ghToken:    auth.TokenForHost,
+		lookupHost: net.LookupHost,
+		dialTLS: func(host string) (string, error) {
+			conn, err := tls.DialWithDialer(&net.Dialer{Timeout: doctorTimeout}, "tcp", net.JoinHostPort(host, "443"), &tls.Config{ServerName: host})
+			if err != nil {
+				return "", err
+			}
+			defer func() { _ = conn.Close() }()
+			return tls.VersionName(conn.ConnectionState().Version), nil
+		},
+		newClient: createClientWithToken,
+	}
+}
+
+// add records a check
+func (d *doctor) add(name, status, detail, hint string) {
+	d.checks = append(d.checks, doctorCheck{name: name, status: status, detail: detail, hint: hint})
+}
+
+// doctorSide is the credential one side of a migration would use
+type doctorSide struct {
+	name     string
+	hostname string
+	patName  string
+	label    string
+	token    string
+}
+
+// run runs every check for a migration between sourceHostname and
+// targetHostname, empty meaning github.com
+func (d *doctor) run(sourceHostname, targetHostname string) {
+	hosts := []string{"github.com"}
+	for _, h := range []string{sourceHostname, targetHostname} {
+		if h != "" && !containsFold(hosts, h) {
+			hosts = append(hosts, h)
+		}
+	}
+
+	cliLogins := map[string]bool{}
+	for _, host := range hosts {
+		cliLogins[host] = d.checkCLILogin(host)
+	}
+	d.checkTokenVariables(cliLogins[hostLabel(sourceHostname)] && cliLogins[hostLabel(targetHostname)])
+
+	reachable := map[string]bool{}
+	for _, host := range hosts {
+		reachable[host] = d.checkReachable(host)
+	}
+
+	for _, side := range d.sides(sourceHostname, targetHostname) {
+		host := hostLabel(side.hostname)
+		if !reachable[host] {
+			d.add(side.name+" rate limit", doctorSkip, host+" is not reachable", "")
+			d.add(side.name+" token scopes", doctorSkip, host+" is not reachable", "")
+			continue
+		}
+		c := d.checkRateLimit(side)
+		if c == nil {
+			d.add(side.name+" token scopes", doctorSkip, "the credential does not authenticate", "")
+			continue
+		}
+		d.checkScopes(side, c)
+	}
+}
+
+// checkCLILogin checks that the GitHub CLI has a token for host, and
+// reports whether it has
+func (d *doctor) checkCLILogin(host string) bool {
+	name := "GitHub CLI login (" + host + ")"
+	token, source := d.ghToken(host)
+	if token == "" {
+		d.add(name, doctorWarn, "not logged in", fmt.Sprintf("Run gh auth login --hostname %s, or set SOURCE_PAT and TARGET_PAT or GITHUB_TOKEN", host))
+		return false
+	}
+	d.add(name, doctorPass, "token found in "+tokenSourceLabel(source), "")
+	return true
+}
+
+// tokenSourceLabel names where go-gh found a token
+func tokenSourceLabel(source string) string {
+	switch source {
+	case "oauth_token":
+		return "the gh configuration"
+	case "gh":
+		return "the gh keyring"
+	}
+	return source
+}
+
+// checkTokenVariables lists the token variables that are set, masked, and
+// checks that both sides have a credential; cliLogin tells whether the
+// GitHub CLI login covers both sides' hosts
+func (d *doctor) checkTokenVariables(cliLogin bool) {
+	var set, unset []string
+	for _, key := range []string{"GITHUB_TOKEN", "GH_TOKEN", "SOURCE_PAT", "TARGET_PAT"} {
+		if v := d.getenv(key); v != "" {
+			set = append(set, key+"="+maskToken(v))
+		} else {
+			unset = append(unset, key)
+		}
+	}
+	detail := "none set"
+	if len(set) > 0 {
+		detail = strings.Join(set, ", ")
+		if len(unset) > 0 {
+			detail += "; not set: " + strings.Join(unset, ", ")
+		}
+	}
+
+	githubToken, sourcePAT, targetPAT := d.getenv("GITHUB_TOKEN"), d.getenv("SOURCE_PAT"), d.getenv("TARGET_PAT")
+	switch {
+	case githubToken == "" && (sourcePAT == "") != (targetPAT == ""):
+		d.add("Token variables", doctorFail, detail,
+			"A migration needs a token for both sides: set both SOURCE_PAT and TARGET_PAT, or GITHUB_TOKEN for the side without one")
+	case len(set) == 0 && !cliLogin:
+		d.add("Token variables", doctorFail, detail,
+			"Set SOURCE_PAT and TARGET_PAT or GITHUB_TOKEN, in the environment or a .env file, or log in with gh auth login")
+	case len(set) == 0:
+		d.add("Token variables", doctorPass, detail+"; the GitHub CLI login is used", "")
+	default:
+		d.add("Token variables", doctorPass, detail, "")
+	}
+}
+
+// tokenPrefixes are the type prefixes of GitHub tokens, which maskToken
+// keeps
+var tokenPrefixes = []string{"github_pat_", "ghp_", "gho_", "ghu_", "ghs_", "ghr_"}
+
+// maskToken hides a token but for its type prefix, such as ghp_ or
+// github_pat_, and gives its length
+func maskToken(token string) string {
+	prefix := ""
+	for _, p := range tokenPrefixes {
+		if strings.HasPrefix(token, p) {
+			prefix = p
+			break
+		}
+	}
+	return fmt.Sprintf("%s**** (%d chars)", prefix, len(token))
+}
+
+// checkReachable checks that the API of host resolves and completes a TLS
+// handshake, and reports whether it does
+func (d *doctor) checkReachable(host string) bool {
+	apiHost := host
+	if host == "github.com" {
+		apiHost = "api.github.com"
+	}
+	name := "Reach " + apiHost
+
+	addrs, err := d.lookupHost(apiHost)
+	if err != nil {
+		d.add(name, doctorFail, fmt.Sprintf("DNS lookup failed: %v", err),
+			fmt.Sprintf("Check the hostname, your DNS resolver, and any VPN that %s requires", apiHost))
+		return false
+	}
+	version, err := d.dialTLS(apiHost)
+	if err != nil {
+		d.add(name, doctorFail, fmt.Sprintf("resolves to %s, but the TLS handshake failed: %v", addrs[0], err),
+			fmt.Sprintf("Check that a firewall or proxy lets HTTPS through to %s, and that its certificate is trusted", apiHost))
+		return false
+	}
+	d.add(name, doctorPass, fmt.Sprintf("resolves to %s, %s handshake completed", addrs[0], version), "")
+	return true
+}
+
+// sides returns the credentials a migration would use, as resolveTokens
+// resolves them; sides with the same credential and host are checked once
+func (d *doctor) sides(sourceHostname, targetHostname string) []doctorSide {
+	githubToken := d.getenv("GITHUB_TOKEN")
+	side := func(name, hostname, pat, patName string) doctorSide {
+		s := doctorSide{name: name, hostname: hostname, patName: patName, label: credentialLabel(pat, githubToken, patName, hostname)}
+		switch {
+		case pat != "":
+			s.token = pat
+		case githubToken != "":
+			s.token = githubToken
+		default:
+			s.token, _ = d.ghToken(hostLabel(hostname))
+		}
+		return s
+	}
+	source := side("Source", sourceHostname, d.getenv("SOURCE_PAT"), "SOURCE_PAT")
+	target := side("Target", targetHostname, d.getenv("TARGET_PAT"), "TARGET_PAT")
+	if source.label == target.label && source.token == target.token && hostLabel(source.hostname) == hostLabel(target.hostname) {
+		source.name = "Source and target"
+		return []doctorSide{source}
+	}
+	return []doctorSide{source, target}
+}
+
+// checkRateLimit checks that the credential of side authenticates and has
+// core requests left, and returns its client when it authenticates
+func (d *doctor) checkRateLimit(side doctorSide) *client.Client {
+	name := side.name + " rate limit"
+	host := hostLabel(side.hostname)
+	if side.token == "" {
+		d.add(name, doctorFail, "no credential for "+host,
+			fmt.Sprintf("Set %s or GITHUB_TOKEN, or run gh auth login --hostname %s", side.patName, host))
+		return nil
+	}
+
+	c, err := d.newClient(side.token, side.hostname, strings.ToLower(side.name))
+	if err != nil {
+		d.add(name, doctorFail, err.Error(), "")
+		return nil
+	}
+	user, err := c.GetUser()
+	if err != nil {
+		d.add(name, doctorFail, fmt.Sprintf("%s does not authenticate against %s: %v", side.label, host, err),
+			fmt.Sprintf("Check that %s holds a valid, non-expired token for %s", side.label, host))
+		return nil
+	}
+
+	limits, err := c.GetRateLimits()
+	switch {
+	case client.IsNotFound(err):
+		d.add(name, doctorPass, fmt.Sprintf("%s authenticates as %s; rate limiting is disabled on %s", side.label, user, host), "")
+		return c
+	case err != nil:
+		d.add(name, doctorWarn, fmt.Sprintf("%s authenticates as %s, but the rate limit could not be read: %v", side.label, user, err), "")
+		return c
+	}
+
+	core := limits["core"]
+	detail := fmt.Sprintf("%s authenticates as %s; %d of %d core requests left", side.label, user, core.Remaining, core.Limit)
+	reset := core.ResetTime.Local().Format("15:04")
+	switch {
+	case core.Remaining == 0:
+		d.add(name, doctorFail, detail+", until "+reset, "Wait for the rate limit to reset at "+reset+", or use another token")
+	case core.Remaining*10 < core.Limit:
+		d.add(name, doctorWarn, detail+", reset at "+reset,
+			"A migration may pause for the rate limit; run it after "+reset+", or cap it with --max-api-calls")
+	default:
+		d.add(name, doctorPass, detail, "")
+	}
+	return c
+}
+
+// checkScopes checks the OAuth scopes of side's token against the scopes
+// of the repository and organization modes
+func (d *doctor) checkScopes(side doctorSide, c *client.Client) {
+	name := side.name + " token scopes"
+	scopes, err := c.GetTokenScopes()
+	if err != nil {
+		d.add(name, doctorFail, err.Error(), "")
+		return
+	}
+	if scopes == nil {
+		d.add(name, doctorPass, "no OAuth scopes reported (fine-grained token, app token, or GITHUB_TOKEN); permissions are checked when a migration runs", "")
+		return
+	}
+
+	role := strings.ToLower(side.name)
+	repoErr := client.ValidateRepoScopes(c, role)
+	orgErr := client.ValidateOrgScopes(c, role)
+	detail := strings.Join(scopes, ", ")
+	switch {
+	case repoErr == nil && orgErr == nil:
+		d.add(name, doctorPass, detail+"; every mode is allowed", "")
+	case repoErr == nil:
+		d.add(name, doctorWarn, detail+"; repository modes only", "Add the admin:org scope to migrate organization variables")
+	case orgErr == nil:
+		d.add(name, doctorWarn, detail+"; organization modes only", "Add the repo scope to migrate repository and environment variables")
+	default:
+		d.add(name, doctorFail, detail+"; no mode is allowed", "Create a token with the repo and admin:org scopes at https://github.com/settings/tokens")
+	}
+}
+
+// report writes one line per check to w, then the verdict and the hints of
+// the checks that warned or failed, and fails when any check failed
+func (d *doctor) report(w io.Writer) error {
+	failed, warned := 0, 0
+	var hints []string
+	for _, c := range d.checks {
+		mark := map[string]string{doctorPass: "✓", doctorWarn: "!", doctorFail: "✗", doctorSkip: "-"}[c.status]
+		fmt.Fprintf(w, "  %s %s: %s\n", mark, c.name, c.detail)
+		switch c.status {
+		case doctorFail:
+			failed++
+		case doctorWarn:
+			warned++
+		}
+		if c.hint != "" && (c.status == doctorFail || c.status == doctorWarn) {
+			hints = append(hints, c.hint)
+		}
+	}
+
+	fmt.Fprintln(w)
+	if len(hints) > 0 {
+		fmt.Fprintln(w, "Hints:")
+		for _, h := range hints {
+			fmt.Fprintf(w, "  - %s\n", h)
+		}
+		fmt.Fprintln(w)
+	}
+
+	switch {
+	case failed > 0:
+		return fmt.Errorf("%d of %d check(s) failed", failed, len(d.checks))
+	case warned > 0:
+		logger.Warning("No check failed, but %d warned", warned)
+	default:
+		logger.Success("All %d checks passed", len(d.checks))
+	}
+	return nil
+}
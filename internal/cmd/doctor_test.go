@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+)
+
+// fakeDoctor returns a doctor whose environment, GitHub CLI, network, and
+// API are fakes: env holds the variables, cliToken the GitHub CLI token of
+// every host, unreachable the API hosts whose lookup fails, and the API
+// reports scopes and remaining core requests
+func fakeDoctor(t *testing.T, env map[string]string, cliToken string, unreachable []string, scopes string, remaining int) *doctor {
+	t.Helper()
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{"Content-Type": []string{"application/json"}}
+		status, body := http.StatusOK, `{"login":"octocat"}`
+		auth := req.Header.Get("Authorization")
+		switch {
+		case strings.HasSuffix(auth, "bad-token"):
+			status, body = http.StatusUnauthorized, `{"message":"Bad credentials"}`
+		case strings.HasSuffix(req.URL.Path, "/rate_limit"):
+			body = fmt.Sprintf(`{"resources":{"core":{"limit":5000,"remaining":%d,"reset":1790000000}}}`, remaining)
+		case scopes != "":
+			header.Set("X-OAuth-Scopes", scopes)
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	return &doctor{
+		getenv:  func(key string) string { return env[key] },
+		ghToken: func(host string) (string, string) { return cliToken, "gh" },
+		lookupHost: func(host string) ([]string, error) {
+			for _, h := range unreachable {
+				if h == host {
+					return nil, fmt.Errorf("lookup %s: no such host", host)
+				}
+			}
+			return []string{"192.0.2.1"}, nil
+		},
+		dialTLS: func(host string) (string, error) { return "TLS 1.3", nil },
+		newClient: func(token, hostname, side string) (*client.Client, error) {
+			return client.NewWithTransport(token, hostLabel(hostname), rt)
+		},
+	}
+}
+
+// TestDoctor tests the status of each doctor check, that a failed check
+// skips those depending on it, and the verdict
+func TestDoctor(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		cliToken    string
+		unreachable []string
+		scopes      string
+		remaining   int
+		sourceHost  string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			name:      "all passing",
+			env:       map[string]string{"GITHUB_TOKEN": "ghp_0123456789abcdef"},
+			cliToken:  "gho_cli",
+			scopes:    "repo, admin:org",
+			remaining: 4990,
+			want: []string{
+				"✓ GitHub CLI login (github.com): token found in the gh keyring",
+				"✓ Token variables: GITHUB_TOKEN=ghp_**** (20 chars); not set: GH_TOKEN, SOURCE_PAT, TARGET_PAT",
+				"✓ Reach api.github.com: resolves to 192.0.2.1, TLS 1.3 handshake completed",
+				"✓ Source and target rate limit: GITHUB_TOKEN authenticates as octocat; 4990 of 5000 core requests left",
+				"✓ Source and target token scopes: repo, admin:org; every mode is allowed",
+			},
+		},
+		{
+			name:      "one PAT without GITHUB_TOKEN",
+			env:       map[string]string{"SOURCE_PAT": "github_pat_secret"},
+			scopes:    "repo",
+			remaining: 4990,
+			want: []string{
+				"! GitHub CLI login (github.com): not logged in",
+				"✗ Token variables: SOURCE_PAT=github_pat_**** (17 chars)",
+				"✗ Target rate limit: no credential for github.com",
+				"- Target token scopes: the credential does not authenticate",
+				"! Source token scopes: repo; repository modes only",
+				"Set TARGET_PAT or GITHUB_TOKEN, or run gh auth login --hostname github.com",
+			},
+			wantErr: true,
+		},
+		{
+			name:        "unreachable enterprise host",
+			env:         map[string]string{"GITHUB_TOKEN": "ghp_x"},
+			unreachable: []string{"github.example.com"},
+			remaining:   4990,
+			sourceHost:  "github.example.com",
+			want: []string{
+				"✗ Reach github.example.com: DNS lookup failed: lookup github.example.com: no such host",
+				"- Source rate limit: github.example.com is not reachable",
+				"✓ Target rate limit: GITHUB_TOKEN authenticates as octocat",
+				"✓ Target token scopes: no OAuth scopes reported",
+				"Check the hostname, your DNS resolver",
+			},
+			wantErr: true,
+		},
+		{
+			name:      "rejected token and exhausted rate limit",
+			env:       map[string]string{"SOURCE_PAT": "bad-token", "TARGET_PAT": "ghp_target"},
+			remaining: 0,
+			want: []string{
+				"✗ Source rate limit: SOURCE_PAT does not authenticate against github.com",
+				"- Source token scopes: the credential does not authenticate",
+				"✗ Target rate limit: TARGET_PAT authenticates as octocat; 0 of 5000 core requests left, until",
+				"Check that SOURCE_PAT holds a valid, non-expired token for github.com",
+			},
+			wantErr: true,
+		},
+		{
+			name:      "low rate limit only warns",
+			cliToken:  "gho_cli",
+			remaining: 100,
+			want: []string{
+				"✓ Token variables: none set; the GitHub CLI login is used",
+				"! Source and target rate limit: GitHub CLI (github.com) authenticates as octocat; 100 of 5000 core requests left, reset at",
+				"cap it with --max-api-calls",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := fakeDoctor(t, tt.env, tt.cliToken, tt.unreachable, tt.scopes, tt.remaining)
+			d.run(tt.sourceHost, "")
+			var out strings.Builder
+			err := d.report(&out)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("report() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Expected the output to contain %q, got:\n%s", want, out.String())
+				}
+			}
+			for _, v := range tt.env {
+				if len(v) > 6 && strings.Contains(out.String(), v) {
+					t.Errorf("Expected the token %q to be masked, got:\n%s", v, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// envsCmd lists the environments of a repository with their variable counts
+var envsCmd = &cobra.Command{
+	Use:   "envs --repo OWNER/REPO",
+	Short: "List the environments of a repository with their variable counts",
+	Long: `List the environments of a repository (--repo OWNER/REPO, or --owner OWNER
+--repo REPO) with the number of variables each holds, when it was created, and
+when it was last updated, followed by the names of its variables.
+
+--counts leaves the variable names out and only reads each environment's
+count, which is faster for repositories with many environments or variables.
+
+--output json writes a JSON array of {name, variable_count, created_at,
+updated_at, variables} objects to standard output instead of the table, and
+--output csv the same columns as CSV with the variable names separated by
+spaces; every other message then goes to standard error. variables is left
+out with --counts. Authentication works as for list.`,
+	Example: `  # See the environments of a repository before choosing --envs
+  gh vars-migrator envs --repo renan-org/app
+
+  # Only the counts, as JSON
+  gh vars-migrator envs --repo renan-org/app --counts --output json`,
+	RunE:    runEnvs,
+	PreRunE: validateEnvsFlags,
+}
+
+var (
+	envsOwner    string
+	envsRepo     string
+	envsCounts   bool
+	envsPAT      string
+	envsHostname string
+)
+
+func init() {
+	rootCmd.AddCommand(envsCmd)
+	envsCmd.Flags().StringVar(&envsRepo, "repo", "", "Repository, as OWNER/REPO or as REPO with --owner (required)")
+	envsCmd.Flags().StringVar(&envsOwner, "owner", "", "Owner of --repo")
+	envsCmd.Flags().BoolVar(&envsCounts, "counts", false, "Only count the variables of each environment, without listing their names")
+	envsCmd.Flags().StringVar(&envsPAT, "pat", "", "Personal access token; overrides GITHUB_TOKEN")
+	envsCmd.Flags().StringVar(&envsHostname, "hostname", "", "GitHub hostname for GitHub Enterprise Server (default: github.com)")
+	supportOutput(envsCmd, output.JSON, output.CSV)
+}
+
+// validateEnvsFlags checks the repository flags, and splits an OWNER/REPO
+// --repo into envsOwner and envsRepo
+func validateEnvsFlags(cmd *cobra.Command, args []string) error {
+	if envsRepo == "" {
+		return fmt.Errorf("--repo flag is required")
+	}
+	owner, repo, err := resolveRepoFlag(envsOwner, envsRepo)
+	if err != nil {
+		return err
+	}
+	envsOwner, envsRepo = owner, repo
+
+	cmd.SilenceUsage = true
+	envsHostname = normalizeHostname(envsHostname)
+	return nil
+}
+
+func runEnvs(cmd *cobra.Command, args []string) error {
+	c, err := patClient(envsPAT, envsHostname, "envs")
+	if err != nil {
+		return authError(err)
+	}
+	return listEnvironments(c, cmd.OutOrStdout())
+}
+
+// environmentSummary is one environment of the envs output. Variables is
+// nil with --counts.
+type environmentSummary struct {
+	Name          string    `json:"name"`
+	VariableCount int       `json:"variable_count"`
+	CreatedAt     string    `json:"created_at"`
+	UpdatedAt     string    `json:"updated_at"`
+	Variables     *[]string `json:"variables,omitempty"`
+}
+
+// listEnvironments lists the environments of the repository in name order,
+// writing the table or the JSON document to w
+func listEnvironments(c *client.Client, w io.Writer) error {
+	logger.Info("Listing environments for repository: %s/%s", envsOwner, envsRepo)
+	logger.Plain("")
+
+	envs, err := c.ListEnvironments(envsOwner, envsRepo)
+	if err != nil {
+		return err
+	}
+	sort.Slice(envs, func(i, j int) bool { return strings.ToLower(envs[i].Name) < strings.ToLower(envs[j].Name) })
+
+	summaries := make([]environmentSummary, 0, len(envs))
+	for _, env := range envs {
+		s := environmentSummary{Name: env.Name, CreatedAt: env.CreatedAt, UpdatedAt: env.UpdatedAt}
+		if envsCounts {
+			if s.VariableCount, err = c.CountEnvVariables(envsOwner, envsRepo, env.Name); err != nil {
+				return err
+			}
+		} else {
+			vars, err := c.ListEnvVariables(envsOwner, envsRepo, env.Name)
+			if err != nil {
+				return err
+			}
+			names := make([]string, 0, len(vars))
+			for _, v := range vars {
+				names = append(names, v.Name)
+			}
+			s.VariableCount, s.Variables = len(names), &names
+		}
+		summaries = append(summaries, s)
+	}
+
+	switch outputFormat {
+	case output.JSON:
+		return output.WriteJSON(w, summaries)
+	case output.CSV:
+		return writeEnvironmentCSV(w, summaries)
+	}
+	if len(summaries) == 0 {
+		logger.Warning("No environments found in repository '%s/%s'", envsOwner, envsRepo)
+		return nil
+	}
+
+	writeEnvironmentTable(w, summaries)
+	logger.Plain("")
+	logger.Success("Total: %d environment(s)", len(summaries))
+	return nil
+}
+
+// writeEnvironmentCSV writes one row per environment; the variables column
+// holds the names separated by spaces, and is empty with --counts
+func writeEnvironmentCSV(w io.Writer, summaries []environmentSummary) error {
+	rows := make([][]string, 0, len(summaries))
+	for _, s := range summaries {
+		var names string
+		if s.Variables != nil {
+			names = strings.Join(*s.Variables, " ")
+		}
+		rows = append(rows, []string{s.Name, strconv.Itoa(s.VariableCount), s.CreatedAt, s.UpdatedAt, names})
+	}
+	return output.WriteCSV(w, []string{"name", "variable_count", "created_at", "updated_at", "variables"}, rows)
+}
+
+// writeEnvironmentTable writes one row per environment, each followed by
+// the indented names of its variables unless only counts were read
+func writeEnvironmentTable(w io.Writer, summaries []environmentSummary) {
+	fmt.Fprintf(w, "%-30s %-10s %-21s %s\n", "NAME", "VARIABLES", "CREATED AT", "UPDATED AT")
+	fmt.Fprintf(w, "%-30s %-10s %-21s %s\n", "----", "---------", "----------", "----------")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%-30s %-10d %-21s %s\n", s.Name, s.VariableCount, s.CreatedAt, s.UpdatedAt)
+		if s.Variables != nil {
+			for _, name := range *s.Variables {
+				fmt.Fprintf(w, "  %s\n", name)
+			}
+		}
+	}
+}
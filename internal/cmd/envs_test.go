@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/spf13/cobra"
+)
+
+func TestValidateEnvsFlags(t *testing.T) {
+	origOwner, origRepo, origOutput := envsOwner, envsRepo, outputFormat
+	defer func() { envsOwner, envsRepo, outputFormat = origOwner, origRepo, origOutput }()
+
+	tests := []struct {
+		name      string
+		owner     string
+		repo      string
+		output    string
+		wantOwner string
+		wantErr   string
+	}{
+		{name: "owner/repo", repo: "acme/app", output: "table", wantOwner: "acme"},
+		{name: "owner and repo", owner: "acme", repo: "app", output: "json", wantOwner: "acme"},
+		{name: "missing repo", output: "table", wantErr: "--repo flag is required"},
+		{name: "repo without owner", repo: "app", output: "table", wantErr: "--repo app needs an owner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envsOwner, envsRepo, outputFormat = tt.owner, tt.repo, tt.output
+
+			err := validateEnvsFlags(&cobra.Command{Use: "envs"}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("validateEnvsFlags() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateEnvsFlags() unexpected error: %v", err)
+			}
+			if envsOwner != tt.wantOwner || envsRepo != "app" {
+				t.Errorf("Resolved %q/%q, want %q/app", envsOwner, envsRepo, tt.wantOwner)
+			}
+		})
+	}
+}
+
+func TestListEnvironments(t *testing.T) {
+	origOwner, origRepo, origOutput, origCounts := envsOwner, envsRepo, outputFormat, envsCounts
+	defer func() { envsOwner, envsRepo, outputFormat, envsCounts = origOwner, origRepo, origOutput, origCounts }()
+	envsOwner = "acme"
+
+	c := fakeAPIClient(t, map[string]fakeResponse{
+		"repos/acme/app/environments": {http.StatusOK, `{"total_count":2,"environments":[
+			{"name":"staging","created_at":"2024-01-01T00:00:00Z","updated_at":"2024-03-01T00:00:00Z"},
+			{"name":"empty","created_at":"2024-02-01T00:00:00Z","updated_at":"2024-02-01T00:00:00Z"}]}`},
+		"repos/acme/app/environments/staging/variables": {http.StatusOK, `{"total_count":2,"variables":[{"name":"URL"},{"name":"REPLICAS"}]}`},
+		"repos/acme/app/environments/empty/variables":   {http.StatusOK, `{"total_count":0,"variables":[]}`},
+		"repos/acme/none/environments":                  {http.StatusOK, `{"total_count":0,"environments":[]}`},
+	})
+
+	t.Run("table", func(t *testing.T) {
+		envsRepo, outputFormat, envsCounts = "app", output.Table, false
+		var b strings.Builder
+		if err := listEnvironments(c, &b); err != nil {
+			t.Fatalf("listEnvironments() unexpected error: %v", err)
+		}
+		want := "NAME                           VARIABLES  CREATED AT            UPDATED AT\n" +
+			"----                           ---------  ----------            ----------\n" +
+			"empty                          0          2024-02-01T00:00:00Z  2024-02-01T00:00:00Z\n" +
+			"staging                        2          2024-01-01T00:00:00Z  2024-03-01T00:00:00Z\n" +
+			"  URL\n" +
+			"  REPLICAS\n"
+		if b.String() != want {
+			t.Errorf("listEnvironments() wrote\n%s\nwant\n%s", b.String(), want)
+		}
+	})
+
+	jsonTests := []struct {
+		name   string
+		repo   string
+		counts bool
+		want   string
+	}{
+		{
+			name: "json",
+			repo: "app",
+			want: `[{"name":"empty","variable_count":0,"created_at":"2024-02-01T00:00:00Z","updated_at":"2024-02-01T00:00:00Z","variables":[]},` +
+				`{"name":"staging","variable_count":2,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-03-01T00:00:00Z","variables":["URL","REPLICAS"]}]`,
+		},
+		{
+			name:   "json counts",
+			repo:   "app",
+			counts: true,
+			want: `[{"name":"empty","variable_count":0,"created_at":"2024-02-01T00:00:00Z","updated_at":"2024-02-01T00:00:00Z"},` +
+				`{"name":"staging","variable_count":2,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-03-01T00:00:00Z"}]`,
+		},
+		{name: "no environments", repo: "none", want: `[]`},
+	}
+	for _, tt := range jsonTests {
+		t.Run(tt.name, func(t *testing.T) {
+			envsRepo, outputFormat, envsCounts = tt.repo, output.JSON, tt.counts
+			var b strings.Builder
+			if err := listEnvironments(c, &b); err != nil {
+				t.Fatalf("listEnvironments() unexpected error: %v", err)
+			}
+			var got, want any
+			if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+				t.Fatalf("Output is not JSON: %v\n%s", err, b.String())
+			}
+			_ = json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("listEnvironments() =\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("no environments in a table", func(t *testing.T) {
+		envsRepo, outputFormat, envsCounts = "none", output.Table, false
+		var b strings.Builder
+		if err := listEnvironments(c, &b); err != nil {
+			t.Fatalf("listEnvironments() unexpected error: %v", err)
+		}
+		if b.String() != "" {
+			t.Errorf("Expected no table, got %q", b.String())
+		}
+	})
+}
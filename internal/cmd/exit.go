@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+)
+
+// Process exit codes. Every error not classified otherwise, including usage
+// and validation errors, exits with exitCodeUsage.
+const (
+	// exitCodeUsage is returned for invalid flags or configuration and for
+	// failures that stop the run before or during migration
+	exitCodeUsage = 1
+	// exitCodeAuth is returned when a token is missing, invalid, or lacks
+	// the permissions the migration needs
+	exitCodeAuth = 2
+	// exitCodePartial is returned when the migration ran to the end but some
+	// variables or repositories failed
+	exitCodePartial = 3
+	// exitCodeAborted is returned when the run was stopped at an interactive
+	// prompt or by a signal
+	exitCodeAborted = 4
+	// exitCodeErrorLimit is returned when the run was stopped because
+	// --max-errors errors had been recorded
+	exitCodeErrorLimit = 5
+	// exitCodeHook is returned when the migration succeeded but the
+	// --post-hook command failed
+	exitCodeHook = 6
+	// exitCodeEmpty is returned when --fail-if-empty is set and no source
+	// variable was left to migrate after filtering
+	exitCodeEmpty = 7
+	// exitCodeBudget is returned when the run was stopped because
+	// --max-api-calls requests had been made
+	exitCodeBudget = 8
+)
+
+// exitCodeDiff is returned by --diff when source and target differ, and by a
+// --dry-run with pending changes when --exit-code-on-diff is set
+const exitCodeDiff = 2
+
+// exitCodeInterrupted is the conventional exit code after SIGINT; it is used
+// when an interrupted run does not stop in time, or is interrupted again
+const exitCodeInterrupted = 130
+
+// exitError carries a specific process exit code out of a command. When err
+// is nil the process exits with the code without printing an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// authError marks err as an authentication or permission failure
+func authError(err error) error {
+	return &exitError{code: exitCodeAuth, err: err}
+}
+
+// exitCode maps an error returned by a command to the process exit code.
+// API responses rejecting a token are authentication failures wherever they
+// occur.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	if client.IsAuthError(err) {
+		return exitCodeAuth
+	}
+	return exitCodeUsage
+}
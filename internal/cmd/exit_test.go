@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// TestExitCode tests the classification of errors returned by the command
+func TestExitCode(t *testing.T) {
+	unauthorized := &api.HTTPError{StatusCode: 401, Message: "Bad credentials"}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", err: nil, want: 0},
+		{name: "usage error", err: errors.New("--source-org flag is required"), want: exitCodeUsage},
+		{name: "hard failure", err: fmt.Errorf("migration failed: %w", errors.New("connection refused")), want: exitCodeUsage},
+		{name: "authentication failure", err: authError(errors.New("source authentication failed")), want: exitCodeAuth},
+		{name: "rejected token during run", err: fmt.Errorf("migration failed: %w", unauthorized), want: exitCodeAuth},
+		{name: "partial errors", err: &exitError{code: exitCodePartial, err: errors.New("migration completed with 1 error(s)")}, want: exitCodePartial},
+		{name: "aborted", err: &exitError{code: exitCodeAborted, err: errors.New("migration aborted at user request")}, want: exitCodeAborted},
+		{name: "diff", err: &exitError{code: exitCodeDiff}, want: exitCodeDiff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
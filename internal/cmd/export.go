@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/dump"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/manifest"
+	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/renan-alm/gh-vars-migrator/internal/terraform"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd writes the current variables of an organization, repository, or
+// environment to a file: a dump for backups and import, or a manifest that
+// apply --manifest accepts
+var exportCmd = &cobra.Command{
+	Use:   "export (--org ORG [--repo REPO] | -R OWNER[/REPO]) [--env ENV | --with-envs]",
+	Short: "Export variables to JSON, YAML, .env, or CSV, or to a manifest",
+	Long: `Export the current Actions variables of an organization, of one of its
+repositories (--repo), or of an environment of that repository (--env).
+-R OWNER or -R OWNER/REPO names the organization and repository at once,
+like the GitHub CLI, and so does their URL, whose host sets --hostname;
+--org and --repo win over it.
+
+The variables are written with their names, values, visibility, and last
+update time in the --format json, yaml, env, or csv, to --output or to
+standard output. Values are masked unless --include-values is set; the JSON
+and YAML files written with values are what the import command reads.
+--with-envs adds the variables of every environment of the repository.
+
+--format terraform writes the variables as resources of the Terraform GitHub
+provider instead: github_actions_organization_variable,
+github_actions_variable, and github_actions_environment_variable blocks named
+after the variables, with github_repository data sources for the selected
+repositories of organization variables. Each value is a reference to an input
+variable of the same name, declared in the output, unless --inline-values
+writes the values themselves. --module-prefix starts every name with a prefix.
+
+With --manifest, the organization, or the repository together with its
+environments, is written as a YAML manifest instead. Applying it with apply
+--manifest to the same target changes nothing, so it can be kept under
+version control as the desired state.
+
+Files are written with owner-only permissions. The GITHUB_TOKEN environment
+variable is used when set, otherwise the GitHub CLI authentication.
+
+The --output flag of export names the file to write, in place of the global
+--output format; the dump is always machine-readable in its --format.`,
+	Example: `  # Review organization variables without their values
+  gh vars-migrator export --org myorg --format yaml
+
+  # Back up a repository and its environments
+  gh vars-migrator export -R myorg/app --with-envs --include-values --output app.json
+
+  # Render a repository and its environments as Terraform resources
+  gh vars-migrator export --org myorg --repo app --with-envs --format terraform --output variables.tf
+
+  # Export one environment as a .env file
+  gh vars-migrator export --org myorg --repo app --env production --format env --include-values --output production.env
+
+  # Export a repository with its environments to a manifest, from GitHub Enterprise Server
+  gh vars-migrator export --manifest app.yaml --org myorg --repo app --hostname github.example.com`,
+	PreRunE:       validateExportFlags,
+	RunE:          runExport,
+	SilenceErrors: true,
+}
+
+var (
+	exportManifest      string
+	exportOrg           string
+	exportScope         string
+	exportRepo          string
+	exportEnv           string
+	exportWithEnvs      bool
+	exportFormat        string
+	exportOutput        string
+	exportIncludeValues bool
+	exportHostname      string
+	exportModulePrefix  string
+	exportInlineValues  bool
+)
+
+// exportFormats are the --format values of export: the dump formats and
+// Terraform
+var exportFormats = append(slices.Clone(dump.Formats), terraform.Format)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportManifest, "manifest", "", "Write a manifest for apply --manifest to this file")
+	exportCmd.Flags().StringVarP(&exportOrg, "org", "o", "", "Organization to export (required)")
+	exportCmd.Flags().StringVarP(&exportScope, "scope", "R", "", "Organization or repository to export, as OWNER, OWNER/REPO, or a GitHub URL; --org and --repo win over it")
+	exportCmd.Flags().StringVar(&exportRepo, "repo", "", "Export this repository of the organization instead of the organization variables")
+	exportCmd.Flags().StringVar(&exportEnv, "env", "", "Export this environment of --repo")
+	exportCmd.Flags().BoolVar(&exportWithEnvs, "with-envs", false, "Also export the variables of every environment of --repo")
+	exportCmd.Flags().StringVar(&exportFormat, "format", dump.FormatJSON, "Output format: "+strings.Join(exportFormats, ", "))
+	exportCmd.Flags().StringVar(&exportOutput, "output", "", "File to write (default: standard output)")
+	exportCmd.Flags().BoolVar(&exportIncludeValues, "include-values", false, "Write variable values instead of masking them")
+	exportCmd.Flags().StringVar(&exportHostname, "hostname", "", "GitHub hostname for GitHub Enterprise Server (default: github.com)")
+	exportCmd.Flags().StringVar(&exportModulePrefix, "module-prefix", "", "Prefix of the resource and input variable names of --format terraform")
+	exportCmd.Flags().BoolVar(&exportInlineValues, "inline-values", false, "Write the values into the resources of --format terraform instead of input variable references")
+	registerCompletion(exportCmd, "format", fixedCompletion(exportFormats...))
+}
+
+// validateExportFlags checks the export flags; --manifest writes a complete
+// organization or repository with values, so the dump options do not apply
+func validateExportFlags(cmd *cobra.Command, args []string) error {
+	if exportScope != "" {
+		r, err := parseRef("scope", exportScope)
+		if err != nil {
+			return err
+		}
+		if exportOrg == "" {
+			exportOrg = r.owner
+		}
+		if exportRepo == "" {
+			exportRepo = r.repo
+		}
+		if _, err := applyRefHost("scope", "hostname", r, &exportHostname); err != nil {
+			return err
+		}
+	}
+	if exportOrg == "" {
+		return fmt.Errorf("--org flag is required")
+	}
+	if exportManifest != "" {
+		for _, name := range []string{"env", "with-envs", "format", "output", "include-values"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("--%s cannot be combined with --manifest", name)
+			}
+		}
+	}
+	if exportFormat == terraform.Format {
+		if cmd.Flags().Changed("include-values") {
+			return fmt.Errorf("--include-values does not apply to --format terraform; use --inline-values to write the values")
+		}
+	} else {
+		for _, name := range []string{"module-prefix", "inline-values"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("--%s requires --format terraform", name)
+			}
+		}
+	}
+	switch {
+	case !slices.Contains(exportFormats, exportFormat):
+		return fmt.Errorf("invalid --format %q: must be one of %s", exportFormat, strings.Join(exportFormats, ", "))
+	case exportEnv != "" && exportRepo == "":
+		return fmt.Errorf("--env requires --repo")
+	case exportWithEnvs && exportRepo == "":
+		return fmt.Errorf("--with-envs requires --repo")
+	case exportWithEnvs && exportEnv != "":
+		return fmt.Errorf("--with-envs cannot be combined with --env")
+	}
+	cmd.SilenceUsage = true
+	exportHostname = normalizeHostname(exportHostname)
+	return nil
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	c, err := createClientWithToken(os.Getenv("GITHUB_TOKEN"), exportHostname, "export")
+	if err != nil {
+		return authError(err)
+	}
+
+	if exportManifest != "" {
+		scopes, err := migrator.Export(c, exportOrg, exportRepo)
+		if err != nil {
+			return fmt.Errorf("export failed: %w", err)
+		}
+
+		m := manifest.FromDesired(scopes)
+		if err := manifest.Save(exportManifest, m); err != nil {
+			return fmt.Errorf("export failed: %w", err)
+		}
+
+		count := 0
+		for _, s := range m.Scopes {
+			count += len(s.Variables)
+		}
+		logger.Success("Exported %d variable(s) in %d scope(s) to %s", count, len(m.Scopes), exportManifest)
+		return nil
+	}
+
+	d, err := migrator.ExportDump(c, exportOrg, exportRepo, exportEnv, exportWithEnvs)
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	if !exportIncludeValues && !exportInlineValues {
+		d.Mask()
+	}
+	write := func(w io.Writer) error { return dump.Write(w, d, exportFormat) }
+	if exportFormat == terraform.Format {
+		opts := terraform.Options{ModulePrefix: exportModulePrefix, InlineValues: exportInlineValues}
+		write = func(w io.Writer) error { return terraform.Write(w, d, opts) }
+	}
+
+	// Without --output the dump is the only thing written to standard
+	// output, so it can be piped
+	if exportOutput == "" {
+		return write(cmd.OutOrStdout())
+	}
+	// Written with owner-only permissions, as dump.Save does, because the
+	// file may contain variable values
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	if err := os.WriteFile(exportOutput, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("export failed: writing %s: %w", exportOutput, err)
+	}
+	logger.Success("Exported %d variable(s) to %s", d.Count(), exportOutput)
+	switch {
+	case exportFormat == terraform.Format && !exportInlineValues:
+		logger.Info("Values are input variables; use --inline-values to write them")
+	case d.ValuesMasked:
+		logger.Info("Values are masked; use --include-values to write them")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestValidateExportFlags(t *testing.T) {
+	origOrg, origRepo, origEnv, origWithEnvs := exportOrg, exportRepo, exportEnv, exportWithEnvs
+	origManifest, origFormat, origHostname, origScope := exportManifest, exportFormat, exportHostname, exportScope
+	defer func() {
+		exportOrg, exportRepo, exportEnv, exportWithEnvs = origOrg, origRepo, origEnv, origWithEnvs
+		exportManifest, exportFormat, exportHostname, exportScope = origManifest, origFormat, origHostname, origScope
+	}()
+
+	tests := []struct {
+		name     string
+		org      string
+		repo     string
+		scope    string
+		hostname string
+		wantOrg  string
+		wantRepo string
+		wantHost string
+		env      string
+		withEnvs bool
+		manifest string
+		format   string
+		flag     string
+		wantErr  string
+	}{
+		{name: "organization", org: "acme", format: "json"},
+		{name: "environment", org: "acme", repo: "app", env: "production", format: "env"},
+		{name: "repository with environments", org: "acme", repo: "app", withEnvs: true, format: "csv"},
+		{name: "manifest", org: "acme", repo: "app", manifest: "vars.yaml", format: "json"},
+		{name: "missing org", format: "json", wantErr: "--org flag is required"},
+		{name: "invalid format", org: "acme", format: "xml", wantErr: `invalid --format "xml"`},
+		{name: "env without repo", org: "acme", env: "production", format: "json", wantErr: "--env requires --repo"},
+		{name: "with-envs without repo", org: "acme", withEnvs: true, format: "json", wantErr: "--with-envs requires --repo"},
+		{name: "with-envs and env", org: "acme", repo: "app", env: "production", withEnvs: true, format: "json", wantErr: "--with-envs cannot be combined with --env"},
+		{name: "manifest and format", org: "acme", manifest: "vars.yaml", format: "json", flag: "format", wantErr: "--format cannot be combined with --manifest"},
+		{name: "manifest and include-values", org: "acme", manifest: "vars.yaml", format: "json", flag: "include-values", wantErr: "--include-values cannot be combined with --manifest"},
+		{name: "terraform", org: "acme", repo: "app", withEnvs: true, format: "terraform", flag: "module-prefix"},
+		{name: "terraform and include-values", org: "acme", format: "terraform", flag: "include-values", wantErr: "use --inline-values to write the values"},
+		{name: "-R organization", scope: "acme", format: "json", wantOrg: "acme"},
+		{name: "-R repository", scope: "acme/app", withEnvs: true, format: "json", wantOrg: "acme", wantRepo: "app"},
+		{name: "--repo wins over -R", scope: "acme/app", repo: "api", format: "json", wantOrg: "acme", wantRepo: "api"},
+		{name: "--org wins over -R", scope: "acme", org: "corp", format: "json", wantOrg: "corp"},
+		{name: "-R URL", scope: "https://ghes.corp.example/acme/app/settings/variables/actions", format: "json", wantOrg: "acme", wantRepo: "app", wantHost: "ghes.corp.example"},
+		{name: "-R URL on another host", scope: "https://github.com/acme/app", hostname: "ghes.corp.example", format: "json", wantErr: "--scope is on github.com, but --hostname is ghes.corp.example"},
+		{name: "malformed -R", scope: "acme/", format: "json", wantErr: `invalid --scope "acme/"`},
+		{name: "env with -R organization", scope: "acme", env: "production", format: "json", wantErr: "--env requires --repo"},
+		{name: "inline-values without terraform", org: "acme", format: "json", flag: "inline-values", wantErr: "--inline-values requires --format terraform"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exportOrg, exportRepo, exportEnv, exportWithEnvs = tt.org, tt.repo, tt.env, tt.withEnvs
+			exportManifest, exportFormat, exportScope = tt.manifest, tt.format, tt.scope
+			exportHostname = tt.hostname
+
+			// A throwaway command, so that setting a flag leaves exportCmd alone
+			cmd := &cobra.Command{Use: "export"}
+			if tt.flag != "" {
+				cmd.Flags().String(tt.flag, "", "")
+				if err := cmd.Flags().Set(tt.flag, "x"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := validateExportFlags(cmd, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateExportFlags() unexpected error: %v", err)
+				}
+				if tt.wantOrg != "" && (exportOrg != tt.wantOrg || exportRepo != tt.wantRepo) {
+					t.Errorf("Resolved %q/%q, want %q/%q", exportOrg, exportRepo, tt.wantOrg, tt.wantRepo)
+				}
+				if tt.wantOrg != "" && exportHostname != tt.wantHost {
+					t.Errorf("Resolved the hostname %q, want %q", exportHostname, tt.wantHost)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateExportFlags() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// getCmd prints the value of one variable
+var getCmd = &cobra.Command{
+	Use:   "get (--org ORG | --repo OWNER/REPO [--env ENV]) --name NAME",
+	Short: "Print the value of a variable",
+	Long: `Print the value of one variable of an organization (--org), a repository
+(--repo OWNER/REPO, or --owner OWNER --repo REPO), or an environment of that
+repository (--env).
+
+Only the value and a newline are written to standard output, so it can be
+captured by a script; every other message goes to standard error. --output
+json writes a {name, updated_at, scope, value} object instead, and --output
+csv the same columns as CSV. The command
+exits 1 when the variable does not exist. Authentication works as for list.`,
+	Example: `  # Read back a value after a migration
+  gh vars-migrator get --repo renan-org/app --name API_URL
+
+  # Read an environment variable as JSON
+  gh vars-migrator get --repo renan-org/app --env production --name API_URL --output json`,
+	RunE:    runGet,
+	PreRunE: validateGetFlags,
+}
+
+var (
+	getOrg      string
+	getOwner    string
+	getRepo     string
+	getEnv      string
+	getName     string
+	getPAT      string
+	getHostname string
+)
+
+// getScope is the scope resolved from the get flags during validation
+var getScope types.DesiredScope
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+	getCmd.Flags().StringVarP(&getOrg, "org", "o", "", "Organization of the variable")
+	getCmd.Flags().StringVar(&getRepo, "repo", "", "Repository of the variable, as OWNER/REPO or as REPO with --owner")
+	getCmd.Flags().StringVar(&getOwner, "owner", "", "Owner of --repo")
+	getCmd.Flags().StringVar(&getEnv, "env", "", "Environment of --repo holding the variable")
+	getCmd.Flags().StringVar(&getName, "name", "", "Name of the variable (required)")
+	getCmd.Flags().StringVar(&getPAT, "pat", "", "Personal access token; overrides GITHUB_TOKEN")
+	getCmd.Flags().StringVar(&getHostname, "hostname", "", "GitHub hostname for GitHub Enterprise Server (default: github.com)")
+	registerCompletion(getCmd, "env", environmentCompletion(&getOwner, &getRepo, &getPAT, &getHostname))
+	supportOutput(getCmd, output.JSON, output.CSV)
+}
+
+// validateGetFlags checks the scope and name flags
+func validateGetFlags(cmd *cobra.Command, args []string) error {
+	if err := config.ValidateVariableName("--name", getName); err != nil {
+		return err
+	}
+	scope, err := variableScope(getOrg, getOwner, getRepo, getEnv)
+	if err != nil {
+		return err
+	}
+	getScope = scope
+
+	cmd.SilenceUsage = true
+	getHostname = normalizeHostname(getHostname)
+	return nil
+}
+
+// variableScope resolves the --org, --owner, --repo, and --env flags of the
+// single-variable commands into the scope they select
+func variableScope(org, owner, repo, env string) (types.DesiredScope, error) {
+	switch {
+	case org == "" && repo == "":
+		return types.DesiredScope{}, fmt.Errorf("--org or --repo flag is required")
+	case org != "" && repo != "":
+		return types.DesiredScope{}, fmt.Errorf("--org and --repo cannot be combined")
+	case owner != "" && repo == "":
+		return types.DesiredScope{}, fmt.Errorf("--owner requires --repo")
+	case env != "" && repo == "":
+		return types.DesiredScope{}, fmt.Errorf("--env requires --repo")
+	}
+	if org != "" {
+		return types.DesiredScope{Org: org}, nil
+	}
+	owner, repo, err := resolveRepoFlag(owner, repo)
+	if err != nil {
+		return types.DesiredScope{}, err
+	}
+	return types.DesiredScope{Owner: owner, Repo: repo, Environment: env}, nil
+}
+
+func runGet(cmd *cobra.Command, args []string) error {
+	// Standard output only carries the value
+	logger.UseStderr(true)
+	defer logger.UseStderr(false)
+
+	c, err := patClient(getPAT, getHostname, "get")
+	if err != nil {
+		return authError(err)
+	}
+	return getVariable(c, cmd.OutOrStdout())
+}
+
+// getVariable writes the value of the variable, or its JSON object, to w
+func getVariable(c *client.Client, w io.Writer) error {
+	v, err := scopeVariable(c, getScope, getName)
+	if client.IsNotFound(err) {
+		return fmt.Errorf("variable %s not found in %s", getName, getScope.Label())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read variable %s of %s: %w", getName, getScope.Label(), err)
+	}
+
+	switch outputFormat {
+	case output.JSON:
+		value := v.Value
+		return output.WriteJSON(w, listEntry{Name: v.Name, UpdatedAt: v.UpdatedAt, Scope: getScope.Label(), Value: &value})
+	case output.CSV:
+		return output.WriteCSV(w, []string{"name", "updated_at", "scope", "value"}, [][]string{{v.Name, v.UpdatedAt, getScope.Label(), v.Value}})
+	}
+	_, err = fmt.Fprintln(w, v.Value)
+	return err
+}
+
+// scopeVariable reads one variable of an organization, repository, or
+// environment
+func scopeVariable(c *client.Client, scope types.DesiredScope, name string) (*types.Variable, error) {
+	switch {
+	case scope.Org != "":
+		return c.GetOrgVariable(scope.Org, name)
+	case scope.Environment != "":
+		return c.GetEnvVariable(scope.Owner, scope.Repo, scope.Environment, name)
+	default:
+		return c.GetRepoVariable(scope.Owner, scope.Repo, name)
+	}
+}
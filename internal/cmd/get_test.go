@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func TestVariableScope(t *testing.T) {
+	tests := []struct {
+		name                  string
+		org, owner, repo, env string
+		want                  string
+		wantErr               string
+	}{
+		{name: "organization", org: "acme", want: "org:acme"},
+		{name: "repository", repo: "acme/app", want: "acme/app"},
+		{name: "environment", owner: "acme", repo: "app", env: "prod", want: "acme/app:env:prod"},
+		{name: "no scope", wantErr: "--org or --repo flag is required"},
+		{name: "org and repo", org: "acme", repo: "acme/app", wantErr: "--org and --repo cannot be combined"},
+		{name: "env without repo", org: "acme", env: "prod", wantErr: "--env requires --repo"},
+		{name: "repo without owner", repo: "app", wantErr: "--repo app needs an owner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := variableScope(tt.org, tt.owner, tt.repo, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("variableScope() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("variableScope() unexpected error: %v", err)
+			}
+			if got.Label() != tt.want {
+				t.Errorf("variableScope() = %s, want %s", got.Label(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGetVariable(t *testing.T) {
+	origScope, origName, origOutput := getScope, getName, outputFormat
+	defer func() { getScope, getName, outputFormat = origScope, origName, origOutput }()
+	getScope = types.DesiredScope{Owner: "acme", Repo: "app", Environment: "prod"}
+
+	c := fakeAPIClient(t, map[string]fakeResponse{
+		"repos/acme/app/environments/prod/variables/API_URL": {http.StatusOK, `{"name":"API_URL","value":"https://api.example.com","updated_at":"2024-01-02T03:04:05Z"}`},
+	})
+
+	t.Run("value", func(t *testing.T) {
+		getName, outputFormat = "API_URL", output.Table
+		var b strings.Builder
+		if err := getVariable(c, &b); err != nil {
+			t.Fatalf("getVariable() unexpected error: %v", err)
+		}
+		if b.String() != "https://api.example.com\n" {
+			t.Errorf("getVariable() wrote %q, want the bare value", b.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		getName, outputFormat = "API_URL", output.JSON
+		var b strings.Builder
+		if err := getVariable(c, &b); err != nil {
+			t.Fatalf("getVariable() unexpected error: %v", err)
+		}
+		var got map[string]any
+		if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+			t.Fatalf("Output is not JSON: %v\n%s", err, b.String())
+		}
+		want := map[string]any{"name": "API_URL", "updated_at": "2024-01-02T03:04:05Z", "scope": "acme/app:env:prod", "value": "https://api.example.com"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("getVariable() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		getName, outputFormat = "MISSING", output.Table
+		var b strings.Builder
+		err := getVariable(c, &b)
+		if err == nil || !strings.Contains(err.Error(), "variable MISSING not found in acme/app:env:prod") {
+			t.Errorf("getVariable() error = %v, want the missing variable", err)
+		}
+		if code := exitCode(err); code != 1 {
+			t.Errorf("exitCode() = %d, want 1", code)
+		}
+		if b.Len() != 0 {
+			t.Errorf("getVariable() wrote %q for a missing variable", b.String())
+		}
+	})
+}
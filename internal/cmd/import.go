@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/term"
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/dump"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// importCmd creates and updates variables from a file written by export
+var importCmd = &cobra.Command{
+	Use:   "import --file FILE --org ORG [--repo REPO [--env ENV]] [--prune]",
+	Short: "Create or update variables from a file written by export",
+	Long: `Create or update the variables of a JSON, YAML, or .env file in an
+organization, in one of its repositories (--repo), or in an environment of that
+repository (--env).
+
+JSON and YAML files are read in the structure the export command writes with
+--include-values; a .env file holds NAME=value lines for one scope. The format
+is taken from the file extension unless --format is set. The whole file is
+validated before anything is read from GitHub, and a missing name or value, an
+invalid name, or a value over GitHub's 48 KB limit is reported with the entry
+it was found in.
+
+Environments exported with --with-envs are imported into the environments of
+the same name in the target repository, which are created when they do not
+exist. Variables outside --vars, --include, and --exclude are left out.
+Existing variables are handled by --on-conflict as in a migration; variables
+that already match are not written. With --prune, variables of the imported
+scopes that the file does not list, and that --vars and the name filters
+select, are deleted; they are listed first, and nothing is deleted until the
+scope name (e.g. myorg/app) is typed to confirm. --yes skips the confirmation,
+and is required when standard input is not a terminal. Only the target
+credentials and hostname are used; --dry-run, --diff, --report-file, and the
+hooks work as for a migration.`,
+	Example: `  # Restore a repository and its environments from a backup
+  gh vars-migrator import --file app.json --org myorg --repo app
+
+  # Preview importing a .env file into an environment, without overwriting
+  gh vars-migrator import --file production.env --org myorg --repo app --env production --on-conflict skip --dry-run
+
+  # Make a repository hold exactly the variables of a file
+  gh vars-migrator import --file app.json --org myorg --repo app --prune`,
+	PreRunE:       validateImportFlags,
+	RunE:          runImport,
+	SilenceErrors: true,
+}
+
+var (
+	importFile   string
+	importFormat string
+	importOrg    string
+	importRepo   string
+	importEnv    string
+	importPrune  bool
+)
+
+// importScopes holds the target scopes of the file loaded from --file
+// during flag validation
+var importScopes []types.DesiredScope
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	supportOutput(importCmd, output.JSON, output.CSV)
+	importCmd.Flags().StringVar(&importFile, "file", "", "File written by export to import (required)")
+	importCmd.Flags().StringVar(&importFormat, "format", "", "Format of --file: "+strings.Join(dump.ImportFormats, ", ")+" (default: from the file extension)")
+	importCmd.Flags().StringVarP(&importOrg, "org", "o", "", "Organization to import into (required)")
+	importCmd.Flags().StringVar(&importRepo, "repo", "", "Import into this repository of the organization instead")
+	importCmd.Flags().StringVar(&importEnv, "env", "", "Import into this environment of --repo")
+	importCmd.Flags().BoolVar(&importPrune, "prune", false, "Delete variables of the imported scopes that the file does not list")
+	registerCompletion(importCmd, "format", fixedCompletion(dump.ImportFormats...))
+	// The migration flags are added by the root command once it has
+	// registered them.
+}
+
+// importFlags are the flags import accepts besides its own: those of
+// apply --manifest, the name filters, and --yes for --prune
+var importFlags = map[string]bool{
+	"file": true, "format": true, "org": true, "repo": true, "env": true, "prune": true,
+	"vars": true, "include": true, "exclude": true, "filter-regex": true, "yes": true,
+}
+
+// validateImportFlags loads and validates the file, and checks the flags it
+// is imported with
+func validateImportFlags(cmd *cobra.Command, args []string) error {
+	importScopes = nil
+	switch {
+	case importFile == "":
+		return fmt.Errorf("--file flag is required")
+	case importOrg == "":
+		return fmt.Errorf("--org flag is required")
+	case importEnv != "" && importRepo == "":
+		return fmt.Errorf("--env requires --repo")
+	case importPrune && !assumeYes && !dryRun && !diffMode && !term.IsTerminal(os.Stdin):
+		return fmt.Errorf("import --prune asks to type the scope name on a terminal before deleting; pass --yes to delete without it")
+	}
+	cmd.SilenceUsage = true
+
+	if err := rejectFlags(cmd, "import", func(name string) bool {
+		return importFlags[name] || (manifestFlags[name] && name != "manifest" && name != "prune")
+	}); err != nil {
+		return err
+	}
+
+	format := importFormat
+	if format == "" {
+		if format = dump.FormatFromPath(importFile); format == "" {
+			return fmt.Errorf("cannot tell the format of %s from its name; set --format to one of %s", importFile, strings.Join(dump.ImportFormats, ", "))
+		}
+	}
+
+	if err := validateImportOptions(); err != nil {
+		return err
+	}
+
+	d, err := dump.Load(importFile, format)
+	if err != nil {
+		return fmt.Errorf("--file: %w", err)
+	}
+	scopes, err := d.Desired(importOrg, importRepo, importEnv)
+	if err != nil {
+		return fmt.Errorf("--file: %s: %w", importFile, err)
+	}
+	importScopes = scopes
+	return nil
+}
+
+// validateImportOptions checks the migration options an import or a
+// restore is run with
+func validateImportOptions() error {
+	targetHostname = normalizeHostname(targetHostname)
+	if err := validateRunOptions(); err != nil {
+		return err
+	}
+	if err := validateConflictOptions(); err != nil {
+		return err
+	}
+	if err := config.ValidatePatterns(includePatterns, excludePatterns); err != nil {
+		return err
+	}
+	return config.ValidateFilterRegex(filterRegex)
+}
+
+// runImport writes the variables of the --file file to the target. Only the
+// target credentials and hostname are used.
+func runImport(cmd *cobra.Command, args []string) error {
+	return importScopesFrom(cmd, "import", importFile, "file", importScopes)
+}
+
+// importScopesFrom writes the scopes read from file, given by the fileFlag
+// flag of the command, to the target as command (import or restore) does.
+// Only the target credentials and hostname are used.
+func importScopesFrom(cmd *cobra.Command, command, file, fileFlag string, scopes []types.DesiredScope) error {
+	targetClient, err := targetOnlyClient(command)
+	if err != nil {
+		return authError(err)
+	}
+	var orgScope, repoScope bool
+	for _, s := range scopes {
+		if s.Org != "" {
+			orgScope = true
+		} else {
+			repoScope = true
+		}
+	}
+	if orgScope {
+		if err := client.ValidateOrgScopes(targetClient, "target"); err != nil {
+			return authError(err)
+		}
+	}
+	if repoScope {
+		if err := client.ValidateRepoScopes(targetClient, "target"); err != nil {
+			return authError(err)
+		}
+	}
+
+	cfg := &types.MigrationConfig{
+		Mode:          types.ModeImport,
+		Manifest:      file,
+		Desired:       scopes,
+		DryRun:        dryRun,
+		SkipOverwrite: skipOverwrite,
+		OnConflict:    types.ConflictStrategy(onConflict),
+		Interactive:   interactive,
+		ShowValues:    showValues,
+		FailFast:      failFast,
+		MaxErrors:     maxErrors,
+		MaxAPICalls:   maxAPICalls,
+		AlwaysWrite:   alwaysWrite,
+		Vars:          varNames,
+		Include:       includePatterns,
+		Exclude:       excludePatterns,
+		FilterRegex:   filterRegex,
+		Prune:         importPrune,
+		Yes:           assumeYes,
+	}
+
+	title := strings.ToUpper(command[:1]) + command[1:] + ":"
+	logger.Info("%-17s%s → %s  ← %s", title, file, scopes[0].Label(), flagSource(cmd, fileFlag, ""))
+	if len(varNames) > 0 {
+		logger.Info("Vars:            %s  ← %s", strings.Join(varNames, ", "), flagSource(cmd, "vars", "VARS"))
+	}
+	if len(includePatterns) > 0 {
+		logger.Info("Include:         %s  ← %s", strings.Join(includePatterns, ", "), flagSource(cmd, "include", "INCLUDE_VARS"))
+	}
+	if len(excludePatterns) > 0 {
+		logger.Info("Exclude:         %s  ← %s", strings.Join(excludePatterns, ", "), flagSource(cmd, "exclude", "EXCLUDE_VARS"))
+	}
+	if filterRegex != "" {
+		logger.Info("Filter Regex:    %s  ← %s", filterRegex, flagSource(cmd, "filter-regex", "FILTER_REGEX"))
+	}
+	if importPrune {
+		logger.Info("Prune:           true  ← %s", flagSource(cmd, "prune", ""))
+	}
+
+	// The file is the source, so the target client serves both roles
+	m, err := migrator.New(cfg, targetClient, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrator: %w", err)
+	}
+	if diffMode {
+		return runDiff(m)
+	}
+	return runMigrator(cfg, m)
+}
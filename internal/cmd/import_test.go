@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestValidateImportFlags(t *testing.T) {
+	origFile, origFormat, origOrg, origRepo, origEnv := importFile, importFormat, importOrg, importRepo, importEnv
+	origScopes, origInclude, origPrune, origYes := importScopes, includePatterns, importPrune, assumeYes
+	defer func() {
+		importFile, importFormat, importOrg, importRepo, importEnv = origFile, origFormat, origOrg, origRepo, origEnv
+		importScopes, includePatterns, importPrune, assumeYes = origScopes, origInclude, origPrune, origYes
+	}()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	repoFile := write("app.json", `{"version":1,"org":"acme","repo":"app","variables":[{"name":"A","value":"a"}],"environments":[{"name":"prod","variables":[{"name":"B","value":"b"}]}]}`)
+	envFile := write("prod.env", "A=a\n")
+	noExtFile := write("vars", "A=a\n")
+	badFile := write("bad.env", "A=a\nB=\n")
+
+	tests := []struct {
+		name       string
+		file       string
+		format     string
+		org        string
+		repo       string
+		env        string
+		include    []string
+		flag       string
+		prune      bool
+		yes        bool
+		wantScopes int
+		wantErr    string
+	}{
+		{name: "repository with environments", file: repoFile, org: "acme", repo: "web", wantScopes: 2},
+		{name: "env file into an environment", file: envFile, org: "acme", repo: "web", env: "prod", wantScopes: 1},
+		{name: "format flag", file: noExtFile, format: "env", org: "acme", wantScopes: 1},
+		{name: "filter flag", file: envFile, org: "acme", flag: "exclude", wantScopes: 1},
+		{name: "missing file", org: "acme", wantErr: "--file flag is required"},
+		{name: "missing org", file: envFile, wantErr: "--org flag is required"},
+		{name: "env without repo", file: envFile, org: "acme", env: "prod", wantErr: "--env requires --repo"},
+		{name: "unknown format", file: noExtFile, org: "acme", wantErr: "set --format to one of json, yaml, env"},
+		{name: "invalid entry", file: badFile, org: "acme", wantErr: "bad.env: line 2 (B): missing value"},
+		{name: "environments into an organization", file: repoFile, org: "acme", wantErr: "app.json: the file holds the variables of 1 environment(s)"},
+		{name: "invalid include", file: envFile, org: "acme", include: []string{"["}, wantErr: `invalid include pattern "["`},
+		{name: "source flag", file: envFile, org: "acme", flag: "source-org", wantErr: "--source-org cannot be combined with import"},
+		{name: "manifest flag", file: envFile, org: "acme", flag: "manifest", wantErr: "--manifest cannot be combined with import"},
+		{name: "prune with yes", file: envFile, org: "acme", prune: true, yes: true, wantScopes: 1},
+		{name: "prune without a terminal", file: envFile, org: "acme", prune: true, wantErr: "pass --yes to delete without it"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importFile, importFormat, importOrg, importRepo, importEnv = tt.file, tt.format, tt.org, tt.repo, tt.env
+			includePatterns, importPrune, assumeYes = tt.include, tt.prune, tt.yes
+
+			// A throwaway command, so that setting a flag leaves importCmd alone
+			cmd := &cobra.Command{Use: "import"}
+			if tt.flag != "" {
+				cmd.Flags().String(tt.flag, "", "")
+				if err := cmd.Flags().Set(tt.flag, "x"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := validateImportFlags(cmd, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateImportFlags() unexpected error: %v", err)
+				}
+				if len(importScopes) != tt.wantScopes {
+					t.Errorf("Expected %d scope(s), got %+v", tt.wantScopes, importScopes)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateImportFlags() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,533 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/term"
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/profile"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// initCmd walks through the settings of a migration one question at a time
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up a migration by answering questions",
+	Long: `Ask for the settings of a migration one at a time — the mode, the source and
+target, how each side authenticates, and the main options — checking every
+answer as it is given: each side must authenticate, organizations and
+repositories must be visible to their token, and the environments chosen
+must exist in the source repository.
+
+Then run the migration, print the equivalent command line, or save the source,
+target, hostnames, and token variables as a profile of the configuration file
+(see "gh vars-migrator profiles"). Tokens are read from environment variables
+and are never asked for, printed, or saved.
+
+init needs a terminal to ask its questions.`,
+	Example: `  # Answer the questions, then choose what to do with them
+  gh vars-migrator init
+
+  # Save the profile in another configuration file
+  gh vars-migrator init --config ./migrations.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runInit,
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringVar(&configPath, "config", "", "Configuration file to save the profile in (default ~/.config/gh-vars-migrator/config.yaml)")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if !term.IsTerminal(os.Stdin) {
+		return fmt.Errorf("init asks its questions on a terminal, but standard input is not one; pass the migration flags instead")
+	}
+	w := newWizard(os.Stdin, cmd.OutOrStdout())
+	return w.run()
+}
+
+// wizard asks the questions of init and holds the answers
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// connect creates and authenticates the client of one side of the
+	// migration
+	connect func(side, token, hostname string) (*client.Client, error)
+
+	answers wizardAnswers
+}
+
+// wizardAnswers are the settings of the migration init sets up. The token
+// variables are empty for a side that authenticates with GITHUB_TOKEN or
+// the GitHub CLI.
+type wizardAnswers struct {
+	mode           types.MigrationMode
+	sourceOrg      string
+	sourceRepo     string
+	sourceHostname string
+	sourceTokenEnv string
+	targetOrg      string
+	targetRepo     string
+	targetHostname string
+	targetTokenEnv string
+	dryRun         bool
+	skipExisting   bool
+	skipEnvs       bool
+	envs           []string
+}
+
+// errInputEnded reports that the input ended before the last question
+var errInputEnded = errors.New("input ended before the questions were answered")
+
+// wizardModes are the modes init offers, in the order they are listed
+var wizardModes = []struct {
+	mode  types.MigrationMode
+	label string
+}{
+	{types.ModeRepoToRepo, "Repository to repository, with its environments"},
+	{types.ModeOrgToOrg, "Organization to organization"},
+	{types.ModeOrgToRepo, "Organization variables into a repository"},
+	{types.ModeRepoToOrg, "Repository variables into an organization"},
+}
+
+func newWizard(in io.Reader, out io.Writer) *wizard {
+	return &wizard{in: bufio.NewReader(in), out: out, connect: connectSide}
+}
+
+// connectSide creates the client of one side from token, or from the GitHub
+// CLI authentication when it is empty, and checks that it authenticates
+func connectSide(side, token, hostname string) (*client.Client, error) {
+	c, err := createClientWithToken(token, hostname, side)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.GetUser(); err != nil {
+		return nil, fmt.Errorf("%s authentication failed against %s: %w", side, hostLabel(hostname), err)
+	}
+	return c, nil
+}
+
+// run asks every question, then what to do with the answers, and does it
+func (w *wizard) run() error {
+	if err := w.ask(); err != nil {
+		return err
+	}
+
+	action, err := w.choose("What now?", []string{
+		"Run the migration",
+		"Print the command line",
+		"Save the source and target as a profile",
+	}, 0)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case 0:
+		return w.answers.runMigration()
+	case 1:
+		fmt.Fprintln(w.out)
+		fmt.Fprintln(w.out, w.answers.commandLine(""))
+		return nil
+	}
+	return w.saveProfile()
+}
+
+// ask asks for the mode, both sides, and the options of the migration
+func (w *wizard) ask() error {
+	a := &w.answers
+	labels := make([]string, len(wizardModes))
+	for i, m := range wizardModes {
+		labels[i] = m.label
+	}
+	choice, err := w.choose("Migration mode", labels, 0)
+	if err != nil {
+		return err
+	}
+	a.mode = wizardModes[choice].mode
+
+	repoSource := a.mode == types.ModeRepoToRepo || a.mode == types.ModeRepoToOrg
+	repoTarget := a.mode == types.ModeRepoToRepo || a.mode == types.ModeOrgToRepo
+	source, err := w.askSide("source", "SOURCE_PAT", repoSource, &a.sourceOrg, &a.sourceRepo, &a.sourceHostname, &a.sourceTokenEnv)
+	if err != nil {
+		return err
+	}
+	if _, err := w.askSide("target", "TARGET_PAT", repoTarget, &a.targetOrg, &a.targetRepo, &a.targetHostname, &a.targetTokenEnv); err != nil {
+		return err
+	}
+
+	if a.dryRun, err = w.confirm("Dry run: preview the changes without writing?", false); err != nil {
+		return err
+	}
+	overwrite, err := w.confirm("Overwrite variables that already exist in the target?", true)
+	if err != nil {
+		return err
+	}
+	a.skipExisting = !overwrite
+
+	if a.mode != types.ModeRepoToRepo {
+		return nil
+	}
+	withEnvs, err := w.confirm("Migrate the environments and their variables?", true)
+	if err != nil {
+		return err
+	}
+	a.skipEnvs = !withEnvs
+	if a.skipEnvs {
+		return nil
+	}
+	envs, err := source.ListEnvironments(a.sourceOrg, a.sourceRepo)
+	if err != nil {
+		return fmt.Errorf("failed to list the environments of %s/%s: %w", a.sourceOrg, a.sourceRepo, err)
+	}
+	names := make([]string, len(envs))
+	for i, env := range envs {
+		names[i] = env.Name
+	}
+	answer, err := w.prompt("Environments to migrate, comma-separated (empty for all)", "", true, func(s string) error {
+		for _, name := range splitList(s) {
+			if !containsFold(names, name) {
+				return fmt.Errorf("environment %s not found in %s/%s (found: %s)", name, a.sourceOrg, a.sourceRepo, orDash(strings.Join(names, ", ")))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	a.envs = splitList(answer)
+	return nil
+}
+
+// askSide asks for the hostname and authentication of one side, then its
+// organization, or its owner and repository with repo, checking each
+// against the side's client, which it returns
+func (w *wizard) askSide(side, defaultTokenEnv string, repo bool, org, repoName, hostname, tokenEnv *string) (*client.Client, error) {
+	title := strings.ToUpper(side[:1]) + side[1:]
+	host, err := w.prompt(title+" GitHub hostname", "github.com", false, nil)
+	if err != nil {
+		return nil, err
+	}
+	*hostname = normalizeHostname(host)
+	if *hostname == "github.com" {
+		*hostname = ""
+	}
+
+	var c *client.Client
+	for c == nil {
+		method, err := w.choose(title+" authentication", []string{
+			"GITHUB_TOKEN, or the GitHub CLI login",
+			"A token held by another environment variable",
+		}, 0)
+		if err != nil {
+			return nil, err
+		}
+		*tokenEnv = ""
+		token := os.Getenv("GITHUB_TOKEN")
+		if method == 1 {
+			if *tokenEnv, err = w.prompt("Environment variable holding the "+side+" token", defaultTokenEnv, false, checkTokenEnv); err != nil {
+				return nil, err
+			}
+			token = os.Getenv(*tokenEnv)
+		}
+		if c, err = w.connect(side, token, *hostname); err != nil {
+			fmt.Fprintf(w.out, "  ✗ %v\n", err)
+		}
+	}
+	e := endpoint{side: side, client: c, host: hostLabel(*hostname)}
+
+	if !repo {
+		*org, err = w.prompt(title+" organization", "", false, e.checkOrg)
+		return c, err
+	}
+	if *org, err = w.prompt(title+" repository owner (organization or user)", "", false, nil); err != nil {
+		return nil, err
+	}
+	*repoName, err = w.prompt(title+" repository", "", false, func(s string) error {
+		return e.checkRepo(*org, s, false)
+	})
+	return c, err
+}
+
+// tokenEnvPattern matches the names accepted for token variables, as in the
+// configuration file
+var tokenEnvPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkTokenEnv checks that name is an environment variable name that is
+// set
+func checkTokenEnv(name string) error {
+	if !tokenEnvPattern.MatchString(name) {
+		return fmt.Errorf("%q is not an environment variable name", name)
+	}
+	if os.Getenv(name) == "" {
+		return fmt.Errorf("%s is not set; export it, or add it to .env or an --env-file", name)
+	}
+	return nil
+}
+
+// saveProfile asks for a profile name and saves the source and target in
+// the configuration file, then prints the command line that uses it
+func (w *wizard) saveProfile() error {
+	path := configPath
+	if path == "" {
+		p, err := profile.DefaultPath()
+		if err != nil {
+			return err
+		}
+		path = p
+	}
+	cfg, err := profile.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		cfg, err = &profile.Config{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var name string
+	for {
+		if name, err = w.prompt("Profile name", "", false, checkProfileName); err != nil {
+			return err
+		}
+		if _, exists := cfg.Profiles[name]; !exists {
+			break
+		}
+		replace, err := w.confirm(fmt.Sprintf("Profile %s exists in %s; replace it?", name, path), false)
+		if err != nil {
+			return err
+		}
+		if replace {
+			break
+		}
+	}
+
+	a := w.answers
+	cfg.Put(name, profile.Profile{
+		SourceOrg:      a.sourceOrg,
+		SourceRepo:     a.sourceRepo,
+		SourceHostname: a.sourceHostname,
+		SourceTokenEnv: a.sourceTokenEnv,
+		TargetOrg:      a.targetOrg,
+		TargetRepo:     a.targetRepo,
+		TargetHostname: a.targetHostname,
+		TargetTokenEnv: a.targetTokenEnv,
+	})
+	if err := cfg.Save(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(w.out, "\nSaved profile %s to %s\n", name, path)
+	fmt.Fprintln(w.out, a.commandLine(name))
+	return nil
+}
+
+// checkProfileName rejects profile names that do not fit in a command line
+// without quoting
+func checkProfileName(name string) error {
+	if strings.ContainsAny(name, " \t'\"$`\\") {
+		return fmt.Errorf("profile name %q must not contain spaces, quotes, $, `, or \\", name)
+	}
+	return nil
+}
+
+// prompt asks question and returns the trimmed answer, def when it is empty.
+// An empty answer without def is asked again unless optional, and so is an
+// answer check rejects, after printing why.
+func (w *wizard) prompt(question, def string, optional bool, check func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(w.out, "%s: ", question)
+		}
+		line, err := w.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			fmt.Fprintln(w.out)
+			if err == io.EOF {
+				return "", errInputEnded
+			}
+			return "", fmt.Errorf("failed to read answer: %w", err)
+		}
+
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+		if answer == "" && !optional {
+			fmt.Fprintln(w.out, "  ✗ an answer is required")
+			continue
+		}
+		if check != nil {
+			if err := check(answer); err != nil {
+				fmt.Fprintf(w.out, "  ✗ %v\n", err)
+				continue
+			}
+		}
+		return answer, nil
+	}
+}
+
+// choose lists options, numbered from 1, and returns the index of the one
+// chosen; def is chosen by an empty answer
+func (w *wizard) choose(question string, options []string, def int) (int, error) {
+	fmt.Fprintln(w.out, question)
+	for i, option := range options {
+		fmt.Fprintf(w.out, "  %d) %s\n", i+1, option)
+	}
+	answer, err := w.prompt("Choice", strconv.Itoa(def+1), false, func(s string) error {
+		if n, err := strconv.Atoi(s); err != nil || n < 1 || n > len(options) {
+			return fmt.Errorf("enter a number from 1 to %d", len(options))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	n, _ := strconv.Atoi(answer)
+	return n - 1, nil
+}
+
+// confirm asks a yes or no question, answered def when empty
+func (w *wizard) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	answer, err := w.prompt(fmt.Sprintf("%s (%s)", question, hint), "", true, func(s string) error {
+		switch strings.ToLower(s) {
+		case "", "y", "yes", "n", "no":
+			return nil
+		}
+		return fmt.Errorf("answer y or n")
+	})
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+	return def, nil
+}
+
+// commandLine returns the gh vars-migrator command line of the answers,
+// with the connection settings replaced by --profile when profileName is
+// set. Tokens are passed as references to their environment variables.
+func (a wizardAnswers) commandLine(profileName string) string {
+	args := []string{"gh", "vars-migrator"}
+	if profileName != "" {
+		args = append(args, "--profile", profileName)
+	} else {
+		for _, f := range []struct{ flag, value string }{
+			{"--source-org", a.sourceOrg},
+			{"--source-repo", a.sourceRepo},
+			{"--source-hostname", a.sourceHostname},
+			{"--target-org", a.targetOrg},
+			{"--target-repo", a.targetRepo},
+			{"--target-hostname", a.targetHostname},
+		} {
+			if f.value != "" {
+				args = append(args, f.flag, shellQuote(f.value))
+			}
+		}
+		if a.sourceTokenEnv != "" {
+			args = append(args, "--source-pat", `"$`+a.sourceTokenEnv+`"`)
+		}
+		if a.targetTokenEnv != "" {
+			args = append(args, "--target-pat", `"$`+a.targetTokenEnv+`"`)
+		}
+	}
+
+	if a.mode != types.ModeRepoToRepo {
+		args = append(args, "--"+string(a.mode))
+	}
+	if a.skipEnvs {
+		args = append(args, "--skip-envs")
+	}
+	if len(a.envs) > 0 {
+		args = append(args, "--envs", shellQuote(strings.Join(a.envs, ",")))
+	}
+	if a.skipExisting {
+		args = append(args, "--on-conflict", "skip")
+	}
+	if a.dryRun {
+		args = append(args, "--dry-run")
+	}
+	return strings.Join(args, " ")
+}
+
+// runMigration sets the migration flags from the answers and runs the
+// migration as the root command would. Choosing to run it stands for the
+// confirmation a migration otherwise asks for.
+func (a wizardAnswers) runMigration() error {
+	sourceOrg, sourceRepo, sourceHostname = a.sourceOrg, a.sourceRepo, a.sourceHostname
+	targetOrg, targetRepo, targetHostname = a.targetOrg, a.targetRepo, a.targetHostname
+	for _, t := range []struct {
+		env         string
+		token, name *string
+	}{
+		{a.sourceTokenEnv, &sourcePAT, &sourcePATName},
+		{a.targetTokenEnv, &targetPAT, &targetPATName},
+	} {
+		if t.env != "" {
+			*t.token, *t.name = os.Getenv(t.env), t.env
+		}
+	}
+
+	orgToOrg = a.mode == types.ModeOrgToOrg
+	orgToRepo = a.mode == types.ModeOrgToRepo
+	repoToOrg = a.mode == types.ModeRepoToOrg
+	dryRun, skipEnvs, envNames = a.dryRun, a.skipEnvs, a.envs
+	if a.skipExisting {
+		onConflict = string(types.ConflictSkip)
+	}
+	assumeYes = true
+
+	if err := validateFlags(rootCmd, nil); err != nil {
+		return err
+	}
+	return runMigration(rootCmd, nil)
+}
+
+// shellQuote quotes s for a POSIX shell when it holds characters the shell
+// would interpret
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-.,/:@=+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// splitList splits a comma-separated answer, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// containsFold reports whether names holds name, ignoring case as GitHub
+// does for environment names
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/profile"
+)
+
+// runWizard drives the init wizard with scripted answers against a
+// fake API and returns what it wrote and the tokens it connected with
+func runWizard(t *testing.T, input string) (string, []string, error) {
+	t.Helper()
+	c := fakeAPIClient(t, map[string]fakeResponse{
+		"user":                       {http.StatusOK, `{"login":"octocat"}`},
+		"orgs/acme":                  {http.StatusOK, `{"login":"acme"}`},
+		"orgs/acme-new":              {http.StatusOK, `{"login":"acme-new"}`},
+		"repos/src/app":              {http.StatusOK, `{"name":"app"}`},
+		"repos/dst/app":              {http.StatusOK, `{"name":"app"}`},
+		"repos/src/app/environments": {http.StatusOK, `{"total_count":2,"environments":[{"name":"production"},{"name":"staging"}]}`},
+	})
+	var out strings.Builder
+	w := newWizard(strings.NewReader(input), &out)
+	var tokens []string
+	w.connect = func(side, token, hostname string) (*client.Client, error) {
+		tokens = append(tokens, side+"="+token+"@"+hostname)
+		if token == "" {
+			return nil, fmt.Errorf("%s authentication failed", side)
+		}
+		return c, nil
+	}
+	err := w.run()
+	return out.String(), tokens, err
+}
+
+// TestWizard_PrintCommandLine tests that the wizard asks again after an
+// invalid answer and prints the command line of its answers
+func TestWizard_PrintCommandLine(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("SRC_TOKEN", "src-secret")
+	t.Setenv("TARGET_PAT", "dst-secret")
+
+	input := strings.Join([]string{
+		"9", "1", // mode: out of range, then repo-to-repo
+		"",                                       // source hostname
+		"1", "2", "UNSET_TOKEN_VAR", "SRC_TOKEN", // GITHUB_TOKEN is empty, then an unset variable
+		"src", "web", "app", // repository web does not exist
+		"github.com", "2", "", // target hostname, TARGET_PAT
+		"dst", "app",
+		"", "n", "maybe", "y", // not a dry run, skip existing, environments
+		"qa", "production, Staging",
+		"2",
+	}, "\n") + "\n"
+	out, tokens, err := runWizard(t, input)
+	if err != nil {
+		t.Fatalf("run() unexpected error: %v\n%s", err, out)
+	}
+
+	wantTokens := []string{"source=@", "source=src-secret@", "target=dst-secret@"}
+	if !reflect.DeepEqual(tokens, wantTokens) {
+		t.Errorf("connected with %v, want %v", tokens, wantTokens)
+	}
+	for _, want := range []string{
+		"enter a number from 1 to 4",
+		"source authentication failed",
+		"UNSET_TOKEN_VAR is not set",
+		"source repository src/web not found on github.com",
+		"answer y or n",
+		"environment qa not found in src/app (found: production, staging)",
+		`gh vars-migrator --source-org src --source-repo app --target-org dst --target-repo app --source-pat "$SRC_TOKEN" --target-pat "$TARGET_PAT" --envs production,Staging --on-conflict skip` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("Expected no token value in the output, got:\n%s", out)
+	}
+}
+
+// TestWizard_SaveProfile tests that the wizard saves the source and target
+// as a profile and prints the command line that uses it
+func TestWizard_SaveProfile(t *testing.T) {
+	origConfigPath := configPath
+	defer func() { configPath = origConfigPath }()
+	configPath = filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("profiles:\n  cloud:\n    source_org: old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_TOKEN", "shared")
+	t.Setenv("CLOUD_TOKEN", "cloud-secret")
+
+	input := strings.Join([]string{
+		"2",                                     // org-to-org
+		"https://ghes.example.com/", "", "acme", // source on GHES with GITHUB_TOKEN
+		"", "2", "CLOUD_TOKEN", "nope", "acme-new", // organization nope does not exist
+		"y", "",
+		"3", "bad name", "cloud", "n", "cloud", "y",
+	}, "\n") + "\n"
+	out, tokens, err := runWizard(t, input)
+	if err != nil {
+		t.Fatalf("run() unexpected error: %v\n%s", err, out)
+	}
+	if want := []string{"source=shared@ghes.example.com", "target=cloud-secret@"}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("connected with %v, want %v", tokens, want)
+	}
+
+	cfg, err := profile.Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	want := profile.Profile{SourceOrg: "acme", SourceHostname: "ghes.example.com", TargetOrg: "acme-new", TargetTokenEnv: "CLOUD_TOKEN"}
+	if got, _ := cfg.Get("cloud"); got != want {
+		t.Errorf("saved profile = %+v, want %+v", got, want)
+	}
+	for _, want := range []string{
+		"target organization nope not found",
+		`profile name "bad name" must not contain spaces`,
+		"Profile cloud exists in " + configPath + "; replace it?",
+		"Saved profile cloud to " + configPath,
+		"gh vars-migrator --profile cloud --org-to-org --dry-run\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+// TestWizard_InputEnded tests that the wizard stops when its input ends
+func TestWizard_InputEnded(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "shared")
+	if _, _, err := runWizard(t, "1\n\n"); !errors.Is(err, errInputEnded) {
+		t.Errorf("run() error = %v, want %v", err, errInputEnded)
+	}
+}
+
+// TestShellQuote tests that values are only quoted when the shell would
+// interpret them
+func TestShellQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"acme-new":     "acme-new",
+		"prod,staging": "prod,staging",
+		"my env":       "'my env'",
+		"it's":         `'it'\''s'`,
+		"":             "''",
+	} {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,77 +2,609 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
 	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
 	"github.com/renan-alm/gh-vars-migrator/internal/types"
 	"github.com/spf13/cobra"
 )
 
 // listCmd represents the list command
 var listCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List variables in an organization",
-	Long:  `List all GitHub Actions variables in the specified organization.`,
+	Use:   "list (--org ORG [--all-repos] | --repo OWNER/REPO [--env ENV | --all-envs] | -R OWNER[/REPO])",
+	Short: "List variables in an organization, repository, or environment",
+	Long: `List all GitHub Actions variables in the specified organization (--org) or
+repository (--repo OWNER/REPO, or --owner OWNER --repo REPO). -R, like the
+GitHub CLI, takes either: -R OWNER lists an organization and -R OWNER/REPO a
+repository. It also takes their URL, whose host sets --hostname. --org,
+--owner, and --repo win over it.
+
+--env lists the variables of one environment of the repository instead, and
+--all-envs lists every environment of the repository with its variables,
+grouped by environment; environments without variables are listed too.
+
+--all-repos, with --org, lists the variables of every repository of the
+organization instead, grouped by repository. Archived repositories are left
+out unless --include-archived is set, and --parallel repositories are read
+at a time. A repository whose variables cannot be read, e.g. for lack of
+access, is reported and skipped; the listing goes on and the command exits
+with 3.
+
+--filter keeps only the variables whose names match one of its globs,
+case-insensitively, and --sort orders the variables of each scope by name or
+by last update (updated), --desc reversing the order; variables without a
+valid update time come last either way. Both apply to every scope and output.
+
+--output json writes a JSON array of {name, updated_at, scope} objects to
+standard output instead of the table, with a repo field for --all-repos, and
+--output csv the same columns as CSV; every other message then goes to standard error so the output can be
+piped. Values are left out unless --show-values is set, which adds a VALUE
+column to the tables, cut to --value-width characters with an ellipsis, and
+the full value to JSON and CSV. Values come with the listing, so showing
+them makes no extra requests.
+
+The --pat token is used when set, then the GITHUB_TOKEN environment variable,
+otherwise the GitHub CLI authentication. --hostname selects a GitHub
+Enterprise Server or data residency host.`,
 	Example: `  # List variables in an organization
-  gh vars-migrator list --org renan-org`,
-	RunE: runList,
-	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if listOrg == "" {
-			return fmt.Errorf("--org flag is required")
-		}
-		cmd.SilenceUsage = true
-		return nil
-	},
+  gh vars-migrator list --org renan-org
+
+  # List variables in a repository
+  gh vars-migrator list --repo renan-org/app
+
+  # The same with the GitHub CLI shorthand
+  gh vars-migrator list -R renan-org/app
+
+  # List the variables of every environment of a repository
+  gh vars-migrator list --repo renan-org/app --all-envs
+
+  # The deployment variables of a repository, most recently updated first
+  gh vars-migrator list --repo renan-org/app --filter 'DEPLOY_*' --sort updated --desc
+
+  # Inventory the repository variables of a whole organization as CSV
+  gh vars-migrator list --org renan-org --all-repos --output csv > inventory.csv
+
+  # List the names of repository variables with jq
+  gh vars-migrator list --repo renan-org/app --output json | jq -r '.[].name'
+
+  # List variables in a repository on GitHub Enterprise Server
+  gh vars-migrator list --owner renan-org --repo app --hostname github.example.com`,
+	RunE:    runList,
+	PreRunE: validateListFlags,
 }
 
-var listOrg string
+var (
+	listOrg        string
+	listScope      string
+	listOwner      string
+	listRepo       string
+	listEnv        string
+	listAllEnvs    bool
+	listAllRepos   bool
+	listArchived   bool
+	listParallel   int
+	listFilter     []string
+	listSort       string
+	listDesc       bool
+	listShowValues bool
+	listValueWidth int
+	listPAT        string
+	listHostname   string
+)
 
 func init() {
 	rootCmd.AddCommand(listCmd)
-	listCmd.Flags().StringVarP(&listOrg, "org", "o", "", "Organization name (required)")
-	_ = listCmd.MarkFlagRequired("org")
+	listCmd.Flags().StringVarP(&listOrg, "org", "o", "", "Organization to list")
+	listCmd.Flags().StringVar(&listRepo, "repo", "", "Repository to list, as OWNER/REPO or as REPO with --owner")
+	listCmd.Flags().StringVarP(&listScope, "scope", "R", "", "Organization or repository to list, as OWNER, OWNER/REPO, or a GitHub URL")
+	listCmd.Flags().StringVar(&listOwner, "owner", "", "Owner of --repo")
+	listCmd.Flags().StringVar(&listEnv, "env", "", "List this environment of --repo instead")
+	listCmd.Flags().BoolVar(&listAllEnvs, "all-envs", false, "List the variables of every environment of --repo")
+	listCmd.Flags().BoolVar(&listAllRepos, "all-repos", false, "List the variables of every repository of --org")
+	listCmd.Flags().BoolVar(&listArchived, "include-archived", false, "Include archived repositories in --all-repos")
+	listCmd.Flags().IntVar(&listParallel, "parallel", 4, "Number of repositories read at the same time with --all-repos")
+	listCmd.Flags().StringSliceVar(&listFilter, "filter", nil, "Only list variables whose names match this glob (repeatable or comma-separated)")
+	listCmd.Flags().StringVar(&listSort, "sort", "", "Sort variables by name or updated")
+	listCmd.Flags().BoolVar(&listDesc, "desc", false, "Reverse the --sort order")
+	listCmd.Flags().BoolVar(&listShowValues, "show-values", false, "Include variable values, cut to --value-width in tables")
+	listCmd.Flags().IntVar(&listValueWidth, "value-width", 40, "Maximum width of the VALUE column of --show-values; 0 shows values in full")
+	listCmd.Flags().StringVar(&listPAT, "pat", "", "Personal access token; overrides GITHUB_TOKEN")
+	listCmd.Flags().StringVar(&listHostname, "hostname", "", "GitHub hostname for GitHub Enterprise Server (default: github.com)")
+	registerCompletion(listCmd, "env", environmentCompletion(&listOwner, &listRepo, &listPAT, &listHostname))
+	registerCompletion(listCmd, "sort", fixedCompletion("name", "updated"))
+	supportOutput(listCmd, output.JSON, output.CSV)
+}
+
+// validateListFlags checks that exactly one of --org and --repo is given,
+// or -R in their place, and splits an OWNER/REPO --repo into listOwner and
+// listRepo
+func validateListFlags(cmd *cobra.Command, args []string) error {
+	if listScope != "" {
+		r, err := parseRef("scope", listScope)
+		if err != nil {
+			return err
+		}
+		if listOrg == "" && listOwner == "" && listRepo == "" {
+			if r.repo == "" {
+				listOrg = r.owner
+			} else {
+				listOwner, listRepo = r.owner, r.repo
+			}
+			if _, err := applyRefHost("scope", "hostname", r, &listHostname); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case listOrg == "" && listRepo == "":
+		return fmt.Errorf("--org or --repo flag is required")
+	case listOrg != "" && listRepo != "":
+		return fmt.Errorf("--org and --repo cannot be combined")
+	case listOwner != "" && listRepo == "":
+		return fmt.Errorf("--owner requires --repo")
+	case listEnv != "" && listRepo == "":
+		return fmt.Errorf("--env requires --repo")
+	case listAllEnvs && listRepo == "":
+		return fmt.Errorf("--all-envs requires --repo")
+	case listAllEnvs && listEnv != "":
+		return fmt.Errorf("--all-envs cannot be combined with --env")
+	case listAllRepos && listOrg == "":
+		return fmt.Errorf("--all-repos requires --org")
+	case listArchived && !listAllRepos:
+		return fmt.Errorf("--include-archived requires --all-repos")
+	case listParallel < 1:
+		return fmt.Errorf("--parallel must be at least 1")
+	case listSort != "" && listSort != "name" && listSort != "updated":
+		return fmt.Errorf("invalid --sort %q: must be name or updated", listSort)
+	case listDesc && listSort == "":
+		return fmt.Errorf("--desc requires --sort")
+	case listValueWidth < 0:
+		return fmt.Errorf("--value-width must not be negative")
+	case cmd.Flags().Changed("value-width") && !listShowValues:
+		return fmt.Errorf("--value-width requires --show-values")
+	}
+
+	for _, p := range listFilter {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid --filter pattern %q: %w", p, err)
+		}
+	}
+
+	if listRepo != "" {
+		owner, repo, err := resolveRepoFlag(listOwner, listRepo)
+		if err != nil {
+			return err
+		}
+		listOwner, listRepo = owner, repo
+	}
+
+	cmd.SilenceUsage = true
+	listHostname = normalizeHostname(listHostname)
+	return nil
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	// Check authentication first
-	if err := checkAuth(); err != nil {
-		return err
+	c, err := patClient(listPAT, listHostname, "list")
+	if err != nil {
+		return authError(err)
+	}
+	return listVariables(c, cmd.OutOrStdout())
+}
+
+// resolveRepoFlag splits a --repo OWNER/REPO value, or pairs a bare --repo
+// REPO with --owner
+func resolveRepoFlag(owner, repo string) (string, string, error) {
+	if o, r, ok := strings.Cut(repo, "/"); ok {
+		if owner != "" {
+			return "", "", fmt.Errorf("--owner cannot be combined with --repo OWNER/REPO")
+		}
+		if o == "" || r == "" || strings.Contains(r, "/") {
+			return "", "", fmt.Errorf("invalid --repo %q: must be OWNER/REPO", repo)
+		}
+		return o, r, nil
+	}
+	if owner == "" {
+		return "", "", fmt.Errorf("--repo %s needs an owner: pass --repo OWNER/%s or --owner", repo, repo)
 	}
+	return owner, repo, nil
+}
 
-	logger.Info("Listing variables for organization: %s", listOrg)
-	logger.Plain("")
+// patClient creates a client for the read-only commands from --pat, then
+// GITHUB_TOKEN, then the GitHub CLI authentication, and checks that it
+// authenticates
+func patClient(pat, hostname, purpose string) (*client.Client, error) {
+	githubToken := os.Getenv("GITHUB_TOKEN")
+	token := githubToken
+	if pat != "" {
+		token = pat
+	}
+	logger.Info("%s used", credentialLabel(pat, githubToken, "--pat", hostname))
 
-	client, err := api.DefaultRESTClient()
+	c, err := createClientWithToken(token, hostname, purpose)
 	if err != nil {
-		return fmt.Errorf("failed to create GitHub API client: %w", err)
+		return nil, err
 	}
+	user, err := c.GetUser()
+	if err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	logger.Success("Authenticated as: %s", user)
+	return c, nil
+}
 
-	var response struct {
-		TotalCount int              `json:"total_count"`
-		Variables  []types.Variable `json:"variables"`
+// listVariables lists the variables of the scope selected by the list flags,
+// writing the tables or the JSON document to w
+func listVariables(c *client.Client, w io.Writer) error {
+	if listAllEnvs {
+		return listEnvironmentVariables(c, w)
+	}
+	if listAllRepos {
+		return listRepositoryVariables(c, w)
 	}
 
-	path := fmt.Sprintf("orgs/%s/actions/variables", listOrg)
-	if err := client.Get(path, &response); err != nil {
-		return fmt.Errorf("failed to list variables: %w", err)
+	var vars []types.Variable
+	var scope types.DesiredScope
+	var what string
+	var err error
+	switch {
+	case listOrg != "":
+		scope = types.DesiredScope{Org: listOrg}
+		what = fmt.Sprintf("organization '%s'", listOrg)
+		logger.Info("Listing variables for organization: %s", listOrg)
+		vars, err = c.ListOrgVariables(listOrg)
+	case listEnv != "":
+		scope = types.DesiredScope{Owner: listOwner, Repo: listRepo, Environment: listEnv}
+		what = fmt.Sprintf("environment '%s' of '%s/%s'", listEnv, listOwner, listRepo)
+		logger.Info("Listing variables for environment: %s/%s:%s", listOwner, listRepo, listEnv)
+		vars, err = c.ListEnvVariables(listOwner, listRepo, listEnv)
+	default:
+		scope = types.DesiredScope{Owner: listOwner, Repo: listRepo}
+		what = fmt.Sprintf("repository '%s/%s'", listOwner, listRepo)
+		logger.Info("Listing variables for repository: %s/%s", listOwner, listRepo)
+		vars, err = c.ListRepoVariables(listOwner, listRepo)
+	}
+	logger.Plain("")
+	if err != nil {
+		return err
 	}
+	vars = arrangeVariables(vars)
 
-	if len(response.Variables) == 0 {
-		logger.Warning("No variables found in organization '%s'", listOrg)
+	if outputFormat != output.Table {
+		return writeVariableEntries(w, appendEntries(nil, scope.Label(), vars))
+	}
+	if len(vars) == 0 {
+		matching := ""
+		if len(listFilter) > 0 {
+			matching = " matching --filter"
+		}
+		logger.Warning("No variables%s found in %s", matching, what)
 		return nil
 	}
 
-	logger.Info("Found %d variable(s):", len(response.Variables))
+	logger.Info("Found %d variable(s):", len(vars))
+	logger.Plain("")
+	writeVariableTable(w, vars)
 	logger.Plain("")
-	logger.Plain("%-30s %s", "NAME", "UPDATED AT")
-	logger.Plain("%-30s %s", "----", "----------")
+	logger.Success("Total: %d variable(s)", len(vars))
+	return nil
+}
+
+// arrangeVariables keeps the variables matching --filter and orders them by
+// --sort, leaving the API order when it is not set
+func arrangeVariables(vars []types.Variable) []types.Variable {
+	if len(listFilter) > 0 {
+		kept := make([]types.Variable, 0, len(vars))
+		for _, v := range vars {
+			if matchesListFilter(v.Name) {
+				kept = append(kept, v)
+			}
+		}
+		vars = kept
+	}
+
+	switch listSort {
+	case "name":
+		sort.SliceStable(vars, func(i, j int) bool {
+			a, b := strings.ToLower(vars[i].Name), strings.ToLower(vars[j].Name)
+			if listDesc {
+				return a > b
+			}
+			return a < b
+		})
+	case "updated":
+		updated := make(map[string]time.Time, len(vars))
+		for _, v := range vars {
+			if t, err := time.Parse(time.RFC3339, v.UpdatedAt); err == nil {
+				updated[v.Name] = t
+			}
+		}
+		sort.SliceStable(vars, func(i, j int) bool {
+			a, aOK := updated[vars[i].Name]
+			b, bOK := updated[vars[j].Name]
+			switch {
+			case !aOK || !bOK:
+				// Variables without a valid update time come last
+				return aOK
+			case listDesc:
+				return a.After(b)
+			}
+			return a.Before(b)
+		})
+	}
+	return vars
+}
 
-	for _, v := range response.Variables {
-		logger.Plain("%-30s %s", v.Name, v.UpdatedAt)
+// matchesListFilter reports whether name matches one of the --filter globs;
+// names are matched case-insensitively, like --include
+func matchesListFilter(name string) bool {
+	for _, p := range listFilter {
+		if ok, _ := path.Match(strings.ToUpper(p), strings.ToUpper(name)); ok {
+			return true
+		}
 	}
+	return false
+}
+
+// environmentVariables are the variables of one environment in the
+// --all-envs listing
+type environmentVariables struct {
+	Name      string
+	Variables []types.Variable
+}
 
+// listEnvironmentVariables lists every environment of the repository with
+// its variables, in name order
+func listEnvironmentVariables(c *client.Client, w io.Writer) error {
+	logger.Info("Listing environment variables for repository: %s/%s", listOwner, listRepo)
 	logger.Plain("")
-	logger.Success("Total: %d variable(s)", len(response.Variables))
+
+	envs, err := c.ListEnvironments(listOwner, listRepo)
+	if err != nil {
+		return err
+	}
+	sort.Slice(envs, func(i, j int) bool { return strings.ToLower(envs[i].Name) < strings.ToLower(envs[j].Name) })
+
+	groups := make([]environmentVariables, 0, len(envs))
+	total := 0
+	for _, env := range envs {
+		vars, err := c.ListEnvVariables(listOwner, listRepo, env.Name)
+		if err != nil {
+			return err
+		}
+		vars = arrangeVariables(vars)
+		groups = append(groups, environmentVariables{Name: env.Name, Variables: vars})
+		total += len(vars)
+	}
+
+	if outputFormat != output.Table {
+		var entries []listEntry
+		for _, g := range groups {
+			scope := types.DesiredScope{Owner: listOwner, Repo: listRepo, Environment: g.Name}
+			entries = appendEntries(entries, scope.Label(), g.Variables)
+		}
+		return writeVariableEntries(w, entries)
+	}
+	if len(groups) == 0 {
+		logger.Warning("No environments found in repository '%s/%s'", listOwner, listRepo)
+		return nil
+	}
+
+	writeEnvironmentGroups(w, groups)
+	logger.Success("Total: %d variable(s) in %d environment(s)", total, len(groups))
 	return nil
 }
+
+// repositoryVariables are the variables of one repository in the
+// --all-repos listing; Err is set when they could not be read
+type repositoryVariables struct {
+	Name      string
+	Variables []types.Variable
+	Err       error
+}
+
+// listRepositoryVariables lists the variables of every repository of the
+// organization, in name order, reading --parallel repositories at a time. A
+// repository that cannot be read is reported without stopping the others,
+// and fails the command once everything else is written.
+func listRepositoryVariables(c *client.Client, w io.Writer) error {
+	logger.Info("Listing repository variables for organization: %s", listOrg)
+	repos, err := c.ListOrgRepos(listOrg)
+	if err != nil {
+		return err
+	}
+	var names []string
+	archived := 0
+	for _, r := range repos {
+		if r.Archived && !listArchived {
+			archived++
+			continue
+		}
+		names = append(names, r.Name)
+	}
+	if archived > 0 {
+		logger.Info("Skipping %d archived repository(ies) in %s", archived, listOrg)
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+	logger.Info("Reading the variables of %d repository(ies)", len(names))
+	logger.Plain("")
+
+	groups := make([]repositoryVariables, len(names))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(listParallel, len(names)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				vars, err := c.ListRepoVariables(listOrg, names[i])
+				groups[i] = repositoryVariables{Name: names[i], Variables: arrangeVariables(vars), Err: err}
+			}
+		}()
+	}
+	for i := range names {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	total, failed := 0, 0
+	for _, g := range groups {
+		if g.Err != nil {
+			failed++
+			logger.Warning("Could not list the variables of repository '%s/%s': %v", listOrg, g.Name, g.Err)
+			continue
+		}
+		total += len(g.Variables)
+	}
+
+	switch {
+	case outputFormat != output.Table:
+		var entries []listEntry
+		for _, g := range groups {
+			scope := types.DesiredScope{Owner: listOrg, Repo: g.Name}
+			for _, e := range appendEntries(nil, scope.Label(), g.Variables) {
+				e.Repo = g.Name
+				entries = append(entries, e)
+			}
+		}
+		if err := writeVariableEntries(w, entries); err != nil {
+			return err
+		}
+	case len(groups) == 0:
+		logger.Warning("No repositories found in organization '%s'", listOrg)
+	default:
+		writeRepositoryGroups(w, groups)
+		logger.Success("Total: %d variable(s) in %d repository(ies)", total, len(groups)-failed)
+	}
+
+	if failed > 0 {
+		return &exitError{code: exitCodePartial, err: fmt.Errorf("%d of %d repository(ies) could not be listed", failed, len(groups))}
+	}
+	return nil
+}
+
+// writeRepositoryGroups writes one section per repository, like
+// writeEnvironmentGroups; a repository that could not be read says so in
+// its heading
+func writeRepositoryGroups(w io.Writer, groups []repositoryVariables) {
+	for _, g := range groups {
+		switch {
+		case g.Err != nil:
+			fmt.Fprintf(w, "Repository: %s (could not be listed)\n\n", g.Name)
+		case len(g.Variables) == 0:
+			fmt.Fprintf(w, "Repository: %s (no variables)\n\n", g.Name)
+		default:
+			fmt.Fprintf(w, "Repository: %s (%d variable(s))\n", g.Name, len(g.Variables))
+			writeVariableTable(w, g.Variables)
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// writeEnvironmentGroups writes one section per environment: a heading with
+// its variable count, then its table, or "(no variables)" in the heading
+func writeEnvironmentGroups(w io.Writer, groups []environmentVariables) {
+	for _, g := range groups {
+		if len(g.Variables) == 0 {
+			fmt.Fprintf(w, "Environment: %s (no variables)\n\n", g.Name)
+			continue
+		}
+		fmt.Fprintf(w, "Environment: %s (%d variable(s))\n", g.Name, len(g.Variables))
+		writeVariableTable(w, g.Variables)
+		fmt.Fprintln(w)
+	}
+}
+
+// writeVariableTable writes the NAME and UPDATED AT table of the list
+// command, with a VALUE column for --show-values
+func writeVariableTable(w io.Writer, vars []types.Variable) {
+	if !listShowValues {
+		fmt.Fprintf(w, "%-30s %s\n", "NAME", "UPDATED AT")
+		fmt.Fprintf(w, "%-30s %s\n", "----", "----------")
+		for _, v := range vars {
+			fmt.Fprintf(w, "%-30s %s\n", v.Name, v.UpdatedAt)
+		}
+		return
+	}
+	fmt.Fprintf(w, "%-30s %-20s %s\n", "NAME", "UPDATED AT", "VALUE")
+	fmt.Fprintf(w, "%-30s %-20s %s\n", "----", "----------", "-----")
+	for _, v := range vars {
+		fmt.Fprintf(w, "%-30s %-20s %s\n", v.Name, v.UpdatedAt, truncateValue(v.Value, listValueWidth))
+	}
+}
+
+// truncateValue fits value on one table line of at most width characters:
+// line breaks are shown escaped, and a longer value is cut with an ellipsis.
+// A width of 0 keeps the whole value.
+func truncateValue(value string, width int) string {
+	value = strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(value)
+	runes := []rune(value)
+	if width == 0 || len(runes) <= width {
+		return value
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// listEntry is one variable of the --output json array and csv rows. Scope is
+// "org:ORG", "OWNER/REPO", or "OWNER/REPO:env:ENV"; Repo is only set with
+// --all-repos, and Value only with --show-values, so that an empty value can
+// still be told from a hidden one.
+type listEntry struct {
+	Name      string  `json:"name"`
+	UpdatedAt string  `json:"updated_at"`
+	Scope     string  `json:"scope"`
+	Repo      string  `json:"repo,omitempty"`
+	Value     *string `json:"value,omitempty"`
+}
+
+// appendEntries appends the --output json entries of the variables of scope
+func appendEntries(entries []listEntry, scope string, vars []types.Variable) []listEntry {
+	for _, v := range vars {
+		e := listEntry{Name: v.Name, UpdatedAt: v.UpdatedAt, Scope: scope}
+		if listShowValues {
+			value := v.Value
+			e.Value = &value
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// writeVariableEntries writes entries as an indented JSON array, or as CSV
+// with --output csv; no variables give an empty array rather than null
+func writeVariableEntries(w io.Writer, entries []listEntry) error {
+	if outputFormat == output.CSV {
+		header := []string{"name", "updated_at", "scope"}
+		if listAllRepos {
+			header = append(header, "repo")
+		}
+		if listShowValues {
+			header = append(header, "value")
+		}
+		rows := make([][]string, 0, len(entries))
+		for _, e := range entries {
+			row := []string{e.Name, e.UpdatedAt, e.Scope}
+			if listAllRepos {
+				row = append(row, e.Repo)
+			}
+			if e.Value != nil {
+				row = append(row, *e.Value)
+			}
+			rows = append(rows, row)
+		}
+		return output.WriteCSV(w, header, rows)
+	}
+	if entries == nil {
+		entries = []listEntry{}
+	}
+	return output.WriteJSON(w, entries)
+}
@@ -0,0 +1,483 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+func TestValidateListFlags(t *testing.T) {
+	origOrg, origOwner, origRepo, origHostname, origScope := listOrg, listOwner, listRepo, listHostname, listScope
+	origEnv, origAllEnvs, origOutput, origShowValues := listEnv, listAllEnvs, outputFormat, listShowValues
+	origAllRepos, origArchived, origParallel := listAllRepos, listArchived, listParallel
+	origFilter, origSort, origDesc := listFilter, listSort, listDesc
+	defer func() {
+		listOrg, listOwner, listRepo, listHostname, listScope = origOrg, origOwner, origRepo, origHostname, origScope
+		listEnv, listAllEnvs, outputFormat, listShowValues = origEnv, origAllEnvs, origOutput, origShowValues
+		listAllRepos, listArchived, listParallel = origAllRepos, origArchived, origParallel
+		listFilter, listSort, listDesc = origFilter, origSort, origDesc
+	}()
+
+	tests := []struct {
+		name      string
+		org       string
+		scope     string
+		owner     string
+		repo      string
+		env       string
+		allEnvs   bool
+		allRepos  bool
+		archived  bool
+		parallel  int
+		filter    []string
+		sort      string
+		desc      bool
+		output    string
+		showVals  bool
+		wantOrg   string
+		wantOwner string
+		wantRepo  string
+		wantErr   string
+	}{
+		{name: "organization", org: "acme"},
+		{name: "owner/repo", repo: "acme/app", wantOwner: "acme", wantRepo: "app"},
+		{name: "owner and repo", owner: "acme", repo: "app", wantOwner: "acme", wantRepo: "app"},
+		{name: "neither", wantErr: "--org or --repo flag is required"},
+		{name: "both", org: "acme", repo: "acme/app", wantErr: "--org and --repo cannot be combined"},
+		{name: "owner without repo", org: "acme", owner: "acme", wantErr: "--owner requires --repo"},
+		{name: "repo without owner", repo: "app", wantErr: "--repo app needs an owner"},
+		{name: "owner with owner/repo", owner: "acme", repo: "acme/app", wantErr: "--owner cannot be combined with --repo OWNER/REPO"},
+		{name: "empty owner", repo: "/app", wantErr: `invalid --repo "/app"`},
+		{name: "extra segment", repo: "acme/app/extra", wantErr: `invalid --repo "acme/app/extra"`},
+		{name: "environment", repo: "acme/app", env: "prod", wantOwner: "acme", wantRepo: "app"},
+		{name: "all environments", repo: "acme/app", allEnvs: true, wantOwner: "acme", wantRepo: "app"},
+		{name: "env without repo", org: "acme", env: "prod", wantErr: "--env requires --repo"},
+		{name: "all-envs without repo", org: "acme", allEnvs: true, wantErr: "--all-envs requires --repo"},
+		{name: "env and all-envs", repo: "acme/app", env: "prod", allEnvs: true, wantErr: "--all-envs cannot be combined with --env"},
+		{name: "all repositories", org: "acme", allRepos: true, archived: true},
+		{name: "all-repos without org", repo: "acme/app", allRepos: true, wantErr: "--all-repos requires --org"},
+		{name: "include-archived without all-repos", org: "acme", archived: true, wantErr: "--include-archived requires --all-repos"},
+		{name: "no parallel reads", org: "acme", allRepos: true, parallel: -1, wantErr: "--parallel must be at least 1"},
+		{name: "sorted and filtered", org: "acme", filter: []string{"DEPLOY_*"}, sort: "updated", desc: true},
+		{name: "unknown sort key", org: "acme", sort: "size", wantErr: `invalid --sort "size": must be name or updated`},
+		{name: "desc without sort", org: "acme", desc: true, wantErr: "--desc requires --sort"},
+		{name: "malformed filter", org: "acme", filter: []string{"DEPLOY_["}, wantErr: `invalid --filter pattern "DEPLOY_["`},
+		{name: "json with values", org: "acme", output: "json", showVals: true},
+		{name: "csv with values", org: "acme", output: "csv", showVals: true},
+		{name: "values in a table", org: "acme", showVals: true},
+		{name: "-R organization", scope: "acme", wantOrg: "acme"},
+		{name: "-R repository", scope: "acme/app", wantOwner: "acme", wantRepo: "app"},
+		{name: "-R with environments", scope: "acme/app", allEnvs: true, wantOwner: "acme", wantRepo: "app"},
+		{name: "--repo wins over -R", scope: "acme", repo: "corp/api", wantOwner: "corp", wantRepo: "api"},
+		{name: "malformed -R", scope: "acme/app/extra", wantErr: `invalid --scope "acme/app/extra": must be OWNER, OWNER/REPO, or a GitHub URL`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listOrg, listOwner, listRepo, listEnv, listAllEnvs = tt.org, tt.owner, tt.repo, tt.env, tt.allEnvs
+			listScope = tt.scope
+			listAllRepos, listArchived, listParallel = tt.allRepos, tt.archived, 4
+			listFilter, listSort, listDesc = tt.filter, tt.sort, tt.desc
+			if tt.parallel != 0 {
+				listParallel = tt.parallel
+			}
+			outputFormat, listShowValues = output.Table, tt.showVals
+			if tt.output != "" {
+				outputFormat = tt.output
+			}
+
+			err := validateListFlags(&cobra.Command{Use: "list"}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("validateListFlags() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateListFlags() unexpected error: %v", err)
+			}
+			if listOwner != tt.wantOwner || listRepo != tt.wantRepo {
+				t.Errorf("Resolved %q/%q, want %q/%q", listOwner, listRepo, tt.wantOwner, tt.wantRepo)
+			}
+			if tt.wantOrg != "" && listOrg != tt.wantOrg {
+				t.Errorf("Resolved organization %q, want %q", listOrg, tt.wantOrg)
+			}
+		})
+	}
+}
+
+func TestWriteVariableTable(t *testing.T) {
+	var b strings.Builder
+	writeVariableTable(&b, []types.Variable{
+		{Name: "LOG_LEVEL", UpdatedAt: "2024-01-02T03:04:05Z"},
+		{Name: "TIMEOUT"},
+	})
+
+	want := "NAME                           UPDATED AT\n" +
+		"----                           ----------\n" +
+		"LOG_LEVEL                      2024-01-02T03:04:05Z\n" +
+		"TIMEOUT                        \n"
+	if b.String() != want {
+		t.Errorf("writeVariableTable() =\n%q\nwant\n%q", b.String(), want)
+	}
+}
+
+func TestWriteVariableTable_Values(t *testing.T) {
+	origShowValues, origWidth := listShowValues, listValueWidth
+	defer func() { listShowValues, listValueWidth = origShowValues, origWidth }()
+	listShowValues, listValueWidth = true, 12
+
+	var b strings.Builder
+	writeVariableTable(&b, []types.Variable{
+		{Name: "URL", Value: "https://app.example.com/api", UpdatedAt: "2024-01-02T03:04:05Z"},
+		{Name: "EMPTY"},
+	})
+
+	want := "NAME                           UPDATED AT           VALUE\n" +
+		"----                           ----------           -----\n" +
+		"URL                            2024-01-02T03:04:05Z https://app…\n" +
+		"EMPTY" + strings.Repeat(" ", 47) + "\n"
+	if b.String() != want {
+		t.Errorf("writeVariableTable() =\n%q\nwant\n%q", b.String(), want)
+	}
+}
+
+func TestTruncateValue(t *testing.T) {
+	tests := []struct {
+		value string
+		width int
+		want  string
+	}{
+		{value: "short", width: 10, want: "short"},
+		{value: "exactly10!", width: 10, want: "exactly10!"},
+		{value: "one more char", width: 10, want: "one more …"},
+		{value: "ünïcödé välüé", width: 8, want: "ünïcödé…"},
+		{value: "line one\nline two", width: 0, want: `line one\nline two`},
+		{value: "a\r\nb", width: 3, want: `a\…`},
+		{value: "no limit at all", width: 0, want: "no limit at all"},
+	}
+	for _, tt := range tests {
+		if got := truncateValue(tt.value, tt.width); got != tt.want {
+			t.Errorf("truncateValue(%q, %d) = %q, want %q", tt.value, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestListVariables_AllEnvs(t *testing.T) {
+	origOrg, origOwner, origRepo, origEnv, origAllEnvs := listOrg, listOwner, listRepo, listEnv, listAllEnvs
+	origOutput := outputFormat
+	defer func() {
+		listOrg, listOwner, listRepo, listEnv, listAllEnvs = origOrg, origOwner, origRepo, origEnv, origAllEnvs
+		outputFormat = origOutput
+	}()
+	listOrg, listOwner, listRepo, listEnv, listAllEnvs = "", "acme", "app", "", true
+	outputFormat = output.Table
+
+	t.Run("grouped by environment", func(t *testing.T) {
+		c := fakeAPIClient(t, map[string]fakeResponse{
+			"repos/acme/app/environments":                      {http.StatusOK, `{"total_count":3,"environments":[{"name":"staging"},{"name":"empty"},{"name":"Production"}]}`},
+			"repos/acme/app/environments/Production/variables": {http.StatusOK, `{"variables":[{"name":"URL","updated_at":"2024-01-02T03:04:05Z"},{"name":"REPLICAS"}]}`},
+			"repos/acme/app/environments/staging/variables":    {http.StatusOK, `{"variables":[{"name":"URL"}]}`},
+			"repos/acme/app/environments/empty/variables":      {http.StatusOK, `{"variables":[]}`},
+		})
+
+		var b strings.Builder
+		if err := listVariables(c, &b); err != nil {
+			t.Fatalf("listVariables() unexpected error: %v", err)
+		}
+		want := "Environment: empty (no variables)\n\n" +
+			"Environment: Production (2 variable(s))\n" +
+			"NAME                           UPDATED AT\n" +
+			"----                           ----------\n" +
+			"URL                            2024-01-02T03:04:05Z\n" +
+			"REPLICAS                       \n\n" +
+			"Environment: staging (1 variable(s))\n" +
+			"NAME                           UPDATED AT\n" +
+			"----                           ----------\n" +
+			"URL                            \n\n"
+		if b.String() != want {
+			t.Errorf("listVariables() wrote\n%s\nwant\n%s", b.String(), want)
+		}
+	})
+
+	t.Run("no environments", func(t *testing.T) {
+		c := fakeAPIClient(t, map[string]fakeResponse{
+			"repos/acme/app/environments": {http.StatusOK, `{"total_count":0,"environments":[]}`},
+		})
+
+		var b strings.Builder
+		if err := listVariables(c, &b); err != nil {
+			t.Fatalf("listVariables() unexpected error: %v", err)
+		}
+		if b.String() != "" {
+			t.Errorf("Expected no tables, got %q", b.String())
+		}
+	})
+
+	t.Run("failing environment", func(t *testing.T) {
+		c := fakeAPIClient(t, map[string]fakeResponse{
+			"repos/acme/app/environments": {http.StatusOK, `{"total_count":1,"environments":[{"name":"gone"}]}`},
+		})
+
+		if err := listVariables(c, io.Discard); err == nil {
+			t.Error("Expected the failed environment listing to be returned")
+		}
+	})
+}
+
+func TestListVariables_JSON(t *testing.T) {
+	origOrg, origOwner, origRepo, origEnv, origAllEnvs := listOrg, listOwner, listRepo, listEnv, listAllEnvs
+	origOutput, origShowValues, origWidth := outputFormat, listShowValues, listValueWidth
+	defer func() {
+		listOrg, listOwner, listRepo, listEnv, listAllEnvs = origOrg, origOwner, origRepo, origEnv, origAllEnvs
+		outputFormat, listShowValues, listValueWidth = origOutput, origShowValues, origWidth
+	}()
+	// JSON has the full values whatever the width of the table column
+	listValueWidth = 3
+
+	c := fakeAPIClient(t, map[string]fakeResponse{
+		"repos/acme/app/actions/variables":           {http.StatusOK, `{"variables":[{"name":"LOG_LEVEL","value":"debug","updated_at":"2024-01-02T03:04:05Z"},{"name":"EMPTY","value":""}]}`},
+		"repos/acme/empty/actions/variables":         {http.StatusOK, `{"variables":[]}`},
+		"repos/acme/app/environments":                {http.StatusOK, `{"environments":[{"name":"prod"},{"name":"dev"}]}`},
+		"repos/acme/app/environments/prod/variables": {http.StatusOK, `{"variables":[{"name":"URL","value":"https://app.example.com"}]}`},
+		"repos/acme/app/environments/dev/variables":  {http.StatusOK, `{"variables":[]}`},
+	})
+
+	tests := []struct {
+		name       string
+		repo       string
+		allEnvs    bool
+		showValues bool
+		want       []map[string]any
+	}{
+		{
+			name: "values left out",
+			repo: "app",
+			want: []map[string]any{
+				{"name": "LOG_LEVEL", "updated_at": "2024-01-02T03:04:05Z", "scope": "acme/app"},
+				{"name": "EMPTY", "updated_at": "", "scope": "acme/app"},
+			},
+		},
+		{
+			name:       "show values",
+			repo:       "app",
+			showValues: true,
+			want: []map[string]any{
+				{"name": "LOG_LEVEL", "updated_at": "2024-01-02T03:04:05Z", "scope": "acme/app", "value": "debug"},
+				{"name": "EMPTY", "updated_at": "", "scope": "acme/app", "value": ""},
+			},
+		},
+		{name: "no variables", repo: "empty", want: []map[string]any{}},
+		{
+			name:    "all environments",
+			repo:    "app",
+			allEnvs: true,
+			want:    []map[string]any{{"name": "URL", "updated_at": "", "scope": "acme/app:env:prod"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listOrg, listOwner, listRepo, listEnv, listAllEnvs = "", "acme", tt.repo, "", tt.allEnvs
+			outputFormat, listShowValues = output.JSON, tt.showValues
+
+			var b strings.Builder
+			if err := listVariables(c, &b); err != nil {
+				t.Fatalf("listVariables() unexpected error: %v", err)
+			}
+			var got []map[string]any
+			if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+				t.Fatalf("Output is not a JSON array: %v\n%s", err, b.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listVariables() =\n %v\nwant\n %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestArrangeVariables tests the --filter, --sort, and --desc of list
+func TestArrangeVariables(t *testing.T) {
+	origFilter, origSort, origDesc := listFilter, listSort, listDesc
+	defer func() { listFilter, listSort, listDesc = origFilter, origSort, origDesc }()
+
+	vars := []types.Variable{
+		{Name: "deploy_region", UpdatedAt: "2024-03-01T00:00:00Z"},
+		{Name: "LOG_LEVEL", UpdatedAt: "2024-05-01T00:00:00Z"},
+		{Name: "DEPLOY_URL", UpdatedAt: "not a time"},
+		{Name: "API_KEY", UpdatedAt: "2024-01-01T00:00:00Z"},
+		{Name: "DEPLOY_TARGET"},
+		{Name: "DEPLOY_ENV", UpdatedAt: "2024-02-01T00:00:00+02:00"},
+	}
+
+	tests := []struct {
+		name   string
+		filter []string
+		sort   string
+		desc   bool
+		want   []string
+	}{
+		{name: "as returned", want: []string{"deploy_region", "LOG_LEVEL", "DEPLOY_URL", "API_KEY", "DEPLOY_TARGET", "DEPLOY_ENV"}},
+		{name: "by name", sort: "name", want: []string{"API_KEY", "DEPLOY_ENV", "deploy_region", "DEPLOY_TARGET", "DEPLOY_URL", "LOG_LEVEL"}},
+		{name: "by name descending", sort: "name", desc: true, want: []string{"LOG_LEVEL", "DEPLOY_URL", "DEPLOY_TARGET", "deploy_region", "DEPLOY_ENV", "API_KEY"}},
+		{name: "by update, unparseable last", sort: "updated", want: []string{"API_KEY", "DEPLOY_ENV", "deploy_region", "LOG_LEVEL", "DEPLOY_URL", "DEPLOY_TARGET"}},
+		{name: "by update descending, unparseable still last", sort: "updated", desc: true, want: []string{"LOG_LEVEL", "deploy_region", "DEPLOY_ENV", "API_KEY", "DEPLOY_URL", "DEPLOY_TARGET"}},
+		{name: "filtered, case-insensitively", filter: []string{"DEPLOY_*"}, want: []string{"deploy_region", "DEPLOY_URL", "DEPLOY_TARGET", "DEPLOY_ENV"}},
+		{name: "filtered and sorted by update", filter: []string{"deploy_*", "API_*"}, sort: "updated", desc: true, want: []string{"deploy_region", "DEPLOY_ENV", "API_KEY", "DEPLOY_URL", "DEPLOY_TARGET"}},
+		{name: "nothing matches", filter: []string{"NONE_*"}, sort: "name", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listFilter, listSort, listDesc = tt.filter, tt.sort, tt.desc
+			got := []string{}
+			for _, v := range arrangeVariables(slices.Clone(vars)) {
+				got = append(got, v.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("arrangeVariables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestListVariables_SortFilter checks that --filter and --sort apply to an
+// organization as JSON and to the environments of a repository as tables
+func TestListVariables_SortFilter(t *testing.T) {
+	origOrg, origOwner, origRepo, origEnv, origAllEnvs := listOrg, listOwner, listRepo, listEnv, listAllEnvs
+	origFilter, origSort, origDesc, origAllRepos := listFilter, listSort, listDesc, listAllRepos
+	origOutput, origShowValues := outputFormat, listShowValues
+	defer func() {
+		listOrg, listOwner, listRepo, listEnv, listAllEnvs = origOrg, origOwner, origRepo, origEnv, origAllEnvs
+		listFilter, listSort, listDesc, listAllRepos = origFilter, origSort, origDesc, origAllRepos
+		outputFormat, listShowValues = origOutput, origShowValues
+	}()
+	listFilter, listSort, listDesc, listAllRepos, listShowValues = []string{"DEPLOY_*"}, "updated", true, false, false
+
+	c := fakeAPIClient(t, map[string]fakeResponse{
+		"orgs/acme/actions/variables":                {http.StatusOK, `{"variables":[{"name":"DEPLOY_A","updated_at":"2024-01-01T00:00:00Z"},{"name":"OTHER","updated_at":"2024-06-01T00:00:00Z"},{"name":"DEPLOY_B","updated_at":"2024-02-01T00:00:00Z"}]}`},
+		"repos/acme/app/environments":                {http.StatusOK, `{"environments":[{"name":"prod"},{"name":"dev"}]}`},
+		"repos/acme/app/environments/prod/variables": {http.StatusOK, `{"variables":[{"name":"DEPLOY_OLD","updated_at":"2023-01-01T00:00:00Z"},{"name":"DEPLOY_NEW","updated_at":"2024-01-01T00:00:00Z"}]}`},
+		"repos/acme/app/environments/dev/variables":  {http.StatusOK, `{"variables":[{"name":"DEBUG"}]}`},
+	})
+
+	listOrg, listOwner, listRepo, listEnv, listAllEnvs = "acme", "", "", "", false
+	outputFormat = output.JSON
+	var b strings.Builder
+	if err := listVariables(c, &b); err != nil {
+		t.Fatalf("listVariables() unexpected error: %v", err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("Output is not a JSON array: %v\n%s", err, b.String())
+	}
+	want := []map[string]any{
+		{"name": "DEPLOY_B", "updated_at": "2024-02-01T00:00:00Z", "scope": "org:acme"},
+		{"name": "DEPLOY_A", "updated_at": "2024-01-01T00:00:00Z", "scope": "org:acme"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listVariables() =\n %v\nwant\n %v", got, want)
+	}
+
+	listOrg, listOwner, listRepo, listAllEnvs = "", "acme", "app", true
+	outputFormat = output.Table
+	b.Reset()
+	if err := listVariables(c, &b); err != nil {
+		t.Fatalf("listVariables() unexpected error: %v", err)
+	}
+	wantTable := "Environment: dev (no variables)\n\n" +
+		"Environment: prod (2 variable(s))\n" +
+		"NAME                           UPDATED AT\n" +
+		"----                           ----------\n" +
+		"DEPLOY_NEW                     2024-01-01T00:00:00Z\n" +
+		"DEPLOY_OLD                     2023-01-01T00:00:00Z\n\n"
+	if b.String() != wantTable {
+		t.Errorf("listVariables() wrote\n%s\nwant\n%s", b.String(), wantTable)
+	}
+}
+
+// TestListVariables_AllRepos lists an organization whose repositories are
+// partly inaccessible, as a grouped table and as JSON records
+func TestListVariables_AllRepos(t *testing.T) {
+	origOrg, origOwner, origRepo, origEnv, origAllEnvs := listOrg, listOwner, listRepo, listEnv, listAllEnvs
+	origAllRepos, origArchived, origParallel := listAllRepos, listArchived, listParallel
+	origOutput, origShowValues := outputFormat, listShowValues
+	defer func() {
+		listOrg, listOwner, listRepo, listEnv, listAllEnvs = origOrg, origOwner, origRepo, origEnv, origAllEnvs
+		listAllRepos, listArchived, listParallel = origAllRepos, origArchived, origParallel
+		outputFormat, listShowValues = origOutput, origShowValues
+	}()
+	listOrg, listOwner, listRepo, listEnv, listAllEnvs = "acme", "", "", "", false
+	listAllRepos, listParallel, listShowValues = true, 2, false
+
+	c := fakeAPIClient(t, map[string]fakeResponse{
+		"orgs/acme/repos":                     {http.StatusOK, `[{"name":"web"},{"name":"secret"},{"name":"api"},{"name":"old","archived":true},{"name":"empty"}]`},
+		"repos/acme/api/actions/variables":    {http.StatusOK, `{"variables":[{"name":"URL","updated_at":"2024-01-02T03:04:05Z"}]}`},
+		"repos/acme/web/actions/variables":    {http.StatusOK, `{"variables":[{"name":"URL"},{"name":"THEME"}]}`},
+		"repos/acme/empty/actions/variables":  {http.StatusOK, `{"variables":[]}`},
+		"repos/acme/old/actions/variables":    {http.StatusOK, `{"variables":[{"name":"LEGACY"}]}`},
+		"repos/acme/secret/actions/variables": {http.StatusForbidden, `{"message":"Resource not accessible by personal access token"}`},
+	})
+
+	t.Run("grouped by repository", func(t *testing.T) {
+		listArchived, outputFormat = false, output.Table
+
+		var b strings.Builder
+		var err error
+		stdout, stderr := captureStdio(t, func() { err = listVariables(c, &b) })
+		if code := exitCode(err); code != exitCodePartial || !strings.Contains(err.Error(), "1 of 4 repository(ies) could not be listed") {
+			t.Errorf("listVariables() error = %v (exit code %d), want the inaccessible repository reported with %d", err, code, exitCodePartial)
+		}
+		want := "Repository: api (1 variable(s))\n" +
+			"NAME                           UPDATED AT\n" +
+			"----                           ----------\n" +
+			"URL                            2024-01-02T03:04:05Z\n\n" +
+			"Repository: empty (no variables)\n\n" +
+			"Repository: secret (could not be listed)\n\n" +
+			"Repository: web (2 variable(s))\n" +
+			"NAME                           UPDATED AT\n" +
+			"----                           ----------\n" +
+			"URL                            \n" +
+			"THEME                          \n\n"
+		if b.String() != want {
+			t.Errorf("listVariables() wrote\n%s\nwant\n%s", b.String(), want)
+		}
+		for _, msg := range []string{"Skipping 1 archived repository(ies) in acme", "Could not list the variables of repository 'acme/secret'"} {
+			if !strings.Contains(stdout+stderr, msg) {
+				t.Errorf("Expected %q in the log, got:\n%s%s", msg, stdout, stderr)
+			}
+		}
+	})
+
+	t.Run("json with archived repositories", func(t *testing.T) {
+		listArchived, outputFormat = true, output.JSON
+
+		var b strings.Builder
+		var err error
+		captureStdio(t, func() { err = listVariables(c, &b) })
+		if exitCode(err) != exitCodePartial {
+			t.Errorf("listVariables() error = %v, want exit code %d", err, exitCodePartial)
+		}
+		var got []map[string]any
+		if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+			t.Fatalf("Output is not a JSON array: %v\n%s", err, b.String())
+		}
+		want := []map[string]any{
+			{"name": "URL", "updated_at": "2024-01-02T03:04:05Z", "scope": "acme/api", "repo": "api"},
+			{"name": "LEGACY", "updated_at": "", "scope": "acme/old", "repo": "old"},
+			{"name": "URL", "updated_at": "", "scope": "acme/web", "repo": "web"},
+			{"name": "THEME", "updated_at": "", "scope": "acme/web", "repo": "web"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("listVariables() =\n %v\nwant\n %v", got, want)
+		}
+	})
+}
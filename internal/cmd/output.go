@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// outputAnnotation is the command annotation listing, comma-separated, the
+// --output formats the command writes besides table
+const outputAnnotation = "output-formats"
+
+// outputFormat is the --output format of every command
+var outputFormat = output.Table
+
+// supportOutput records that cmd writes formats besides table with --output
+func supportOutput(cmd *cobra.Command, formats ...string) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[outputAnnotation] = strings.Join(formats, ",")
+}
+
+// outputFormats returns the --output formats cmd supports, table first
+func outputFormats(cmd *cobra.Command) []string {
+	formats := []string{output.Table}
+	if s := cmd.Annotations[outputAnnotation]; s != "" {
+		formats = append(formats, strings.Split(s, ",")...)
+	}
+	return formats
+}
+
+// selectOutput checks --output against the formats cmd supports. Any other
+// format than table sends every message to standard error, so that standard
+// output only carries the payload.
+func selectOutput(cmd *cobra.Command) error {
+	if !output.Valid(outputFormat) {
+		return fmt.Errorf("invalid --output %q: must be %s", outputFormat, joinChoices(output.Formats))
+	}
+	if outputFormat == output.Table {
+		return nil
+	}
+	if formats := outputFormats(cmd); !slices.Contains(formats, outputFormat) {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%s does not support --output %s: must be %s", cmd.CommandPath(), outputFormat, joinChoices(formats))
+	}
+	logger.UseStderr(true)
+	return nil
+}
+
+// joinChoices lists choices for a message: "table", "table or json", or
+// "table, json, or csv"
+func joinChoices(choices []string) string {
+	switch len(choices) {
+	case 1:
+		return choices[0]
+	case 2:
+		return choices[0] + " or " + choices[1]
+	}
+	return strings.Join(choices[:len(choices)-1], ", ") + ", or " + choices[len(choices)-1]
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/spf13/cobra"
+)
+
+func TestSelectOutput(t *testing.T) {
+	origOutput := outputFormat
+	defer func() {
+		outputFormat = origOutput
+		logger.UseStderr(false)
+	}()
+
+	tests := []struct {
+		name    string
+		cmd     *cobra.Command
+		format  string
+		wantErr string
+	}{
+		{name: "table", cmd: profilesListCmd, format: "table"},
+		{name: "json", cmd: listCmd, format: "json"},
+		{name: "csv", cmd: rootCmd, format: "csv"},
+		{name: "unknown", cmd: listCmd, format: "yaml", wantErr: `invalid --output "yaml": must be table, json, or csv`},
+		{name: "unsupported", cmd: auditCmd, format: "csv", wantErr: "gh-vars-migrator audit does not support --output csv: must be table or json"},
+		{name: "table only", cmd: profilesListCmd, format: "json", wantErr: "gh-vars-migrator profiles list does not support --output json: must be table"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger.UseStderr(false)
+			outputFormat = tt.format
+
+			err := selectOutput(tt.cmd)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("selectOutput() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectOutput() unexpected error: %v", err)
+			}
+			if toStderr := logger.Writer() == os.Stderr; toStderr != (tt.format != "table") {
+				t.Errorf("messages sent to standard error = %v with --output %s", toStderr, tt.format)
+			}
+		})
+	}
+}
+
+// TestOutput_StdoutPurity tests that with --output json or csv standard
+// output only carries the payload, and the messages go to standard error
+func TestOutput_StdoutPurity(t *testing.T) {
+	origOutput := outputFormat
+	origOrg, origOwner, origRepo, origEnv, origAllEnvs := listOrg, listOwner, listRepo, listEnv, listAllEnvs
+	defer func() {
+		outputFormat = origOutput
+		listOrg, listOwner, listRepo, listEnv, listAllEnvs = origOrg, origOwner, origRepo, origEnv, origAllEnvs
+		logger.UseStderr(false)
+	}()
+
+	t.Run("list json", func(t *testing.T) {
+		listOrg, listOwner, listRepo, listEnv, listAllEnvs = "acme", "", "", "", false
+		outputFormat = output.JSON
+		c := fakeAPIClient(t, map[string]fakeResponse{
+			"orgs/acme/actions/variables": {http.StatusOK, `{"variables":[{"name":"API_URL","updated_at":"2024-01-02T03:04:05Z"}]}`},
+		})
+
+		var runErr error
+		stdout, stderr := captureStdio(t, func() {
+			if runErr = selectOutput(listCmd); runErr == nil {
+				runErr = listVariables(c, os.Stdout)
+			}
+		})
+		logger.UseStderr(false)
+		if runErr != nil {
+			t.Fatalf("list unexpected error: %v", runErr)
+		}
+		var got []map[string]any
+		if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+			t.Fatalf("standard output is not only the JSON array: %v\n%s", err, stdout)
+		}
+		if len(got) != 1 || got[0]["name"] != "API_URL" {
+			t.Errorf("standard output = %s", stdout)
+		}
+		if !strings.Contains(stderr, "Listing variables for organization: acme") {
+			t.Errorf("standard error = %q, want the log messages", stderr)
+		}
+	})
+
+	t.Run("validate csv", func(t *testing.T) {
+		outputFormat = output.CSV
+		checks := []preflightCheck{
+			{name: "Authentication", detail: "source and target tokens are valid"},
+			{name: "Source and target access", err: errors.New("target repository acme/app not found")},
+			{name: "Variable counts", skipped: true},
+		}
+
+		var runErr error
+		stdout, stderr := captureStdio(t, func() {
+			if runErr = selectOutput(validateCmd); runErr == nil {
+				runErr = reportPreflight(os.Stdout, checks)
+			}
+		})
+		logger.UseStderr(false)
+		if runErr == nil || runErr.Error() != "1 of 3 preflight check(s) failed" {
+			t.Errorf("validate error = %v, want the failed check count", runErr)
+		}
+		want := "check,status,detail\n" +
+			"Authentication,passed,source and target tokens are valid\n" +
+			"Source and target access,failed,target repository acme/app not found\n" +
+			"Variable counts,skipped,\n"
+		if stdout != want {
+			t.Errorf("standard output =\n%s\nwant\n%s", stdout, want)
+		}
+		if !strings.Contains(stderr, "Preflight checklist:") {
+			t.Errorf("standard error = %q, want the log messages", stderr)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+// patStdin is what a --source-pat-file or --target-pat-file of "-" reads;
+// tests replace it
+var patStdin io.Reader = os.Stdin
+
+// readPATFile reads the token in path, or in standard input for "-",
+// without the trailing newline and whitespace that mounted secret files
+// usually end with
+func readPATFile(flag, path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(patStdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read --%s: %w", flag, err)
+	}
+	token := strings.TrimRightFunc(string(data), unicode.IsSpace)
+	if token == "" {
+		return "", fmt.Errorf("--%s %s holds no token", flag, path)
+	}
+	return token, nil
+}
+
+// applyPATFiles fills --source-pat and --target-pat from --source-pat-file
+// and --target-pat-file, which win over SOURCE_PAT, TARGET_PAT, and the
+// profile token variables but not over the PAT flags themselves. Only one
+// side can read standard input. It runs before any API request, so an
+// unreadable file stops the command right away.
+func applyPATFiles(cmd *cobra.Command) error {
+	if cmd.Flags().Lookup("source-pat-file") == nil && cmd.Flags().Lookup("target-pat-file") == nil {
+		return nil
+	}
+	if sourcePATFile == "-" && targetPATFile == "-" {
+		return errors.New("--source-pat-file and --target-pat-file cannot both read standard input")
+	}
+	for _, f := range []struct {
+		flag, patFlag, path, name string
+		pat, patName              *string
+	}{
+		{"source-pat-file", "source-pat", sourcePATFile, "SOURCE_PAT_FILE", &sourcePAT, &sourcePATName},
+		{"target-pat-file", "target-pat", targetPATFile, "TARGET_PAT_FILE", &targetPAT, &targetPATName},
+	} {
+		if f.path == "" || cmd.Flags().Changed(f.patFlag) {
+			continue
+		}
+		token, err := readPATFile(f.flag, f.path)
+		if err != nil {
+			return err
+		}
+		*f.pat = token
+		*f.patName = f.name
+	}
+	return nil
+}
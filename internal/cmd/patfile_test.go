@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// TestApplyPATFiles tests that --source-pat-file and --target-pat-file win
+// over the PAT variables but not over the PAT flags, and read standard input
+func TestApplyPATFiles(t *testing.T) {
+	origSourcePAT, origTargetPAT := sourcePAT, targetPAT
+	origSourceName, origTargetName := sourcePATName, targetPATName
+	origSourceFile, origTargetFile := sourcePATFile, targetPATFile
+	origStdin := patStdin
+	defer func() {
+		sourcePAT, targetPAT = origSourcePAT, origTargetPAT
+		sourcePATName, targetPATName = origSourceName, origTargetName
+		sourcePATFile, targetPATFile = origSourceFile, origTargetFile
+		patStdin = origStdin
+	}()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	sourceFile := write("source", "ghp_fromfile\n")
+	targetFile := write("target", "ghp_target \r\n\n")
+	emptyFile := write("empty", "\n")
+
+	tests := []struct {
+		name           string
+		envSourcePAT   string
+		sourcePATFlag  string
+		sourceFile     string
+		targetFile     string
+		stdin          string
+		wantSource     string
+		wantSourceName string
+		wantTarget     string
+		wantTargetName string
+		wantErr        string
+	}{
+		{
+			name:       "trailing newline and whitespace trimmed",
+			sourceFile: sourceFile, targetFile: targetFile,
+			wantSource: "ghp_fromfile", wantSourceName: "SOURCE_PAT_FILE",
+			wantTarget: "ghp_target", wantTargetName: "TARGET_PAT_FILE",
+		},
+		{
+			name:         "file wins over SOURCE_PAT",
+			envSourcePAT: "ghp_env",
+			sourceFile:   sourceFile,
+			wantSource:   "ghp_fromfile", wantSourceName: "SOURCE_PAT_FILE",
+			wantTargetName: "TARGET_PAT",
+		},
+		{
+			name:          "--source-pat wins over the file",
+			sourcePATFlag: "ghp_flag",
+			sourceFile:    sourceFile,
+			wantSource:    "ghp_flag", wantSourceName: "SOURCE_PAT",
+			wantTargetName: "TARGET_PAT",
+		},
+		{
+			name:         "no file keeps SOURCE_PAT",
+			envSourcePAT: "ghp_env",
+			wantSource:   "ghp_env", wantSourceName: "SOURCE_PAT",
+			wantTargetName: "TARGET_PAT",
+		},
+		{
+			name:       "target from standard input",
+			sourceFile: sourceFile, targetFile: "-",
+			stdin:      "ghp_stdin\n",
+			wantSource: "ghp_fromfile", wantSourceName: "SOURCE_PAT_FILE",
+			wantTarget: "ghp_stdin", wantTargetName: "TARGET_PAT_FILE",
+		},
+		{
+			name:       "both sides from standard input",
+			sourceFile: "-", targetFile: "-",
+			wantErr: "--source-pat-file and --target-pat-file cannot both read standard input",
+		},
+		{
+			name:       "unreadable file",
+			sourceFile: filepath.Join(dir, "missing"),
+			wantErr:    "failed to read --source-pat-file",
+		},
+		{
+			name:       "empty file",
+			targetFile: emptyFile,
+			wantErr:    "holds no token",
+		},
+		{
+			name:       "empty standard input",
+			sourceFile: "-",
+			wantErr:    "--source-pat-file - holds no token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourcePAT, targetPAT = tt.envSourcePAT, ""
+			sourcePATName, targetPATName = "SOURCE_PAT", "TARGET_PAT"
+			sourcePATFile, targetPATFile = tt.sourceFile, tt.targetFile
+			patStdin = strings.NewReader(tt.stdin)
+
+			cmd := &cobra.Command{Use: "x"}
+			cmd.Flags().StringVar(&sourcePAT, "source-pat", sourcePAT, "")
+			cmd.Flags().StringVar(&targetPAT, "target-pat", targetPAT, "")
+			cmd.Flags().StringVar(&sourcePATFile, "source-pat-file", sourcePATFile, "")
+			cmd.Flags().StringVar(&targetPATFile, "target-pat-file", targetPATFile, "")
+			if tt.sourcePATFlag != "" {
+				if err := cmd.Flags().Set("source-pat", tt.sourcePATFlag); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := applyPATFiles(cmd)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyPATFiles() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyPATFiles() unexpected error: %v", err)
+			}
+			if sourcePAT != tt.wantSource || targetPAT != tt.wantTarget {
+				t.Errorf("PATs = %q, %q; want %q, %q", sourcePAT, targetPAT, tt.wantSource, tt.wantTarget)
+			}
+			if sourcePATName != tt.wantSourceName || targetPATName != tt.wantTargetName {
+				t.Errorf("PAT names = %q, %q; want %q, %q", sourcePATName, targetPATName, tt.wantSourceName, tt.wantTargetName)
+			}
+			if got := credentialLabel(sourcePAT, "", sourcePATName, ""); tt.wantSource != "" && got != tt.wantSourceName {
+				t.Errorf("credentialLabel() = %q, want %q", got, tt.wantSourceName)
+			}
+		})
+	}
+}
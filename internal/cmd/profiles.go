@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/profile"
+	"github.com/spf13/cobra"
+)
+
+// profilesCmd groups the commands about connection profiles
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "Inspect the connection profiles of the configuration file",
+	Long: `Connection profiles name the source and target of a recurring migration in
+the configuration file, ~/.config/gh-vars-migrator/config.yaml by default
+($XDG_CONFIG_HOME/gh-vars-migrator/config.yaml when XDG_CONFIG_HOME is set)
+or the file given with --config:
+
+  profiles:
+    ghes-to-cloud:
+      source_org: acme-legacy
+      source_hostname: github.acme.internal
+      source_token_env: GHES_TOKEN
+      target_org: acme
+      target_token_env: CLOUD_TOKEN
+
+--profile NAME fills --source-org, --source-repo, --source-hostname,
+--target-org, --target-repo, and --target-hostname from the profile, and
+--source-pat and --target-pat from the environment variables named by
+source_token_env and target_token_env. Tokens are never stored in the file.
+A flag given on the command line or through its environment variable wins
+over the profile.`,
+}
+
+// profilesListCmd lists the profiles of the configuration file
+var profilesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the connection profiles of the configuration file",
+	Long: `List the profiles of the configuration file with their source, target, and
+the environment variables their tokens are read from. Token values are never
+printed.`,
+	Example: `  # See the profiles before choosing --profile
+  gh vars-migrator profiles list
+
+  # Profiles of another configuration file
+  gh vars-migrator profiles list --config ./migrations.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runProfilesList,
+}
+
+func init() {
+	rootCmd.AddCommand(profilesCmd)
+	profilesCmd.AddCommand(profilesListCmd)
+	profilesListCmd.Flags().StringVar(&configPath, "config", "", "Configuration file holding the connection profiles (default ~/.config/gh-vars-migrator/config.yaml)")
+}
+
+// loadProfiles loads the --config configuration file, or the default one
+func loadProfiles() (*profile.Config, error) {
+	path := configPath
+	if path == "" {
+		p, err := profile.DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+		path = p
+	}
+	return profile.Load(path)
+}
+
+// applyProfile fills the endpoint and token flags of commands taking the
+// migration flags from the --profile profile. A flag set on the command line
+// or through its environment variable keeps its value, and a token variable
+// the profile names must be set.
+func applyProfile(cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Lookup("profile") == nil || profileName == "" {
+		return nil
+	}
+	cmd.SilenceUsage = true
+
+	cfg, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	p, err := cfg.Get(profileName)
+	if err != nil {
+		return err
+	}
+
+	profileFlags = map[string]bool{}
+	for _, f := range []struct {
+		flag, envKey, value string
+		target              *string
+	}{
+		{"source-org", "SOURCE_ORG", p.SourceOrg, &sourceOrg},
+		{"source-repo", "SOURCE_REPO", p.SourceRepo, &sourceRepo},
+		{"source-hostname", "SOURCE_HOSTNAME", p.SourceHostname, &sourceHostname},
+		{"target-org", "TARGET_ORG", p.TargetOrg, &targetOrg},
+		{"target-repo", "TARGET_REPO", p.TargetRepo, &targetRepo},
+		{"target-hostname", "TARGET_HOSTNAME", p.TargetHostname, &targetHostname},
+	} {
+		if f.value == "" || cmd.Flags().Changed(f.flag) || os.Getenv(f.envKey) != "" {
+			continue
+		}
+		*f.target = f.value
+		profileFlags[f.flag] = true
+	}
+
+	for _, t := range []struct {
+		side, flag, envKey, tokenEnv string
+		target, name                 *string
+	}{
+		{"source", "source-pat", "SOURCE_PAT", p.SourceTokenEnv, &sourcePAT, &sourcePATName},
+		{"target", "target-pat", "TARGET_PAT", p.TargetTokenEnv, &targetPAT, &targetPATName},
+	} {
+		if t.tokenEnv == "" || cmd.Flags().Changed(t.flag) || os.Getenv(t.envKey) != "" {
+			continue
+		}
+		token := os.Getenv(t.tokenEnv)
+		if token == "" {
+			return fmt.Errorf("profile %s reads its %s token from %s, which is not set", profileName, t.side, t.tokenEnv)
+		}
+		*t.target = token
+		*t.name = t.tokenEnv
+		profileFlags[t.flag] = true
+	}
+	return nil
+}
+
+func runProfilesList(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	cfg, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	return writeProfiles(cmd.OutOrStdout(), cfg)
+}
+
+// writeProfiles writes one row per profile. Only the names of the token
+// variables are shown.
+func writeProfiles(w io.Writer, cfg *profile.Config) error {
+	names := cfg.Names()
+	if len(names) == 0 {
+		_, err := fmt.Fprintln(w, "No profiles defined")
+		return err
+	}
+
+	fmt.Fprintf(w, "%-20s %-36s %-36s %s\n", "NAME", "SOURCE", "TARGET", "TOKENS")
+	fmt.Fprintf(w, "%-20s %-36s %-36s %s\n", "----", "------", "------", "------")
+	for _, name := range names {
+		p := cfg.Profiles[name]
+		fmt.Fprintf(w, "%-20s %-36s %-36s %s\n", name,
+			profileEndpoint(p.SourceOrg, p.SourceRepo, p.SourceHostname),
+			profileEndpoint(p.TargetOrg, p.TargetRepo, p.TargetHostname),
+			orDash(p.SourceTokenEnv)+", "+orDash(p.TargetTokenEnv))
+	}
+	return nil
+}
+
+// profileEndpoint describes one side of a profile, e.g. "acme/app" or
+// "acme on github.acme.internal"
+func profileEndpoint(org, repo, hostname string) string {
+	s := orDash(org)
+	if repo != "" {
+		s += "/" + repo
+	}
+	if hostname != "" {
+		s += " on " + hostname
+	}
+	return s
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
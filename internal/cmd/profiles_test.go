@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// profileCommand returns a command with the flags applyProfile reads; the
+// flags named in set are marked as given on the command line
+func profileCommand(t *testing.T, set ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{Use: "x"}
+	for _, name := range []string{"profile", "source-org", "source-repo", "source-hostname", "source-pat", "target-org", "target-repo", "target-hostname", "target-pat"} {
+		cmd.Flags().String(name, "", "")
+	}
+	for _, name := range set {
+		if err := cmd.Flags().Set(name, "x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cmd
+}
+
+// TestApplyProfile tests that a flag wins over its environment variable,
+// which wins over the --profile profile
+func TestApplyProfile(t *testing.T) {
+	origs := []*string{&sourceOrg, &sourceRepo, &sourceHostname, &sourcePAT, &targetOrg, &targetRepo, &targetHostname, &targetPAT, &configPath, &profileName, &sourcePATName, &targetPATName}
+	saved := make([]string, len(origs))
+	for i, p := range origs {
+		saved[i] = *p
+	}
+	origFlags := profileFlags
+	defer func() {
+		for i, p := range origs {
+			*p = saved[i]
+		}
+		profileFlags = origFlags
+	}()
+	for _, key := range []string{"SOURCE_ORG", "SOURCE_REPO", "SOURCE_HOSTNAME", "SOURCE_PAT", "TARGET_ORG", "TARGET_REPO", "TARGET_HOSTNAME", "TARGET_PAT"} {
+		t.Setenv(key, "")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(`profiles:
+  app:
+    source_org: profile-src
+    source_repo: app
+    source_hostname: github.example.com
+    source_token_env: PROFILE_SOURCE_TOKEN
+    target_org: profile-dst
+    target_repo: app-new
+    target_token_env: PROFILE_TARGET_TOKEN
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reset := func() {
+		sourceOrg, sourceRepo, sourceHostname, sourcePAT = "", "", "", ""
+		targetOrg, targetRepo, targetHostname, targetPAT = "", "", "", ""
+		sourcePATName, targetPATName = "SOURCE_PAT", "TARGET_PAT"
+		configPath, profileName, profileFlags = path, "app", nil
+	}
+
+	t.Run("precedence", func(t *testing.T) {
+		reset()
+		t.Setenv("PROFILE_SOURCE_TOKEN", "ghp_profile_source")
+		t.Setenv("PROFILE_TARGET_TOKEN", "ghp_profile_target")
+		// --source-org and --target-pat on the command line, TARGET_ORG in
+		// the environment, which the flag picked up as its default
+		cmd := profileCommand(t, "source-org", "target-pat")
+		sourceOrg, targetPAT = "flag-src", "ghp_flag_target"
+		t.Setenv("TARGET_ORG", "env-dst")
+		targetOrg = "env-dst"
+
+		if err := applyProfile(cmd, nil); err != nil {
+			t.Fatalf("applyProfile() unexpected error: %v", err)
+		}
+		for _, tt := range []struct{ name, got, want string }{
+			{"sourceOrg", sourceOrg, "flag-src"},
+			{"sourceRepo", sourceRepo, "app"},
+			{"sourceHostname", sourceHostname, "github.example.com"},
+			{"sourcePAT", sourcePAT, "ghp_profile_source"},
+			{"sourcePATName", sourcePATName, "PROFILE_SOURCE_TOKEN"},
+			{"targetOrg", targetOrg, "env-dst"},
+			{"targetRepo", targetRepo, "app-new"},
+			{"targetPAT", targetPAT, "ghp_flag_target"},
+			{"targetPATName", targetPATName, "TARGET_PAT"},
+			{"source-repo source", flagSource(cmd, "source-repo", "SOURCE_REPO"), "profile app"},
+			{"source-org source", flagSource(cmd, "source-org", "SOURCE_ORG"), "--source-org (CLI flag)"},
+			{"target-org source", flagSource(cmd, "target-org", "TARGET_ORG"), "TARGET_ORG (env var)"},
+		} {
+			if tt.got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+			}
+		}
+	})
+
+	t.Run("no profile", func(t *testing.T) {
+		reset()
+		profileName = ""
+		if err := applyProfile(profileCommand(t), nil); err != nil || sourceOrg != "" {
+			t.Errorf("applyProfile() = %v with sourceOrg %q; want nothing applied", err, sourceOrg)
+		}
+	})
+
+	t.Run("command without --profile", func(t *testing.T) {
+		reset()
+		if err := applyProfile(&cobra.Command{Use: "x"}, nil); err != nil || sourceOrg != "" {
+			t.Errorf("applyProfile() = %v with sourceOrg %q; want nothing applied", err, sourceOrg)
+		}
+	})
+
+	t.Run("token variable not set", func(t *testing.T) {
+		reset()
+		t.Setenv("PROFILE_SOURCE_TOKEN", "")
+		err := applyProfile(profileCommand(t), nil)
+		if err == nil || !strings.Contains(err.Error(), "PROFILE_SOURCE_TOKEN, which is not set") {
+			t.Errorf("applyProfile() error = %v, want the missing token variable", err)
+		}
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		reset()
+		profileName = "prod"
+		err := applyProfile(profileCommand(t), nil)
+		if err == nil || !strings.Contains(err.Error(), `profile "prod" not found in `+path) {
+			t.Errorf("applyProfile() error = %v, want the unknown profile and the file", err)
+		}
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		reset()
+		configPath = filepath.Join(t.TempDir(), "broken.yaml")
+		if err := os.WriteFile(configPath, []byte("profiles:\n  app: [\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		err := applyProfile(profileCommand(t), nil)
+		if err == nil || !strings.Contains(err.Error(), configPath+": invalid configuration") {
+			t.Errorf("applyProfile() error = %v, want the file and the parse error", err)
+		}
+	})
+}
+
+// TestWriteProfiles tests that profiles are listed with the names of their
+// token variables and never the tokens
+func TestWriteProfiles(t *testing.T) {
+	origPath := configPath
+	defer func() { configPath = origPath }()
+	t.Setenv("CLOUD_TOKEN", "ghp_secret_value")
+
+	configPath = filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(`profiles:
+  ghes-to-cloud:
+    source_org: acme-legacy
+    source_hostname: github.acme.internal
+    source_token_env: GHES_TOKEN
+    target_org: acme
+    target_token_env: CLOUD_TOKEN
+  app:
+    source_org: acme
+    source_repo: app
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadProfiles()
+	if err != nil {
+		t.Fatalf("loadProfiles() unexpected error: %v", err)
+	}
+
+	var b strings.Builder
+	if err := writeProfiles(&b, cfg); err != nil {
+		t.Fatalf("writeProfiles() unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("writeProfiles() wrote %d lines, want 4:\n%s", len(lines), b.String())
+	}
+	for i, want := range [][]string{
+		{"app", "acme/app", "-, -"},
+		{"ghes-to-cloud", "acme-legacy on github.acme.internal", "acme", "GHES_TOKEN, CLOUD_TOKEN"},
+	} {
+		for _, w := range want {
+			if !strings.Contains(lines[i+2], w) {
+				t.Errorf("line %q does not contain %q", lines[i+2], w)
+			}
+		}
+	}
+	if strings.Contains(b.String(), "ghp_secret_value") {
+		t.Errorf("writeProfiles() printed a token:\n%s", b.String())
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"golang.org/x/term"
+)
+
+// enteredTokenLabel names a token typed at the prompt in credential
+// messages
+const enteredTokenLabel = "Entered token"
+
+// tokenPrompt asks for the personal access token of a side that has none.
+// Its functions stand in for the terminal, the GitHub CLI, and the GitHub
+// API in tests. The entered tokens are only kept in memory.
+type tokenPrompt struct {
+	out io.Writer
+	// readSecret reads a line from the terminal without echoing it
+	readSecret func() (string, error)
+	// cliToken reports whether the GitHub CLI has a token for host
+	cliToken  func(host string) bool
+	newClient func(token, hostname, side string) (*client.Client, error)
+}
+
+// newTokenPrompt returns the prompt of resolveTokens, or nil when standard
+// input is not a terminal to read a token from; tests replace it
+var newTokenPrompt = func() *tokenPrompt {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	return &tokenPrompt{
+		out: os.Stderr,
+		readSecret: func() (string, error) {
+			b, err := term.ReadPassword(int(os.Stdin.Fd()))
+			return string(b), err
+		},
+		cliToken: func(host string) bool {
+			return cliTokenForHost(host) != ""
+		},
+		newClient: createClientWithToken,
+	}
+}
+
+// needs reports whether a side with token, on hostname, has to be asked
+// for one: it has none, and either the other side has one, since the two
+// sides do not mix a token with the GitHub CLI authentication, or the
+// GitHub CLI is not logged in to hostname either
+func (p *tokenPrompt) needs(token, otherToken, hostname string) bool {
+	if token != "" {
+		return false
+	}
+	if hostname == "" {
+		hostname = "github.com"
+	}
+	return otherToken != "" || !p.cliToken(hostname)
+}
+
+// ask prompts for the token of side, whose organization is org, and checks
+// that it authenticates to hostname before returning it
+func (p *tokenPrompt) ask(side, org, hostname string) (string, error) {
+	label := side
+	if org != "" {
+		label += " (" + org + ")"
+	}
+	fmt.Fprintf(p.out, "Enter PAT for %s: ", label)
+	token, err := p.readSecret()
+	// The terminal does not echo the line break either
+	fmt.Fprintln(p.out)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the %s token: %w", side, err)
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", errors.New("no " + side + " token entered")
+	}
+
+	c, err := p.newClient(token, hostname, side)
+	if err != nil {
+		return "", err
+	}
+	login, err := c.GetUser()
+	if err != nil {
+		return "", fmt.Errorf("the entered %s token does not authenticate: %w", side, err)
+	}
+	logger.Success("The %s token authenticates as %s", side, login)
+	return token, nil
+}
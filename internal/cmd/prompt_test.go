@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+)
+
+// fakeTokenPrompt returns a prompt that reads the inputs in turn, where the
+// GitHub CLI is logged in to cliHosts, and where only the token "ghp_good"
+// authenticates
+func fakeTokenPrompt(t *testing.T, out io.Writer, cliHosts []string, inputs ...string) *tokenPrompt {
+	return &tokenPrompt{
+		out: out,
+		readSecret: func() (string, error) {
+			if len(inputs) == 0 {
+				return "", io.EOF
+			}
+			input := inputs[0]
+			inputs = inputs[1:]
+			return input, nil
+		},
+		cliToken: func(host string) bool { return slices.Contains(cliHosts, host) },
+		newClient: func(token, hostname, side string) (*client.Client, error) {
+			if token == "ghp_good" {
+				return fakeAPIClient(t, map[string]fakeResponse{"user": {http.StatusOK, `{"login":"octocat"}`}}), nil
+			}
+			return fakeAPIClient(t, map[string]fakeResponse{"user": {http.StatusUnauthorized, `{"message":"Bad credentials"}`}}), nil
+		},
+	}
+}
+
+func TestTokenPrompt_Ask(t *testing.T) {
+	tests := []struct {
+		name    string
+		org     string
+		input   []string
+		want    string
+		wantErr string
+	}{
+		{name: "ghp_good", org: "acme", input: []string{"ghp_good"}, want: "ghp_good"},
+		{name: "surrounding spaces", input: []string{"  ghp_good\r"}, want: "ghp_good"},
+		{name: "invalid", org: "acme", input: []string{"ghp_wrong"}, wantErr: "the entered target token does not authenticate"},
+		{name: "empty", org: "acme", input: []string{""}, wantErr: "no target token entered"},
+		{name: "end of input", wantErr: "failed to read the target token: EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			p := fakeTokenPrompt(t, &out, nil, tt.input...)
+
+			var token string
+			var err error
+			captureStdio(t, func() { token, err = p.ask("target", tt.org, "ghes.corp.example") })
+			wantPrompt := "Enter PAT for target: \n"
+			if tt.org != "" {
+				wantPrompt = "Enter PAT for target (" + tt.org + "): \n"
+			}
+			if out.String() != wantPrompt {
+				t.Errorf("Prompt = %q, want %q", out.String(), wantPrompt)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ask() error = %v, want containing %q", err, tt.wantErr)
+				}
+				if strings.Contains(err.Error(), "ghp_wrong") {
+					t.Errorf("ask() error %q shows the entered token", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ask() unexpected error: %v", err)
+			}
+			if token != tt.want {
+				t.Errorf("ask() = %q, want %q", token, tt.want)
+			}
+		})
+	}
+}
+
+// TestResolveTokens_Prompt tests that the sides left without a token are
+// asked for one on a terminal, and that the entered tokens are not logged
+func TestResolveTokens_Prompt(t *testing.T) {
+	origSourcePAT, origTargetPAT := sourcePAT, targetPAT
+	origSourceHostname, origTargetHostname := sourceHostname, targetHostname
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origPrompt := newTokenPrompt
+	defer func() {
+		sourcePAT, targetPAT = origSourcePAT, origTargetPAT
+		sourceHostname, targetHostname = origSourceHostname, origTargetHostname
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		newTokenPrompt = origPrompt
+	}()
+	t.Setenv("GITHUB_TOKEN", "")
+
+	tests := []struct {
+		name       string
+		sourcePAT  string
+		cliHosts   []string
+		inputs     []string
+		wantSource string
+		wantTarget string
+		wantPrompt string
+		wantErr    string
+	}{
+		{
+			name:       "target of a source PAT",
+			sourcePAT:  "source-pat",
+			cliHosts:   []string{"github.com", "ghes.corp.example"},
+			inputs:     []string{"ghp_good"},
+			wantSource: "source-pat", wantTarget: "ghp_good",
+			wantPrompt: "Enter PAT for target (corp): \n",
+		},
+		{
+			name:       "GitHub CLI logged in to both hosts",
+			cliHosts:   []string{"github.com", "ghes.corp.example"},
+			wantPrompt: "",
+		},
+		{
+			name:       "GitHub CLI logged in to the source host only",
+			cliHosts:   []string{"github.com"},
+			inputs:     []string{"ghp_good"},
+			wantTarget: "ghp_good",
+			wantPrompt: "Enter PAT for target (corp): \n",
+		},
+		{
+			name:       "no GitHub CLI login",
+			inputs:     []string{"ghp_good", "ghp_good"},
+			wantSource: "ghp_good", wantTarget: "ghp_good",
+			wantPrompt: "Enter PAT for source (acme): \nEnter PAT for target (corp): \n",
+		},
+		{
+			name:       "invalid token",
+			sourcePAT:  "source-pat",
+			inputs:     []string{"ghp_wrong"},
+			wantPrompt: "Enter PAT for target (corp): \n",
+			wantErr:    "the entered target token does not authenticate",
+		},
+		{
+			name:       "empty token",
+			sourcePAT:  "source-pat",
+			inputs:     []string{""},
+			wantPrompt: "Enter PAT for target (corp): \n",
+			wantErr:    "no target token entered",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourcePAT, targetPAT = tt.sourcePAT, ""
+			sourceHostname, targetHostname = "", "ghes.corp.example"
+			sourceOrg, targetOrg = "acme", "corp"
+			var prompt strings.Builder
+			newTokenPrompt = func() *tokenPrompt { return fakeTokenPrompt(t, &prompt, tt.cliHosts, tt.inputs...) }
+
+			var sourceToken, targetToken string
+			var err error
+			stdout, stderr := captureStdio(t, func() { sourceToken, targetToken, err = resolveTokens() })
+			if prompt.String() != tt.wantPrompt {
+				t.Errorf("Prompts = %q, want %q", prompt.String(), tt.wantPrompt)
+			}
+			if strings.Contains(stdout+stderr, "ghp_") {
+				t.Errorf("The entered token was logged:\n%s%s", stdout, stderr)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveTokens() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveTokens() unexpected error: %v", err)
+			}
+			if sourceToken != tt.wantSource || targetToken != tt.wantTarget {
+				t.Errorf("resolveTokens() = %q, %q; want %q, %q", sourceToken, targetToken, tt.wantSource, tt.wantTarget)
+			}
+			if tt.wantTarget == "ghp_good" && !strings.Contains(stdout, "Entered token used for Target Org corp") {
+				t.Errorf("Expected the entered target token to be reported, got:\n%s", stdout)
+			}
+		})
+	}
+}
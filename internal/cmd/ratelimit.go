@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// ratelimitCmd shows the rate limits left to the source and target tokens
+var ratelimitCmd = &cobra.Command{
+	Use:   "ratelimit",
+	Short: "Show the API rate limits left to the source and target tokens",
+	Long: `Show how many API requests the source and target tokens have left, and when
+their limits reset in local time, for the REST (core) and GraphQL resources.
+
+The tokens and hostnames are resolved as for a migration: --source-pat and
+--target-pat, then SOURCE_PAT and TARGET_PAT, then GITHUB_TOKEN, otherwise the
+GitHub CLI authentication, with --source-hostname and --target-hostname.
+Checking the rate limit does not count against it.
+
+--output json writes a JSON array of {side, host, resources} objects to
+standard output instead of the table, and --output csv one row per side and
+resource; every other message then goes to standard error.`,
+	Example: `  # Check the limits before a large migration
+  gh vars-migrator ratelimit
+
+  # Check the limits of two tokens as JSON
+  gh vars-migrator ratelimit --source-pat "$SRC" --target-pat "$DST" --output json`,
+	PreRunE:       validateRatelimitFlags,
+	RunE:          runRatelimit,
+	SilenceErrors: true,
+}
+
+// rateLimitResources are the resources shown, in order: the REST API the
+// migration uses, and GraphQL
+var rateLimitResources = []string{"core", "graphql"}
+
+func init() {
+	rootCmd.AddCommand(ratelimitCmd)
+	supportOutput(ratelimitCmd, output.JSON, output.CSV)
+	// The migration flags are added by the root command once it has
+	// registered them.
+}
+
+// ratelimitFlags are the flags ratelimit accepts besides its own: the
+// credentials and hosts of both sides, or a profile holding them
+var ratelimitFlags = map[string]bool{
+	"output": true, "verbose": true, "quiet": true, "no-color": true, "no-annotations": true, "env-file": true,
+	"source-pat": true, "target-pat": true, "source-pat-file": true, "target-pat-file": true, "source-hostname": true, "target-hostname": true, "no-keyring": true,
+	"source-org": true, "target-org": true, "config": true, "profile": true,
+}
+
+// validateRatelimitFlags checks the credential flags
+func validateRatelimitFlags(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if err := rejectFlags(cmd, "ratelimit", func(name string) bool { return ratelimitFlags[name] }); err != nil {
+		return err
+	}
+	sourceHostname = normalizeHostname(sourceHostname)
+	targetHostname = normalizeHostname(targetHostname)
+	return nil
+}
+
+func runRatelimit(cmd *cobra.Command, args []string) error {
+	sourceToken, targetToken, err := resolveTokens()
+	if err != nil {
+		return authError(err)
+	}
+	sourceClient, targetClient, err := createClients(sourceToken, targetToken)
+	if err != nil {
+		return authError(err)
+	}
+
+	var sides []sideRateLimits
+	for _, side := range []struct {
+		name, hostname string
+		client         *client.Client
+	}{
+		{"source", sourceHostname, sourceClient},
+		{"target", targetHostname, targetClient},
+	} {
+		s, err := readRateLimits(side.name, side.hostname, side.client)
+		if err != nil {
+			return err
+		}
+		sides = append(sides, s)
+	}
+	return writeRateLimits(cmd.OutOrStdout(), sides)
+}
+
+// sideRateLimits are the rate limits of the source or target token
+type sideRateLimits struct {
+	Side      string                   `json:"side"`
+	Host      string                   `json:"host"`
+	Resources map[string]rateLimitItem `json:"resources"`
+}
+
+// rateLimitItem is the limit of one API resource. Reset is in local time.
+type rateLimitItem struct {
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+	Used      int    `json:"used"`
+	Reset     string `json:"reset"`
+	resetAt   time.Time
+}
+
+// readRateLimits reads the limits of the shown resources the host reports.
+// GitHub Enterprise Server answers 404 when rate limiting is disabled, which
+// leaves the resources empty.
+func readRateLimits(side, hostname string, c *client.Client) (sideRateLimits, error) {
+	s := sideRateLimits{Side: side, Host: hostLabel(hostname), Resources: map[string]rateLimitItem{}}
+	limits, err := c.GetRateLimits()
+	if client.IsNotFound(err) {
+		return s, nil
+	}
+	if err != nil {
+		return sideRateLimits{}, fmt.Errorf("%s: %w", side, err)
+	}
+	for _, name := range rateLimitResources {
+		rl, ok := limits[name]
+		if !ok {
+			continue
+		}
+		s.Resources[name] = rateLimitItem{
+			Limit:     rl.Limit,
+			Remaining: rl.Remaining,
+			Used:      rl.Limit - rl.Remaining,
+			Reset:     rl.ResetTime.Local().Format(time.RFC3339),
+			resetAt:   rl.ResetTime.Local(),
+		}
+	}
+	return s, nil
+}
+
+// writeRateLimits writes one row per side and resource, or the JSON array
+func writeRateLimits(w io.Writer, sides []sideRateLimits) error {
+	switch outputFormat {
+	case output.JSON:
+		return output.WriteJSON(w, sides)
+	case output.CSV:
+		var rows [][]string
+		for _, s := range sides {
+			for _, name := range rateLimitResources {
+				if r, ok := s.Resources[name]; ok {
+					rows = append(rows, []string{s.Side, s.Host, name, strconv.Itoa(r.Remaining), strconv.Itoa(r.Limit), strconv.Itoa(r.Used), r.Reset})
+				}
+			}
+		}
+		return output.WriteCSV(w, []string{"side", "host", "resource", "remaining", "limit", "used", "reset"}, rows)
+	}
+
+	fmt.Fprintf(w, "%-8s %-24s %-9s %-10s %-7s %s\n", "SIDE", "HOST", "RESOURCE", "REMAINING", "LIMIT", "RESETS AT")
+	fmt.Fprintf(w, "%-8s %-24s %-9s %-10s %-7s %s\n", "----", "----", "--------", "---------", "-----", "---------")
+	for _, s := range sides {
+		if len(s.Resources) == 0 {
+			fmt.Fprintf(w, "%-8s %-24s %s\n", s.Side, s.Host, "no rate limit reported")
+			continue
+		}
+		for _, name := range rateLimitResources {
+			r, ok := s.Resources[name]
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(w, "%-8s %-24s %-9s %-10d %-7d %s\n", s.Side, s.Host, name, r.Remaining, r.Limit, r.resetAt.Format("2006-01-02 15:04:05 MST"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+)
+
+func TestRateLimits(t *testing.T) {
+	origOutput := outputFormat
+	defer func() { outputFormat = origOutput }()
+
+	source := fakeAPIClient(t, map[string]fakeResponse{
+		"rate_limit": {http.StatusOK, `{"resources":{
+			"core":{"limit":5000,"remaining":4990,"reset":1700000000},
+			"graphql":{"limit":5000,"remaining":5000,"reset":1700003600},
+			"search":{"limit":30,"remaining":30,"reset":1700000060}}}`},
+	})
+	// A GitHub Enterprise Server host without rate limiting
+	target := fakeAPIClient(t, map[string]fakeResponse{})
+
+	var sides []sideRateLimits
+	for _, side := range []struct {
+		name, hostname string
+		client         *client.Client
+	}{{"source", "", source}, {"target", "github.example.com", target}} {
+		s, err := readRateLimits(side.name, side.hostname, side.client)
+		if err != nil {
+			t.Fatalf("readRateLimits(%s) unexpected error: %v", side.name, err)
+		}
+		sides = append(sides, s)
+	}
+	coreReset := time.Unix(1700000000, 0).Local()
+	graphqlReset := time.Unix(1700003600, 0).Local()
+
+	t.Run("table", func(t *testing.T) {
+		outputFormat = output.Table
+		var b strings.Builder
+		if err := writeRateLimits(&b, sides); err != nil {
+			t.Fatalf("writeRateLimits() unexpected error: %v", err)
+		}
+		want := "SIDE     HOST                     RESOURCE  REMAINING  LIMIT   RESETS AT\n" +
+			"----     ----                     --------  ---------  -----   ---------\n" +
+			"source   github.com               core      4990       5000    " + coreReset.Format("2006-01-02 15:04:05 MST") + "\n" +
+			"source   github.com               graphql   5000       5000    " + graphqlReset.Format("2006-01-02 15:04:05 MST") + "\n" +
+			"target   github.example.com       no rate limit reported\n"
+		if b.String() != want {
+			t.Errorf("writeRateLimits() wrote\n%s\nwant\n%s", b.String(), want)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		outputFormat = output.JSON
+		var b strings.Builder
+		if err := writeRateLimits(&b, sides); err != nil {
+			t.Fatalf("writeRateLimits() unexpected error: %v", err)
+		}
+		var got []map[string]any
+		if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+			t.Fatalf("Output is not a JSON array: %v\n%s", err, b.String())
+		}
+		want := []map[string]any{
+			{"side": "source", "host": "github.com", "resources": map[string]any{
+				"core":    map[string]any{"limit": 5000.0, "remaining": 4990.0, "used": 10.0, "reset": coreReset.Format(time.RFC3339)},
+				"graphql": map[string]any{"limit": 5000.0, "remaining": 5000.0, "used": 0.0, "reset": graphqlReset.Format(time.RFC3339)},
+			}},
+			{"side": "target", "host": "github.example.com", "resources": map[string]any{}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("writeRateLimits() =\n %v\nwant\n %v", got, want)
+		}
+	})
+}
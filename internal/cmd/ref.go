@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ref is an organization or repository named by a shorthand flag; host is
+// set when it was named by a URL
+type ref struct {
+	host  string
+	owner string
+	repo  string
+}
+
+// parseRef parses the value of the shorthand flag named flag: OWNER or
+// OWNER/REPO, like the -R flag of the GitHub CLI, or the URL of an
+// organization or repository as copied from the browser; repo is empty for
+// an owner alone
+func parseRef(flag, value string) (ref, error) {
+	if isRefURL(value) {
+		return parseRefURL(flag, value)
+	}
+	owner, repo, hasRepo := strings.Cut(value, "/")
+	if owner == "" || (hasRepo && repo == "") || strings.Contains(repo, "/") {
+		return ref{}, fmt.Errorf("invalid --%s %q: must be OWNER, OWNER/REPO, or a GitHub URL", flag, value)
+	}
+	return ref{owner: owner, repo: repo}, nil
+}
+
+// isRefURL reports whether value is a URL rather than OWNER[/REPO] or a
+// repository name: it has an http or https scheme, or a path after a host
+// name, which unlike an owner has a dot
+func isRefURL(value string) bool {
+	if strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://") {
+		return true
+	}
+	first, _, hasPath := strings.Cut(value, "/")
+	return hasPath && strings.Contains(first, ".")
+}
+
+// parseRefURL parses the URL of an organization or repository. A .git
+// suffix and the path past the repository, e.g. /settings/variables/actions,
+// are left out, and the /orgs/ and /organizations/ pages name an
+// organization.
+func parseRefURL(flag, value string) (ref, error) {
+	raw := value
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return ref{}, fmt.Errorf("invalid --%s %q: not a valid URL", flag, value)
+	}
+
+	var segments []string
+	for _, s := range strings.Split(u.Path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	r := ref{host: strings.ToLower(strings.TrimPrefix(u.Hostname(), "www."))}
+	switch {
+	case len(segments) >= 2 && (segments[0] == "orgs" || segments[0] == "organizations"):
+		r.owner = segments[1]
+	case len(segments) >= 2:
+		r.owner, r.repo = segments[0], strings.TrimSuffix(segments[1], ".git")
+		if r.repo == "" {
+			return ref{}, fmt.Errorf("invalid --%s %q: the URL has no repository name", flag, value)
+		}
+	case len(segments) == 1 && segments[0] != "orgs" && segments[0] != "organizations":
+		r.owner = segments[0]
+	default:
+		return ref{}, fmt.Errorf("invalid --%s %q: the URL names no organization or repository", flag, value)
+	}
+	return r, nil
+}
+
+// sameHost reports whether two host names are the same GitHub host;
+// the API host api.HOST counts as HOST
+func sameHost(a, b string) bool {
+	trim := func(h string) string {
+		h = strings.ToLower(normalizeHostname(h))
+		return strings.TrimPrefix(strings.TrimPrefix(h, "www."), "api.")
+	}
+	return trim(a) == trim(b)
+}
+
+// applyRefHost sets hostname, when unset, to the host of r named by flag;
+// github.com is the default host and is left unset. A hostname already set
+// to another host is an error rather than a silent switch of hosts.
+func applyRefHost(flag, hostnameFlag string, r ref, hostname *string) (bool, error) {
+	switch {
+	case r.host == "":
+		return false, nil
+	case *hostname == "":
+		if r.host == "github.com" {
+			return false, nil
+		}
+		*hostname = r.host
+		return true, nil
+	case !sameHost(*hostname, r.host):
+		return false, fmt.Errorf("--%s is on %s, but --%s is %s", flag, r.host, hostnameFlag, *hostname)
+	}
+	return false, nil
+}
+
+// applyRef fills org and repo from the --source or --target shorthand of
+// side. --SIDE-org and --SIDE-repo win when given on the command line,
+// while the values the environment or a profile set give way to the
+// shorthand. A URL, in the shorthand or in --SIDE-org or --SIDE-repo,
+// also sets --SIDE-hostname. refFlags records the flags it filled for
+// flagSource.
+func applyRef(cmd *cobra.Command, side, value string, org, repo, hostname *string) error {
+	if value != "" {
+		r, err := parseRef(side, value)
+		if err != nil {
+			return err
+		}
+		for _, f := range []struct {
+			flag, value string
+			target      *string
+		}{
+			{side + "-org", r.owner, org},
+			{side + "-repo", r.repo, repo},
+		} {
+			if cmd.Flags().Changed(f.flag) {
+				continue
+			}
+			*f.target = f.value
+			refFlags[f.flag] = side
+		}
+		set, err := applyRefHost(side, side+"-hostname", r, hostname)
+		if err != nil {
+			return err
+		}
+		if set {
+			refFlags[side+"-hostname"] = side
+		}
+	}
+	return applyURLFlags(side, org, repo, hostname)
+}
+
+// applyURLFlags replaces a URL pasted into --SIDE-org or --SIDE-repo with
+// the organization and repository it names. The organization of a
+// repository URL must match --SIDE-org when both are set.
+func applyURLFlags(side string, org, repo, hostname *string) error {
+	orgFlag, repoFlag, hostnameFlag := side+"-org", side+"-repo", side+"-hostname"
+	setHost := func(flag string, r ref) error {
+		set, err := applyRefHost(flag, hostnameFlag, r, hostname)
+		if set {
+			refFlags[hostnameFlag] = flag
+		}
+		return err
+	}
+	if isRefURL(*org) {
+		r, err := parseRefURL(orgFlag, *org)
+		if err != nil {
+			return err
+		}
+		if r.repo != "" {
+			return fmt.Errorf("invalid --%s %q: the URL names a repository; use --%s or --%s", orgFlag, *org, side, repoFlag)
+		}
+		if err := setHost(orgFlag, r); err != nil {
+			return err
+		}
+		*org = r.owner
+	}
+	if isRefURL(*repo) {
+		r, err := parseRefURL(repoFlag, *repo)
+		if err != nil {
+			return err
+		}
+		if r.repo == "" {
+			return fmt.Errorf("invalid --%s %q: the URL names no repository", repoFlag, *repo)
+		}
+		if *org != "" && !strings.EqualFold(*org, r.owner) {
+			return fmt.Errorf("--%s %s does not match the owner %s of --%s %s", orgFlag, *org, r.owner, repoFlag, *repo)
+		}
+		if err := setHost(repoFlag, r); err != nil {
+			return err
+		}
+		*org, *repo = r.owner, r.repo
+	}
+	return nil
+}
+
+// applyRefs applies the --source and --target shorthands and the URLs of
+// the org and repo flags
+func applyRefs(cmd *cobra.Command) error {
+	refFlags = map[string]string{}
+	if err := applyRef(cmd, "source", sourceRef, &sourceOrg, &sourceRepo, &sourceHostname); err != nil {
+		return err
+	}
+	return applyRef(cmd, "target", targetRef, &targetOrg, &targetRepo, &targetHostname)
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    ref
+		wantErr string
+	}{
+		{name: "owner only", value: "acme", want: ref{owner: "acme"}},
+		{name: "owner and repo", value: "acme/app", want: ref{owner: "acme", repo: "app"}},
+		{name: "repo with a dot", value: "acme/app.js", want: ref{owner: "acme", repo: "app.js"}},
+		{name: "extra slash", value: "acme/app/extra", wantErr: `invalid --source "acme/app/extra": must be OWNER, OWNER/REPO, or a GitHub URL`},
+		{name: "double slash", value: "acme//app", wantErr: `invalid --source "acme//app": must be OWNER, OWNER/REPO, or a GitHub URL`},
+		{name: "empty owner", value: "/app", wantErr: `invalid --source "/app": must be OWNER, OWNER/REPO, or a GitHub URL`},
+		{name: "empty repo", value: "acme/", wantErr: `invalid --source "acme/": must be OWNER, OWNER/REPO, or a GitHub URL`},
+		{name: "slash only", value: "/", wantErr: `invalid --source "/": must be OWNER, OWNER/REPO, or a GitHub URL`},
+		{name: "empty", value: "", wantErr: `invalid --source "": must be OWNER, OWNER/REPO, or a GitHub URL`},
+
+		// github.com URLs
+		{name: "repository URL", value: "https://github.com/acme/api", want: ref{host: "github.com", owner: "acme", repo: "api"}},
+		{name: "clone URL", value: "https://github.com/acme/api.git", want: ref{host: "github.com", owner: "acme", repo: "api"}},
+		{name: "settings page", value: "https://github.com/acme/api/settings/variables/actions", want: ref{host: "github.com", owner: "acme", repo: "api"}},
+		{name: "trailing slash and query", value: "https://www.github.com/acme/api/?tab=readme#top", want: ref{host: "github.com", owner: "acme", repo: "api"}},
+		{name: "organization URL", value: "https://github.com/acme", want: ref{host: "github.com", owner: "acme"}},
+		{name: "organization page", value: "https://github.com/orgs/acme/repositories", want: ref{host: "github.com", owner: "acme"}},
+		{name: "organization settings", value: "https://github.com/organizations/acme/settings/variables/actions", want: ref{host: "github.com", owner: "acme"}},
+		{name: "without scheme", value: "github.com/acme/api", want: ref{host: "github.com", owner: "acme", repo: "api"}},
+
+		// GitHub Enterprise Server and data residency URLs
+		{name: "GHES repository", value: "https://ghes.corp.example/acme/api", want: ref{host: "ghes.corp.example", owner: "acme", repo: "api"}},
+		{name: "GHES with port", value: "http://GHES.corp.example:8080/acme/api.git", want: ref{host: "ghes.corp.example", owner: "acme", repo: "api"}},
+		{name: "data residency", value: "https://acme.ghe.com/acme/api/settings", want: ref{host: "acme.ghe.com", owner: "acme", repo: "api"}},
+
+		// Malformed URLs
+		{name: "no path", value: "https://github.com", wantErr: `invalid --source "https://github.com": the URL names no organization or repository`},
+		{name: "orgs without a name", value: "https://github.com/orgs/", wantErr: "the URL names no organization or repository"},
+		{name: "no host", value: "https:///acme/api", wantErr: "not a valid URL"},
+		{name: "bad escape", value: "https://github.com/acme/%zz", wantErr: "not a valid URL"},
+		{name: "only .git", value: "https://github.com/acme/.git", wantErr: "the URL has no repository name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRef("source", tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseRef(%q) error = %v, want containing %q", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRef(%q) unexpected error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseRef(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyRefs tests that --source and --target fill the org and repo
+// flags, and that those flags win when given on the command line
+func TestApplyRefs(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origSourceRef, origTargetRef, origRefFlags := sourceRef, targetRef, refFlags
+	origSourceHostname, origTargetHostname := sourceHostname, targetHostname
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		sourceRef, targetRef, refFlags = origSourceRef, origTargetRef, origRefFlags
+		sourceHostname, targetHostname = origSourceHostname, origTargetHostname
+	}()
+
+	tests := []struct {
+		name       string
+		source     string
+		target     string
+		flags      map[string]string
+		env        [4]string // source org and repo, target org and repo
+		hostname   string    // source hostname
+		want       [4]string
+		wantHost   string
+		wantErr    string
+		wantSource string
+	}{
+		{name: "repositories", source: "acme/app", target: "corp/service", want: [4]string{"acme", "app", "corp", "service"}, wantSource: "--source (CLI flag)"},
+		{name: "organizations", source: "acme", target: "corp", want: [4]string{"acme", "", "corp", ""}, wantSource: "--source (CLI flag)"},
+		{name: "target only", target: "corp/service", env: [4]string{"acme", "app", "", ""}, want: [4]string{"acme", "app", "corp", "service"}, wantSource: "default"},
+		{name: "shorthand wins over environment", source: "acme/app", env: [4]string{"old", "legacy", "", ""}, want: [4]string{"acme", "app", "", ""}, wantSource: "--source (CLI flag)"},
+		{name: "specific flags win", source: "acme/app", flags: map[string]string{"source-repo": "api"}, want: [4]string{"acme", "api", "", ""}, wantSource: "--source (CLI flag)"},
+		{name: "specific org flag wins", source: "acme/app", flags: map[string]string{"source-org": "corp"}, want: [4]string{"corp", "app", "", ""}, wantSource: "--source-org (CLI flag)"},
+		{name: "source URL", source: "https://github.com/acme/app/settings/variables/actions", want: [4]string{"acme", "app", "", ""}, wantSource: "--source (CLI flag)"},
+		{name: "GHES source URL", source: "https://ghes.corp.example/acme/app.git", want: [4]string{"acme", "app", "", ""}, wantHost: "ghes.corp.example", wantSource: "--source (CLI flag)"},
+		{name: "GHES URL matching the hostname", source: "https://ghes.corp.example/acme/app", hostname: "https://ghes.corp.example/", want: [4]string{"acme", "app", "", ""}, wantHost: "https://ghes.corp.example/", wantSource: "--source (CLI flag)"},
+		{name: "URL on another host", source: "https://github.com/acme/app", hostname: "ghes.corp.example", wantErr: "--source is on github.com, but --source-hostname is ghes.corp.example"},
+		{name: "URL in --source-repo", env: [4]string{"", "https://ghes.corp.example/acme/app", "", ""}, want: [4]string{"acme", "app", "", ""}, wantHost: "ghes.corp.example", wantSource: "default"},
+		{name: "URL in --source-org", env: [4]string{"https://github.com/orgs/acme", "", "", ""}, want: [4]string{"acme", "", "", ""}, wantSource: "default"},
+		{name: "URL in --target-repo matching --target-org", env: [4]string{"", "", "corp", "https://github.com/corp/service"}, want: [4]string{"", "", "corp", "service"}, wantSource: "default"},
+		{name: "URL in --target-repo of another owner", env: [4]string{"", "", "corp", "https://github.com/acme/service"}, wantErr: "--target-org corp does not match the owner acme of --target-repo https://github.com/acme/service"},
+		{name: "repository URL in --source-org", env: [4]string{"https://github.com/acme/app", "", "", ""}, wantErr: "the URL names a repository; use --source or --source-repo"},
+		{name: "organization URL in --source-repo", env: [4]string{"", "https://github.com/acme", "", ""}, wantErr: "the URL names no repository"},
+		{name: "repository name with a dot", env: [4]string{"acme", "app.js", "", ""}, want: [4]string{"acme", "app.js", "", ""}, wantSource: "default"},
+		{name: "malformed source", source: "acme/app/extra", wantErr: `invalid --source "acme/app/extra"`},
+		{name: "malformed target", source: "acme", target: "/service", wantErr: `invalid --target "/service"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, sourceRepo, targetOrg, targetRepo = tt.env[0], tt.env[1], tt.env[2], tt.env[3]
+			sourceRef, targetRef = tt.source, tt.target
+			sourceHostname, targetHostname = tt.hostname, ""
+
+			// A throwaway command, so that setting a flag leaves rootCmd alone
+			cmd := &cobra.Command{Use: "x"}
+			cmd.Flags().StringVar(&sourceOrg, "source-org", sourceOrg, "")
+			cmd.Flags().StringVar(&sourceRepo, "source-repo", sourceRepo, "")
+			for name, value := range tt.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := applyRefs(cmd)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyRefs() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyRefs() unexpected error: %v", err)
+			}
+			if got := [4]string{sourceOrg, sourceRepo, targetOrg, targetRepo}; got != tt.want {
+				t.Errorf("applyRefs() set %q, want %q", got, tt.want)
+			}
+			if sourceHostname != tt.wantHost {
+				t.Errorf("applyRefs() set the source hostname %q, want %q", sourceHostname, tt.wantHost)
+			}
+			if got := flagSource(cmd, "source-org", ""); got != tt.wantSource {
+				t.Errorf("flagSource(source-org) = %q, want %q", got, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/dump"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// restoreCmd recreates the variables of a file written by backup in an
+// organization
+var restoreCmd = &cobra.Command{
+	Use:   "restore --input FILE --org ORG",
+	Short: "Recreate the variables of a backup in an organization",
+	Long: `Recreate the variables of a file written by backup in an organization: its
+organization variables, with their visibility and selected repositories, and,
+for a --deep backup, the variables of its repositories and their environments.
+
+The organization may differ from the one backed up. Repositories are matched
+by name, as are the selected repositories of a variable; missing environments
+are created. The whole file is validated before anything is read from GitHub.
+
+Existing variables are handled by --on-conflict as in a migration; variables
+that already match are not written. Variables outside --vars, --include, and
+--exclude are left out. The summary breaks the results down per scope. Only
+the target credentials and hostname are used; --dry-run, --diff,
+--report-file, and the hooks work as for a migration.`,
+	Example: `  # Preview a restore
+  gh vars-migrator restore --input myorg.json --org myorg --dry-run
+
+  # Restore into a new organization without overwriting what it has
+  gh vars-migrator restore --input myorg.json --org neworg --on-conflict skip`,
+	PreRunE:       validateRestoreFlags,
+	RunE:          runRestore,
+	SilenceErrors: true,
+}
+
+var (
+	restoreInput string
+	restoreOrg   string
+)
+
+// restoreScopes holds the target scopes of the backup loaded from --input
+// during flag validation
+var restoreScopes []types.DesiredScope
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	supportOutput(restoreCmd, output.JSON, output.CSV)
+	restoreCmd.Flags().StringVar(&restoreInput, "input", "", "File written by backup to restore (required)")
+	restoreCmd.Flags().StringVarP(&restoreOrg, "org", "o", "", "Organization to restore into (required)")
+	// The migration flags are added by the root command once it has
+	// registered them.
+}
+
+// restoreFlags are the flags restore accepts besides its own and those of
+// apply --manifest: the name filters
+var restoreFlags = map[string]bool{
+	"input": true, "org": true,
+	"vars": true, "include": true, "exclude": true, "filter-regex": true,
+}
+
+// validateRestoreFlags loads and validates the backup, and checks the flags
+// it is restored with
+func validateRestoreFlags(cmd *cobra.Command, args []string) error {
+	restoreScopes = nil
+	switch {
+	case restoreInput == "":
+		return fmt.Errorf("--input flag is required")
+	case restoreOrg == "":
+		return fmt.Errorf("--org flag is required")
+	}
+	cmd.SilenceUsage = true
+
+	if err := rejectFlags(cmd, "restore", func(name string) bool {
+		return restoreFlags[name] || (manifestFlags[name] && name != "manifest" && name != "prune")
+	}); err != nil {
+		return err
+	}
+	if err := validateImportOptions(); err != nil {
+		return err
+	}
+
+	d, err := dump.Load(restoreInput, dump.FormatJSON)
+	if err != nil {
+		return fmt.Errorf("--input: %w", err)
+	}
+	if d.Repo != "" {
+		return fmt.Errorf("--input: %s holds the variables of repository %s/%s, not an organization backup; use import", restoreInput, d.Org, d.Repo)
+	}
+	scopes, err := d.Desired(restoreOrg, "", "")
+	if err != nil {
+		return fmt.Errorf("--input: %s: %w", restoreInput, err)
+	}
+	restoreScopes = scopes
+	return nil
+}
+
+// runRestore writes the variables of the --input backup to the target
+// organization
+func runRestore(cmd *cobra.Command, args []string) error {
+	return importScopesFrom(cmd, "restore", restoreInput, "input", restoreScopes)
+}
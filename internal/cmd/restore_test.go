@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestValidateRestoreFlags(t *testing.T) {
+	origInput, origOrg, origScopes := restoreInput, restoreOrg, restoreScopes
+	defer func() { restoreInput, restoreOrg, restoreScopes = origInput, origOrg, origScopes }()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	backupFile := write("acme.json", `{"version":1,"org":"acme","variables":[{"name":"A","value":"a","visibility":"selected","selected_repositories":["app"]}],`+
+		`"repositories":[{"name":"app","variables":[{"name":"B","value":"b"}],"environments":[{"name":"prod","variables":[{"name":"C","value":"c"}]}]}]}`)
+	repoFile := write("app.json", `{"version":1,"org":"acme","repo":"app","variables":[{"name":"A","value":"a"}]}`)
+	maskedFile := write("masked.json", `{"version":1,"org":"acme","values_masked":true,"variables":[]}`)
+
+	tests := []struct {
+		name       string
+		input      string
+		org        string
+		flag       string
+		wantScopes int
+		wantErr    string
+	}{
+		{name: "deep backup", input: backupFile, org: "neworg", wantScopes: 3},
+		{name: "filter flag", input: backupFile, org: "acme", flag: "include", wantScopes: 3},
+		{name: "missing input", org: "acme", wantErr: "--input flag is required"},
+		{name: "missing org", input: backupFile, wantErr: "--org flag is required"},
+		{name: "repository dump", input: repoFile, org: "acme", wantErr: "not an organization backup; use import"},
+		{name: "masked values", input: maskedFile, org: "acme", wantErr: "values are masked"},
+		{name: "source flag", input: backupFile, org: "acme", flag: "source-org", wantErr: "--source-org cannot be combined with restore"},
+		{name: "manifest flag", input: backupFile, org: "acme", flag: "prune", wantErr: "--prune cannot be combined with restore"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreInput, restoreOrg = tt.input, tt.org
+
+			// A throwaway command, so that setting a flag leaves restoreCmd alone
+			cmd := &cobra.Command{Use: "restore"}
+			if tt.flag != "" {
+				cmd.Flags().String(tt.flag, "", "")
+				if err := cmd.Flags().Set(tt.flag, "x"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := validateRestoreFlags(cmd, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateRestoreFlags() unexpected error: %v", err)
+				}
+				if len(restoreScopes) != tt.wantScopes || restoreScopes[0].Org != tt.org {
+					t.Errorf("Expected %d scope(s) starting with org:%s, got %+v", tt.wantScopes, tt.org, restoreScopes)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateRestoreFlags() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,42 +1,208 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/cli/go-gh/v2/pkg/term"
 	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
 	"github.com/renan-alm/gh-vars-migrator/internal/envfile"
+	"github.com/renan-alm/gh-vars-migrator/internal/hooks"
 	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/mapfile"
 	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/plan"
+	"github.com/renan-alm/gh-vars-migrator/internal/report"
+	"github.com/renan-alm/gh-vars-migrator/internal/snapshot"
 	"github.com/renan-alm/gh-vars-migrator/internal/types"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
-	// Version is set at build time
+	// Version is set at build time with -ldflags "-X github.com/renan-alm/gh-vars-migrator/internal/cmd.Version=..."
 	Version = "dev"
 
 	// Source flags
 	sourceOrg      string
 	sourceRepo     string
 	sourcePAT      string
+	sourcePATFile  string
 	sourceHostname string
-
-	// Target flags
-	targetOrg      string
-	targetRepo     string
-	targetPAT      string
-	targetHostname string
+	// sourceRef and targetRef are the OWNER[/REPO] shorthands of --source
+	// and --target; refFlags holds the org and repo flags they set
+	sourceRef string
+	targetRef string
+	refFlags  map[string]string
+	// noDetect turns off the detection of the source from the git remotes
+	// of the working directory; detectedFlags holds the flags it set, with
+	// the remote they came from
+	noDetect      bool
+	detectedFlags map[string]string
+	// helpShown is set when the root command is run without a source or
+	// target and validateFlags printed the help instead of a migration
+	helpShown bool
+	// noKeyring turns off the lookup of tokens stored with auth store
+	noKeyring bool
+
+	// Target flags; targets holds the parsed --target-repos-file
+	targetOrg       string
+	targetRepo      string
+	targetReposFile string
+	targetPAT       string
+	targetPATFile   string
+	targetHostname  string
+	targets         []types.RepoRef
 
 	// Mode flags
-	orgToOrg bool
-	skipEnvs bool
+	orgToOrg          bool
+	orgToRepo         bool
+	repoToOrg         bool
+	targetVisibility  string
+	visibility        string
+	repoMapFile       string
+	selectedFallback  string
+	skipEnvs          bool
+	envNames          []string
+	envName           string
+	sourceEnv         string
+	targetEnv         string
+	envsOnly          bool
+	excludeEnvs       []string
+	envMapSpecs       []string
+	copyEnvProtection bool
+	updateEnvSettings bool
+	deep              bool
+	excludeRepos      []string
+
+	// Fan-out flags; fanOutRepos holds the validated repository selection
+	fanOut      bool
+	repoNames   []string
+	reposFile   string
+	allRepos    bool
+	fanOutRepos []string
 
 	// Option flags
 	dryRun        bool
 	skipOverwrite bool
+	onConflict    string
+	interactive   bool
+	assumeYes     bool
+	diffMode      bool
+	showValues    bool
+	verify        bool
+	checkUsage    bool
+	failFast      bool
+	maxErrors     int
+	failIfEmpty   bool
+	maxAPICalls   int
+	// exitCodeOnDiff makes a dry run with pending changes exit with
+	// exitCodeDiff
+	exitCodeOnDiff  bool
+	skipLimitChecks bool
+	strictNames     bool
+	alwaysWrite     bool
+	allowCollisions bool
+
+	// Name transformation flags
+	nameMapFile  string
+	targetPrefix string
+	targetSuffix string
+
+	// Value transformation flags
+	valueOverridesFile    string
+	rewriteValues         bool
+	replaceSpecs          []string
+	replaceWordBoundaries bool
+
+	// nameMap and valueOverrides hold the mappings loaded from --name-map
+	// and --value-overrides during flag validation
+	nameMap        map[string]string
+	valueOverrides map[string]string
+
+	// replacements holds the parsed --replace substitutions
+	replacements []types.Replacement
+
+	// envMap holds the environment renames parsed from --env-map and
+	// repoMap the repository renames loaded from --repo-map
+	envMap  map[string]string
+	repoMap map[string]string
+
+	// Filter flags
+	varNames        []string
+	includePatterns []string
+	excludePatterns []string
+	filterRegex     string
+	since           string
+	skipUnused      bool
+
+	// sinceTime holds the --since cutoff resolved during flag validation
+	sinceTime time.Time
+
+	// Snapshot flags
+	snapshotFile string
+	rollbackFile string
+
+	// Report flags
+	reportFile          string
+	reportIncludeValues bool
+	// lastReportFile keeps the report of every run for the status command;
+	// empty means report.DefaultLastPath
+	lastReportFile string
+	// githubSummary appends a Markdown summary of the run to the
+	// GITHUB_STEP_SUMMARY file
+	githubSummary bool
+
+	// Plan flags
+	planOut           string
+	planIncludeValues bool
+
+	// Retry flags
+	retryFailed string
+	// retryReport holds the report loaded from --retry-failed during flag
+	// validation
+	retryReport *report.Report
+
+	// Hook flags
+	preHook       string
+	postHook      string
+	hooksInDryRun bool
+
+	// Profile flags; profileFlags holds the flags the profile set
+	configPath   string
+	profileName  string
+	profileFlags map[string]bool
+
+	// verbose adds the debug messages of every command, quiet leaves out
+	// their per-variable messages, and noColor their colors
+	verbose bool
+	quiet   bool
+	noColor bool
+	// noAnnotations leaves out the GitHub Actions annotations of warnings
+	// and errors
+	noAnnotations bool
+
+	// envFiles are the --env-file files whose variables set the flags not
+	// given on the command line
+	envFiles []string
+
+	// Names of the PAT credentials in messages; a profile replaces them
+	// with the environment variables its tokens are read from
+	sourcePATName = "SOURCE_PAT"
+	targetPATName = "TARGET_PAT"
 )
 
 // rootCmd represents the base command
@@ -48,27 +214,82 @@ GitHub Actions variables between organizations, repositories, and environments.
 
 It supports:
   • Organization to organization variable migration (with automatic visibility preservation)
+  • Deep organization migration of the repositories found in both organizations
   • Repository to repository variable migration (with auto-discovery of environments)
+  • Organization to repository migration (org variables copied down as repo variables)
+  • Repository to organization promotion (repo variables promoted to org variables)
+  • Fan-out of organization variables to many repositories as repository variables
+  • Repository migration into many target repositories listed in a file
   • Dry-run mode to preview changes before applying
+  • Reviewed plans from a dry run with --plan-out, applied with the apply command
+  • Diff mode to compare source and target without migrating
+  • Post-migration verification of the target state with --verify
+  • Warnings for workflow variables missing from the target with --check-usage
+  • Target snapshots before migrating and rollback to a snapshot
+  • Machine-readable JSON reports of every run with --report-file
+  • Retrying only the variables a previous run failed on with --retry-failed
+  • Pre- and post-migration hook commands, e.g. to pause and resume a pipeline
   • Skip-overwrite mode to preserve existing variables in the target
+  • Conflict strategies for existing target variables (skip, overwrite, fail, prompt)
+  • Interactive per-variable approval with --interactive
+  • Include/exclude glob and regular-expression filters on variable names
+  • Failing runs that find no source variables with --fail-if-empty
+  • Clean stops at an API request budget with --max-api-calls
+  • Explicit variable selection with --vars
+  • Migration of only the variables the source workflows reference with --skip-unused
+  • Variable renames via a name-mapping file and target prefix/suffix transformations
+  • Environment selection, exclusion, and renames between source and target
+  • Copying of environment reviewers, wait timers, and branch policies with --copy-env-protection
+  • Per-variable value overrides from a file
+  • Rewriting of org/repo references and custom substitutions inside values
   • Data residency compliance via custom GitHub hostnames
+  • Named connection profiles of recurring migrations with --profile
+  • Batches of migrations listed in a YAML file, run with the batch command
 
 Mode Detection:
   - If --org-to-org flag is set → Organization migration mode
+  - If --org-to-repo flag is set → Organization-to-Repository migration mode
+  - If --repo-to-org flag is set → Repository-to-Organization promotion mode
+  - If --fan-out flag is set → Organization-to-many-Repositories fan-out mode
+  - If --source OWNER names an organization → Organization migration mode, or
+    Organization-to-Repository mode when the target is a repository
   - Otherwise → Repository-to-Repository migration mode (includes all environments)
 
+Source and Target Shorthand:
+  - --source and --target take OWNER or OWNER/REPO, like the -R flag of the
+    GitHub CLI, in place of --source-org/--source-repo and --target-org/--target-repo
+  - They, and the org and repo flags, also take the URL of an organization or
+    repository, e.g. https://ghes.example.com/acme/app/settings; its host sets
+    --source-hostname or --target-hostname, and must match them when set
+  - --source-org, --source-repo, --target-org, and --target-repo win over them
+    when given on the command line; the same settings from environment
+    variables or a profile give way to them
+  - Inside a clone, a source left out is detected from GH_REPO or the git
+    remotes of the working directory, unless they point to different
+    repositories or --no-detect is set
+
 Organization Variable Visibility:
   - Source variable visibility is automatically preserved during migration
   - Variables with 'selected' visibility have their repository selections matched
     by name in the target organisation
   - If no matching repositories are found, the variable is created with zero
-    selected repositories
+    selected repositories; --selected-fallback private, all, or skip changes this
 
 Authentication:
   - Primary: GITHUB_TOKEN environment variable (used for both source and target)
   - Override: --source-pat / --target-pat flags take precedence over GITHUB_TOKEN
+  - Override: --source-pat-file / --target-pat-file, files holding the
+    tokens or - for standard input (when the PAT flags are not provided)
   - Override: SOURCE_PAT / TARGET_PAT env vars (when flags are not provided)
-  - Fallback: GitHub CLI authentication (gh auth login) when no tokens are set
+  - Profile: the token variables named by the --profile profile (when neither
+    the flags nor SOURCE_PAT / TARGET_PAT are set)
+  - Keyring: the token of the side and host saved in the OS keyring with
+    'gh vars-migrator auth store' (unless --no-keyring is set)
+  - Fallback: GitHub CLI authentication (gh auth login) when no tokens are set,
+    with the login to each side's host (gh auth login --hostname HOST)
+  - Prompt: on a terminal, a side left without a token, or without a GitHub
+    CLI login to its host, is asked for one; the entered token is checked
+    and never logged or saved
 
 Data Residency:
   - Use --source-hostname and --target-hostname to target specific GitHub Enterprise
@@ -78,18 +299,110 @@ Data Residency:
 	Example: `  # Organization to Organization migration (preserves source visibility)
   gh vars-migrator --source-org myorg --target-org targetorg --org-to-org
 
+  # Organization migration including every repository found in both organizations
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --deep --exclude-repos 'sandbox-*'
+
+  # Copy organization variables into a single repository as repository variables
+  gh vars-migrator --source-org myorg --target-org targetorg --target-repo service --org-to-repo
+
+  # Promote a repository's variables to private organization variables
+  gh vars-migrator --source-org myorg --source-repo service --target-org myorg --repo-to-org --target-visibility private
+
+  # Copy organization variables into every repository of the target organization
+  gh vars-migrator --source-org myorg --target-org myorg --fan-out --all-repos --include 'DEPLOY_*'
+
   # Repository to Repository migration (auto-discovers and migrates all environments)
   gh vars-migrator --source-org myorg --source-repo myrepo --target-org targetorg --target-repo targetrepo
 
+  # The same with the OWNER/REPO shorthand
+  gh vars-migrator --source myorg/myrepo --target targetorg/targetrepo
+
+  # Replicate a template repository's variables into every repository listed in a file
+  gh vars-migrator --source-org myorg --source-repo template --target-repos-file new-repos.txt
+
   # Repository migration without environments
   gh vars-migrator --source-org myorg --source-repo myrepo --target-org targetorg --target-repo targetrepo --skip-envs
 
+  # Repository migration of the environments only, without repository-level variables
+  gh vars-migrator --source-org myorg --source-repo myrepo --target-org targetorg --target-repo targetrepo --envs-only
+
+  # Repository migration of the production and staging environments only
+  gh vars-migrator --source-org myorg --source-repo myrepo --target-org targetorg --target-repo targetrepo --envs production,staging
+
+  # Repository migration that also copies environment reviewers and wait timers
+  gh vars-migrator --source-org myorg --source-repo myrepo --target-org targetorg --target-repo targetrepo --copy-env-protection
+
   # Dry-run mode (preview changes)
   gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --dry-run
 
+  # Diff mode (report Add / Update / Unchanged / Target-only; exit 2 on differences)
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --diff
+
+  # Verify the target after migrating (mismatches are reported as errors)
+  gh vars-migrator --source-org myorg --source-repo repo1 --target-org targetorg --target-repo repo2 --verify
+
+  # Warn about variables the target's workflows use but the target does not have
+  gh vars-migrator --source-org myorg --source-repo repo1 --target-org targetorg --target-repo repo2 --check-usage
+
+  # In CI, fail (exit 7) instead of succeeding when no DEPLOY_* variable is found
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --include 'DEPLOY_*' --fail-if-empty
+
+  # Snapshot the target before migrating, then roll back to it if needed
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --snapshot-file before.json
+  gh vars-migrator --rollback before.json --dry-run
+  gh vars-migrator --rollback before.json
+
+  # Write a plan for review, then apply exactly that plan
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --dry-run --plan-out plan.json
+  gh vars-migrator apply --plan plan.json --source-org myorg --target-org targetorg --org-to-org
+
+  # Write a JSON report of everything the run changed
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --report-file migration-report.json
+
+  # Retry only the variables that failed in that run
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --retry-failed migration-report.json
+
+  # Pause a deployment pipeline while variables change
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org \
+    --pre-hook './pipeline.sh pause' --post-hook './pipeline.sh resume'
+
   # Skip overwriting existing variables in the target
   gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --skip-overwrite
 
+  # Abort before writing anything if any variable already exists in the target
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --on-conflict fail
+
+  # Approve every create and update by hand
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --interactive
+
+  # Migrate only an explicit list of variables
+  gh vars-migrator --source-org myorg --source-repo myrepo --target-org targetorg --target-repo targetrepo \
+    --vars DATABASE_URL,REGION
+
+  # Leave behind variables no source workflow references
+  gh vars-migrator --source-org myorg --source-repo myrepo --target-org targetorg --target-repo targetrepo --skip-unused
+
+  # Land migrated variables as NEWORG_<NAME> in the target
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --target-prefix NEWORG_
+
+  # Rename variables using a mapping file (one OLD=NEW per line, or JSON)
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --name-map renames.map
+
+  # Replace selected values in the target (one NAME=VALUE per line, or JSON)
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org --value-overrides overrides.env
+
+  # Rewrite references to the source org inside values, plus a custom substitution
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org \
+    --rewrite-values --replace registry.old.example.com=registry.new.example.com
+
+  # Only migrate DEPLOY_* variables, never anything ending in _LEGACY
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org \
+    --include 'DEPLOY_*' --exclude '*_LEGACY'
+
+  # Only migrate variables matching a regular expression
+  gh vars-migrator --source-org myorg --target-org targetorg --org-to-org \
+    --filter-regex '^APP_(EU|US)_.*_URL$'
+
   # Using explicit PATs for different accounts
   gh vars-migrator --source-org myorg --target-org targetorg --org-to-org \
     --source-pat ghp_sourcetoken --target-pat ghp_targettoken
@@ -104,53 +417,208 @@ Data Residency:
     --source-hostname github.source-company.com --target-hostname github.target-company.com \
     --source-pat ghp_sourcetoken --target-pat ghp_targettoken
 
+  # Source and target from a profile of ~/.config/gh-vars-migrator/config.yaml
+  gh vars-migrator --profile ghes-to-cloud --org-to-org --dry-run
+
   # Utility commands
   gh vars-migrator auth
   gh vars-migrator list --org myorg`,
-	Version:       Version,
-	PreRunE:       validateFlags,
-	RunE:          runMigration,
-	SilenceErrors: true, // we handle error display via logger.Error
+	Version:           Version,
+	PersistentPreRunE: persistentPreRun,
+	PreRunE:           validateFlags,
+	RunE:              runMigration,
+	SilenceErrors:     true, // we handle error display via logger.Error
+}
+
+// persistentPreRun runs before every command: it sets the log level from
+// --verbose and --quiet, selects the --output format, loads the --env-file
+// files, applies --no-color and --no-annotations, then fills the flags left unset from --profile
+// and reads --source-pat-file and --target-pat-file
+func persistentPreRun(cmd *cobra.Command, args []string) error {
+	switch {
+	case verbose && quiet:
+		return fmt.Errorf("--quiet and --verbose cannot be combined")
+	case verbose:
+		logger.SetLevel(logger.LevelVerbose)
+	case quiet:
+		logger.SetLevel(logger.LevelQuiet)
+	default:
+		logger.SetLevel(logger.LevelNormal)
+	}
+	if err := selectOutput(cmd); err != nil {
+		return err
+	}
+	if err := loadEnvFiles(cmd); err != nil {
+		return err
+	}
+	// An env file can set NO_COLOR or CLICOLOR_FORCE
+	logger.SetColor(!noColor && logger.DetectColor(os.Getenv, term.IsTerminal(os.Stdout)))
+	logger.SetAnnotations(!noAnnotations && logger.DetectAnnotations(os.Getenv))
+	if err := applyProfile(cmd, args); err != nil {
+		return err
+	}
+	return applyPATFiles(cmd)
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		logger.Error("%v", err)
-		os.Exit(1)
+		var exitErr *exitError
+		if !errors.As(err, &exitErr) || exitErr.err != nil {
+			logger.Error("%v", err)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
-	// Load .env file before registering flags so that os.Getenv picks up
-	// file-defined values. Variables already set in the real environment
-	// are never overwritten, and CLI flags always override env vars.
-	if err := envfile.Load(".env"); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to load .env file: %v\n", err)
-	}
-
 	// Source flags
 	rootCmd.Flags().StringVar(&sourceOrg, "source-org", os.Getenv("SOURCE_ORG"), "Source organization name (required) (env: SOURCE_ORG)")
 	rootCmd.Flags().StringVar(&sourceRepo, "source-repo", os.Getenv("SOURCE_REPO"), "Source repository name (required for repo-to-repo) (env: SOURCE_REPO)")
 	rootCmd.Flags().StringVar(&sourcePAT, "source-pat", os.Getenv("SOURCE_PAT"), "Source personal access token; overrides GITHUB_TOKEN (env: SOURCE_PAT)")
+	rootCmd.Flags().StringVar(&sourcePATFile, "source-pat-file", os.Getenv("SOURCE_PAT_FILE"), "File holding the source personal access token, or - for standard input; overrides SOURCE_PAT (env: SOURCE_PAT_FILE)")
+	rootCmd.Flags().StringVar(&sourceRef, "source", "", "Source as OWNER, OWNER/REPO, or a GitHub URL; --source-org and --source-repo win over it")
+	rootCmd.Flags().BoolVar(&noDetect, "no-detect", envBool("NO_DETECT"), "Do not use the repository of the working directory's git remote as the source when --source-org and --source-repo are not set (env: NO_DETECT)")
 	rootCmd.Flags().StringVar(&sourceHostname, "source-hostname", os.Getenv("SOURCE_HOSTNAME"), "Source GitHub hostname for data residency (env: SOURCE_HOSTNAME)")
 
 	// Target flags
 	rootCmd.Flags().StringVar(&targetOrg, "target-org", os.Getenv("TARGET_ORG"), "Target organization name (required) (env: TARGET_ORG)")
 	rootCmd.Flags().StringVar(&targetRepo, "target-repo", os.Getenv("TARGET_REPO"), "Target repository name (required for repo-to-repo) (env: TARGET_REPO)")
+	rootCmd.Flags().StringVar(&targetRef, "target", "", "Target as OWNER, OWNER/REPO, or a GitHub URL; --target-org and --target-repo win over it")
+	rootCmd.Flags().StringVar(&targetReposFile, "target-repos-file", os.Getenv("TARGET_REPOS_FILE"), "File of owner/repo lines to migrate --source-repo into, instead of --target-org/--target-repo (env: TARGET_REPOS_FILE)")
 	rootCmd.Flags().StringVar(&targetPAT, "target-pat", os.Getenv("TARGET_PAT"), "Target personal access token; overrides GITHUB_TOKEN (env: TARGET_PAT)")
+	rootCmd.Flags().StringVar(&targetPATFile, "target-pat-file", os.Getenv("TARGET_PAT_FILE"), "File holding the target personal access token, or - for standard input; overrides TARGET_PAT (env: TARGET_PAT_FILE)")
+	rootCmd.Flags().BoolVar(&noKeyring, "no-keyring", envBool("NO_KEYRING"), "Do not use the tokens stored in the OS keyring with auth store (env: NO_KEYRING)")
 	rootCmd.Flags().StringVar(&targetHostname, "target-hostname", os.Getenv("TARGET_HOSTNAME"), "Target GitHub hostname for data residency (env: TARGET_HOSTNAME)")
 
 	// Mode flags
 	rootCmd.Flags().BoolVar(&orgToOrg, "org-to-org", envBool("ORG_TO_ORG"), "Migrate organization variables only (env: ORG_TO_ORG)")
-	rootCmd.Flags().BoolVar(&skipEnvs, "skip-envs", envBool("SKIP_ENVS"), "Skip environment variable migration during repo-to-repo (env: SKIP_ENVS)")
+	rootCmd.Flags().BoolVar(&orgToRepo, "org-to-repo", envBool("ORG_TO_REPO"), "Copy source organization variables into --target-repo as repository variables (env: ORG_TO_REPO)")
+	rootCmd.Flags().BoolVar(&repoToOrg, "repo-to-org", envBool("REPO_TO_ORG"), "Promote --source-repo variables to organization variables in --target-org (env: REPO_TO_ORG)")
+	rootCmd.Flags().BoolVar(&fanOut, "fan-out", envBool("FAN_OUT"), "Copy source organization variables into many --target-org repositories as repository variables (env: FAN_OUT)")
+	rootCmd.Flags().StringSliceVar(&repoNames, "repos", envList("REPOS"), "Repositories of --target-org to fan out to; comma-separated or repeatable (env: REPOS)")
+	rootCmd.Flags().StringVar(&reposFile, "repos-file", os.Getenv("REPOS_FILE"), "File listing repositories to fan out to, one per line (env: REPOS_FILE)")
+	rootCmd.Flags().BoolVar(&allRepos, "all-repos", envBool("ALL_REPOS"), "Fan out to every non-archived repository of --target-org (env: ALL_REPOS)")
+	rootCmd.Flags().StringVar(&visibility, "visibility", os.Getenv("VISIBILITY"), "Override the source visibility of every variable with --org-to-org: all, private, or selected (env: VISIBILITY)")
+	rootCmd.Flags().StringVar(&repoMapFile, "repo-map", os.Getenv("REPO_MAP"), "File of SOURCE_REPO=TARGET_REPO lines matching renamed repositories for 'selected' variables with --org-to-org (env: REPO_MAP)")
+	rootCmd.Flags().StringVar(&selectedFallback, "selected-fallback", os.Getenv("SELECTED_FALLBACK"), "How to migrate 'selected' variables with no matching target repository with --org-to-org: empty, private, all, or skip (default empty) (env: SELECTED_FALLBACK)")
+	rootCmd.Flags().StringVar(&targetVisibility, "target-visibility", os.Getenv("TARGET_VISIBILITY"), "Visibility of promoted variables with --repo-to-org: all or private (default all) (env: TARGET_VISIBILITY)")
+	rootCmd.Flags().BoolVar(&skipEnvs, "skip-envs", envBool("SKIP_ENVS"), "Skip environment variable migration during repo-to-repo and --deep (env: SKIP_ENVS)")
+	rootCmd.Flags().StringSliceVar(&envNames, "envs", envList("ENVS"), "Migrate only these source environments during repo-to-repo; comma-separated or repeatable (env: ENVS)")
+	rootCmd.Flags().StringVar(&envName, "env", os.Getenv("ENV_NAME"), "Migrate only this source environment during repo-to-repo, without repository-level variables (env: ENV_NAME)")
+	rootCmd.Flags().StringVar(&sourceEnv, "source-env", os.Getenv("SOURCE_ENV"), "Migrate only this source environment, like --env, into --target-env (env: SOURCE_ENV)")
+	rootCmd.Flags().StringVar(&targetEnv, "target-env", os.Getenv("TARGET_ENV"), "Target environment of --source-env or --env; defaults to the same name (env: TARGET_ENV)")
+	rootCmd.Flags().BoolVar(&envsOnly, "envs-only", envBool("ENVS_ONLY"), "Migrate only environments and their variables during repo-to-repo, without repository-level variables (env: ENVS_ONLY)")
+	rootCmd.Flags().StringSliceVar(&excludeEnvs, "exclude-envs", envList("EXCLUDE_ENVS"), "Glob patterns of source environments to leave out during repo-to-repo and --deep; comma-separated or repeatable (env: EXCLUDE_ENVS)")
+	rootCmd.Flags().BoolVar(&copyEnvProtection, "copy-env-protection", envBool("COPY_ENV_PROTECTION"), "Create target environments with the wait timer, self-review setting, required reviewers, and deployment branch policy of the source environment (env: COPY_ENV_PROTECTION)")
+	rootCmd.Flags().BoolVar(&updateEnvSettings, "update-env-settings", envBool("UPDATE_ENV_SETTINGS"), "With --copy-env-protection, also apply the source protection settings to existing target environments (env: UPDATE_ENV_SETTINGS)")
+	rootCmd.Flags().StringArrayVar(&envMapSpecs, "env-map", envList("ENV_MAP"), "Rename environments in the target: SOURCE=TARGET pairs or a mapping file; repeatable (env: ENV_MAP, comma-separated)")
+	rootCmd.Flags().BoolVar(&deep, "deep", envBool("DEEP"), "With --org-to-org, also migrate repository and environment variables of every repository found in both organizations (env: DEEP)")
+	rootCmd.Flags().StringSliceVar(&excludeRepos, "exclude-repos", envList("EXCLUDE_REPOS"), "Glob patterns of source repositories to leave out of --deep; comma-separated or repeatable (env: EXCLUDE_REPOS)")
 
 	// Option flags
 	rootCmd.Flags().BoolVar(&dryRun, "dry-run", envBool("DRY_RUN"), "Preview changes without applying them (env: DRY_RUN)")
-	rootCmd.Flags().BoolVar(&skipOverwrite, "skip-overwrite", envBool("SKIP_OVERWRITE"), "Skip overwriting existing variables in target (env: SKIP_OVERWRITE)")
+	rootCmd.Flags().BoolVar(&skipOverwrite, "skip-overwrite", envBool("SKIP_OVERWRITE"), "Skip overwriting existing variables in target; alias for --on-conflict=skip (env: SKIP_OVERWRITE)")
+	rootCmd.Flags().BoolVar(&interactive, "interactive", envBool("INTERACTIVE"), "Ask for approval before every create or update; requires a terminal (env: INTERACTIVE)")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", envBool("ASSUME_YES"), "Start writing without asking to confirm the migration plan; required when standard input is not a terminal (env: ASSUME_YES)")
+	rootCmd.Flags().StringVar(&onConflict, "on-conflict", os.Getenv("ON_CONFLICT"), "What to do when a variable already exists in the target: skip, overwrite, fail, or prompt (default overwrite) (env: ON_CONFLICT)")
+	rootCmd.Flags().BoolVar(&diffMode, "diff", envBool("DIFF"), "Report differences between source and target without migrating; exits 2 when they differ (env: DIFF)")
+	rootCmd.Flags().BoolVar(&verify, "verify", envBool("VERIFY"), "Re-read the target after migrating and report variables that do not match (env: VERIFY)")
+	rootCmd.Flags().BoolVar(&checkUsage, "check-usage", envBool("CHECK_USAGE"), "After migrating, warn about variables the target repository's workflows reference but the target does not have (env: CHECK_USAGE)")
+	rootCmd.Flags().BoolVar(&failFast, "fail-fast", envBool("FAIL_FAST"), "Stop at the first variable, environment, or repository that fails instead of continuing with the rest (env: FAIL_FAST)")
+	rootCmd.Flags().IntVar(&maxErrors, "max-errors", envInt("MAX_ERRORS"), "Stop once this many errors have been recorded; 0 means no limit (env: MAX_ERRORS)")
+	rootCmd.Flags().IntVar(&maxAPICalls, "max-api-calls", envInt("MAX_API_CALLS"), "Stop once the source and target together have made this many API requests; 0 means no limit (env: MAX_API_CALLS)")
+	rootCmd.Flags().BoolVar(&failIfEmpty, "fail-if-empty", envBool("FAIL_IF_EMPTY"), "Fail when no source variable is left to migrate after filtering (env: FAIL_IF_EMPTY)")
+	rootCmd.Flags().BoolVar(&alwaysWrite, "always-write", envBool("ALWAYS_WRITE"), "Update existing target variables even when their value is already identical (env: ALWAYS_WRITE)")
+	rootCmd.Flags().BoolVar(&skipLimitChecks, "skip-limit-checks", envBool("SKIP_LIMIT_CHECKS"), "Do not check target names and value sizes against GitHub's limits before writing (env: SKIP_LIMIT_CHECKS)")
+	rootCmd.Flags().BoolVar(&strictNames, "strict-names", envBool("STRICT_NAMES"), "Fail instead of skipping variables whose target name starts with GITHUB_ or a number (env: STRICT_NAMES)")
+	rootCmd.Flags().BoolVar(&showValues, "show-values", envBool("SHOW_VALUES"), "Show variable values instead of masking them in diff and dry-run output (env: SHOW_VALUES)")
+	rootCmd.Flags().BoolVar(&exitCodeOnDiff, "exit-code-on-diff", envBool("EXIT_CODE_ON_DIFF"), "With --dry-run, exit 2 when there are pending changes (env: EXIT_CODE_ON_DIFF)")
+
+	// Name transformation flags
+	rootCmd.Flags().StringVar(&nameMapFile, "name-map", os.Getenv("NAME_MAP"), "File of OLD=NEW lines or a JSON object renaming variables in the target (env: NAME_MAP)")
+	rootCmd.Flags().StringVar(&targetPrefix, "target-prefix", os.Getenv("TARGET_PREFIX"), "Prefix added to every variable name in the target (env: TARGET_PREFIX)")
+	rootCmd.Flags().StringVar(&targetSuffix, "target-suffix", os.Getenv("TARGET_SUFFIX"), "Suffix added to every variable name in the target (env: TARGET_SUFFIX)")
+	rootCmd.Flags().BoolVar(&allowCollisions, "allow-collisions", envBool("ALLOW_COLLISIONS"), "Let several source variables be written to the same target name, the last one winning (env: ALLOW_COLLISIONS)")
+
+	// Value transformation flags
+	rootCmd.Flags().StringVar(&valueOverridesFile, "value-overrides", os.Getenv("VALUE_OVERRIDES"), "File of NAME=VALUE lines or a JSON object replacing source values in the target (env: VALUE_OVERRIDES)")
+	rootCmd.Flags().BoolVar(&rewriteValues, "rewrite-values", envBool("REWRITE_VALUES"), "Replace source org/repo names with the target ones inside values (env: REWRITE_VALUES)")
+	rootCmd.Flags().StringArrayVar(&replaceSpecs, "replace", envList("REPLACE_VALUES"), "Substring substitution OLD=NEW applied inside values; repeatable (env: REPLACE_VALUES, comma-separated)")
+	rootCmd.Flags().BoolVar(&replaceWordBoundaries, "replace-word-boundaries", envBool("REPLACE_WORD_BOUNDARIES"), "Only replace whole-word matches in values instead of plain substrings (env: REPLACE_WORD_BOUNDARIES)")
+
+	// Filter flags
+	rootCmd.Flags().StringSliceVar(&varNames, "vars", envList("VARS"), "Migrate exactly these variable names; comma-separated or repeatable, case-insensitive (env: VARS)")
+	rootCmd.Flags().StringSliceVar(&includePatterns, "include", envList("INCLUDE_VARS"), "Only migrate variables whose names match this glob; repeatable, case-insensitive (env: INCLUDE_VARS)")
+	rootCmd.Flags().StringSliceVar(&excludePatterns, "exclude", envList("EXCLUDE_VARS"), "Never migrate variables whose names match this glob; repeatable, wins over --include (env: EXCLUDE_VARS)")
+	rootCmd.Flags().StringVar(&since, "since", os.Getenv("SINCE"), "Only migrate variables updated since this RFC3339 timestamp or this long ago, e.g. 168h (env: SINCE)")
+	rootCmd.Flags().BoolVar(&skipUnused, "skip-unused", envBool("SKIP_UNUSED"), "Only migrate variables referenced by the source repository's workflow files (env: SKIP_UNUSED)")
+	rootCmd.Flags().StringVar(&filterRegex, "filter-regex", os.Getenv("FILTER_REGEX"), "Only migrate variables whose names match this regular expression; applied after --include/--exclude (env: FILTER_REGEX)")
+
+	// Snapshot flags
+	rootCmd.Flags().StringVar(&snapshotFile, "snapshot-file", os.Getenv("SNAPSHOT_FILE"), "Save the current target variables to this JSON file before migrating (env: SNAPSHOT_FILE)")
+	rootCmd.Flags().StringVar(&rollbackFile, "rollback", os.Getenv("ROLLBACK_FILE"), "Restore the target recorded in this snapshot file instead of migrating (env: ROLLBACK_FILE)")
+
+	// Report flags
+	rootCmd.Flags().StringVar(&reportFile, "report-file", os.Getenv("REPORT_FILE"), "Write a JSON report of the run to this file, even when it ends with errors (env: REPORT_FILE)")
+	rootCmd.Flags().BoolVar(&reportIncludeValues, "report-include-values", envBool("REPORT_INCLUDE_VALUES"), "Include the written variable values in the --report-file report (env: REPORT_INCLUDE_VALUES)")
+	rootCmd.Flags().StringVar(&lastReportFile, "last-report-file", os.Getenv("LAST_REPORT_FILE"), "Keep the report of the run, without values, in this file for the status command (default under the user cache directory) (env: LAST_REPORT_FILE)")
+	rootCmd.Flags().BoolVar(&githubSummary, "github-summary", os.Getenv(stepSummaryEnv) != "", "Append a Markdown summary of the run to the GITHUB_STEP_SUMMARY file of GitHub Actions (default: on when GITHUB_STEP_SUMMARY is set)")
+
+	// Plan flags
+	rootCmd.Flags().StringVar(&planOut, "plan-out", os.Getenv("PLAN_OUT"), "With --dry-run, write the planned creates and updates to this file for the apply command (env: PLAN_OUT)")
+	rootCmd.Flags().BoolVar(&planIncludeValues, "plan-include-values", envBool("PLAN_INCLUDE_VALUES"), "Include the target values in the --plan-out plan (env: PLAN_INCLUDE_VALUES)")
+
+	// Retry flags
+	rootCmd.Flags().StringVar(&retryFailed, "retry-failed", os.Getenv("RETRY_FAILED"), "Migrate only the variables recorded as failed in this --report-file report (env: RETRY_FAILED)")
+
+	// Hook flags
+	rootCmd.Flags().StringVar(&preHook, "pre-hook", os.Getenv("PRE_HOOK"), "Shell command to run before the first write; a non-zero exit aborts the migration (env: PRE_HOOK)")
+	rootCmd.Flags().StringVar(&postHook, "post-hook", os.Getenv("POST_HOOK"), "Shell command to run after the summary (env: POST_HOOK)")
+	rootCmd.Flags().BoolVar(&hooksInDryRun, "hooks-in-dry-run", envBool("HOOKS_IN_DRY_RUN"), "Run --pre-hook and --post-hook in dry-run mode too (env: HOOKS_IN_DRY_RUN)")
+
+	// Profile flags
+	rootCmd.Flags().StringVar(&configPath, "config", "", "Configuration file holding the connection profiles (default ~/.config/gh-vars-migrator/config.yaml)")
+	rootCmd.Flags().StringVar(&profileName, "profile", "", "Connection profile supplying the source and target organizations, repositories, hostnames, and tokens that are not set otherwise")
 
 	// Global flags
-	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print debug messages, e.g. how repositories were matched and why variables were left out")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Leave out per-variable messages; phase headers, warnings, errors, and the summary are still printed")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Print messages without colors; also set by NO_COLOR, while CLICOLOR_FORCE keeps colors when standard output is not a terminal")
+	rootCmd.PersistentFlags().BoolVar(&noAnnotations, "no-annotations", false, "Do not add GitHub Actions annotations for warnings and errors, which are added when GITHUB_ACTIONS is true")
+	rootCmd.PersistentFlags().StringArrayVar(&envFiles, "env-file", []string{".env"}, "Env file setting the flags through their environment variables; repeatable, later files override earlier ones (skipped when missing)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", output.Table, "Output format: table, json, or csv; json and csv leave standard output to the command's result")
+
+	// Values completed by the shell completion scripts; the subcommands
+	// sharing these flags complete them too
+	registerCompletion(rootCmd, "output", fixedCompletion(output.Formats...))
+	supportOutput(rootCmd, output.JSON, output.CSV)
+	registerCompletion(rootCmd, "on-conflict", fixedCompletion(string(types.ConflictSkip), string(types.ConflictOverwrite), string(types.ConflictFail), string(types.ConflictPrompt)))
+	registerCompletion(rootCmd, "visibility", fixedCompletion("all", "private", "selected"))
+	registerCompletion(rootCmd, "target-visibility", fixedCompletion("all", "private"))
+	registerCompletion(rootCmd, "selected-fallback", fixedCompletion(string(types.FallbackEmpty), string(types.FallbackPrivate), string(types.FallbackAll), string(types.FallbackSkip)))
+
+	// apply takes the same migration flags as the dry run that wrote its plan
+	applyCmd.Flags().AddFlagSet(rootCmd.Flags())
+	// import takes the write, run, and filter options of a migration; its
+	// own --env replaces the source environment flag
+	importCmd.Flags().AddFlagSet(rootCmd.Flags())
+	// restore takes the same options as import
+	restoreCmd.Flags().AddFlagSet(rootCmd.Flags())
+	// delete takes the filter and run options of a migration
+	deleteCmd.Flags().AddFlagSet(rootCmd.Flags())
+	// cp takes the credentials of both sides and the write options
+	cpCmd.Flags().AddFlagSet(rootCmd.Flags())
+	// validate takes the endpoints, credentials, and mode of a migration
+	validateCmd.Flags().AddFlagSet(rootCmd.Flags())
+	// ratelimit takes the credentials and hosts of both sides
+	ratelimitCmd.Flags().AddFlagSet(rootCmd.Flags())
+	// batch takes the credentials of both sides and the options its
+	// entries share
+	batchCmd.Flags().AddFlagSet(rootCmd.Flags())
+	// auth takes the endpoints, credentials, and mode of a migration to
+	// check both of its sides
+	authCmd.Flags().AddFlagSet(rootCmd.Flags())
 }
 
 // normalizeHostname strips scheme prefixes (https://, http://) and
@@ -172,13 +640,105 @@ func envBool(key string) bool {
 	return v == "1" || v == "true" || v == "yes"
 }
 
+// envInt parses the environment variable identified by key as an integer.
+// An unset variable returns 0 and a malformed one -1, which validateFlags
+// then rejects like a negative flag value.
+func envInt(key string) int {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// envList splits the comma-separated environment variable identified by
+// key into a slice, trimming whitespace and dropping empty entries. An
+// unset variable returns nil.
+func envList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// envKeyPattern finds the environment variable a flag can be set with in
+// its usage, e.g. "(env: SOURCE_ORG)"
+var envKeyPattern = regexp.MustCompile(`\(env: ([A-Z0-9_]+)`)
+
+// loadEnvFiles loads the --env-file files and sets the flags of cmd not
+// given on the command line from the variables they defined, parsed as
+// their defaults are at startup. A missing default .env is skipped and its
+// other errors are only warned about; any error with a file named on the
+// command line is fatal.
+func loadEnvFiles(cmd *cobra.Command) error {
+	f := cmd.Flag("env-file")
+	explicit := f != nil && f.Changed
+	if err := envfile.LoadFiles(envFiles, explicit); err != nil {
+		if explicit {
+			cmd.SilenceUsage = true
+			return err
+		}
+		logger.Warning("Failed to load .env file: %v", err)
+		return nil
+	}
+
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		m := envKeyPattern.FindStringSubmatch(f.Usage)
+		if err != nil || f.Changed || m == nil || !envfile.LoadedFromFile(m[1]) {
+			return
+		}
+		err = setFlagFromEnv(f, m[1])
+	})
+	return err
+}
+
+// setFlagFromEnv sets the value of f, leaving it unchanged on the command
+// line, from the environment variable key
+func setFlagFromEnv(f *pflag.Flag, key string) error {
+	var err error
+	switch v := f.Value.(type) {
+	case pflag.SliceValue:
+		err = v.Replace(envList(key))
+	default:
+		switch f.Value.Type() {
+		case "bool":
+			err = f.Value.Set(strconv.FormatBool(envBool(key)))
+		case "int":
+			err = f.Value.Set(strconv.Itoa(envInt(key)))
+		default:
+			err = f.Value.Set(os.Getenv(key))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return nil
+}
+
 // flagSource returns a human-readable label for where a flag's value
 // originated. The priority order mirrors the one documented in the CLI
-// help: CLI flag → shell env var → .env file → default.
+// help: CLI flag → shell env var → .env file → profile → default.
 func flagSource(cmd *cobra.Command, flagName, envKey string) string {
 	if cmd.Flags().Changed(flagName) {
 		return "--" + flagName + " (CLI flag)"
 	}
+	if side, ok := refFlags[flagName]; ok {
+		return "--" + side + " (CLI flag)"
+	}
+	if remote, ok := detectedFlags[flagName]; ok {
+		return remoteLabel(remote)
+	}
+	if profileFlags[flagName] {
+		return "profile " + profileName
+	}
 	if envKey != "" {
 		if envfile.LoadedFromFile(envKey) {
 			return envKey + " (.env file)"
@@ -200,6 +760,12 @@ func logResolvedConfig(cmd *cobra.Command, mode types.MigrationMode) {
 		logger.Info("gh-vars-migrator - Organization Variable Migration")
 	case types.ModeRepoToRepo:
 		logger.Info("gh-vars-migrator - Repository Variable Migration")
+	case types.ModeOrgToRepo:
+		logger.Info("gh-vars-migrator - Organization to Repository Variable Migration")
+	case types.ModeRepoToOrg:
+		logger.Info("gh-vars-migrator - Repository to Organization Variable Promotion")
+	case types.ModeFanOut:
+		logger.Info("gh-vars-migrator - Organization Variable Fan-out")
 	}
 	logger.Info("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
@@ -215,7 +781,11 @@ func logResolvedConfig(cmd *cobra.Command, mode types.MigrationMode) {
 	}
 
 	// Target configuration
-	logger.Info("Target Org:      %s  ← %s", targetOrg, flagSource(cmd, "target-org", "TARGET_ORG"))
+	if targetReposFile != "" {
+		logger.Info("Target Repos:    %d from %s  ← %s", len(targets), targetReposFile, flagSource(cmd, "target-repos-file", "TARGET_REPOS_FILE"))
+	} else {
+		logger.Info("Target Org:      %s  ← %s", targetOrg, flagSource(cmd, "target-org", "TARGET_ORG"))
+	}
 	if targetRepo != "" {
 		logger.Info("Target Repo:     %s  ← %s", targetRepo, flagSource(cmd, "target-repo", "TARGET_REPO"))
 	}
@@ -227,32 +797,242 @@ func logResolvedConfig(cmd *cobra.Command, mode types.MigrationMode) {
 
 	// Mode-specific details
 	if mode == types.ModeOrgToOrg {
-		logger.Info("Org Visibility:  preserve source")
+		if visibility != "" {
+			logger.Info("Org Visibility:  %s (override)  ← %s", visibility, flagSource(cmd, "visibility", "VISIBILITY"))
+		} else {
+			logger.Info("Org Visibility:  preserve source")
+		}
+		if repoMapFile != "" {
+			logger.Info("Repo Map:        %s (%d rename(s))  ← %s", repoMapFile, len(repoMap), flagSource(cmd, "repo-map", "REPO_MAP"))
+		}
+		if selectedFallback != "" {
+			logger.Info("Sel. Fallback:   %s  ← %s", selectedFallback, flagSource(cmd, "selected-fallback", "SELECTED_FALLBACK"))
+		}
+		if deep {
+			logger.Info("Deep:            true  ← %s", flagSource(cmd, "deep", "DEEP"))
+			if len(excludeRepos) > 0 {
+				logger.Info("Exclude Repos:   %s  ← %s", strings.Join(excludeRepos, ", "), flagSource(cmd, "exclude-repos", "EXCLUDE_REPOS"))
+			}
+			if skipEnvs {
+				logger.Info("Skip Envs:       true  ← %s", flagSource(cmd, "skip-envs", "SKIP_ENVS"))
+			}
+			if len(excludeEnvs) > 0 {
+				logger.Info("Exclude Envs:    %s  ← %s", strings.Join(excludeEnvs, ", "), flagSource(cmd, "exclude-envs", "EXCLUDE_ENVS"))
+			}
+			if len(envMap) > 0 {
+				logger.Info("Env Map:         %d rename(s)  ← %s", len(envMap), flagSource(cmd, "env-map", "ENV_MAP"))
+			}
+		}
+	}
+	if mode == types.ModeOrgToRepo {
+		logger.Info("Org Visibility:  dropped (repository variables have none)  ← %s", flagSource(cmd, "org-to-repo", "ORG_TO_REPO"))
+	}
+	if mode == types.ModeRepoToOrg {
+		if targetVisibility != "" {
+			logger.Info("Org Visibility:  %s  ← %s", targetVisibility, flagSource(cmd, "target-visibility", "TARGET_VISIBILITY"))
+		} else {
+			logger.Info("Org Visibility:  all (default)")
+		}
+	}
+	if mode == types.ModeFanOut {
+		if allRepos {
+			logger.Info("Repositories:    all non-archived  ← %s", flagSource(cmd, "all-repos", "ALL_REPOS"))
+		} else {
+			logger.Info("Repositories:    %s", strings.Join(fanOutRepos, ", "))
+		}
 	}
 	if mode == types.ModeRepoToRepo {
-		if skipEnvs {
+		switch {
+		case skipEnvs:
 			logger.Info("Skip Envs:       true  ← %s", flagSource(cmd, "skip-envs", "SKIP_ENVS"))
-		} else {
+		case envName != "" || sourceEnv != "":
+			name, flag := singleEnv()
+			envKey := "ENV_NAME"
+			if flag == "source-env" {
+				envKey = "SOURCE_ENV"
+			}
+			if targetEnv != "" {
+				name += " → " + targetEnv
+			}
+			logger.Info("Environment:     %s only, no repository variables  ← %s", name, flagSource(cmd, flag, envKey))
+		case len(envNames) > 0:
+			logger.Info("Environments:    %s  ← %s", strings.Join(envNames, ", "), flagSource(cmd, "envs", "ENVS"))
+		case len(excludeEnvs) > 0:
+			logger.Info("Exclude Envs:    %s  ← %s", strings.Join(excludeEnvs, ", "), flagSource(cmd, "exclude-envs", "EXCLUDE_ENVS"))
+		default:
 			logger.Info("Environments:    auto-discover and migrate")
 		}
+		if envsOnly {
+			logger.Info("Repo Variables:  skipped, environments only  ← %s", flagSource(cmd, "envs-only", "ENVS_ONLY"))
+		}
+		if len(envMap) > 0 {
+			logger.Info("Env Map:         %d rename(s)  ← %s", len(envMap), flagSource(cmd, "env-map", "ENV_MAP"))
+		}
+	}
+
+	if copyEnvProtection {
+		if updateEnvSettings {
+			logger.Info("Env Protection:  copy, existing environments updated  ← %s", flagSource(cmd, "update-env-settings", "UPDATE_ENV_SETTINGS"))
+		} else {
+			logger.Info("Env Protection:  copy to new environments  ← %s", flagSource(cmd, "copy-env-protection", "COPY_ENV_PROTECTION"))
+		}
 	}
 
 	// Common options
 	logger.Info("Dry-run:         %v  ← %s", dryRun, flagSource(cmd, "dry-run", "DRY_RUN"))
+	if exitCodeOnDiff {
+		logger.Info("Exit Code Diff:  true  ← %s", flagSource(cmd, "exit-code-on-diff", "EXIT_CODE_ON_DIFF"))
+	}
 	logger.Info("Skip Overwrite:  %v  ← %s", skipOverwrite, flagSource(cmd, "skip-overwrite", "SKIP_OVERWRITE"))
+	if interactive {
+		logger.Info("Interactive:     true  ← %s", flagSource(cmd, "interactive", "INTERACTIVE"))
+	}
+	if assumeYes {
+		logger.Info("Yes:             true  ← %s", flagSource(cmd, "yes", "ASSUME_YES"))
+	}
+	if onConflict != "" {
+		logger.Info("On Conflict:     %s  ← %s", onConflict, flagSource(cmd, "on-conflict", "ON_CONFLICT"))
+	}
+	if diffMode {
+		logger.Info("Diff:            true  ← %s", flagSource(cmd, "diff", "DIFF"))
+	}
+	if verify {
+		logger.Info("Verify:          true  ← %s", flagSource(cmd, "verify", "VERIFY"))
+	}
+	if checkUsage {
+		logger.Info("Check Usage:     true  ← %s", flagSource(cmd, "check-usage", "CHECK_USAGE"))
+	}
+	if failFast {
+		logger.Info("Fail Fast:       true  ← %s", flagSource(cmd, "fail-fast", "FAIL_FAST"))
+	}
+	if maxErrors > 0 {
+		logger.Info("Max Errors:      %d  ← %s", maxErrors, flagSource(cmd, "max-errors", "MAX_ERRORS"))
+	}
+	if maxAPICalls > 0 {
+		logger.Info("Max API Calls:   %d  ← %s", maxAPICalls, flagSource(cmd, "max-api-calls", "MAX_API_CALLS"))
+	}
+	if failIfEmpty {
+		logger.Info("Fail If Empty:   true  ← %s", flagSource(cmd, "fail-if-empty", "FAIL_IF_EMPTY"))
+	}
+	if alwaysWrite {
+		logger.Info("Always Write:    true  ← %s", flagSource(cmd, "always-write", "ALWAYS_WRITE"))
+	}
+	if skipLimitChecks {
+		logger.Info("Limit Checks:    skipped  ← %s", flagSource(cmd, "skip-limit-checks", "SKIP_LIMIT_CHECKS"))
+	}
+	if strictNames {
+		logger.Info("Strict Names:    true  ← %s", flagSource(cmd, "strict-names", "STRICT_NAMES"))
+	}
+	if snapshotFile != "" {
+		logger.Info("Snapshot File:   %s  ← %s", snapshotFile, flagSource(cmd, "snapshot-file", "SNAPSHOT_FILE"))
+	}
+	if reportFile != "" {
+		values := "values omitted"
+		if reportIncludeValues {
+			values = "values included"
+		}
+		logger.Info("Report File:     %s (%s)  ← %s", reportFile, values, flagSource(cmd, "report-file", "REPORT_FILE"))
+	}
+	if planOut != "" {
+		values := "values omitted"
+		if planIncludeValues {
+			values = "values included"
+		}
+		logger.Info("Plan Out:        %s (%s)  ← %s", planOut, values, flagSource(cmd, "plan-out", "PLAN_OUT"))
+	}
+	if preHook != "" {
+		logger.Info("Pre-hook:        %s  ← %s", preHook, flagSource(cmd, "pre-hook", "PRE_HOOK"))
+	}
+	if postHook != "" {
+		logger.Info("Post-hook:       %s  ← %s", postHook, flagSource(cmd, "post-hook", "POST_HOOK"))
+	}
+	if retryReport != nil {
+		logger.Info("Retry Failed:    %s (%d variable(s), on-conflict %s)  ← %s", retryFailed, len(retryReport.Failed()), retryReport.Config.OnConflict, flagSource(cmd, "retry-failed", "RETRY_FAILED"))
+	}
+	if applyPlan != nil {
+		logger.Info("Plan:            %s (%d change(s), made %s)", planFile, len(applyPlan.Actions), applyPlan.CreatedAt.Format(time.RFC3339))
+	}
+	if nameMapFile != "" {
+		logger.Info("Name Map:        %s (%d rename(s))  ← %s", nameMapFile, len(nameMap), flagSource(cmd, "name-map", "NAME_MAP"))
+	}
+	if targetPrefix != "" {
+		logger.Info("Target Prefix:   %s  ← %s", targetPrefix, flagSource(cmd, "target-prefix", "TARGET_PREFIX"))
+	}
+	if targetSuffix != "" {
+		logger.Info("Target Suffix:   %s  ← %s", targetSuffix, flagSource(cmd, "target-suffix", "TARGET_SUFFIX"))
+	}
+	if allowCollisions {
+		logger.Info("Collisions:      allowed  ← %s", flagSource(cmd, "allow-collisions", "ALLOW_COLLISIONS"))
+	}
+	if valueOverridesFile != "" {
+		logger.Info("Value Overrides: %s (%d value(s))  ← %s", valueOverridesFile, len(valueOverrides), flagSource(cmd, "value-overrides", "VALUE_OVERRIDES"))
+	}
+	if rewriteValues {
+		logger.Info("Rewrite Values:  true  ← %s", flagSource(cmd, "rewrite-values", "REWRITE_VALUES"))
+	}
+	if len(replacements) > 0 {
+		logger.Info("Replacements:    %d (word boundaries: %v)  ← %s", len(replacements), replaceWordBoundaries, flagSource(cmd, "replace", "REPLACE_VALUES"))
+	}
+	if len(varNames) > 0 {
+		logger.Info("Vars:            %s  ← %s", strings.Join(varNames, ", "), flagSource(cmd, "vars", "VARS"))
+	}
+	if len(includePatterns) > 0 {
+		logger.Info("Include:         %s  ← %s", strings.Join(includePatterns, ", "), flagSource(cmd, "include", "INCLUDE_VARS"))
+	}
+	if len(excludePatterns) > 0 {
+		logger.Info("Exclude:         %s  ← %s", strings.Join(excludePatterns, ", "), flagSource(cmd, "exclude", "EXCLUDE_VARS"))
+	}
+	if filterRegex != "" {
+		logger.Info("Filter Regex:    %s  ← %s", filterRegex, flagSource(cmd, "filter-regex", "FILTER_REGEX"))
+	}
+	if since != "" {
+		logger.Info("Since:           %s  ← %s", since, flagSource(cmd, "since", "SINCE"))
+	}
+	if skipUnused {
+		logger.Info("Skip Unused:     true  ← %s", flagSource(cmd, "skip-unused", "SKIP_UNUSED"))
+	}
 	logger.Info("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 }
 
 // validateFlags validates the flags based on the detected migration mode
 func validateFlags(cmd *cobra.Command, args []string) error {
-	// If a subcommand is being run, skip validation
-	if cmd.Name() != "gh-vars-migrator" {
+	helpShown = false
+	// If a subcommand other than apply is being run, skip validation
+	if name := cmd.Name(); name != "gh-vars-migrator" && name != "apply" && name != "validate" {
+		return nil
+	}
+
+	// A rollback only needs the snapshot file and target credentials
+	if rollbackFile != "" {
+		cmd.SilenceUsage = true
+		targetHostname = normalizeHostname(targetHostname)
+		if diffMode {
+			return fmt.Errorf("--rollback cannot be combined with --diff")
+		}
+		if snapshotFile != "" {
+			return fmt.Errorf("--rollback cannot be combined with --snapshot-file")
+		}
+		if reportFile != "" {
+			return fmt.Errorf("--rollback cannot be combined with --report-file")
+		}
+		if preHook != "" || postHook != "" {
+			return fmt.Errorf("--rollback cannot be combined with --pre-hook or --post-hook")
+		}
+		if retryFailed != "" {
+			return fmt.Errorf("--rollback cannot be combined with --retry-failed")
+		}
 		return nil
 	}
 
+	if err := applyRefs(cmd); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+
 	// Check if any migration flags were provided
 	if sourceOrg == "" && targetOrg == "" {
-		// No flags provided, show help
+		// No flags provided, show help; runMigration then does nothing
+		helpShown = true
 		return cmd.Help()
 	}
 
@@ -263,17 +1043,110 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 	sourceHostname = normalizeHostname(sourceHostname)
 	targetHostname = normalizeHostname(targetHostname)
 
+	if err := detectSource(); err != nil {
+		return err
+	}
+
 	// Validate required flags
 	if sourceOrg == "" {
-		return fmt.Errorf("--source-org flag is required")
+		return fmt.Errorf("--source-org or --source flag is required")
+	}
+	if targetOrg == "" && targetReposFile == "" {
+		return fmt.Errorf("--target-org or --target flag is required")
+	}
+
+	if err := validateRunOptions(); err != nil {
+		return err
+	}
+
+	retryReport = nil
+	if retryFailed != "" {
+		if diffMode {
+			return fmt.Errorf("--retry-failed cannot be combined with --diff")
+		}
+		r, err := report.Load(retryFailed)
+		if err != nil {
+			return fmt.Errorf("--retry-failed: %w", err)
+		}
+		retryReport = r
+		// The retry resolves conflicts the way the original run did unless
+		// told otherwise
+		if onConflict == "" && !skipOverwrite {
+			onConflict = string(r.Config.OnConflict)
+		}
+	}
+
+	if err := validateConflictOptions(); err != nil {
+		return err
+	}
+
+	if err := config.ValidatePatterns(includePatterns, excludePatterns); err != nil {
+		return err
+	}
+	if err := config.ValidateFilterRegex(filterRegex); err != nil {
+		return err
+	}
+	sinceTime = time.Time{}
+	if since != "" {
+		t, err := config.ParseSince(since, time.Now())
+		if err != nil {
+			return fmt.Errorf("--since: %w", err)
+		}
+		sinceTime = t
+	}
+	if err := config.ValidateNameAffixes(targetPrefix, targetSuffix); err != nil {
+		return err
+	}
+
+	// Load and validate mapping files before any API calls are made
+	nameMap = nil
+	if nameMapFile != "" {
+		m, err := mapfile.Load(nameMapFile)
+		if err != nil {
+			return fmt.Errorf("--name-map: %w", err)
+		}
+		if err := config.ValidateNameMap(m); err != nil {
+			return fmt.Errorf("--name-map: %w", err)
+		}
+		nameMap = m
+	}
+
+	valueOverrides = nil
+	if valueOverridesFile != "" {
+		m, err := mapfile.Load(valueOverridesFile)
+		if err != nil {
+			return fmt.Errorf("--value-overrides: %w", err)
+		}
+		if err := config.ValidateValueOverrides(m); err != nil {
+			return fmt.Errorf("--value-overrides: %w", err)
+		}
+		valueOverrides = m
+	}
+
+	parsed, err := config.ParseReplacements(replaceSpecs)
+	if err != nil {
+		return fmt.Errorf("--replace: %w", err)
+	}
+	replacements = parsed
+
+	modeFlags := 0
+	for _, set := range []bool{orgToOrg, orgToRepo, repoToOrg, fanOut} {
+		if set {
+			modeFlags++
+		}
 	}
-	if targetOrg == "" {
-		return fmt.Errorf("--target-org flag is required")
+	if modeFlags > 1 {
+		return fmt.Errorf("only one of --org-to-org, --org-to-repo, --repo-to-org, and --fan-out can be used")
 	}
 
 	// Detect mode and validate accordingly
 	mode := detectMigrationMode()
 
+	targets = nil
+	if targetReposFile != "" && mode != types.ModeRepoToRepo {
+		return fmt.Errorf("--target-repos-file can only be used for repository-to-repository migration")
+	}
+
 	switch mode {
 	case types.ModeOrgToOrg:
 		// Org-to-org: no additional requirements
@@ -286,62 +1159,590 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 		if sourceRepo == "" {
 			return fmt.Errorf("--source-repo is required for repository migration")
 		}
+		if targetReposFile != "" {
+			if err := validateTargetReposFile(); err != nil {
+				return err
+			}
+			break
+		}
 		if targetRepo == "" {
 			return fmt.Errorf("--target-repo is required for repository migration")
 		}
 		if sourceOrg == targetOrg && sourceRepo == targetRepo {
 			return fmt.Errorf("source and target repositories cannot be the same")
 		}
-	}
 
-	return nil
-}
+	case types.ModeOrgToRepo:
+		// Org-to-repo: requires a target repo; a source repo is meaningless
+		if targetRepo == "" {
+			return fmt.Errorf("--target-repo is required for organization-to-repository migration")
+		}
+		if sourceRepo != "" {
+			return fmt.Errorf("--source-repo cannot be used with --org-to-repo")
+		}
 
-// detectMigrationMode determines the migration mode based on the provided flags
-func detectMigrationMode() types.MigrationMode {
-	// If --org-to-org flag is set, it's organization migration
-	if orgToOrg {
-		return types.ModeOrgToOrg
+	case types.ModeRepoToOrg:
+		// Repo-to-org: requires a source repo; a target repo is meaningless
+		if sourceRepo == "" {
+			return fmt.Errorf("--source-repo is required for repository-to-organization promotion")
+		}
+		if targetRepo != "" {
+			return fmt.Errorf("--target-repo cannot be used with --repo-to-org")
+		}
+		if err := config.ValidateTargetVisibility(targetVisibility); err != nil {
+			return fmt.Errorf("--target-visibility: %w", err)
+		}
 	}
 
-	// Default to repository-to-repository migration
-	return types.ModeRepoToRepo
-}
-
-// runMigration executes the migration based on the detected mode
-func runMigration(cmd *cobra.Command, args []string) error {
-	// Resolve tokens for source and target
-	sourceToken, targetToken, err := resolveTokens()
-	if err != nil {
-		return err
+	if targetVisibility != "" && mode != types.ModeRepoToOrg {
+		return fmt.Errorf("--target-visibility can only be used with --repo-to-org")
 	}
-
-	// Create source and target clients
-	sourceClient, targetClient, err := createClients(sourceToken, targetToken)
-	if err != nil {
-		return err
+	if checkUsage {
+		if mode != types.ModeRepoToRepo && mode != types.ModeOrgToRepo {
+			return fmt.Errorf("--check-usage can only be used when the target is a repository (repo-to-repo or --org-to-repo)")
+		}
+		if diffMode {
+			return fmt.Errorf("--check-usage cannot be combined with --diff")
+		}
 	}
-
-	// Validate authentication
-	if err := validateAuth(sourceClient, targetClient); err != nil {
-		return err
+	if skipUnused {
+		if mode != types.ModeRepoToRepo {
+			return fmt.Errorf("--skip-unused can only be used in repo-to-repo mode")
+		}
+		if diffMode {
+			return fmt.Errorf("--skip-unused cannot be combined with --diff")
+		}
+	}
+	repoMap = nil
+	if repoMapFile != "" {
+		if mode != types.ModeOrgToOrg {
+			return fmt.Errorf("--repo-map can only be used with --org-to-org")
+		}
+		m, err := mapfile.Load(repoMapFile)
+		if err != nil {
+			return fmt.Errorf("--repo-map: %w", err)
+		}
+		if err := config.ValidateRepoMap(m); err != nil {
+			return fmt.Errorf("--repo-map: %w", err)
+		}
+		repoMap = m
+	}
+	if visibility != "" {
+		if mode != types.ModeOrgToOrg {
+			return fmt.Errorf("--visibility can only be used with --org-to-org")
+		}
+		if err := config.ValidateVisibility(visibility); err != nil {
+			return fmt.Errorf("--visibility: %w", err)
+		}
 	}
 
-	// Detect migration mode
-	mode := detectMigrationMode()
-
-	// Validate PAT permissions before starting migration
-	if err := validatePermissions(sourceClient, targetClient, mode); err != nil {
-		return err
+	if selectedFallback != "" {
+		if mode != types.ModeOrgToOrg {
+			return fmt.Errorf("--selected-fallback can only be used with --org-to-org")
+		}
+		if err := config.ValidateSelectedFallback(types.SelectedFallback(selectedFallback)); err != nil {
+			return fmt.Errorf("--selected-fallback: %w", err)
+		}
 	}
 
-	// Build migration configuration
-	cfg := &types.MigrationConfig{
-		Mode:          mode,
-		SourceOrg:     sourceOrg,
+	if sourceEnv != "" && envName != "" {
+		return fmt.Errorf("--env and --source-env cannot be used together; both name the source environment")
+	}
+	if targetEnv != "" {
+		if sourceEnv == "" && envName == "" {
+			return fmt.Errorf("--target-env requires --source-env")
+		}
+		if len(envMapSpecs) > 0 {
+			return fmt.Errorf("--target-env and --env-map cannot be used together")
+		}
+	}
+	if single, flag := singleEnv(); single != "" {
+		if mode != types.ModeRepoToRepo {
+			return fmt.Errorf("--%s can only be used for repository-to-repository migration", flag)
+		}
+		if skipEnvs {
+			return fmt.Errorf("--%s and --skip-envs cannot be used together", flag)
+		}
+		if len(envNames) > 0 {
+			return fmt.Errorf("--%s and --envs cannot be used together", flag)
+		}
+		if len(excludeEnvs) > 0 {
+			return fmt.Errorf("--%s and --exclude-envs cannot be used together", flag)
+		}
+	}
+
+	if envsOnly {
+		if mode != types.ModeRepoToRepo {
+			return fmt.Errorf("--envs-only can only be used for repository-to-repository migration")
+		}
+		if skipEnvs {
+			return fmt.Errorf("--envs-only and --skip-envs cannot be used together; they would leave nothing to migrate")
+		}
+		if single, flag := singleEnv(); single != "" {
+			return fmt.Errorf("--%s already leaves out repository-level variables and cannot be combined with --envs-only", flag)
+		}
+	}
+
+	if len(envNames) > 0 {
+		if mode != types.ModeRepoToRepo {
+			return fmt.Errorf("--envs can only be used for repository-to-repository migration")
+		}
+		if skipEnvs {
+			return fmt.Errorf("--envs and --skip-envs cannot be used together")
+		}
+	}
+
+	envMap = nil
+	if len(envMapSpecs) > 0 {
+		if mode != types.ModeRepoToRepo && !deep {
+			return fmt.Errorf("--env-map can only be used for repository-to-repository migration or with --deep")
+		}
+		if skipEnvs {
+			return fmt.Errorf("--env-map and --skip-envs cannot be used together")
+		}
+		m, err := loadEnvMap(envMapSpecs)
+		if err != nil {
+			return fmt.Errorf("--env-map: %w", err)
+		}
+		envMap = m
+	}
+
+	if len(excludeEnvs) > 0 {
+		if mode != types.ModeRepoToRepo && !deep {
+			return fmt.Errorf("--exclude-envs can only be used for repository-to-repository migration or with --deep")
+		}
+		if len(envNames) > 0 {
+			return fmt.Errorf("--envs and --exclude-envs cannot be used together")
+		}
+		if skipEnvs {
+			return fmt.Errorf("--exclude-envs and --skip-envs cannot be used together")
+		}
+		if err := config.ValidateEnvPatterns(excludeEnvs); err != nil {
+			return fmt.Errorf("--exclude-envs: %w", err)
+		}
+	}
+
+	if deep {
+		if mode != types.ModeOrgToOrg {
+			return fmt.Errorf("--deep can only be used with --org-to-org")
+		}
+		if snapshotFile != "" {
+			return fmt.Errorf("--snapshot-file cannot be used with --deep")
+		}
+		if err := config.ValidateRepoPatterns(excludeRepos); err != nil {
+			return fmt.Errorf("--exclude-repos: %w", err)
+		}
+	} else if len(excludeRepos) > 0 {
+		return fmt.Errorf("--exclude-repos can only be used with --deep")
+	}
+
+	if copyEnvProtection {
+		if mode != types.ModeRepoToRepo && !deep {
+			return fmt.Errorf("--copy-env-protection can only be used for repository-to-repository migration or with --deep")
+		}
+		if skipEnvs {
+			return fmt.Errorf("--copy-env-protection and --skip-envs cannot be used together")
+		}
+		if diffMode {
+			return fmt.Errorf("--copy-env-protection cannot be combined with --diff")
+		}
+	} else if updateEnvSettings {
+		return fmt.Errorf("--update-env-settings requires --copy-env-protection")
+	}
+
+	fanOutRepos = nil
+	if mode == types.ModeFanOut {
+		if err := validateFanOutFlags(); err != nil {
+			return err
+		}
+	} else if len(repoNames) > 0 || reposFile != "" || allRepos {
+		return fmt.Errorf("--repos, --repos-file, and --all-repos can only be used with --fan-out")
+	}
+
+	return nil
+}
+
+// validateRunOptions checks the output, hook, and stop options shared by
+// migrations and manifest applies
+func validateRunOptions() error {
+	if diffMode && snapshotFile != "" {
+		return fmt.Errorf("--snapshot-file cannot be combined with --diff")
+	}
+	if diffMode && reportFile != "" {
+		return fmt.Errorf("--report-file cannot be combined with --diff")
+	}
+	if reportIncludeValues && reportFile == "" {
+		return fmt.Errorf("--report-include-values requires --report-file")
+	}
+	if diffMode && (preHook != "" || postHook != "") {
+		return fmt.Errorf("--pre-hook and --post-hook cannot be combined with --diff")
+	}
+	if hooksInDryRun && preHook == "" && postHook == "" {
+		return fmt.Errorf("--hooks-in-dry-run requires --pre-hook or --post-hook")
+	}
+	if planOut != "" && !dryRun {
+		return fmt.Errorf("--plan-out requires --dry-run")
+	}
+	if planIncludeValues && planOut == "" {
+		return fmt.Errorf("--plan-include-values requires --plan-out")
+	}
+	if maxErrors < 0 {
+		return fmt.Errorf("--max-errors must be a non-negative number")
+	}
+	if failFast && maxErrors > 1 {
+		return fmt.Errorf("--fail-fast stops at the first error and cannot be combined with --max-errors %d", maxErrors)
+	}
+	if maxAPICalls < 0 {
+		return fmt.Errorf("--max-api-calls must be a non-negative number")
+	}
+	if maxAPICalls > 0 && diffMode {
+		return fmt.Errorf("--max-api-calls cannot be combined with --diff")
+	}
+	if failIfEmpty && diffMode {
+		return fmt.Errorf("--fail-if-empty cannot be combined with --diff")
+	}
+	if exitCodeOnDiff && !dryRun {
+		return fmt.Errorf("--exit-code-on-diff requires --dry-run")
+	}
+	if strictNames && skipLimitChecks {
+		return fmt.Errorf("--strict-names cannot be combined with --skip-limit-checks")
+	}
+	return nil
+}
+
+// validateConflictOptions checks the conflict strategy and that prompts
+// can be shown when they are asked for
+func validateConflictOptions() error {
+	if err := config.ValidateConflictStrategy(types.ConflictStrategy(onConflict), skipOverwrite); err != nil {
+		return err
+	}
+	if types.ConflictStrategy(onConflict) == types.ConflictPrompt && !diffMode && !dryRun && !term.IsTerminal(os.Stdin) {
+		return fmt.Errorf("--on-conflict=prompt requires an interactive terminal")
+	}
+	if interactive && !diffMode && !dryRun && (!term.IsTerminal(os.Stdin) || !term.IsTerminal(os.Stdout)) {
+		return fmt.Errorf("--interactive requires an interactive terminal")
+	}
+	return nil
+}
+
+// loadEnvMap builds the environment rename map from --env-map entries.
+// An entry containing '=' is a SOURCE=TARGET pair; any other entry is the
+// path of a mapping file.
+func loadEnvMap(specs []string) (map[string]string, error) {
+	out := make(map[string]string)
+	add := func(src, dst string) error {
+		for existing := range out {
+			if strings.EqualFold(existing, src) {
+				return fmt.Errorf("environment %q is mapped more than once", src)
+			}
+		}
+		out[src] = dst
+		return nil
+	}
+
+	for _, spec := range specs {
+		if strings.Contains(spec, "=") {
+			src, dst, err := config.ParseEnvMapping(spec)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(src, dst); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		m, err := mapfile.Load(spec)
+		if err != nil {
+			return nil, err
+		}
+		for src, dst := range m {
+			if err := add(src, dst); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := config.ValidateEnvMap(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// validateTargetReposFile parses --target-repos-file into targets. Every
+// invalid line is reported before anything is migrated.
+func validateTargetReposFile() error {
+	if targetOrg != "" || targetRepo != "" {
+		return fmt.Errorf("--target-repos-file cannot be combined with --target-org or --target-repo")
+	}
+	if snapshotFile != "" {
+		return fmt.Errorf("--snapshot-file cannot be used with --target-repos-file")
+	}
+
+	data, err := os.ReadFile(targetReposFile)
+	if err != nil {
+		return fmt.Errorf("--target-repos-file: %w", err)
+	}
+	parsed, err := config.ParseTargetRepos(string(data))
+	if err != nil {
+		return fmt.Errorf("--target-repos-file %s: %w", targetReposFile, err)
+	}
+	for _, t := range parsed {
+		if strings.EqualFold(t.Owner, sourceOrg) && strings.EqualFold(t.Repo, sourceRepo) {
+			return fmt.Errorf("--target-repos-file %s: target %s is the source repository", targetReposFile, t)
+		}
+	}
+	targets = parsed
+	return nil
+}
+
+// validateFanOutFlags checks the fan-out repository selection and resolves
+// --repos and --repos-file into fanOutRepos
+func validateFanOutFlags() error {
+	if sourceRepo != "" || targetRepo != "" {
+		return fmt.Errorf("--source-repo and --target-repo cannot be used with --fan-out; select repositories with --repos, --repos-file, or --all-repos")
+	}
+	if snapshotFile != "" {
+		return fmt.Errorf("--snapshot-file cannot be used with --fan-out")
+	}
+
+	if allRepos {
+		if len(repoNames) > 0 || reposFile != "" {
+			return fmt.Errorf("--all-repos cannot be combined with --repos or --repos-file")
+		}
+		return nil
+	}
+
+	names := append([]string(nil), repoNames...)
+	if reposFile != "" {
+		data, err := os.ReadFile(reposFile)
+		if err != nil {
+			return fmt.Errorf("--repos-file: %w", err)
+		}
+		names = append(names, config.ParseRepoList(string(data))...)
+	}
+
+	repos, err := config.NormalizeRepoNames(names, targetOrg)
+	if err != nil {
+		return fmt.Errorf("--repos: %w", err)
+	}
+	if len(repos) == 0 {
+		return fmt.Errorf("--fan-out requires --repos, --repos-file, or --all-repos")
+	}
+	fanOutRepos = repos
+	return nil
+}
+
+// singleEnv returns the one source environment --source-env or --env
+// selects, and the flag that named it
+func singleEnv() (string, string) {
+	if sourceEnv != "" {
+		return sourceEnv, "source-env"
+	}
+	return envName, "env"
+}
+
+// detectMigrationMode determines the migration mode based on the provided flags
+func detectMigrationMode() types.MigrationMode {
+	// If --org-to-org flag is set, it's organization migration
+	if orgToOrg {
+		return types.ModeOrgToOrg
+	}
+
+	// If --org-to-repo flag is set, org variables are copied into a repo
+	if orgToRepo {
+		return types.ModeOrgToRepo
+	}
+
+	// If --repo-to-org flag is set, repo variables are promoted to the org
+	if repoToOrg {
+		return types.ModeRepoToOrg
+	}
+
+	// If --fan-out flag is set, org variables are copied into many repos
+	if fanOut {
+		return types.ModeFanOut
+	}
+
+	// --source-env and --target-env name environments of a source and a
+	// target repository
+	if sourceEnv != "" || targetEnv != "" {
+		return types.ModeRepoToRepo
+	}
+
+	// A --source naming an organization copies its variables into the
+	// organization or repository --target names
+	if sourceRef != "" && sourceRepo == "" && targetReposFile == "" {
+		if targetRepo != "" {
+			return types.ModeOrgToRepo
+		}
+		return types.ModeOrgToOrg
+	}
+
+	// Default to repository-to-repository migration
+	return types.ModeRepoToRepo
+}
+
+// runMigration executes the migration based on the detected mode
+func runMigration(cmd *cobra.Command, args []string) error {
+	if helpShown {
+		return nil
+	}
+	if rollbackFile != "" {
+		return runRollback()
+	}
+	if applyManifest != nil {
+		return runManifest(cmd)
+	}
+
+	// Resolve tokens for source and target
+	sourceToken, targetToken, err := resolveTokens()
+	if err != nil {
+		return authError(err)
+	}
+
+	// Create source and target clients
+	sourceClient, targetClient, err := createClients(sourceToken, targetToken)
+	if err != nil {
+		return authError(err)
+	}
+
+	// Validate authentication
+	if err := validateAuth(sourceClient, targetClient); err != nil {
+		return authError(err)
+	}
+
+	// Detect migration mode
+	mode := detectMigrationMode()
+
+	// Validate PAT permissions before starting migration
+	if err := validatePermissions(sourceClient, targetClient, mode); err != nil {
+		return authError(err)
+	}
+
+	// Build migration configuration
+	cfg := migrationConfig(mode)
+
+	// Check that the source and target exist before anything is read
+	if err := checkEndpoints(sourceClient, targetClient, cfg); err != nil {
+		return err
+	}
+
+	if applyPlan != nil {
+		if err := applyPlan.Check(cfg); err != nil {
+			return fmt.Errorf("--plan %s: %w", planFile, err)
+		}
+		if len(applyPlan.Actions) == 0 {
+			logger.Success("Plan %s has no changes to apply", planFile)
+			return nil
+		}
+		cfg.Plan = applyPlan.Actions
+	}
+	if retryReport != nil {
+		if err := retryReport.Check(cfg); err != nil {
+			return fmt.Errorf("--retry-failed %s: %w", retryFailed, err)
+		}
+		failed := retryReport.Failed()
+		if len(failed) == 0 {
+			logger.Success("Report %s has no failed variables to retry", retryFailed)
+			return nil
+		}
+		cfg.Retry = failed
+	}
+
+	// Print resolved configuration with provenance
+	logResolvedConfig(cmd, mode)
+
+	// Create and run migrator with both clients
+	m, err := migrator.New(cfg, sourceClient, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrator: %w", err)
+	}
+
+	if diffMode {
+		return runDiff(m)
+	}
+
+	if err := confirmMigration(m, term.IsTerminal(os.Stdin)); err != nil {
+		return err
+	}
+
+	if snapshotFile != "" {
+		snap, err := m.Snapshot()
+		if err != nil {
+			return fmt.Errorf("snapshot failed: %w", err)
+		}
+		if err := snapshot.Save(snapshotFile, snap); err != nil {
+			return fmt.Errorf("snapshot failed: %w", err)
+		}
+		logger.Success("Saved target snapshot to %s", snapshotFile)
+	}
+
+	return runMigrator(cfg, m)
+}
+
+// confirmMigration prints the plan of a migration that writes and asks
+// whether to proceed. Nothing is asked in dry-run mode, with --yes, or with
+// --interactive, which asks about every variable instead. Without a
+// terminal to ask on, --yes is required.
+func confirmMigration(m *migrator.Migrator, stdinIsTerminal bool) error {
+	if dryRun || assumeYes || interactive {
+		return nil
+	}
+	if !stdinIsTerminal {
+		return fmt.Errorf("a migration asks for confirmation before writing, but standard input is not a terminal; pass --yes to run it without asking, or --dry-run to preview it")
+	}
+	ok, err := m.Confirm()
+	if err != nil {
+		return fmt.Errorf("migration aborted: %w", err)
+	}
+	if !ok {
+		return &exitError{code: exitCodeAborted, err: fmt.Errorf("migration aborted at user request")}
+	}
+	return nil
+}
+
+// migrationConfig builds the configuration of a migration in mode from the
+// flags
+func migrationConfig(mode types.MigrationMode) *types.MigrationConfig {
+	cfg := &types.MigrationConfig{
+		Mode:          mode,
+		SourceOrg:     sourceOrg,
 		TargetOrg:     targetOrg,
 		DryRun:        dryRun,
 		SkipOverwrite: skipOverwrite,
+		OnConflict:    types.ConflictStrategy(onConflict),
+		Interactive:   interactive,
+		ShowValues:    showValues,
+		Verify:        verify,
+		CheckUsage:    checkUsage,
+		FailFast:      failFast,
+		MaxErrors:     maxErrors,
+		FailIfEmpty:   failIfEmpty,
+		MaxAPICalls:   maxAPICalls,
+		AlwaysWrite:   alwaysWrite,
+
+		// Preflight checks
+		SkipLimitChecks: skipLimitChecks,
+		StrictNames:     strictNames,
+
+		// Name and value transformations
+		NameMap:               nameMap,
+		TargetPrefix:          targetPrefix,
+		TargetSuffix:          targetSuffix,
+		AllowCollisions:       allowCollisions,
+		ValueOverrides:        valueOverrides,
+		RewriteValues:         rewriteValues,
+		Replacements:          replacements,
+		ReplaceWordBoundaries: replaceWordBoundaries,
+
+		// Filters
+		Vars:        varNames,
+		Include:     includePatterns,
+		Exclude:     excludePatterns,
+		FilterRegex: filterRegex,
+		Since:       sinceTime,
+		SkipUnused:  skipUnused,
 	}
 
 	// Set mode-specific configuration
@@ -350,38 +1751,428 @@ func runMigration(cmd *cobra.Command, args []string) error {
 		cfg.SourceRepo = sourceRepo
 		cfg.TargetOwner = targetOrg
 		cfg.TargetRepo = targetRepo
+		cfg.Targets = targets
+		cfg.SkipEnvs = skipEnvs
+		cfg.Envs = envNames
+		cfg.ExcludeEnvs = excludeEnvs
+		cfg.EnvMap = envMap
+		cfg.CopyEnvProtection = copyEnvProtection
+		cfg.UpdateEnvSettings = updateEnvSettings
+		cfg.SkipRepoVars = envsOnly
+		if single, _ := singleEnv(); single != "" {
+			cfg.Envs = []string{single}
+			cfg.SkipRepoVars = true
+			if targetEnv != "" {
+				cfg.EnvMap = map[string]string{single: targetEnv}
+			}
+		}
+	}
+	if mode == types.ModeOrgToOrg {
+		cfg.Visibility = visibility
+		cfg.RepoMap = repoMap
+		cfg.SelectedFallback = types.SelectedFallback(selectedFallback)
+		cfg.Deep = deep
+		cfg.ExcludeRepos = excludeRepos
 		cfg.SkipEnvs = skipEnvs
+		cfg.ExcludeEnvs = excludeEnvs
+		cfg.EnvMap = envMap
+		cfg.CopyEnvProtection = copyEnvProtection
+		cfg.UpdateEnvSettings = updateEnvSettings
 	}
+	if mode == types.ModeOrgToRepo {
+		cfg.TargetOwner = targetOrg
+		cfg.TargetRepo = targetRepo
+	}
+	if mode == types.ModeRepoToOrg {
+		cfg.SourceOwner = sourceOrg
+		cfg.SourceRepo = sourceRepo
+		cfg.TargetVisibility = targetVisibility
+	}
+	if mode == types.ModeFanOut {
+		cfg.TargetRepos = fanOutRepos
+		cfg.AllRepos = allRepos
+	}
+	return cfg
+}
 
-	// Print resolved configuration with provenance
-	logResolvedConfig(cmd, mode)
+// runMigrator runs a configured migrator between the pre- and post-hooks,
+// writing the report and the --output summary and stopping on interrupts
+func runMigrator(cfg *types.MigrationConfig, m *migrator.Migrator) error {
+	if err := runHook("pre-hook", preHook, hooks.Env(cfg, reportFile, nil, nil)); err != nil {
+		return fmt.Errorf("migration aborted: %w", err)
+	}
 
-	// Create and run migrator with both clients
-	m, err := migrator.New(cfg, sourceClient, targetClient)
-	if err != nil {
-		return fmt.Errorf("failed to initialize migrator: %w", err)
+	reports := &reportWriter{}
+	if reportFile != "" {
+		reports.rep = report.New(cfg, time.Now(), reportIncludeValues)
 	}
+	// The report of every run, without values, is kept for the status
+	// command, and is the --output json and csv summary
+	last := report.New(cfg, time.Now(), false)
 
+	stop := stopOnInterrupt(m, reports)
 	result, err := m.Run()
+	stop()
+	runErr := finishMigration(cfg, reports, result, err)
+
+	last.Finish(result, err, time.Now())
+	saveLastReport(last)
+	if githubSummary {
+		appendStepSummary(cfg, last)
+	}
+	if outputFormat != output.Table {
+		if err := writeMigrationSummary(os.Stdout, last); err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+
+	if hookErr := runHook("post-hook", postHook, hooks.Env(cfg, reportFile, result, err)); hookErr != nil {
+		logger.Error("%v", hookErr)
+		if runErr == nil {
+			return &exitError{code: exitCodeHook, err: hookErr}
+		}
+	}
+	return runErr
+}
+
+// writeMigrationSummary writes the summary of a run with --output json or
+// csv: the report as JSON, or one CSV row per target scope
+func writeMigrationSummary(w io.Writer, r *report.Report) error {
+	if outputFormat == output.JSON {
+		return output.WriteJSON(w, r)
+	}
+	rows := make([][]string, 0, len(r.Scopes))
+	for _, s := range r.Scopes {
+		rows = append(rows, []string{s.Scope, strconv.Itoa(s.Created), strconv.Itoa(s.Updated), strconv.Itoa(s.Unchanged),
+			strconv.Itoa(s.Skipped), strconv.Itoa(s.Failed), strconv.Itoa(s.Deleted)})
+	}
+	return output.WriteCSV(w, []string{"scope", "created", "updated", "unchanged", "skipped", "failed", "deleted"}, rows)
+}
+
+// finishMigration saves the report and the plan of a finished run and maps
+// its result to the command's exit behavior
+func finishMigration(cfg *types.MigrationConfig, reports *reportWriter, result *types.MigrationResult, err error) error {
+	if reportErr := reports.save(result, err); reportErr != nil && err == nil {
+		return reportErr
+	}
 	if err != nil {
 		return fmt.Errorf("migration failed: %w", err)
 	}
+	if planOut != "" {
+		p := plan.New(cfg, result, planIncludeValues, time.Now())
+		if err := plan.Save(planOut, p); err != nil {
+			return fmt.Errorf("plan failed: %w", err)
+		}
+		logger.Success("Saved plan with %d change(s) to %s", len(p.Actions), planOut)
+	}
+	return migrationExitError(result)
+}
+
+// runHook runs a --pre-hook or --post-hook command with the GVM_* variables
+// in env. Nothing is run without a command, or in dry-run mode unless
+// --hooks-in-dry-run is set.
+func runHook(flag, command string, env []string) error {
+	if command == "" {
+		return nil
+	}
+	if dryRun && !hooksInDryRun {
+		logger.Info("Skipping --%s in dry-run mode (--hooks-in-dry-run to run it)", flag)
+		return nil
+	}
+	logger.Info("Running --%s: %s", flag, command)
+	if err := hooks.Run(command, env); err != nil {
+		return fmt.Errorf("--%s failed: %w", flag, err)
+	}
+	return nil
+}
+
+// migrationExitError maps the result of a finished run to the command's exit
+// behavior: exitCodeAborted when it was stopped at a prompt or interrupted,
+// exitCodeErrorLimit when it was stopped by --max-errors, exitCodeBudget
+// when it was stopped by --max-api-calls, exitCodePartial
+// when repositories or variables failed, exitCodeEmpty when --fail-if-empty
+// found nothing to migrate, and with --exit-code-on-diff, exitCodeDiff for a
+// dry run with pending changes.
+func migrationExitError(result *types.MigrationResult) error {
+	if result.Interrupted {
+		return &exitError{code: exitCodeAborted, err: fmt.Errorf("migration interrupted; %d variable(s) written before stopping", result.Created+result.Updated)}
+	}
+	if result.Aborted {
+		return &exitError{code: exitCodeAborted, err: fmt.Errorf("migration aborted at user request")}
+	}
+	if result.ErrorLimitReached {
+		return &exitError{code: exitCodeErrorLimit, err: fmt.Errorf("migration stopped after reaching --max-errors with %d error(s)", len(result.Errors))}
+	}
+	if result.BudgetExhausted {
+		return &exitError{code: exitCodeBudget, err: fmt.Errorf("API call budget exhausted (--max-api-calls %d); %d variable(s) written before stopping", maxAPICalls, result.Created+result.Updated)}
+	}
+
+	failed := 0
+	for _, r := range result.Repos {
+		if r.Failed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return &exitError{code: exitCodePartial, err: fmt.Errorf("migration failed for %d of %d repositories", failed, len(result.Repos))}
+	}
 
 	if result.HasErrors() {
-		return fmt.Errorf("migration completed with %d error(s)", len(result.Errors))
+		return &exitError{code: exitCodePartial, err: fmt.Errorf("migration completed with %d error(s)", len(result.Errors))}
+	}
+
+	if result.Empty {
+		return &exitError{code: exitCodeEmpty, err: fmt.Errorf("no source variables to migrate (--fail-if-empty)")}
+	}
+
+	if dryRun && exitCodeOnDiff {
+		if pending := result.Created + result.Updated + result.Deleted; pending > 0 {
+			logger.Warning("Dry run found %d pending change(s)", pending)
+			return &exitError{code: exitCodeDiff}
+		}
 	}
 
 	logger.Success("Migration completed successfully!")
 	return nil
 }
 
+// reportWriter writes the --report-file report of a run once: when the run
+// finishes, or from the interrupt handler when it does not stop in time,
+// whichever comes first. A nil report writes nothing.
+type reportWriter struct {
+	rep  *report.Report
+	once sync.Once
+	err  error
+}
+
+// save completes the report with the outcome of the run and writes it,
+// unless it was already written; it returns the error of the write
+func (w *reportWriter) save(result *types.MigrationResult, runErr error) error {
+	if w.rep == nil {
+		return nil
+	}
+	w.once.Do(func() { w.err = saveReport(w.rep, result, runErr) })
+	return w.err
+}
+
+// saveReport completes the --report-file report with the outcome of the run
+// and writes it
+func saveReport(rep *report.Report, result *types.MigrationResult, runErr error) error {
+	rep.Finish(result, runErr, time.Now())
+	if err := report.Save(reportFile, rep); err != nil {
+		logger.Error("Failed to save report: %v", err)
+		return fmt.Errorf("report failed: %w", err)
+	}
+	logger.Success("Saved migration report to %s", reportFile)
+	return nil
+}
+
+// lastReportPath returns the file the report of the last run is kept in:
+// --last-report-file, or the default under the user cache directory
+func lastReportPath() (string, error) {
+	if lastReportFile != "" {
+		return lastReportFile, nil
+	}
+	return report.DefaultLastPath()
+}
+
+// saveLastReport keeps the report of a finished run for the status command.
+// A failure only warns, since the run itself is over.
+func saveLastReport(rep *report.Report) {
+	path, err := lastReportPath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o700)
+	}
+	if err == nil {
+		err = report.Save(path, rep)
+	}
+	if err != nil {
+		logger.Warning("Could not keep the report of this run for status: %v", err)
+		return
+	}
+	logger.Debug("Kept the report of this run in %s", path)
+}
+
+// interruptGrace is how long an interrupted run may take to finish the
+// variable in progress before the process exits without it.
+// notifyInterrupt and exitProcess are replaced by tests.
+var (
+	interruptGrace  = 10 * time.Second
+	notifyInterrupt = func(c chan<- os.Signal) { signal.Notify(c, os.Interrupt, syscall.SIGTERM) }
+	exitProcess     = os.Exit
+)
+
+// stopOnInterrupt stops the migration before its next variable when the
+// process is interrupted or terminated during the run, so that the partial
+// summary is printed and the report written as usual. If the run does not
+// stop within interruptGrace, or a second signal arrives, the report is
+// written with what the run has recorded so far and the process exits
+// right away. The returned function stops watching for signals; it waits
+// for a report being written on the way out, so the run's own report is
+// never written at the same time.
+func stopOnInterrupt(m *migrator.Migrator, reports *reportWriter) func() {
+	signals := make(chan os.Signal, 2)
+	notifyInterrupt(signals)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		var sig os.Signal
+		select {
+		case sig = <-signals:
+		case <-done:
+			return
+		}
+		logger.Warning("Received %v; stopping after the variable in progress (press Ctrl+C again to exit now)", sig)
+		m.Interrupt()
+
+		select {
+		case sig = <-signals:
+		case <-time.After(interruptGrace):
+		case <-done:
+			return
+		}
+		partial := m.Partial()
+		partial.Interrupted = true
+		_ = reports.save(partial, fmt.Errorf("interrupted by signal: %v", sig))
+		exitProcess(exitCodeInterrupted)
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		<-exited
+	}
+}
+
+// runDiff reports the differences between source and target without
+// migrating. It returns an exitError with exitCodeDiff when they differ.
+func runDiff(m *migrator.Migrator) error {
+	diff, err := m.Diff()
+	if err != nil {
+		return fmt.Errorf("diff failed: %w", err)
+	}
+	return diffExitError(diff)
+}
+
+// diffExitError maps a diff result to the command's exit behavior: nil when
+// source and target match, otherwise a silent exit with exitCodeDiff.
+func diffExitError(diff *types.DiffResult) error {
+	if !diff.HasDifferences() {
+		logger.Success("No differences found")
+		return nil
+	}
+	return &exitError{code: exitCodeDiff}
+}
+
+// runRollback restores the target recorded in the --rollback snapshot file.
+// Only the target credentials and hostname are used; the target location
+// comes from the snapshot.
+func runRollback() error {
+	snap, err := snapshot.Load(rollbackFile)
+	if err != nil {
+		return fmt.Errorf("--rollback: %w", err)
+	}
+	if err := checkRollbackTarget(snap); err != nil {
+		return err
+	}
+
+	targetClient, err := targetOnlyClient("rollback")
+	if err != nil {
+		return authError(err)
+	}
+
+	if snap.Mode.TargetsOrg() {
+		err = client.ValidateOrgScopes(targetClient, "target")
+	} else {
+		err = client.ValidateRepoScopes(targetClient, "target")
+	}
+	if err != nil {
+		return authError(err)
+	}
+
+	result, err := migrator.Rollback(targetClient, snap, dryRun)
+	if err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+
+	if result.HasErrors() {
+		return &exitError{code: exitCodePartial, err: fmt.Errorf("rollback completed with %d error(s)", len(result.Errors))}
+	}
+
+	logger.Success("Rollback completed successfully!")
+	return nil
+}
+
+// targetOnlyClient creates and authenticates the target client of a run
+// that has no source, such as a rollback or a manifest apply. purpose names
+// the run in the credential log line.
+func targetOnlyClient(purpose string) (*client.Client, error) {
+	return sideClient("target", "Target", targetPAT, targetPATName, targetHostname, purpose)
+}
+
+// sourceOnlyClient creates and authenticates the source client of a run
+// that reads a single source scope, such as cp
+func sourceOnlyClient(purpose string) (*client.Client, error) {
+	return sideClient("source", "Source", sourcePAT, sourcePATName, sourceHostname, purpose)
+}
+
+// sideClient creates and authenticates the source or target client from its
+// PAT, then GITHUB_TOKEN, then the token in the OS keyring, then the GitHub
+// CLI authentication
+func sideClient(side, title, pat, patName, hostname, purpose string) (*client.Client, error) {
+	githubToken := os.Getenv("GITHUB_TOKEN")
+	token := githubToken
+	if pat != "" {
+		token = pat
+	}
+	label := credentialLabel(pat, githubToken, patName, hostname)
+	if token == "" && !noKeyring {
+		if token = newKeyringLookup().token(side, hostname); token != "" {
+			label = keyringLabel
+		}
+	}
+	logger.Info("%s used for %s %s", label, purpose, side)
+
+	c, err := createClientWithToken(token, hostname, side)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := c.GetUser()
+	if err != nil {
+		return nil, fmt.Errorf("%s authentication failed: %w", side, err)
+	}
+	logger.Success("%s authenticated as: %s", title, user)
+	return c, nil
+}
+
+// checkRollbackTarget guards against rolling back the wrong target: when
+// --target-org or --target-repo is given it must match the snapshot.
+func checkRollbackTarget(snap *snapshot.Snapshot) error {
+	owner := snap.TargetOrg
+	if !snap.Mode.TargetsOrg() {
+		owner = snap.TargetOwner
+	}
+	if targetOrg != "" && !strings.EqualFold(targetOrg, owner) {
+		return fmt.Errorf("--target-org %s does not match the snapshot target %s", targetOrg, owner)
+	}
+	if targetRepo != "" && !snap.Mode.TargetsOrg() && !strings.EqualFold(targetRepo, snap.TargetRepo) {
+		return fmt.Errorf("--target-repo %s does not match the snapshot target %s", targetRepo, snap.TargetRepo)
+	}
+	return nil
+}
+
 // resolveTokens determines which tokens to use for source and target.
 //
 // Priority per side (source / target):
-//  1. --source-pat / --target-pat flag  (highest)
-//  2. SOURCE_PAT / TARGET_PAT env var   (loaded as flag default)
-//  3. GITHUB_TOKEN env var              (primary shared token)
-//  4. GitHub CLI authentication         (lowest – empty string returned)
+//  1. --source-pat / --target-pat flag            (highest)
+//  2. --source-pat-file / --target-pat-file file  (read by applyPATFiles)
+//  3. SOURCE_PAT / TARGET_PAT env var             (loaded as flag default)
+//  4. GITHUB_TOKEN env var                        (primary shared token)
+//  5. token stored with auth store                (unless --no-keyring)
+//  6. GitHub CLI authentication                   (lowest – empty string returned)
 func resolveTokens() (sourceToken, targetToken string, err error) {
 	githubToken := os.Getenv("GITHUB_TOKEN")
 
@@ -398,12 +2189,50 @@ func resolveTokens() (sourceToken, targetToken string, err error) {
 	}
 
 	// Determine the label for each side's credential.
-	sourceLabel := credentialLabel(sourcePAT, githubToken, "SOURCE_PAT", "GITHUB_TOKEN", "GitHub CLI")
-	targetLabel := credentialLabel(targetPAT, githubToken, "TARGET_PAT", "GITHUB_TOKEN", "GitHub CLI")
+	sourceLabel := credentialLabel(sourcePAT, githubToken, sourcePATName, sourceHostname)
+	targetLabel := credentialLabel(targetPAT, githubToken, targetPATName, targetHostname)
+
+	// Then the tokens kept in the OS keyring by auth store.
+	stored := false
+	if !noKeyring && (sourceToken == "" || targetToken == "") {
+		k := newKeyringLookup()
+		if sourceToken == "" {
+			if sourceToken = k.token("source", sourceHostname); sourceToken != "" {
+				sourceLabel, stored = keyringLabel, true
+			}
+		}
+		if targetToken == "" {
+			if targetToken = k.token("target", targetHostname); targetToken != "" {
+				targetLabel, stored = keyringLabel, true
+			}
+		}
+	}
+
+	// On a terminal, ask for the tokens of the sides left without one.
+	prompted := false
+	if sourceToken == "" || targetToken == "" {
+		if p := newTokenPrompt(); p != nil {
+			askSource := p.needs(sourceToken, targetToken, sourceHostname)
+			askTarget := p.needs(targetToken, sourceToken, targetHostname)
+			if askSource {
+				if sourceToken, err = p.ask("source", sourceOrg, sourceHostname); err != nil {
+					return "", "", err
+				}
+				sourceLabel = enteredTokenLabel
+			}
+			if askTarget {
+				if targetToken, err = p.ask("target", targetOrg, targetHostname); err != nil {
+					return "", "", err
+				}
+				targetLabel = enteredTokenLabel
+			}
+			prompted = askSource || askTarget
+		}
+	}
 
 	// Log which credential is used for each side.
-	logger.Info("%s used for Source Org %s", sourceLabel, sourceOrg)
-	logger.Info("%s used for Target Org %s", targetLabel, targetOrg)
+	logger.Info("%s used for %s", sourceLabel, sideLabel("Source", sourceOrg))
+	logger.Info("%s used for %s", targetLabel, sideLabel("Target", targetOrg))
 
 	// Both resolved → done.
 	if sourceToken != "" && targetToken != "" {
@@ -415,21 +2244,36 @@ func resolveTokens() (sourceToken, targetToken string, err error) {
 		return "", "", nil
 	}
 
+	// A token was entered or stored for one side only → the other side is
+	// logged in with the GitHub CLI.
+	if prompted || stored {
+		return sourceToken, targetToken, nil
+	}
+
 	// One side resolved, the other did not → cannot proceed.
 	return "", "", fmt.Errorf("authentication required: please provide --source-pat and --target-pat flags, or set GITHUB_TOKEN environment variable")
 }
 
+// sideLabel names one side of the run in credential messages: its
+// organization when one is given, otherwise just the side
+func sideLabel(title, org string) string {
+	if org == "" {
+		return "the " + strings.ToLower(title)
+	}
+	return title + " Org " + org
+}
+
 // credentialLabel returns a human-readable label describing which credential
 // was selected for one side of the migration (e.g. "SOURCE_PAT", "GITHUB_TOKEN",
-// or "GitHub CLI").
-func credentialLabel(pat, githubToken, patName, ghTokenName, cliFallback string) string {
+// or "GitHub CLI (github.com)" for the GitHub CLI login to hostname).
+func credentialLabel(pat, githubToken, patName, hostname string) string {
 	if pat != "" {
 		return patName
 	}
 	if githubToken != "" {
-		return ghTokenName
+		return "GITHUB_TOKEN"
 	}
-	return cliFallback
+	return "GitHub CLI (" + hostLabel(hostname) + ")"
 }
 
 // createClients creates source and target API clients
@@ -452,8 +2296,9 @@ func createClients(sourceToken, targetToken string) (*client.Client, *client.Cli
 	return sourceClient, targetClient, nil
 }
 
-// createClientWithToken creates a client with an explicit token or default auth,
-// optionally scoped to a custom GitHub hostname for data residency compliance.
+// createClientWithToken creates a client with an explicit token or the GitHub
+// CLI token of the host, optionally scoped to a custom GitHub hostname for data
+// residency compliance.
 func createClientWithToken(token string, hostname string, clientType string) (*client.Client, error) {
 	if token != "" {
 		if hostname != "" {
@@ -470,20 +2315,22 @@ func createClientWithToken(token string, hostname string, clientType string) (*c
 		return c, nil
 	}
 
-	// Fallback to GitHub CLI authentication, with optional custom hostname
-	if hostname != "" {
-		c, err := client.NewWithHost(hostname)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create %s client for host %s: %w", clientType, hostname, err)
-		}
-		return c, nil
+	// Fall back to the token the GitHub CLI keeps for the host: github.com
+	// unless hostname is set, and never the CLI's own default host
+	host := hostLabel(hostname)
+	cliToken := cliTokenForHost(host)
+	if cliToken == "" {
+		return nil, fmt.Errorf("no token for the %s client: the GitHub CLI is not logged in to %s; run gh auth login --hostname %s", clientType, host, host)
 	}
+	return createClientWithToken(cliToken, hostname, clientType)
+}
 
-	c, err := client.New()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create %s client: %w", clientType, err)
-	}
-	return c, nil
+// cliTokenForHost returns the token the GitHub CLI keeps for host, as gh
+// auth token --hostname prints it, or "" when it is not logged in there;
+// tests replace it
+var cliTokenForHost = func(host string) string {
+	token, _ := auth.TokenForHost(host)
+	return token
 }
 
 // validatePermissions validates that source and target tokens have the required
@@ -492,102 +2339,163 @@ func createClientWithToken(token string, hostname string, clientType string) (*c
 func validatePermissions(sourceClient, targetClient *client.Client, mode types.MigrationMode) error {
 	logger.Info("Validating token permissions...")
 
+	if err := validateSidePermissions("source", sourceClient, mode); err != nil {
+		return err
+	}
+	if err := validateSidePermissions("target", targetClient, mode); err != nil {
+		return err
+	}
+
+	logger.Success("Token permissions validated")
+	return nil
+}
+
+// validateSidePermissions validates the scopes the token of side needs for
+// mode: admin:org for an organization side and repo for a repository side,
+// and both for the two sides of --deep
+func validateSidePermissions(side string, c *client.Client, mode types.MigrationMode) error {
+	var orgSide bool
 	switch mode {
 	case types.ModeOrgToOrg:
-		if err := client.ValidateOrgScopes(sourceClient, "source"); err != nil {
+		orgSide = true
+	case types.ModeOrgToRepo, types.ModeFanOut:
+		orgSide = side == "source"
+	case types.ModeRepoToOrg:
+		orgSide = side == "target"
+	case types.ModeRepoToRepo:
+	default:
+		return nil
+	}
+	if orgSide {
+		if err := client.ValidateOrgScopes(c, side); err != nil {
 			return err
 		}
-		if err := client.ValidateOrgScopes(targetClient, "target"); err != nil {
-			return err
+		if mode != types.ModeOrgToOrg || !deep {
+			return nil
+		}
+	}
+	return client.ValidateRepoScopes(c, side)
+}
+
+// checkEndpoints verifies that the source and target organizations or
+// repositories of the migration cfg exist and are visible to their tokens, so
+// that a typo or a missing grant is reported up front and names the side it
+// concerns. A target repository must also be writable unless nothing is
+// written. Targets given with --targets are checked one by one during the
+// migration instead.
+func checkEndpoints(sourceClient, targetClient *client.Client, cfg *types.MigrationConfig) error {
+	source := endpoint{side: "source", client: sourceClient, host: hostLabel(sourceHostname)}
+	target := endpoint{side: "target", client: targetClient, host: hostLabel(targetHostname)}
+	write := !cfg.DryRun && !diffMode
+
+	var err error
+	switch cfg.Mode {
+	case types.ModeOrgToOrg, types.ModeFanOut:
+		if err = source.checkOrg(cfg.SourceOrg); err == nil {
+			err = target.checkOrg(cfg.TargetOrg)
 		}
 	case types.ModeRepoToRepo:
-		if err := client.ValidateRepoScopes(sourceClient, "source"); err != nil {
-			return err
+		if err = source.checkRepo(cfg.SourceOrg, cfg.SourceRepo, false); err == nil && len(cfg.Targets) == 0 {
+			err = target.checkRepo(cfg.TargetOrg, cfg.TargetRepo, write)
 		}
-		if err := client.ValidateRepoScopes(targetClient, "target"); err != nil {
-			return err
+	case types.ModeOrgToRepo:
+		if err = source.checkOrg(cfg.SourceOrg); err == nil {
+			err = target.checkRepo(cfg.TargetOrg, cfg.TargetRepo, write)
+		}
+	case types.ModeRepoToOrg:
+		if err = source.checkRepo(cfg.SourceOrg, cfg.SourceRepo, false); err == nil {
+			err = target.checkOrg(cfg.TargetOrg)
 		}
 	}
+	return err
+}
 
-	logger.Success("Token permissions validated")
-	return nil
+// endpoint is one side of the migration as seen by checkEndpoints
+type endpoint struct {
+	side   string
+	client *client.Client
+	host   string
 }
 
-// validateAuth validates that both source and target clients are authenticated
-func validateAuth(sourceClient, targetClient *client.Client) error {
-	sourceHost := sourceHostname
-	if sourceHost == "" {
-		sourceHost = "github.com"
-	}
-	targetHost := targetHostname
-	if targetHost == "" {
-		targetHost = "github.com"
+// checkOrg verifies that org exists and is visible to the endpoint's token
+func (e endpoint) checkOrg(org string) error {
+	if _, err := e.client.GetOrg(org); err != nil {
+		return e.lookupError("organization "+org, err)
 	}
+	return nil
+}
 
-	sourceLabel := credentialLabel(sourcePAT, os.Getenv("GITHUB_TOKEN"), "SOURCE_PAT", "GITHUB_TOKEN", "GitHub CLI")
-	targetLabel := credentialLabel(targetPAT, os.Getenv("GITHUB_TOKEN"), "TARGET_PAT", "GITHUB_TOKEN", "GitHub CLI")
-
-	// Validate source authentication
-	sourceUser, err := sourceClient.GetUser()
+// checkRepo verifies that owner/repo exists and is visible to the
+// endpoint's token and, with write, that the token can push to it
+func (e endpoint) checkRepo(owner, repo string, write bool) error {
+	r, err := e.client.GetRepo(owner, repo)
 	if err != nil {
-		return fmt.Errorf("source authentication failed against %s using %s: %w\n\n"+
-			"Hints:\n"+
-			"  • Verify that %s holds a valid, non-expired token\n"+
-			"  • Make sure the token has access to %s\n"+
-			"  • If targeting a custom host, set --source-hostname (env: SOURCE_HOSTNAME)",
-			sourceHost, sourceLabel, err, sourceLabel, sourceHost)
+		return e.lookupError(fmt.Sprintf("repository %s/%s", owner, repo), err)
 	}
-
-	// Validate target authentication
-	targetUser, err := targetClient.GetUser()
-	if err != nil {
-		return fmt.Errorf("target authentication failed against %s using %s: %w\n\n"+
-			"Hints:\n"+
-			"  • Verify that %s holds a valid, non-expired token\n"+
-			"  • Make sure the token has access to %s\n"+
-			"  • If targeting a custom host, set --target-hostname (env: TARGET_HOSTNAME)",
-			targetHost, targetLabel, err, targetLabel, targetHost)
+	// Permissions are missing for some tokens, such as GITHUB_TOKEN; the
+	// writes then fail on their own if they are not allowed
+	if write && r.Permissions != nil && !r.Permissions.Push && !r.Permissions.Admin {
+		return authError(fmt.Errorf("%s token can read repository %s/%s on %s but cannot write to it; it needs write access to migrate variables",
+			e.side, owner, repo, e.host))
 	}
-
-	logger.Success("Source authenticated as: %s", sourceUser)
-	logger.Success("Target authenticated as: %s", targetUser)
 	return nil
 }
 
-// checkAuth verifies that the user is authenticated with GitHub CLI (used by subcommands)
-func checkAuth() error {
-	restClient, err := api.DefaultRESTClient()
-	if err != nil {
-		return fmt.Errorf("failed to create GitHub API client: %w\n\nPlease authenticate using: gh auth login", err)
-	}
-
-	var user struct {
-		Login string `json:"login"`
+// lookupError turns the error of looking up what on the endpoint into a
+// message that names the side and says what to check
+func (e endpoint) lookupError(what string, err error) error {
+	switch {
+	case client.IsNotFound(err):
+		return fmt.Errorf("%s %s not found on %s, or the %s token cannot see it; check the name, --%s-hostname, and the token's access",
+			e.side, what, e.host, e.side, e.side)
+	case client.IsAuthError(err):
+		return authError(fmt.Errorf("%s token is not allowed to access %s on %s: %w", e.side, what, e.host, err))
 	}
+	return fmt.Errorf("failed to look up %s %s on %s: %w", e.side, what, e.host, err)
+}
 
-	if err := restClient.Get("user", &user); err != nil {
-		return fmt.Errorf("authentication failed: %w\n\nPlease authenticate using: gh auth login", err)
+// hostLabel names a GitHub host in messages, defaulting to github.com
+func hostLabel(hostname string) string {
+	if hostname == "" {
+		return "github.com"
 	}
-
-	logger.Success("Authenticated as: %s", user.Login)
-	return nil
+	return hostname
 }
 
-// CheckOrgAccess verifies the user has access to the specified organization
-func CheckOrgAccess(orgName string) error {
-	client, err := api.DefaultRESTClient()
+// validateAuth validates that both source and target clients are authenticated
+func validateAuth(sourceClient, targetClient *client.Client) error {
+	sourceUser, err := validateSideAuth("source", sourceClient)
 	if err != nil {
 		return err
 	}
-
-	var org struct {
-		Login string `json:"login"`
+	targetUser, err := validateSideAuth("target", targetClient)
+	if err != nil {
+		return err
 	}
 
-	path := fmt.Sprintf("orgs/%s", orgName)
-	if err := client.Get(path, &org); err != nil {
-		return fmt.Errorf("cannot access organization '%s': %w", orgName, err)
+	logger.Success("Source authenticated as: %s", sourceUser)
+	logger.Success("Target authenticated as: %s", targetUser)
+	return nil
+}
+
+// validateSideAuth validates that the client of side authenticates and
+// returns the user it authenticates as
+func validateSideAuth(side string, c *client.Client) (string, error) {
+	pat, patName, hostname := sourcePAT, sourcePATName, sourceHostname
+	if side == "target" {
+		pat, patName, hostname = targetPAT, targetPATName, targetHostname
 	}
+	host := hostLabel(hostname)
+	label := credentialLabel(pat, os.Getenv("GITHUB_TOKEN"), patName, hostname)
 
-	return nil
+	user, err := c.GetUser()
+	if err != nil {
+		return "", fmt.Errorf("%s authentication failed against %s using %s: %w\n\n"+
+			"Hints:\n"+
+			"  • Verify that %s holds a valid, non-expired token\n"+
+			"  • Make sure the token has access to %s\n"+
+			"  • If targeting a custom host, set --%s-hostname (env: %s_HOSTNAME)",
+			side, host, label, err, label, host, side, strings.ToUpper(side))
+	}
+	return user, nil
 }
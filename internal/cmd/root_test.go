@@ -1,10 +1,39 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/envfile"
+	"github.com/renan-alm/gh-vars-migrator/internal/keyring"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/report"
+	"github.com/renan-alm/gh-vars-migrator/internal/snapshot"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
 )
 
+// TestMain keeps the tests away from the tokens stored in the OS keyring and
+// the GitHub CLI logins of the machine running them
+func TestMain(m *testing.M) {
+	openKeyring = func() keyring.Keyring { return keyring.NewMemory() }
+	cliTokenForHost = func(host string) string { return "" }
+	os.Exit(m.Run())
+}
+
 // TestResolveTokens_BothPATsProvided tests that explicit PATs override GITHUB_TOKEN
 func TestResolveTokens_BothPATsProvided(t *testing.T) {
 	// Save original values
@@ -196,12 +225,88 @@ func TestResolveTokens_OnlySourcePATNoFallback(t *testing.T) {
 	targetPAT = ""
 	_ = os.Unsetenv("GITHUB_TOKEN")
 
+	// Standard input is not a terminal to ask for the target token on
+	origPrompt := newTokenPrompt
+	defer func() { newTokenPrompt = origPrompt }()
+	newTokenPrompt = func() *tokenPrompt { return nil }
+
 	_, _, err := resolveTokens()
 	if err == nil {
 		t.Fatal("Expected error when only source PAT provided without GITHUB_TOKEN, got nil")
 	}
 }
 
+// TestCreateClientWithToken_CLIFallback tests that a side without a token
+// uses the GitHub CLI token of its own host
+func TestCreateClientWithToken_CLIFallback(t *testing.T) {
+	origCLIToken := cliTokenForHost
+	defer func() { cliTokenForHost = origCLIToken }()
+	logins := map[string]string{"github.com": "gho_default", "ghes.corp.example": "gho_ghes"}
+
+	tests := []struct {
+		name     string
+		hostname string
+		wantHost string
+		wantErr  string
+	}{
+		{name: "default host", hostname: "", wantHost: "github.com"},
+		{name: "custom host", hostname: "ghes.corp.example", wantHost: "ghes.corp.example"},
+		{
+			name: "no login to the host", hostname: "other.example", wantHost: "other.example",
+			wantErr: "no token for the target client: the GitHub CLI is not logged in to other.example; run gh auth login --hostname other.example",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var asked []string
+			cliTokenForHost = func(host string) string {
+				asked = append(asked, host)
+				return logins[host]
+			}
+			c, err := createClientWithToken("", tt.hostname, "target")
+			if !slices.Equal(asked, []string{tt.wantHost}) {
+				t.Errorf("GitHub CLI tokens looked up for %v, want %s", asked, tt.wantHost)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("createClientWithToken() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || c == nil {
+				t.Fatalf("createClientWithToken() = %v, %v", c, err)
+			}
+		})
+	}
+
+	// An explicit token never consults the GitHub CLI
+	cliTokenForHost = func(host string) string {
+		t.Errorf("GitHub CLI token looked up for %s with an explicit token", host)
+		return ""
+	}
+	if _, err := createClientWithToken("ghp_explicit", "ghes.corp.example", "source"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCredentialLabel tests the credential names in the logs
+func TestCredentialLabel(t *testing.T) {
+	tests := []struct {
+		pat, githubToken, hostname string
+		want                       string
+	}{
+		{"ghp_pat", "ghp_env", "", "SOURCE_PAT"},
+		{"", "ghp_env", "", "GITHUB_TOKEN"},
+		{"", "", "", "GitHub CLI (github.com)"},
+		{"", "", "ghes.corp.example", "GitHub CLI (ghes.corp.example)"},
+	}
+	for _, tt := range tests {
+		if got := credentialLabel(tt.pat, tt.githubToken, "SOURCE_PAT", tt.hostname); got != tt.want {
+			t.Errorf("credentialLabel(%q, %q, SOURCE_PAT, %q) = %q, want %q", tt.pat, tt.githubToken, tt.hostname, got, tt.want)
+		}
+	}
+}
+
 // TestEnvBool tests that envBool correctly parses boolean environment variables
 func TestEnvBool(t *testing.T) {
 	const key = "TEST_ENV_BOOL_VAR"
@@ -241,3 +346,2216 @@ func TestEnvBool(t *testing.T) {
 		})
 	}
 }
+
+// TestValidateFlags_MappingFiles tests that --name-map and --value-overrides
+// files are loaded and rejected up front when missing or malformed
+func TestValidateFlags_MappingFiles(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg := orgToOrg
+	origNameMapFile, origValueOverridesFile := nameMapFile, valueOverridesFile
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg = origOrgToOrg
+		nameMapFile, valueOverridesFile = origNameMapFile, origValueOverridesFile
+		nameMap, valueOverrides = nil, nil
+	}()
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name           string
+		nameMapFile    string
+		overridesFile  string
+		wantErr        bool
+		wantOverridden string
+	}{
+		{name: "no files", wantErr: false},
+		{name: "missing overrides file", overridesFile: filepath.Join(dir, "missing.env"), wantErr: true},
+		{name: "malformed overrides entry", overridesFile: writeFile("bad.env", "REGISTRY_URL\n"), wantErr: true},
+		{name: "malformed overrides JSON", overridesFile: writeFile("bad.json", `{"A": 1}`), wantErr: true},
+		{name: "valid overrides", overridesFile: writeFile("good.env", "REGISTRY_URL=ghcr.io/target\n"), wantErr: false, wantOverridden: "ghcr.io/target"},
+		{name: "missing name map", nameMapFile: filepath.Join(dir, "missing.map"), wantErr: true},
+		{name: "colliding name map", nameMapFile: writeFile("collide.map", "A=SAME\nB=SAME\n"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "source-org", "target-org"
+			orgToOrg = true
+			nameMapFile, valueOverridesFile = tt.nameMapFile, tt.overridesFile
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantOverridden != "" && valueOverrides["REGISTRY_URL"] != tt.wantOverridden {
+				t.Errorf("Expected override %q, got %v", tt.wantOverridden, valueOverrides)
+			}
+		})
+	}
+}
+
+// TestDiffExitError tests the --diff exit code behavior
+func TestDiffExitError(t *testing.T) {
+	noDiff := &types.DiffResult{Entries: []types.DiffEntry{{Status: types.DiffUnchanged}}}
+	if err := diffExitError(noDiff); err != nil {
+		t.Errorf("Expected nil error when there are no differences, got: %v", err)
+	}
+
+	withDiff := &types.DiffResult{Entries: []types.DiffEntry{{Status: types.DiffAdd}}}
+	err := diffExitError(withDiff)
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Expected exitError, got: %v", err)
+	}
+	if exitErr.code != exitCodeDiff {
+		t.Errorf("Expected exit code %d, got %d", exitCodeDiff, exitErr.code)
+	}
+	if exitErr.err != nil {
+		t.Errorf("Expected silent exit, got error: %v", exitErr.err)
+	}
+}
+
+func TestValidateFlags_Rollback(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origOrgToOrg, origDiffMode := orgToOrg, diffMode
+	origSnapshotFile, origRollbackFile := snapshotFile, rollbackFile
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		orgToOrg, diffMode = origOrgToOrg, origDiffMode
+		snapshotFile, rollbackFile = origSnapshotFile, origRollbackFile
+	}()
+
+	tests := []struct {
+		name         string
+		sourceOrg    string
+		rollbackFile string
+		snapshotFile string
+		diffMode     bool
+		wantErr      bool
+	}{
+		{name: "rollback without source or target", rollbackFile: "snap.json", wantErr: false},
+		{name: "rollback with diff", rollbackFile: "snap.json", diffMode: true, wantErr: true},
+		{name: "rollback with snapshot file", rollbackFile: "snap.json", snapshotFile: "new.json", wantErr: true},
+		{name: "snapshot with diff", sourceOrg: "source-org", snapshotFile: "new.json", diffMode: true, wantErr: true},
+		{name: "snapshot with migration", sourceOrg: "source-org", snapshotFile: "new.json", wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = tt.sourceOrg, ""
+			if tt.sourceOrg != "" {
+				targetOrg = "target-org"
+			}
+			orgToOrg = true
+			diffMode = tt.diffMode
+			snapshotFile, rollbackFile = tt.snapshotFile, tt.rollbackFile
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckRollbackTarget(t *testing.T) {
+	origTargetOrg, origTargetRepo := targetOrg, targetRepo
+	defer func() {
+		targetOrg, targetRepo = origTargetOrg, origTargetRepo
+	}()
+
+	repoSnap := &snapshot.Snapshot{Mode: types.ModeRepoToRepo, TargetOwner: "acme", TargetRepo: "app"}
+	orgSnap := &snapshot.Snapshot{Mode: types.ModeOrgToOrg, TargetOrg: "acme"}
+
+	tests := []struct {
+		name       string
+		snap       *snapshot.Snapshot
+		targetOrg  string
+		targetRepo string
+		wantErr    bool
+	}{
+		{name: "no target flags", snap: repoSnap},
+		{name: "matching repo target", snap: repoSnap, targetOrg: "ACME", targetRepo: "app"},
+		{name: "wrong owner", snap: repoSnap, targetOrg: "other", wantErr: true},
+		{name: "wrong repo", snap: repoSnap, targetOrg: "acme", targetRepo: "other", wantErr: true},
+		{name: "matching org", snap: orgSnap, targetOrg: "acme"},
+		{name: "wrong org", snap: orgSnap, targetOrg: "other", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetOrg, targetRepo = tt.targetOrg, tt.targetRepo
+			err := checkRollbackTarget(tt.snap)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkRollbackTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_Prompts(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origOrgToOrg, origSkipOverwrite, origOnConflict := orgToOrg, skipOverwrite, onConflict
+	origInteractive := interactive
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		orgToOrg, skipOverwrite, onConflict = origOrgToOrg, origSkipOverwrite, origOnConflict
+		interactive = origInteractive
+	}()
+
+	tests := []struct {
+		name          string
+		onConflict    string
+		skipOverwrite bool
+		interactive   bool
+		wantErr       bool
+	}{
+		{name: "default", wantErr: false},
+		{name: "fail", onConflict: "fail", wantErr: false},
+		{name: "unknown strategy", onConflict: "merge", wantErr: true},
+		{name: "skip-overwrite with overwrite", onConflict: "overwrite", skipOverwrite: true, wantErr: true},
+		// Test stdin is not a terminal.
+		{name: "prompt without terminal", onConflict: "prompt", wantErr: true},
+		{name: "interactive without terminal", interactive: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "source-org", "target-org"
+			orgToOrg = true
+			onConflict, skipOverwrite, interactive = tt.onConflict, tt.skipOverwrite, tt.interactive
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_OrgToRepo(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origOrgToRepo := orgToOrg, orgToRepo
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, orgToRepo = origOrgToOrg, origOrgToRepo
+	}()
+
+	tests := []struct {
+		name       string
+		sourceRepo string
+		targetRepo string
+		orgToOrg   bool
+		wantErr    bool
+	}{
+		{name: "valid", targetRepo: "service", wantErr: false},
+		{name: "missing target repo", wantErr: true},
+		{name: "source repo given", sourceRepo: "mono", targetRepo: "service", wantErr: true},
+		{name: "combined with org-to-org", targetRepo: "service", orgToOrg: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "source-org", "target-org"
+			sourceRepo, targetRepo = tt.sourceRepo, tt.targetRepo
+			orgToOrg, orgToRepo = tt.orgToOrg, true
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && detectMigrationMode() != types.ModeOrgToRepo {
+				t.Errorf("detectMigrationMode() = %s, want %s", detectMigrationMode(), types.ModeOrgToRepo)
+			}
+		})
+	}
+}
+
+// TestValidateFlags_Refs tests the migration mode --source and --target
+// select without a mode flag
+func TestValidateFlags_Refs(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origSourceRef, origTargetRef, origRefFlags := sourceRef, targetRef, refFlags
+	origOrgToOrg, origRepoToOrg := orgToOrg, repoToOrg
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		sourceRef, targetRef, refFlags = origSourceRef, origTargetRef, origRefFlags
+		orgToOrg, repoToOrg = origOrgToOrg, origRepoToOrg
+	}()
+
+	tests := []struct {
+		name      string
+		source    string
+		target    string
+		repoToOrg bool
+		wantMode  types.MigrationMode
+		wantErr   string
+	}{
+		{name: "repository to repository", source: "acme/app", target: "corp/app", wantMode: types.ModeRepoToRepo},
+		{name: "organization to organization", source: "acme", target: "corp", wantMode: types.ModeOrgToOrg},
+		{name: "organization to repository", source: "acme", target: "corp/app", wantMode: types.ModeOrgToRepo},
+		{name: "repository to organization", source: "acme/app", target: "corp", repoToOrg: true, wantMode: types.ModeRepoToOrg},
+		{name: "repository without target repository", source: "acme/app", target: "corp", wantErr: "--target-repo is required"},
+		{name: "same repository", source: "acme/app", target: "acme/app", wantErr: "cannot be the same"},
+		{name: "missing target", source: "acme/app", wantErr: "--target-org or --target flag is required"},
+		{name: "malformed", source: "acme/", target: "corp", wantErr: `invalid --source "acme/"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, sourceRepo, targetOrg, targetRepo = "", "", "", ""
+			sourceRef, targetRef = tt.source, tt.target
+			orgToOrg, repoToOrg = false, tt.repoToOrg
+
+			err := validateFlags(rootCmd, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validateFlags() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateFlags() unexpected error: %v", err)
+			}
+			if mode := detectMigrationMode(); mode != tt.wantMode {
+				t.Errorf("detectMigrationMode() = %s, want %s", mode, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_RepoToOrg(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToRepo, origRepoToOrg := orgToRepo, repoToOrg
+	origVisibility := targetVisibility
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToRepo, repoToOrg = origOrgToRepo, origRepoToOrg
+		targetVisibility = origVisibility
+	}()
+
+	tests := []struct {
+		name       string
+		sourceRepo string
+		targetRepo string
+		visibility string
+		orgToRepo  bool
+		wantErr    bool
+	}{
+		{name: "valid", sourceRepo: "service", wantErr: false},
+		{name: "private visibility", sourceRepo: "service", visibility: "private", wantErr: false},
+		{name: "invalid visibility", sourceRepo: "service", visibility: "selected", wantErr: true},
+		{name: "missing source repo", wantErr: true},
+		{name: "target repo given", sourceRepo: "service", targetRepo: "other", wantErr: true},
+		{name: "combined with org-to-repo", sourceRepo: "service", orgToRepo: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Promoting into the source organization itself is allowed.
+			sourceOrg, targetOrg = "acme", "acme"
+			sourceRepo, targetRepo = tt.sourceRepo, tt.targetRepo
+			orgToRepo, repoToOrg = tt.orgToRepo, true
+			targetVisibility = tt.visibility
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && detectMigrationMode() != types.ModeRepoToOrg {
+				t.Errorf("detectMigrationMode() = %s, want %s", detectMigrationMode(), types.ModeRepoToOrg)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_FanOut(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origFanOut, origOrgToOrg := fanOut, orgToOrg
+	origRepoNames, origReposFile, origAllRepos := repoNames, reposFile, allRepos
+	origSnapshotFile := snapshotFile
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		fanOut, orgToOrg = origFanOut, origOrgToOrg
+		repoNames, reposFile, allRepos = origRepoNames, origReposFile, origAllRepos
+		snapshotFile = origSnapshotFile
+		fanOutRepos = nil
+	}()
+
+	dir := t.TempDir()
+	listFile := filepath.Join(dir, "repos.txt")
+	if err := os.WriteFile(listFile, []byte("# services\nworker\nacme/api\n"), 0o600); err != nil {
+		t.Fatalf("failed to write repos file: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		fanOut     bool
+		repos      []string
+		reposFile  string
+		allRepos   bool
+		targetRepo string
+		snapshot   string
+		orgToOrg   bool
+		wantRepos  []string
+		wantErr    bool
+	}{
+		{name: "repos list", fanOut: true, repos: []string{"api", "web"}, wantRepos: []string{"api", "web"}},
+		{name: "repos file merged with list", fanOut: true, repos: []string{"api"}, reposFile: listFile, wantRepos: []string{"api", "worker"}},
+		{name: "all repos", fanOut: true, allRepos: true},
+		{name: "no selector", fanOut: true, wantErr: true},
+		{name: "all repos with list", fanOut: true, allRepos: true, repos: []string{"api"}, wantErr: true},
+		{name: "repo of another org", fanOut: true, repos: []string{"other/api"}, wantErr: true},
+		{name: "missing repos file", fanOut: true, reposFile: filepath.Join(dir, "missing.txt"), wantErr: true},
+		{name: "target repo given", fanOut: true, repos: []string{"api"}, targetRepo: "web", wantErr: true},
+		{name: "snapshot file", fanOut: true, repos: []string{"api"}, snapshot: filepath.Join(dir, "snap.json"), wantErr: true},
+		{name: "combined with org-to-org", fanOut: true, repos: []string{"api"}, orgToOrg: true, wantErr: true},
+		{name: "selector without fan-out", orgToOrg: true, repos: []string{"api"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "src", "acme"
+			sourceRepo, targetRepo = "", tt.targetRepo
+			fanOut, orgToOrg = tt.fanOut, tt.orgToOrg
+			repoNames, reposFile, allRepos = tt.repos, tt.reposFile, tt.allRepos
+			snapshotFile = tt.snapshot
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if detectMigrationMode() != types.ModeFanOut {
+				t.Errorf("detectMigrationMode() = %s, want %s", detectMigrationMode(), types.ModeFanOut)
+			}
+			if !reflect.DeepEqual(fanOutRepos, tt.wantRepos) {
+				t.Errorf("fanOutRepos = %v, want %v", fanOutRepos, tt.wantRepos)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_TargetReposFile(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origFile, origOrgToOrg, origSnapshotFile := targetReposFile, orgToOrg, snapshotFile
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		targetReposFile, orgToOrg, snapshotFile = origFile, origOrgToOrg, origSnapshotFile
+		targets = nil
+	}()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	valid := write("valid.txt", "# new services\nacme/api\nother/web\n")
+	invalid := write("invalid.txt", "acme/api\nweb\nacme/bad name\n")
+	self := write("self.txt", "acme/api\nACME/template\n")
+
+	tests := []struct {
+		name       string
+		file       string
+		targetOrg  string
+		targetRepo string
+		snapshot   string
+		orgToOrg   bool
+		wantErr    string
+		wantCount  int
+	}{
+		{name: "valid", file: valid, wantCount: 2},
+		{name: "invalid lines", file: invalid, wantErr: "line 2"},
+		{name: "source listed as target", file: self, wantErr: "is the source repository"},
+		{name: "missing file", file: filepath.Join(dir, "missing.txt"), wantErr: "--target-repos-file"},
+		{name: "with target org", file: valid, targetOrg: "acme", wantErr: "cannot be combined"},
+		{name: "with target repo", file: valid, targetRepo: "api", wantErr: "cannot be combined"},
+		{name: "with snapshot file", file: valid, snapshot: filepath.Join(dir, "snap.json"), wantErr: "--snapshot-file"},
+		{name: "org-to-org mode", file: valid, orgToOrg: true, targetOrg: "other", wantErr: "repository-to-repository"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "acme", tt.targetOrg
+			sourceRepo, targetRepo = "template", tt.targetRepo
+			if tt.orgToOrg {
+				sourceRepo = ""
+			}
+			targetReposFile, orgToOrg, snapshotFile = tt.file, tt.orgToOrg, tt.snapshot
+
+			err := validateFlags(rootCmd, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validateFlags() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateFlags() unexpected error: %v", err)
+			}
+			if len(targets) != tt.wantCount {
+				t.Errorf("targets = %v, want %d target(s)", targets, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_Deep(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origDeep, origExcludeRepos := orgToOrg, deep, excludeRepos
+	origSnapshotFile := snapshotFile
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, deep, excludeRepos = origOrgToOrg, origDeep, origExcludeRepos
+		snapshotFile = origSnapshotFile
+	}()
+
+	tests := []struct {
+		name         string
+		orgToOrg     bool
+		deep         bool
+		excludeRepos []string
+		snapshotFile string
+		wantErr      bool
+	}{
+		{name: "deep org to org", orgToOrg: true, deep: true, wantErr: false},
+		{name: "with exclusions", orgToOrg: true, deep: true, excludeRepos: []string{"legacy-*", "sandbox"}, wantErr: false},
+		{name: "invalid exclusion pattern", orgToOrg: true, deep: true, excludeRepos: []string{"[abc"}, wantErr: true},
+		{name: "deep without org-to-org", deep: true, wantErr: true},
+		{name: "exclusions without deep", orgToOrg: true, excludeRepos: []string{"legacy-*"}, wantErr: true},
+		{name: "deep with snapshot file", orgToOrg: true, deep: true, snapshotFile: "snap.json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "old-org", "new-org"
+			sourceRepo, targetRepo = "", ""
+			if !tt.orgToOrg {
+				sourceRepo, targetRepo = "app", "app"
+			}
+			orgToOrg, deep, excludeRepos = tt.orgToOrg, tt.deep, tt.excludeRepos
+			snapshotFile = tt.snapshotFile
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_Envs(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origSkipEnvs, origEnvNames := orgToOrg, skipEnvs, envNames
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, skipEnvs, envNames = origOrgToOrg, origSkipEnvs, origEnvNames
+	}()
+
+	tests := []struct {
+		name     string
+		orgToOrg bool
+		skipEnvs bool
+		envNames []string
+		wantErr  bool
+	}{
+		{name: "selected environments", envNames: []string{"production", "staging"}, wantErr: false},
+		{name: "combined with skip-envs", skipEnvs: true, envNames: []string{"production"}, wantErr: true},
+		{name: "org to org", orgToOrg: true, envNames: []string{"production"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "old-org", "new-org"
+			sourceRepo, targetRepo = "app", "app"
+			if tt.orgToOrg {
+				sourceRepo, targetRepo = "", ""
+			}
+			orgToOrg, skipEnvs, envNames = tt.orgToOrg, tt.skipEnvs, tt.envNames
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_ExcludeEnvs(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origDeep, origSkipEnvs := orgToOrg, deep, skipEnvs
+	origEnvNames, origExcludeEnvs := envNames, excludeEnvs
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, deep, skipEnvs = origOrgToOrg, origDeep, origSkipEnvs
+		envNames, excludeEnvs = origEnvNames, origExcludeEnvs
+	}()
+
+	tests := []struct {
+		name        string
+		orgToOrg    bool
+		deep        bool
+		skipEnvs    bool
+		envNames    []string
+		excludeEnvs []string
+		wantErr     bool
+	}{
+		{name: "repo to repo", excludeEnvs: []string{"pr-*", "preview-*"}, wantErr: false},
+		{name: "overlapping patterns", excludeEnvs: []string{"pr-*", "pr-1*"}, wantErr: false},
+		{name: "deep", orgToOrg: true, deep: true, excludeEnvs: []string{"pr-*"}, wantErr: false},
+		{name: "org to org without deep", orgToOrg: true, excludeEnvs: []string{"pr-*"}, wantErr: true},
+		{name: "invalid pattern", excludeEnvs: []string{"pr-[*"}, wantErr: true},
+		{name: "combined with envs", envNames: []string{"production"}, excludeEnvs: []string{"pr-*"}, wantErr: true},
+		{name: "combined with skip-envs", skipEnvs: true, excludeEnvs: []string{"pr-*"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "old-org", "new-org"
+			sourceRepo, targetRepo = "app", "app"
+			if tt.orgToOrg {
+				sourceRepo, targetRepo = "", ""
+			}
+			orgToOrg, deep, skipEnvs = tt.orgToOrg, tt.deep, tt.skipEnvs
+			envNames, excludeEnvs = tt.envNames, tt.excludeEnvs
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadEnvMap(t *testing.T) {
+	dir := t.TempDir()
+	mapFile := filepath.Join(dir, "envs.txt")
+	if err := os.WriteFile(mapFile, []byte("uat=staging\nprod=production\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		specs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "pairs and file",
+			specs: []string{"stage=staging", mapFile},
+			want:  map[string]string{"stage": "staging", "uat": "staging", "prod": "production"},
+		},
+		{name: "same source twice", specs: []string{"stage=staging", "Stage=qa"}, wantErr: true},
+		{name: "source also in file", specs: []string{"prod=live", mapFile}, wantErr: true},
+		{name: "missing file", specs: []string{filepath.Join(dir, "missing.txt")}, wantErr: true},
+		{name: "empty target", specs: []string{"stage="}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadEnvMap(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadEnvMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadEnvMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_EnvMap(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origSkipEnvs, origEnvMapSpecs := orgToOrg, skipEnvs, envMapSpecs
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, skipEnvs, envMapSpecs = origOrgToOrg, origSkipEnvs, origEnvMapSpecs
+		envMap = nil
+	}()
+
+	tests := []struct {
+		name     string
+		orgToOrg bool
+		skipEnvs bool
+		specs    []string
+		wantErr  bool
+	}{
+		{name: "rename", specs: []string{"stage=staging"}, wantErr: false},
+		{name: "combined with skip-envs", skipEnvs: true, specs: []string{"stage=staging"}, wantErr: true},
+		{name: "org to org", orgToOrg: true, specs: []string{"stage=staging"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "old-org", "new-org"
+			sourceRepo, targetRepo = "app", "app"
+			if tt.orgToOrg {
+				sourceRepo, targetRepo = "", ""
+			}
+			orgToOrg, skipEnvs, envMapSpecs = tt.orgToOrg, tt.skipEnvs, tt.specs
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_Env(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origSkipEnvs := orgToOrg, skipEnvs
+	origEnvName, origEnvNames, origExcludeEnvs := envName, envNames, excludeEnvs
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, skipEnvs = origOrgToOrg, origSkipEnvs
+		envName, envNames, excludeEnvs = origEnvName, origEnvNames, origExcludeEnvs
+	}()
+
+	tests := []struct {
+		name        string
+		orgToOrg    bool
+		skipEnvs    bool
+		envNames    []string
+		excludeEnvs []string
+		wantErr     bool
+	}{
+		{name: "single environment", wantErr: false},
+		{name: "combined with skip-envs", skipEnvs: true, wantErr: true},
+		{name: "combined with envs", envNames: []string{"staging"}, wantErr: true},
+		{name: "combined with exclude-envs", excludeEnvs: []string{"pr-*"}, wantErr: true},
+		{name: "org to org", orgToOrg: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "old-org", "new-org"
+			sourceRepo, targetRepo = "app", "app"
+			if tt.orgToOrg {
+				sourceRepo, targetRepo = "", ""
+			}
+			orgToOrg, skipEnvs = tt.orgToOrg, tt.skipEnvs
+			envName, envNames, excludeEnvs = "production", tt.envNames, tt.excludeEnvs
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateFlags_SourceTargetEnv tests the validation, mode detection,
+// and configuration of --source-env and --target-env
+func TestValidateFlags_SourceTargetEnv(t *testing.T) {
+	origSourceOrg, origTargetOrg, origSourceRef := sourceOrg, targetOrg, sourceRef
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origSkipEnvs, origEnvsOnly := orgToOrg, skipEnvs, envsOnly
+	origEnvName, origEnvNames, origEnvMapSpecs := envName, envNames, envMapSpecs
+	origSourceEnv, origTargetEnv := sourceEnv, targetEnv
+	defer func() {
+		sourceOrg, targetOrg, sourceRef = origSourceOrg, origTargetOrg, origSourceRef
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, skipEnvs, envsOnly = origOrgToOrg, origSkipEnvs, origEnvsOnly
+		envName, envNames, envMapSpecs = origEnvName, origEnvNames, origEnvMapSpecs
+		sourceEnv, targetEnv = origSourceEnv, origTargetEnv
+	}()
+
+	tests := []struct {
+		name       string
+		sourceEnv  string
+		targetEnv  string
+		envName    string
+		orgToOrg   bool
+		skipEnvs   bool
+		envsOnly   bool
+		envNames   []string
+		envMap     []string
+		wantErr    string
+		wantEnvMap map[string]string
+	}{
+		{name: "source environment only", sourceEnv: "production"},
+		{name: "renamed", sourceEnv: "production", targetEnv: "prod", wantEnvMap: map[string]string{"production": "prod"}},
+		{name: "env renamed", envName: "staging", targetEnv: "stage", wantEnvMap: map[string]string{"staging": "stage"}},
+		{name: "target without source", targetEnv: "prod", wantErr: "--target-env requires --source-env"},
+		{name: "with env", sourceEnv: "production", envName: "staging", wantErr: "--env and --source-env cannot be used together"},
+		{name: "with env-map", sourceEnv: "production", targetEnv: "prod", envMap: []string{"a=b"}, wantErr: "--target-env and --env-map cannot be used together"},
+		{name: "with skip-envs", sourceEnv: "production", skipEnvs: true, wantErr: "--source-env and --skip-envs cannot be used together"},
+		{name: "with envs", sourceEnv: "production", envNames: []string{"staging"}, wantErr: "--source-env and --envs cannot be used together"},
+		{name: "with envs-only", sourceEnv: "production", envsOnly: true, wantErr: "--source-env already leaves out repository-level variables"},
+		{name: "org to org", sourceEnv: "production", orgToOrg: true, wantErr: "--source-env can only be used for repository-to-repository migration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg, sourceRef = "old-org", "new-org", ""
+			sourceRepo, targetRepo = "app", "app"
+			if tt.orgToOrg {
+				sourceRepo, targetRepo = "", ""
+			}
+			orgToOrg, skipEnvs, envsOnly = tt.orgToOrg, tt.skipEnvs, tt.envsOnly
+			envName, envNames, envMapSpecs = tt.envName, tt.envNames, tt.envMap
+			sourceEnv, targetEnv = tt.sourceEnv, tt.targetEnv
+
+			err := validateFlags(rootCmd, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("validateFlags() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateFlags() unexpected error: %v", err)
+			}
+
+			cfg := migrationConfig(types.ModeRepoToRepo)
+			want := tt.sourceEnv
+			if want == "" {
+				want = tt.envName
+			}
+			if len(cfg.Envs) != 1 || cfg.Envs[0] != want || !cfg.SkipRepoVars {
+				t.Errorf("Envs = %v, SkipRepoVars = %v, want [%s] and true", cfg.Envs, cfg.SkipRepoVars, want)
+			}
+			if !reflect.DeepEqual(cfg.EnvMap, tt.wantEnvMap) {
+				t.Errorf("EnvMap = %v, want %v", cfg.EnvMap, tt.wantEnvMap)
+			}
+		})
+	}
+
+	// An organization --source with an environment is a repository
+	// migration, which then needs --source-repo
+	sourceRef, sourceRepo, targetRepo, orgToOrg = "old-org", "", "app", false
+	sourceEnv, targetEnv, envName, envNames, envMapSpecs, skipEnvs, envsOnly = "production", "", "", nil, nil, false, false
+	if mode := detectMigrationMode(); mode != types.ModeRepoToRepo {
+		t.Errorf("detectMigrationMode() with --source-env = %s, want %s", mode, types.ModeRepoToRepo)
+	}
+	orgToOrg = true
+	if mode := detectMigrationMode(); mode != types.ModeOrgToOrg {
+		t.Errorf("detectMigrationMode() with --org-to-org and --source-env = %s, want %s", mode, types.ModeOrgToOrg)
+	}
+}
+
+func TestValidateFlags_Visibility(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origVisibility := orgToOrg, visibility
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, visibility = origOrgToOrg, origVisibility
+	}()
+
+	tests := []struct {
+		name       string
+		orgToOrg   bool
+		visibility string
+		wantErr    bool
+	}{
+		{name: "all", orgToOrg: true, visibility: "all", wantErr: false},
+		{name: "private", orgToOrg: true, visibility: "private", wantErr: false},
+		{name: "selected", orgToOrg: true, visibility: "selected", wantErr: false},
+		{name: "invalid", orgToOrg: true, visibility: "public", wantErr: true},
+		{name: "repo to repo", visibility: "private", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "old-org", "new-org"
+			sourceRepo, targetRepo = "app", "app"
+			if tt.orgToOrg {
+				sourceRepo, targetRepo = "", ""
+			}
+			orgToOrg, visibility = tt.orgToOrg, tt.visibility
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_RepoMap(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origRepoMapFile := orgToOrg, repoMapFile
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, repoMapFile = origOrgToOrg, origRepoMapFile
+		repoMap = nil
+	}()
+
+	dir := t.TempDir()
+	validFile := filepath.Join(dir, "repos.txt")
+	if err := os.WriteFile(validFile, []byte("api=platform-api\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	invalidFile := filepath.Join(dir, "invalid.txt")
+	if err := os.WriteFile(invalidFile, []byte("api=platform/api\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		orgToOrg bool
+		file     string
+		wantErr  bool
+	}{
+		{name: "org to org", orgToOrg: true, file: validFile, wantErr: false},
+		{name: "invalid repository name", orgToOrg: true, file: invalidFile, wantErr: true},
+		{name: "missing file", orgToOrg: true, file: filepath.Join(dir, "missing.txt"), wantErr: true},
+		{name: "repo to repo", file: validFile, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "old-org", "new-org"
+			sourceRepo, targetRepo = "app", "app"
+			if tt.orgToOrg {
+				sourceRepo, targetRepo = "", ""
+			}
+			orgToOrg, repoMapFile = tt.orgToOrg, tt.file
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && repoMap["api"] != "platform-api" {
+				t.Errorf("repoMap = %v, want api=platform-api", repoMap)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_SelectedFallback(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origSelectedFallback := orgToOrg, selectedFallback
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, selectedFallback = origOrgToOrg, origSelectedFallback
+	}()
+
+	tests := []struct {
+		name     string
+		orgToOrg bool
+		fallback string
+		wantErr  bool
+	}{
+		{name: "empty", orgToOrg: true, fallback: "empty", wantErr: false},
+		{name: "private", orgToOrg: true, fallback: "private", wantErr: false},
+		{name: "all", orgToOrg: true, fallback: "all", wantErr: false},
+		{name: "skip", orgToOrg: true, fallback: "skip", wantErr: false},
+		{name: "invalid", orgToOrg: true, fallback: "none", wantErr: true},
+		{name: "repo to repo", fallback: "skip", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "old-org", "new-org"
+			sourceRepo, targetRepo = "app", "app"
+			if tt.orgToOrg {
+				sourceRepo, targetRepo = "", ""
+			}
+			orgToOrg, selectedFallback = tt.orgToOrg, tt.fallback
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_Report(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origOrgToOrg, origDiffMode, origRollbackFile := orgToOrg, diffMode, rollbackFile
+	origReportFile, origReportIncludeValues := reportFile, reportIncludeValues
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		orgToOrg, diffMode, rollbackFile = origOrgToOrg, origDiffMode, origRollbackFile
+		reportFile, reportIncludeValues = origReportFile, origReportIncludeValues
+	}()
+
+	tests := []struct {
+		name          string
+		reportFile    string
+		includeValues bool
+		diffMode      bool
+		rollbackFile  string
+		wantErr       bool
+	}{
+		{name: "report", reportFile: "report.json", wantErr: false},
+		{name: "report with values", reportFile: "report.json", includeValues: true, wantErr: false},
+		{name: "values without report", includeValues: true, wantErr: true},
+		{name: "report with diff", reportFile: "report.json", diffMode: true, wantErr: true},
+		{name: "report with rollback", reportFile: "report.json", rollbackFile: "snap.json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "source-org", "target-org"
+			orgToOrg, diffMode, rollbackFile = true, tt.diffMode, tt.rollbackFile
+			reportFile, reportIncludeValues = tt.reportFile, tt.includeValues
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_ExitCodeOnDiff(t *testing.T) {
+	origSourceOrg, origTargetOrg, origOrgToOrg := sourceOrg, targetOrg, orgToOrg
+	origDryRun, origExitCodeOnDiff := dryRun, exitCodeOnDiff
+	defer func() {
+		sourceOrg, targetOrg, orgToOrg = origSourceOrg, origTargetOrg, origOrgToOrg
+		dryRun, exitCodeOnDiff = origDryRun, origExitCodeOnDiff
+	}()
+
+	tests := []struct {
+		name    string
+		dryRun  bool
+		wantErr bool
+	}{
+		{name: "with dry run", dryRun: true, wantErr: false},
+		{name: "without dry run", dryRun: false, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg, orgToOrg = "source-org", "target-org", true
+			dryRun, exitCodeOnDiff = tt.dryRun, true
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && exitCode(err) != exitCodeUsage {
+				t.Errorf("Expected exit code %d for a validation error, got %d", exitCodeUsage, exitCode(err))
+			}
+		})
+	}
+}
+
+func TestValidateFlags_MaxErrors(t *testing.T) {
+	origSourceOrg, origTargetOrg, origOrgToOrg := sourceOrg, targetOrg, orgToOrg
+	origFailFast, origMaxErrors := failFast, maxErrors
+	defer func() {
+		sourceOrg, targetOrg, orgToOrg = origSourceOrg, origTargetOrg, origOrgToOrg
+		failFast, maxErrors = origFailFast, origMaxErrors
+	}()
+
+	tests := []struct {
+		name      string
+		failFast  bool
+		maxErrors int
+		wantErr   bool
+	}{
+		{name: "no limit", maxErrors: 0, wantErr: false},
+		{name: "limit", maxErrors: 5, wantErr: false},
+		{name: "negative", maxErrors: -1, wantErr: true},
+		{name: "fail fast alone", failFast: true, wantErr: false},
+		{name: "fail fast with a limit of one", failFast: true, maxErrors: 1, wantErr: false},
+		{name: "fail fast with a higher limit", failFast: true, maxErrors: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg, orgToOrg = "source-org", "target-org", true
+			failFast, maxErrors = tt.failFast, tt.maxErrors
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvInt(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{value: "", want: 0},
+		{value: "25", want: 25},
+		{value: " 3 ", want: 3},
+		{value: "many", want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("MAX_ERRORS", tt.value)
+		if got := envInt("MAX_ERRORS"); got != tt.want {
+			t.Errorf("envInt(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestMigrationExitError tests the exit code of each outcome of a finished run
+func TestMigrationExitError(t *testing.T) {
+	origDryRun, origExitCodeOnDiff := dryRun, exitCodeOnDiff
+	defer func() { dryRun, exitCodeOnDiff = origDryRun, origExitCodeOnDiff }()
+
+	tests := []struct {
+		name           string
+		result         *types.MigrationResult
+		dryRun         bool
+		exitCodeOnDiff bool
+		wantCode       int
+	}{
+		{name: "success", result: &types.MigrationResult{Created: 2}, wantCode: 0},
+		{name: "nothing to migrate", result: &types.MigrationResult{}, wantCode: 0},
+		{name: "variable errors", result: &types.MigrationResult{Created: 1, Errors: []error{errors.New("boom")}}, wantCode: exitCodePartial},
+		{name: "failed repository", result: &types.MigrationResult{Repos: []types.RepoResult{{Repo: "api", Failed: true}, {Repo: "web"}}}, wantCode: exitCodePartial},
+		{name: "aborted", result: &types.MigrationResult{Aborted: true, Errors: []error{errors.New("boom")}}, wantCode: exitCodeAborted},
+		{name: "interrupted", result: &types.MigrationResult{Interrupted: true, Created: 1}, wantCode: exitCodeAborted},
+		{name: "error limit reached", result: &types.MigrationResult{ErrorLimitReached: true, Errors: []error{errors.New("a"), errors.New("b")}}, wantCode: exitCodeErrorLimit},
+		{name: "budget exhausted", result: &types.MigrationResult{BudgetExhausted: true, Created: 3}, wantCode: exitCodeBudget},
+		{name: "empty with fail-if-empty", result: &types.MigrationResult{Empty: true}, wantCode: exitCodeEmpty},
+		{name: "empty with errors", result: &types.MigrationResult{Empty: true, Errors: []error{errors.New("boom")}}, wantCode: exitCodePartial},
+		{name: "dry run with changes", result: &types.MigrationResult{Updated: 1}, dryRun: true, wantCode: 0},
+		{name: "dry run with changes and exit code on diff", result: &types.MigrationResult{Updated: 1}, dryRun: true, exitCodeOnDiff: true, wantCode: exitCodeDiff},
+		{name: "dry run without changes and exit code on diff", result: &types.MigrationResult{Skipped: 3}, dryRun: true, exitCodeOnDiff: true, wantCode: 0},
+		{name: "dry run with errors and exit code on diff", result: &types.MigrationResult{Created: 1, Errors: []error{errors.New("boom")}}, dryRun: true, exitCodeOnDiff: true, wantCode: exitCodePartial},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dryRun, exitCodeOnDiff = tt.dryRun, tt.exitCodeOnDiff
+			if got := exitCode(migrationExitError(tt.result)); got != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+// TestExecute_Smoke runs the command tree as main does, with --help,
+// --version, and invalid flags, and checks the output and exit code
+func TestExecute_Smoke(t *testing.T) {
+	origSourceOrg, origTargetOrg, origSourceRef, origTargetRef := sourceOrg, targetOrg, sourceRef, targetRef
+	defer func() {
+		sourceOrg, targetOrg, sourceRef, targetRef = origSourceOrg, origTargetOrg, origSourceRef, origTargetRef
+		helpShown = false
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		_ = rootCmd.Flags().Set("help", "false")
+		_ = rootCmd.Flags().Set("version", "false")
+	}()
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		wantErr  string
+		wantCode int
+	}{
+		{name: "no arguments", args: []string{}, want: "Usage:\n  gh-vars-migrator [flags]"},
+		{name: "help", args: []string{"--help"}, want: "Usage:\n  gh-vars-migrator [flags]"},
+		{name: "version", args: []string{"--version"}, want: "gh-vars-migrator version " + Version},
+		{name: "unknown flag", args: []string{"--no-such-flag"}, wantErr: "unknown flag: --no-such-flag", wantCode: exitCodeUsage},
+		{name: "missing value", args: []string{"--source-org"}, wantErr: "flag needs an argument: --source-org", wantCode: exitCodeUsage},
+		{name: "unknown command flag", args: []string{"auth", "--no-such-flag"}, wantErr: "unknown flag: --no-such-flag", wantCode: exitCodeUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The environment of the test run must not name a source or target
+			sourceOrg, targetOrg, sourceRef, targetRef = "", "", "", ""
+			var out strings.Builder
+			rootCmd.SetOut(&out)
+			rootCmd.SetErr(&out)
+			rootCmd.SetArgs(tt.args)
+			var err error
+			stdout, stderr := captureStdio(t, func() { err = rootCmd.Execute() })
+			_ = rootCmd.Flags().Set("help", "false")
+			_ = rootCmd.Flags().Set("version", "false")
+
+			if code := exitCode(err); code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (err: %v)", code, tt.wantCode, err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Execute() error = %v, want %q", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("Expected %q in the output, got:\n%s", tt.want, out.String())
+			}
+			// Nothing is run past the help, such as a token lookup
+			if logged := stdout + stderr; logged != "" && tt.wantCode == 0 {
+				t.Errorf("Unexpected output past the help:\n%s", logged)
+			}
+		})
+	}
+}
+
+// TestRunMigration_AuthExitCode tests that missing credentials for one side
+// stop the run with the authentication exit code
+func TestRunMigration_AuthExitCode(t *testing.T) {
+	origSourcePAT, origTargetPAT, origRollbackFile := sourcePAT, targetPAT, rollbackFile
+	origGitHubToken := os.Getenv("GITHUB_TOKEN")
+	defer func() {
+		sourcePAT, targetPAT, rollbackFile = origSourcePAT, origTargetPAT, origRollbackFile
+		os.Setenv("GITHUB_TOKEN", origGitHubToken)
+	}()
+
+	sourcePAT, targetPAT, rollbackFile = "ghp_source", "", ""
+	os.Unsetenv("GITHUB_TOKEN")
+
+	err := runMigration(rootCmd, nil)
+	if err == nil {
+		t.Fatal("Expected an error when only the source token is set")
+	}
+	if got := exitCode(err); got != exitCodeAuth {
+		t.Errorf("exit code = %d, want %d (error: %v)", got, exitCodeAuth, err)
+	}
+}
+
+// TestConfirmMigration tests when a migration asks for confirmation, and
+// that without a terminal --yes is required
+func TestConfirmMigration(t *testing.T) {
+	origDryRun, origYes, origInteractive := dryRun, assumeYes, interactive
+	defer func() {
+		dryRun, assumeYes, interactive = origDryRun, origYes, origInteractive
+	}()
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		yes         bool
+		interactive bool
+		wantErr     string
+	}{
+		{name: "dry run", dryRun: true},
+		{name: "yes", yes: true},
+		{name: "interactive", interactive: true},
+		{name: "no terminal", wantErr: "pass --yes to run it without asking"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dryRun, assumeYes, interactive = tt.dryRun, tt.yes, tt.interactive
+			// Without a terminal nothing is read, so no migrator is needed
+			err := confirmMigration(nil, false)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("confirmMigration() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("confirmMigration() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_StrictNames(t *testing.T) {
+	origSourceOrg, origTargetOrg, origOrgToOrg := sourceOrg, targetOrg, orgToOrg
+	origStrictNames, origSkipLimitChecks := strictNames, skipLimitChecks
+	defer func() {
+		sourceOrg, targetOrg, orgToOrg = origSourceOrg, origTargetOrg, origOrgToOrg
+		strictNames, skipLimitChecks = origStrictNames, origSkipLimitChecks
+	}()
+
+	tests := []struct {
+		name            string
+		skipLimitChecks bool
+		wantErr         bool
+	}{
+		{name: "strict names", wantErr: false},
+		{name: "strict names without limit checks", skipLimitChecks: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg, orgToOrg = "source-org", "target-org", true
+			strictNames, skipLimitChecks = true, tt.skipLimitChecks
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_PlanOut(t *testing.T) {
+	origSourceOrg, origTargetOrg, origOrgToOrg, origDryRun := sourceOrg, targetOrg, orgToOrg, dryRun
+	origPlanOut, origPlanIncludeValues := planOut, planIncludeValues
+	defer func() {
+		sourceOrg, targetOrg, orgToOrg, dryRun = origSourceOrg, origTargetOrg, origOrgToOrg, origDryRun
+		planOut, planIncludeValues = origPlanOut, origPlanIncludeValues
+	}()
+
+	tests := []struct {
+		name          string
+		dryRun        bool
+		planOut       string
+		includeValues bool
+		wantErr       bool
+	}{
+		{name: "no plan", wantErr: false},
+		{name: "plan with dry run", dryRun: true, planOut: "plan.json", wantErr: false},
+		{name: "plan with values", dryRun: true, planOut: "plan.json", includeValues: true, wantErr: false},
+		{name: "plan without dry run", planOut: "plan.json", wantErr: true},
+		{name: "values without plan", dryRun: true, includeValues: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg, orgToOrg = "source-org", "target-org", true
+			dryRun, planOut, planIncludeValues = tt.dryRun, tt.planOut, tt.includeValues
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_Hooks(t *testing.T) {
+	origSourceOrg, origTargetOrg, origOrgToOrg, origDiffMode := sourceOrg, targetOrg, orgToOrg, diffMode
+	origPreHook, origPostHook, origHooksInDryRun := preHook, postHook, hooksInDryRun
+	defer func() {
+		sourceOrg, targetOrg, orgToOrg, diffMode = origSourceOrg, origTargetOrg, origOrgToOrg, origDiffMode
+		preHook, postHook, hooksInDryRun = origPreHook, origPostHook, origHooksInDryRun
+	}()
+
+	tests := []struct {
+		name          string
+		preHook       string
+		postHook      string
+		hooksInDryRun bool
+		diff          bool
+		wantErr       bool
+	}{
+		{name: "no hooks", wantErr: false},
+		{name: "both hooks", preHook: "./pause.sh", postHook: "./resume.sh", wantErr: false},
+		{name: "hooks in dry run", postHook: "./resume.sh", hooksInDryRun: true, wantErr: false},
+		{name: "hooks in dry run without a hook", hooksInDryRun: true, wantErr: true},
+		{name: "hook with diff", preHook: "./pause.sh", diff: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg, orgToOrg, diffMode = "source-org", "target-org", true, tt.diff
+			preHook, postHook, hooksInDryRun = tt.preHook, tt.postHook, tt.hooksInDryRun
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunHook_DryRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh exit codes")
+	}
+	origDryRun, origHooksInDryRun := dryRun, hooksInDryRun
+	defer func() { dryRun, hooksInDryRun = origDryRun, origHooksInDryRun }()
+
+	tests := []struct {
+		name          string
+		dryRun        bool
+		hooksInDryRun bool
+		wantRun       bool
+	}{
+		{name: "real run", wantRun: true},
+		{name: "dry run", dryRun: true, wantRun: false},
+		{name: "dry run with hooks", dryRun: true, hooksInDryRun: true, wantRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dryRun, hooksInDryRun = tt.dryRun, tt.hooksInDryRun
+			// The command fails, so an error tells that it was run
+			err := runHook("pre-hook", "exit 1", nil)
+			if ran := err != nil; ran != tt.wantRun {
+				t.Errorf("runHook() error = %v, want run %v", err, tt.wantRun)
+			}
+			if err != nil && !strings.Contains(err.Error(), "--pre-hook failed") {
+				t.Errorf("Error should name the flag, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_RetryFailed(t *testing.T) {
+	origSourceOrg, origTargetOrg, origOrgToOrg, origDiffMode := sourceOrg, targetOrg, orgToOrg, diffMode
+	origRetryFailed, origRetryReport := retryFailed, retryReport
+	origOnConflict, origSkipOverwrite := onConflict, skipOverwrite
+	defer func() {
+		sourceOrg, targetOrg, orgToOrg, diffMode = origSourceOrg, origTargetOrg, origOrgToOrg, origDiffMode
+		retryFailed, retryReport = origRetryFailed, origRetryReport
+		onConflict, skipOverwrite = origOnConflict, origSkipOverwrite
+	}()
+
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, "report.json")
+	cfg := &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "source-org", TargetOrg: "target-org", OnConflict: types.ConflictSkip}
+	rep := report.New(cfg, time.Now(), false)
+	rep.Finish(&types.MigrationResult{}, nil, time.Now())
+	if err := report.Save(reportPath, rep); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		file           string
+		onConflict     string
+		diff           bool
+		wantOnConflict string
+		wantErr        string
+	}{
+		{name: "conflict strategy from the report", file: reportPath, wantOnConflict: "skip"},
+		{name: "explicit conflict strategy", file: reportPath, onConflict: "overwrite", wantOnConflict: "overwrite"},
+		{name: "missing report", file: filepath.Join(dir, "missing.json"), wantErr: "reading report"},
+		{name: "with diff", file: reportPath, diff: true, wantErr: "--retry-failed cannot be combined with --diff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg, orgToOrg, diffMode = "source-org", "target-org", true, tt.diff
+			retryFailed, retryReport = tt.file, nil
+			onConflict, skipOverwrite = tt.onConflict, false
+
+			err := validateFlags(rootCmd, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("validateFlags() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateFlags() unexpected error: %v", err)
+			}
+			if retryReport == nil {
+				t.Fatal("Expected the report to be loaded")
+			}
+			if onConflict != tt.wantOnConflict {
+				t.Errorf("onConflict = %q, want %q", onConflict, tt.wantOnConflict)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_CheckUsage(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origOrgToRepo, origDiffMode := orgToOrg, orgToRepo, diffMode
+	origCheckUsage := checkUsage
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, orgToRepo, diffMode = origOrgToOrg, origOrgToRepo, origDiffMode
+		checkUsage = origCheckUsage
+	}()
+
+	tests := []struct {
+		name       string
+		sourceRepo string
+		orgToOrg   bool
+		orgToRepo  bool
+		diff       bool
+		wantErr    bool
+	}{
+		{name: "repo to repo", sourceRepo: "app", wantErr: false},
+		{name: "org to repo", orgToRepo: true, wantErr: false},
+		{name: "org to org", orgToOrg: true, wantErr: true},
+		{name: "with diff", sourceRepo: "app", diff: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "source-org", "target-org"
+			sourceRepo, targetRepo = tt.sourceRepo, "app"
+			if tt.orgToOrg {
+				targetRepo = ""
+			}
+			orgToOrg, orgToRepo, diffMode = tt.orgToOrg, tt.orgToRepo, tt.diff
+			checkUsage = true
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_SkipUnused(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origOrgToRepo, origDiffMode := orgToOrg, orgToRepo, diffMode
+	origSkipUnused := skipUnused
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, orgToRepo, diffMode = origOrgToOrg, origOrgToRepo, origDiffMode
+		skipUnused = origSkipUnused
+	}()
+
+	tests := []struct {
+		name       string
+		sourceRepo string
+		targetRepo string
+		orgToOrg   bool
+		orgToRepo  bool
+		diff       bool
+		wantErr    bool
+	}{
+		{name: "repo to repo", sourceRepo: "app", targetRepo: "app", wantErr: false},
+		{name: "org to repo", targetRepo: "app", orgToRepo: true, wantErr: true},
+		{name: "org to org", orgToOrg: true, wantErr: true},
+		{name: "with diff", sourceRepo: "app", targetRepo: "app", diff: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "source-org", "target-org"
+			sourceRepo, targetRepo = tt.sourceRepo, tt.targetRepo
+			orgToOrg, orgToRepo, diffMode = tt.orgToOrg, tt.orgToRepo, tt.diff
+			skipUnused = true
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_CopyEnvProtection(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origOrgToRepo, origDiffMode := orgToOrg, orgToRepo, diffMode
+	origSkipEnvs, origDeep := skipEnvs, deep
+	origCopy, origUpdate := copyEnvProtection, updateEnvSettings
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, orgToRepo, diffMode = origOrgToOrg, origOrgToRepo, origDiffMode
+		skipEnvs, deep = origSkipEnvs, origDeep
+		copyEnvProtection, updateEnvSettings = origCopy, origUpdate
+	}()
+
+	tests := []struct {
+		name     string
+		orgToOrg bool
+		deep     bool
+		skipEnvs bool
+		diff     bool
+		copy     bool
+		update   bool
+		wantErr  bool
+	}{
+		{name: "repo to repo", copy: true, wantErr: false},
+		{name: "repo to repo with update", copy: true, update: true, wantErr: false},
+		{name: "deep", orgToOrg: true, deep: true, copy: true, wantErr: false},
+		{name: "org to org without deep", orgToOrg: true, copy: true, wantErr: true},
+		{name: "with skip-envs", copy: true, skipEnvs: true, wantErr: true},
+		{name: "with diff", copy: true, diff: true, wantErr: true},
+		{name: "update without copy", update: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "source-org", "target-org"
+			sourceRepo, targetRepo = "app", "app"
+			if tt.orgToOrg {
+				sourceRepo, targetRepo = "", ""
+			}
+			orgToOrg, orgToRepo, diffMode = tt.orgToOrg, false, tt.diff
+			skipEnvs, deep = tt.skipEnvs, tt.deep
+			copyEnvProtection, updateEnvSettings = tt.copy, tt.update
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// roundTripFunc adapts a function to an http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// fakeAPIClient returns a client whose GET requests are answered from
+// responses by path; other paths return 404
+func fakeAPIClient(t *testing.T, responses map[string]fakeResponse) *client.Client {
+	t.Helper()
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, ok := responses[strings.TrimPrefix(req.URL.Path, "/")]
+		if !ok {
+			resp = fakeResponse{http.StatusNotFound, `{"message":"Not Found"}`}
+		}
+		return &http.Response{
+			StatusCode: resp.status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(resp.body)),
+			Request:    req,
+		}, nil
+	})
+	c, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("NewWithTransport() unexpected error: %v", err)
+	}
+	return c
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+// TestCheckEndpoints tests that a missing or inaccessible source or target
+// is reported before the migration, naming the side and the host
+func TestCheckEndpoints(t *testing.T) {
+	origSourceOrg, origTargetOrg, origSourceRepo, origTargetRepo := sourceOrg, targetOrg, sourceRepo, targetRepo
+	origTargets, origDryRun, origDiff := targets, dryRun, diffMode
+	origSourceHost, origTargetHost := sourceHostname, targetHostname
+	defer func() {
+		sourceOrg, targetOrg, sourceRepo, targetRepo = origSourceOrg, origTargetOrg, origSourceRepo, origTargetRepo
+		targets, dryRun, diffMode = origTargets, origDryRun, origDiff
+		sourceHostname, targetHostname = origSourceHost, origTargetHost
+	}()
+
+	writable := fakeResponse{http.StatusOK, `{"name":"app","permissions":{"admin":false,"push":true,"pull":true}}`}
+	readOnly := fakeResponse{http.StatusOK, `{"name":"app","permissions":{"admin":false,"push":false,"pull":true}}`}
+	forbidden := fakeResponse{http.StatusForbidden, `{"message":"Resource not accessible by personal access token"}`}
+	org := fakeResponse{http.StatusOK, `{"login":"acme"}`}
+
+	tests := []struct {
+		name       string
+		mode       types.MigrationMode
+		source     map[string]fakeResponse
+		target     map[string]fakeResponse
+		targets    []types.RepoRef
+		dryRun     bool
+		wantErr    string
+		wantCode   int
+		targetHost string
+	}{
+		{
+			name:   "repositories exist",
+			mode:   types.ModeRepoToRepo,
+			source: map[string]fakeResponse{"repos/src/app": writable},
+			target: map[string]fakeResponse{"repos/dst/app": writable},
+		},
+		{
+			name:     "missing source repository",
+			mode:     types.ModeRepoToRepo,
+			target:   map[string]fakeResponse{"repos/dst/app": writable},
+			wantErr:  "source repository src/app not found on github.com, or the source token cannot see it",
+			wantCode: exitCodeUsage,
+		},
+		{
+			name:       "missing target repository",
+			mode:       types.ModeRepoToRepo,
+			source:     map[string]fakeResponse{"repos/src/app": writable},
+			targetHost: "ghe.example.com",
+			wantErr:    "target repository dst/app not found on ghe.example.com",
+			wantCode:   exitCodeUsage,
+		},
+		{
+			name:     "forbidden target repository",
+			mode:     types.ModeRepoToRepo,
+			source:   map[string]fakeResponse{"repos/src/app": writable},
+			target:   map[string]fakeResponse{"repos/dst/app": forbidden},
+			wantErr:  "target token is not allowed to access repository dst/app on github.com",
+			wantCode: exitCodeAuth,
+		},
+		{
+			name:     "read-only target repository",
+			mode:     types.ModeRepoToRepo,
+			source:   map[string]fakeResponse{"repos/src/app": readOnly},
+			target:   map[string]fakeResponse{"repos/dst/app": readOnly},
+			wantErr:  "target token can read repository dst/app on github.com but cannot write to it",
+			wantCode: exitCodeAuth,
+		},
+		{
+			name:   "read-only target repository in a dry run",
+			mode:   types.ModeRepoToRepo,
+			source: map[string]fakeResponse{"repos/src/app": readOnly},
+			target: map[string]fakeResponse{"repos/dst/app": readOnly},
+			dryRun: true,
+		},
+		{
+			name:    "targets are checked during the migration",
+			mode:    types.ModeRepoToRepo,
+			source:  map[string]fakeResponse{"repos/src/app": writable},
+			targets: []types.RepoRef{{Owner: "dst", Repo: "missing"}},
+		},
+		{
+			name:   "organizations exist",
+			mode:   types.ModeOrgToOrg,
+			source: map[string]fakeResponse{"orgs/src": org},
+			target: map[string]fakeResponse{"orgs/dst": org},
+		},
+		{
+			name:     "missing target organization",
+			mode:     types.ModeOrgToOrg,
+			source:   map[string]fakeResponse{"orgs/src": org},
+			wantErr:  "target organization dst not found on github.com",
+			wantCode: exitCodeUsage,
+		},
+		{
+			name:     "missing source organization for org to repo",
+			mode:     types.ModeOrgToRepo,
+			target:   map[string]fakeResponse{"repos/dst/app": writable},
+			wantErr:  "source organization src not found",
+			wantCode: exitCodeUsage,
+		},
+		{
+			name:   "repo to org",
+			mode:   types.ModeRepoToOrg,
+			source: map[string]fakeResponse{"repos/src/app": writable},
+			target: map[string]fakeResponse{"orgs/dst": org},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg, sourceRepo, targetRepo = "src", "dst", "app", "app"
+			targets, dryRun, diffMode = tt.targets, tt.dryRun, false
+			sourceHostname, targetHostname = "", tt.targetHost
+
+			err := checkEndpoints(fakeAPIClient(t, tt.source), fakeAPIClient(t, tt.target), migrationConfig(tt.mode))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkEndpoints() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("checkEndpoints() error = %v, want containing %q", err, tt.wantErr)
+			}
+			if got := exitCode(err); got != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_FailIfEmpty(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origOrgToRepo, origDiffMode := orgToOrg, orgToRepo, diffMode
+	origFailIfEmpty := failIfEmpty
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, orgToRepo, diffMode = origOrgToOrg, origOrgToRepo, origDiffMode
+		failIfEmpty = origFailIfEmpty
+	}()
+
+	tests := []struct {
+		name    string
+		diff    bool
+		wantErr bool
+	}{
+		{name: "migration", wantErr: false},
+		{name: "with diff", diff: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "source-org", "target-org"
+			sourceRepo, targetRepo = "app", "app"
+			orgToOrg, orgToRepo, diffMode = false, false, tt.diff
+			failIfEmpty = true
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_EnvsOnly(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origSkipEnvs, origEnvsOnly := orgToOrg, skipEnvs, envsOnly
+	origEnvName, origEnvNames, origExcludeEnvs := envName, envNames, excludeEnvs
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, skipEnvs, envsOnly = origOrgToOrg, origSkipEnvs, origEnvsOnly
+		envName, envNames, excludeEnvs = origEnvName, origEnvNames, origExcludeEnvs
+	}()
+
+	tests := []struct {
+		name        string
+		orgToOrg    bool
+		skipEnvs    bool
+		envName     string
+		envNames    []string
+		excludeEnvs []string
+		wantErr     string
+	}{
+		{name: "every environment"},
+		{name: "with envs", envNames: []string{"production", "staging"}},
+		{name: "with exclude-envs", excludeEnvs: []string{"pr-*"}},
+		{name: "combined with skip-envs", skipEnvs: true, wantErr: "--envs-only and --skip-envs cannot be used together"},
+		{name: "combined with env", envName: "production", wantErr: "cannot be combined with --envs-only"},
+		{name: "org to org", orgToOrg: true, wantErr: "--envs-only can only be used for repository-to-repository migration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "old-org", "new-org"
+			sourceRepo, targetRepo = "app", "app"
+			if tt.orgToOrg {
+				sourceRepo, targetRepo = "", ""
+			}
+			orgToOrg, skipEnvs, envsOnly = tt.orgToOrg, tt.skipEnvs, true
+			envName, envNames, excludeEnvs = tt.envName, tt.envNames, tt.excludeEnvs
+
+			err := validateFlags(rootCmd, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateFlags() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateFlags() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_MaxAPICalls(t *testing.T) {
+	origSourceOrg, origTargetOrg := sourceOrg, targetOrg
+	origSourceRepo, origTargetRepo := sourceRepo, targetRepo
+	origOrgToOrg, origOrgToRepo, origDiffMode := orgToOrg, orgToRepo, diffMode
+	origMaxAPICalls := maxAPICalls
+	defer func() {
+		sourceOrg, targetOrg = origSourceOrg, origTargetOrg
+		sourceRepo, targetRepo = origSourceRepo, origTargetRepo
+		orgToOrg, orgToRepo, diffMode = origOrgToOrg, origOrgToRepo, origDiffMode
+		maxAPICalls = origMaxAPICalls
+	}()
+
+	tests := []struct {
+		name        string
+		maxAPICalls int
+		diff        bool
+		wantErr     bool
+	}{
+		{name: "no limit", maxAPICalls: 0, wantErr: false},
+		{name: "budget", maxAPICalls: 2000, wantErr: false},
+		{name: "negative", maxAPICalls: -1, wantErr: true},
+		{name: "with diff", maxAPICalls: 2000, diff: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg, targetOrg = "source-org", "target-org"
+			sourceRepo, targetRepo = "app", "app"
+			orgToOrg, orgToRepo, diffMode = false, false, tt.diff
+			maxAPICalls = tt.maxAPICalls
+
+			err := validateFlags(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// envFileCommand returns a command with an --env-file flag naming files
+// and flags set by the LAYER_* environment variables, of which --layer-cli
+// is given on the command line
+func envFileCommand(t *testing.T, files ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{Use: "x"}
+	cmd.Flags().StringArrayVar(&envFiles, "env-file", []string{".env"}, "")
+	cmd.Flags().String("layer-cli", "", "(env: LAYER_CLI)")
+	cmd.Flags().String("layer-org", "", "(env: LAYER_ORG)")
+	cmd.Flags().Bool("layer-dry-run", false, "(env: LAYER_DRY_RUN)")
+	cmd.Flags().StringSlice("layer-repos", nil, "(env: LAYER_REPOS, comma-separated)")
+	cmd.Flags().Int("layer-max", 0, "(env: LAYER_MAX)")
+	if err := cmd.Flags().Set("layer-cli", "from-cli"); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if err := cmd.Flags().Set("env-file", file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cmd
+}
+
+// TestLoadEnvFiles tests that layered --env-file files set the flags not
+// given on the command line, later files winning, and that a missing file
+// named on the command line is fatal while a missing default .env is not
+func TestLoadEnvFiles(t *testing.T) {
+	origFiles := envFiles
+	defer func() {
+		envFiles = origFiles
+		envfile.ResetLoaded()
+	}()
+	for _, key := range []string{"LAYER_CLI", "LAYER_ORG", "LAYER_DRY_RUN", "LAYER_REPOS", "LAYER_MAX"} {
+		t.Setenv(key, "")
+		_ = os.Unsetenv(key)
+	}
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, ".env")
+	staging := filepath.Join(dir, ".env.staging")
+	if err := os.WriteFile(base, []byte("LAYER_CLI=from-file\nLAYER_ORG=base-org\nLAYER_DRY_RUN=yes\nLAYER_REPOS=api, web\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(staging, []byte("LAYER_ORG=staging-org\nLAYER_MAX=5\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("layered", func(t *testing.T) {
+		cmd := envFileCommand(t, base, staging)
+		if err := loadEnvFiles(cmd); err != nil {
+			t.Fatalf("loadEnvFiles() unexpected error: %v", err)
+		}
+		cli, _ := cmd.Flags().GetString("layer-cli")
+		org, _ := cmd.Flags().GetString("layer-org")
+		dryRun, _ := cmd.Flags().GetBool("layer-dry-run")
+		repos, _ := cmd.Flags().GetStringSlice("layer-repos")
+		maxErrors, _ := cmd.Flags().GetInt("layer-max")
+		if cli != "from-cli" || org != "staging-org" || !dryRun || maxErrors != 5 {
+			t.Errorf("flags = %q, %q, %v, %d; want from-cli, staging-org, true, 5", cli, org, dryRun, maxErrors)
+		}
+		if want := []string{"api", "web"}; !reflect.DeepEqual(repos, want) {
+			t.Errorf("--layer-repos = %v, want %v", repos, want)
+		}
+		if cmd.Flags().Changed("layer-org") {
+			t.Error("A flag set from an env file must not be marked as given on the command line")
+		}
+	})
+
+	t.Run("missing explicit file", func(t *testing.T) {
+		err := loadEnvFiles(envFileCommand(t, filepath.Join(dir, ".env.prod-migration")))
+		if err == nil || !strings.Contains(err.Error(), ".env.prod-migration") {
+			t.Errorf("loadEnvFiles() error = %v, want one naming the missing file", err)
+		}
+	})
+
+	t.Run("missing default file", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		envfile.ResetLoaded()
+		_ = os.Unsetenv("LAYER_ORG")
+		cmd := envFileCommand(t)
+		if err := loadEnvFiles(cmd); err != nil {
+			t.Errorf("loadEnvFiles() without .env unexpected error: %v", err)
+		}
+		if org, _ := cmd.Flags().GetString("layer-org"); org != "" {
+			t.Errorf("--layer-org = %q, want it left unset", org)
+		}
+	})
+}
+
+func TestPersistentPreRun_Annotations(t *testing.T) {
+	origNoAnnotations, origProfile := noAnnotations, profileName
+	defer func() {
+		noAnnotations, profileName = origNoAnnotations, origProfile
+		logger.SetAnnotations(false)
+	}()
+	profileName = ""
+
+	tests := []struct {
+		name          string
+		githubActions string
+		noAnnotations bool
+		want          bool
+	}{
+		{name: "in GitHub Actions", githubActions: "true", want: true},
+		{name: "opted out", githubActions: "true", noAnnotations: true, want: false},
+		{name: "outside GitHub Actions", githubActions: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_ACTIONS", tt.githubActions)
+			noAnnotations = tt.noAnnotations
+			if err := persistentPreRun(rootCmd, nil); err != nil {
+				t.Fatalf("persistentPreRun() unexpected error: %v", err)
+			}
+
+			out, errOut := captureStdio(t, func() {
+				logger.Warning("careful")
+				logger.VariableError("URL", "failed")
+			})
+			got := strings.Contains(out, "::warning::careful") && strings.Contains(errOut, "::error title=Variable URL::failed")
+			if got != tt.want {
+				t.Errorf("annotations written = %v, want %v; stdout %q, stderr %q", got, tt.want, out, errOut)
+			}
+		})
+	}
+}
+
+func TestPersistentPreRun_LogLevel(t *testing.T) {
+	origVerbose, origQuiet, origProfile := verbose, quiet, profileName
+	defer func() {
+		verbose, quiet, profileName = origVerbose, origQuiet, origProfile
+		logger.SetLevel(logger.LevelNormal)
+		logger.SetAnnotations(false)
+	}()
+	profileName = ""
+
+	tests := []struct {
+		name       string
+		verbose    bool
+		quiet      bool
+		wantDetail bool
+		wantDebug  bool
+		wantErr    string
+	}{
+		{name: "default", wantDetail: true},
+		{name: "verbose", verbose: true, wantDetail: true, wantDebug: true},
+		{name: "quiet", quiet: true},
+		{name: "both", verbose: true, quiet: true, wantErr: "--quiet and --verbose cannot be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verbose, quiet = tt.verbose, tt.quiet
+			err := persistentPreRun(rootCmd, nil)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("persistentPreRun() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("persistentPreRun() unexpected error: %v", err)
+			}
+
+			out, _ := captureStdio(t, func() {
+				logger.Detail("per-variable line")
+				logger.Debug("debug line")
+			})
+			if got := strings.Contains(out, "per-variable line"); got != tt.wantDetail {
+				t.Errorf("per-variable line printed = %v, want %v; output:\n%s", got, tt.wantDetail, out)
+			}
+			if got := strings.Contains(out, "debug line"); got != tt.wantDebug {
+				t.Errorf("debug line printed = %v, want %v; output:\n%s", got, tt.wantDebug, out)
+			}
+		})
+	}
+}
+
+// captureStdio runs f with standard output and standard error redirected,
+// and returns what was written to each
+func captureStdio(t *testing.T, f func()) (string, string) {
+	t.Helper()
+	oldStdout, oldStderr := os.Stdout, os.Stderr
+	rOut, wOut, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rErr, wErr, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = wOut, wErr
+
+	f()
+
+	_ = wOut.Close()
+	_ = wErr.Close()
+	os.Stdout, os.Stderr = oldStdout, oldStderr
+	stdout, _ := io.ReadAll(rOut)
+	stderr, _ := io.ReadAll(rErr)
+	return string(stdout), string(stderr)
+}
+
+func TestWriteMigrationSummary(t *testing.T) {
+	origOutput := outputFormat
+	defer func() { outputFormat = origOutput }()
+
+	r := report.New(&types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "acme", TargetOrg: "acme-new"}, time.Now(), false)
+	result := &types.MigrationResult{}
+	result.AddDetail(types.VariableResult{Scope: "", Name: "API_URL", Action: types.ActionCreated, Value: "https://api"})
+	result.IncCreated()
+	r.Finish(result, nil, time.Now())
+
+	outputFormat = output.CSV
+	var b strings.Builder
+	if err := writeMigrationSummary(&b, r); err != nil {
+		t.Fatalf("writeMigrationSummary() unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(b.String(), "scope,created,updated,unchanged,skipped,failed,deleted\n") {
+		t.Errorf("CSV summary =\n%s", b.String())
+	}
+
+	outputFormat = output.JSON
+	b.Reset()
+	if err := writeMigrationSummary(&b, r); err != nil {
+		t.Fatalf("writeMigrationSummary() unexpected error: %v", err)
+	}
+	var got report.Report
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("JSON summary is invalid: %v\n%s", err, b.String())
+	}
+	if got.Summary.Created != 1 || len(got.Variables) != 1 || got.Variables[0].Value != nil {
+		t.Errorf("JSON summary = %+v, want one created variable without its value", got)
+	}
+}
+
+// interruptedImport runs an import of three variables whose second write
+// is interrupted by a signal, with the given interrupt grace. held, when
+// set, holds that write until the interrupt handler has exited, so that the
+// handler writes the report. It returns the saved report and the exit code
+// of the handler, or -1 when it did not exit.
+func interruptedImport(t *testing.T, grace time.Duration, held bool) (*report.Report, int) {
+	t.Helper()
+	origReport, origLast, origOutput := reportFile, lastReportFile, outputFormat
+	origGrace, origNotify, origExit := interruptGrace, notifyInterrupt, exitProcess
+	defer func() {
+		reportFile, lastReportFile, outputFormat = origReport, origLast, origOutput
+		interruptGrace, notifyInterrupt, exitProcess = origGrace, origNotify, origExit
+	}()
+	reportFile = filepath.Join(t.TempDir(), "report.json")
+	lastReportFile = filepath.Join(t.TempDir(), "last.json")
+	outputFormat = output.Table
+	interruptGrace = grace
+
+	var signals chan<- os.Signal
+	notifyInterrupt = func(c chan<- os.Signal) { signals = c }
+	exitCode := make(chan int, 1)
+	exitProcess = func(code int) { exitCode <- code }
+
+	writes := 0
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		status, body := http.StatusOK, `{"total_count":0,"variables":[]}`
+		if req.Method == http.MethodPost {
+			status, body = http.StatusCreated, `{}`
+			if writes++; writes == 2 {
+				signals <- os.Interrupt
+				if held {
+					select {
+					case code := <-exitCode:
+						exitCode <- code
+					case <-time.After(5 * time.Second):
+						t.Error("The interrupt handler did not exit")
+					}
+				}
+			}
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+	c, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &types.MigrationConfig{
+		Mode:     types.ModeImport,
+		Manifest: "app.json",
+		Desired: []types.DesiredScope{{Owner: "acme", Repo: "app", Variables: []types.DesiredVariable{
+			{Name: "A", Value: "1"}, {Name: "B", Value: "2"}, {Name: "C", Value: "3"},
+		}}},
+	}
+	m, err := migrator.New(cfg, c, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	captureStdio(t, func() { _ = runMigrator(cfg, m) })
+
+	rep, err := report.Load(reportFile)
+	if err != nil {
+		t.Fatalf("The report is not readable: %v", err)
+	}
+	select {
+	case code := <-exitCode:
+		return rep, code
+	default:
+		return rep, -1
+	}
+}
+
+// TestRunMigrator_InterruptGrace lets the interrupt grace expire around the
+// time the run finishes; run with -race to detect the report being written
+// from both the handler and the run
+func TestRunMigrator_InterruptGrace(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		rep, code := interruptedImport(t, time.Duration(i)*20*time.Microsecond, false)
+		switch code {
+		case exitCodeInterrupted:
+			if !rep.Interrupted || rep.Summary.Created < 1 {
+				t.Fatalf("Report = %+v, want the interrupted run written by the handler", rep)
+			}
+		case -1:
+			if rep.Summary.Created < 2 {
+				t.Fatalf("Report = %+v, want the variables written before the run stopped", rep)
+			}
+		default:
+			t.Fatalf("Exit code = %d, want %d", code, exitCodeInterrupted)
+		}
+	}
+}
+
+// TestRunMigrator_InterruptGraceReport expects the report written by the
+// interrupt handler to hold what the run recorded so far, and to be kept
+// when the run finishes afterwards
+func TestRunMigrator_InterruptGraceReport(t *testing.T) {
+	rep, code := interruptedImport(t, time.Millisecond, true)
+	if code != exitCodeInterrupted {
+		t.Errorf("Exit code = %d, want %d", code, exitCodeInterrupted)
+	}
+	if !rep.Interrupted || rep.Summary.Created != 1 || len(rep.Variables) != 1 || rep.Variables[0].Name != "A" {
+		t.Errorf("Report = %+v, want the one variable written before the interrupt", rep)
+	}
+	if len(rep.Errors) != 1 || !strings.Contains(rep.Errors[0], "interrupted by signal: interrupt") {
+		t.Errorf("Errors = %q, want the interrupt", rep.Errors)
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// setCmd creates or updates one variable
+var setCmd = &cobra.Command{
+	Use:   "set (--org ORG | --repo OWNER/REPO [--env ENV]) --name NAME --value VALUE",
+	Short: "Create or update a variable",
+	Long: `Set one variable of an organization (--org), a repository (--repo
+OWNER/REPO, or --owner OWNER --repo REPO), or an environment of that
+repository (--env) to --value.
+
+The variable is created when it does not exist and updated when its value
+differs; a variable that already holds the value is not written. A new
+organization variable is visible to all repositories, and an existing one
+keeps its visibility and selected repositories. Authentication works as for
+list.`,
+	Example: `  # Pre-seed a canary variable before a migration
+  gh vars-migrator set --repo renan-org/app --name MIGRATION_CANARY --value 1
+
+  # Set an organization variable
+  gh vars-migrator set --org renan-org --name LOG_LEVEL --value debug`,
+	RunE:    runSet,
+	PreRunE: validateSetFlags,
+}
+
+var (
+	setOrg      string
+	setOwner    string
+	setRepo     string
+	setEnv      string
+	setName     string
+	setValue    string
+	setPAT      string
+	setHostname string
+)
+
+// setScope is the scope resolved from the set flags during validation
+var setScope types.DesiredScope
+
+func init() {
+	rootCmd.AddCommand(setCmd)
+	setCmd.Flags().StringVarP(&setOrg, "org", "o", "", "Organization of the variable")
+	setCmd.Flags().StringVar(&setRepo, "repo", "", "Repository of the variable, as OWNER/REPO or as REPO with --owner")
+	setCmd.Flags().StringVar(&setOwner, "owner", "", "Owner of --repo")
+	setCmd.Flags().StringVar(&setEnv, "env", "", "Environment of --repo holding the variable")
+	setCmd.Flags().StringVar(&setName, "name", "", "Name of the variable (required)")
+	setCmd.Flags().StringVar(&setValue, "value", "", "Value of the variable (required)")
+	setCmd.Flags().StringVar(&setPAT, "pat", "", "Personal access token; overrides GITHUB_TOKEN")
+	setCmd.Flags().StringVar(&setHostname, "hostname", "", "GitHub hostname for GitHub Enterprise Server (default: github.com)")
+	registerCompletion(setCmd, "env", environmentCompletion(&setOwner, &setRepo, &setPAT, &setHostname))
+}
+
+// validateSetFlags checks the scope, name, and value flags
+func validateSetFlags(cmd *cobra.Command, args []string) error {
+	if err := config.ValidateVariableName("--name", setName); err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("value") {
+		return fmt.Errorf("--value flag is required")
+	}
+	scope, err := variableScope(setOrg, setOwner, setRepo, setEnv)
+	if err != nil {
+		return err
+	}
+	setScope = scope
+
+	cmd.SilenceUsage = true
+	setHostname = normalizeHostname(setHostname)
+	return nil
+}
+
+func runSet(cmd *cobra.Command, args []string) error {
+	c, err := patClient(setPAT, setHostname, "set")
+	if err != nil {
+		return authError(err)
+	}
+	return setVariable(c)
+}
+
+// setVariable creates the variable, or updates it when its value differs
+func setVariable(c *client.Client) error {
+	label := setScope.Label()
+	existing, err := scopeVariable(c, setScope, setName)
+	if err != nil && !client.IsNotFound(err) {
+		return fmt.Errorf("failed to read variable %s of %s: %w", setName, label, err)
+	}
+
+	v := types.Variable{Name: setName, Value: setValue}
+	if existing == nil {
+		if err := writeScopeVariable(c, setScope, v, false); err != nil {
+			return err
+		}
+		logger.Success("Created %s in %s", setName, label)
+		return nil
+	}
+
+	if existing.Value == setValue {
+		logger.Success("%s in %s already has this value", existing.Name, label)
+		return nil
+	}
+	v.Name = existing.Name
+	if setScope.Org != "" {
+		// An update sends the visibility, so the current one is kept along
+		// with its selected repositories
+		v.Visibility = existing.Visibility
+		if v.Visibility == "selected" {
+			repos, err := c.ListOrgVariableSelectedRepos(setScope.Org, existing.Name)
+			if err != nil {
+				return fmt.Errorf("failed to list the selected repositories of %s: %w", existing.Name, err)
+			}
+			for _, r := range repos {
+				v.SelectedRepositoryIDs = append(v.SelectedRepositoryIDs, r.ID)
+			}
+		}
+	}
+	if err := writeScopeVariable(c, setScope, v, true); err != nil {
+		return err
+	}
+	logger.Success("Updated %s in %s", v.Name, label)
+	return nil
+}
+
+// writeScopeVariable creates or updates one variable of an organization,
+// repository, or environment
+func writeScopeVariable(c *client.Client, scope types.DesiredScope, v types.Variable, update bool) error {
+	switch {
+	case scope.Org != "" && update:
+		return c.UpdateOrgVariable(scope.Org, v)
+	case scope.Org != "":
+		return c.CreateOrgVariable(scope.Org, v)
+	case scope.Environment != "" && update:
+		return c.UpdateEnvVariable(scope.Owner, scope.Repo, scope.Environment, v)
+	case scope.Environment != "":
+		return c.CreateEnvVariable(scope.Owner, scope.Repo, scope.Environment, v)
+	case update:
+		return c.UpdateRepoVariable(scope.Owner, scope.Repo, v)
+	default:
+		return c.CreateRepoVariable(scope.Owner, scope.Repo, v)
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// recordingAPIClient returns a client answering "METHOD path" keys from
+// responses, with 404 for the others, and the requests it received as
+// "METHOD path body" lines
+func recordingAPIClient(t *testing.T, responses map[string]fakeResponse) (*client.Client, *[]string) {
+	t.Helper()
+	var requests []string
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		key := req.Method + " " + strings.TrimPrefix(req.URL.Path, "/")
+		line := key
+		if req.Body != nil {
+			body, _ := io.ReadAll(req.Body)
+			line += " " + string(body)
+		}
+		requests = append(requests, strings.TrimSpace(line))
+		resp, ok := responses[key]
+		if !ok {
+			resp = fakeResponse{http.StatusNotFound, `{"message":"Not Found"}`}
+		}
+		return &http.Response{
+			StatusCode: resp.status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(resp.body)),
+			Request:    req,
+		}, nil
+	})
+	c, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("NewWithTransport() unexpected error: %v", err)
+	}
+	return c, &requests
+}
+
+func TestSetVariable(t *testing.T) {
+	origScope, origName, origValue := setScope, setName, setValue
+	defer func() { setScope, setName, setValue = origScope, origName, origValue }()
+
+	created := fakeResponse{http.StatusCreated, `{}`}
+	noContent := fakeResponse{http.StatusNoContent, ``}
+
+	tests := []struct {
+		name      string
+		scope     types.DesiredScope
+		responses map[string]fakeResponse
+		wantWrite string
+	}{
+		{
+			name:      "create in a repository",
+			scope:     types.DesiredScope{Owner: "acme", Repo: "app"},
+			responses: map[string]fakeResponse{"POST repos/acme/app/actions/variables": created},
+			wantWrite: `POST repos/acme/app/actions/variables {"name":"CANARY","value":"1"}`,
+		},
+		{
+			name:  "update in a repository",
+			scope: types.DesiredScope{Owner: "acme", Repo: "app"},
+			responses: map[string]fakeResponse{
+				"GET repos/acme/app/actions/variables/CANARY":   {http.StatusOK, `{"name":"CANARY","value":"0"}`},
+				"PATCH repos/acme/app/actions/variables/CANARY": noContent,
+			},
+			wantWrite: `PATCH repos/acme/app/actions/variables/CANARY {"name":"CANARY","value":"1"}`,
+		},
+		{
+			name:  "unchanged",
+			scope: types.DesiredScope{Owner: "acme", Repo: "app"},
+			responses: map[string]fakeResponse{
+				"GET repos/acme/app/actions/variables/CANARY": {http.StatusOK, `{"name":"CANARY","value":"1"}`},
+			},
+		},
+		{
+			name:      "create in an environment",
+			scope:     types.DesiredScope{Owner: "acme", Repo: "app", Environment: "prod"},
+			responses: map[string]fakeResponse{"POST repos/acme/app/environments/prod/variables": created},
+			wantWrite: `POST repos/acme/app/environments/prod/variables {"name":"CANARY","value":"1"}`,
+		},
+		{
+			name:      "create in an organization",
+			scope:     types.DesiredScope{Org: "acme"},
+			responses: map[string]fakeResponse{"POST orgs/acme/actions/variables": created},
+			wantWrite: `POST orgs/acme/actions/variables {"name":"CANARY","value":"1","visibility":"all"}`,
+		},
+		{
+			name:  "update keeps the selected repositories",
+			scope: types.DesiredScope{Org: "acme"},
+			responses: map[string]fakeResponse{
+				"GET orgs/acme/actions/variables/CANARY":              {http.StatusOK, `{"name":"CANARY","value":"0","visibility":"selected"}`},
+				"GET orgs/acme/actions/variables/CANARY/repositories": {http.StatusOK, `{"total_count":1,"repositories":[{"id":7,"name":"app"}]}`},
+				"PATCH orgs/acme/actions/variables/CANARY":            noContent,
+			},
+			wantWrite: `PATCH orgs/acme/actions/variables/CANARY {"name":"CANARY","selected_repository_ids":[7],"value":"1","visibility":"selected"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setScope, setName, setValue = tt.scope, "CANARY", "1"
+			c, requests := recordingAPIClient(t, tt.responses)
+
+			if err := setVariable(c); err != nil {
+				t.Fatalf("setVariable() unexpected error: %v", err)
+			}
+			var writes []string
+			for _, r := range *requests {
+				if !strings.HasPrefix(r, "GET ") {
+					writes = append(writes, r)
+				}
+			}
+			var want []string
+			if tt.wantWrite != "" {
+				want = []string{tt.wantWrite}
+			}
+			if !reflect.DeepEqual(writes, want) {
+				t.Errorf("Writes = %q, want %q", writes, want)
+			}
+		})
+	}
+}
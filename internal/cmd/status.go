@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/report"
+	"github.com/spf13/cobra"
+)
+
+// statusCmd shows the outcome of the last migration from its kept report
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the outcome of the last migration",
+	Long: `Show what happened in the last migration: its mode, source and target, how
+it ended, when it started and how long it took, the counts of its summary,
+and its first errors.
+
+Every migration run, including dry runs and the runs of apply, import,
+restore, cp, and delete, keeps its report without values in
+gh-vars-migrator/last-report.json under the user cache directory
+(~/.cache on Linux), or in --last-report-file (env: LAST_REPORT_FILE) when
+set. status reads that file, or --report FILE, which may also be a
+--report-file report.
+
+--output json writes a JSON object of {report, outcome, started_at,
+finished_at, duration_seconds, config, summary, errors} to standard output
+instead, with the first --errors errors.`,
+	Example: `  # What happened in last night's migration?
+  gh vars-migrator status
+
+  # All errors of a saved report, as JSON
+  gh vars-migrator status --report migration-report.json --errors 0 --output json`,
+	Args:    cobra.NoArgs,
+	RunE:    runStatus,
+	PreRunE: validateStatusFlags,
+}
+
+var (
+	statusReport string
+	statusErrors int
+)
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVar(&statusReport, "report", "", "Report to read instead of the one kept by the last run")
+	statusCmd.Flags().IntVar(&statusErrors, "errors", 5, "Number of errors to show; 0 shows them all")
+	supportOutput(statusCmd, output.JSON)
+}
+
+// validateStatusFlags checks --errors
+func validateStatusFlags(cmd *cobra.Command, args []string) error {
+	if statusErrors < 0 {
+		return fmt.Errorf("invalid --errors %d: must not be negative", statusErrors)
+	}
+	cmd.SilenceUsage = true
+	return nil
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	path := statusReport
+	if path == "" {
+		p, err := lastReportPath()
+		if err != nil {
+			return err
+		}
+		path = p
+	}
+
+	r, err := report.Load(path)
+	if err != nil {
+		if statusReport == "" && errors.Is(err, fs.ErrNotExist) {
+			logger.Info("No migration has been run yet: there is no report in %s", path)
+			return nil
+		}
+		return err
+	}
+	return writeStatus(cmd.OutOrStdout(), path, r)
+}
+
+// migrationStatus is the --output json document of status. Errors holds
+// the first --errors errors; Summary.Errors counts them all.
+type migrationStatus struct {
+	Report          string         `json:"report"`
+	Outcome         string         `json:"outcome"`
+	StartedAt       time.Time      `json:"started_at"`
+	FinishedAt      time.Time      `json:"finished_at"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Config          report.Config  `json:"config"`
+	Summary         report.Summary `json:"summary"`
+	Errors          []string       `json:"errors"`
+}
+
+// writeStatus writes the status of the run of r, read from path, to w
+func writeStatus(w io.Writer, path string, r *report.Report) error {
+	errs := r.Errors
+	if statusErrors > 0 && len(errs) > statusErrors {
+		errs = errs[:statusErrors]
+	}
+
+	if outputFormat == output.JSON {
+		return output.WriteJSON(w, migrationStatus{
+			Report:          path,
+			Outcome:         runOutcome(r),
+			StartedAt:       r.StartedAt,
+			FinishedAt:      r.FinishedAt,
+			DurationSeconds: r.Metrics.DurationSeconds,
+			Config:          r.Config,
+			Summary:         r.Summary,
+			Errors:          errs,
+		})
+	}
+
+	target := r.Config.Target
+	if len(r.Config.Targets) > 0 {
+		target = strings.Join(r.Config.Targets, ", ")
+	}
+	duration := time.Duration(r.Metrics.DurationSeconds * float64(time.Second)).Round(time.Millisecond)
+
+	fmt.Fprintf(w, "Last migration (%s)\n", path)
+	fmt.Fprintf(w, "  %-12s %s\n", "Outcome:", runOutcome(r))
+	fmt.Fprintf(w, "  %-12s %s\n", "Started:", r.StartedAt.Local().Format("2006-01-02 15:04:05 MST"))
+	fmt.Fprintf(w, "  %-12s %s\n", "Duration:", duration)
+	fmt.Fprintf(w, "  %-12s %s\n", "Mode:", r.Config.Mode)
+	fmt.Fprintf(w, "  %-12s %s\n", "Source:", r.Config.Source)
+	fmt.Fprintf(w, "  %-12s %s\n", "Target:", orDash(target))
+	fmt.Fprintf(w, "  %-12s %s\n", "Dry run:", yesNo(r.Config.DryRun))
+	fmt.Fprintf(w, "  %-12s %s\n", "On conflict:", r.Config.OnConflict)
+	s := r.Summary
+	fmt.Fprintf(w, "\nCreated: %d, Updated: %d, Unchanged: %d, Skipped: %d, Failed: %d, Filtered: %d, Deleted: %d\n",
+		s.Created, s.Updated, s.Unchanged, s.Skipped, s.Failed, s.Filtered, s.Deleted)
+
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	if len(errs) < len(r.Errors) {
+		fmt.Fprintf(w, "\nErrors (first %d of %d):\n", len(errs), len(r.Errors))
+	} else {
+		fmt.Fprintf(w, "\nErrors (%d):\n", len(r.Errors))
+	}
+	for _, e := range errs {
+		fmt.Fprintf(w, "  - %s\n", e)
+	}
+	return nil
+}
+
+// runOutcome says how the run of r ended
+func runOutcome(r *report.Report) string {
+	switch {
+	case r.Interrupted:
+		return "interrupted"
+	case r.Aborted:
+		return "aborted at user request"
+	case r.ErrorLimitReached:
+		return "stopped by --max-errors"
+	case r.BudgetExhausted:
+		return "stopped by --max-api-calls"
+	case r.Summary.Errors > 0:
+		return fmt.Sprintf("finished with %d error(s)", r.Summary.Errors)
+	case r.Empty:
+		return "no source variables to migrate"
+	}
+	return "completed"
+}
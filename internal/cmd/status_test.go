@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/report"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// TestStatus tests that the report kept by a run is read back by status
+// from the default location and from --last-report-file, that --report
+// overrides it, and that a missing default report is not an error
+func TestStatus(t *testing.T) {
+	origLast, origReport, origErrors, origOutput := lastReportFile, statusReport, statusErrors, outputFormat
+	defer func() {
+		lastReportFile, statusReport, statusErrors, outputFormat = origLast, origReport, origErrors, origOutput
+	}()
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+	lastReportFile, statusReport, statusErrors, outputFormat = "", "", 2, output.Table
+
+	stdout, _ := captureStdio(t, func() {
+		if err := runStatus(statusCmd, nil); err != nil {
+			t.Errorf("runStatus() without a report unexpected error: %v", err)
+		}
+	})
+	if !strings.Contains(stdout, "No migration has been run yet") {
+		t.Errorf("Expected status to say no migration was run, got %q", stdout)
+	}
+
+	start := time.Date(2026, 10, 16, 2, 0, 0, 0, time.UTC)
+	cfg := &types.MigrationConfig{Mode: types.ModeRepoToRepo, SourceOwner: "src", SourceRepo: "app", TargetOwner: "dst", TargetRepo: "app"}
+	rep := report.New(cfg, start, false)
+	rep.Finish(&types.MigrationResult{
+		Created: 3, Updated: 1,
+		Errors: []error{errors.New("env:production/URL: 403"), errors.New("env:staging/URL: 403"), errors.New("env:qa/URL: 403")},
+	}, nil, start.Add(12300*time.Millisecond))
+	saveLastReport(rep)
+
+	defaultPath, err := report.DefaultLastPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(defaultPath, cache) {
+		t.Fatalf("DefaultLastPath() = %s, want it under %s", defaultPath, cache)
+	}
+	stdout, _ = captureStdio(t, func() {
+		if err := runStatus(statusCmd, nil); err != nil {
+			t.Errorf("runStatus() unexpected error: %v", err)
+		}
+	})
+	for _, want := range []string{
+		"Last migration (" + defaultPath + ")",
+		"Outcome:     finished with 3 error(s)",
+		"Duration:    12.3s",
+		"Source:      src/app",
+		"Target:      dst/app",
+		"Created: 3, Updated: 1,",
+		"Errors (first 2 of 3):\n  - env:production/URL: 403\n  - env:staging/URL: 403\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected status to contain %q, got:\n%s", want, stdout)
+		}
+	}
+
+	// A run with --last-report-file keeps its report there instead, and
+	// --report reads any report
+	lastReportFile = filepath.Join(t.TempDir(), "nightly", "last.json")
+	rep.Config.DryRun = true
+	saveLastReport(rep)
+	statusReport, outputFormat = lastReportFile, output.JSON
+	stdout, _ = captureStdio(t, func() {
+		if err := runStatus(statusCmd, nil); err != nil {
+			t.Errorf("runStatus() --report unexpected error: %v", err)
+		}
+	})
+	var got migrationStatus
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("status --output json is not JSON: %v\n%s", err, stdout)
+	}
+	if got.Report != lastReportFile || !got.Config.DryRun || got.Summary.Errors != 3 || len(got.Errors) != 2 || got.DurationSeconds != 12.3 {
+		t.Errorf("status --output json = %+v", got)
+	}
+
+	statusReport = filepath.Join(t.TempDir(), "missing.json")
+	if err := runStatus(statusCmd, nil); err == nil {
+		t.Error("Expected an error for a missing --report file")
+	}
+}
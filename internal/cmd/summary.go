@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/report"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// stepSummaryEnv names the file GitHub Actions renders as the Markdown
+// summary of a workflow step
+const stepSummaryEnv = "GITHUB_STEP_SUMMARY"
+
+// appendStepSummary appends the Markdown summary of a finished run to the
+// GITHUB_STEP_SUMMARY file. A failure only warns, since the run itself is
+// over.
+func appendStepSummary(cfg *types.MigrationConfig, r *report.Report) {
+	path := os.Getenv(stepSummaryEnv)
+	if path == "" {
+		logger.Warning("--github-summary is set but %s is not; no step summary written", stepSummaryEnv)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err == nil {
+		err = writeStepSummary(f, config.GetDescription(cfg), r)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		logger.Warning("Could not write the step summary: %v", err)
+		return
+	}
+	logger.Debug("Wrote the step summary to %s", path)
+}
+
+// writeStepSummary writes the Markdown section of the run of r: its
+// description and outcome, a table of the counts per scope, and collapsible
+// lists of its errors and skipped variables
+func writeStepSummary(w io.Writer, description string, r *report.Report) error {
+	var b strings.Builder
+	b.WriteString("## Variables migration\n\n")
+	fmt.Fprintf(&b, "**%s**", markdownText(description))
+	if r.Config.DryRun {
+		b.WriteString(" (dry run)")
+	}
+	b.WriteString("\n\n")
+	duration := time.Duration(r.Metrics.DurationSeconds * float64(time.Second)).Round(time.Millisecond)
+	fmt.Fprintf(&b, "Outcome: %s in %s\n\n", runOutcome(r), duration)
+
+	if len(r.Scopes) == 0 {
+		b.WriteString("No variable was processed.\n\n")
+	} else {
+		b.WriteString("| Scope | Created | Updated | Unchanged | Skipped | Failed | Deleted |\n")
+		b.WriteString("|-------|--------:|--------:|----------:|--------:|-------:|--------:|\n")
+		var total report.Scope
+		for _, s := range r.Scopes {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d | %d |\n",
+				markdownCell(s.Scope), s.Created, s.Updated, s.Unchanged, s.Skipped, s.Failed, s.Deleted)
+			total.Created += s.Created
+			total.Updated += s.Updated
+			total.Unchanged += s.Unchanged
+			total.Skipped += s.Skipped
+			total.Failed += s.Failed
+			total.Deleted += s.Deleted
+		}
+		fmt.Fprintf(&b, "| **Total** | %d | %d | %d | %d | %d | %d |\n\n",
+			total.Created, total.Updated, total.Unchanged, total.Skipped, total.Failed, total.Deleted)
+	}
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintf(&b, "<details><summary>Errors (%d)</summary>\n\n", len(r.Errors))
+		for _, e := range r.Errors {
+			fmt.Fprintf(&b, "- %s\n", markdownText(e))
+		}
+		b.WriteString("\n</details>\n\n")
+	}
+
+	var skipped []report.Variable
+	for _, v := range r.Variables {
+		if v.Action == types.ActionSkipped {
+			skipped = append(skipped, v)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&b, "<details><summary>Skipped variables (%d)</summary>\n\n", len(skipped))
+		b.WriteString("| Scope | Variable | Reason |\n")
+		b.WriteString("|-------|----------|--------|\n")
+		for _, v := range skipped {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(v.Scope), markdownCell(v.Name), markdownCell(v.Reason))
+		}
+		b.WriteString("\n</details>\n\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownText makes s safe to show as one line of Markdown: line breaks
+// become spaces and angle brackets are escaped, so that it cannot close the
+// surrounding HTML
+var markdownText = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "<", "&lt;", ">", "&gt;").Replace
+
+// markdownCell makes s safe to show in a Markdown table cell
+func markdownCell(s string) string {
+	return strings.ReplaceAll(markdownText(s), "|", `\|`)
+}
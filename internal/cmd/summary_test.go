@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/report"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func TestWriteStepSummary(t *testing.T) {
+	start := time.Date(2026, 10, 16, 2, 0, 0, 0, time.UTC)
+	cfg := &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "acme", TargetOrg: "neworg", DryRun: true}
+	rep := report.New(cfg, start, false)
+	result := &types.MigrationResult{Created: 1, Skipped: 1, Errors: []error{errors.New("org:neworg variable 'BAD': <403> forbidden\nretry later")}}
+	result.AddDetail(types.VariableResult{Scope: "organization", Name: "REGION", Action: types.ActionCreated})
+	result.AddDetail(types.VariableResult{Scope: "organization", Name: "URL", Action: types.ActionSkipped, Reason: "exists in target (a|b)"})
+	result.AddDetail(types.VariableResult{Scope: "organization", Name: "BAD", Action: types.ActionFailed, Reason: "403"})
+	rep.Finish(result, nil, start.Add(1500*time.Millisecond))
+
+	var b strings.Builder
+	if err := writeStepSummary(&b, config.GetDescription(cfg), rep); err != nil {
+		t.Fatalf("writeStepSummary() unexpected error: %v", err)
+	}
+	want := `## Variables migration
+
+**Organization acme → neworg** (dry run)
+
+Outcome: finished with 1 error(s) in 1.5s
+
+| Scope | Created | Updated | Unchanged | Skipped | Failed | Deleted |
+|-------|--------:|--------:|----------:|--------:|-------:|--------:|
+| organization | 1 | 0 | 0 | 1 | 1 | 0 |
+| **Total** | 1 | 0 | 0 | 1 | 1 | 0 |
+
+<details><summary>Errors (1)</summary>
+
+- org:neworg variable 'BAD': &lt;403&gt; forbidden retry later
+
+</details>
+
+<details><summary>Skipped variables (1)</summary>
+
+| Scope | Variable | Reason |
+|-------|----------|--------|
+| organization | URL | exists in target (a\|b) |
+
+</details>
+
+`
+	if got := b.String(); got != want {
+		t.Errorf("writeStepSummary() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestRunMigrator_StepSummary runs an import whose update fails and
+// expects its summary appended to GITHUB_STEP_SUMMARY all the same
+func TestRunMigrator_StepSummary(t *testing.T) {
+	origLast, origSummary, origOutput := lastReportFile, githubSummary, outputFormat
+	defer func() { lastReportFile, githubSummary, outputFormat = origLast, origSummary, origOutput }()
+	lastReportFile = filepath.Join(t.TempDir(), "last.json")
+	outputFormat = output.Table
+
+	summary := filepath.Join(t.TempDir(), "step-summary.md")
+	if err := os.WriteFile(summary, []byte("Earlier step\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(stepSummaryEnv, summary)
+
+	c := fakeAPIClient(t, map[string]fakeResponse{
+		"repos/acme/app/actions/variables": {http.StatusOK, `{"total_count":1,"variables":[{"name":"URL","value":"old"}]}`},
+	})
+	cfg := &types.MigrationConfig{
+		Mode:       types.ModeImport,
+		Manifest:   "app.json",
+		OnConflict: types.ConflictOverwrite,
+		Desired:    []types.DesiredScope{{Owner: "acme", Repo: "app", Variables: []types.DesiredVariable{{Name: "URL", Value: "new"}}}},
+	}
+	m, err := migrator.New(cfg, c, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, enabled := range []bool{false, true} {
+		githubSummary = enabled
+		var runErr error
+		captureStdio(t, func() { runErr = runMigrator(cfg, m) })
+		var exitErr *exitError
+		if !errors.As(runErr, &exitErr) || exitErr.code != exitCodePartial {
+			t.Fatalf("runMigrator() = %v, want a partial failure", runErr)
+		}
+	}
+
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		"Earlier step\n\n## Variables migration\n\n**Import app.json → acme/app**\n",
+		"| acme/app | 0 | 0 | 0 | 0 | 1 | 0 |",
+		"<details><summary>Errors (1)</summary>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected the step summary to contain %q, got:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, "## Variables migration"); n != 1 {
+		t.Errorf("Expected one summary, written only with --github-summary, got %d", n)
+	}
+}
@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/migrator"
+	"github.com/renan-alm/gh-vars-migrator/internal/output"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// validateCmd runs the checks a migration starts with, without migrating
+var validateCmd = &cobra.Command{
+	Use:   "validate --source-org ORG --target-org ORG [flags]",
+	Short: "Check the credentials, access, and scopes of a migration without running it",
+	Long: `Run the checks a migration starts with and report each as passed or failed,
+without writing anything or printing any variable:
+
+  - both tokens authenticate
+  - both tokens have the scopes the migration mode needs
+  - the source and target organizations or repositories exist, and a target
+    repository is writable
+  - the source environments to migrate are found (repository migrations)
+  - the variables of the source and target are counted
+
+Pass the source, target, and mode flags of the migration to check; --env,
+--envs, --exclude-envs, --envs-only, and --skip-envs select the environments
+as they would for the migration. Nothing is written to the target.
+--output json writes a JSON array of {check, status, detail} objects instead
+of the checklist, and --output csv the same columns as CSV. The command exits
+non-zero when any check fails, with the exit code the migration
+would have stopped with (2 for authentication and permission failures).`,
+	Example: `  # Check a repository migration before its window
+  gh vars-migrator validate --source-org myorg --source-repo app --target-org targetorg --target-repo app
+
+  # Check an organization migration on GitHub Enterprise Server
+  gh vars-migrator validate --source-org myorg --target-org targetorg --org-to-org --target-hostname github.example.com`,
+	PreRunE:       validateValidateFlags,
+	RunE:          runValidate,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	supportOutput(validateCmd, output.JSON, output.CSV)
+	// The migration flags are added by the root command once it has
+	// registered them.
+}
+
+// validateFlagNames are the flags validate accepts: the endpoints and
+// credentials of both sides or a profile holding them, the mode, and the
+// environment selection
+var validateFlagNames = map[string]bool{
+	"source": true, "source-org": true, "source-repo": true, "source-pat": true, "source-pat-file": true, "source-hostname": true, "no-detect": true,
+	"target": true, "target-org": true, "target-repo": true, "target-pat": true, "target-pat-file": true, "target-hostname": true, "no-keyring": true,
+	"org-to-org": true, "org-to-repo": true, "repo-to-org": true,
+	"env": true, "source-env": true, "target-env": true, "envs": true, "exclude-envs": true, "envs-only": true, "skip-envs": true,
+	"config": true, "profile": true,
+	"verbose": true, "quiet": true, "no-color": true, "no-annotations": true, "env-file": true, "output": true,
+}
+
+// validateValidateFlags checks the migration flags the checks run with
+func validateValidateFlags(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if err := rejectFlags(cmd, "validate", func(name string) bool { return validateFlagNames[name] }); err != nil {
+		return err
+	}
+	if err := applyRefs(cmd); err != nil {
+		return err
+	}
+	// Without a target either, validateFlags would print the help; with
+	// one, it detects the source from the working directory
+	if sourceOrg == "" && targetOrg == "" {
+		return fmt.Errorf("--source-org or --source flag is required")
+	}
+	return validateFlags(cmd, args)
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	sourceToken, targetToken, err := resolveTokens()
+	if err != nil {
+		return authError(err)
+	}
+	sourceClient, targetClient, err := createClients(sourceToken, targetToken)
+	if err != nil {
+		return authError(err)
+	}
+
+	checks := preflight(migrationConfig(detectMigrationMode()), sourceClient, targetClient)
+	return reportPreflight(cmd.OutOrStdout(), checks)
+}
+
+// preflightCheck is one line of the validate checklist. A check is skipped
+// when one it depends on failed.
+type preflightCheck struct {
+	name    string
+	detail  string
+	err     error
+	skipped bool
+}
+
+// preflight runs the checks of a migration configured by cfg in order.
+// Nothing past authentication runs without it, and the environments and
+// counts need the source and target to exist.
+func preflight(cfg *types.MigrationConfig, sourceClient, targetClient *client.Client) []preflightCheck {
+	var checks []preflightCheck
+	run := func(name string, blocked bool, check func() (string, error)) bool {
+		c := preflightCheck{name: name, skipped: blocked}
+		if !blocked {
+			c.detail, c.err = check()
+		}
+		checks = append(checks, c)
+		return !blocked && c.err == nil
+	}
+
+	authenticated := run("Authentication", false, func() (string, error) {
+		if err := validateAuth(sourceClient, targetClient); err != nil {
+			return "", authError(err)
+		}
+		return "source and target tokens are valid", nil
+	})
+	run("Token permissions", !authenticated, func() (string, error) {
+		if err := validatePermissions(sourceClient, targetClient, cfg.Mode); err != nil {
+			return "", authError(err)
+		}
+		return fmt.Sprintf("scopes allow %s", cfg.Mode), nil
+	})
+	found := run("Source and target access", !authenticated, func() (string, error) {
+		if err := checkEndpoints(sourceClient, targetClient, cfg); err != nil {
+			return "", err
+		}
+		source, target := config.Endpoints(cfg)
+		return fmt.Sprintf("%s and %s found", source, target), nil
+	})
+
+	var environments []types.Environment
+	if cfg.Mode == types.ModeRepoToRepo {
+		run("Environments", !found, func() (string, error) {
+			if cfg.SkipEnvs {
+				return "skipped with --skip-envs", nil
+			}
+			m, err := migrator.New(cfg, sourceClient, targetClient)
+			if err != nil {
+				return "", err
+			}
+			if environments, err = m.SourceEnvironments(); err != nil {
+				return "", err
+			}
+			names := make([]string, 0, len(environments))
+			for _, env := range environments {
+				names = append(names, env.Name)
+			}
+			if len(names) == 0 {
+				return "no environments to migrate", nil
+			}
+			return fmt.Sprintf("%d to migrate: %s", len(names), strings.Join(names, ", ")), nil
+		})
+	}
+
+	run("Variable counts", !found, func() (string, error) {
+		return countVariables(cfg, sourceClient, targetClient, environments)
+	})
+	return checks
+}
+
+// countVariables counts the variables of the source and target scopes of
+// the migration, and those of the source environments to migrate
+func countVariables(cfg *types.MigrationConfig, sourceClient, targetClient *client.Client, environments []types.Environment) (string, error) {
+	var sourceVars, targetVars []types.Variable
+	var err error
+	switch cfg.Mode {
+	case types.ModeOrgToOrg, types.ModeOrgToRepo:
+		sourceVars, err = sourceClient.ListOrgVariables(cfg.SourceOrg)
+	default:
+		sourceVars, err = sourceClient.ListRepoVariables(cfg.SourceOwner, cfg.SourceRepo)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to count source variables: %w", err)
+	}
+	switch cfg.Mode {
+	case types.ModeOrgToOrg, types.ModeRepoToOrg:
+		targetVars, err = targetClient.ListOrgVariables(cfg.TargetOrg)
+	default:
+		targetVars, err = targetClient.ListRepoVariables(cfg.TargetOwner, cfg.TargetRepo)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to count target variables: %w", err)
+	}
+
+	detail := fmt.Sprintf("source %d", len(sourceVars))
+	if len(environments) > 0 {
+		envVars := 0
+		for _, env := range environments {
+			n, err := sourceClient.CountEnvVariables(cfg.SourceOwner, cfg.SourceRepo, env.Name)
+			if err != nil {
+				return "", fmt.Errorf("failed to count the variables of environment %s: %w", env.Name, err)
+			}
+			envVars += n
+		}
+		detail += fmt.Sprintf(" (+%d in %d environment(s))", envVars, len(environments))
+	}
+	return detail + fmt.Sprintf(", target %d", len(targetVars)), nil
+}
+
+// Statuses of a check in the --output json and csv of validate
+const (
+	checkPassed  = "passed"
+	checkFailed  = "failed"
+	checkSkipped = "skipped"
+)
+
+// checkResult is one check of the --output json and csv of validate. Detail
+// is the error of a failed check.
+type checkResult struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// result returns the --output json and csv form of the check
+func (c preflightCheck) result() checkResult {
+	switch {
+	case c.skipped:
+		return checkResult{Check: c.name, Status: checkSkipped}
+	case c.err != nil:
+		return checkResult{Check: c.name, Status: checkFailed, Detail: c.err.Error()}
+	}
+	return checkResult{Check: c.name, Status: checkPassed, Detail: c.detail}
+}
+
+// reportPreflight writes the checklist, or its --output json or csv form, to
+// w and fails with the exit code of the first failed check
+func reportPreflight(w io.Writer, checks []preflightCheck) error {
+	logger.Plain("")
+	logger.Info("Preflight checklist:")
+
+	var firstErr error
+	failed := 0
+	for _, c := range checks {
+		if c.err != nil {
+			if firstErr == nil {
+				firstErr = c.err
+			}
+			failed++
+		}
+	}
+	if outputFormat == output.Table {
+		writeChecklist(w, checks)
+	} else if err := writeCheckResults(w, checks); err != nil {
+		return err
+	}
+	logger.Plain("")
+
+	if failed > 0 {
+		return &exitError{code: exitCode(firstErr), err: fmt.Errorf("%d of %d preflight check(s) failed", failed, len(checks))}
+	}
+	logger.Success("All %d preflight checks passed", len(checks))
+	return nil
+}
+
+// writeChecklist writes one line per check, marked as passed, failed, or
+// skipped
+func writeChecklist(w io.Writer, checks []preflightCheck) {
+	for _, c := range checks {
+		switch {
+		case c.skipped:
+			fmt.Fprintf(w, "  - %s: skipped\n", c.name)
+		case c.err != nil:
+			// Indent the hints of multi-line errors under their check
+			fmt.Fprintf(w, "  ✗ %s: %s\n", c.name, strings.ReplaceAll(c.err.Error(), "\n", "\n    "))
+		default:
+			fmt.Fprintf(w, "  ✓ %s: %s\n", c.name, c.detail)
+		}
+	}
+}
+
+// writeCheckResults writes the checks to w with --output json or csv
+func writeCheckResults(w io.Writer, checks []preflightCheck) error {
+	results := make([]checkResult, 0, len(checks))
+	for _, c := range checks {
+		results = append(results, c.result())
+	}
+	switch outputFormat {
+	case output.JSON:
+		return output.WriteJSON(w, results)
+	case output.CSV:
+		rows := make([][]string, 0, len(results))
+		for _, r := range results {
+			rows = append(rows, []string{r.Check, r.Status, r.Detail})
+		}
+		return output.WriteCSV(w, []string{"check", "status", "detail"}, rows)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// preflightClient returns a client answering GET requests from responses,
+// with scopes as the token's OAuth scopes, that fails the test on any write
+func preflightClient(t *testing.T, responses map[string]fakeResponse, scopes string) *client.Client {
+	t.Helper()
+	fake := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet {
+			t.Errorf("validate must not write, got %s %s", req.Method, req.URL.Path)
+		}
+		resp, ok := responses[strings.TrimPrefix(req.URL.Path, "/")]
+		if !ok {
+			resp = fakeResponse{http.StatusNotFound, `{"message":"Not Found"}`}
+		}
+		header := http.Header{"Content-Type": []string{"application/json"}}
+		if scopes != "" {
+			header.Set("X-OAuth-Scopes", scopes)
+		}
+		return &http.Response{
+			StatusCode: resp.status,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader(resp.body)),
+			Request:    req,
+		}, nil
+	})
+	c, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("NewWithTransport() unexpected error: %v", err)
+	}
+	return c
+}
+
+// TestPreflight fails each check of validate in turn and checks the
+// checklist and the exit code
+func TestPreflight(t *testing.T) {
+	origSourceOrg, origTargetOrg, origSourceRepo, origTargetRepo := sourceOrg, targetOrg, sourceRepo, targetRepo
+	origTargets, origDryRun, origDiff := targets, dryRun, diffMode
+	defer func() {
+		sourceOrg, targetOrg, sourceRepo, targetRepo = origSourceOrg, origTargetOrg, origSourceRepo, origTargetRepo
+		targets, dryRun, diffMode = origTargets, origDryRun, origDiff
+	}()
+	sourceOrg, targetOrg, sourceRepo, targetRepo = "acme", "other", "app", "app"
+	targets, dryRun, diffMode = nil, false, false
+
+	writable := fakeResponse{http.StatusOK, `{"name":"app","permissions":{"admin":false,"push":true,"pull":true}}`}
+	source := func() map[string]fakeResponse {
+		return map[string]fakeResponse{
+			"user":                             {http.StatusOK, `{"login":"alice"}`},
+			"repos/acme/app":                   writable,
+			"repos/acme/app/environments":      {http.StatusOK, `{"total_count":2,"environments":[{"name":"prod"},{"name":"dev"}]}`},
+			"repos/acme/app/actions/variables": {http.StatusOK, `{"total_count":2,"variables":[{"name":"A"},{"name":"B"}]}`},
+			"repos/acme/app/environments/prod/variables": {http.StatusOK, `{"total_count":3,"variables":[]}`},
+			"repos/acme/app/environments/dev/variables":  {http.StatusOK, `{"total_count":1,"variables":[]}`},
+		}
+	}
+	target := func() map[string]fakeResponse {
+		return map[string]fakeResponse{
+			"user":                              {http.StatusOK, `{"login":"bob"}`},
+			"repos/other/app":                   writable,
+			"repos/other/app/actions/variables": {http.StatusOK, `{"total_count":1,"variables":[{"name":"A"}]}`},
+		}
+	}
+
+	const (
+		authOK   = "  ✓ Authentication: source and target tokens are valid\n"
+		scopesOK = "  ✓ Token permissions: scopes allow repo-to-repo\n"
+		accessOK = "  ✓ Source and target access: acme/app and other/app found\n"
+		envsOK   = "  ✓ Environments: 2 to migrate: prod, dev\n"
+		countsOK = "  ✓ Variable counts: source 2 (+4 in 2 environment(s)), target 1\n"
+	)
+
+	tests := []struct {
+		name         string
+		envs         []string
+		source       func(map[string]fakeResponse)
+		target       func(map[string]fakeResponse)
+		targetScopes string
+		want         []string
+		wantCode     int
+	}{
+		{
+			name: "all pass",
+			want: []string{authOK, scopesOK, accessOK, envsOK, countsOK},
+		},
+		{
+			name: "authentication",
+			target: func(r map[string]fakeResponse) {
+				r["user"] = fakeResponse{http.StatusUnauthorized, `{"message":"Bad credentials"}`}
+			},
+			want:     []string{"  ✗ Authentication: target authentication failed", "  - Token permissions: skipped\n", "  - Source and target access: skipped\n", "  - Environments: skipped\n", "  - Variable counts: skipped\n"},
+			wantCode: exitCodeAuth,
+		},
+		{
+			name:         "permissions",
+			targetScopes: "read:org",
+			want:         []string{authOK, `  ✗ Token permissions: target token is missing required scope "repo"`, accessOK, envsOK, countsOK},
+			wantCode:     exitCodeAuth,
+		},
+		{
+			name:     "missing target repository",
+			target:   func(r map[string]fakeResponse) { delete(r, "repos/other/app") },
+			want:     []string{authOK, scopesOK, "  ✗ Source and target access: target repository other/app not found", "  - Environments: skipped\n", "  - Variable counts: skipped\n"},
+			wantCode: exitCodeUsage,
+		},
+		{
+			name:     "missing environment",
+			envs:     []string{"staging"},
+			want:     []string{authOK, scopesOK, accessOK, "  ✗ Environments: environment(s) not found in source repository acme/app: staging\n", "  ✓ Variable counts: source 2, target 1\n"},
+			wantCode: exitCodeUsage,
+		},
+		{
+			name:     "counts",
+			source:   func(r map[string]fakeResponse) { delete(r, "repos/acme/app/environments/dev/variables") },
+			want:     []string{authOK, scopesOK, accessOK, envsOK, "  ✗ Variable counts: failed to count the variables of environment dev"},
+			wantCode: exitCodeUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceResponses, targetResponses := source(), target()
+			if tt.source != nil {
+				tt.source(sourceResponses)
+			}
+			if tt.target != nil {
+				tt.target(targetResponses)
+			}
+			targetScopes := tt.targetScopes
+			if targetScopes == "" {
+				targetScopes = "repo"
+			}
+			cfg := &types.MigrationConfig{
+				Mode:        types.ModeRepoToRepo,
+				SourceOrg:   "acme",
+				TargetOrg:   "other",
+				SourceOwner: "acme",
+				SourceRepo:  "app",
+				TargetOwner: "other",
+				TargetRepo:  "app",
+				Envs:        tt.envs,
+			}
+
+			var b strings.Builder
+			checks := preflight(cfg, preflightClient(t, sourceResponses, "repo"), preflightClient(t, targetResponses, targetScopes))
+			err := reportPreflight(&b, checks)
+
+			// Continuation lines of multi-line errors are indented further
+			var lines []string
+			for _, line := range strings.SplitAfter(b.String(), "\n") {
+				if line != "" && !strings.HasPrefix(line, "    ") {
+					lines = append(lines, line)
+				}
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("Checklist has %d lines, want %d:\n%s", len(lines), len(tt.want), b.String())
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(lines[i], want) {
+					t.Errorf("Line %d = %q, want starting with %q", i+1, lines[i], want)
+				}
+			}
+			if code := exitCode(err); code != tt.wantCode {
+				t.Errorf("exitCode() = %d, want %d (err: %v)", code, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestValidateValidateFlags(t *testing.T) {
+	origSourceOrg := sourceOrg
+	defer func() { sourceOrg = origSourceOrg }()
+
+	tests := []struct {
+		name      string
+		sourceOrg string
+		flag      string
+		wantErr   string
+	}{
+		{name: "missing source", wantErr: "--source-org or --source flag is required"},
+		{name: "write flag", sourceOrg: "acme", flag: "dry-run", wantErr: "--dry-run cannot be combined with validate"},
+		{name: "fan-out", sourceOrg: "acme", flag: "fan-out", wantErr: "--fan-out cannot be combined with validate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceOrg = tt.sourceOrg
+			cmd := &cobra.Command{Use: "validate"}
+			if tt.flag != "" {
+				cmd.Flags().String(tt.flag, "", "")
+				if err := cmd.Flags().Set(tt.flag, "x"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := validateValidateFlags(cmd, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateValidateFlags() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
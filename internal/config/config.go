@@ -3,6 +3,11 @@ package config
 import (
 	"errors"
 	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/renan-alm/gh-vars-migrator/internal/types"
 )
@@ -13,11 +18,92 @@ func Validate(cfg *types.MigrationConfig) error {
 		return errors.New("configuration is nil")
 	}
 
+	if err := ValidatePatterns(cfg.Include, cfg.Exclude); err != nil {
+		return err
+	}
+	if err := ValidateFilterRegex(cfg.FilterRegex); err != nil {
+		return err
+	}
+	if err := ValidateNameAffixes(cfg.TargetPrefix, cfg.TargetSuffix); err != nil {
+		return err
+	}
+	if err := ValidateNameMap(cfg.NameMap); err != nil {
+		return err
+	}
+	if err := ValidateValueOverrides(cfg.ValueOverrides); err != nil {
+		return err
+	}
+	if err := ValidateConflictStrategy(cfg.OnConflict, cfg.SkipOverwrite); err != nil {
+		return err
+	}
+	if cfg.Deep && cfg.Mode != types.ModeOrgToOrg {
+		return errors.New("deep migration is only supported in org-to-org mode")
+	}
+	if cfg.Visibility != "" && cfg.Mode != types.ModeOrgToOrg {
+		return errors.New("a visibility override is only supported in org-to-org mode")
+	}
+	if err := ValidateVisibility(cfg.Visibility); err != nil {
+		return err
+	}
+	if cfg.SelectedFallback != "" && cfg.Mode != types.ModeOrgToOrg {
+		return errors.New("a selected fallback is only supported in org-to-org mode")
+	}
+	if err := ValidateSelectedFallback(cfg.SelectedFallback); err != nil {
+		return err
+	}
+	if len(cfg.RepoMap) > 0 {
+		if cfg.Mode != types.ModeOrgToOrg {
+			return errors.New("a repository map is only supported in org-to-org mode")
+		}
+		if err := ValidateRepoMap(cfg.RepoMap); err != nil {
+			return err
+		}
+	}
+	if len(cfg.Envs) > 0 && cfg.Mode != types.ModeRepoToRepo {
+		return errors.New("environment selection is only supported in repo-to-repo mode")
+	}
+	if len(cfg.EnvMap) > 0 {
+		if cfg.Mode != types.ModeRepoToRepo && !cfg.Deep {
+			return errors.New("environment mapping is only supported in repo-to-repo mode and deep migrations")
+		}
+		if cfg.SkipEnvs {
+			return errors.New("environment mapping cannot be combined with skipping environments")
+		}
+		if err := ValidateEnvMap(cfg.EnvMap); err != nil {
+			return err
+		}
+	}
+	if len(cfg.ExcludeEnvs) > 0 {
+		if cfg.Mode != types.ModeRepoToRepo && !cfg.Deep {
+			return errors.New("environment exclusion is only supported in repo-to-repo mode and deep migrations")
+		}
+		if len(cfg.Envs) > 0 {
+			return errors.New("environment selection cannot be combined with environment exclusion")
+		}
+		if err := ValidateEnvPatterns(cfg.ExcludeEnvs); err != nil {
+			return err
+		}
+	}
+
 	switch cfg.Mode {
 	case types.ModeRepoToRepo:
 		return validateRepoToRepo(cfg)
 	case types.ModeOrgToOrg:
 		return validateOrgToOrg(cfg)
+	case types.ModeOrgToRepo:
+		return validateOrgToRepo(cfg)
+	case types.ModeRepoToOrg:
+		return validateRepoToOrg(cfg)
+	case types.ModeFanOut:
+		return validateFanOut(cfg)
+	case types.ModeManifest:
+		return validateManifest(cfg)
+	case types.ModeImport:
+		return validateImport(cfg)
+	case types.ModeDelete:
+		return validateDelete(cfg)
+	case types.ModeCopy:
+		return validateCopy(cfg)
 	default:
 		return fmt.Errorf("invalid migration mode: %s", cfg.Mode)
 	}
@@ -31,6 +117,18 @@ func validateRepoToRepo(cfg *types.MigrationConfig) error {
 	if cfg.SourceRepo == "" {
 		return errors.New("source repository is required")
 	}
+	if cfg.SkipEnvs && len(cfg.Envs) > 0 {
+		return errors.New("environment selection cannot be combined with skipping environments")
+	}
+	if cfg.SkipRepoVars && cfg.SkipEnvs {
+		return errors.New("skipping both repository and environment variables leaves nothing to migrate")
+	}
+	if len(cfg.Targets) > 0 {
+		if cfg.TargetOwner != "" || cfg.TargetRepo != "" {
+			return errors.New("a target repository cannot be combined with a list of targets")
+		}
+		return nil
+	}
 	if cfg.TargetOwner == "" {
 		return errors.New("target owner is required")
 	}
@@ -48,9 +146,583 @@ func validateOrgToOrg(cfg *types.MigrationConfig) error {
 	if cfg.TargetOrg == "" {
 		return errors.New("target organization is required")
 	}
+	if cfg.Deep {
+		if err := ValidateRepoPatterns(cfg.ExcludeRepos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateOrgToRepo validates organization to repository migration
+// configuration
+func validateOrgToRepo(cfg *types.MigrationConfig) error {
+	if cfg.SourceOrg == "" {
+		return errors.New("source organization is required")
+	}
+	if cfg.TargetOwner == "" {
+		return errors.New("target owner is required")
+	}
+	if cfg.TargetRepo == "" {
+		return errors.New("target repository is required")
+	}
+	return nil
+}
+
+// validateRepoToOrg validates repository to organization promotion
+// configuration
+func validateRepoToOrg(cfg *types.MigrationConfig) error {
+	if cfg.SourceOwner == "" {
+		return errors.New("source owner is required")
+	}
+	if cfg.SourceRepo == "" {
+		return errors.New("source repository is required")
+	}
+	if cfg.TargetOrg == "" {
+		return errors.New("target organization is required")
+	}
+	return ValidateTargetVisibility(cfg.TargetVisibility)
+}
+
+// validateFanOut validates organization to many repositories configuration
+func validateFanOut(cfg *types.MigrationConfig) error {
+	if cfg.SourceOrg == "" {
+		return errors.New("source organization is required")
+	}
+	if cfg.TargetOrg == "" {
+		return errors.New("target organization is required")
+	}
+	if cfg.AllRepos && len(cfg.TargetRepos) > 0 {
+		return errors.New("target repositories cannot be listed when all repositories are selected")
+	}
+	if !cfg.AllRepos && len(cfg.TargetRepos) == 0 {
+		return errors.New("at least one target repository is required")
+	}
+	return nil
+}
+
+// validateManifest validates the configuration of a manifest apply. The
+// manifest itself is validated when it is loaded.
+func validateManifest(cfg *types.MigrationConfig) error {
+	if cfg.Manifest == "" {
+		return errors.New("manifest is required")
+	}
+	if len(cfg.Desired) == 0 {
+		return errors.New("manifest declares no scopes")
+	}
+	return nil
+}
+
+// validateImport validates an import of an exported file
+func validateImport(cfg *types.MigrationConfig) error {
+	if cfg.Manifest == "" {
+		return errors.New("import file is required")
+	}
+	if len(cfg.Desired) == 0 {
+		return errors.New("import target is required")
+	}
+	return nil
+}
+
+// validateDelete validates a bulk deletion from one scope
+func validateDelete(cfg *types.MigrationConfig) error {
+	if len(cfg.Desired) != 1 {
+		return errors.New("delete needs exactly one target scope")
+	}
+	return nil
+}
+
+// validateCopy validates the copy of one variable
+func validateCopy(cfg *types.MigrationConfig) error {
+	switch {
+	case cfg.CopyName == "":
+		return errors.New("variable to copy is required")
+	case cfg.CopyFrom.Org == "" && cfg.CopyFrom.Repo == "":
+		return errors.New("copy source is required")
+	case len(cfg.Desired) != 1:
+		return errors.New("copy needs exactly one target scope")
+	}
+	return nil
+}
+
+// ParseScope parses a scope spec: org:ORG, repo:OWNER/REPO, or
+// env:OWNER/REPO/ENV. The environment name is the rest of the spec.
+func ParseScope(spec string) (types.DesiredScope, error) {
+	const want = "expected org:ORG, repo:OWNER/REPO, or env:OWNER/REPO/ENV"
+	kind, rest, ok := strings.Cut(spec, ":")
+	if !ok {
+		return types.DesiredScope{}, fmt.Errorf("invalid scope %q: %s", spec, want)
+	}
+
+	switch kind {
+	case "org":
+		if rest == "" || strings.Contains(rest, "/") {
+			return types.DesiredScope{}, fmt.Errorf("invalid scope %q: org: takes an organization name, e.g. org:acme", spec)
+		}
+		return types.DesiredScope{Org: rest}, nil
+	case "repo":
+		parts := strings.Split(rest, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return types.DesiredScope{}, fmt.Errorf("invalid scope %q: repo: takes OWNER/REPO, e.g. repo:acme/api", spec)
+		}
+		return types.DesiredScope{Owner: parts[0], Repo: parts[1]}, nil
+	case "env":
+		parts := strings.SplitN(rest, "/", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return types.DesiredScope{}, fmt.Errorf("invalid scope %q: env: takes OWNER/REPO/ENV, e.g. env:acme/api/production", spec)
+		}
+		return types.DesiredScope{Owner: parts[0], Repo: parts[1], Environment: parts[2]}, nil
+	default:
+		return types.DesiredScope{}, fmt.Errorf("invalid scope %q: unknown kind %q; %s", spec, kind, want)
+	}
+}
+
+// ValidateTargetVisibility checks the visibility requested for promoted
+// variables. Only "all" and "private" are accepted: "selected" would need a
+// repository list that a promotion has no source for. Empty means "all".
+func ValidateTargetVisibility(visibility string) error {
+	switch visibility {
+	case "", "all", "private":
+		return nil
+	default:
+		return fmt.Errorf("invalid target visibility %q (expected all or private)", visibility)
+	}
+}
+
+// ValidateVisibility checks the org-to-org visibility override. Empty
+// preserves the source visibility.
+func ValidateVisibility(visibility string) error {
+	switch visibility {
+	case "", "all", "private", "selected":
+		return nil
+	default:
+		return fmt.Errorf("invalid visibility %q (expected all, private, or selected)", visibility)
+	}
+}
+
+// ValidateSelectedFallback checks the fallback for "selected" variables
+// without matching target repositories. Empty means empty.
+func ValidateSelectedFallback(fallback types.SelectedFallback) error {
+	switch fallback {
+	case "", types.FallbackEmpty, types.FallbackPrivate, types.FallbackAll, types.FallbackSkip:
+		return nil
+	default:
+		return fmt.Errorf("invalid selected fallback %q (expected empty, private, all, or skip)", fallback)
+	}
+}
+
+// ValidateRepoMap checks a repository rename map: both names must be valid
+// repository names and each source repository may appear only once
+// (case-insensitive)
+func ValidateRepoMap(repoMap map[string]string) error {
+	sources := make([]string, 0, len(repoMap))
+	for src := range repoMap {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+
+	seen := make(map[string]string, len(repoMap))
+	for _, src := range sources {
+		if err := validateRepoName(src); err != nil {
+			return fmt.Errorf("repository map source: %w", err)
+		}
+		if err := validateRepoName(repoMap[src]); err != nil {
+			return fmt.Errorf("repository map entry %q: %w", src, err)
+		}
+		key := strings.ToLower(src)
+		if other, dup := seen[key]; dup {
+			return fmt.Errorf("repository map lists %q and %q, which are the same repository", other, src)
+		}
+		seen[key] = src
+	}
+	return nil
+}
+
+// ValidatePatterns checks that every include and exclude glob is well-formed
+func ValidatePatterns(include, exclude []string) error {
+	for _, p := range include {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid include pattern %q: %w", p, err)
+		}
+	}
+	for _, p := range exclude {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// ValidateRepoPatterns checks that every repository exclude glob is
+// well-formed
+func ValidateRepoPatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid repository pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// ValidateEnvMap checks an environment rename map: names must be non-empty
+// and each source environment may appear only once (case-insensitive).
+// Several sources may share a target environment.
+func ValidateEnvMap(envMap map[string]string) error {
+	sources := make([]string, 0, len(envMap))
+	for src := range envMap {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+
+	seen := make(map[string]string, len(envMap))
+	for _, src := range sources {
+		if strings.TrimSpace(src) == "" {
+			return errors.New("environment map entry has an empty source name")
+		}
+		if strings.TrimSpace(envMap[src]) == "" {
+			return fmt.Errorf("environment map entry %q has an empty target name", src)
+		}
+		key := strings.ToUpper(src)
+		if other, dup := seen[key]; dup {
+			return fmt.Errorf("environment map lists %q and %q, which are the same environment", other, src)
+		}
+		seen[key] = src
+	}
+	return nil
+}
+
+// ParseEnvMapping parses one SOURCE=TARGET environment mapping
+func ParseEnvMapping(spec string) (string, string, error) {
+	src, dst, ok := strings.Cut(spec, "=")
+	if !ok {
+		return "", "", fmt.Errorf("invalid environment mapping %q: expected SOURCE=TARGET", spec)
+	}
+	src, dst = strings.TrimSpace(src), strings.TrimSpace(dst)
+	if src == "" || dst == "" {
+		return "", "", fmt.Errorf("invalid environment mapping %q: SOURCE and TARGET cannot be empty", spec)
+	}
+	return src, dst, nil
+}
+
+// ValidateEnvPatterns checks that every environment exclude glob is
+// well-formed
+func ValidateEnvPatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid environment pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// ValidateFilterRegex checks that the name filter regular expression compiles.
+// An empty expression is valid and disables regex filtering.
+func ValidateFilterRegex(expr string) error {
+	if expr == "" {
+		return nil
+	}
+	if _, err := regexp.Compile(expr); err != nil {
+		return fmt.Errorf("invalid filter regex %q: %w", expr, err)
+	}
+	return nil
+}
+
+// ValidateNameAffixes checks that the target name prefix and suffix only use
+// characters GitHub allows in variable names and that the prefix does not
+// introduce a leading number or the reserved GITHUB_ prefix.
+func ValidateNameAffixes(prefix, suffix string) error {
+	if err := validateNameChars("target prefix", prefix); err != nil {
+		return err
+	}
+	if err := validateNameChars("target suffix", suffix); err != nil {
+		return err
+	}
+	if prefix != "" && prefix[0] >= '0' && prefix[0] <= '9' {
+		return fmt.Errorf("target prefix %q must not start with a number", prefix)
+	}
+	if strings.HasPrefix(strings.ToUpper(prefix), "GITHUB_") {
+		return fmt.Errorf("target prefix %q must not start with the reserved GITHUB_ prefix", prefix)
+	}
+	return nil
+}
+
+// ValidateNameMap checks a source → target rename mapping. Every name must
+// be a usable variable name, and no two source names (compared
+// case-insensitively, as GitHub does) may map to the same target name.
+func ValidateNameMap(nameMap map[string]string) error {
+	sources := make([]string, 0, len(nameMap))
+	for src := range nameMap {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+
+	seenSources := make(map[string]string, len(nameMap))
+	seenTargets := make(map[string]string, len(nameMap))
+	for _, src := range sources {
+		dst := nameMap[src]
+		if err := validateNameChars("name map source", src); err != nil {
+			return err
+		}
+		if dst == "" {
+			return fmt.Errorf("name map entry %q has an empty target name", src)
+		}
+		if err := validateNameChars("name map target", dst); err != nil {
+			return err
+		}
+
+		srcKey := strings.ToUpper(src)
+		if other, dup := seenSources[srcKey]; dup {
+			return fmt.Errorf("name map lists %q and %q, which are the same variable", other, src)
+		}
+		seenSources[srcKey] = src
+
+		dstKey := strings.ToUpper(dst)
+		if other, dup := seenTargets[dstKey]; dup {
+			return fmt.Errorf("name map collision: %q and %q both map to target name %q", other, src, dst)
+		}
+		seenTargets[dstKey] = src
+	}
+	return nil
+}
+
+// ValidateVariableName checks a variable name given with the flag named by
+// label: it must be set and only use the characters GitHub allows
+func ValidateVariableName(label, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s cannot be empty", label)
+	}
+	return validateNameChars(label, name)
+}
+
+// ValidateConflictStrategy checks that strategy is a known conflict strategy
+// (or empty) and that it does not contradict --skip-overwrite, which is an
+// alias for the skip strategy.
+func ValidateConflictStrategy(strategy types.ConflictStrategy, skipOverwrite bool) error {
+	switch strategy {
+	case "", types.ConflictOverwrite, types.ConflictSkip, types.ConflictFail, types.ConflictPrompt:
+	default:
+		return fmt.Errorf("invalid conflict strategy %q (expected skip, overwrite, fail, or prompt)", strategy)
+	}
+	if skipOverwrite && strategy != "" && strategy != types.ConflictSkip {
+		return fmt.Errorf("--skip-overwrite cannot be combined with --on-conflict=%s", strategy)
+	}
+	return nil
+}
+
+// ValidateValueOverrides checks that every override key is a usable variable
+// name and that no variable is overridden twice under different casing.
+func ValidateValueOverrides(overrides map[string]string) error {
+	seen := make(map[string]string, len(overrides))
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := validateNameChars("value override name", name); err != nil {
+			return err
+		}
+		key := strings.ToUpper(name)
+		if other, dup := seen[key]; dup {
+			return fmt.Errorf("value overrides list %q and %q, which are the same variable", other, name)
+		}
+		seen[key] = name
+	}
 	return nil
 }
 
+// ParseReplacements parses OLD=NEW substitution specs as given to --replace.
+// OLD must not be empty; NEW may be. Only the first '=' separates the two
+// sides, so NEW may itself contain '='.
+func ParseReplacements(specs []string) ([]types.Replacement, error) {
+	var out []types.Replacement
+	for _, spec := range specs {
+		oldStr, newStr, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid replacement %q: expected OLD=NEW", spec)
+		}
+		if oldStr == "" {
+			return nil, fmt.Errorf("invalid replacement %q: OLD cannot be empty", spec)
+		}
+		out = append(out, types.Replacement{Old: oldStr, New: newStr})
+	}
+	return out, nil
+}
+
+// ParseSince parses a --since value: an RFC3339 timestamp, or a positive
+// duration such as 168h that is counted back from now.
+func ParseSince(spec string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, spec); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(spec)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid value %q: expected an RFC3339 timestamp (e.g. 2024-05-01T00:00:00Z) or a duration (e.g. 168h)", spec)
+	}
+	if d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid value %q: the duration must be positive", spec)
+	}
+	return now.Add(-d), nil
+}
+
+// ParseRepoList parses a repository list file: one repository per line.
+// Blank lines and lines starting with '#' are ignored.
+func ParseRepoList(data string) []string {
+	var out []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// NormalizeRepoNames trims repository names, strips an "org/" prefix that
+// matches org, and drops duplicates (case-insensitive, first one wins).
+// Repositories of another owner and names with invalid characters are
+// rejected.
+func NormalizeRepoNames(names []string, org string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	var out []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if owner, repo, ok := strings.Cut(name, "/"); ok {
+			if !strings.EqualFold(owner, org) {
+				return nil, fmt.Errorf("repository %q is not in target organization %s", name, org)
+			}
+			name = repo
+		}
+		if err := validateRepoName(name); err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, name)
+	}
+	return out, nil
+}
+
+// ParseTargetRepos parses a target list file: one owner/repo per line.
+// Blank lines and '#' comments, whole-line or after the entry, are ignored.
+// Every invalid or duplicate line is reported, with its line number, in a
+// single error.
+func ParseTargetRepos(data string) ([]types.RepoRef, error) {
+	var targets []types.RepoRef
+	var problems []string
+	firstLine := make(map[string]int)
+
+	for i, line := range strings.Split(data, "\n") {
+		lineNo := i + 1
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		owner, repo, ok := strings.Cut(line, "/")
+		if !ok || strings.Contains(repo, "/") {
+			problems = append(problems, fmt.Sprintf("line %d: %q is not owner/repo", lineNo, line))
+			continue
+		}
+		if err := validateRepoName(owner); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", lineNo, err))
+			continue
+		}
+		if err := validateRepoName(repo); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", lineNo, err))
+			continue
+		}
+
+		key := strings.ToLower(line)
+		if first, dup := firstLine[key]; dup {
+			problems = append(problems, fmt.Sprintf("line %d: duplicate target %s (first on line %d)", lineNo, line, first))
+			continue
+		}
+		firstLine[key] = lineNo
+		targets = append(targets, types.RepoRef{Owner: owner, Repo: repo})
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid target list:\n  %s", strings.Join(problems, "\n  "))
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("target list is empty")
+	}
+	return targets, nil
+}
+
+// validateRepoName checks that name is a usable repository or owner name:
+// letters, digits, '-', '_', and '.'.
+func validateRepoName(name string) error {
+	if name == "" {
+		return errors.New("empty repository or owner name")
+	}
+	for _, r := range name {
+		if r != '-' && r != '_' && r != '.' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return fmt.Errorf("name %q contains invalid character %q", name, r)
+		}
+	}
+	return nil
+}
+
+// validateNameChars checks that value only contains letters, digits, and
+// underscores, the characters GitHub allows in variable names.
+func validateNameChars(label, value string) error {
+	for _, r := range value {
+		if r != '_' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return fmt.Errorf("%s %q contains invalid character %q; only letters, digits, and underscores are allowed", label, value, r)
+		}
+	}
+	return nil
+}
+
+// Endpoints describes the source and target of cfg. The target is empty
+// for a repository migration into many targets, which are listed in
+// cfg.Targets instead.
+func Endpoints(cfg *types.MigrationConfig) (string, string) {
+	sourceRepo := cfg.SourceOwner + "/" + cfg.SourceRepo
+	targetRepo := cfg.TargetOwner + "/" + cfg.TargetRepo
+
+	switch cfg.Mode {
+	case types.ModeOrgToOrg, types.ModeFanOut:
+		return cfg.SourceOrg, cfg.TargetOrg
+	case types.ModeOrgToRepo:
+		return cfg.SourceOrg, targetRepo
+	case types.ModeRepoToOrg:
+		return sourceRepo, cfg.TargetOrg
+	case types.ModeManifest:
+		return cfg.Manifest, ""
+	case types.ModeImport:
+		if len(cfg.Desired) == 0 {
+			return cfg.Manifest, ""
+		}
+		return cfg.Manifest, cfg.Desired[0].Label()
+	case types.ModeDelete:
+		if len(cfg.Desired) == 0 {
+			return "", ""
+		}
+		return "", cfg.Desired[0].Label()
+	case types.ModeCopy:
+		if len(cfg.Desired) == 0 {
+			return cfg.CopyFrom.Label(), ""
+		}
+		return cfg.CopyFrom.Label(), cfg.Desired[0].Label()
+	default:
+		if len(cfg.Targets) > 0 {
+			return sourceRepo, ""
+		}
+		return sourceRepo, targetRepo
+	}
+}
+
 // GetDescription returns a human-readable description of the migration
 func GetDescription(cfg *types.MigrationConfig) string {
 	switch cfg.Mode {
@@ -58,13 +730,82 @@ func GetDescription(cfg *types.MigrationConfig) string {
 		desc := fmt.Sprintf("Repository %s/%s → %s/%s",
 			cfg.SourceOwner, cfg.SourceRepo,
 			cfg.TargetOwner, cfg.TargetRepo)
-		if !cfg.SkipEnvs {
+		if len(cfg.Targets) > 0 {
+			desc = fmt.Sprintf("Repository %s/%s → %d target repository(ies)",
+				cfg.SourceOwner, cfg.SourceRepo, len(cfg.Targets))
+		}
+		switch {
+		case cfg.SkipRepoVars && len(cfg.Envs) == 1:
+			desc += fmt.Sprintf(" (environment %s only)", cfg.Envs[0])
+		case cfg.SkipRepoVars:
+			desc += " (environments only)"
+		case !cfg.SkipEnvs:
 			desc += " (with environments)"
 		}
 		return desc
 	case types.ModeOrgToOrg:
-		return fmt.Sprintf("Organization %s → %s",
+		desc := fmt.Sprintf("Organization %s → %s",
 			cfg.SourceOrg, cfg.TargetOrg)
+		if cfg.Deep {
+			desc += " (deep: with repositories)"
+		}
+		return desc
+	case types.ModeOrgToRepo:
+		return fmt.Sprintf("Organization %s → Repository %s/%s",
+			cfg.SourceOrg, cfg.TargetOwner, cfg.TargetRepo)
+	case types.ModeRepoToOrg:
+		return fmt.Sprintf("Repository %s/%s → Organization %s (promote)",
+			cfg.SourceOwner, cfg.SourceRepo, cfg.TargetOrg)
+	case types.ModeFanOut:
+		if cfg.AllRepos {
+			return fmt.Sprintf("Organization %s → all repositories in %s (fan-out)",
+				cfg.SourceOrg, cfg.TargetOrg)
+		}
+		return fmt.Sprintf("Organization %s → %d repository(ies) in %s (fan-out)",
+			cfg.SourceOrg, len(cfg.TargetRepos), cfg.TargetOrg)
+	case types.ModeManifest:
+		desc := fmt.Sprintf("Manifest %s → %d scope(s)", cfg.Manifest, len(cfg.Desired))
+		if cfg.Prune {
+			desc += " (with prune)"
+		}
+		return desc
+	case types.ModeImport:
+		if len(cfg.Desired) == 0 {
+			return fmt.Sprintf("Import %s", cfg.Manifest)
+		}
+		desc := fmt.Sprintf("Import %s → %s", cfg.Manifest, cfg.Desired[0].Label())
+		repos, envs := 0, 0
+		for _, s := range cfg.Desired[1:] {
+			if s.Environment != "" {
+				envs++
+			} else {
+				repos++
+			}
+		}
+		switch {
+		case repos > 0:
+			desc += fmt.Sprintf(" (with %d repository(ies) and %d environment(s))", repos, envs)
+		case envs > 0:
+			desc += fmt.Sprintf(" (with %d environment(s))", envs)
+		}
+		if cfg.Prune {
+			desc += " (with prune)"
+		}
+		return desc
+	case types.ModeDelete:
+		if len(cfg.Desired) == 0 {
+			return "Delete variables"
+		}
+		return fmt.Sprintf("Delete variables of %s", cfg.Desired[0].Label())
+	case types.ModeCopy:
+		name := cfg.CopyName
+		if cfg.CopyAs != "" && cfg.CopyAs != cfg.CopyName {
+			name += " as " + cfg.CopyAs
+		}
+		if len(cfg.Desired) == 0 {
+			return fmt.Sprintf("Copy %s from %s", name, cfg.CopyFrom.Label())
+		}
+		return fmt.Sprintf("Copy %s: %s → %s", name, cfg.CopyFrom.Label(), cfg.Desired[0].Label())
 	default:
 		return "Unknown migration"
 	}
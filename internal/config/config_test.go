@@ -1,7 +1,10 @@
 package config
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/renan-alm/gh-vars-migrator/internal/types"
 )
@@ -105,6 +108,628 @@ func TestValidate_OrgToOrg(t *testing.T) {
 	}
 }
 
+func TestValidate_OrgToRepo(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *types.MigrationConfig
+		wantErr bool
+	}{
+		{
+			name: "valid config",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeOrgToRepo,
+				SourceOrg:   "source-org",
+				TargetOwner: "target-org",
+				TargetRepo:  "target-repo",
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing source org",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeOrgToRepo,
+				TargetOwner: "target-org",
+				TargetRepo:  "target-repo",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing target owner",
+			cfg: &types.MigrationConfig{
+				Mode:       types.ModeOrgToRepo,
+				SourceOrg:  "source-org",
+				TargetRepo: "target-repo",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing target repo",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeOrgToRepo,
+				SourceOrg:   "source-org",
+				TargetOwner: "target-org",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_RepoToOrg(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *types.MigrationConfig
+		wantErr bool
+	}{
+		{
+			name: "valid config",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeRepoToOrg,
+				SourceOwner: "source-org",
+				SourceRepo:  "source-repo",
+				TargetOrg:   "target-org",
+			},
+			wantErr: false,
+		},
+		{
+			name: "private visibility",
+			cfg: &types.MigrationConfig{
+				Mode:             types.ModeRepoToOrg,
+				SourceOwner:      "source-org",
+				SourceRepo:       "source-repo",
+				TargetOrg:        "target-org",
+				TargetVisibility: "private",
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing source repo",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeRepoToOrg,
+				SourceOwner: "source-org",
+				TargetOrg:   "target-org",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing target org",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeRepoToOrg,
+				SourceOwner: "source-org",
+				SourceRepo:  "source-repo",
+			},
+			wantErr: true,
+		},
+		{
+			name: "selected visibility",
+			cfg: &types.MigrationConfig{
+				Mode:             types.ModeRepoToOrg,
+				SourceOwner:      "source-org",
+				SourceRepo:       "source-repo",
+				TargetOrg:        "target-org",
+				TargetVisibility: "selected",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		wantErr bool
+	}{
+		{name: "no patterns", wantErr: false},
+		{name: "valid patterns", include: []string{"DEPLOY_*"}, exclude: []string{"*_LEGACY", "TMP_?"}, wantErr: false},
+		{name: "invalid include", include: []string{"DEPLOY_["}, wantErr: true},
+		{name: "invalid exclude", exclude: []string{"[*_LEGACY"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePatterns(tt.include, tt.exclude)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePatterns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFilterRegex(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "empty", expr: "", wantErr: false},
+		{name: "valid anchored", expr: "^APP_(EU|US)_.*_URL$", wantErr: false},
+		{name: "unbalanced group", expr: "^APP_(EU|US_.*$", wantErr: true},
+		{name: "invalid repetition", expr: "*_URL", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFilterRegex(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFilterRegex() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_InvalidFilterRegex(t *testing.T) {
+	cfg := &types.MigrationConfig{
+		Mode:        types.ModeOrgToOrg,
+		SourceOrg:   "source-org",
+		TargetOrg:   "target-org",
+		FilterRegex: "(",
+	}
+	if err := Validate(cfg); err == nil {
+		t.Error("Validate() expected error for invalid filter regex, got nil")
+	}
+}
+
+func TestValidateNameAffixes(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		suffix  string
+		wantErr bool
+	}{
+		{name: "empty", wantErr: false},
+		{name: "valid prefix and suffix", prefix: "NEWORG_", suffix: "_V2", wantErr: false},
+		{name: "suffix may start with number", suffix: "2", wantErr: false},
+		{name: "prefix with dash", prefix: "NEW-", wantErr: true},
+		{name: "suffix with space", suffix: " V2", wantErr: true},
+		{name: "prefix leading number", prefix: "1_", wantErr: true},
+		{name: "reserved prefix", prefix: "GITHUB_", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNameAffixes(tt.prefix, tt.suffix)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNameAffixes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateVariableName(t *testing.T) {
+	if err := ValidateVariableName("--name", "API_URL_2"); err != nil {
+		t.Errorf("ValidateVariableName() unexpected error: %v", err)
+	}
+	if err := ValidateVariableName("--name", ""); err == nil || err.Error() != "--name cannot be empty" {
+		t.Errorf("ValidateVariableName() error = %v, want an empty name error", err)
+	}
+	if err := ValidateVariableName("--new-name", "API-URL"); err == nil || !strings.Contains(err.Error(), `--new-name "API-URL" contains invalid character '-'`) {
+		t.Errorf("ValidateVariableName() error = %v, want the flag and the character", err)
+	}
+}
+
+func TestValidateConflictStrategy(t *testing.T) {
+	tests := []struct {
+		name          string
+		strategy      types.ConflictStrategy
+		skipOverwrite bool
+		wantErr       bool
+	}{
+		{name: "empty", wantErr: false},
+		{name: "skip", strategy: types.ConflictSkip, wantErr: false},
+		{name: "overwrite", strategy: types.ConflictOverwrite, wantErr: false},
+		{name: "fail", strategy: types.ConflictFail, wantErr: false},
+		{name: "prompt", strategy: types.ConflictPrompt, wantErr: false},
+		{name: "unknown", strategy: "merge", wantErr: true},
+		{name: "skip-overwrite alone", skipOverwrite: true, wantErr: false},
+		{name: "skip-overwrite with skip", strategy: types.ConflictSkip, skipOverwrite: true, wantErr: false},
+		{name: "skip-overwrite with overwrite", strategy: types.ConflictOverwrite, skipOverwrite: true, wantErr: true},
+		{name: "skip-overwrite with fail", strategy: types.ConflictFail, skipOverwrite: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConflictStrategy(tt.strategy, tt.skipOverwrite)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConflictStrategy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateNameMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		nameMap map[string]string
+		wantErr bool
+	}{
+		{name: "nil map", nameMap: nil, wantErr: false},
+		{name: "valid renames", nameMap: map[string]string{"OLD_DB_HOST": "DATABASE_HOST", "OLD_PORT": "PORT"}, wantErr: false},
+		{name: "two sources one target", nameMap: map[string]string{"A": "SAME", "B": "SAME"}, wantErr: true},
+		{name: "two sources one target case-insensitive", nameMap: map[string]string{"A": "SAME", "B": "same"}, wantErr: true},
+		{name: "duplicate source case-insensitive", nameMap: map[string]string{"old": "X", "OLD": "Y"}, wantErr: true},
+		{name: "empty target", nameMap: map[string]string{"A": ""}, wantErr: true},
+		{name: "invalid target characters", nameMap: map[string]string{"A": "NEW-NAME"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNameMap(tt.nameMap)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNameMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseReplacements(t *testing.T) {
+	got, err := ParseReplacements([]string{"oldorg=neworg", "key=a=b", "drop="})
+	if err != nil {
+		t.Fatalf("ParseReplacements() unexpected error: %v", err)
+	}
+	want := []types.Replacement{{Old: "oldorg", New: "neworg"}, {Old: "key", New: "a=b"}, {Old: "drop", New: ""}}
+	if len(got) != len(want) {
+		t.Fatalf("ParseReplacements() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ParseReplacements()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	for _, spec := range []string{"no-equals", "=new"} {
+		if _, err := ParseReplacements([]string{spec}); err == nil {
+			t.Errorf("ParseReplacements(%q) expected error, got nil", spec)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 5, 8, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "timestamp", spec: "2026-05-01T00:00:00Z", want: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "timestamp with offset", spec: "2026-05-01T02:00:00+02:00", want: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "one week", spec: "168h", want: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{name: "hours and minutes", spec: "1h30m", want: time.Date(2026, 5, 8, 10, 30, 0, 0, time.UTC)},
+		{name: "date without time", spec: "2026-05-01", wantErr: true},
+		{name: "days are not a duration unit", spec: "7d", wantErr: true},
+		{name: "zero duration", spec: "0s", wantErr: true},
+		{name: "negative duration", spec: "-24h", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSince(tt.spec, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSince(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("ParseSince(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRepoList(t *testing.T) {
+	got := ParseRepoList("# services\napi\n\n  web  \r\n#old\nacme/worker\n")
+	want := []string{"api", "web", "acme/worker"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRepoList() = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeRepoNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "plain names", in: []string{"api", "web.site"}, want: []string{"api", "web.site"}},
+		{name: "matching owner prefix", in: []string{"ACME/api", "acme/web"}, want: []string{"api", "web"}},
+		{name: "duplicates dropped", in: []string{"api", "API", "acme/api"}, want: []string{"api"}},
+		{name: "other owner", in: []string{"other/api"}, wantErr: true},
+		{name: "empty name", in: []string{"acme/"}, wantErr: true},
+		{name: "invalid character", in: []string{"my repo"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeRepoNames(tt.in, "acme")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeRepoNames() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeRepoNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTargetRepos(t *testing.T) {
+	got, err := ParseTargetRepos("# new services\nacme/api\n\n  acme/web  # frontend\r\nother/worker\n")
+	if err != nil {
+		t.Fatalf("ParseTargetRepos() unexpected error: %v", err)
+	}
+	want := []types.RepoRef{{Owner: "acme", Repo: "api"}, {Owner: "acme", Repo: "web"}, {Owner: "other", Repo: "worker"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTargetRepos() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name      string
+		data      string
+		wantLines []string
+	}{
+		{name: "missing owner", data: "acme/api\napi\n", wantLines: []string{"line 2:"}},
+		{name: "too many parts", data: "acme/api/extra\n", wantLines: []string{"line 1:"}},
+		{name: "invalid character", data: "acme/api\n\nacme/my repo\n", wantLines: []string{"line 3:"}},
+		{name: "duplicate", data: "acme/api\nACME/api\n", wantLines: []string{"line 2: duplicate target ACME/api (first on line 1)"}},
+		{name: "every problem reported", data: "api\nacme/\nacme/ok\n", wantLines: []string{"line 1:", "line 2:"}},
+		{name: "only comments", data: "# nothing yet\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTargetRepos(tt.data)
+			if err == nil {
+				t.Fatal("ParseTargetRepos() expected error, got nil")
+			}
+			for _, line := range tt.wantLines {
+				if !strings.Contains(err.Error(), line) {
+					t.Errorf("ParseTargetRepos() error %q does not mention %q", err, line)
+				}
+			}
+		})
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    types.DesiredScope
+		wantErr string
+	}{
+		{spec: "org:acme", want: types.DesiredScope{Org: "acme"}},
+		{spec: "repo:acme/api", want: types.DesiredScope{Owner: "acme", Repo: "api"}},
+		{spec: "env:acme/api/production", want: types.DesiredScope{Owner: "acme", Repo: "api", Environment: "production"}},
+		{spec: "env:acme/api/team/blue", want: types.DesiredScope{Owner: "acme", Repo: "api", Environment: "team/blue"}},
+		{spec: "acme/api", wantErr: `invalid scope "acme/api": expected org:ORG, repo:OWNER/REPO, or env:OWNER/REPO/ENV`},
+		{spec: "team:acme/devs", wantErr: `unknown kind "team"`},
+		{spec: "org:", wantErr: "org: takes an organization name"},
+		{spec: "org:acme/api", wantErr: "org: takes an organization name"},
+		{spec: "repo:api", wantErr: "repo: takes OWNER/REPO"},
+		{spec: "repo:acme/api/prod", wantErr: "repo: takes OWNER/REPO"},
+		{spec: "env:acme/api", wantErr: "env: takes OWNER/REPO/ENV"},
+		{spec: "env:acme//prod", wantErr: "env: takes OWNER/REPO/ENV"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseScope(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseScope() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseScope() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseScope() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_RepoToRepoTargets(t *testing.T) {
+	cfg := &types.MigrationConfig{
+		Mode:        types.ModeRepoToRepo,
+		SourceOwner: "acme",
+		SourceRepo:  "template",
+		Targets:     []types.RepoRef{{Owner: "acme", Repo: "api"}},
+	}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+
+	cfg.TargetRepo = "web"
+	if err := Validate(cfg); err == nil {
+		t.Error("Validate() expected error when a target repository is combined with targets")
+	}
+}
+
+func TestValidate_Deep(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *types.MigrationConfig
+		wantErr bool
+	}{
+		{
+			name:    "org to org",
+			cfg:     &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", Deep: true, ExcludeRepos: []string{"legacy-*"}},
+			wantErr: false,
+		},
+		{
+			name:    "invalid repository pattern",
+			cfg:     &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", Deep: true, ExcludeRepos: []string{"[legacy"}},
+			wantErr: true,
+		},
+		{
+			name: "repo to repo",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeRepoToRepo,
+				SourceOwner: "src",
+				SourceRepo:  "app",
+				TargetOwner: "dst",
+				TargetRepo:  "app",
+				Deep:        true,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Envs(t *testing.T) {
+	repoCfg := func(skipEnvs bool, envs ...string) *types.MigrationConfig {
+		return &types.MigrationConfig{
+			Mode:        types.ModeRepoToRepo,
+			SourceOwner: "src",
+			SourceRepo:  "app",
+			TargetOwner: "dst",
+			TargetRepo:  "app",
+			SkipEnvs:    skipEnvs,
+			Envs:        envs,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		cfg     *types.MigrationConfig
+		wantErr bool
+	}{
+		{name: "selected environments", cfg: repoCfg(false, "production", "staging"), wantErr: false},
+		{name: "combined with skip envs", cfg: repoCfg(true, "production"), wantErr: true},
+		{
+			name: "nothing left to migrate",
+			cfg: func() *types.MigrationConfig {
+				cfg := repoCfg(true)
+				cfg.SkipRepoVars = true
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name:    "org to org",
+			cfg:     &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", Envs: []string{"production"}},
+			wantErr: true,
+		},
+		{
+			name: "excluded environments",
+			cfg: func() *types.MigrationConfig {
+				cfg := repoCfg(false)
+				cfg.ExcludeEnvs = []string{"pr-*", "preview-*"}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "invalid exclude pattern",
+			cfg: func() *types.MigrationConfig {
+				cfg := repoCfg(false)
+				cfg.ExcludeEnvs = []string{"[pr"}
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "selection and exclusion",
+			cfg: func() *types.MigrationConfig {
+				cfg := repoCfg(false, "production")
+				cfg.ExcludeEnvs = []string{"pr-*"}
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name:    "excluded environments in deep migration",
+			cfg:     &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", Deep: true, ExcludeEnvs: []string{"pr-*"}},
+			wantErr: false,
+		},
+		{
+			name:    "excluded environments in org to org",
+			cfg:     &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", ExcludeEnvs: []string{"pr-*"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_FanOut(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *types.MigrationConfig
+		wantErr bool
+	}{
+		{
+			name:    "listed repos",
+			cfg:     &types.MigrationConfig{Mode: types.ModeFanOut, SourceOrg: "src", TargetOrg: "dst", TargetRepos: []string{"api"}},
+			wantErr: false,
+		},
+		{
+			name:    "all repos",
+			cfg:     &types.MigrationConfig{Mode: types.ModeFanOut, SourceOrg: "src", TargetOrg: "dst", AllRepos: true},
+			wantErr: false,
+		},
+		{
+			name:    "no repos selected",
+			cfg:     &types.MigrationConfig{Mode: types.ModeFanOut, SourceOrg: "src", TargetOrg: "dst"},
+			wantErr: true,
+		},
+		{
+			name:    "listed and all repos",
+			cfg:     &types.MigrationConfig{Mode: types.ModeFanOut, SourceOrg: "src", TargetOrg: "dst", TargetRepos: []string{"api"}, AllRepos: true},
+			wantErr: true,
+		},
+		{
+			name:    "missing source org",
+			cfg:     &types.MigrationConfig{Mode: types.ModeFanOut, TargetOrg: "dst", AllRepos: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestGetDescription(t *testing.T) {
 	tests := []struct {
 		name string
@@ -144,6 +769,121 @@ func TestGetDescription(t *testing.T) {
 			},
 			want: "Organization org1 → org2",
 		},
+		{
+			name: "org to repo",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeOrgToRepo,
+				SourceOrg:   "org1",
+				TargetOwner: "org2",
+				TargetRepo:  "repo2",
+			},
+			want: "Organization org1 → Repository org2/repo2",
+		},
+		{
+			name: "repo to org",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeRepoToOrg,
+				SourceOwner: "org1",
+				SourceRepo:  "repo1",
+				TargetOrg:   "org2",
+			},
+			want: "Repository org1/repo1 → Organization org2 (promote)",
+		},
+		{
+			name: "single environment",
+			cfg: &types.MigrationConfig{
+				Mode:         types.ModeRepoToRepo,
+				SourceOwner:  "org1",
+				SourceRepo:   "repo1",
+				TargetOwner:  "org2",
+				TargetRepo:   "repo2",
+				Envs:         []string{"production"},
+				SkipRepoVars: true,
+			},
+			want: "Repository org1/repo1 → org2/repo2 (environment production only)",
+		},
+		{
+			name: "deep org to org",
+			cfg: &types.MigrationConfig{
+				Mode:      types.ModeOrgToOrg,
+				SourceOrg: "org1",
+				TargetOrg: "org2",
+				Deep:      true,
+			},
+			want: "Organization org1 → org2 (deep: with repositories)",
+		},
+		{
+			name: "fan-out to listed repos",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeFanOut,
+				SourceOrg:   "org1",
+				TargetOrg:   "org2",
+				TargetRepos: []string{"a", "b"},
+			},
+			want: "Organization org1 → 2 repository(ies) in org2 (fan-out)",
+		},
+		{
+			name: "fan-out to all repos",
+			cfg: &types.MigrationConfig{
+				Mode:      types.ModeFanOut,
+				SourceOrg: "org1",
+				TargetOrg: "org2",
+				AllRepos:  true,
+			},
+			want: "Organization org1 → all repositories in org2 (fan-out)",
+		},
+		{
+			name: "manifest with prune",
+			cfg: &types.MigrationConfig{
+				Mode:     types.ModeManifest,
+				Manifest: "vars.yaml",
+				Desired:  []types.DesiredScope{{Org: "acme"}, {Owner: "acme", Repo: "app"}},
+				Prune:    true,
+			},
+			want: "Manifest vars.yaml → 2 scope(s) (with prune)",
+		},
+		{
+			name: "import with environments",
+			cfg: &types.MigrationConfig{
+				Mode:     types.ModeImport,
+				Manifest: "app.json",
+				Desired:  []types.DesiredScope{{Owner: "acme", Repo: "app"}, {Owner: "acme", Repo: "app", Environment: "production"}},
+			},
+			want: "Import app.json → acme/app (with 1 environment(s))",
+		},
+		{
+			name: "restore of an organization backup",
+			cfg: &types.MigrationConfig{
+				Mode:     types.ModeImport,
+				Manifest: "acme.json",
+				Desired: []types.DesiredScope{
+					{Org: "acme"},
+					{Owner: "acme", Repo: "app"},
+					{Owner: "acme", Repo: "app", Environment: "production"},
+					{Owner: "acme", Repo: "web"},
+				},
+			},
+			want: "Import acme.json → org:acme (with 2 repository(ies) and 1 environment(s))",
+		},
+		{
+			name: "delete",
+			cfg: &types.MigrationConfig{
+				Mode:    types.ModeDelete,
+				Desired: []types.DesiredScope{{Owner: "acme", Repo: "app", Environment: "production"}},
+			},
+			want: "Delete variables of acme/app:env:production",
+		},
+		{
+			name: "copy with a new name",
+			cfg: &types.MigrationConfig{
+				Mode:     types.ModeCopy,
+				CopyFrom: types.DesiredScope{Org: "acme"},
+				CopyName: "API_URL",
+				CopyAs:   "BACKEND_URL",
+				Desired:  []types.DesiredScope{{Owner: "acme", Repo: "api"}},
+			},
+			want: "Copy API_URL as BACKEND_URL: org:acme → acme/api",
+		},
 	}
 
 	for _, tt := range tests {
@@ -155,3 +895,169 @@ func TestGetDescription(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateEnvMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		envMap  map[string]string
+		wantErr bool
+	}{
+		{name: "rename", envMap: map[string]string{"stage": "staging"}, wantErr: false},
+		{name: "many to one", envMap: map[string]string{"stage": "staging", "qa": "staging"}, wantErr: false},
+		{name: "empty target", envMap: map[string]string{"stage": " "}, wantErr: true},
+		{name: "empty source", envMap: map[string]string{"": "staging"}, wantErr: true},
+		{name: "same source twice", envMap: map[string]string{"stage": "staging", "STAGE": "qa"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEnvMap(tt.envMap)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEnvMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseEnvMapping(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantSrc string
+		wantDst string
+		wantErr bool
+	}{
+		{spec: "stage=staging", wantSrc: "stage", wantDst: "staging"},
+		{spec: " stage = staging ", wantSrc: "stage", wantDst: "staging"},
+		{spec: "stage", wantErr: true},
+		{spec: "=staging", wantErr: true},
+		{spec: "stage=", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			src, dst, err := ParseEnvMapping(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEnvMapping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if src != tt.wantSrc || dst != tt.wantDst {
+				t.Errorf("ParseEnvMapping() = %q, %q, want %q, %q", src, dst, tt.wantSrc, tt.wantDst)
+			}
+		})
+	}
+}
+
+func TestValidate_Visibility(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *types.MigrationConfig
+		wantErr bool
+	}{
+		{name: "all", cfg: &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", Visibility: "all"}, wantErr: false},
+		{name: "private", cfg: &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", Visibility: "private"}, wantErr: false},
+		{name: "selected", cfg: &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", Visibility: "selected"}, wantErr: false},
+		{name: "invalid", cfg: &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", Visibility: "internal"}, wantErr: true},
+		{
+			name:    "org to repo",
+			cfg:     &types.MigrationConfig{Mode: types.ModeOrgToRepo, SourceOrg: "src", TargetOwner: "dst", TargetRepo: "app", Visibility: "private"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_SelectedFallback(t *testing.T) {
+	orgToOrg := func(fallback types.SelectedFallback) *types.MigrationConfig {
+		return &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", SelectedFallback: fallback}
+	}
+	tests := []struct {
+		name    string
+		cfg     *types.MigrationConfig
+		wantErr bool
+	}{
+		{name: "empty", cfg: orgToOrg(types.FallbackEmpty), wantErr: false},
+		{name: "private", cfg: orgToOrg(types.FallbackPrivate), wantErr: false},
+		{name: "all", cfg: orgToOrg(types.FallbackAll), wantErr: false},
+		{name: "skip", cfg: orgToOrg(types.FallbackSkip), wantErr: false},
+		{name: "invalid", cfg: orgToOrg("selected"), wantErr: true},
+		{
+			name:    "repo to repo",
+			cfg:     &types.MigrationConfig{Mode: types.ModeRepoToRepo, SourceOwner: "src", SourceRepo: "app", TargetOwner: "dst", TargetRepo: "app", SelectedFallback: types.FallbackSkip},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRepoMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoMap map[string]string
+		wantErr bool
+	}{
+		{name: "renames", repoMap: map[string]string{"api": "platform-api", "web": "platform.web"}, wantErr: false},
+		{name: "invalid target", repoMap: map[string]string{"api": "platform/api"}, wantErr: true},
+		{name: "empty target", repoMap: map[string]string{"api": ""}, wantErr: true},
+		{name: "same source twice", repoMap: map[string]string{"api": "a", "API": "b"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRepoMap(tt.repoMap)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRepoMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        *types.MigrationConfig
+		wantSource string
+		wantTarget string
+	}{
+		{name: "org to org", cfg: &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "a", TargetOrg: "b"}, wantSource: "a", wantTarget: "b"},
+		{name: "org to repo", cfg: &types.MigrationConfig{Mode: types.ModeOrgToRepo, SourceOrg: "a", TargetOwner: "b", TargetRepo: "r"}, wantSource: "a", wantTarget: "b/r"},
+		{name: "repo to org", cfg: &types.MigrationConfig{Mode: types.ModeRepoToOrg, SourceOwner: "a", SourceRepo: "r", TargetOrg: "b"}, wantSource: "a/r", wantTarget: "b"},
+		{
+			name:       "many targets",
+			cfg:        &types.MigrationConfig{Mode: types.ModeRepoToRepo, SourceOwner: "a", SourceRepo: "r", Targets: []types.RepoRef{{Owner: "b", Repo: "x"}}},
+			wantSource: "a/r",
+		},
+		{name: "manifest", cfg: &types.MigrationConfig{Mode: types.ModeManifest, Manifest: "vars.yaml"}, wantSource: "vars.yaml"},
+		{name: "delete", cfg: &types.MigrationConfig{Mode: types.ModeDelete, Desired: []types.DesiredScope{{Owner: "acme", Repo: "app"}}}, wantTarget: "acme/app"},
+		{name: "import", cfg: &types.MigrationConfig{Mode: types.ModeImport, Manifest: "vars.env", Desired: []types.DesiredScope{{Org: "acme"}}}, wantSource: "vars.env", wantTarget: "org:acme"},
+		{
+			name:       "copy",
+			cfg:        &types.MigrationConfig{Mode: types.ModeCopy, CopyFrom: types.DesiredScope{Owner: "acme", Repo: "api", Environment: "prod"}, Desired: []types.DesiredScope{{Org: "other"}}},
+			wantSource: "acme/api:env:prod",
+			wantTarget: "org:other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, target := Endpoints(tt.cfg)
+			if source != tt.wantSource || target != tt.wantTarget {
+				t.Errorf("Endpoints() = %q, %q, want %q, %q", source, target, tt.wantSource, tt.wantTarget)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// Package dump writes the variables of an organization, repository, or
+// environment to JSON, YAML, .env, or CSV for backups and offline review,
+// and reads them back for the import command. The JSON and YAML documents
+// share one structure.
+package dump
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the dump format version written by Write
+const Version = 1
+
+// Output formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatEnv  = "env"
+	FormatCSV  = "csv"
+)
+
+// Formats lists the accepted output formats in the order they are documented
+var Formats = []string{FormatJSON, FormatYAML, FormatEnv, FormatCSV}
+
+// MaskedValue replaces every value of a masked dump
+const MaskedValue = "********"
+
+// Dump holds the variables of one scope: an organization (Org), a
+// repository of it (Repo), or an environment of that repository
+// (Environment). A repository dump may also carry the variables of its
+// environments, and an organization backup those of its repositories.
+type Dump struct {
+	Version     int       `json:"version" yaml:"version"`
+	ExportedAt  time.Time `json:"exported_at" yaml:"exported_at"`
+	Org         string    `json:"org" yaml:"org"`
+	Repo        string    `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Environment string    `json:"environment,omitempty" yaml:"environment,omitempty"`
+
+	// ValuesMasked records that the values were replaced by MaskedValue,
+	// so the dump cannot be imported.
+	ValuesMasked bool `json:"values_masked,omitempty" yaml:"values_masked,omitempty"`
+
+	Variables    []Variable    `json:"variables" yaml:"variables"`
+	Environments []Environment `json:"environments,omitempty" yaml:"environments,omitempty"`
+	Repositories []Repository  `json:"repositories,omitempty" yaml:"repositories,omitempty"`
+}
+
+// Variable is one exported variable. Visibility and SelectedRepositories
+// are only set for organization variables.
+type Variable struct {
+	Name                 string   `json:"name" yaml:"name"`
+	Value                string   `json:"value" yaml:"value"`
+	Visibility           string   `json:"visibility,omitempty" yaml:"visibility,omitempty"`
+	SelectedRepositories []string `json:"selected_repositories,omitempty" yaml:"selected_repositories,omitempty,flow"`
+	UpdatedAt            string   `json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+
+	// line is where the variable was read from a .env file
+	line int
+}
+
+// Environment holds the variables of one environment of a repository dump
+type Environment struct {
+	Name      string     `json:"name" yaml:"name"`
+	Variables []Variable `json:"variables" yaml:"variables"`
+}
+
+// Repository holds the variables of one repository of an organization
+// backup, with those of its environments
+type Repository struct {
+	Name         string        `json:"name" yaml:"name"`
+	Variables    []Variable    `json:"variables" yaml:"variables"`
+	Environments []Environment `json:"environments,omitempty" yaml:"environments,omitempty"`
+}
+
+// ValidFormat reports whether format is one of Formats
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of variables in the dump, environments and
+// repositories included
+func (d *Dump) Count() int {
+	n := len(d.Variables) + countEnvs(d.Environments)
+	for _, r := range d.Repositories {
+		n += len(r.Variables) + countEnvs(r.Environments)
+	}
+	return n
+}
+
+func countEnvs(envs []Environment) int {
+	n := 0
+	for _, env := range envs {
+		n += len(env.Variables)
+	}
+	return n
+}
+
+// Mask replaces every value with MaskedValue
+func (d *Dump) Mask() {
+	d.ValuesMasked = true
+	mask(d.Variables)
+	for _, env := range d.Environments {
+		mask(env.Variables)
+	}
+	for _, r := range d.Repositories {
+		mask(r.Variables)
+		for _, env := range r.Environments {
+			mask(env.Variables)
+		}
+	}
+}
+
+func mask(vars []Variable) {
+	for i := range vars {
+		vars[i].Value = MaskedValue
+	}
+}
+
+// Write encodes the dump to w in the given format
+func Write(w io.Writer, d *Dump, format string) error {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding dump: %w", err)
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(d); err != nil {
+			return fmt.Errorf("encoding dump: %w", err)
+		}
+		return enc.Close()
+	case FormatEnv:
+		return writeEnv(w, d)
+	case FormatCSV:
+		return writeCSV(w, d)
+	default:
+		return fmt.Errorf("unsupported format %q (expected %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Save writes the dump to path in the given format. The file is created
+// with owner-only permissions because it may contain variable values.
+func Save(path string, d *Dump, format string) error {
+	var buf bytes.Buffer
+	if err := Write(&buf, d, format); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("writing dump: %w", err)
+	}
+	return nil
+}
+
+// scopeLabel names the dumped scope, e.g. "org:acme", "acme/app", or
+// "acme/app:env:production"
+func (d *Dump) scopeLabel() string {
+	switch {
+	case d.Repo == "":
+		return "org:" + d.Org
+	case d.Environment != "":
+		return d.Org + "/" + d.Repo + ":env:" + d.Environment
+	default:
+		return d.Org + "/" + d.Repo
+	}
+}
+
+// writeEnv writes NAME=value lines, with the environments of a repository
+// dump in sections of their own
+func writeEnv(w io.Writer, d *Dump) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", d.scopeLabel())
+	for _, v := range d.Variables {
+		fmt.Fprintf(&b, "%s=%s\n", v.Name, envValue(v.Value))
+	}
+	for _, env := range d.Environments {
+		fmt.Fprintf(&b, "\n# %s/%s:env:%s\n", d.Org, d.Repo, env.Name)
+		for _, v := range env.Variables {
+			fmt.Fprintf(&b, "%s=%s\n", v.Name, envValue(v.Value))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// envValue returns value as written to a .env file: unchanged when it
+// reads back as is, otherwise double-quoted with escapes
+func envValue(value string) string {
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, "\"'\\\n\r") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// csvHeader is the first row of a CSV dump
+var csvHeader = []string{"environment", "name", "value", "visibility", "selected_repositories", "updated_at"}
+
+// writeCSV writes one row per variable; the environment column is empty
+// for the dumped scope itself unless it is an environment
+func writeCSV(w io.Writer, d *Dump) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	rows := func(env string, vars []Variable) error {
+		for _, v := range vars {
+			row := []string{env, v.Name, v.Value, v.Visibility, strings.Join(v.SelectedRepositories, ";"), v.UpdatedAt}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := rows(d.Environment, d.Variables); err != nil {
+		return err
+	}
+	for _, env := range d.Environments {
+		if err := rows(env.Name, env.Variables); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
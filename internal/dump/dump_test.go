@@ -0,0 +1,143 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sampleDump returns a repository dump with one environment
+func sampleDump() *Dump {
+	return &Dump{
+		Version:    Version,
+		ExportedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Org:        "acme",
+		Repo:       "app",
+		Variables: []Variable{
+			{Name: "LOG_LEVEL", Value: "info", UpdatedAt: "2026-01-01T00:00:00Z"},
+			{Name: "SCRIPT", Value: "echo \"hi\"\nexit 0"},
+		},
+		Environments: []Environment{
+			{Name: "production", Variables: []Variable{{Name: "URL", Value: " https://app.example.com, eu "}}},
+		},
+	}
+}
+
+func write(t *testing.T, d *Dump, format string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, d, format); err != nil {
+		t.Fatalf("Write(%s) unexpected error: %v", format, err)
+	}
+	return buf.String()
+}
+
+func TestWrite_JSON(t *testing.T) {
+	want := sampleDump()
+	var got Dump
+	if err := json.Unmarshal([]byte(write(t, want, FormatJSON)), &got); err != nil {
+		t.Fatalf("JSON output does not decode: %v", err)
+	}
+	if !reflect.DeepEqual(&got, want) {
+		t.Errorf("JSON round trip mismatch:\n got  %+v\n want %+v", got, want)
+	}
+}
+
+func TestWrite_YAML(t *testing.T) {
+	want := sampleDump()
+	want.Repo = ""
+	want.Environments = nil
+	want.Variables = []Variable{
+		{Name: "NUMBER", Value: "007", Visibility: "selected", SelectedRepositories: []string{"app", "web"}},
+		{Name: "FLAG", Value: "true", Visibility: "all"},
+	}
+
+	out := write(t, want, FormatYAML)
+	if !strings.Contains(out, "selected_repositories: [app, web]") {
+		t.Errorf("Expected selected repositories in flow style:\n%s", out)
+	}
+	var got Dump
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("YAML output does not decode: %v", err)
+	}
+	if !reflect.DeepEqual(&got, want) {
+		t.Errorf("YAML round trip mismatch:\n got  %+v\n want %+v", got, want)
+	}
+}
+
+func TestWrite_Env(t *testing.T) {
+	want := `# acme/app
+LOG_LEVEL=info
+SCRIPT="echo \"hi\"\nexit 0"
+
+# acme/app:env:production
+URL=" https://app.example.com, eu "
+`
+	if got := write(t, sampleDump(), FormatEnv); got != want {
+		t.Errorf("Env output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWrite_CSV(t *testing.T) {
+	rows, err := csv.NewReader(strings.NewReader(write(t, sampleDump(), FormatCSV))).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV output does not parse: %v", err)
+	}
+	want := [][]string{
+		csvHeader,
+		{"", "LOG_LEVEL", "info", "", "", "2026-01-01T00:00:00Z"},
+		{"", "SCRIPT", "echo \"hi\"\nexit 0", "", "", ""},
+		{"production", "URL", " https://app.example.com, eu ", "", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("CSV rows =\n %q\nwant\n %q", rows, want)
+	}
+}
+
+func TestWrite_UnsupportedFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, sampleDump(), "xml"); err == nil || !strings.Contains(err.Error(), `unsupported format "xml"`) {
+		t.Errorf("Write() error = %v, want unsupported format", err)
+	}
+}
+
+func TestMask(t *testing.T) {
+	d := sampleDump()
+	d.Mask()
+
+	if !d.ValuesMasked {
+		t.Error("Expected ValuesMasked to be set")
+	}
+	for _, format := range Formats {
+		out := write(t, d, format)
+		for _, secret := range []string{"info", "echo", "example.com"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("%s output contains the value %q:\n%s", format, secret, out)
+			}
+		}
+		if !strings.Contains(out, MaskedValue) || !strings.Contains(out, "LOG_LEVEL") {
+			t.Errorf("%s output should list names with masked values:\n%s", format, out)
+		}
+	}
+}
+
+func TestSave_Permissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.json")
+	if err := Save(path, sampleDump(), FormatJSON); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected file mode 0600, got %o", perm)
+	}
+}
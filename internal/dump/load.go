@@ -0,0 +1,287 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// MaxValueSize is GitHub's limit for the size of one variable value
+const MaxValueSize = 48 * 1024
+
+// ImportFormats lists the formats Load reads
+var ImportFormats = []string{FormatJSON, FormatYAML, FormatEnv}
+
+// variableName matches the names GitHub accepts for variables
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// FormatFromPath guesses the format of a file from its name: .json, .yaml
+// or .yml, and .env or a name ending in .env. It returns "" otherwise.
+func FormatFromPath(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(base, ".json"):
+		return FormatJSON
+	case strings.HasSuffix(base, ".yaml"), strings.HasSuffix(base, ".yml"):
+		return FormatYAML
+	case strings.HasSuffix(base, ".env"):
+		return FormatEnv
+	}
+	return ""
+}
+
+// Load reads and validates the file at path in the given format. Errors
+// name the file and the offending entry.
+func Load(path, format string) (*Dump, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	d, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Parse decodes and validates data in the given format. JSON and YAML must
+// follow the structure written by Write, without unknown keys; a .env file
+// holds the variables of one scope.
+func Parse(data []byte, format string) (*Dump, error) {
+	var d Dump
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&d); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("file is empty")
+			}
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&d); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("file is empty")
+			}
+			return nil, fmt.Errorf("invalid YAML: %s", strings.TrimPrefix(err.Error(), "yaml: "))
+		}
+	case FormatEnv:
+		vars, err := parseEnv(data)
+		if err != nil {
+			return nil, err
+		}
+		d = Dump{Version: Version, Variables: vars}
+	default:
+		return nil, fmt.Errorf("unsupported format %q (expected %s)", format, strings.Join(ImportFormats, ", "))
+	}
+
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// parseEnv reads NAME=value lines as written by Write: blank lines and
+// comments are skipped, an "export " prefix is allowed, double-quoted
+// values are unescaped, and single-quoted values are taken as they are.
+// Every variable records its line for validation messages.
+func parseEnv(data []byte) ([]Variable, error) {
+	vars := []Variable{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*MaxValueSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+
+		name, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected NAME=value", line)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value: %w", line, err)
+			}
+			value = unquoted
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		}
+		vars = append(vars, Variable{Name: strings.TrimSpace(name), Value: value, line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", line+1, err)
+	}
+	return vars, nil
+}
+
+// Validate checks that the dump can be imported: it has the supported
+// version and real values, and every variable has a valid name, a value
+// within GitHub's size limit, and is listed once per scope
+func (d *Dump) Validate() error {
+	if d.Version != Version {
+		return fmt.Errorf("unsupported version %d (expected %d)", d.Version, Version)
+	}
+	if d.ValuesMasked {
+		return fmt.Errorf("values are masked; export again with --include-values")
+	}
+	if err := validateVariables("", d.Variables); err != nil {
+		return err
+	}
+	if err := validateEnvironments("", d.Environments); err != nil {
+		return err
+	}
+	if len(d.Repositories) > 0 && d.Repo != "" {
+		return fmt.Errorf("a repository dump cannot hold repositories")
+	}
+
+	seen := make(map[string]bool, len(d.Repositories))
+	for i, r := range d.Repositories {
+		if r.Name == "" {
+			return fmt.Errorf("repository %d has no name", i+1)
+		}
+		key := strings.ToLower(r.Name)
+		if seen[key] {
+			return fmt.Errorf("repository %s is listed more than once", r.Name)
+		}
+		seen[key] = true
+		prefix := "repository " + r.Name + ", "
+		if err := validateVariables(prefix, r.Variables); err != nil {
+			return err
+		}
+		if err := validateEnvironments(prefix, r.Environments); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateEnvironments checks the environments of one repository; prefix
+// names the repository in messages
+func validateEnvironments(prefix string, envs []Environment) error {
+	seen := make(map[string]bool, len(envs))
+	for i, env := range envs {
+		if env.Name == "" {
+			return fmt.Errorf("%senvironment %d has no name", prefix, i+1)
+		}
+		key := strings.ToUpper(env.Name)
+		if seen[key] {
+			return fmt.Errorf("%senvironment %s is listed more than once", prefix, env.Name)
+		}
+		seen[key] = true
+		if err := validateVariables(prefix+"environment "+env.Name+", ", env.Variables); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateVariables checks the variables of one scope; prefix names the
+// scope in messages
+func validateVariables(prefix string, vars []Variable) error {
+	names := make(map[string]string, len(vars))
+	for i, v := range vars {
+		entry := fmt.Sprintf("%svariable %d", prefix, i+1)
+		if v.line > 0 {
+			entry = fmt.Sprintf("%sline %d", prefix, v.line)
+		}
+		if v.Name == "" {
+			return fmt.Errorf("%s: missing name", entry)
+		}
+		entry += " (" + v.Name + ")"
+		if !variableName.MatchString(v.Name) {
+			return fmt.Errorf("%s: name may only contain letters, digits, and underscores, and must not start with a digit", entry)
+		}
+		switch v.Visibility {
+		case "", "all", "private", "selected":
+		default:
+			return fmt.Errorf("%s: invalid visibility %q (expected all, private, or selected)", entry, v.Visibility)
+		}
+		if v.Value == "" {
+			return fmt.Errorf("%s: missing value", entry)
+		}
+		if size := len(v.Value); size > MaxValueSize {
+			return fmt.Errorf("%s: value is %d bytes, over the %d-byte limit", entry, size, MaxValueSize)
+		}
+		key := strings.ToUpper(v.Name)
+		if first, dup := names[key]; dup {
+			return fmt.Errorf("%s: name is already used by %s", entry, first)
+		}
+		names[key] = entry
+	}
+	return nil
+}
+
+// Desired maps the dump onto a target: an organization, a repository of it
+// (repo set), or an environment of that repository (env set as well). The
+// environments of a dump are imported as environments of the target
+// repository, and the repositories of an organization backup as the
+// repositories of the same name in the target organization. Visibility only
+// carries over to an organization, where a variable without one is visible
+// to all repositories.
+func (d *Dump) Desired(org, repo, env string) ([]types.DesiredScope, error) {
+	if len(d.Environments) > 0 && (repo == "" || env != "") {
+		return nil, fmt.Errorf("the file holds the variables of %d environment(s); import it into a repository", len(d.Environments))
+	}
+	if len(d.Repositories) > 0 && repo != "" {
+		return nil, fmt.Errorf("the file holds the variables of %d repository(ies); import it into an organization", len(d.Repositories))
+	}
+
+	if repo == "" {
+		scope := types.DesiredScope{Org: org}
+		for _, v := range d.Variables {
+			dv := types.DesiredVariable{Name: v.Name, Value: v.Value, Visibility: v.Visibility}
+			if dv.Visibility == "" {
+				dv.Visibility = "all"
+			}
+			if dv.Visibility == "selected" {
+				dv.SelectedRepositories = v.SelectedRepositories
+			}
+			scope.Variables = append(scope.Variables, dv)
+		}
+		scopes := []types.DesiredScope{scope}
+		for _, r := range d.Repositories {
+			scopes = append(scopes, types.DesiredScope{Owner: org, Repo: r.Name, Variables: desiredVariables(r.Variables)})
+			for _, e := range r.Environments {
+				scopes = append(scopes, types.DesiredScope{Owner: org, Repo: r.Name, Environment: e.Name, Variables: desiredVariables(e.Variables)})
+			}
+		}
+		return scopes, nil
+	}
+
+	scopes := []types.DesiredScope{{Owner: org, Repo: repo, Environment: env, Variables: desiredVariables(d.Variables)}}
+	for _, e := range d.Environments {
+		scopes = append(scopes, types.DesiredScope{Owner: org, Repo: repo, Environment: e.Name, Variables: desiredVariables(e.Variables)})
+	}
+	return scopes, nil
+}
+
+// desiredVariables converts variables for a repository or environment,
+// which have no visibility
+func desiredVariables(vars []Variable) []types.DesiredVariable {
+	var out []types.DesiredVariable
+	for _, v := range vars {
+		out = append(out, types.DesiredVariable{Name: v.Name, Value: v.Value})
+	}
+	return out
+}
@@ -0,0 +1,196 @@
+package dump
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func TestParse_WrittenFormats(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			want := sampleDump()
+			got, err := Parse([]byte(write(t, want, format)), format)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Parse() =\n %+v\nwant\n %+v", got, want)
+			}
+		})
+	}
+
+	t.Run("env", func(t *testing.T) {
+		d := sampleDump()
+		d.Environments = nil
+		got, err := Parse([]byte(write(t, d, FormatEnv)+"export QUOTED='single'\n"), FormatEnv)
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		want := []Variable{
+			{Name: "LOG_LEVEL", Value: "info", line: 2},
+			{Name: "SCRIPT", Value: "echo \"hi\"\nexit 0", line: 3},
+			{Name: "QUOTED", Value: "single", line: 4},
+		}
+		if !reflect.DeepEqual(got.Variables, want) {
+			t.Errorf("Variables =\n %+v\nwant\n %+v", got.Variables, want)
+		}
+	})
+}
+
+func TestParse_Errors(t *testing.T) {
+	big := strings.Repeat("x", MaxValueSize+1)
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		wantErr string
+	}{
+		{"empty", FormatJSON, "", "file is empty"},
+		{"unknown key", FormatJSON, `{"version":1,"org":"acme","variables":[{"name":"A","value":"a","secret":true}]}`, `unknown field "secret"`},
+		{"wrong version", FormatYAML, "version: 2\norg: acme\nvariables: []\n", "unsupported version 2"},
+		{"masked", FormatJSON, `{"version":1,"org":"acme","values_masked":true,"variables":[]}`, "values are masked"},
+		{"missing name", FormatJSON, `{"version":1,"org":"acme","variables":[{"name":"A","value":"a"},{"value":"b"}]}`, "variable 2: missing name"},
+		{"missing value", FormatYAML, "version: 1\norg: acme\nvariables:\n  - name: A\n", "variable 1 (A): missing value"},
+		{"invalid name", FormatEnv, "# comment\nMY-VAR=x\n", "line 2 (MY-VAR): name may only contain"},
+		{"oversized value", FormatEnv, "A=a\nBIG=" + big + "\n", "line 2 (BIG): value is 49153 bytes, over the 49152-byte limit"},
+		{"duplicate name", FormatEnv, "A=a\na=b\n", "line 2 (a): name is already used by line 1 (A)"},
+		{"invalid visibility", FormatJSON, `{"version":1,"org":"acme","variables":[{"name":"A","value":"a","visibility":"public"}]}`, `variable 1 (A): invalid visibility "public"`},
+		{"environment entry", FormatJSON, `{"version":1,"org":"acme","repo":"app","variables":[],"environments":[{"name":"prod","variables":[{"name":"A","value":""}]}]}`, "environment prod, variable 1 (A): missing value"},
+		{"duplicate environment", FormatJSON, `{"version":1,"org":"acme","repo":"app","variables":[],"environments":[{"name":"prod","variables":[]},{"name":"PROD","variables":[]}]}`, "environment PROD is listed more than once"},
+		{"repository entry", FormatJSON, `{"version":1,"org":"acme","variables":[],"repositories":[{"name":"app","variables":[],"environments":[{"name":"prod","variables":[{"name":"1A","value":"a"}]}]}]}`, "repository app, environment prod, variable 1 (1A): name may only contain"},
+		{"duplicate repository", FormatJSON, `{"version":1,"org":"acme","variables":[],"repositories":[{"name":"app","variables":[]},{"name":"App","variables":[]}]}`, "repository App is listed more than once"},
+		{"repositories in a repository dump", FormatJSON, `{"version":1,"org":"acme","repo":"app","variables":[],"repositories":[{"name":"web","variables":[]}]}`, "a repository dump cannot hold repositories"},
+		{"env syntax", FormatEnv, "A=a\nNOT A PAIR\n", "line 2: expected NAME=value"},
+		{"env bad quotes", FormatEnv, `A="a\q"` + "\n", "line 1: invalid quoted value"},
+		{"csv", FormatCSV, "name,value\n", `unsupported format "csv"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content), tt.format)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_NamesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.env")
+	if err := os.WriteFile(path, []byte("A=\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(path, FormatEnv)
+	if err == nil || err.Error() != path+": line 1 (A): missing value" {
+		t.Errorf("Load() error = %v, want the file and entry", err)
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	tests := map[string]string{
+		"backup.json":     FormatJSON,
+		"dir/vars.YAML":   FormatYAML,
+		"vars.yml":        FormatYAML,
+		".env":            FormatEnv,
+		"production.env":  FormatEnv,
+		"vars.csv":        "",
+		"vars.json.bak":   "",
+		"env/environment": "",
+	}
+	for path, want := range tests {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDesired(t *testing.T) {
+	d := sampleDump()
+	d.Variables[0].Visibility = "private"
+
+	tests := []struct {
+		name    string
+		repo    string
+		env     string
+		dump    *Dump
+		want    []types.DesiredScope
+		wantErr string
+	}{
+		{
+			name: "repository with environments",
+			repo: "web",
+			dump: d,
+			want: []types.DesiredScope{
+				{Owner: "other", Repo: "web", Variables: []types.DesiredVariable{
+					{Name: "LOG_LEVEL", Value: "info"},
+					{Name: "SCRIPT", Value: "echo \"hi\"\nexit 0"},
+				}},
+				{Owner: "other", Repo: "web", Environment: "production", Variables: []types.DesiredVariable{
+					{Name: "URL", Value: " https://app.example.com, eu "},
+				}},
+			},
+		},
+		{
+			name: "organization",
+			dump: &Dump{Variables: []Variable{
+				{Name: "A", Value: "a"},
+				{Name: "B", Value: "b", Visibility: "selected", SelectedRepositories: []string{"app"}},
+			}},
+			want: []types.DesiredScope{{Org: "other", Variables: []types.DesiredVariable{
+				{Name: "A", Value: "a", Visibility: "all"},
+				{Name: "B", Value: "b", Visibility: "selected", SelectedRepositories: []string{"app"}},
+			}}},
+		},
+		{
+			name: "environment",
+			repo: "web",
+			env:  "staging",
+			dump: &Dump{Variables: []Variable{{Name: "A", Value: "a", Visibility: "private"}}},
+			want: []types.DesiredScope{{Owner: "other", Repo: "web", Environment: "staging", Variables: []types.DesiredVariable{{Name: "A", Value: "a"}}}},
+		},
+		{
+			name: "organization backup",
+			dump: &Dump{
+				Variables: []Variable{{Name: "A", Value: "a", Visibility: "private"}},
+				Repositories: []Repository{
+					{Name: "app", Variables: []Variable{{Name: "B", Value: "b"}}, Environments: []Environment{
+						{Name: "production", Variables: []Variable{{Name: "C", Value: "c"}}},
+					}},
+					{Name: "web", Variables: []Variable{}},
+				},
+			},
+			want: []types.DesiredScope{
+				{Org: "other", Variables: []types.DesiredVariable{{Name: "A", Value: "a", Visibility: "private"}}},
+				{Owner: "other", Repo: "app", Variables: []types.DesiredVariable{{Name: "B", Value: "b"}}},
+				{Owner: "other", Repo: "app", Environment: "production", Variables: []types.DesiredVariable{{Name: "C", Value: "c"}}},
+				{Owner: "other", Repo: "web"},
+			},
+		},
+		{name: "repositories into a repository", repo: "web", dump: &Dump{Repositories: []Repository{{Name: "app"}}}, wantErr: "holds the variables of 1 repository(ies); import it into an organization"},
+		{name: "environments into an organization", dump: d, wantErr: "holds the variables of 1 environment(s); import it into a repository"},
+		{name: "environments into an environment", repo: "web", env: "staging", dump: d, wantErr: "import it into a repository"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dump.Desired("other", tt.repo, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Desired() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Desired() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Desired() =\n %+v\nwant\n %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -19,16 +20,75 @@ var loadedFromFile = make(map[string]bool)
 // present in the environment. It silently returns nil when the file
 // does not exist so callers don't need to guard with os.Stat first.
 func Load(path string) error {
+	return LoadFiles([]string{path}, false)
+}
+
+// LoadFiles reads the env files at paths, in order, and sets their
+// variables. A variable in a later file overrides the same variable in an
+// earlier one, but variables set in the environment by anything other
+// than an env file are never overwritten. A missing file is an error when
+// required is true and skipped otherwise.
+func LoadFiles(paths []string, required bool) error {
+	values := make(map[string]string)
+	var keys []string
+	for _, path := range paths {
+		entries, err := readFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && !required {
+				continue // missing .env file is not an error
+			}
+			return err
+		}
+		for _, e := range entries {
+			if _, seen := values[e.Key]; !seen {
+				keys = append(keys, e.Key)
+			}
+			values[e.Key] = e.Value
+		}
+	}
+
+	for _, key := range keys {
+		// Only set variables that are not already in the environment so
+		// real env vars and CLI flags always take precedence.
+		if _, exists := os.LookupEnv(key); exists && !loadedFromFile[key] {
+			continue
+		}
+		if err := os.Setenv(key, values[key]); err != nil {
+			return fmt.Errorf("setting env var %s: %w", key, err)
+		}
+		loadedFromFile[key] = true
+	}
+	return nil
+}
+
+// readFile parses the env file at path
+func readFile(path string) ([]Entry, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil // missing .env file is not an error
-		}
-		return fmt.Errorf("opening env file: %w", err)
+		return nil, fmt.Errorf("opening env file: %w", err)
 	}
 	defer f.Close() //nolint:errcheck // best-effort close on read-only file
 
-	scanner := bufio.NewScanner(f)
+	entries, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Entry is a single KEY=VALUE pair read from an env file.
+type Entry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// Parse reads KEY=VALUE lines from r in .env syntax and returns them in
+// file order. Blank lines, comments, and an optional "export " prefix are
+// handled the same way as Load. The environment is not modified.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
 	lineNum := 0
 
 	for scanner.Scan() {
@@ -45,20 +105,16 @@ func Load(path string) error {
 
 		key, value, err := parseLine(line)
 		if err != nil {
-			return fmt.Errorf("env file line %d: %w", lineNum, err)
+			return nil, fmt.Errorf("env file line %d: %w", lineNum, err)
 		}
 
-		// Only set variables that are not already in the environment so
-		// real env vars and CLI flags always take precedence.
-		if _, exists := os.LookupEnv(key); !exists {
-			if err := os.Setenv(key, value); err != nil {
-				return fmt.Errorf("setting env var %s: %w", key, err)
-			}
-			loadedFromFile[key] = true
-		}
+		entries = append(entries, Entry{Key: key, Value: value, Line: lineNum})
 	}
 
-	return scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
 }
 
 // parseLine splits a "KEY=VALUE" line and returns the unquoted key and
@@ -3,6 +3,7 @@ package envfile
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -64,6 +65,55 @@ func TestLoad_DoesNotOverrideExistingVars(t *testing.T) {
 	}
 }
 
+func TestLoadFiles_Layered(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, ".env")
+	staging := filepath.Join(dir, ".env.staging")
+	if err := os.WriteFile(base, []byte("LAYER_ORG=base-org\nLAYER_REPO=base-repo\nLAYER_SHELL=from-file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(staging, []byte("LAYER_ORG=staging-org\nLAYER_SHELL=from-staging\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"LAYER_ORG", "LAYER_REPO"} {
+		t.Setenv(key, "")
+		_ = os.Unsetenv(key)
+	}
+	t.Setenv("LAYER_SHELL", "from-shell")
+	defer ResetLoaded()
+
+	if err := LoadFiles([]string{base, staging}, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for key, want := range map[string]string{
+		"LAYER_ORG":   "staging-org", // the later file wins
+		"LAYER_REPO":  "base-repo",
+		"LAYER_SHELL": "from-shell", // the shell wins over every file
+	} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if !LoadedFromFile("LAYER_ORG") || LoadedFromFile("LAYER_SHELL") {
+		t.Errorf("LoadedFromFile() = %v for LAYER_ORG and %v for LAYER_SHELL, want true and false",
+			LoadedFromFile("LAYER_ORG"), LoadedFromFile("LAYER_SHELL"))
+	}
+}
+
+func TestLoadFiles_MissingRequired(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), ".env.prod-migration")
+
+	err := LoadFiles([]string{missing}, true)
+	if err == nil || !strings.Contains(err.Error(), ".env.prod-migration") {
+		t.Fatalf("LoadFiles() error = %v, want one naming the missing file", err)
+	}
+	if err := LoadFiles([]string{missing}, false); err != nil {
+		t.Fatalf("LoadFiles() of an optional missing file unexpected error: %v", err)
+	}
+}
+
 func TestLoad_QuotedValues(t *testing.T) {
 	dir := t.TempDir()
 	envPath := filepath.Join(dir, ".env")
@@ -184,3 +234,31 @@ func TestParseLine(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_DoesNotSetEnv(t *testing.T) {
+	t.Setenv("PARSE_ONLY_KEY", "")
+	_ = os.Unsetenv("PARSE_ONLY_KEY")
+
+	content := "# comment\n\nexport PARSE_ONLY_KEY=value\nOTHER='quoted'\n"
+	entries, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Entry{
+		{Key: "PARSE_ONLY_KEY", Value: "value", Line: 3},
+		{Key: "OTHER", Value: "quoted", Line: 4},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %v", len(entries), len(want), entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	if _, ok := os.LookupEnv("PARSE_ONLY_KEY"); ok {
+		t.Error("Parse must not modify the environment")
+	}
+}
@@ -0,0 +1,76 @@
+// Package hooks runs the shell commands configured with --pre-hook and
+// --post-hook around a migration.
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// Env returns the GVM_* variables exported to a hook: the mode, source,
+// target, whether the run is a dry run, and the report file. For the
+// post-hook, result and runErr are those of the finished run and the
+// created, updated, and error counts are added; the pre-hook passes nil for
+// both.
+func Env(cfg *types.MigrationConfig, reportFile string, result *types.MigrationResult, runErr error) []string {
+	source, target := config.Endpoints(cfg)
+	if target == "" {
+		var targets []string
+		for _, t := range cfg.Targets {
+			targets = append(targets, t.String())
+		}
+		target = strings.Join(targets, ",")
+	}
+
+	env := []string{
+		"GVM_MODE=" + string(cfg.Mode),
+		"GVM_SOURCE=" + source,
+		"GVM_TARGET=" + target,
+		"GVM_DRY_RUN=" + strconv.FormatBool(cfg.DryRun),
+		"GVM_REPORT_FILE=" + reportFile,
+	}
+	if result == nil && runErr == nil {
+		return env
+	}
+
+	created, updated, errors := 0, 0, 0
+	if result != nil {
+		created, updated, errors = result.Created, result.Updated, len(result.Errors)
+	}
+	if runErr != nil {
+		errors++
+	}
+	return append(env,
+		"GVM_CREATED="+strconv.Itoa(created),
+		"GVM_UPDATED="+strconv.Itoa(updated),
+		"GVM_ERRORS="+strconv.Itoa(errors),
+	)
+}
+
+// Run runs command with the system shell, adding env to the environment of
+// the process. The command's output goes where the log messages go, and its
+// errors to stderr; a non-zero exit status is returned as an error.
+func Run(command string, env []string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = logger.Writer()
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%q: %w", command, err)
+	}
+	return nil
+}
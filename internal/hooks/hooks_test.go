@@ -0,0 +1,101 @@
+package hooks
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// writeScript writes a shell script that records the GVM_* variables it is
+// run with, one NAME=VALUE per line, to the file passed as its argument
+func writeScript(t *testing.T, dir string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts are shell scripts")
+	}
+	path := filepath.Join(dir, "hook.sh")
+	script := "#!/bin/sh\nenv | grep '^GVM_' | sort > \"$1\"\n"
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestRun_PreHookEnv(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir)
+	out := filepath.Join(dir, "env.txt")
+
+	cfg := &types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "src", TargetOrg: "dst", DryRun: true}
+	if err := Run(script+" "+out, Env(cfg, "report.json", nil, nil)); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	want := []string{
+		"GVM_DRY_RUN=true",
+		"GVM_MODE=org-to-org",
+		"GVM_REPORT_FILE=report.json",
+		"GVM_SOURCE=src",
+		"GVM_TARGET=dst",
+	}
+	if got := readLines(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("Hook environment = %v, want %v", got, want)
+	}
+}
+
+func TestRun_PostHookEnv(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir)
+	out := filepath.Join(dir, "env.txt")
+
+	cfg := &types.MigrationConfig{
+		Mode:        types.ModeRepoToRepo,
+		SourceOwner: "src",
+		SourceRepo:  "app",
+		Targets:     []types.RepoRef{{Owner: "dst", Repo: "api"}, {Owner: "dst", Repo: "web"}},
+	}
+	result := &types.MigrationResult{Created: 3, Updated: 2}
+	result.AddError(errors.New("boom"))
+	if err := Run(script+" "+out, Env(cfg, "", result, errors.New("stopped"))); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	want := []string{
+		"GVM_CREATED=3",
+		"GVM_DRY_RUN=false",
+		"GVM_ERRORS=2",
+		"GVM_MODE=repo-to-repo",
+		"GVM_REPORT_FILE=",
+		"GVM_SOURCE=src/app",
+		"GVM_TARGET=dst/api,dst/web",
+		"GVM_UPDATED=2",
+	}
+	if got := readLines(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("Hook environment = %v, want %v", got, want)
+	}
+}
+
+func TestRun_Failure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh exit codes")
+	}
+	err := Run("exit 3", nil)
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("Run() error = %v, want the exit status", err)
+	}
+}
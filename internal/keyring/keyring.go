@@ -0,0 +1,205 @@
+// Package keyring keeps personal access tokens in the credential store of
+// the operating system: the macOS keychain, the Secret Service of Linux
+// desktops through libsecret's secret-tool, or the Windows Credential
+// Manager.
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Service is the service name tokens are stored under
+const Service = "gh-vars-migrator"
+
+// indexAccount holds the accounts of the stored tokens, one per line, since
+// the OS keyrings cannot list the entries of a service portably
+const indexAccount = "index"
+
+var (
+	// ErrNotFound is returned when the keyring holds no secret for an account
+	ErrNotFound = errors.New("not found in the keyring")
+	// ErrUnavailable is returned when there is no keyring to use, such as
+	// on a Linux host without secret-tool or a Secret Service
+	ErrUnavailable = errors.New("the OS keyring is not available")
+)
+
+// Keyring stores secrets by service and account
+type Keyring interface {
+	Get(service, account string) (string, error)
+	Set(service, account, secret string) error
+	Delete(service, account string) error
+}
+
+// Memory is a Keyring kept in memory, for tests
+type Memory struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// NewMemory returns an empty in-memory keyring
+func NewMemory() *Memory {
+	return &Memory{secrets: map[string]string{}}
+}
+
+// Get returns the secret of account
+func (m *Memory) Get(service, account string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, ok := m.secrets[service+"\x00"+account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set stores the secret of account, replacing any earlier one
+func (m *Memory) Set(service, account, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[service+"\x00"+account] = secret
+	return nil
+}
+
+// Delete removes the secret of account
+func (m *Memory) Delete(service, account string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := service + "\x00" + account
+	if _, ok := m.secrets[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.secrets, key)
+	return nil
+}
+
+// Entry is a token stored for one side of a migration on one host
+type Entry struct {
+	Side  string
+	Host  string
+	Token string
+}
+
+// Store keeps the tokens of the source and target sides per host in a
+// Keyring
+type Store struct {
+	ring Keyring
+}
+
+// NewStore returns a Store of the tokens in ring
+func NewStore(ring Keyring) *Store {
+	return &Store{ring: ring}
+}
+
+// account names the keyring entry of side on host
+func account(side, host string) string {
+	return side + ":" + strings.ToLower(host)
+}
+
+// Token returns the token stored for side on host
+func (s *Store) Token(side, host string) (string, error) {
+	return s.ring.Get(Service, account(side, host))
+}
+
+// Save stores token for side on host, replacing any earlier one
+func (s *Store) Save(side, host, token string) error {
+	if err := s.ring.Set(Service, account(side, host), token); err != nil {
+		return err
+	}
+	accounts, err := s.index()
+	if err != nil {
+		return err
+	}
+	for _, a := range accounts {
+		if a == account(side, host) {
+			return nil
+		}
+	}
+	return s.setIndex(append(accounts, account(side, host)))
+}
+
+// Remove deletes the token of side on host
+func (s *Store) Remove(side, host string) error {
+	if err := s.ring.Delete(Service, account(side, host)); err != nil {
+		return err
+	}
+	accounts, err := s.index()
+	if err != nil {
+		return err
+	}
+	kept := accounts[:0]
+	for _, a := range accounts {
+		if a != account(side, host) {
+			kept = append(kept, a)
+		}
+	}
+	return s.setIndex(kept)
+}
+
+// Entries returns the stored tokens sorted by host and side. Entries
+// removed from the keyring by other tools are left out.
+func (s *Store) Entries() ([]Entry, error) {
+	accounts, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, a := range accounts {
+		side, host, ok := strings.Cut(a, ":")
+		if !ok {
+			continue
+		}
+		token, err := s.ring.Get(Service, a)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{Side: side, Host: host, Token: token})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Host != entries[j].Host {
+			return entries[i].Host < entries[j].Host
+		}
+		return entries[i].Side < entries[j].Side
+	})
+	return entries, nil
+}
+
+// index returns the accounts listed in the index entry
+func (s *Store) index() ([]string, error) {
+	list, err := s.ring.Get(Service, indexAccount)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var accounts []string
+	for _, a := range strings.Split(list, "\n") {
+		if a != "" {
+			accounts = append(accounts, a)
+		}
+	}
+	return accounts, nil
+}
+
+// setIndex replaces the index entry with accounts, deleting it when there
+// are none
+func (s *Store) setIndex(accounts []string) error {
+	if len(accounts) == 0 {
+		if err := s.ring.Delete(Service, indexAccount); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return nil
+	}
+	sort.Strings(accounts)
+	if err := s.ring.Set(Service, indexAccount, strings.Join(accounts, "\n")); err != nil {
+		return fmt.Errorf("failed to update the keyring index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityPath is the macOS tool managing the keychain
+const securityPath = "/usr/bin/security"
+
+// Default returns the keyring of the operating system: the login keychain
+func Default() Keyring {
+	return keychain{}
+}
+
+// keychain runs security, passing commands on standard input with security
+// -i so that the token stays off the command line
+type keychain struct{}
+
+// run runs security with args and stdin, returning its standard output;
+// exit status 44 is the keychain's item not found
+func (keychain) run(stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(securityPath, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(err, &exitErr) && exitErr.ExitCode() == 44:
+			return "", ErrNotFound
+		case !errors.As(err, &exitErr):
+			return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		return "", fmt.Errorf("%w: security: %s", ErrUnavailable, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (k keychain) Get(service, account string) (string, error) {
+	out, err := k.run("", "find-generic-password", "-s", service, "-a", account, "-w")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+func (k keychain) Set(service, account, secret string) error {
+	// The service and account names have no spaces or quotes to escape,
+	// and -X takes the secret hex-encoded
+	_, err := k.run(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", service, account, hex.EncodeToString([]byte(secret))), "-i")
+	return err
+}
+
+func (k keychain) Delete(service, account string) error {
+	_, err := k.run("", "delete-generic-password", "-s", service, "-a", account)
+	return err
+}
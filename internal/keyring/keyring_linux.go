@@ -0,0 +1,67 @@
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Default returns the keyring of the operating system: the Secret Service,
+// such as GNOME Keyring or KWallet, through libsecret's secret-tool
+func Default() Keyring {
+	return secretService{}
+}
+
+// secretService runs secret-tool, which keeps the token off the command
+// line by reading it from standard input
+type secretService struct{}
+
+// run runs secret-tool with args and stdin, returning its standard output;
+// a missing secret-tool or a failing Secret Service is ErrUnavailable
+func (secretService) run(stdin string, args ...string) (string, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return "", fmt.Errorf("%w: secret-tool is not installed", ErrUnavailable)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		// lookup exits 1 without a message when nothing matches
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("%w: secret-tool: %s", ErrUnavailable, msg)
+	}
+	return stdout.String(), nil
+}
+
+func (s secretService) Get(service, account string) (string, error) {
+	out, err := s.run("", "lookup", "service", service, "account", account)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+func (s secretService) Set(service, account, secret string) error {
+	_, err := s.run(secret, "store", "--label="+service+" ("+account+")", "service", service, "account", account)
+	return err
+}
+
+func (s secretService) Delete(service, account string) error {
+	// clear succeeds whether or not anything matched
+	if _, err := s.Get(service, account); err != nil {
+		return err
+	}
+	_, err := s.run("", "clear", "service", service, "account", account)
+	return err
+}
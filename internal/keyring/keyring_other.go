@@ -0,0 +1,16 @@
+//go:build !darwin && !linux && !windows
+
+package keyring
+
+// Default returns the keyring of the operating system, which is not
+// supported on this one
+func Default() Keyring {
+	return unsupported{}
+}
+
+// unsupported is the keyring of an operating system without one
+type unsupported struct{}
+
+func (unsupported) Get(service, account string) (string, error) { return "", ErrUnavailable }
+func (unsupported) Set(service, account, secret string) error   { return ErrUnavailable }
+func (unsupported) Delete(service, account string) error        { return ErrUnavailable }
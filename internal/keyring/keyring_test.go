@@ -0,0 +1,110 @@
+package keyring
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	if _, err := m.Get(Service, "source:github.com"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of a missing secret: err = %v, want ErrNotFound", err)
+	}
+	if err := m.Set(Service, "source:github.com", "ghp_one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set(Service, "source:github.com", "ghp_two"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := m.Get(Service, "source:github.com"); err != nil || got != "ghp_two" {
+		t.Errorf("Get = %q, %v, want ghp_two", got, err)
+	}
+	if _, err := m.Get("other", "source:github.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of another service: err = %v, want ErrNotFound", err)
+	}
+	if err := m.Delete(Service, "source:github.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Delete(Service, "source:github.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	m := NewMemory()
+	s := NewStore(m)
+
+	if _, err := s.Token("source", "github.com"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Token before Save: err = %v, want ErrNotFound", err)
+	}
+	entries, err := s.Entries()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Entries of an empty store = %v, %v", entries, err)
+	}
+
+	for _, e := range []Entry{
+		{"target", "GHE.example.com", "ghp_target"},
+		{"source", "github.com", "ghp_old"},
+		{"source", "github.com", "ghp_source"},
+	} {
+		if err := s.Save(e.Side, e.Host, e.Token); err != nil {
+			t.Fatalf("Save(%s, %s): %v", e.Side, e.Host, err)
+		}
+	}
+	if got, err := s.Token("source", "github.com"); err != nil || got != "ghp_source" {
+		t.Errorf("Token(source) = %q, %v, want the replaced token", got, err)
+	}
+	if got, err := s.Token("target", "ghe.example.com"); err != nil || got != "ghp_target" {
+		t.Errorf("Token(target) = %q, %v; hosts are case-insensitive", got, err)
+	}
+	if _, err := s.Token("target", "github.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Token on another host: err = %v, want ErrNotFound", err)
+	}
+
+	want := []Entry{
+		{"target", "ghe.example.com", "ghp_target"},
+		{"source", "github.com", "ghp_source"},
+	}
+	if entries, err := s.Entries(); err != nil || !reflect.DeepEqual(entries, want) {
+		t.Errorf("Entries = %v, %v, want %v", entries, err, want)
+	}
+
+	// A token deleted by another tool is left out of the entries
+	if err := m.Delete(Service, "target:ghe.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := s.Entries(); err != nil || !reflect.DeepEqual(entries, want[1:]) {
+		t.Errorf("Entries after an outside delete = %v, %v, want %v", entries, err, want[1:])
+	}
+
+	if err := s.Remove("source", "github.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove("source", "github.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Remove: err = %v, want ErrNotFound", err)
+	}
+	if entries, err := s.Entries(); err != nil || len(entries) != 0 {
+		t.Errorf("Entries after Remove = %v, %v", entries, err)
+	}
+}
+
+// unavailable is a keyring that cannot be reached
+type unavailable struct{}
+
+func (unavailable) Get(service, account string) (string, error) { return "", ErrUnavailable }
+func (unavailable) Set(service, account, secret string) error   { return ErrUnavailable }
+func (unavailable) Delete(service, account string) error        { return ErrUnavailable }
+
+func TestStore_Unavailable(t *testing.T) {
+	s := NewStore(unavailable{})
+	if _, err := s.Token("source", "github.com"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Token: err = %v, want ErrUnavailable", err)
+	}
+	if err := s.Save("source", "github.com", "ghp_x"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Save: err = %v, want ErrUnavailable", err)
+	}
+	if _, err := s.Entries(); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Entries: err = %v, want ErrUnavailable", err)
+	}
+}
@@ -2,7 +2,11 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/term"
 )
 
 // Color codes for terminal output
@@ -15,37 +19,194 @@ const (
 	colorCyan   = "\033[36m"
 )
 
+// color adds the ANSI color codes to the message prefixes
+var color = DetectColor(os.Getenv, term.IsTerminal(os.Stdout))
+
+// DetectColor reports whether messages should be colored: never when
+// NO_COLOR is set, always when CLICOLOR_FORCE is set to anything but "0",
+// and otherwise only when standard output is a terminal
+func DetectColor(getenv func(string) string, isTerminal bool) bool {
+	if getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		return true
+	}
+	return isTerminal
+}
+
+// SetColor turns the colors of the message prefixes on or off
+func SetColor(on bool) {
+	color = on
+}
+
+// prefix returns the prefix of a message, in the color code when colors
+// are on
+func prefix(code, text string) string {
+	if !color {
+		return text
+	}
+	return code + text + colorReset
+}
+
+// annotate adds a GitHub Actions workflow command after every warning and
+// error, so that they are shown as annotations of the workflow run
+var annotate bool
+
+// DetectAnnotations reports whether the process runs in a GitHub Actions
+// job, where GITHUB_ACTIONS is set to "true"
+func DetectAnnotations(getenv func(string) string) bool {
+	return getenv("GITHUB_ACTIONS") == "true"
+}
+
+// SetAnnotations turns the workflow annotations of warnings and errors on
+// or off
+func SetAnnotations(on bool) {
+	annotate = on
+}
+
+// annotation writes the ::warning or ::error workflow command of a message
+// to w, with title as the title of the annotation when it is set
+func annotation(w io.Writer, command, title, message string) {
+	if !annotate {
+		return
+	}
+	props := ""
+	if title != "" {
+		props = " title=" + escapeProperty(title)
+	}
+	fmt.Fprintf(w, "::%s%s::%s\n", command, props, escapeData(message))
+}
+
+// escapeData escapes the message of a workflow command: "%" and line
+// breaks, so that the message stays on the line of its command and no
+// part of it can start another. The command ends at its first "::", so
+// colons in the message need no escaping.
+var escapeData = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace
+
+// escapeProperty escapes a property value of a workflow command, where ":"
+// and "," would end the value
+var escapeProperty = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace
+
+// toStderr sends every message to standard error, so that standard output
+// only carries a command's machine-readable output
+var toStderr bool
+
+// UseStderr sends all messages to standard error when on is true, and back
+// to standard output (errors aside) when it is false
+func UseStderr(on bool) {
+	toStderr = on
+}
+
+// Level decides which messages are printed
+type Level int
+
+// Levels from the fewest messages to the most. LevelQuiet leaves out the
+// per-variable messages printed with Detail and DetailSuccess; phase
+// headers, warnings, errors, and summaries are still printed. LevelVerbose
+// adds the Debug messages.
+const (
+	LevelQuiet Level = iota
+	LevelNormal
+	LevelVerbose
+)
+
+// level is the current Level
+var level = LevelNormal
+
+// SetLevel sets which messages are printed
+func SetLevel(l Level) {
+	level = l
+}
+
+// Writer returns where messages other than errors are printed: standard
+// output, or standard error after UseStderr(true). Output that accompanies
+// the messages, e.g. that of hook commands, is written there too.
+func Writer() io.Writer {
+	if toStderr {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
 // Info prints an info message
 func Info(format string, args ...interface{}) {
-	fmt.Printf(colorBlue+"ℹ "+colorReset+format+"\n", args...)
+	fmt.Fprintf(Writer(), prefix(colorBlue, "ℹ ")+format+"\n", args...)
 }
 
 // Success prints a success message
 func Success(format string, args ...interface{}) {
-	fmt.Printf(colorGreen+"✓ "+colorReset+format+"\n", args...)
+	fmt.Fprintf(Writer(), prefix(colorGreen, "✓ ")+format+"\n", args...)
+}
+
+// Detail prints an info message about a single variable, unless the level
+// is LevelQuiet
+func Detail(format string, args ...interface{}) {
+	if level > LevelQuiet {
+		Info(format, args...)
+	}
 }
 
-// Warning prints a warning message
+// DetailSuccess prints a success message about a single variable, unless
+// the level is LevelQuiet
+func DetailSuccess(format string, args ...interface{}) {
+	if level > LevelQuiet {
+		Success(format, args...)
+	}
+}
+
+// Warning prints a warning message, and in a GitHub Actions job a warning
+// annotation
 func Warning(format string, args ...interface{}) {
-	fmt.Printf(colorYellow+"⚠ "+colorReset+format+"\n", args...)
+	message := fmt.Sprintf(format, args...)
+	warningLine(message)
+	annotation(Writer(), "warning", "", message)
 }
 
-// Error prints an error message
+// warningLine prints a warning message without an annotation
+func warningLine(message string) {
+	fmt.Fprintln(Writer(), prefix(colorYellow, "⚠ ")+message)
+}
+
+// Error prints an error message, and in a GitHub Actions job an error
+// annotation
 func Error(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, colorRed+"✗ "+colorReset+format+"\n", args...)
+	printError("", fmt.Sprintf(format, args...))
+}
+
+// VariableError prints an error message about the variable name; its
+// annotation in a GitHub Actions job is titled with the name
+func VariableError(name, format string, args ...interface{}) {
+	printError("Variable "+name, fmt.Sprintf(format, args...))
+}
+
+// SummaryError prints an error message without an annotation, for the
+// errors a summary lists again after they were reported
+func SummaryError(format string, args ...interface{}) {
+	fmt.Fprintln(os.Stderr, prefix(colorRed, "✗ ")+fmt.Sprintf(format, args...))
 }
 
-// Debug prints a debug message
+// printError prints an error message and its annotation with title
+func printError(title, message string) {
+	fmt.Fprintln(os.Stderr, prefix(colorRed, "✗ ")+message)
+	annotation(os.Stderr, "error", title, message)
+}
+
+// Debug prints a debug message at LevelVerbose
 func Debug(format string, args ...interface{}) {
-	fmt.Printf(colorCyan+"[DEBUG] "+colorReset+format+"\n", args...)
+	if level < LevelVerbose {
+		return
+	}
+	fmt.Fprintf(Writer(), prefix(colorCyan, "[DEBUG] ")+format+"\n", args...)
 }
 
 // Plain prints a plain message without formatting
 func Plain(format string, args ...interface{}) {
-	fmt.Printf(format+"\n", args...)
+	fmt.Fprintf(Writer(), format+"\n", args...)
 }
 
-// PrintSummary prints a summary of the migration results
+// PrintSummary prints a summary of the migration results. Its skipped and
+// error counts are not annotated, since each was reported when it occurred.
 func PrintSummary(created, updated, skipped, errors int) {
 	Plain("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	Plain("Migration Summary")
@@ -58,10 +219,10 @@ func PrintSummary(created, updated, skipped, errors int) {
 		Success("Updated: %d", updated)
 	}
 	if skipped > 0 {
-		Warning("Skipped: %d", skipped)
+		warningLine(fmt.Sprintf("Skipped: %d", skipped))
 	}
 	if errors > 0 {
-		Error("Errors: %d", errors)
+		SummaryError("Errors: %d", errors)
 	}
 
 	total := created + updated + skipped
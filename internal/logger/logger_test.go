@@ -91,6 +91,12 @@ func TestError(t *testing.T) {
 
 // TestDebug tests the Debug logging function
 func TestDebug(t *testing.T) {
+	if output := captureOutput(func() { Debug("hidden message") }); output != "" {
+		t.Errorf("Expected no debug output without verbose, got: %s", output)
+	}
+
+	SetLevel(LevelVerbose)
+	defer SetLevel(LevelNormal)
 	output := captureOutput(func() {
 		Debug("debug message")
 	})
@@ -212,3 +218,209 @@ func TestFormattingWithArguments(t *testing.T) {
 		t.Errorf("Expected formatted output, got: %s", output)
 	}
 }
+
+// TestUseStderr tests that messages leave standard output while UseStderr
+// is on
+func TestUseStderr(t *testing.T) {
+	oldStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+	defer func() { os.Stderr = oldStderr }()
+
+	UseStderr(true)
+	stdout := captureOutput(func() {
+		Info("to stderr")
+		Plain("also to stderr")
+	})
+	UseStderr(false)
+	_ = w.Close()
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	if stdout != "" {
+		t.Errorf("Expected nothing on stdout, got: %s", stdout)
+	}
+	if !strings.Contains(buf.String(), "to stderr") || !strings.Contains(buf.String(), "also to stderr") {
+		t.Errorf("Expected the messages on stderr, got: %s", buf.String())
+	}
+
+	if output := captureOutput(func() { Info("back") }); !strings.Contains(output, "back") {
+		t.Errorf("Expected stdout again after UseStderr(false), got: %s", output)
+	}
+}
+
+// TestSetLevel_Quiet tests that LevelQuiet leaves out Detail and
+// DetailSuccess but not the other messages
+func TestSetLevel_Quiet(t *testing.T) {
+	SetLevel(LevelQuiet)
+	defer SetLevel(LevelNormal)
+
+	output := captureOutput(func() {
+		Detail("detail message")
+		DetailSuccess("created message")
+		Info("phase message")
+		Warning("warning message")
+		Debug("debug message")
+	})
+
+	for _, absent := range []string{"detail message", "created message", "debug message"} {
+		if strings.Contains(output, absent) {
+			t.Errorf("Expected %q to be left out, got: %s", absent, output)
+		}
+	}
+	for _, want := range []string{"phase message", "warning message"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, output)
+		}
+	}
+
+	SetLevel(LevelNormal)
+	if output := captureOutput(func() { Detail("shown") }); !strings.Contains(output, "shown") {
+		t.Errorf("Expected Detail to print when not quiet, got: %s", output)
+	}
+}
+
+// TestDetectColor tests how NO_COLOR, CLICOLOR_FORCE, and the terminal
+// decide whether messages are colored
+func TestDetectColor(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		isTerminal bool
+		want       bool
+	}{
+		{name: "terminal", isTerminal: true, want: true},
+		{name: "not a terminal", isTerminal: false, want: false},
+		{name: "NO_COLOR", env: map[string]string{"NO_COLOR": "1"}, isTerminal: true, want: false},
+		{name: "CLICOLOR_FORCE", env: map[string]string{"CLICOLOR_FORCE": "1"}, isTerminal: false, want: true},
+		{name: "CLICOLOR_FORCE=0", env: map[string]string{"CLICOLOR_FORCE": "0"}, isTerminal: false, want: false},
+		{name: "NO_COLOR wins", env: map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, isTerminal: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			if got := DetectColor(getenv, tt.isTerminal); got != tt.want {
+				t.Errorf("DetectColor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSetColor tests that every message prefix is colored only while
+// colors are on
+func TestSetColor(t *testing.T) {
+	SetLevel(LevelVerbose)
+	defer SetLevel(LevelNormal)
+	defer SetColor(color)
+
+	log := func() string {
+		return captureOutput(func() {
+			Info("info")
+			Success("success")
+			Warning("warning")
+			Debug("debug")
+		})
+	}
+
+	SetColor(true)
+	colored := log()
+	for _, want := range []string{colorBlue + "ℹ " + colorReset + "info", colorGreen + "✓ " + colorReset + "success",
+		colorYellow + "⚠ " + colorReset + "warning", colorCyan + "[DEBUG] " + colorReset + "debug"} {
+		if !strings.Contains(colored, want) {
+			t.Errorf("Expected colored output to contain %q, got: %q", want, colored)
+		}
+	}
+
+	SetColor(false)
+	plain := log()
+	if strings.Contains(plain, "\033[") {
+		t.Errorf("Expected no escape codes without colors, got: %q", plain)
+	}
+	if !strings.Contains(plain, "ℹ info\n") || !strings.Contains(plain, "[DEBUG] debug\n") {
+		t.Errorf("Expected the plain prefixes, got: %q", plain)
+	}
+}
+
+// captureStderr captures what f writes to stderr
+func captureStderr(f func()) string {
+	old := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+
+	f()
+
+	_ = w.Close()
+	os.Stderr = old
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	return buf.String()
+}
+
+// TestAnnotations tests the workflow commands added after warnings and
+// errors, and their escaping
+func TestAnnotations(t *testing.T) {
+	defer SetAnnotations(annotate)
+	defer SetColor(color)
+	SetColor(false)
+	SetAnnotations(true)
+
+	stdout := captureOutput(func() { Warning("disk at 90%% of quota") })
+	if want := "⚠ disk at 90% of quota\n::warning::disk at 90%25 of quota\n"; stdout != want {
+		t.Errorf("Warning() wrote %q, want %q", stdout, want)
+	}
+
+	stderr := captureStderr(func() {
+		Error("line one\r\nline two\n::error::injected")
+		VariableError("API_URL", "Failed to migrate variable '%s': %s", "API_URL", "HTTP 403: forbidden")
+		VariableError("A,B:C%", "bad")
+	})
+	for _, want := range []string{
+		"::error::line one%0D%0Aline two%0A::error::injected\n",
+		"::error title=Variable API_URL::Failed to migrate variable 'API_URL': HTTP 403: forbidden\n",
+		"::error title=Variable A%2CB%3AC%25::bad\n",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Expected stderr to contain %q, got:\n%s", want, stderr)
+		}
+	}
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.HasPrefix(line, "::") && !strings.HasPrefix(line, "::error") {
+			t.Errorf("A message started a workflow command of its own: %q", line)
+		}
+	}
+
+	// Summaries repeat errors already reported, so they are not annotated
+	stderr = captureStderr(func() { SummaryError("  1. %s", "failed") })
+	if strings.Contains(stderr, "::error") {
+		t.Errorf("SummaryError() added an annotation: %q", stderr)
+	}
+}
+
+// TestAnnotations_Off tests that no workflow command is written while
+// annotations are off
+func TestAnnotations_Off(t *testing.T) {
+	defer SetAnnotations(annotate)
+	SetAnnotations(false)
+
+	stdout := captureOutput(func() { Warning("careful") })
+	stderr := captureStderr(func() { VariableError("URL", "failed") })
+	if strings.Contains(stdout+stderr, "::") {
+		t.Errorf("Expected no annotations, got stdout %q and stderr %q", stdout, stderr)
+	}
+}
+
+func TestDetectAnnotations(t *testing.T) {
+	for value, want := range map[string]bool{"true": true, "": false, "false": false, "1": false} {
+		getenv := func(key string) string {
+			if key == "GITHUB_ACTIONS" {
+				return value
+			}
+			return ""
+		}
+		if got := DetectAnnotations(getenv); got != want {
+			t.Errorf("DetectAnnotations() with GITHUB_ACTIONS=%q = %v, want %v", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Package manifest loads and saves YAML manifests that declare the desired
+// Actions variables of organizations, repositories, and environments, for
+// apply --manifest and export --manifest.
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the manifest format version written by Save
+const Version = 1
+
+// Manifest is the desired state of a set of target scopes. Applying it
+// creates and updates the variables it declares; with pruning, variables of
+// those scopes that it does not declare are deleted.
+type Manifest struct {
+	Version int     `yaml:"version"`
+	Scopes  []Scope `yaml:"scopes"`
+}
+
+// Scope declares the variables of one organization (Org), repository (Repo
+// as owner/name), or repository environment (Repo and Environment)
+type Scope struct {
+	Org         string     `yaml:"org,omitempty"`
+	Repo        string     `yaml:"repo,omitempty"`
+	Environment string     `yaml:"environment,omitempty"`
+	Variables   []Variable `yaml:"variables"`
+
+	// line is where the scope starts in the loaded file
+	line int
+}
+
+// Variable declares one variable. Visibility and SelectedRepositories only
+// apply to organization variables; an organization variable without a
+// visibility is visible to all repositories.
+type Variable struct {
+	Name                 string   `yaml:"name"`
+	Value                string   `yaml:"value"`
+	Visibility           string   `yaml:"visibility,omitempty"`
+	SelectedRepositories []string `yaml:"selected_repositories,omitempty,flow"`
+
+	// line is where the variable starts in the loaded file
+	line int
+}
+
+// Label returns the scope label used in results, e.g. "org:acme",
+// "acme/app", or "acme/app:env:production"
+func (s Scope) Label() string {
+	return s.desired().Label()
+}
+
+// Load reads and validates the manifest at path. Errors name the file and
+// the line of the offending entry.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes and validates manifest data. Unknown keys are rejected.
+func Parse(data []byte) (*Manifest, error) {
+	var m Manifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("manifest is empty")
+		}
+		return nil, fmt.Errorf("invalid manifest: %s", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	// Decoding into a struct loses the positions, so they are read from
+	// the node tree for the validation messages.
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	m.recordLines(&root)
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// recordLines copies the line of every scope and variable from the node
+// tree of the same document
+func (m *Manifest) recordLines(root *yaml.Node) {
+	scopes := mappingValue(documentContent(root), "scopes")
+	if scopes == nil || scopes.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, sn := range scopes.Content {
+		if i >= len(m.Scopes) {
+			return
+		}
+		m.Scopes[i].line = sn.Line
+		vars := mappingValue(sn, "variables")
+		if vars == nil || vars.Kind != yaml.SequenceNode {
+			continue
+		}
+		for j, vn := range vars.Content {
+			if j < len(m.Scopes[i].Variables) {
+				m.Scopes[i].Variables[j].line = vn.Line
+			}
+		}
+	}
+}
+
+// documentContent returns the top-level node of a document node
+func documentContent(n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		return n.Content[0]
+	}
+	return n
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// variableName matches the names GitHub accepts for variables
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate checks that every scope is addressed exactly once and every
+// variable is complete and declared once per scope
+func (m *Manifest) Validate() error {
+	if m.Version != Version {
+		return fmt.Errorf("unsupported manifest version %d (expected %d)", m.Version, Version)
+	}
+	if len(m.Scopes) == 0 {
+		return fmt.Errorf("manifest declares no scopes")
+	}
+
+	seen := make(map[string]int, len(m.Scopes))
+	for _, s := range m.Scopes {
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("%s%w", at(s.line), err)
+		}
+		key := strings.ToUpper(s.Label())
+		if first, dup := seen[key]; dup {
+			return fmt.Errorf("%sscope %s is already declared at line %d", at(s.line), s.Label(), first)
+		}
+		seen[key] = s.line
+
+		names := make(map[string]int, len(s.Variables))
+		for _, v := range s.Variables {
+			if err := v.validate(s.Org != ""); err != nil {
+				return fmt.Errorf("%s%s: %w", at(v.line), s.Label(), err)
+			}
+			key := strings.ToUpper(v.Name)
+			if first, dup := names[key]; dup {
+				return fmt.Errorf("%s%s: variable %s is already declared at line %d", at(v.line), s.Label(), v.Name, first)
+			}
+			names[key] = v.line
+		}
+	}
+	return nil
+}
+
+// at formats a line prefix for validation messages; line is zero for
+// manifests that were not loaded from a file
+func at(line int) string {
+	if line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("line %d: ", line)
+}
+
+// validate checks how the scope is addressed
+func (s Scope) validate() error {
+	switch {
+	case s.Org != "" && s.Repo != "":
+		return fmt.Errorf("scope sets both org and repo; declare them as separate scopes")
+	case s.Org == "" && s.Repo == "":
+		return fmt.Errorf("scope needs an org or a repo")
+	case s.Org != "" && s.Environment != "":
+		return fmt.Errorf("scope %s: environment needs a repo, not an org", s.Org)
+	}
+	if s.Repo != "" {
+		owner, name, ok := strings.Cut(s.Repo, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("repo %q must be in owner/name form", s.Repo)
+		}
+	}
+	return nil
+}
+
+// validate checks one variable of an organization (org set) or repository
+// scope
+func (v Variable) validate(org bool) error {
+	if v.Name == "" {
+		return fmt.Errorf("variable without a name")
+	}
+	if !variableName.MatchString(v.Name) {
+		return fmt.Errorf("variable %s: name may only contain letters, digits, and underscores, and must not start with a digit", v.Name)
+	}
+	if !org {
+		if v.Visibility != "" || len(v.SelectedRepositories) > 0 {
+			return fmt.Errorf("variable %s: visibility only applies to organization variables", v.Name)
+		}
+		return nil
+	}
+	switch v.Visibility {
+	case "", "all", "private", "selected":
+	default:
+		return fmt.Errorf("variable %s: invalid visibility %q (expected all, private, or selected)", v.Name, v.Visibility)
+	}
+	if len(v.SelectedRepositories) > 0 && v.Visibility != "selected" {
+		return fmt.Errorf("variable %s: selected_repositories needs visibility selected", v.Name)
+	}
+	return nil
+}
+
+// Desired converts the manifest to the scopes applied by the migrator
+func (m *Manifest) Desired() []types.DesiredScope {
+	scopes := make([]types.DesiredScope, 0, len(m.Scopes))
+	for _, s := range m.Scopes {
+		scopes = append(scopes, s.desired())
+	}
+	return scopes
+}
+
+// desired converts one scope; organization variables without a visibility
+// get "all", which is what GitHub is sent for them
+func (s Scope) desired() types.DesiredScope {
+	d := types.DesiredScope{Org: s.Org, Environment: s.Environment}
+	d.Owner, d.Repo, _ = strings.Cut(s.Repo, "/")
+	for _, v := range s.Variables {
+		dv := types.DesiredVariable{Name: v.Name, Value: v.Value}
+		if s.Org != "" {
+			dv.Visibility = v.Visibility
+			if dv.Visibility == "" {
+				dv.Visibility = "all"
+			}
+			dv.SelectedRepositories = v.SelectedRepositories
+		}
+		d.Variables = append(d.Variables, dv)
+	}
+	return d
+}
+
+// FromDesired builds a manifest declaring the given scopes, e.g. as read
+// from a live target by export --manifest
+func FromDesired(scopes []types.DesiredScope) *Manifest {
+	m := &Manifest{Version: Version, Scopes: []Scope{}}
+	for _, d := range scopes {
+		s := Scope{Org: d.Org, Environment: d.Environment, Variables: []Variable{}}
+		if d.Org == "" {
+			s.Repo = d.Owner + "/" + d.Repo
+		}
+		for _, v := range d.Variables {
+			s.Variables = append(s.Variables, Variable{
+				Name:                 v.Name,
+				Value:                v.Value,
+				Visibility:           v.Visibility,
+				SelectedRepositories: v.SelectedRepositories,
+			})
+		}
+		m.Scopes = append(m.Scopes, s)
+	}
+	return m
+}
+
+// Save writes the manifest to path as YAML. The file is created with
+// owner-only permissions because it contains variable values.
+func Save(path string, m *Manifest) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	return nil
+}
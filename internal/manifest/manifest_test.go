@@ -0,0 +1,127 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+const sample = `version: 1
+scopes:
+  - org: acme
+    variables:
+      - name: REGION
+        value: eu
+      - name: SHARED
+        value: "yes"
+        visibility: selected
+        selected_repositories: [app, web]
+  - repo: acme/app
+    variables:
+      - name: TIMEOUT
+        value: 30
+  - repo: acme/app
+    environment: production
+    variables: []
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	want := []types.DesiredScope{
+		{Org: "acme", Variables: []types.DesiredVariable{
+			{Name: "REGION", Value: "eu", Visibility: "all"},
+			{Name: "SHARED", Value: "yes", Visibility: "selected", SelectedRepositories: []string{"app", "web"}},
+		}},
+		{Owner: "acme", Repo: "app", Variables: []types.DesiredVariable{{Name: "TIMEOUT", Value: "30"}}},
+		{Owner: "acme", Repo: "app", Environment: "production"},
+	}
+	if got := m.Desired(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Desired() =\n %+v\nwant\n %+v", got, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"empty", "", "manifest is empty"},
+		{"syntax", "version: 1\nscopes:\n  - org: acme\n\tvariables: []\n", "line 3: found a tab character"},
+		{"unknown key", "version: 1\nscopes:\n  - org: acme\n    varaibles: []\n", "line 4: field varaibles not found"},
+		{"wrong version", "version: 2\nscopes: []\n", "unsupported manifest version 2"},
+		{"no scopes", "version: 1\n", "declares no scopes"},
+		{"org and repo", "version: 1\nscopes:\n  - org: acme\n    repo: acme/app\n", "line 3: scope sets both org and repo"},
+		{"bad repo", "version: 1\nscopes:\n  - repo: app\n", `line 3: repo "app" must be in owner/name form`},
+		{"org environment", "version: 1\nscopes:\n  - org: acme\n    environment: prod\n", "line 3: scope acme: environment needs a repo"},
+		{"duplicate scope", "version: 1\nscopes:\n  - repo: acme/app\n  - repo: ACME/app\n", "line 4: scope ACME/app is already declared at line 3"},
+		{"missing name", "version: 1\nscopes:\n  - repo: acme/app\n    variables:\n      - value: x\n", "line 5: acme/app: variable without a name"},
+		{"invalid name", "version: 1\nscopes:\n  - repo: acme/app\n    variables:\n      - name: MY-VAR\n", "line 5: acme/app: variable MY-VAR: name may only contain"},
+		{"duplicate name", "version: 1\nscopes:\n  - repo: acme/app\n    variables:\n      - name: A\n      - name: a\n", "line 6: acme/app: variable a is already declared at line 5"},
+		{"repo visibility", "version: 1\nscopes:\n  - repo: acme/app\n    variables:\n      - name: A\n        visibility: all\n", "line 5: acme/app: variable A: visibility only applies"},
+		{"bad visibility", "version: 1\nscopes:\n  - org: acme\n    variables:\n      - name: A\n        visibility: public\n", `invalid visibility "public"`},
+		{"selected without visibility", "version: 1\nscopes:\n  - org: acme\n    variables:\n      - name: A\n        selected_repositories: [app]\n", "selected_repositories needs visibility selected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	scopes := []types.DesiredScope{
+		{Org: "acme", Variables: []types.DesiredVariable{
+			{Name: "REGION", Value: "eu", Visibility: "private"},
+			{Name: "SHARED", Value: "", Visibility: "selected"},
+		}},
+		{Owner: "acme", Repo: "app", Variables: []types.DesiredVariable{
+			{Name: "SCRIPT", Value: "line one\nline: two\n  indented"},
+			{Name: "NUMBER", Value: "007"},
+			{Name: "FLAG", Value: "true"},
+		}},
+	}
+
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := Save(path, FromDesired(scopes)); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected file mode 0600, got %o", perm)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if got := m.Desired(); !reflect.DeepEqual(got, scopes) {
+		t.Errorf("Round trip mismatch:\n got  %+v\n want %+v", got, scopes)
+	}
+}
+
+func TestLoad_NamesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := os.WriteFile(path, []byte("version: 1\nscopes:\n  - repo: app\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(path)
+	if err == nil || !strings.HasPrefix(err.Error(), path+": line 3: ") {
+		t.Errorf("Load() error = %v, want the file and line", err)
+	}
+}
@@ -0,0 +1,60 @@
+// Package mapfile loads simple string-to-string mappings from files. A file
+// may contain either a JSON object of string values or .env-style KEY=VALUE
+// lines; the format is detected from the content.
+package mapfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/envfile"
+)
+
+// Load reads the mapping file at path. Unlike envfile.Load, a missing file
+// is an error because the caller asked for it explicitly.
+func Load(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading mapping file: %w", err)
+	}
+
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes mapping data. Content starting with '{' is treated as a JSON
+// object; anything else is parsed as KEY=VALUE lines. Duplicate keys in the
+// line format are rejected.
+func Parse(data []byte) (map[string]string, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var m map[string]string
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("invalid JSON mapping: %w", err)
+		}
+		for k := range m {
+			if k == "" {
+				return nil, fmt.Errorf("empty key in JSON mapping")
+			}
+		}
+		return m, nil
+	}
+
+	entries, err := envfile.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if _, dup := m[e.Key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", e.Line, e.Key)
+		}
+		m[e.Key] = e.Value
+	}
+	return m, nil
+}
@@ -0,0 +1,74 @@
+package mapfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParse_Lines(t *testing.T) {
+	data := []byte("# renames\nOLD_DB_HOST=DATABASE_HOST\nexport OLD_REGION = \"REGION\"\n")
+
+	m, err := Parse(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m) != 2 || m["OLD_DB_HOST"] != "DATABASE_HOST" || m["OLD_REGION"] != "REGION" {
+		t.Errorf("unexpected mapping: %v", m)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	data := []byte(`  {"OLD_DB_HOST": "DATABASE_HOST", "OLD_REGION": "REGION"}`)
+
+	m, err := Parse(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m) != 2 || m["OLD_DB_HOST"] != "DATABASE_HOST" || m["OLD_REGION"] != "REGION" {
+		t.Errorf("unexpected mapping: %v", m)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"missing equals", "OLD_DB_HOST DATABASE_HOST\n"},
+		{"empty key", "=DATABASE_HOST\n"},
+		{"duplicate key", "A=B\nA=C\n"},
+		{"malformed JSON", `{"A": "B"`},
+		{"non-string JSON value", `{"A": 1}`},
+		{"empty JSON key", `{"": "B"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil {
+				t.Errorf("Parse(%q): expected error, got nil", tt.data)
+			}
+		})
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.map")); err == nil {
+		t.Fatal("expected error for missing file, got nil")
+	}
+}
+
+func TestLoad_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.map")
+	if err := os.WriteFile(path, []byte("A=B\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m["A"] != "B" {
+		t.Errorf("unexpected mapping: %v", m)
+	}
+}
@@ -0,0 +1,62 @@
+package migrator
+
+import (
+	"sync"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+)
+
+// apiBudget stops the run once the source and target clients together have
+// sent --max-api-calls requests. Every request since the clients were
+// created counts, including the authentication and preflight checks made
+// before the migration started. It is shared by the Migrators of a
+// multi-repository run and safe for concurrent use.
+type apiBudget struct {
+	mu      sync.Mutex
+	max     int
+	clients []*client.Client
+	reached bool
+}
+
+// newAPIBudget returns the budget for the given clients; zero means no
+// limit. A client passed twice is counted once.
+func newAPIBudget(max int, sourceClient, targetClient *client.Client) *apiBudget {
+	b := &apiBudget{max: max, clients: []*client.Client{sourceClient}}
+	if targetClient != sourceClient {
+		b.clients = append(b.clients, targetClient)
+	}
+	return b
+}
+
+// used returns the number of requests sent by the clients so far
+func (b *apiBudget) used() int {
+	total := 0
+	for _, c := range b.clients {
+		total += c.APICalls().Total()
+	}
+	return total
+}
+
+// exhausted reports whether the budget has been used up, logging the first
+// time it is found to be
+func (b *apiBudget) exhausted() bool {
+	if b.max == 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.reached && b.used() >= b.max {
+		b.reached = true
+		logger.Error("Used the API call budget of %d request(s) (--max-api-calls); stopping the migration", b.max)
+	}
+	return b.reached
+}
+
+// spent reports whether exhausted has found the budget used up, without
+// checking the clients again
+func (b *apiBudget) spent() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.reached
+}
@@ -0,0 +1,102 @@
+package migrator
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// TestAPIBudget verifies the counting of the budget, including requests
+// sent before it was created, and that one client used for both sides is
+// counted once
+func TestAPIBudget(t *testing.T) {
+	fake := newFakeGitHub()
+	c, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		_, _ = c.ListRepoVariables("src", "app")
+	}
+
+	if b := newAPIBudget(0, c, c); b.exhausted() {
+		t.Error("A zero budget must never be exhausted")
+	}
+	b := newAPIBudget(4, c, c)
+	if b.used() != 3 || b.exhausted() {
+		t.Fatalf("used() = %d, exhausted() = %v; want 3 and false", b.used(), b.spent())
+	}
+	_, _ = c.ListRepoVariables("src", "app")
+	if !b.exhausted() || !b.spent() {
+		t.Error("The budget should be exhausted after the fourth request")
+	}
+}
+
+// TestMigrateRepoToRepo_MaxAPICalls verifies that a tiny budget stops the
+// migration cleanly: no error is returned, some variables are left out,
+// environments are not started, and the result is marked
+func TestMigrateRepoToRepo_MaxAPICalls(t *testing.T) {
+	fake := seedEnvsFake()
+	for i := 0; i < 10; i++ {
+		fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: fmt.Sprintf("VAR_%d", i), Value: "v"})
+	}
+
+	cfg := repoToRepoConfig()
+	cfg.MaxAPICalls = 8
+	var result *types.MigrationResult
+	out := captureStdout(t, func() {
+		var err error
+		result, err = newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+	if result == nil {
+		t.Fatal("Run() returned no result")
+	}
+
+	if !result.BudgetExhausted {
+		t.Errorf("Expected the result to be marked, got %+v", result)
+	}
+	if result.Created == 0 || result.Created >= 11 {
+		t.Errorf("Expected a partial migration, got %d created", result.Created)
+	}
+	if total := len(fake.calls); total > cfg.MaxAPICalls+2 {
+		t.Errorf("Expected the run to stop near the budget of %d, got %d call(s):\n%s", cfg.MaxAPICalls, total, strings.Join(fake.calls, "\n"))
+	}
+	if n := fake.countCalls(http.MethodGet + " repos/src/app/environments/production/variables"); n != 0 {
+		t.Errorf("No environment may be started after the budget is used, got %d call(s)", n)
+	}
+	if !strings.Contains(out, "Migration stopped after") || !strings.Contains(out, "(--max-api-calls)") {
+		t.Errorf("Expected the stop to be reported, got:\n%s", out)
+	}
+}
+
+// TestMaxAPICalls_CountsPreflight verifies that requests made before the
+// migration, such as the preflight checks, count against the budget
+func TestMaxAPICalls_CountsPreflight(t *testing.T) {
+	fake := seedEnvsFake()
+	cfg := repoToRepoConfig()
+	cfg.MaxAPICalls = 2
+	m := newFakeMigrator(t, cfg, fake)
+	_, _ = m.sourceClient.GetRepo("src", "app")
+	_, _ = m.targetClient.GetRepo("dst", "app")
+	before := len(fake.calls)
+
+	var result *types.MigrationResult
+	captureStdout(t, func() {
+		result, _ = m.Run()
+	})
+	if result == nil || !result.BudgetExhausted || result.Created != 0 {
+		t.Fatalf("Expected nothing to be migrated, got %+v", result)
+	}
+	for _, call := range fake.calls[before:] {
+		if strings.HasPrefix(call, "POST ") || strings.HasPrefix(call, "PATCH ") {
+			t.Errorf("Nothing may be written once the budget is used, got %s", call)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package migrator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// Confirm prints the plan of the migration — its mode, source and target,
+// conflict strategy, filters, and an estimate of the variables it covers —
+// and asks whether to proceed. Only "y" or "yes" proceeds; anything else,
+// including end of input, declines.
+func (m *Migrator) Confirm() (bool, error) {
+	estimate, err := m.estimateVariables()
+	if err != nil {
+		return false, err
+	}
+	filters := "none"
+	if active := m.activeFilters(); len(active) > 0 {
+		filters = strings.Join(active, " ")
+	}
+
+	out := m.promptOut()
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Migration plan")
+	fmt.Fprintf(out, "  %-11s %s\n", "Migration:", config.GetDescription(m.config))
+	fmt.Fprintf(out, "  %-11s %s\n", "Existing:", conflictLabel(m.config.ConflictStrategy()))
+	fmt.Fprintf(out, "  %-11s %s\n", "Filters:", filters)
+	fmt.Fprintf(out, "  %-11s %s\n", "Variables:", estimate)
+	fmt.Fprint(out, "Proceed? (y/N): ")
+
+	line, err := m.promptReader().ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read answer: %w", err)
+	}
+	if err == io.EOF {
+		fmt.Fprintln(out)
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// conflictLabel describes what happens to variables that already exist in
+// the target
+func conflictLabel(strategy types.ConflictStrategy) string {
+	switch strategy {
+	case types.ConflictSkip:
+		return "skipped (--on-conflict skip)"
+	case types.ConflictFail:
+		return "stop the migration before any write (--on-conflict fail)"
+	case types.ConflictPrompt:
+		return "asked about one by one (--on-conflict prompt)"
+	}
+	return "overwritten (--on-conflict overwrite)"
+}
+
+// estimateVariables counts the source variables the migration covers,
+// before the name filters are applied: the organization or repository
+// variables and, in repo-to-repo mode, those of the selected environments
+func (m *Migrator) estimateVariables() (string, error) {
+	var vars []types.Variable
+	var err error
+	switch m.config.Mode {
+	case types.ModeOrgToOrg, types.ModeOrgToRepo, types.ModeFanOut:
+		vars, err = m.sourceClient.ListOrgVariables(m.config.SourceOrg)
+	default:
+		if !m.config.SkipRepoVars {
+			vars, err = m.sourceClient.ListRepoVariables(m.config.SourceOwner, m.config.SourceRepo)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to count source variables: %w", err)
+	}
+	estimate := fmt.Sprintf("%d in source", len(vars))
+
+	if m.config.Mode != types.ModeRepoToRepo {
+		return estimate + " before filters", nil
+	}
+	environments, err := m.SourceEnvironments()
+	if err != nil {
+		return "", err
+	}
+	if len(environments) > 0 {
+		envVars := 0
+		for _, env := range environments {
+			n, err := m.sourceClient.CountEnvVariables(m.config.SourceOwner, m.config.SourceRepo, env.Name)
+			if err != nil {
+				return "", fmt.Errorf("failed to count the variables of environment %s: %w", env.Name, err)
+			}
+			envVars += n
+		}
+		estimate += fmt.Sprintf(" (+%d in %d environment(s))", envVars, len(environments))
+	}
+	if len(m.config.Targets) > 0 {
+		estimate += fmt.Sprintf(", for each of %d target repository(ies)", len(m.config.Targets))
+	}
+	return estimate + " before filters", nil
+}
@@ -0,0 +1,69 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "yes", input: "y\n", want: true},
+		{name: "full yes", input: "YES\n", want: true},
+		{name: "no", input: "n\n", want: false},
+		{name: "empty answer declines", input: "\n", want: false},
+		{name: "end of input declines", input: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := repoToRepoConfig()
+			cfg.OnConflict = types.ConflictSkip
+			cfg.Include = []string{"URL*"}
+			m := newFakeMigrator(t, cfg, seedEnvsFake())
+			m.input = strings.NewReader(tt.input)
+			var out strings.Builder
+			m.output = &out
+
+			ok, err := m.Confirm()
+			if err != nil {
+				t.Fatalf("Confirm() unexpected error: %v", err)
+			}
+			if ok != tt.want {
+				t.Errorf("Confirm() = %v, want %v", ok, tt.want)
+			}
+			for _, want := range []string{
+				"Repository src/app → dst/app (with environments)",
+				"skipped (--on-conflict skip)",
+				"--include URL*",
+				"1 in source (+3 in 3 environment(s)) before filters",
+				"Proceed? (y/N): ",
+			} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Expected the plan to contain %q, got:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestConfirm_OrgToOrg(t *testing.T) {
+	m := newFakeMigrator(t, orgToOrgConfig(), seedOrgFake())
+	m.input = strings.NewReader("y\n")
+	var out strings.Builder
+	m.output = &out
+
+	if ok, err := m.Confirm(); err != nil || !ok {
+		t.Fatalf("Confirm() = %v, %v; want true", ok, err)
+	}
+	for _, want := range []string{"overwritten (--on-conflict overwrite)", "Filters:    none", " in source before filters"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the plan to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// resolveConflict applies the conflict strategy to a variable that already
+// exists in the target scope and reports whether it should be overwritten.
+// kind ("Variable" or "Environment variable") and label are used in
+// messages; scope and name identify the variable in the result details.
+func (m *Migrator) resolveConflict(kind, scope, name, label string, result *types.MigrationResult) (bool, error) {
+	result.Add(&result.Conflicts, 1)
+
+	strategy := m.config.ConflictStrategy()
+	switch strategy {
+	case types.ConflictSkip:
+		logger.Warning("%s '%s' already exists in target, overwrite skipped (--on-conflict=skip)", kind, label)
+		recordSkipped(scope, name, "already exists in target (--on-conflict=skip)", result)
+		return false, nil
+
+	case types.ConflictFail:
+		// The pre-scan normally catches this; the variable appeared since.
+		return false, fmt.Errorf("already exists in target (--on-conflict=fail)")
+
+	case types.ConflictPrompt:
+		if m.config.DryRun {
+			return true, nil
+		}
+		overwrite, err := m.askOverwrite(kind, label)
+		if err != nil {
+			return false, err
+		}
+		if !overwrite {
+			logger.Warning("%s '%s' already exists in target, overwrite declined", kind, label)
+			recordSkipped(scope, name, "overwrite declined", result)
+		}
+		return overwrite, nil
+
+	default:
+		return true, nil
+	}
+}
+
+// askOverwrite asks whether to overwrite a conflicting variable. Answering
+// "all" or "quit" applies to every remaining conflict without asking again.
+func (m *Migrator) askOverwrite(kind, label string) (bool, error) {
+	switch m.conflictAnswer {
+	case answerAll:
+		return true, nil
+	case answerQuit:
+		return false, nil
+	}
+
+	answer, err := m.ask(fmt.Sprintf("%s '%s' already exists in target. Overwrite?", kind, label))
+	if err != nil {
+		return false, err
+	}
+	if answer == answerAll || answer == answerQuit {
+		m.conflictAnswer = answer
+	}
+	return answer == answerYes || answer == answerAll, nil
+}
+
+// checkConflicts implements the pre-scan for --on-conflict=fail: it compares
+// source and target like Diff and returns an error listing every variable
+// that already exists in the target, before anything is written.
+func (m *Migrator) checkConflicts(result *types.MigrationResult) error {
+	logger.Info("Checking target for conflicting variables (--on-conflict=fail)")
+
+	diff, err := m.compare()
+	if err != nil {
+		return fmt.Errorf("conflict check failed: %w", err)
+	}
+
+	var conflicts []string
+	for _, e := range diff.Entries {
+		if e.Status == types.DiffUpdate || e.Status == types.DiffUnchanged {
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s)", e.TargetName, e.Scope))
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	result.Add(&result.Conflicts, len(conflicts))
+	return fmt.Errorf("%d variable(s) already exist in target (--on-conflict=fail): %s",
+		len(conflicts), strings.Join(conflicts, ", "))
+}
@@ -0,0 +1,143 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// seedConflictFake sets up a source repository whose variables A and B
+// already exist in the target, and C which does not
+func seedConflictFake() *fakeGitHub {
+	fake := newFakeGitHub()
+	for _, v := range []types.Variable{{Name: "A", Value: "new-a"}, {Name: "B", Value: "new-b"}, {Name: "C", Value: "new-c"}} {
+		fake.setVar(repoVarsPath("src", "app"), v)
+	}
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "A", Value: "old-a"})
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "B", Value: "old-b"})
+	return fake
+}
+
+func conflictConfig(strategy types.ConflictStrategy) *types.MigrationConfig {
+	return &types.MigrationConfig{
+		Mode:        types.ModeRepoToRepo,
+		SourceOwner: "src",
+		SourceRepo:  "app",
+		TargetOwner: "dst",
+		TargetRepo:  "app",
+		SkipEnvs:    true,
+		OnConflict:  strategy,
+	}
+}
+
+// targetValues returns the target repository values of A, B, and C
+func targetValues(fake *fakeGitHub) string {
+	var values []string
+	for _, name := range []string{"A", "B", "C"} {
+		v, _ := fake.getVar(repoVarsPath("dst", "app"), name)
+		values = append(values, v.Value)
+	}
+	return strings.Join(values, ",")
+}
+
+func TestConflictStrategies(t *testing.T) {
+	tests := []struct {
+		name          string
+		strategy      types.ConflictStrategy
+		skipOverwrite bool
+		input         string
+		wantUpdated   int
+		wantSkipped   int
+		wantValues    string
+	}{
+		{name: "default overwrites", wantUpdated: 2, wantValues: "new-a,new-b,new-c"},
+		{name: "overwrite", strategy: types.ConflictOverwrite, wantUpdated: 2, wantValues: "new-a,new-b,new-c"},
+		{name: "skip", strategy: types.ConflictSkip, wantSkipped: 2, wantValues: "old-a,old-b,new-c"},
+		{name: "skip-overwrite alias", skipOverwrite: true, wantSkipped: 2, wantValues: "old-a,old-b,new-c"},
+		{name: "prompt yes then no", strategy: types.ConflictPrompt, input: "y\nn\n", wantUpdated: 1, wantSkipped: 1, wantValues: "new-a,old-b,new-c"},
+		{name: "prompt invalid answer is asked again", strategy: types.ConflictPrompt, input: "maybe\nn\ny\n", wantUpdated: 1, wantSkipped: 1, wantValues: "old-a,new-b,new-c"},
+		{name: "prompt all", strategy: types.ConflictPrompt, input: "a\n", wantUpdated: 2, wantValues: "new-a,new-b,new-c"},
+		{name: "prompt quit", strategy: types.ConflictPrompt, input: "q\n", wantSkipped: 2, wantValues: "old-a,old-b,new-c"},
+		{name: "prompt end of input", strategy: types.ConflictPrompt, input: "", wantSkipped: 2, wantValues: "old-a,old-b,new-c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := seedConflictFake()
+			cfg := conflictConfig(tt.strategy)
+			cfg.SkipOverwrite = tt.skipOverwrite
+			m := newFakeMigrator(t, cfg, fake)
+			m.input = strings.NewReader(tt.input)
+
+			result, err := m.Run()
+			if err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			if result.Conflicts != 2 || result.Created != 1 || result.Updated != tt.wantUpdated || result.Skipped != tt.wantSkipped {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+			checkDetails(t, result)
+			if got := targetValues(fake); got != tt.wantValues {
+				t.Errorf("Target values = %s, want %s", got, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestConflictFail_PreScanAbortsBeforeWrites(t *testing.T) {
+	fake := seedConflictFake()
+	m := newFakeMigrator(t, conflictConfig(types.ConflictFail), fake)
+
+	result, err := m.Run()
+	if err == nil {
+		t.Fatal("Expected Run() to fail on conflicts")
+	}
+	if !strings.Contains(err.Error(), "A (repository)") || !strings.Contains(err.Error(), "B (repository)") {
+		t.Errorf("Expected error to list conflicts, got: %v", err)
+	}
+	if result.Conflicts != 2 {
+		t.Errorf("Expected 2 conflicts, got %d", result.Conflicts)
+	}
+
+	for _, method := range []string{"POST", "PATCH"} {
+		for _, call := range fake.calls {
+			if strings.HasPrefix(call, method+" ") {
+				t.Errorf("Expected no writes, got %s", call)
+			}
+		}
+	}
+	if got := targetValues(fake); got != "old-a,old-b," {
+		t.Errorf("Target changed: %s", got)
+	}
+}
+
+func TestConflictFail_NoConflicts(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "C", Value: "new-c"})
+	m := newFakeMigrator(t, conflictConfig(types.ConflictFail), fake)
+
+	result, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 1 || result.Conflicts != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestConflictPrompt_DryRunDoesNotAsk(t *testing.T) {
+	fake := seedConflictFake()
+	cfg := conflictConfig(types.ConflictPrompt)
+	cfg.DryRun = true
+	m := newFakeMigrator(t, cfg, fake)
+	m.input = strings.NewReader("n\nn\n")
+
+	result, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Updated != 2 || m.promptIn != nil {
+		t.Errorf("Expected dry-run to preview overwrites without prompting, got %+v", result)
+	}
+}
@@ -0,0 +1,62 @@
+package migrator
+
+import (
+	"fmt"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// copyVariable copies one variable of the CopyFrom scope to the target
+// scope, under CopyAs when it is set. The target is then converged like a
+// manifest scope declaring that variable alone, so an existing variable is
+// handled by the conflict strategy and an identical one is not written.
+func (m *Migrator) copyVariable() (*types.MigrationResult, error) {
+	from := m.config.CopyFrom
+	m.sourceClient.WaitForRateLimit()
+
+	var v *types.Variable
+	var err error
+	switch {
+	case from.Org != "":
+		v, err = m.sourceClient.GetOrgVariable(from.Org, m.config.CopyName)
+	case from.Environment != "":
+		v, err = m.sourceClient.GetEnvVariable(from.Owner, from.Repo, from.Environment, m.config.CopyName)
+	default:
+		v, err = m.sourceClient.GetRepoVariable(from.Owner, from.Repo, m.config.CopyName)
+	}
+	if client.IsNotFound(err) {
+		return &types.MigrationResult{}, fmt.Errorf("variable %s not found in %s", m.config.CopyName, from.Label())
+	}
+	if err != nil {
+		return &types.MigrationResult{}, fmt.Errorf("failed to read variable %s of %s: %w", m.config.CopyName, from.Label(), err)
+	}
+
+	scope := m.config.Desired[0]
+	want := types.DesiredVariable{Name: v.Name, Value: v.Value}
+	if m.config.CopyAs != "" {
+		want.Name = m.config.CopyAs
+	}
+	// Visibility only exists for organization variables; a repository or
+	// environment variable promoted to an organization is visible to all
+	// of its repositories, as with an import
+	if scope.Org != "" {
+		want.Visibility = "all"
+		if from.Org != "" && v.Visibility != "" {
+			want.Visibility = v.Visibility
+		}
+		if want.Visibility == "selected" {
+			if want.SelectedRepositories, err = selectedRepoNames(m.sourceClient, from.Org, v.Name); err != nil {
+				return &types.MigrationResult{}, err
+			}
+		}
+	}
+	scope.Variables = []types.DesiredVariable{want}
+
+	m.targetClient.WaitForRateLimit()
+	result := m.newResult()
+	if err := m.applyManifestScope(scope, result); err != nil {
+		return result, fmt.Errorf("%s: %w", scope.Label(), err)
+	}
+	return result, nil
+}
@@ -0,0 +1,166 @@
+package migrator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// copyConfig returns the configuration of a copy of NAME from one scope to
+// another
+func copyConfig(from, to types.DesiredScope, name string) *types.MigrationConfig {
+	return &types.MigrationConfig{Mode: types.ModeCopy, CopyFrom: from, CopyName: name, Desired: []types.DesiredScope{to}}
+}
+
+// TestCopy_Scopes copies a variable between every pair of scope kinds
+func TestCopy_Scopes(t *testing.T) {
+	org := types.DesiredScope{Org: "acme"}
+	repo := types.DesiredScope{Owner: "acme", Repo: "api"}
+	env := types.DesiredScope{Owner: "acme", Repo: "api", Environment: "production"}
+	paths := map[string]string{
+		"org":  orgVarsPath("acme"),
+		"repo": repoVarsPath("acme", "api"),
+		"env":  envVarsPath("acme", "api", "production"),
+	}
+	scopes := map[string]types.DesiredScope{"org": org, "repo": repo, "env": env}
+	otherOrg := types.DesiredScope{Org: "other"}
+
+	tests := []struct {
+		from, to       string
+		wantVisibility string
+	}{
+		{from: "org", to: "repo"},
+		{from: "org", to: "env"},
+		{from: "repo", to: "org", wantVisibility: "all"},
+		{from: "repo", to: "env"},
+		{from: "env", to: "org", wantVisibility: "all"},
+		{from: "env", to: "repo"},
+		{from: "repo", to: "repo"},
+		{from: "org", to: "org", wantVisibility: "private"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			fake := newFakeGitHub()
+			fake.addEnv("acme", "api", "production")
+			fake.setVar(paths[tt.from], types.Variable{Name: "API_URL", Value: "https://api.example.com", Visibility: "private"})
+
+			to, toPath := scopes[tt.to], paths[tt.to]
+			if tt.from == tt.to {
+				// A copy to the same scope kind goes to another scope
+				if to.Org != "" {
+					to, toPath = otherOrg, orgVarsPath("other")
+				} else {
+					to, toPath = types.DesiredScope{Owner: "acme", Repo: "web"}, repoVarsPath("acme", "web")
+				}
+			}
+
+			result := runManifest(t, copyConfig(scopes[tt.from], to, "api_url"), fake)
+			if result.Created != 1 || result.HasErrors() {
+				t.Fatalf("Expected 1 create, got %+v (errors: %v)", result, result.Errors)
+			}
+			got, ok := fake.getVar(toPath, "API_URL")
+			if !ok || got.Name != "API_URL" || got.Value != "https://api.example.com" {
+				t.Errorf("Copied variable = %+v, want API_URL with the source value", got)
+			}
+			if got.Visibility != tt.wantVisibility {
+				t.Errorf("Visibility = %q, want %q", got.Visibility, tt.wantVisibility)
+			}
+		})
+	}
+}
+
+func TestCopy_NewName(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("acme", "api"), types.Variable{Name: "URL", Value: "https://api.example.com"})
+
+	cfg := copyConfig(types.DesiredScope{Owner: "acme", Repo: "api"}, types.DesiredScope{Owner: "acme", Repo: "web"}, "URL")
+	cfg.CopyAs = "API_URL"
+	result := runManifest(t, cfg, fake)
+
+	if result.Created != 1 {
+		t.Fatalf("Expected 1 create, got %+v", result)
+	}
+	if _, ok := fake.getVar(repoVarsPath("acme", "web"), "API_URL"); !ok {
+		t.Error("The variable should be copied under its new name")
+	}
+	if _, ok := fake.getVar(repoVarsPath("acme", "web"), "URL"); ok {
+		t.Error("The variable must not be copied under its source name")
+	}
+}
+
+func TestCopy_SelectedRepositories(t *testing.T) {
+	fake := newFakeGitHub()
+	id := fake.addRepo("other", "api")
+	fake.setVar(orgVarsPath("acme"), types.Variable{Name: "TOKEN_URL", Value: "x", Visibility: "selected"})
+	fake.selected["acme/TOKEN_URL"] = []types.Repository{{ID: 11, Name: "api"}}
+
+	result := runManifest(t, copyConfig(types.DesiredScope{Org: "acme"}, types.DesiredScope{Org: "other"}, "TOKEN_URL"), fake)
+	if result.Created != 1 || result.HasErrors() {
+		t.Fatalf("Expected 1 create, got %+v (errors: %v)", result, result.Errors)
+	}
+	got, _ := fake.getVar(orgVarsPath("other"), "TOKEN_URL")
+	if got.Visibility != "selected" || !reflect.DeepEqual(got.SelectedRepositoryIDs, []int64{id}) {
+		t.Errorf("Copied variable = %+v, want the target repository selected", got)
+	}
+}
+
+func TestCopy_Conflicts(t *testing.T) {
+	from := types.DesiredScope{Owner: "acme", Repo: "api"}
+	to := types.DesiredScope{Owner: "acme", Repo: "web"}
+
+	tests := []struct {
+		name       string
+		existing   string
+		onConflict types.ConflictStrategy
+		dryRun     bool
+		wantValue  string
+		wantWrites int
+		check      func(r *types.MigrationResult) bool
+	}{
+		{name: "overwrite", existing: "old", wantValue: "new", wantWrites: 1, check: func(r *types.MigrationResult) bool { return r.Updated == 1 }},
+		{name: "skip", existing: "old", onConflict: types.ConflictSkip, wantValue: "old", check: func(r *types.MigrationResult) bool { return r.Skipped == 1 }},
+		{name: "unchanged", existing: "new", wantValue: "new", check: func(r *types.MigrationResult) bool { return r.Unchanged == 1 }},
+		{name: "dry run", existing: "old", dryRun: true, wantValue: "old", check: func(r *types.MigrationResult) bool { return r.Updated == 1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeGitHub()
+			fake.setVar(repoVarsPath("acme", "api"), types.Variable{Name: "URL", Value: "new"})
+			fake.setVar(repoVarsPath("acme", "web"), types.Variable{Name: "URL", Value: tt.existing})
+
+			cfg := copyConfig(from, to, "URL")
+			cfg.OnConflict, cfg.DryRun = tt.onConflict, tt.dryRun
+			result := runManifest(t, cfg, fake)
+
+			if !tt.check(result) {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+			if got, _ := fake.getVar(repoVarsPath("acme", "web"), "URL"); got.Value != tt.wantValue {
+				t.Errorf("Target value = %q, want %q", got.Value, tt.wantValue)
+			}
+			if writes := writeCount(fake); writes != tt.wantWrites {
+				t.Errorf("Made %d write(s), want %d", writes, tt.wantWrites)
+			}
+		})
+	}
+}
+
+func TestCopy_MissingSource(t *testing.T) {
+	fake := newFakeGitHub()
+	cfg := copyConfig(types.DesiredScope{Owner: "acme", Repo: "api"}, types.DesiredScope{Org: "acme"}, "NOPE")
+
+	var err error
+	captureStdout(t, func() {
+		_, err = newFakeMigrator(t, cfg, fake).Run()
+	})
+	if err == nil || !strings.Contains(err.Error(), "variable NOPE not found in acme/api") {
+		t.Errorf("Run() error = %v, want the missing variable and its scope", err)
+	}
+	if writes := writeCount(fake); writes != 0 {
+		t.Errorf("Made %d write(s) without a source variable", writes)
+	}
+}
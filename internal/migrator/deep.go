@@ -0,0 +1,130 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// Notes for repositories a deep migration does not migrate
+const (
+	noteMissingInTarget  = "missing in target"
+	noteArchivedInTarget = "archived in target"
+)
+
+// migrateDeep runs the repository-to-repository flow, environments
+// included unless SkipEnvs is set, for every source repository that has a
+// same-named repository in the target organization. Repositories missing or
+// archived in the target are reported in result.Repos and left alone. It
+// returns the requested variables missing from the organization and from
+// every migrated repository.
+func (m *Migrator) migrateDeep(result *types.MigrationResult, orgMissing []string) []string {
+	logger.Info("Deep migration: matching repositories of %s and %s", m.config.SourceOrg, m.config.TargetOrg)
+
+	runs, skipped, err := m.deepRuns()
+	if err != nil {
+		logger.Error("Deep migration failed: %v", err)
+		m.addError(result, fmt.Errorf("deep migration: %w", err))
+		return orgMissing
+	}
+
+	logger.Info("Found %d repository(ies) in both organizations", len(runs))
+
+	reposMissing, completed := m.runRepos(runs, result)
+	for _, r := range skipped {
+		result.AddRepo(r)
+	}
+
+	if !completed {
+		return orgMissing
+	}
+	return intersectNames(orgMissing, reposMissing)
+}
+
+// diffDeep compares the organization variables and then, for every
+// repository in both organizations, the repository and environment
+// variables. Repository scopes are prefixed with the repository name, e.g.
+// "api:env:prod".
+func (m *Migrator) diffDeep() (*types.DiffResult, error) {
+	diff, err := m.diffOrgToOrg()
+	if err != nil {
+		return nil, err
+	}
+
+	runs, _, err := m.deepRuns()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range runs {
+		child, err := m.child(r.cfg)
+		if err != nil {
+			return nil, err
+		}
+		repoDiff, err := child.compare()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.label, err)
+		}
+		for _, e := range repoDiff.Entries {
+			e.Scope = r.label + ":" + e.Scope
+			diff.Entries = append(diff.Entries, e)
+		}
+	}
+	return diff, nil
+}
+
+// deepRuns pairs source repositories with same-named target repositories
+// (case-insensitive). Source repositories matching ExcludeRepos are left
+// out; those missing or archived in the target are returned as skipped.
+func (m *Migrator) deepRuns() ([]repoRun, []types.RepoResult, error) {
+	sourceRepos, err := m.sourceClient.ListOrgRepos(m.config.SourceOrg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list source organization repositories: %w", err)
+	}
+	targetRepos, err := m.targetClient.ListOrgRepos(m.config.TargetOrg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list target organization repositories: %w", err)
+	}
+
+	targetByName := make(map[string]types.Repository, len(targetRepos))
+	for _, r := range targetRepos {
+		targetByName[strings.ToLower(r.Name)] = r
+	}
+
+	var runs []repoRun
+	var skipped []types.RepoResult
+	for _, src := range sourceRepos {
+		if matchesAnyGlob(src.Name, m.config.ExcludeRepos) {
+			logger.Debug("Excluding repository '%s' (--exclude-repos)", src.Name)
+			continue
+		}
+
+		target, ok := targetByName[strings.ToLower(src.Name)]
+		switch {
+		case !ok:
+			logger.Warning("Repository '%s' does not exist in %s; skipping", src.Name, m.config.TargetOrg)
+			skipped = append(skipped, types.RepoResult{Repo: src.Name, Note: noteMissingInTarget})
+			continue
+		case target.Archived:
+			logger.Warning("Repository '%s' is archived in %s; skipping", target.Name, m.config.TargetOrg)
+			skipped = append(skipped, types.RepoResult{Repo: src.Name, Note: noteArchivedInTarget})
+			continue
+		}
+
+		cfg := *m.config
+		cfg.Mode = types.ModeRepoToRepo
+		cfg.Deep = false
+		cfg.ExcludeRepos = nil
+		cfg.Visibility = ""
+		cfg.RepoMap = nil
+		cfg.SelectedFallback = ""
+		cfg.SourceOwner = m.config.SourceOrg
+		cfg.SourceRepo = src.Name
+		cfg.TargetOwner = m.config.TargetOrg
+		cfg.TargetRepo = target.Name
+		runs = append(runs, repoRun{label: src.Name, cfg: &cfg})
+	}
+
+	return runs, skipped, nil
+}
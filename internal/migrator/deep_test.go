@@ -0,0 +1,190 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func deepConfig() *types.MigrationConfig {
+	return &types.MigrationConfig{
+		Mode:      types.ModeOrgToOrg,
+		SourceOrg: "src",
+		TargetOrg: "dst",
+		Deep:      true,
+	}
+}
+
+// seedDeepFake returns a fake with one organization variable and the source
+// repositories api (with a prod environment), legacy, old, and web. The
+// target has api, web, and an archived old; legacy is missing.
+func seedDeepFake() *fakeGitHub {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "ORG_VAR", Value: "o", Visibility: "all"})
+
+	for _, repo := range []string{"api", "legacy", "old", "web"} {
+		fake.addRepo("src", repo)
+		fake.setVar(repoVarsPath("src", repo), types.Variable{Name: "NAME", Value: repo})
+	}
+	fake.addEnv("src", "api", "prod")
+	fake.setVar(envVarsPath("src", "api", "prod"), types.Variable{Name: "URL", Value: "https://api"})
+	fake.setVar(repoVarsPath("src", "web"), types.Variable{Name: "WEB_ONLY", Value: "w"})
+
+	for _, repo := range []string{"api", "old", "web"} {
+		fake.addRepo("dst", repo)
+	}
+	fake.archived["dst/old"] = true
+	return fake
+}
+
+func TestMigrateDeep(t *testing.T) {
+	fake := seedDeepFake()
+
+	result, err := newFakeMigrator(t, deepConfig(), fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 5 || result.HasErrors() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	want := []types.RepoResult{
+		{Repo: "api", Created: 2},
+		{Repo: "web", Created: 2},
+		{Repo: "legacy", Note: noteMissingInTarget},
+		{Repo: "old", Note: noteArchivedInTarget},
+	}
+	if !reflect.DeepEqual(result.Repos, want) {
+		t.Errorf("Repos = %+v, want %+v", result.Repos, want)
+	}
+
+	if _, ok := fake.getVar(orgVarsPath("dst"), "ORG_VAR"); !ok {
+		t.Error("Expected organization variable to be migrated")
+	}
+	if v, ok := fake.getVar(envVarsPath("dst", "api", "prod"), "URL"); !ok || v.Value != "https://api" {
+		t.Errorf("Expected environment variable in api/prod, got %+v (found %v)", v, ok)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "old"), "NAME"); ok {
+		t.Error("Archived target repository must not be written to")
+	}
+}
+
+func TestMigrateDeep_ContinuesPastFailures(t *testing.T) {
+	fake := seedDeepFake()
+	fake.failWrites["NAME"] = true
+
+	result, err := newFakeMigrator(t, deepConfig(), fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	want := []types.RepoResult{
+		{Repo: "api", Created: 1, Errors: 1},
+		{Repo: "web", Created: 1, Errors: 1},
+		{Repo: "legacy", Note: noteMissingInTarget},
+		{Repo: "old", Note: noteArchivedInTarget},
+	}
+	if !reflect.DeepEqual(result.Repos, want) {
+		t.Errorf("Repos = %+v, want %+v", result.Repos, want)
+	}
+	if len(result.Errors) != 2 {
+		t.Errorf("Expected 2 errors, got %v", result.Errors)
+	}
+}
+
+func TestMigrateDeep_FailedRepository(t *testing.T) {
+	fake := seedDeepFake()
+	fake.failWrites["WEB_ONLY"] = true
+	fake.failWrites["NAME"] = true
+
+	result, err := newFakeMigrator(t, deepConfig(), fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if len(result.Repos) < 2 || result.Repos[1] != (types.RepoResult{Repo: "web", Errors: 2, Failed: true}) {
+		t.Errorf("Expected web to be reported as failed, got %+v", result.Repos)
+	}
+	if result.Repos[0].Failed {
+		t.Errorf("api still wrote its environment variable and must not be failed: %+v", result.Repos[0])
+	}
+}
+
+func TestMigrateDeep_ExcludeRepos(t *testing.T) {
+	fake := seedDeepFake()
+
+	cfg := deepConfig()
+	cfg.ExcludeRepos = []string{"w*", "leg*"}
+	cfg.SkipEnvs = true
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	want := []types.RepoResult{
+		{Repo: "api", Created: 1},
+		{Repo: "old", Note: noteArchivedInTarget},
+	}
+	if !reflect.DeepEqual(result.Repos, want) {
+		t.Errorf("Repos = %+v, want %+v", result.Repos, want)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "web"), "NAME"); ok {
+		t.Error("Excluded repository must not be written to")
+	}
+	if _, ok := fake.getVar(envVarsPath("dst", "api", "prod"), "URL"); ok {
+		t.Error("Environments must be skipped with SkipEnvs")
+	}
+}
+
+func TestDiffDeep(t *testing.T) {
+	fake := seedDeepFake()
+	fake.setVar(repoVarsPath("dst", "web"), types.Variable{Name: "NAME", Value: "web"})
+
+	diff, err := newFakeMigrator(t, deepConfig(), fake).Diff()
+	if err != nil {
+		t.Fatalf("Diff() unexpected error: %v", err)
+	}
+
+	got := map[string]types.DiffStatus{}
+	for _, e := range diff.Entries {
+		got[e.Scope+"/"+e.TargetName] = e.Status
+	}
+	want := map[string]types.DiffStatus{
+		scopeOrg + "/ORG_VAR":            types.DiffAdd,
+		"api:" + scopeRepo + "/NAME":     types.DiffAdd,
+		"api:env:prod/URL":               types.DiffAdd,
+		"web:" + scopeRepo + "/NAME":     types.DiffUnchanged,
+		"web:" + scopeRepo + "/WEB_ONLY": types.DiffAdd,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff entries = %v, want %v", got, want)
+	}
+	if len(fake.vars[orgVarsPath("dst")]) != 0 {
+		t.Error("Diff must not write to the target")
+	}
+}
+
+func TestMigrateDeep_FailFast(t *testing.T) {
+	fake := seedDeepFake()
+	fake.failWrites["NAME"] = true
+
+	cfg := deepConfig()
+	cfg.FailFast = true
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	want := []types.RepoResult{
+		{Repo: "api", Errors: 1, Failed: true},
+		{Repo: "legacy", Note: noteMissingInTarget},
+		{Repo: "old", Note: noteArchivedInTarget},
+	}
+	if !reflect.DeepEqual(result.Repos, want) {
+		t.Errorf("Repos = %+v, want %+v", result.Repos, want)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "web"), "WEB_ONLY"); ok {
+		t.Error("Repositories after the first error must not be migrated with FailFast")
+	}
+}
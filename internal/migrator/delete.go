@@ -0,0 +1,97 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// deleteVariables deletes the variables of the target scope selected by
+// --vars and the name filters. The selection is listed first; unless Yes or
+// DryRun is set, nothing is deleted until the scope name is typed to
+// confirm. A variable that fails to delete is recorded and the rest are
+// still deleted, unless the target token was refused: every other deletion
+// would be too, so the run stops there. The partial result is still
+// summarized, with the variables not attempted counted as skipped.
+func (m *Migrator) deleteVariables() (*types.MigrationResult, error) {
+	result := m.newResult()
+	scope := m.config.Desired[0]
+	label := scope.Label()
+	m.targetClient.WaitForRateLimit()
+
+	var current []types.Variable
+	var err error
+	switch {
+	case scope.Org != "":
+		current, err = m.targetClient.ListOrgVariables(scope.Org)
+	case scope.Environment != "":
+		if _, err := m.targetClient.GetEnvironment(scope.Owner, scope.Repo, scope.Environment); err != nil {
+			return result, fmt.Errorf("environment %s of %s/%s not found: %w", scope.Environment, scope.Owner, scope.Repo, err)
+		}
+		current, err = m.targetClient.ListEnvVariables(scope.Owner, scope.Repo, scope.Environment)
+	default:
+		current, err = m.targetClient.ListRepoVariables(scope.Owner, scope.Repo)
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to list variables of %s: %w", label, err)
+	}
+
+	selected := m.filterVariables(current, result)
+	result.Add(&result.Selected, len(selected))
+	if len(selected) == 0 {
+		logger.Warning("No variables of %s are selected for deletion", label)
+		return result, nil
+	}
+
+	names := make([]string, 0, len(selected))
+	for _, v := range selected {
+		names = append(names, v.Name)
+	}
+	sort.Strings(names)
+	logger.Info("%d variable(s) of %s selected for deletion:", len(names), label)
+	for _, name := range names {
+		logger.Plain("  - %s", name)
+	}
+
+	if !m.config.DryRun && !m.config.Yes {
+		ok, err := m.confirmTyped(fmt.Sprintf("Delete %d variable(s) from %s", len(names), label), label)
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			logger.Warning("Confirmation did not match %s; nothing was deleted", label)
+			m.aborted = true
+			return result, nil
+		}
+	}
+
+	for i, name := range names {
+		if m.stopped() {
+			break
+		}
+		err := m.deleteVariable(scope, name, "", result)
+		if err == nil {
+			continue
+		}
+		logger.VariableError(name, "Failed to delete variable '%s' (%s): %v", name, label, err)
+		recordFailed(label, name, err, result)
+		if client.IsAuthError(err) {
+			result.AddError(fmt.Errorf("%s variable '%s': %w", label, name, err))
+			if rest := names[i+1:]; len(rest) > 0 {
+				logger.Warning("Not attempted after the refusal: %s", strings.Join(rest, ", "))
+				for _, skipped := range rest {
+					recordSkipped(label, skipped, "not attempted after the target token was refused", result)
+				}
+			}
+			m.refused = true
+			return result, fmt.Errorf("the target token is not allowed to delete variables of %s; %d of %d variable(s) deleted before stopping: %w",
+				label, result.Deleted, len(names), err)
+		}
+		m.addError(result, fmt.Errorf("%s variable '%s': %w", label, name, err))
+	}
+	return result, nil
+}
@@ -0,0 +1,226 @@
+package migrator
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// deleteFixture returns a fake with four variables in repository acme/app
+func deleteFixture() *fakeGitHub {
+	fake := newFakeGitHub()
+	for _, name := range []string{"TMP_A", "TMP_B", "KEEP", "EXPERIMENT"} {
+		fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: name, Value: "x"})
+	}
+	return fake
+}
+
+// deleteConfig returns the configuration of a confirmed deletion from
+// acme/app
+func deleteConfig() *types.MigrationConfig {
+	return &types.MigrationConfig{Mode: types.ModeDelete, Desired: []types.DesiredScope{{Owner: "acme", Repo: "app"}}, Yes: true}
+}
+
+// remaining lists the variables left in acme/app
+func remaining(fake *fakeGitHub) []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	var names []string
+	for _, v := range fake.vars[repoVarsPath("acme", "app")] {
+		names = append(names, v.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDeleteVariables_Selection(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    []string
+		include []string
+		exclude []string
+		regex   string
+		want    []string
+	}{
+		{name: "vars", vars: []string{"tmp_a", "EXPERIMENT"}, want: []string{"KEEP", "TMP_B"}},
+		{name: "include", include: []string{"TMP_*"}, want: []string{"EXPERIMENT", "KEEP"}},
+		{name: "include and exclude", include: []string{"TMP_*", "EXPERIMENT"}, exclude: []string{"TMP_B"}, want: []string{"KEEP", "TMP_B"}},
+		{name: "exclude only", exclude: []string{"KEEP"}, want: []string{"KEEP"}},
+		{name: "regex", regex: "^TMP_B$", want: []string{"EXPERIMENT", "KEEP", "TMP_A"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := deleteFixture()
+			cfg := deleteConfig()
+			cfg.Vars, cfg.Include, cfg.Exclude, cfg.FilterRegex = tt.vars, tt.include, tt.exclude, tt.regex
+			result := runManifest(t, cfg, fake)
+
+			if got := remaining(fake); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Remaining variables = %v, want %v", got, tt.want)
+			}
+			if deleted := 4 - len(tt.want); result.Deleted != deleted || result.Selected != deleted || result.Filtered != len(tt.want) {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+		})
+	}
+}
+
+func TestDeleteVariables_DryRun(t *testing.T) {
+	fake := deleteFixture()
+	cfg := deleteConfig()
+	cfg.Include = []string{"TMP_*"}
+	cfg.DryRun = true
+	cfg.Yes = false
+	var result *types.MigrationResult
+	out := captureStdout(t, func() {
+		var err error
+		if result, err = newFakeMigrator(t, cfg, fake).Run(); err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+
+	for _, want := range []string{
+		"2 variable(s) of acme/app selected for deletion:\n  - TMP_A\n  - TMP_B\n",
+		"[DRY-RUN] Would delete variable: TMP_A (acme/app)",
+		"[DRY-RUN] Would delete variable: TMP_B (acme/app)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "KEEP") || strings.Contains(out, "EXPERIMENT") {
+		t.Errorf("Dry run listed unselected variables:\n%s", out)
+	}
+
+	if result.Deleted != 2 {
+		t.Errorf("Expected 2 variables reported for deletion, got %+v", result)
+	}
+	if writes := writeCount(fake); writes != 0 {
+		t.Errorf("Dry run made %d write(s)", writes)
+	}
+	if got := remaining(fake); len(got) != 4 {
+		t.Errorf("Dry run deleted variables, %v remain", got)
+	}
+}
+
+func TestDeleteVariables_Confirmation(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantDeleted int
+	}{
+		{name: "scope typed", input: "acme/app\n", wantDeleted: 2},
+		{name: "other answer", input: "y\n", wantDeleted: 0},
+		{name: "end of input", input: "", wantDeleted: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := deleteFixture()
+			cfg := deleteConfig()
+			cfg.Include = []string{"TMP_*"}
+			cfg.Yes = false
+
+			var prompt bytes.Buffer
+			m := newFakeMigrator(t, cfg, fake)
+			m.input = strings.NewReader(tt.input)
+			m.output = &prompt
+			var result *types.MigrationResult
+			captureStdout(t, func() {
+				var err error
+				if result, err = m.Run(); err != nil {
+					t.Errorf("Run() unexpected error: %v", err)
+				}
+			})
+
+			if !strings.Contains(prompt.String(), "Delete 2 variable(s) from acme/app. Type acme/app to confirm") {
+				t.Errorf("Unexpected prompt %q", prompt.String())
+			}
+			if result.Deleted != tt.wantDeleted || result.Aborted != (tt.wantDeleted == 0) {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+			if got := remaining(fake); len(got) != 4-tt.wantDeleted {
+				t.Errorf("Remaining variables = %v", got)
+			}
+		})
+	}
+}
+
+func TestDeleteVariables_PartialFailure(t *testing.T) {
+	fake := deleteFixture()
+	fake.failWrites["TMP_A"] = true
+	cfg := deleteConfig()
+	cfg.Include = []string{"TMP_*"}
+	result := runManifest(t, cfg, fake)
+
+	if result.Deleted != 1 || len(result.Errors) != 1 {
+		t.Fatalf("Expected 1 deletion and 1 error, got %+v (errors: %v)", result, result.Errors)
+	}
+	want := []types.VariableResult{
+		{Scope: "acme/app", Name: "TMP_A", Action: types.ActionFailed},
+		{Scope: "acme/app", Name: "TMP_B", Action: types.ActionDeleted},
+	}
+	got := result.Details
+	for i := range got {
+		got[i].Reason = ""
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Details = %+v, want %+v", got, want)
+	}
+}
+
+// TestDeleteVariables_AuthErrorStops checks that a deletion refused for
+// lack of permission stops the run with the partial result
+func TestDeleteVariables_AuthErrorStops(t *testing.T) {
+	fake := deleteFixture()
+	for _, name := range []string{"TMP_C", "TMP_D"} {
+		fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: name, Value: "x"})
+	}
+	fake.forbidDeletes["TMP_C"] = true
+	cfg := deleteConfig()
+	cfg.Include = []string{"TMP_*"}
+
+	var result *types.MigrationResult
+	var err error
+	out := captureStdout(t, func() { result, err = newFakeMigrator(t, cfg, fake).Run() })
+
+	if err == nil || !client.IsAuthError(err) || !strings.Contains(err.Error(), "2 of 4 variable(s) deleted before stopping") {
+		t.Fatalf("Run() error = %v, want the refusal with the partial count", err)
+	}
+	if got, want := remaining(fake), []string{"EXPERIMENT", "KEEP", "TMP_C", "TMP_D"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Remaining variables = %v, want %v", got, want)
+	}
+	if result.Deleted != 2 || result.Skipped != 1 || len(result.Errors) != 1 {
+		t.Errorf("Expected 2 deletions, 1 failure, and 1 variable not attempted, got %+v", result)
+	}
+
+	// The summary of the partial result is printed before the error
+	for _, want := range []string{
+		"Not attempted after the refusal: TMP_D",
+		"Migration Summary",
+		"Deleted before the refusal: 2\n  - TMP_A (acme/app)\n  - TMP_B (acme/app)\n",
+		"acme/app  TMP_C     failed",
+		"acme/app  TMP_D     skipped  not attempted after the target token was refused",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestDeleteVariables_MissingScope(t *testing.T) {
+	cfg := deleteConfig()
+	cfg.Desired = []types.DesiredScope{{Owner: "acme", Repo: "app", Environment: "missing"}}
+	captureStdout(t, func() {
+		_, err := newFakeMigrator(t, cfg, newFakeGitHub()).Run()
+		if err == nil || !strings.Contains(err.Error(), "environment missing of acme/app not found") {
+			t.Errorf("Run() error = %v, want a listing failure", err)
+		}
+	})
+}
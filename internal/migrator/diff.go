@@ -0,0 +1,335 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// Scope labels used in diff and verification output
+const (
+	scopeOrg  = "organization"
+	scopeRepo = "repository"
+)
+
+// envScope returns the scope label for a repository environment
+func envScope(envName string) string {
+	return "env:" + envName
+}
+
+// repoScope returns the scope label for one repository of a fan-out
+func repoScope(repo string) string {
+	return "repo:" + repo
+}
+
+// Diff compares source and target variables for the configured mode without
+// writing anything. The same filters, name transformations, and value
+// transformations as a real migration are applied, so the result shows
+// exactly what a migration would change.
+func (m *Migrator) Diff() (*types.DiffResult, error) {
+	logger.Info("Comparing source and target: %s", config.GetDescription(m.config))
+
+	m.sourceClient.WaitForRateLimit()
+
+	diff, err := m.compare()
+	if err != nil {
+		return nil, err
+	}
+
+	m.printDiff(diff)
+	return diff, nil
+}
+
+// compare builds the diff for the configured mode
+func (m *Migrator) compare() (*types.DiffResult, error) {
+	switch m.config.Mode {
+	case types.ModeRepoToRepo:
+		if len(m.config.Targets) > 0 {
+			return m.diffTargets()
+		}
+		return m.diffRepoToRepo()
+	case types.ModeOrgToOrg:
+		if m.config.Deep {
+			return m.diffDeep()
+		}
+		return m.diffOrgToOrg()
+	case types.ModeOrgToRepo:
+		return m.diffOrgToRepo()
+	case types.ModeRepoToOrg:
+		return m.diffRepoToOrg()
+	case types.ModeFanOut:
+		return m.diffFanOut()
+	case types.ModeManifest, types.ModeImport:
+		return m.diffManifest()
+	default:
+		return nil, fmt.Errorf("unsupported migration mode: %s", m.config.Mode)
+	}
+}
+
+// diffOrgToOrg compares organization variables
+func (m *Migrator) diffOrgToOrg() (*types.DiffResult, error) {
+	sourceVars, err := m.sourceClient.ListOrgVariables(m.config.SourceOrg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source organization variables: %w", err)
+	}
+	targetVars, err := m.targetClient.ListOrgVariables(m.config.TargetOrg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target organization variables: %w", err)
+	}
+
+	if m.config.Visibility != "" {
+		for i := range sourceVars {
+			sourceVars[i].Visibility = m.config.Visibility
+		}
+	}
+
+	return &types.DiffResult{Entries: m.diffScope(scopeOrg, sourceVars, targetVars)}, nil
+}
+
+// diffOrgToRepo compares source organization variables with the target
+// repository's variables
+func (m *Migrator) diffOrgToRepo() (*types.DiffResult, error) {
+	sourceVars, err := m.sourceClient.ListOrgVariables(m.config.SourceOrg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source organization variables: %w", err)
+	}
+	targetVars, err := m.targetClient.ListRepoVariables(m.config.TargetOwner, m.config.TargetRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target repository variables: %w", err)
+	}
+
+	return &types.DiffResult{Entries: m.diffScope(scopeRepo, orgToRepoVariables(sourceVars), targetVars)}, nil
+}
+
+// diffRepoToOrg compares source repository variables, with the promotion
+// visibility applied, against the target organization's variables
+func (m *Migrator) diffRepoToOrg() (*types.DiffResult, error) {
+	sourceVars, err := m.sourceClient.ListRepoVariables(m.config.SourceOwner, m.config.SourceRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source repository variables: %w", err)
+	}
+	targetVars, err := m.targetClient.ListOrgVariables(m.config.TargetOrg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target organization variables: %w", err)
+	}
+
+	return &types.DiffResult{Entries: m.diffScope(scopeOrg, m.repoToOrgVariables(sourceVars), targetVars)}, nil
+}
+
+// diffFanOut compares source organization variables with the variables of
+// every selected target repository
+func (m *Migrator) diffFanOut() (*types.DiffResult, error) {
+	sourceVars, err := m.sourceClient.ListOrgVariables(m.config.SourceOrg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source organization variables: %w", err)
+	}
+	repos, err := m.fanOutRepos()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &types.DiffResult{}
+	for _, repo := range repos {
+		targetVars, err := m.targetClient.ListRepoVariables(m.config.TargetOrg, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list variables of target repository '%s': %w", repo, err)
+		}
+		diff.Entries = append(diff.Entries, m.diffScope(repoScope(repo), orgToRepoVariables(sourceVars), targetVars)...)
+	}
+	return diff, nil
+}
+
+// diffRepoToRepo compares repository variables and, unless environments are
+// skipped, the variables of every source environment
+func (m *Migrator) diffRepoToRepo() (*types.DiffResult, error) {
+	diff := &types.DiffResult{}
+	if !m.config.SkipRepoVars {
+		sourceVars, err := m.sourceClient.ListRepoVariables(m.config.SourceOwner, m.config.SourceRepo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list source repository variables: %w", err)
+		}
+		targetVars, err := m.targetClient.ListRepoVariables(m.config.TargetOwner, m.config.TargetRepo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list target repository variables: %w", err)
+		}
+		diff.Entries = m.diffScope(scopeRepo, sourceVars, targetVars)
+	}
+
+	if m.config.SkipEnvs {
+		return diff, nil
+	}
+
+	environments, err := m.sourceClient.ListEnvironments(m.config.SourceOwner, m.config.SourceRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environments: %w", err)
+	}
+	environments, err = m.selectEnvironments(environments, &types.MigrationResult{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, env := range environments {
+		sourceEnvVars, err := m.sourceClient.ListEnvVariables(m.config.SourceOwner, m.config.SourceRepo, env.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list source variables for environment '%s': %w", env.Name, err)
+		}
+
+		targetEnv := m.targetEnvName(env.Name)
+		targetEnvVars, err := m.targetClient.ListEnvVariables(m.config.TargetOwner, m.config.TargetRepo, targetEnv)
+		if err != nil {
+			// A missing target environment simply means every variable is new.
+			if _, envErr := m.targetClient.GetEnvironment(m.config.TargetOwner, m.config.TargetRepo, targetEnv); envErr == nil {
+				return nil, fmt.Errorf("failed to list target variables for environment '%s': %w", targetEnv, err)
+			}
+			logger.Debug("Environment '%s' does not exist in target repository", targetEnv)
+			targetEnvVars = nil
+		}
+
+		diff.Entries = append(diff.Entries, m.diffScope(envScope(targetEnv), sourceEnvVars, targetEnvVars)...)
+	}
+
+	return diff, nil
+}
+
+// diffScope categorizes the variables of one scope. Source variables go
+// through the migration filters and transformations before being compared
+// with the target by (case-insensitive) target name. Target-only variables
+// are only reported when no name filters or --since are active, since a
+// filtered run deliberately ignores most of the target.
+func (m *Migrator) diffScope(scope string, sourceVars, targetVars []types.Variable) []types.DiffEntry {
+	targetByName := make(map[string]types.Variable, len(targetVars))
+	for _, v := range targetVars {
+		targetByName[strings.ToUpper(v.Name)] = v
+	}
+
+	var entries []types.DiffEntry
+	matched := make(map[string]bool, len(sourceVars))
+
+	// Filtering counts are irrelevant for a diff, so use a throwaway result.
+	for _, v := range m.filterVariables(sourceVars, &types.MigrationResult{}) {
+		target, err := m.targetVariable(v)
+		if err != nil {
+			logger.Warning("Skipping variable '%s' in %s: %v", v.Name, scope, err)
+			continue
+		}
+
+		key := strings.ToUpper(target.Name)
+		matched[key] = true
+		entry := types.DiffEntry{
+			Scope:       scope,
+			Name:        v.Name,
+			TargetName:  target.Name,
+			SourceValue: target.Value,
+		}
+
+		existing, ok := targetByName[key]
+		switch {
+		case !ok:
+			entry.Status = types.DiffAdd
+		case existing.Value != target.Value || !sameVisibility(existing, target):
+			entry.Status = types.DiffUpdate
+			entry.TargetValue = existing.Value
+		default:
+			entry.Status = types.DiffUnchanged
+			entry.TargetValue = existing.Value
+		}
+		entries = append(entries, entry)
+	}
+
+	if !m.hasNameFilters() && m.config.Since.IsZero() {
+		for _, v := range targetVars {
+			if matched[strings.ToUpper(v.Name)] {
+				continue
+			}
+			entries = append(entries, types.DiffEntry{
+				Scope:       scope,
+				Name:        v.Name,
+				TargetName:  v.Name,
+				Status:      types.DiffTargetOnly,
+				TargetValue: v.Value,
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].TargetName < entries[j].TargetName
+	})
+	return entries
+}
+
+// sameVisibility reports whether two organization variables share the same
+// visibility. Repository and environment variables have no visibility.
+func sameVisibility(a, b types.Variable) bool {
+	if a.Visibility == "" || b.Visibility == "" {
+		return true
+	}
+	return a.Visibility == b.Visibility
+}
+
+// hasNameFilters reports whether any name-based selection is configured
+func (m *Migrator) hasNameFilters() bool {
+	return m.requestedVars != nil || len(m.config.Include) > 0 || len(m.config.Exclude) > 0 || m.nameRegex != nil
+}
+
+// printDiff prints a categorized diff report. Values are masked unless
+// ShowValues is set.
+func (m *Migrator) printDiff(diff *types.DiffResult) {
+	sections := []struct {
+		status types.DiffStatus
+		title  string
+		symbol string
+	}{
+		{types.DiffAdd, "Add", "+"},
+		{types.DiffUpdate, "Update", "~"},
+		{types.DiffUnchanged, "Unchanged", "="},
+		{types.DiffTargetOnly, "Target-only", "-"},
+	}
+
+	logger.Plain("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	logger.Plain("Diff Report")
+	logger.Plain("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	for _, sec := range sections {
+		count := diff.Count(sec.status)
+		if count == 0 {
+			continue
+		}
+		logger.Plain("%s (%d):", sec.title, count)
+		for _, e := range diff.Entries {
+			if e.Status != sec.status {
+				continue
+			}
+			logger.Plain("  %s [%s] %s%s", sec.symbol, e.Scope, nameLabel(e.Name, e.TargetName), m.diffValues(e))
+		}
+	}
+
+	logger.Plain("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	logger.Plain("Add: %d  Update: %d  Unchanged: %d  Target-only: %d",
+		diff.Count(types.DiffAdd), diff.Count(types.DiffUpdate),
+		diff.Count(types.DiffUnchanged), diff.Count(types.DiffTargetOnly))
+}
+
+// diffValues formats the value part of a diff line
+func (m *Migrator) diffValues(e types.DiffEntry) string {
+	show := func(v string) string {
+		if m.config.ShowValues {
+			return fmt.Sprintf("%q", v)
+		}
+		return maskedValue
+	}
+
+	switch e.Status {
+	case types.DiffAdd:
+		return " = " + show(e.SourceValue)
+	case types.DiffUpdate:
+		return " " + show(e.TargetValue) + " → " + show(e.SourceValue)
+	case types.DiffTargetOnly:
+		return " = " + show(e.TargetValue)
+	default:
+		return ""
+	}
+}
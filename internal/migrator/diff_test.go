@@ -0,0 +1,149 @@
+package migrator
+
+import (
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// findEntry returns the diff entry for a target name, or nil
+func findEntry(entries []types.DiffEntry, targetName string) *types.DiffEntry {
+	for i := range entries {
+		if entries[i].TargetName == targetName {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// TestDiffScope_Categories verifies each diff category
+func TestDiffScope_Categories(t *testing.T) {
+	m := &Migrator{config: &types.MigrationConfig{}}
+	source := []types.Variable{
+		{Name: "NEW_VAR", Value: "a"},
+		{Name: "CHANGED", Value: "new"},
+		{Name: "SAME", Value: "x"},
+		{Name: "case_only", Value: "y"},
+	}
+	target := []types.Variable{
+		{Name: "CHANGED", Value: "old"},
+		{Name: "SAME", Value: "x"},
+		{Name: "CASE_ONLY", Value: "y"},
+		{Name: "STALE", Value: "z"},
+	}
+
+	entries := m.diffScope(scopeRepo, source, target)
+
+	want := map[string]types.DiffStatus{
+		"NEW_VAR":   types.DiffAdd,
+		"CHANGED":   types.DiffUpdate,
+		"SAME":      types.DiffUnchanged,
+		"case_only": types.DiffUnchanged,
+		"STALE":     types.DiffTargetOnly,
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d: %+v", len(want), len(entries), entries)
+	}
+	for name, status := range want {
+		e := findEntry(entries, name)
+		if e == nil {
+			t.Errorf("Missing entry for %s", name)
+			continue
+		}
+		if e.Status != status {
+			t.Errorf("%s: expected status %s, got %s", name, status, e.Status)
+		}
+		if e.Scope != scopeRepo {
+			t.Errorf("%s: expected scope %s, got %s", name, scopeRepo, e.Scope)
+		}
+	}
+
+	if e := findEntry(entries, "CHANGED"); e.SourceValue != "new" || e.TargetValue != "old" {
+		t.Errorf("Expected update values new/old, got %+v", e)
+	}
+}
+
+// TestDiffScope_OrgVisibility verifies a visibility change counts as an update
+func TestDiffScope_OrgVisibility(t *testing.T) {
+	m := &Migrator{config: &types.MigrationConfig{}}
+	source := []types.Variable{{Name: "A", Value: "1", Visibility: "private"}}
+	target := []types.Variable{{Name: "A", Value: "1", Visibility: "all"}}
+
+	entries := m.diffScope(scopeOrg, source, target)
+	if len(entries) != 1 || entries[0].Status != types.DiffUpdate {
+		t.Errorf("Expected a single update entry, got %+v", entries)
+	}
+}
+
+// TestDiffScope_UsesTransformations verifies the diff applies the same name
+// and value transformations as a migration
+func TestDiffScope_UsesTransformations(t *testing.T) {
+	m := &Migrator{
+		config:         &types.MigrationConfig{TargetPrefix: "NEW_"},
+		nameMap:        newNameMap(map[string]string{"OLD_HOST": "HOST"}),
+		valueOverrides: newValueOverrides(map[string]string{"REGION": "us"}),
+	}
+	source := []types.Variable{
+		{Name: "OLD_HOST", Value: "db"},
+		{Name: "REGION", Value: "eu"},
+	}
+	target := []types.Variable{
+		{Name: "NEW_HOST", Value: "db"},
+		{Name: "NEW_REGION", Value: "us"},
+	}
+
+	entries := m.diffScope(scopeRepo, source, target)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	for _, e := range entries {
+		if e.Status != types.DiffUnchanged {
+			t.Errorf("%s: expected unchanged, got %s", e.TargetName, e.Status)
+		}
+	}
+}
+
+// TestDiffScope_FiltersHideTargetOnly verifies filtered diffs ignore the rest
+// of the target
+func TestDiffScope_FiltersHideTargetOnly(t *testing.T) {
+	m := &Migrator{config: &types.MigrationConfig{Include: []string{"DEPLOY_*"}}}
+	source := []types.Variable{{Name: "DEPLOY_URL", Value: "a"}, {Name: "OTHER", Value: "b"}}
+	target := []types.Variable{{Name: "UNRELATED", Value: "c"}}
+
+	entries := m.diffScope(scopeRepo, source, target)
+	if len(entries) != 1 || entries[0].TargetName != "DEPLOY_URL" || entries[0].Status != types.DiffAdd {
+		t.Errorf("Expected only DEPLOY_URL as add, got %+v", entries)
+	}
+}
+
+// TestDiffValues_Masking verifies values are masked unless ShowValues is set
+func TestDiffValues_Masking(t *testing.T) {
+	entry := types.DiffEntry{Status: types.DiffUpdate, SourceValue: "new-secret", TargetValue: "old-secret"}
+
+	masked := (&Migrator{config: &types.MigrationConfig{}}).diffValues(entry)
+	if masked != " "+maskedValue+" → "+maskedValue {
+		t.Errorf("Expected masked values, got %q", masked)
+	}
+
+	shown := (&Migrator{config: &types.MigrationConfig{ShowValues: true}}).diffValues(entry)
+	if shown != ` "old-secret" → "new-secret"` {
+		t.Errorf("Expected shown values, got %q", shown)
+	}
+}
+
+// TestDiffResult_HasDifferences verifies difference detection
+func TestDiffResult_HasDifferences(t *testing.T) {
+	same := &types.DiffResult{Entries: []types.DiffEntry{{Status: types.DiffUnchanged}}}
+	if same.HasDifferences() {
+		t.Error("Expected no differences for unchanged-only diff")
+	}
+	if (&types.DiffResult{}).HasDifferences() {
+		t.Error("Expected no differences for empty diff")
+	}
+	for _, status := range []types.DiffStatus{types.DiffAdd, types.DiffUpdate, types.DiffTargetOnly} {
+		d := &types.DiffResult{Entries: []types.DiffEntry{{Status: types.DiffUnchanged}, {Status: status}}}
+		if !d.HasDifferences() {
+			t.Errorf("Expected differences for status %s", status)
+		}
+	}
+}
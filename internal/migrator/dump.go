@@ -0,0 +1,123 @@
+package migrator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/dump"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// ExportDump reads the variables of an organization, of a repository of it
+// when repo is set, or of an environment of that repository when env is
+// set. withEnvs adds the variables of every environment to a repository
+// dump. Values are included; the caller masks them when they must not be
+// written.
+func ExportDump(c *client.Client, org, repo, env string, withEnvs bool) (*dump.Dump, error) {
+	if c == nil {
+		return nil, fmt.Errorf("client cannot be nil")
+	}
+	c.WaitForRateLimit()
+
+	d := &dump.Dump{
+		Version:     dump.Version,
+		ExportedAt:  time.Now().UTC(),
+		Org:         org,
+		Repo:        repo,
+		Environment: env,
+		Variables:   []dump.Variable{},
+	}
+
+	switch {
+	case repo == "":
+		vars, err := c.ListOrgVariables(org)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization variables: %w", err)
+		}
+		for _, v := range vars {
+			dv := dumpVariable(v)
+			dv.Visibility = v.Visibility
+			if v.Visibility == "selected" {
+				if dv.SelectedRepositories, err = selectedRepoNames(c, org, v.Name); err != nil {
+					return nil, err
+				}
+			}
+			d.Variables = append(d.Variables, dv)
+		}
+		return d, nil
+
+	case env != "":
+		vars, err := c.ListEnvVariables(org, repo, env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list variables of environment %s: %w", env, err)
+		}
+		d.Variables = dumpVariables(vars)
+		return d, nil
+	}
+
+	vars, err := c.ListRepoVariables(org, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository variables: %w", err)
+	}
+	d.Variables = dumpVariables(vars)
+	if !withEnvs {
+		return d, nil
+	}
+
+	envs, err := c.ListEnvironments(org, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environments: %w", err)
+	}
+	for _, e := range envs {
+		vars, err := c.ListEnvVariables(org, repo, e.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list variables of environment %s: %w", e.Name, err)
+		}
+		d.Environments = append(d.Environments, dump.Environment{Name: e.Name, Variables: dumpVariables(vars)})
+	}
+	return d, nil
+}
+
+// BackupDump reads the variables of an organization, with the visibility
+// and selected repositories of each. With deep, it adds the variables of
+// every repository of the organization and of their environments; archived
+// repositories are left out, as they cannot be restored into.
+func BackupDump(c *client.Client, org string, deep bool) (*dump.Dump, error) {
+	d, err := ExportDump(c, org, "", "", false)
+	if err != nil || !deep {
+		return d, err
+	}
+
+	repos, err := c.ListOrgRepos(org)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	for _, r := range repos {
+		if r.Archived {
+			logger.Info("Skipping archived repository %s/%s", org, r.Name)
+			continue
+		}
+		rd, err := ExportDump(c, org, r.Name, "", true)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", org, r.Name, err)
+		}
+		d.Repositories = append(d.Repositories, dump.Repository{Name: r.Name, Variables: rd.Variables, Environments: rd.Environments})
+	}
+	return d, nil
+}
+
+// dumpVariables converts repository or environment variables
+func dumpVariables(vars []types.Variable) []dump.Variable {
+	out := make([]dump.Variable, 0, len(vars))
+	for _, v := range vars {
+		out = append(out, dumpVariable(v))
+	}
+	return out
+}
+
+// dumpVariable converts a variable without its visibility
+func dumpVariable(v types.Variable) dump.Variable {
+	return dump.Variable{Name: v.Name, Value: v.Value, UpdatedAt: v.UpdatedAt}
+}
@@ -0,0 +1,157 @@
+package migrator
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/dump"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func TestExportDump(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("acme"), types.Variable{Name: "REGION", Value: "eu", Visibility: "all", UpdatedAt: "2026-01-01T00:00:00Z"})
+	fake.setVar(orgVarsPath("acme"), types.Variable{Name: "SHARED", Value: "yes", Visibility: "selected"})
+	fake.selected["acme/SHARED"] = []types.Repository{{ID: 2, Name: "web"}, {ID: 1, Name: "app"}}
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LOG_LEVEL", Value: "info"})
+	fake.addEnv("acme", "app", "production")
+	fake.addEnv("acme", "app", "staging")
+	fake.setVar(envVarsPath("acme", "app", "production"), types.Variable{Name: "URL", Value: "https://app.example.com"})
+
+	c, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		repo     string
+		env      string
+		withEnvs bool
+		wantVars []dump.Variable
+		wantEnvs []dump.Environment
+	}{
+		{
+			name: "organization",
+			wantVars: []dump.Variable{
+				{Name: "REGION", Value: "eu", Visibility: "all", UpdatedAt: "2026-01-01T00:00:00Z"},
+				{Name: "SHARED", Value: "yes", Visibility: "selected", SelectedRepositories: []string{"app", "web"}},
+			},
+		},
+		{
+			name:     "repository",
+			repo:     "app",
+			wantVars: []dump.Variable{{Name: "LOG_LEVEL", Value: "info"}},
+		},
+		{
+			name:     "repository with environments",
+			repo:     "app",
+			withEnvs: true,
+			wantVars: []dump.Variable{{Name: "LOG_LEVEL", Value: "info"}},
+			wantEnvs: []dump.Environment{
+				{Name: "production", Variables: []dump.Variable{{Name: "URL", Value: "https://app.example.com"}}},
+				{Name: "staging", Variables: []dump.Variable{}},
+			},
+		},
+		{
+			name:     "environment",
+			repo:     "app",
+			env:      "production",
+			wantVars: []dump.Variable{{Name: "URL", Value: "https://app.example.com"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ExportDump(c, "acme", tt.repo, tt.env, tt.withEnvs)
+			if err != nil {
+				t.Fatalf("ExportDump() unexpected error: %v", err)
+			}
+			if d.Version != dump.Version || d.Org != "acme" || d.Repo != tt.repo || d.Environment != tt.env || d.ExportedAt.IsZero() {
+				t.Errorf("Unexpected dump header: %+v", d)
+			}
+			if !reflect.DeepEqual(d.Variables, tt.wantVars) {
+				t.Errorf("Variables =\n %+v\nwant\n %+v", d.Variables, tt.wantVars)
+			}
+			if !reflect.DeepEqual(d.Environments, tt.wantEnvs) {
+				t.Errorf("Environments =\n %+v\nwant\n %+v", d.Environments, tt.wantEnvs)
+			}
+		})
+	}
+}
+
+// TestBackupDump_RoundTrip backs up an organization with its repositories,
+// restores the file into an organization with the same repositories, and
+// expects the same variables there, selected repositories included
+func TestBackupDump_RoundTrip(t *testing.T) {
+	source := newFakeGitHub()
+	appID := source.addRepo("acme", "app")
+	webID := source.addRepo("acme", "web")
+	source.addRepo("acme", "old")
+	source.archived["acme/old"] = true
+	source.setVar(orgVarsPath("acme"), types.Variable{Name: "REGION", Value: "eu", Visibility: "private"})
+	source.setVar(orgVarsPath("acme"), types.Variable{Name: "SHARED", Value: "yes", Visibility: "selected"})
+	source.selected["acme/SHARED"] = []types.Repository{{ID: webID, Name: "web"}, {ID: appID, Name: "app"}}
+	source.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LOG_LEVEL", Value: "info"})
+	source.setVar(repoVarsPath("acme", "old"), types.Variable{Name: "STALE", Value: "1"})
+	source.addEnv("acme", "app", "production")
+	source.setVar(envVarsPath("acme", "app", "production"), types.Variable{Name: "URL", Value: "https://app.example.com"})
+
+	c, err := client.NewWithTransport("test-token", "github.com", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backup *dump.Dump
+	captureStdout(t, func() {
+		backup, err = BackupDump(c, "acme", true)
+	})
+	if err != nil {
+		t.Fatalf("BackupDump() unexpected error: %v", err)
+	}
+	if got := len(backup.Repositories); got != 2 {
+		t.Fatalf("Expected the 2 unarchived repositories in the backup, got %+v", backup.Repositories)
+	}
+
+	path := filepath.Join(t.TempDir(), "acme.json")
+	if err := dump.Save(path, backup, dump.FormatJSON); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	loaded, err := dump.Load(path, dump.FormatJSON)
+	if err != nil {
+		t.Fatalf("Load() of a backup: %v", err)
+	}
+	scopes, err := loaded.Desired("acme", "", "")
+	if err != nil {
+		t.Fatalf("Desired() unexpected error: %v", err)
+	}
+
+	target := newFakeGitHub()
+	targetIDs := map[int64]string{target.addRepo("acme", "web"): "web", target.addRepo("acme", "app"): "app"}
+	cfg := &types.MigrationConfig{Mode: types.ModeImport, Manifest: path, Desired: scopes}
+	result := runManifest(t, cfg, target)
+	if result.Created != 4 || result.HasErrors() {
+		t.Fatalf("Expected 4 creates, got %+v (errors: %v)", result, result.Errors)
+	}
+
+	for _, path := range []string{orgVarsPath("acme"), repoVarsPath("acme", "app"), envVarsPath("acme", "app", "production")} {
+		for _, want := range source.vars[path] {
+			got, ok := target.getVar(path, want.Name)
+			if !ok || got.Value != want.Value || got.Visibility != want.Visibility {
+				t.Errorf("%s %s = %+v, want %+v", path, want.Name, got, want)
+			}
+		}
+	}
+	shared, _ := target.getVar(orgVarsPath("acme"), "SHARED")
+	var names []string
+	for _, id := range shared.SelectedRepositoryIDs {
+		names = append(names, targetIDs[id])
+	}
+	if !reflect.DeepEqual(names, []string{"app", "web"}) {
+		t.Errorf("SHARED is visible to %v, want [app web]", names)
+	}
+	if _, ok := target.getVar(repoVarsPath("acme", "old"), "STALE"); ok {
+		t.Error("Expected the archived repository to be left out of the backup")
+	}
+}
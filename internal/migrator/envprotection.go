@@ -0,0 +1,236 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// writeEnvironmentSettings creates the target environment envName, or
+// updates it when it exists, with the protection settings of the source
+// environment sourceEnv for --copy-env-protection. Custom deployment branch
+// policies are added once the environment is written.
+func (m *Migrator) writeEnvironmentSettings(sourceEnv, envName string, exists bool) error {
+	source, err := m.sourceClient.GetEnvironment(m.config.SourceOwner, m.config.SourceRepo, sourceEnv)
+	if err != nil {
+		return fmt.Errorf("failed to read protection settings of source environment '%s': %w", sourceEnv, err)
+	}
+	settings := m.environmentSettings(source)
+
+	var policies []types.BranchPolicy
+	if p := settings.DeploymentBranchPolicy; p != nil && p.CustomBranchPolicies {
+		policies, err = m.sourceClient.ListDeploymentBranchPolicies(m.config.SourceOwner, m.config.SourceRepo, sourceEnv)
+		if err != nil {
+			return fmt.Errorf("failed to read deployment branch policies of source environment '%s': %w", sourceEnv, err)
+		}
+	}
+	summary := describeEnvironmentSettings(settings, len(policies))
+	if p := settings.DeploymentBranchPolicy; p != nil && p.ProtectedBranches {
+		m.checkProtectedBranches(envName)
+	}
+
+	if m.config.DryRun {
+		if exists {
+			logger.Info("[DRY-RUN] Would update protection settings of environment %s: %s", envName, summary)
+		} else {
+			logger.Info("[DRY-RUN] Would create environment: %s (%s)", envName, summary)
+		}
+		return nil
+	}
+
+	if exists {
+		logger.Info("Updating protection settings of environment '%s' in target repository", envName)
+	} else {
+		logger.Info("Creating environment '%s' in target repository", envName)
+	}
+	if err := m.targetClient.UpdateEnvironment(m.config.TargetOwner, m.config.TargetRepo, envName, settings); err != nil {
+		if exists {
+			return fmt.Errorf("failed to update environment protection settings: %w", err)
+		}
+		return fmt.Errorf("failed to create environment: %w", err)
+	}
+	if err := m.addBranchPolicies(envName, policies, exists); err != nil {
+		return err
+	}
+
+	if exists {
+		logger.Success("Updated environment protection settings: %s (%s)", envName, summary)
+	} else {
+		logger.Success("Created environment: %s (%s)", envName, summary)
+	}
+	return nil
+}
+
+// environmentSettings builds the settings of a target environment from the
+// protection rules of a source environment: the wait timer, the
+// self-review setting, the required reviewers, and the deployment branch
+// policy. The custom branch policies themselves are written separately.
+func (m *Migrator) environmentSettings(source *types.Environment) types.EnvironmentSettings {
+	settings := types.EnvironmentSettings{
+		Reviewers:              []types.ReviewerRef{},
+		DeploymentBranchPolicy: source.DeploymentBranchPolicy,
+	}
+	for _, rule := range source.ProtectionRules {
+		switch rule.Type {
+		case types.RuleWaitTimer:
+			settings.WaitTimer = rule.WaitTimer
+		case types.RuleRequiredReviewers:
+			settings.PreventSelfReview = rule.PreventSelfReview
+			for _, r := range rule.Reviewers {
+				if ref, ok := m.targetReviewer(source.Name, r); ok {
+					settings.Reviewers = append(settings.Reviewers, ref)
+				}
+			}
+		}
+	}
+	return settings
+}
+
+// targetReviewer finds a required reviewer of a source environment in the
+// target: a user by login, a team by slug in the target owner's
+// organization. A reviewer that cannot be found is reported with a warning
+// and left out, since the IDs of the source do not carry over.
+func (m *Migrator) targetReviewer(env string, r types.EnvironmentReviewer) (types.ReviewerRef, bool) {
+	var label string
+	var id int64
+	var err error
+	switch r.Type {
+	case types.ReviewerUser:
+		label = "user " + r.Reviewer.Login
+		id, err = m.targetClient.GetUserID(r.Reviewer.Login)
+	case types.ReviewerTeam:
+		label = fmt.Sprintf("team %s/%s", m.config.TargetOwner, r.Reviewer.Slug)
+		id, err = m.targetClient.GetTeamID(m.config.TargetOwner, r.Reviewer.Slug)
+	default:
+		logger.Warning("Environment '%s': reviewer of unknown type %q left out", env, r.Type)
+		return types.ReviewerRef{}, false
+	}
+	if err != nil {
+		logger.Warning("Environment '%s': reviewer %s not found in the target and left out: %v", env, label, err)
+		return types.ReviewerRef{}, false
+	}
+	return types.ReviewerRef{Type: r.Type, ID: id}, true
+}
+
+// addBranchPolicies adds the custom deployment branch policies of the source
+// environment to the target environment envName. When the environment
+// already existed, policies it already has are left alone. Every policy is
+// attempted, and the failures are returned as one error.
+func (m *Migrator) addBranchPolicies(envName string, policies []types.BranchPolicy, exists bool) error {
+	if len(policies) == 0 {
+		return nil
+	}
+	owner, repo := m.config.TargetOwner, m.config.TargetRepo
+
+	have := map[string]bool{}
+	if exists {
+		current, err := m.targetClient.ListDeploymentBranchPolicies(owner, repo, envName)
+		if err != nil {
+			return fmt.Errorf("failed to read deployment branch policies of environment '%s': %w", envName, err)
+		}
+		for _, p := range current {
+			have[branchPolicyKey(p)] = true
+		}
+	}
+
+	var failed []string
+	for _, p := range policies {
+		if have[branchPolicyKey(p)] {
+			logger.Debug("Environment '%s' already has the deployment branch policy %s", envName, p.Name)
+			continue
+		}
+		if err := m.targetClient.CreateDeploymentBranchPolicy(owner, repo, envName, p); err != nil {
+			logger.Error("Failed to add deployment branch policy %s to environment '%s': %v", p.Name, envName, err)
+			failed = append(failed, p.Name)
+			continue
+		}
+		logger.Debug("Added deployment branch policy %s to environment '%s'", p.Name, envName)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to add deployment branch policies to environment '%s': %s", envName, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// branchPolicyKey identifies a branch policy by type and name, treating an
+// empty type as "branch"
+func branchPolicyKey(p types.BranchPolicy) string {
+	typ := p.Type
+	if typ == "" {
+		typ = "branch"
+	}
+	return typ + ":" + p.Name
+}
+
+// checkProtectedBranches warns when the target environment envName is
+// limited to protected branches but the target repository does not protect
+// the branches the source repository does, since deployments from those
+// branches would be rejected. A failure to list the branches is only
+// logged.
+func (m *Migrator) checkProtectedBranches(envName string) {
+	source, err := m.sourceClient.ListProtectedBranches(m.config.SourceOwner, m.config.SourceRepo)
+	if err == nil {
+		var target []string
+		target, err = m.targetClient.ListProtectedBranches(m.config.TargetOwner, m.config.TargetRepo)
+		if err == nil {
+			m.warnUnprotectedBranches(envName, source, target)
+			return
+		}
+	}
+	logger.Warning("Environment '%s' only allows deployments from protected branches; could not compare the protected branches of source and target: %v", envName, err)
+}
+
+// warnUnprotectedBranches reports the source protected branches that are
+// not protected in the target
+func (m *Migrator) warnUnprotectedBranches(envName string, source, target []string) {
+	repo := m.config.TargetOwner + "/" + m.config.TargetRepo
+	if len(target) == 0 {
+		logger.Warning("Environment '%s' only allows deployments from protected branches, but %s has no protected branches; deployments to it will be rejected until branches are protected",
+			envName, repo)
+		return
+	}
+	protected := make(map[string]bool, len(target))
+	for _, name := range target {
+		protected[name] = true
+	}
+	var missing []string
+	for _, name := range source {
+		if !protected[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		logger.Warning("Environment '%s' only allows deployments from protected branches, but %s does not protect %s; deployments from them will be rejected",
+			envName, repo, strings.Join(missing, ", "))
+	}
+}
+
+// describeEnvironmentSettings summarizes protection settings and the number
+// of custom branch policies for log messages, e.g. "wait timer 30 min,
+// 2 reviewer(s), self-review prevented, protected branches only"
+func describeEnvironmentSettings(s types.EnvironmentSettings, branchPolicies int) string {
+	var parts []string
+	if s.WaitTimer > 0 {
+		parts = append(parts, fmt.Sprintf("wait timer %d min", s.WaitTimer))
+	}
+	if len(s.Reviewers) > 0 {
+		parts = append(parts, fmt.Sprintf("%d reviewer(s)", len(s.Reviewers)))
+	}
+	if s.PreventSelfReview {
+		parts = append(parts, "self-review prevented")
+	}
+	if p := s.DeploymentBranchPolicy; p != nil {
+		switch {
+		case p.ProtectedBranches:
+			parts = append(parts, "protected branches only")
+		case p.CustomBranchPolicies:
+			parts = append(parts, fmt.Sprintf("%d branch policy(ies)", branchPolicies))
+		}
+	}
+	if len(parts) == 0 {
+		return "no protection rules"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,336 @@
+package migrator
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// reviewer builds a required reviewer as the environments API returns it
+func reviewer(typ, name string) types.EnvironmentReviewer {
+	var r types.EnvironmentReviewer
+	r.Type = typ
+	r.Reviewer.ID = 999
+	if typ == types.ReviewerTeam {
+		r.Reviewer.Slug = name
+	} else {
+		r.Reviewer.Login = name
+	}
+	return r
+}
+
+func TestEnvironmentSettings(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.users["octocat"] = 11
+	fake.teams["dst/release"] = 22
+	m := newFakeMigrator(t, repoToRepoConfig(), fake)
+
+	tests := []struct {
+		name  string
+		rules []types.ProtectionRule
+		want  types.EnvironmentSettings
+	}{
+		{
+			name: "no rules",
+			want: types.EnvironmentSettings{Reviewers: []types.ReviewerRef{}},
+		},
+		{
+			name:  "wait timer and branch policy",
+			rules: []types.ProtectionRule{{Type: types.RuleWaitTimer, WaitTimer: 30}, {Type: "branch_policy"}},
+			want:  types.EnvironmentSettings{WaitTimer: 30, Reviewers: []types.ReviewerRef{}},
+		},
+		{
+			name: "reviewers mapped by login and slug",
+			rules: []types.ProtectionRule{{
+				Type:              types.RuleRequiredReviewers,
+				PreventSelfReview: true,
+				Reviewers:         []types.EnvironmentReviewer{reviewer(types.ReviewerUser, "octocat"), reviewer(types.ReviewerTeam, "release")},
+			}},
+			want: types.EnvironmentSettings{
+				PreventSelfReview: true,
+				Reviewers:         []types.ReviewerRef{{Type: types.ReviewerUser, ID: 11}, {Type: types.ReviewerTeam, ID: 22}},
+			},
+		},
+		{
+			name: "unmappable reviewers left out",
+			rules: []types.ProtectionRule{{
+				Type: types.RuleRequiredReviewers,
+				Reviewers: []types.EnvironmentReviewer{
+					reviewer(types.ReviewerUser, "ghost"),
+					reviewer(types.ReviewerTeam, "platform"),
+					reviewer("Bot", "deployer"),
+					reviewer(types.ReviewerTeam, "release"),
+				},
+			}},
+			want: types.EnvironmentSettings{Reviewers: []types.ReviewerRef{{Type: types.ReviewerTeam, ID: 22}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got types.EnvironmentSettings
+			out := captureStdout(t, func() {
+				got = m.environmentSettings(&types.Environment{Name: "prod", ProtectionRules: tt.rules})
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("environmentSettings() = %+v, want %+v", got, tt.want)
+			}
+			if tt.name == "unmappable reviewers left out" {
+				for _, want := range []string{"reviewer user ghost not found", "reviewer team dst/platform not found", `unknown type "Bot"`} {
+					if !strings.Contains(out, want) {
+						t.Errorf("Expected a warning containing %q, got:\n%s", want, out)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDescribeEnvironmentSettings(t *testing.T) {
+	tests := []struct {
+		settings types.EnvironmentSettings
+		policies int
+		want     string
+	}{
+		{types.EnvironmentSettings{}, 0, "no protection rules"},
+		{types.EnvironmentSettings{WaitTimer: 5}, 0, "wait timer 5 min"},
+		{
+			types.EnvironmentSettings{WaitTimer: 30, PreventSelfReview: true, Reviewers: []types.ReviewerRef{{}, {}}}, 0,
+			"wait timer 30 min, 2 reviewer(s), self-review prevented",
+		},
+		{
+			types.EnvironmentSettings{DeploymentBranchPolicy: &types.DeploymentBranchPolicy{ProtectedBranches: true}}, 0,
+			"protected branches only",
+		},
+		{
+			types.EnvironmentSettings{DeploymentBranchPolicy: &types.DeploymentBranchPolicy{CustomBranchPolicies: true}}, 3,
+			"3 branch policy(ies)",
+		},
+	}
+	for _, tt := range tests {
+		if got := describeEnvironmentSettings(tt.settings, tt.policies); got != tt.want {
+			t.Errorf("describeEnvironmentSettings(%+v) = %q, want %q", tt.settings, got, tt.want)
+		}
+	}
+}
+
+// seedProtectedEnvFake returns a fake whose source repository has a prod
+// environment with a wait timer and one reviewer that exists in the target
+func seedProtectedEnvFake() *fakeGitHub {
+	fake := newFakeGitHub()
+	fake.setEnvRules("src", "app", "prod",
+		types.ProtectionRule{Type: types.RuleWaitTimer, WaitTimer: 10},
+		types.ProtectionRule{Type: types.RuleRequiredReviewers, PreventSelfReview: true,
+			Reviewers: []types.EnvironmentReviewer{reviewer(types.ReviewerUser, "octocat")}},
+	)
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "URL", Value: "https://prod"})
+	fake.users["octocat"] = 11
+	return fake
+}
+
+// TestCopyEnvProtection verifies that a new target environment is created
+// with the source protection settings, and that an existing one is only
+// updated with UpdateEnvSettings
+func TestCopyEnvProtection(t *testing.T) {
+	wantBody := types.EnvironmentSettings{
+		WaitTimer:         10,
+		PreventSelfReview: true,
+		Reviewers:         []types.ReviewerRef{{Type: types.ReviewerUser, ID: 11}},
+	}
+
+	tests := []struct {
+		name       string
+		existing   bool
+		update     bool
+		dryRun     bool
+		wantPut    bool
+		wantOutput string
+	}{
+		{name: "created with settings", wantPut: true, wantOutput: "Created environment: prod (wait timer 10 min, 1 reviewer(s), self-review prevented)"},
+		{name: "existing left alone", existing: true},
+		{name: "existing updated", existing: true, update: true, wantPut: true, wantOutput: "Updated environment protection settings: prod"},
+		{name: "dry run", dryRun: true, wantOutput: "[DRY-RUN] Would create environment: prod (wait timer 10 min"},
+		{name: "dry run update", existing: true, update: true, dryRun: true, wantOutput: "[DRY-RUN] Would update protection settings of environment prod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := seedProtectedEnvFake()
+			if tt.existing {
+				fake.addEnv("dst", "app", "prod")
+			}
+			cfg := repoToRepoConfig()
+			cfg.CopyEnvProtection = true
+			cfg.UpdateEnvSettings = tt.update
+			cfg.DryRun = tt.dryRun
+
+			var result *types.MigrationResult
+			out := captureStdout(t, func() {
+				var err error
+				result, err = newFakeMigrator(t, cfg, fake).Run()
+				if err != nil {
+					t.Errorf("Run() unexpected error: %v", err)
+				}
+			})
+			if result == nil || result.HasErrors() {
+				t.Fatalf("Unexpected result: %+v", result)
+			}
+
+			body := fake.envBody("dst", "app", "prod")
+			if !tt.wantPut {
+				if body != "" {
+					t.Errorf("Expected no PUT to the target environment, got %s", body)
+				}
+			} else {
+				var got types.EnvironmentSettings
+				if err := json.Unmarshal([]byte(body), &got); err != nil {
+					t.Fatalf("Invalid environment body %q: %v", body, err)
+				}
+				if !reflect.DeepEqual(got, wantBody) {
+					t.Errorf("Environment body = %+v, want %+v", got, wantBody)
+				}
+			}
+			if tt.wantOutput != "" && !strings.Contains(out, tt.wantOutput) {
+				t.Errorf("Expected %q in output:\n%s", tt.wantOutput, out)
+			}
+		})
+	}
+}
+
+// TestCopyEnvProtection_Disabled verifies that environments are still
+// created with an empty body without the flag
+func TestCopyEnvProtection_Disabled(t *testing.T) {
+	fake := seedProtectedEnvFake()
+	if _, err := newFakeMigrator(t, repoToRepoConfig(), fake).Run(); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if body := fake.envBody("dst", "app", "prod"); body != "{}" {
+		t.Errorf("Expected an empty environment body, got %s", body)
+	}
+	if fake.countCalls("GET users/octocat") != 0 {
+		t.Error("Reviewers must not be looked up without --copy-env-protection")
+	}
+}
+
+// TestCopyEnvProtection_CustomBranchPolicies verifies that the custom
+// branch and tag policies are recreated on a new environment, and that an
+// existing environment only gets the ones it lacks
+func TestCopyEnvProtection_CustomBranchPolicies(t *testing.T) {
+	policies := []types.BranchPolicy{{Name: "main", Type: "branch"}, {Name: "release/*", Type: "branch"}, {Name: "v*", Type: "tag"}}
+
+	for _, existing := range []bool{false, true} {
+		fake := newFakeGitHub()
+		fake.setEnvBranchPolicy("src", "app", "prod", types.DeploymentBranchPolicy{CustomBranchPolicies: true}, policies...)
+		if existing {
+			fake.setEnvBranchPolicy("dst", "app", "prod", types.DeploymentBranchPolicy{CustomBranchPolicies: true}, types.BranchPolicy{Name: "main"})
+		}
+		cfg := repoToRepoConfig()
+		cfg.CopyEnvProtection = true
+		cfg.UpdateEnvSettings = true
+
+		var result *types.MigrationResult
+		out := captureStdout(t, func() {
+			var err error
+			result, err = newFakeMigrator(t, cfg, fake).Run()
+			if err != nil {
+				t.Errorf("Run() unexpected error: %v", err)
+			}
+		})
+		if result == nil || result.HasErrors() {
+			t.Fatalf("existing=%v: unexpected result: %+v", existing, result)
+		}
+
+		var body types.EnvironmentSettings
+		if err := json.Unmarshal([]byte(fake.envBody("dst", "app", "prod")), &body); err != nil {
+			t.Fatalf("existing=%v: invalid environment body: %v", existing, err)
+		}
+		if p := body.DeploymentBranchPolicy; p == nil || !p.CustomBranchPolicies || p.ProtectedBranches {
+			t.Errorf("existing=%v: deployment_branch_policy = %+v, want custom branch policies", existing, p)
+		}
+
+		want := policies
+		if existing {
+			want = []types.BranchPolicy{{Name: "main"}, {Name: "release/*", Type: "branch"}, {Name: "v*", Type: "tag"}}
+		}
+		if got := fake.branchPolicies["dst/app/prod"]; !reflect.DeepEqual(got, want) {
+			t.Errorf("existing=%v: target branch policies = %+v, want %+v", existing, got, want)
+		}
+		if !strings.Contains(out, "3 branch policy(ies)") {
+			t.Errorf("existing=%v: expected the policies in the environment summary, got:\n%s", existing, out)
+		}
+	}
+}
+
+// TestCopyEnvProtection_BranchPolicyDryRun verifies that nothing is written
+// to the target in dry-run mode
+func TestCopyEnvProtection_BranchPolicyDryRun(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setEnvBranchPolicy("src", "app", "prod", types.DeploymentBranchPolicy{CustomBranchPolicies: true}, types.BranchPolicy{Name: "main"})
+	cfg := repoToRepoConfig()
+	cfg.CopyEnvProtection = true
+	cfg.DryRun = true
+
+	out := captureStdout(t, func() {
+		if _, err := newFakeMigrator(t, cfg, fake).Run(); err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+	if len(fake.branchPolicies["dst/app/prod"]) != 0 || fake.envBody("dst", "app", "prod") != "" {
+		t.Error("Nothing may be written in dry-run mode")
+	}
+	if !strings.Contains(out, "[DRY-RUN] Would create environment: prod (1 branch policy(ies))") {
+		t.Errorf("Expected the policies in the dry-run output, got:\n%s", out)
+	}
+}
+
+// TestCopyEnvProtection_ProtectedBranches verifies that protected_branches
+// is copied and that the target's missing protected branches are reported
+func TestCopyEnvProtection_ProtectedBranches(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   []string
+		wantWarn string
+	}{
+		{name: "matching", target: []string{"main", "release"}},
+		{name: "missing branch", target: []string{"main"}, wantWarn: "dst/app does not protect release; deployments from them will be rejected"},
+		{name: "none protected", wantWarn: "dst/app has no protected branches; deployments to it will be rejected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeGitHub()
+			fake.setEnvBranchPolicy("src", "app", "prod", types.DeploymentBranchPolicy{ProtectedBranches: true})
+			fake.protectedBranches["src/app"] = []string{"main", "release"}
+			fake.protectedBranches["dst/app"] = tt.target
+			cfg := repoToRepoConfig()
+			cfg.CopyEnvProtection = true
+
+			out := captureStdout(t, func() {
+				if _, err := newFakeMigrator(t, cfg, fake).Run(); err != nil {
+					t.Errorf("Run() unexpected error: %v", err)
+				}
+			})
+
+			var body types.EnvironmentSettings
+			if err := json.Unmarshal([]byte(fake.envBody("dst", "app", "prod")), &body); err != nil {
+				t.Fatalf("Invalid environment body: %v", err)
+			}
+			if p := body.DeploymentBranchPolicy; p == nil || !p.ProtectedBranches || p.CustomBranchPolicies {
+				t.Errorf("deployment_branch_policy = %+v, want protected branches", p)
+			}
+			if fake.countCalls("GET repos/src/app/environments/prod/deployment-branch-policies") != 0 {
+				t.Error("Custom policies must not be listed for a protected-branches policy")
+			}
+			if tt.wantWarn == "" {
+				if strings.Contains(out, "only allows deployments from protected branches") {
+					t.Errorf("Expected no warning, got:\n%s", out)
+				}
+			} else if !strings.Contains(out, tt.wantWarn) {
+				t.Errorf("Expected %q in output:\n%s", tt.wantWarn, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package migrator
+
+import "sync"
+
+// errorLimit counts the errors recorded during a run and reports when the
+// configured maximum is reached. It is shared by the Migrators of a
+// multi-repository run and safe for concurrent use.
+type errorLimit struct {
+	mu      sync.Mutex
+	max     int
+	count   int
+	reached bool
+}
+
+// newErrorLimit returns the limit for cfg: one error with --fail-fast, else
+// --max-errors, where zero means no limit
+func newErrorLimit(failFast bool, maxErrors int) *errorLimit {
+	if failFast {
+		maxErrors = 1
+	}
+	return &errorLimit{max: maxErrors}
+}
+
+// add records one error and reports whether it is the one that reached the
+// limit
+func (l *errorLimit) add() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.count++
+	if l.max == 0 || l.reached || l.count < l.max {
+		return false
+	}
+	l.reached = true
+	return true
+}
+
+// exceeded reports whether the limit has been reached
+func (l *errorLimit) exceeded() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.reached
+}
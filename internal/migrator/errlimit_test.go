@@ -0,0 +1,67 @@
+package migrator
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestErrorLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		failFast  bool
+		maxErrors int
+		errors    int
+		want      bool
+	}{
+		{name: "no limit", maxErrors: 0, errors: 100, want: false},
+		{name: "below the limit", maxErrors: 3, errors: 2, want: false},
+		{name: "exactly at the limit", maxErrors: 3, errors: 3, want: true},
+		{name: "past the limit", maxErrors: 3, errors: 5, want: true},
+		{name: "fail fast", failFast: true, errors: 1, want: true},
+		{name: "fail fast overrides the limit", failFast: true, maxErrors: 1, errors: 1, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newErrorLimit(tt.failFast, tt.maxErrors)
+			hits := 0
+			for i := 0; i < tt.errors; i++ {
+				if l.add() {
+					hits++
+				}
+			}
+			if got := l.exceeded(); got != tt.want {
+				t.Errorf("exceeded() = %v, want %v", got, tt.want)
+			}
+			if tt.want && hits != 1 {
+				t.Errorf("add() should report reaching the limit exactly once, got %d", hits)
+			}
+		})
+	}
+}
+
+// TestErrorLimit_Concurrent verifies that the limit is reached exactly once
+// when errors are recorded from many goroutines
+func TestErrorLimit_Concurrent(t *testing.T) {
+	l := newErrorLimit(false, 10)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	hits := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.add() {
+				mu.Lock()
+				hits++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if hits != 1 || !l.exceeded() || l.count != 50 {
+		t.Errorf("hits=%d exceeded=%v count=%d, want 1, true, 50", hits, l.exceeded(), l.count)
+	}
+}
@@ -0,0 +1,526 @@
+package migrator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// fakeGitHub is an in-memory stand-in for the subset of the GitHub REST API
+// used by the migrator. It implements http.RoundTripper so real client.Client
+// instances can be pointed at it. Variables are stored per collection path,
+// e.g. "orgs/acme/actions/variables" or
+// "repos/acme/app/environments/prod/variables".
+type fakeGitHub struct {
+	mu sync.Mutex
+
+	vars     map[string]map[string]types.Variable
+	envs     map[string]map[string]bool
+	repos    map[string]int64
+	archived map[string]bool
+	selected map[string][]types.Repository
+	// workflows holds workflow file contents by "owner/repo" and path
+	workflows map[string]map[string]string
+	// envRules and envBranchPolicy hold the protection settings returned for
+	// an environment, branchPolicies its custom deployment branch policies,
+	// and envBodies the body of the last PUT to it, all by "owner/repo/env"
+	envRules        map[string][]types.ProtectionRule
+	envBranchPolicy map[string]*types.DeploymentBranchPolicy
+	branchPolicies  map[string][]types.BranchPolicy
+	envBodies       map[string]string
+	// protectedBranches holds the protected branch names by "owner/repo"
+	protectedBranches map[string][]string
+	// users and teams hold IDs by login and by "org/slug"
+	users map[string]int64
+	teams map[string]int64
+
+	// staleValues makes list calls return a different value for a variable,
+	// keyed by "<collection path>/<NAME>".
+	staleValues map[string]string
+	// failWrites makes create, update, and delete calls fail for the named
+	// variables.
+	failWrites map[string]bool
+	// forbidDeletes makes delete calls for the named variables fail with
+	// 403, as for a token without write access.
+	forbidDeletes map[string]bool
+	// afterCall, when set, is called with each request once it is handled,
+	// e.g. "POST repos/acme/app/actions/variables".
+	afterCall func(call string)
+
+	calls []string
+}
+
+// newFakeGitHub returns an empty fake API
+func newFakeGitHub() *fakeGitHub {
+	return &fakeGitHub{
+		vars:              map[string]map[string]types.Variable{},
+		envs:              map[string]map[string]bool{},
+		repos:             map[string]int64{},
+		archived:          map[string]bool{},
+		selected:          map[string][]types.Repository{},
+		workflows:         map[string]map[string]string{},
+		envRules:          map[string][]types.ProtectionRule{},
+		envBodies:         map[string]string{},
+		envBranchPolicy:   map[string]*types.DeploymentBranchPolicy{},
+		branchPolicies:    map[string][]types.BranchPolicy{},
+		protectedBranches: map[string][]string{},
+		users:             map[string]int64{},
+		teams:             map[string]int64{},
+		staleValues:       map[string]string{},
+		failWrites:        map[string]bool{},
+		forbidDeletes:     map[string]bool{},
+	}
+}
+
+// orgVarsPath returns the collection path for organization variables
+func orgVarsPath(org string) string {
+	return fmt.Sprintf("orgs/%s/actions/variables", org)
+}
+
+// repoVarsPath returns the collection path for repository variables
+func repoVarsPath(owner, repo string) string {
+	return fmt.Sprintf("repos/%s/%s/actions/variables", owner, repo)
+}
+
+// envVarsPath returns the collection path for environment variables
+func envVarsPath(owner, repo, env string) string {
+	return fmt.Sprintf("repos/%s/%s/environments/%s/variables", owner, repo, env)
+}
+
+// setVar stores a variable in a collection
+func (f *fakeGitHub) setVar(path string, v types.Variable) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.vars[path] == nil {
+		f.vars[path] = map[string]types.Variable{}
+	}
+	f.vars[path][strings.ToUpper(v.Name)] = v
+}
+
+// getVar returns a stored variable
+func (f *fakeGitHub) getVar(path, name string) (types.Variable, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.vars[path][strings.ToUpper(name)]
+	return v, ok
+}
+
+// addEnv registers an environment on a repository
+func (f *fakeGitHub) addEnv(owner, repo, env string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := owner + "/" + repo
+	if f.envs[key] == nil {
+		f.envs[key] = map[string]bool{}
+	}
+	f.envs[key][env] = true
+}
+
+// setEnvRules registers an environment with protection rules
+func (f *fakeGitHub) setEnvRules(owner, repo, env string, rules ...types.ProtectionRule) {
+	f.addEnv(owner, repo, env)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.envRules[owner+"/"+repo+"/"+env] = rules
+}
+
+// setEnvBranchPolicy registers an environment with a deployment branch
+// policy and, for custom policies, the branch and tag patterns
+func (f *fakeGitHub) setEnvBranchPolicy(owner, repo, env string, policy types.DeploymentBranchPolicy, patterns ...types.BranchPolicy) {
+	f.addEnv(owner, repo, env)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := owner + "/" + repo + "/" + env
+	f.envBranchPolicy[key] = &policy
+	f.branchPolicies[key] = patterns
+}
+
+// envBody returns the body of the last PUT to an environment
+func (f *fakeGitHub) envBody(owner, repo, env string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.envBodies[owner+"/"+repo+"/"+env]
+}
+
+// addRepo registers a repository and returns its ID
+func (f *fakeGitHub) addRepo(owner, name string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := int64(len(f.repos) + 1)
+	f.repos[owner+"/"+name] = id
+	return id
+}
+
+// addWorkflow stores a workflow file of a repository
+func (f *fakeGitHub) addWorkflow(owner, repo, name, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := owner + "/" + repo
+	if f.workflows[key] == nil {
+		f.workflows[key] = map[string]string{}
+	}
+	f.workflows[key][".github/workflows/"+name] = content
+}
+
+// countCalls returns how many recorded calls equal the given method and
+// path, e.g. "GET repos/acme/app/actions/variables".
+func (f *fakeGitHub) countCalls(call string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		if c == call {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	varItemRe      = regexp.MustCompile(`^(.*/variables)/([^/]+)$`)
+	selectedRe     = regexp.MustCompile(`^orgs/([^/]+)/actions/variables/([^/]+)/repositories$`)
+	envListRe      = regexp.MustCompile(`^repos/([^/]+)/([^/]+)/environments$`)
+	envItemRe      = regexp.MustCompile(`^repos/([^/]+)/([^/]+)/environments/([^/]+)$`)
+	repoItemRe     = regexp.MustCompile(`^repos/([^/]+)/([^/]+)$`)
+	orgReposRe     = regexp.MustCompile(`^orgs/([^/]+)/repos$`)
+	contentsRe     = regexp.MustCompile(`^repos/([^/]+)/([^/]+)/contents/(.+)$`)
+	userRe         = regexp.MustCompile(`^users/([^/]+)$`)
+	policiesRe     = regexp.MustCompile(`^repos/([^/]+)/([^/]+)/environments/([^/]+)/deployment-branch-policies$`)
+	branchesRe     = regexp.MustCompile(`^repos/([^/]+)/([^/]+)/branches$`)
+	teamRe         = regexp.MustCompile(`^orgs/([^/]+)/teams/([^/]+)$`)
+	notFoundBody   = `{"message":"Not Found"}`
+	writeFailedMsg = `{"message":"injected failure"}`
+)
+
+// RoundTrip implements http.RoundTripper
+func (f *fakeGitHub) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := strings.TrimPrefix(req.URL.Path, "/")
+	path = strings.TrimPrefix(path, "api/v3/")
+
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, req.Method+" "+path)
+	f.mu.Unlock()
+
+	status, payload := f.handle(req.Method, path, req.URL.Query(), body)
+	if f.afterCall != nil {
+		f.afterCall(req.Method + " " + path)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(payload)),
+		Request:    req,
+	}, nil
+}
+
+// handle routes a request to the in-memory state
+func (f *fakeGitHub) handle(method, path string, query url.Values, body []byte) (int, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case path == "rate_limit":
+		return 200, `{"resources":{"core":{"limit":5000,"remaining":5000,"reset":0}}}`
+	case path == "user":
+		return 200, `{"login":"tester"}`
+	case strings.HasSuffix(path, "/variables"):
+		return f.handleCollection(method, path, body)
+	}
+
+	if m := contentsRe.FindStringSubmatch(path); m != nil && method == http.MethodGet {
+		return f.handleContents(m[1]+"/"+m[2], m[3])
+	}
+	if m := selectedRe.FindStringSubmatch(path); m != nil {
+		repos := f.selected[m[1]+"/"+strings.ToUpper(m[2])]
+		return 200, mustJSON(map[string]interface{}{"total_count": len(repos), "repositories": repos})
+	}
+	if m := varItemRe.FindStringSubmatch(path); m != nil {
+		return f.handleItem(method, m[1], m[2], body)
+	}
+	if m := envListRe.FindStringSubmatch(path); m != nil {
+		var names []string
+		for name := range f.envs[m[1]+"/"+m[2]] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		envs := make([]types.Environment, len(names))
+		for i, name := range names {
+			envs[i] = types.Environment{Name: name}
+		}
+		return 200, mustJSON(map[string]interface{}{"total_count": len(envs), "environments": envs})
+	}
+	if m := envItemRe.FindStringSubmatch(path); m != nil {
+		key := m[1] + "/" + m[2]
+		switch method {
+		case http.MethodGet:
+			if !f.envs[key][m[3]] {
+				return 404, notFoundBody
+			}
+			return 200, mustJSON(types.Environment{
+				Name:                   m[3],
+				ProtectionRules:        f.envRules[key+"/"+m[3]],
+				DeploymentBranchPolicy: f.envBranchPolicy[key+"/"+m[3]],
+			})
+		case http.MethodPut:
+			if f.envs[key] == nil {
+				f.envs[key] = map[string]bool{}
+			}
+			f.envs[key][m[3]] = true
+			f.envBodies[key+"/"+m[3]] = string(body)
+			return 200, mustJSON(types.Environment{Name: m[3]})
+		}
+	}
+	if m := policiesRe.FindStringSubmatch(path); m != nil {
+		key := m[1] + "/" + m[2] + "/" + m[3]
+		if !f.envs[m[1]+"/"+m[2]][m[3]] {
+			return 404, notFoundBody
+		}
+		if method == http.MethodPost {
+			var p types.BranchPolicy
+			_ = json.Unmarshal(body, &p)
+			f.branchPolicies[key] = append(f.branchPolicies[key], p)
+			return 200, mustJSON(p)
+		}
+		policies := f.branchPolicies[key]
+		return 200, mustJSON(map[string]interface{}{"total_count": len(policies), "branch_policies": policies})
+	}
+	if m := branchesRe.FindStringSubmatch(path); m != nil && method == http.MethodGet && query.Get("protected") == "true" {
+		branches := []map[string]interface{}{}
+		for _, name := range f.protectedBranches[m[1]+"/"+m[2]] {
+			branches = append(branches, map[string]interface{}{"name": name, "protected": true})
+		}
+		return 200, mustJSON(branches)
+	}
+	if m := userRe.FindStringSubmatch(path); m != nil && method == http.MethodGet {
+		id, ok := f.users[m[1]]
+		if !ok {
+			return 404, notFoundBody
+		}
+		return 200, mustJSON(map[string]interface{}{"id": id, "login": m[1]})
+	}
+	if m := teamRe.FindStringSubmatch(path); m != nil && method == http.MethodGet {
+		id, ok := f.teams[m[1]+"/"+m[2]]
+		if !ok {
+			return 404, notFoundBody
+		}
+		return 200, mustJSON(map[string]interface{}{"id": id, "slug": m[2]})
+	}
+	if m := orgReposRe.FindStringSubmatch(path); m != nil && method == http.MethodGet {
+		return 200, mustJSON(f.orgRepos(m[1], query))
+	}
+	if m := repoItemRe.FindStringSubmatch(path); m != nil && method == http.MethodGet {
+		id, ok := f.repos[m[1]+"/"+m[2]]
+		if !ok {
+			return 404, notFoundBody
+		}
+		return 200, mustJSON(types.Repository{ID: id, Name: m[2], Archived: f.archived[m[1]+"/"+m[2]]})
+	}
+
+	return 404, notFoundBody
+}
+
+// orgRepos returns one page of an organization's repositories, sorted by
+// name
+func (f *fakeGitHub) orgRepos(org string, query url.Values) []types.Repository {
+	var repos []types.Repository
+	for key, id := range f.repos {
+		owner, name, _ := strings.Cut(key, "/")
+		if owner == org {
+			repos = append(repos, types.Repository{ID: id, Name: name, Archived: f.archived[key]})
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+
+	perPage, _ := strconv.Atoi(query.Get("per_page"))
+	page, _ := strconv.Atoi(query.Get("page"))
+	if perPage <= 0 {
+		perPage = 30
+	}
+	if page <= 0 {
+		page = 1
+	}
+	start := min((page-1)*perPage, len(repos))
+	end := min(start+perPage, len(repos))
+	return repos[start:end]
+}
+
+// handleCollection serves list and create calls for a variables collection
+func (f *fakeGitHub) handleCollection(method, path string, body []byte) (int, string) {
+	switch method {
+	case http.MethodGet:
+		var names []string
+		for name := range f.vars[path] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		list := make([]types.Variable, 0, len(names))
+		for _, name := range names {
+			v := f.vars[path][name]
+			if stale, ok := f.staleValues[path+"/"+name]; ok {
+				v.Value = stale
+			}
+			list = append(list, v)
+		}
+		return 200, mustJSON(map[string]interface{}{"total_count": len(list), "variables": list})
+	case http.MethodPost:
+		var v types.Variable
+		if err := json.Unmarshal(body, &v); err != nil {
+			return 400, `{"message":"bad body"}`
+		}
+		if f.failWrites[strings.ToUpper(v.Name)] {
+			return 500, writeFailedMsg
+		}
+		if _, exists := f.vars[path][strings.ToUpper(v.Name)]; exists {
+			return 409, `{"message":"Already exists"}`
+		}
+		if f.vars[path] == nil {
+			f.vars[path] = map[string]types.Variable{}
+		}
+		f.vars[path][strings.ToUpper(v.Name)] = v
+		return 201, `{}`
+	}
+	return 405, `{"message":"Method not allowed"}`
+}
+
+// handleItem serves get, update, and delete calls for a single variable
+func (f *fakeGitHub) handleItem(method, collection, name string, body []byte) (int, string) {
+	key := strings.ToUpper(name)
+	existing, ok := f.vars[collection][key]
+
+	switch method {
+	case http.MethodGet:
+		if !ok {
+			return 404, notFoundBody
+		}
+		return 200, mustJSON(existing)
+	case http.MethodPatch:
+		if !ok {
+			return 404, notFoundBody
+		}
+		if f.failWrites[key] {
+			return 500, writeFailedMsg
+		}
+		var v types.Variable
+		if err := json.Unmarshal(body, &v); err != nil {
+			return 400, `{"message":"bad body"}`
+		}
+		f.vars[collection][key] = v
+		return 204, ``
+	case http.MethodDelete:
+		if !ok {
+			return 404, notFoundBody
+		}
+		if f.failWrites[key] {
+			return 500, writeFailedMsg
+		}
+		if f.forbidDeletes[key] {
+			return 403, `{"message":"Resource not accessible by personal access token"}`
+		}
+		delete(f.vars[collection], key)
+		return 204, ``
+	}
+	return 405, `{"message":"Method not allowed"}`
+}
+
+// mustJSON encodes v or panics; inputs are always encodable test data
+func mustJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// newFakeMigrator builds a Migrator whose source and target clients both talk
+// to the fake API.
+func newFakeMigrator(t *testing.T, cfg *types.MigrationConfig, fake *fakeGitHub) *Migrator {
+	t.Helper()
+
+	sourceClient, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("failed to create source client: %v", err)
+	}
+	targetClient, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatalf("failed to create target client: %v", err)
+	}
+
+	m, err := New(cfg, sourceClient, targetClient)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+	return m
+}
+
+// TestFakeGitHub_RepoMigration exercises a full repo-to-repo migration
+// against the fake API to make sure the harness itself behaves
+func TestFakeGitHub_RepoMigration(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "A", Value: "1"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "B", Value: "2"})
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "B", Value: "old"})
+	fake.addEnv("src", "app", "prod")
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "E", Value: "3"})
+
+	m := newFakeMigrator(t, &types.MigrationConfig{
+		Mode:        types.ModeRepoToRepo,
+		SourceOwner: "src",
+		SourceRepo:  "app",
+		TargetOwner: "dst",
+		TargetRepo:  "app",
+	}, fake)
+
+	result, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 2 || result.Updated != 1 || result.HasErrors() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if v, _ := fake.getVar(repoVarsPath("dst", "app"), "B"); v.Value != "2" {
+		t.Errorf("Expected B to be updated to 2, got %q", v.Value)
+	}
+	if v, ok := fake.getVar(envVarsPath("dst", "app", "prod"), "E"); !ok || v.Value != "3" {
+		t.Errorf("Expected environment variable E to be created, got %+v (found %v)", v, ok)
+	}
+}
+
+// handleContents serves the contents API for workflow files: the listing of
+// .github/workflows and each file, base64-encoded
+func (f *fakeGitHub) handleContents(repo, path string) (int, string) {
+	files := f.workflows[repo]
+	if path == ".github/workflows" {
+		if len(files) == 0 {
+			return 404, notFoundBody
+		}
+		var entries []map[string]string
+		for p := range files {
+			entries = append(entries, map[string]string{"path": p, "type": "file"})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i]["path"] < entries[j]["path"] })
+		return 200, mustJSON(entries)
+	}
+	content, ok := files[path]
+	if !ok {
+		return 404, notFoundBody
+	}
+	return 200, mustJSON(map[string]string{"content": base64.StdEncoding.EncodeToString([]byte(content)), "encoding": "base64"})
+}
@@ -0,0 +1,158 @@
+package migrator
+
+import (
+	"fmt"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// migrateFanOut copies organization variables into every selected
+// repository of the target organization as repository variables. A failing
+// repository is recorded and the run moves on to the next one; the counts
+// of each repository are kept in result.Repos.
+func (m *Migrator) migrateFanOut() (*types.MigrationResult, error) {
+	result := m.newResult()
+
+	// Check rate limit before starting the API-intensive migration
+	m.sourceClient.WaitForRateLimit()
+
+	logger.Info("Fetching variables from source organization: %s", m.config.SourceOrg)
+
+	sourceVars, err := m.sourceClient.ListOrgVariables(m.config.SourceOrg)
+	if err != nil {
+		return result, fmt.Errorf("failed to list source organization variables: %w", err)
+	}
+
+	logger.Info("Found %d variable(s) in source organization", len(sourceVars))
+
+	sourceVars = m.filterVariables(sourceVars, result)
+	result.Add(&result.Selected, len(sourceVars))
+	if err := m.checkNameCollisions(sourceVars); err != nil {
+		return result, err
+	}
+	sourceVars, err = m.checkLimits("each repository", sourceVars, maxRepoVariables, result)
+	if err != nil {
+		return result, err
+	}
+
+	repos, err := m.fanOutRepos()
+	if err != nil {
+		return result, err
+	}
+
+	logger.Info("Fanning out %d variable(s) to %d repository(ies) in %s", len(sourceVars), len(repos), m.config.TargetOrg)
+
+	// Visibility does not exist at repository level, so it is dropped
+	vars := orgToRepoVariables(sourceVars)
+	defer func() { m.fanOutRepo = "" }()
+
+	for i, repo := range repos {
+		if m.stopped() {
+			break
+		}
+		logger.Info("Repository %s/%s (%d/%d)", m.config.TargetOrg, repo, i+1, len(repos))
+
+		m.fanOutRepo = repo
+		repoResult := &types.MigrationResult{}
+		fatal := m.migrateFanOutRepo(repo, vars, repoResult)
+
+		result.AddCounts(repoResult)
+		for _, e := range repoResult.Errors {
+			result.AddError(e)
+		}
+		for _, d := range repoResult.Details {
+			result.AddDetail(d)
+		}
+		result.AddRepo(newRepoResult(repo, repoResult, fatal))
+	}
+
+	return result, nil
+}
+
+// migrateFanOutRepo writes the variables to one fan-out repository,
+// recording failures in the result. It reports whether the repository could
+// not be accessed at all.
+func (m *Migrator) migrateFanOutRepo(repo string, vars []types.Variable, result *types.MigrationResult) bool {
+	if _, err := m.targetClient.GetRepo(m.config.TargetOrg, repo); err != nil {
+		logger.Error("Failed to access repository '%s': %v", repo, err)
+		m.addError(result, fmt.Errorf("repository '%s': %w", repo, err))
+		return true
+	}
+
+	for _, variable := range vars {
+		if m.stopped() {
+			break
+		}
+		if err := m.migrateRepoVariable(variable, result); err != nil {
+			logger.VariableError(variable.Name, "Failed to migrate variable '%s' to repository '%s': %v", variable.Name, repo, err)
+			recordFailed(m.currentRepoScope(), variable.Name, err, result)
+			m.addError(result, fmt.Errorf("repository '%s' variable '%s': %w", repo, variable.Name, err))
+		}
+	}
+	return false
+}
+
+// fanOutRepos returns the fan-out target repositories: the configured list,
+// or every non-archived repository of the target organization when
+// AllRepos is set. The result is cached so the --on-conflict=fail pre-scan
+// and the migration see the same repositories.
+func (m *Migrator) fanOutRepos() ([]string, error) {
+	if m.fanOutRepoList != nil {
+		logger.Debug("Reusing the %d fan-out repository(ies) resolved earlier", len(m.fanOutRepoList))
+		return m.fanOutRepoList, nil
+	}
+	if !m.config.AllRepos {
+		m.fanOutRepoList = m.config.TargetRepos
+		return m.fanOutRepoList, nil
+	}
+
+	repos, err := m.targetClient.ListOrgRepos(m.config.TargetOrg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target organization repositories: %w", err)
+	}
+
+	names := make([]string, 0, len(repos))
+	archived := 0
+	for _, r := range repos {
+		if r.Archived {
+			archived++
+			continue
+		}
+		names = append(names, r.Name)
+	}
+	if archived > 0 {
+		logger.Info("Skipping %d archived repository(ies) in %s", archived, m.config.TargetOrg)
+	}
+	if len(names) == 0 {
+		logger.Warning("No repositories found in target organization %s", m.config.TargetOrg)
+	}
+
+	m.fanOutRepoList = names
+	return names, nil
+}
+
+// targetRepository returns the owner and name of the repository that
+// repository variables are written to
+func (m *Migrator) targetRepository() (string, string) {
+	if m.fanOutRepo != "" {
+		return m.config.TargetOrg, m.fanOutRepo
+	}
+	return m.config.TargetOwner, m.config.TargetRepo
+}
+
+// currentRepoScope returns the scope label for repository variables written now
+func (m *Migrator) currentRepoScope() string {
+	if m.fanOutRepo != "" {
+		return repoScope(m.fanOutRepo)
+	}
+	return scopeRepo
+}
+
+// fanOutNote returns the repository suffix used in fan-out log lines
+func (m *Migrator) fanOutNote() string {
+	if m.fanOutRepo == "" {
+		return ""
+	}
+	return " (repo: " + m.fanOutRepo + ")"
+}
@@ -0,0 +1,177 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func fanOutConfig(repos ...string) *types.MigrationConfig {
+	return &types.MigrationConfig{
+		Mode:        types.ModeFanOut,
+		SourceOrg:   "src",
+		TargetOrg:   "dst",
+		TargetRepos: repos,
+	}
+}
+
+// seedFanOutFake returns a fake with two source organization variables and
+// the target repositories api, web, and worker; web already has A.
+func seedFanOutFake() *fakeGitHub {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "A", Value: "1", Visibility: "all"})
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "B", Value: "2", Visibility: "private"})
+	for _, repo := range []string{"api", "web", "worker"} {
+		fake.addRepo("dst", repo)
+	}
+	fake.setVar(repoVarsPath("dst", "web"), types.Variable{Name: "A", Value: "old"})
+	return fake
+}
+
+func TestMigrateFanOut(t *testing.T) {
+	fake := seedFanOutFake()
+
+	result, err := newFakeMigrator(t, fanOutConfig("api", "web"), fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 3 || result.Updated != 1 || result.HasErrors() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	want := []types.RepoResult{
+		{Repo: "api", Created: 2},
+		{Repo: "web", Created: 1, Updated: 1},
+	}
+	if !reflect.DeepEqual(result.Repos, want) {
+		t.Errorf("Repos = %+v, want %+v", result.Repos, want)
+	}
+
+	for _, repo := range []string{"api", "web"} {
+		for name, value := range map[string]string{"A": "1", "B": "2"} {
+			v, ok := fake.getVar(repoVarsPath("dst", repo), name)
+			if !ok || v.Value != value || v.Visibility != "" {
+				t.Errorf("Expected %s=%q in %s without visibility, got %+v (found %v)", name, value, repo, v, ok)
+			}
+		}
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "worker"), "A"); ok {
+		t.Error("Unselected repository must not be written to")
+	}
+}
+
+func TestMigrateFanOut_PartialFailures(t *testing.T) {
+	fake := seedFanOutFake()
+	fake.failWrites["B"] = true
+
+	cfg := fanOutConfig("api", "missing", "web")
+	cfg.OnConflict = types.ConflictSkip
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	want := []types.RepoResult{
+		{Repo: "api", Created: 1, Errors: 1},
+		{Repo: "missing", Errors: 1, Failed: true},
+		{Repo: "web", Skipped: 1, Errors: 1, Failed: true},
+	}
+	if !reflect.DeepEqual(result.Repos, want) {
+		t.Errorf("Repos = %+v, want %+v", result.Repos, want)
+	}
+	if len(result.Errors) != 3 {
+		t.Errorf("Expected 3 errors, got %v", result.Errors)
+	}
+	wantDetails := []string{
+		"repo:api A created",
+		"repo:api B failed",
+		"repo:web A skipped",
+		"repo:web B failed",
+	}
+	if got := detailLines(result.Details); !reflect.DeepEqual(got, wantDetails) {
+		t.Errorf("Details = %v, want %v", got, wantDetails)
+	}
+	checkDetails(t, result)
+	// The missing repository must not stop the run before web.
+	if got := fake.countCalls("GET repos/dst/web/actions/variables/A"); got != 1 {
+		t.Errorf("Expected web to be processed after the failing repository, got %d lookup(s)", got)
+	}
+	if v, _ := fake.getVar(repoVarsPath("dst", "web"), "A"); v.Value != "old" {
+		t.Errorf("Expected existing A in web to be skipped, got %q", v.Value)
+	}
+}
+
+func TestMigrateFanOut_AllRepos(t *testing.T) {
+	fake := seedFanOutFake()
+	fake.archived["dst/worker"] = true
+	fake.addRepo("other", "api")
+
+	cfg := fanOutConfig()
+	cfg.AllRepos = true
+	cfg.DryRun = true
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	var repos []string
+	for _, r := range result.Repos {
+		repos = append(repos, r.Repo)
+	}
+	if !reflect.DeepEqual(repos, []string{"api", "web"}) {
+		t.Errorf("Expected archived and foreign repositories to be left out, got %v", repos)
+	}
+	if result.Created != 3 || result.Updated != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "api"), "A"); ok {
+		t.Error("Dry run must not create variables")
+	}
+}
+
+func TestMigrateFanOut_ConflictFail(t *testing.T) {
+	fake := seedFanOutFake()
+
+	cfg := fanOutConfig("api", "web")
+	cfg.OnConflict = types.ConflictFail
+	if _, err := newFakeMigrator(t, cfg, fake).Run(); err == nil {
+		t.Fatal("Expected --on-conflict=fail to reject the existing variable A in web")
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "api"), "A"); ok {
+		t.Error("A failed conflict check must not write anything")
+	}
+}
+
+func TestMigrateFanOut_Verify(t *testing.T) {
+	fake := seedFanOutFake()
+	fake.staleValues[repoVarsPath("dst", "web")+"/B"] = "stale"
+
+	cfg := fanOutConfig("api", "web")
+	cfg.Verify = true
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Verified != 3 || result.Mismatched != 1 {
+		t.Errorf("Expected 3 verified and 1 mismatched, got %+v", result)
+	}
+}
+
+func TestDiffFanOut(t *testing.T) {
+	fake := seedFanOutFake()
+	fake.setVar(repoVarsPath("dst", "api"), types.Variable{Name: "B", Value: "2"})
+
+	diff, err := newFakeMigrator(t, fanOutConfig("api", "web"), fake).Diff()
+	if err != nil {
+		t.Fatalf("Diff() unexpected error: %v", err)
+	}
+	if diff.Count(types.DiffAdd) != 2 || diff.Count(types.DiffUpdate) != 1 || diff.Count(types.DiffUnchanged) != 1 {
+		t.Errorf("Unexpected diff: %+v", diff.Entries)
+	}
+	for _, e := range diff.Entries {
+		if e.Scope != "repo:api" && e.Scope != "repo:web" {
+			t.Errorf("Unexpected scope %q", e.Scope)
+		}
+	}
+}
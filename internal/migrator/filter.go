@@ -0,0 +1,226 @@
+package migrator
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// filterVariables applies the --vars selection, the configured name filters
+// (include/exclude globs, then the optional regular expression), and --since
+// to a list of source variables. Variables that are filtered out are counted
+// in result.Filtered, or result.UnchangedSince for --since, and are never
+// checked against or written to the target.
+func (m *Migrator) filterVariables(vars []types.Variable, result *types.MigrationResult) []types.Variable {
+	if m.requestedVars == nil && len(m.config.Include) == 0 && len(m.config.Exclude) == 0 && m.nameRegex == nil && m.config.Since.IsZero() {
+		return vars
+	}
+
+	unchangedSince := 0
+	kept := make([]types.Variable, 0, len(vars))
+	for _, v := range vars {
+		if m.requestedVars != nil {
+			key := strings.ToUpper(v.Name)
+			if !m.requestedVars[key] {
+				logger.Debug("Variable '%s' not in --vars selection", v.Name)
+				result.Add(&result.Filtered, 1)
+				continue
+			}
+			m.foundVars[key] = true
+		}
+		if !matchesNameFilters(v.Name, m.config.Include, m.config.Exclude, m.nameRegex) {
+			logger.Debug("Variable '%s' filtered out by name filters", v.Name)
+			result.Add(&result.Filtered, 1)
+			continue
+		}
+		if !m.updatedSince(v) {
+			logger.Debug("Variable '%s' not updated since %s", v.Name, m.config.Since.Format(time.RFC3339))
+			unchangedSince++
+			continue
+		}
+		kept = append(kept, v)
+	}
+
+	if filtered := len(vars) - len(kept) - unchangedSince; filtered > 0 {
+		logger.Info("Filtered out %d variable(s); %d remaining", filtered, len(kept))
+	}
+	if unchangedSince > 0 {
+		logger.Info("Left out %d variable(s) not updated since %s (--since)", unchangedSince, m.config.Since.Format(time.RFC3339))
+		result.Add(&result.UnchangedSince, unchangedSince)
+	}
+
+	return kept
+}
+
+// activeFilters lists the options that narrowed down the source variables
+// or environments, so that an empty run can be told apart from an
+// over-filtered one
+func (m *Migrator) activeFilters() []string {
+	var filters []string
+	if len(m.config.Vars) > 0 {
+		filters = append(filters, "--vars "+strings.Join(m.config.Vars, ","))
+	}
+	if len(m.config.Include) > 0 {
+		filters = append(filters, "--include "+strings.Join(m.config.Include, ","))
+	}
+	if len(m.config.Exclude) > 0 {
+		filters = append(filters, "--exclude "+strings.Join(m.config.Exclude, ","))
+	}
+	if m.config.FilterRegex != "" {
+		filters = append(filters, "--filter-regex "+m.config.FilterRegex)
+	}
+	if !m.config.Since.IsZero() {
+		filters = append(filters, "--since "+m.config.Since.Format(time.RFC3339))
+	}
+	if m.config.SkipUnused {
+		filters = append(filters, "--skip-unused")
+	}
+	switch {
+	case m.config.SkipRepoVars && len(m.config.Envs) == 1:
+		filters = append(filters, "--env "+m.config.Envs[0])
+	case m.config.SkipRepoVars:
+		filters = append(filters, "--envs-only")
+	}
+	if len(m.config.Envs) > 1 || (len(m.config.Envs) == 1 && !m.config.SkipRepoVars) {
+		filters = append(filters, "--envs "+strings.Join(m.config.Envs, ","))
+	}
+	if len(m.config.ExcludeEnvs) > 0 {
+		filters = append(filters, "--exclude-envs "+strings.Join(m.config.ExcludeEnvs, ","))
+	}
+	if m.config.SkipEnvs && (m.config.Mode == types.ModeRepoToRepo || m.config.Deep) {
+		filters = append(filters, "--skip-envs")
+	}
+	if len(m.config.ExcludeRepos) > 0 && m.config.Deep {
+		filters = append(filters, "--exclude-repos "+strings.Join(m.config.ExcludeRepos, ","))
+	}
+	return filters
+}
+
+// updatedSince reports whether v was updated at or after --since. Without
+// --since every variable passes, and so does one whose updated_at is missing
+// or cannot be parsed, with a warning.
+func (m *Migrator) updatedSince(v types.Variable) bool {
+	if m.config.Since.IsZero() {
+		return true
+	}
+	updated, err := time.Parse(time.RFC3339, v.UpdatedAt)
+	if err != nil {
+		logger.Warning("Variable '%s' has no usable updated_at (%q); including it despite --since", v.Name, v.UpdatedAt)
+		return true
+	}
+	return !updated.Before(m.config.Since)
+}
+
+// selectEnvironments applies the --envs selection or the --exclude-envs
+// patterns to the source environments. Every requested environment must
+// exist in the source; the ones left out are recorded in result.FilteredEnvs.
+func (m *Migrator) selectEnvironments(envs []types.Environment, result *types.MigrationResult) ([]types.Environment, error) {
+	if len(m.config.ExcludeEnvs) > 0 {
+		kept := make([]types.Environment, 0, len(envs))
+		for _, env := range envs {
+			if matchesAnyGlob(env.Name, m.config.ExcludeEnvs) {
+				logger.Debug("Excluding environment '%s' (--exclude-envs)", env.Name)
+				result.AddFilteredEnv(env.Name)
+				continue
+			}
+			kept = append(kept, env)
+		}
+		return kept, nil
+	}
+
+	requested := newNameSet(m.config.Envs)
+	if requested == nil {
+		return envs, nil
+	}
+
+	kept := make([]types.Environment, 0, len(requested))
+	found := make(map[string]bool, len(requested))
+	for _, env := range envs {
+		key := strings.ToUpper(env.Name)
+		if !requested[key] {
+			logger.Debug("Skipping environment '%s' (not in --envs)", env.Name)
+			result.AddFilteredEnv(env.Name)
+			continue
+		}
+		found[key] = true
+		kept = append(kept, env)
+	}
+
+	var missing []string
+	for _, name := range m.config.Envs {
+		key := strings.ToUpper(strings.TrimSpace(name))
+		if key != "" && !found[key] {
+			found[key] = true
+			missing = append(missing, strings.TrimSpace(name))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("environment(s) not found in source repository %s/%s: %s",
+			m.config.SourceOwner, m.config.SourceRepo, strings.Join(missing, ", "))
+	}
+
+	return kept, nil
+}
+
+// newNameSet builds an upper-cased lookup set from a list of variable names,
+// ignoring blank entries. It returns nil when no names are given.
+func newNameSet(names []string) map[string]bool {
+	var set map[string]bool
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if set == nil {
+			set = make(map[string]bool, len(names))
+		}
+		set[strings.ToUpper(name)] = true
+	}
+	return set
+}
+
+// missingVars returns the --vars names, upper-cased and sorted, that were
+// not seen in any source variable list during the migration.
+func (m *Migrator) missingVars() []string {
+	var missing []string
+	for name := range m.requestedVars {
+		if !m.foundVars[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// matchesNameFilters reports whether a variable name passes the include and
+// exclude globs and, when set, the regular expression. Glob matching is
+// case-insensitive because GitHub treats variable names case-insensitively;
+// the regex is matched as written (use (?i) for case-insensitivity). An empty
+// include list matches every name, and an exclude match always wins over an
+// include match.
+func matchesNameFilters(name string, include, exclude []string, re *regexp.Regexp) bool {
+	if matchesAnyGlob(name, exclude) {
+		return false
+	}
+	if len(include) > 0 && !matchesAnyGlob(name, include) {
+		return false
+	}
+	return re == nil || re.MatchString(name)
+}
+
+// matchesAnyGlob reports whether name matches at least one of the patterns.
+// Malformed patterns never match; they are rejected during config validation.
+func matchesAnyGlob(name string, patterns []string) bool {
+	upper := strings.ToUpper(name)
+	for _, p := range patterns {
+		if ok, err := path.Match(strings.ToUpper(p), upper); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,355 @@
+package migrator
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// TestMatchesNameFilters verifies include/exclude glob semantics
+func TestMatchesNameFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		varName string
+		include []string
+		exclude []string
+		want    bool
+	}{
+		{"no filters", "ANY_VAR", nil, nil, true},
+		{"include match", "DEPLOY_KEY", []string{"DEPLOY_*"}, nil, true},
+		{"include no match", "BUILD_KEY", []string{"DEPLOY_*"}, nil, false},
+		{"exclude match", "APP_LEGACY", nil, []string{"*_LEGACY"}, false},
+		{"exclude no match", "APP_URL", nil, []string{"*_LEGACY"}, true},
+		{"exclude wins over include", "DEPLOY_LEGACY", []string{"DEPLOY_*"}, []string{"*_LEGACY"}, false},
+		{"overlapping includes", "DEPLOY_URL", []string{"DEPLOY_*", "*_URL"}, nil, true},
+		{"case insensitive include", "deploy_key", []string{"DEPLOY_*"}, nil, true},
+		{"case insensitive exclude", "APP_LEGACY", nil, []string{"*_legacy"}, false},
+		{"single char wildcard", "ENV_A", []string{"ENV_?"}, nil, true},
+		{"character class", "ENV_B", []string{"ENV_[AB]"}, nil, true},
+		{"malformed pattern never matches", "ENV_A", []string{"ENV_["}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesNameFilters(tt.varName, tt.include, tt.exclude, nil); got != tt.want {
+				t.Errorf("matchesNameFilters(%q, %v, %v) = %v, want %v", tt.varName, tt.include, tt.exclude, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMatchesNameFilters_Regex verifies regex filtering and its interaction
+// with the glob filters
+func TestMatchesNameFilters_Regex(t *testing.T) {
+	tests := []struct {
+		name    string
+		varName string
+		include []string
+		exclude []string
+		expr    string
+		want    bool
+	}{
+		{"anchored match", "APP_EU_API_URL", nil, nil, `^APP_(EU|US)_.*_URL$`, true},
+		{"anchored no match on prefix", "MY_APP_EU_API_URL", nil, nil, `^APP_(EU|US)_.*_URL$`, false},
+		{"anchored no match on suffix", "APP_US_API_URL_OLD", nil, nil, `^APP_(EU|US)_.*_URL$`, false},
+		{"unanchored matches substring", "MY_APP_EU_API_URL_OLD", nil, nil, `APP_(EU|US)_.*_URL`, true},
+		{"regex is case-sensitive", "app_eu_api_url", nil, nil, `^APP_EU_.*_URL$`, false},
+		{"regex case-insensitive flag", "app_eu_api_url", nil, nil, `(?i)^APP_EU_.*_URL$`, true},
+		{"glob exclude wins before regex", "APP_EU_LEGACY_URL", nil, []string{"*LEGACY*"}, `^APP_`, false},
+		{"glob include must also match", "APP_EU_API_URL", []string{"DEPLOY_*"}, nil, `^APP_`, false},
+		{"glob and regex both match", "APP_EU_API_URL", []string{"APP_*"}, nil, `_URL$`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := regexp.MustCompile(tt.expr)
+			if got := matchesNameFilters(tt.varName, tt.include, tt.exclude, re); got != tt.want {
+				t.Errorf("matchesNameFilters(%q, %v, %v, %q) = %v, want %v", tt.varName, tt.include, tt.exclude, tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFilterVariables_CountsFiltered verifies that filtered variables are
+// removed from the list and counted in the result
+func TestFilterVariables_CountsFiltered(t *testing.T) {
+	m := &Migrator{config: &types.MigrationConfig{
+		Include: []string{"DEPLOY_*"},
+		Exclude: []string{"*_LEGACY"},
+	}}
+	vars := []types.Variable{
+		{Name: "DEPLOY_URL"},
+		{Name: "DEPLOY_LEGACY"},
+		{Name: "BUILD_FLAGS"},
+		{Name: "deploy_region"},
+	}
+	result := &types.MigrationResult{}
+
+	kept := m.filterVariables(vars, result)
+
+	if len(kept) != 2 {
+		t.Fatalf("Expected 2 variables to remain, got %d: %v", len(kept), kept)
+	}
+	if kept[0].Name != "DEPLOY_URL" || kept[1].Name != "deploy_region" {
+		t.Errorf("Unexpected variables kept: %v", kept)
+	}
+	if result.Filtered != 2 {
+		t.Errorf("Expected Filtered 2, got %d", result.Filtered)
+	}
+}
+
+// TestFilterVariables_Since verifies the --since cutoff at its boundary and
+// for variables without a usable updated_at
+func TestFilterVariables_Since(t *testing.T) {
+	since := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	m := &Migrator{config: &types.MigrationConfig{Since: since, Exclude: []string{"*_LEGACY"}}}
+	vars := []types.Variable{
+		{Name: "AT_CUTOFF", UpdatedAt: "2026-05-01T00:00:00Z"},
+		{Name: "JUST_BEFORE", UpdatedAt: "2026-04-30T23:59:59Z"},
+		{Name: "AFTER", UpdatedAt: "2026-05-03T08:00:00Z"},
+		{Name: "OFFSET_AFTER", UpdatedAt: "2026-05-01T03:00:00+02:00"},
+		{Name: "OFFSET_BEFORE", UpdatedAt: "2026-05-01T01:00:00+02:00"},
+		{Name: "NO_TIMESTAMP"},
+		{Name: "BAD_TIMESTAMP", UpdatedAt: "yesterday"},
+		{Name: "OLD_LEGACY", UpdatedAt: "2026-05-03T08:00:00Z"},
+	}
+	result := &types.MigrationResult{}
+
+	kept := m.filterVariables(vars, result)
+
+	var names []string
+	for _, v := range kept {
+		names = append(names, v.Name)
+	}
+	want := []string{"AT_CUTOFF", "AFTER", "OFFSET_AFTER", "NO_TIMESTAMP", "BAD_TIMESTAMP"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Kept %v, want %v", names, want)
+	}
+	if result.UnchangedSince != 2 || result.Filtered != 1 {
+		t.Errorf("UnchangedSince=%d Filtered=%d, want 2 and 1", result.UnchangedSince, result.Filtered)
+	}
+}
+
+// TestFilterVariables_NoMatches verifies that an include pattern matching
+// nothing filters out every variable
+func TestFilterVariables_NoMatches(t *testing.T) {
+	m := &Migrator{config: &types.MigrationConfig{
+		Include: []string{"NOTHING_*"},
+	}}
+	vars := []types.Variable{{Name: "A"}, {Name: "B"}}
+	result := &types.MigrationResult{}
+
+	kept := m.filterVariables(vars, result)
+
+	if len(kept) != 0 {
+		t.Errorf("Expected no variables to remain, got %v", kept)
+	}
+	if result.Filtered != 2 {
+		t.Errorf("Expected Filtered 2, got %d", result.Filtered)
+	}
+}
+
+// TestFilterVariables_NoFilters verifies the list is returned untouched when
+// no filters are configured
+func TestFilterVariables_NoFilters(t *testing.T) {
+	m := &Migrator{config: &types.MigrationConfig{}}
+	vars := []types.Variable{{Name: "A"}, {Name: "B"}}
+	result := &types.MigrationResult{}
+
+	kept := m.filterVariables(vars, result)
+
+	if len(kept) != 2 || result.Filtered != 0 {
+		t.Errorf("Expected all variables kept and none filtered, got %v (filtered %d)", kept, result.Filtered)
+	}
+}
+
+// TestFilterVariables_VarsSelection verifies that --vars keeps only the
+// requested names, matched case-insensitively
+func TestFilterVariables_VarsSelection(t *testing.T) {
+	m := &Migrator{
+		config:        &types.MigrationConfig{},
+		requestedVars: newNameSet([]string{"database_url", "REGION"}),
+		foundVars:     map[string]bool{},
+	}
+	vars := []types.Variable{
+		{Name: "DATABASE_URL"},
+		{Name: "REGION"},
+		{Name: "OTHER"},
+	}
+	result := &types.MigrationResult{}
+
+	kept := m.filterVariables(vars, result)
+
+	if len(kept) != 2 || kept[0].Name != "DATABASE_URL" || kept[1].Name != "REGION" {
+		t.Errorf("Unexpected variables kept: %v", kept)
+	}
+	if result.Filtered != 1 {
+		t.Errorf("Expected Filtered 1, got %d", result.Filtered)
+	}
+	if missing := m.missingVars(); len(missing) != 0 {
+		t.Errorf("Expected no missing variables, got %v", missing)
+	}
+}
+
+// TestMissingVars_ReportsUnseenNames verifies that requested variables never
+// seen across any source list are reported, sorted and upper-cased
+func TestMissingVars_ReportsUnseenNames(t *testing.T) {
+	m := &Migrator{
+		config:        &types.MigrationConfig{},
+		requestedVars: newNameSet([]string{"zeta", "DATABASE_URL", "alpha", "Region"}),
+		foundVars:     map[string]bool{},
+	}
+	result := &types.MigrationResult{}
+
+	// Simulate repository-level and environment-level lists
+	m.filterVariables([]types.Variable{{Name: "DATABASE_URL"}}, result)
+	m.filterVariables([]types.Variable{{Name: "region"}}, result)
+
+	missing := m.missingVars()
+	want := []string{"ALPHA", "ZETA"}
+	if len(missing) != len(want) {
+		t.Fatalf("Expected missing %v, got %v", want, missing)
+	}
+	for i := range want {
+		if missing[i] != want[i] {
+			t.Errorf("Expected missing %v, got %v", want, missing)
+		}
+	}
+}
+
+// TestNewNameSet verifies blank handling and case folding
+func TestNewNameSet(t *testing.T) {
+	if set := newNameSet(nil); set != nil {
+		t.Errorf("Expected nil set for no names, got %v", set)
+	}
+	if set := newNameSet([]string{" ", ""}); set != nil {
+		t.Errorf("Expected nil set for blank names, got %v", set)
+	}
+
+	set := newNameSet([]string{"Foo", "FOO", " bar "})
+	if len(set) != 2 || !set["FOO"] || !set["BAR"] {
+		t.Errorf("Unexpected set: %v", set)
+	}
+}
+
+// TestFailIfEmpty verifies that a run is marked empty only when no source
+// variable is left in any scope, and only with FailIfEmpty
+func TestFailIfEmpty(t *testing.T) {
+	tests := []struct {
+		name      string
+		seed      func(fake *fakeGitHub)
+		modify    func(cfg *types.MigrationConfig)
+		wantEmpty bool
+	}{
+		{
+			name:      "empty source",
+			seed:      func(fake *fakeGitHub) {},
+			wantEmpty: true,
+		},
+		{
+			name: "over-filtered source",
+			seed: func(fake *fakeGitHub) {
+				fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "REGION", Value: "eu"})
+				fake.addEnv("src", "app", "production")
+				fake.setVar(envVarsPath("src", "app", "production"), types.Variable{Name: "URL", Value: "https://prod"})
+			},
+			modify:    func(cfg *types.MigrationConfig) { cfg.Include = []string{"DEPLOY_*"} },
+			wantEmpty: true,
+		},
+		{
+			name: "only environment variables",
+			seed: func(fake *fakeGitHub) {
+				fake.addEnv("src", "app", "production")
+				fake.setVar(envVarsPath("src", "app", "production"), types.Variable{Name: "URL", Value: "https://prod"})
+			},
+		},
+		{
+			name:   "empty source without the flag",
+			seed:   func(fake *fakeGitHub) {},
+			modify: func(cfg *types.MigrationConfig) { cfg.FailIfEmpty = false },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeGitHub()
+			tt.seed(fake)
+			cfg := repoToRepoConfig()
+			cfg.FailIfEmpty = true
+			if tt.modify != nil {
+				tt.modify(cfg)
+			}
+
+			var result *types.MigrationResult
+			captureStdout(t, func() {
+				var err error
+				result, err = newFakeMigrator(t, cfg, fake).Run()
+				if err != nil {
+					t.Errorf("Run() unexpected error: %v", err)
+				}
+			})
+			if result == nil {
+				t.Fatal("Run() returned no result")
+			}
+			if result.Empty != tt.wantEmpty {
+				t.Errorf("Empty = %v, want %v (selected %d)", result.Empty, tt.wantEmpty, result.Selected)
+			}
+		})
+	}
+}
+
+// TestActiveFilters verifies the filters listed when --fail-if-empty finds
+// nothing to migrate
+func TestActiveFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *types.MigrationConfig
+		want []string
+	}{
+		{name: "no filters", cfg: &types.MigrationConfig{Mode: types.ModeRepoToRepo}},
+		{
+			name: "name filters",
+			cfg: &types.MigrationConfig{
+				Mode:        types.ModeOrgToOrg,
+				Vars:        []string{"A", "B"},
+				Include:     []string{"DEPLOY_*"},
+				Exclude:     []string{"*_OLD"},
+				FilterRegex: "^X",
+				Since:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+			want: []string{"--vars A,B", "--include DEPLOY_*", "--exclude *_OLD", "--filter-regex ^X", "--since 2026-01-02T03:04:05Z"},
+		},
+		{
+			name: "single environment",
+			cfg:  &types.MigrationConfig{Mode: types.ModeRepoToRepo, Envs: []string{"production"}, SkipRepoVars: true, SkipUnused: true},
+			want: []string{"--skip-unused", "--env production"},
+		},
+		{
+			name: "environment selection",
+			cfg:  &types.MigrationConfig{Mode: types.ModeRepoToRepo, Envs: []string{"production", "staging"}, ExcludeEnvs: []string{"pr-*"}},
+			want: []string{"--envs production,staging", "--exclude-envs pr-*"},
+		},
+		{
+			name: "environments only",
+			cfg:  &types.MigrationConfig{Mode: types.ModeRepoToRepo, Envs: []string{"production", "staging"}, SkipRepoVars: true},
+			want: []string{"--envs-only", "--envs production,staging"},
+		},
+		{
+			name: "deep",
+			cfg:  &types.MigrationConfig{Mode: types.ModeOrgToOrg, Deep: true, SkipEnvs: true, ExcludeRepos: []string{"sandbox-*"}},
+			want: []string{"--skip-envs", "--exclude-repos sandbox-*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{config: tt.cfg}
+			if got := m.activeFilters(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("activeFilters() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
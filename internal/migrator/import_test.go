@@ -0,0 +1,308 @@
+package migrator
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/dump"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// importConfig returns the configuration of an import of d into repository
+// acme/app
+func importConfig(t *testing.T, d *dump.Dump) *types.MigrationConfig {
+	t.Helper()
+	scopes, err := d.Desired("acme", "app", "")
+	if err != nil {
+		t.Fatalf("Desired() unexpected error: %v", err)
+	}
+	return &types.MigrationConfig{Mode: types.ModeImport, Manifest: "app.json", Desired: scopes}
+}
+
+// importDump is a repository dump with one environment
+func importDump() *dump.Dump {
+	return &dump.Dump{
+		Version: dump.Version,
+		Org:     "acme",
+		Repo:    "app",
+		Variables: []dump.Variable{
+			{Name: "LOG_LEVEL", Value: "info"},
+			{Name: "TIMEOUT", Value: "30"},
+		},
+		Environments: []dump.Environment{
+			{Name: "production", Variables: []dump.Variable{{Name: "URL", Value: "https://app.example.com"}}},
+		},
+	}
+}
+
+// TestImport_RoundTrip exports a repository with its environments, imports
+// the file into an empty target, and expects the same variables there
+func TestImport_RoundTrip(t *testing.T) {
+	source := newFakeGitHub()
+	source.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LOG_LEVEL", Value: "info"})
+	source.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "SCRIPT", Value: "echo \"hi\"\nexit 0"})
+	source.addEnv("acme", "app", "production")
+	source.setVar(envVarsPath("acme", "app", "production"), types.Variable{Name: "URL", Value: "https://app.example.com"})
+
+	c, err := client.NewWithTransport("test-token", "github.com", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ExportDump(c, "acme", "app", "", true)
+	if err != nil {
+		t.Fatalf("ExportDump() unexpected error: %v", err)
+	}
+
+	for _, format := range []string{dump.FormatJSON, dump.FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app."+format)
+			if err := dump.Save(path, exported, format); err != nil {
+				t.Fatalf("Save() unexpected error: %v", err)
+			}
+			loaded, err := dump.Load(path, format)
+			if err != nil {
+				t.Fatalf("Load() of an exported file: %v", err)
+			}
+
+			target := newFakeGitHub()
+			result := runManifest(t, importConfig(t, loaded), target)
+			if result.Created != 3 || result.HasErrors() {
+				t.Fatalf("Expected 3 creates, got %+v (errors: %v)", result, result.Errors)
+			}
+			for _, path := range []string{repoVarsPath("acme", "app"), envVarsPath("acme", "app", "production")} {
+				for _, want := range source.vars[path] {
+					if got, ok := target.getVar(path, want.Name); !ok || got.Value != want.Value {
+						t.Errorf("%s %s = %+v, want value %q", path, want.Name, got, want.Value)
+					}
+				}
+			}
+			if !target.envs["acme/app"]["production"] {
+				t.Error("The missing environment should have been created")
+			}
+		})
+	}
+}
+
+func TestImport_PartialFailure(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.failWrites["TIMEOUT"] = true
+
+	result := runManifest(t, importConfig(t, importDump()), fake)
+	if result.Created != 2 || len(result.Errors) != 1 {
+		t.Fatalf("Expected 2 creates and 1 error, got %+v (errors: %v)", result, result.Errors)
+	}
+	failed := 0
+	for _, d := range result.Details {
+		if d.Action == types.ActionFailed {
+			failed++
+			if d.Scope != "acme/app" || d.Name != "TIMEOUT" || !strings.Contains(d.Reason, "injected failure") {
+				t.Errorf("Unexpected failure detail: %+v", d)
+			}
+		}
+	}
+	if failed != 1 {
+		t.Errorf("Expected 1 failed variable, got %d", failed)
+	}
+	if _, ok := fake.getVar(envVarsPath("acme", "app", "production"), "URL"); !ok {
+		t.Error("The environment variable after the failure should still be imported")
+	}
+}
+
+func TestImport_DryRun(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LOG_LEVEL", Value: "debug"})
+
+	cfg := importConfig(t, importDump())
+	cfg.DryRun = true
+	result := runManifest(t, cfg, fake)
+
+	if result.Created != 2 || result.Updated != 1 {
+		t.Errorf("Unexpected dry-run result: %+v", result)
+	}
+	if writes := writeCount(fake); writes != 0 {
+		t.Errorf("Dry run made %d write(s)", writes)
+	}
+	if fake.envs["acme/app"]["production"] {
+		t.Error("Dry run must not create environments")
+	}
+}
+
+func TestImport_Filters(t *testing.T) {
+	fake := newFakeGitHub()
+
+	cfg := importConfig(t, importDump())
+	cfg.Include = []string{"LOG_*", "URL"}
+	cfg.Exclude = []string{"URL"}
+	result := runManifest(t, cfg, fake)
+
+	if result.Created != 1 || result.Filtered != 2 || result.Selected != 1 {
+		t.Errorf("Expected only LOG_LEVEL to be imported, got %+v", result)
+	}
+	if _, ok := fake.getVar(repoVarsPath("acme", "app"), "LOG_LEVEL"); !ok {
+		t.Error("LOG_LEVEL should have been imported")
+	}
+	if fake.envs["acme/app"]["production"] {
+		t.Error("An environment with every variable filtered out must not be created")
+	}
+}
+
+// importTarget is a target where LOG_LEVEL differs from importDump, TIMEOUT
+// matches it, and EXTRA and LEGACY_TOKEN are not in it
+func importTarget() *fakeGitHub {
+	fake := newFakeGitHub()
+	for _, v := range []types.Variable{
+		{Name: "LOG_LEVEL", Value: "debug"},
+		{Name: "TIMEOUT", Value: "30"},
+		{Name: "EXTRA", Value: "1"},
+		{Name: "LEGACY_TOKEN", Value: "x"},
+	} {
+		fake.setVar(repoVarsPath("acme", "app"), v)
+	}
+	return fake
+}
+
+func TestImport_OnConflict(t *testing.T) {
+	tests := []struct {
+		strategy  types.ConflictStrategy
+		wantErr   string
+		wantLevel string
+		created   int
+		updated   int
+		skipped   int
+	}{
+		{strategy: types.ConflictOverwrite, wantLevel: "info", created: 1, updated: 1},
+		{strategy: types.ConflictSkip, wantLevel: "debug", created: 1, skipped: 1},
+		{strategy: types.ConflictFail, wantLevel: "debug", wantErr: "2 variable(s) already exist in target (--on-conflict=fail)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			fake := importTarget()
+			cfg := importConfig(t, importDump())
+			cfg.OnConflict = tt.strategy
+
+			var result *types.MigrationResult
+			var err error
+			captureStdout(t, func() { result, err = newFakeMigrator(t, cfg, fake).Run() })
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+				if writes := writeCount(fake); writes != 0 {
+					t.Errorf("--on-conflict fail made %d write(s)", writes)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Run() unexpected error: %v", err)
+				}
+				if result.Created != tt.created || result.Updated != tt.updated || result.Skipped != tt.skipped || result.Unchanged != 1 {
+					t.Errorf("Unexpected result: %+v", result)
+				}
+			}
+			if got, _ := fake.getVar(repoVarsPath("acme", "app"), "LOG_LEVEL"); got.Value != tt.wantLevel {
+				t.Errorf("LOG_LEVEL = %q, want %q", got.Value, tt.wantLevel)
+			}
+			if _, ok := fake.getVar(repoVarsPath("acme", "app"), "EXTRA"); !ok {
+				t.Error("Variables absent from the file must be kept without --prune")
+			}
+		})
+	}
+}
+
+// TestImport_Prune checks that --prune deletes the variables absent from
+// the file, except those the name filters leave out
+func TestImport_Prune(t *testing.T) {
+	fake := importTarget()
+	cfg := importConfig(t, importDump())
+	cfg.Prune = true
+	cfg.Yes = true
+	cfg.Exclude = []string{"LEGACY_*"}
+	result := runManifest(t, cfg, fake)
+
+	if result.Deleted != 1 || result.Created != 1 || result.Updated != 1 || result.HasErrors() {
+		t.Fatalf("Unexpected result: %+v (errors: %v)", result, result.Errors)
+	}
+	if _, ok := fake.getVar(repoVarsPath("acme", "app"), "EXTRA"); ok {
+		t.Error("EXTRA should have been pruned")
+	}
+	if _, ok := fake.getVar(repoVarsPath("acme", "app"), "LEGACY_TOKEN"); !ok {
+		t.Error("A variable excluded by the name filters must not be pruned")
+	}
+}
+
+func TestImport_PruneDryRun(t *testing.T) {
+	fake := importTarget()
+	cfg := importConfig(t, importDump())
+	cfg.Prune = true
+	cfg.DryRun = true
+
+	var result *types.MigrationResult
+	out := captureStdout(t, func() {
+		var err error
+		if result, err = newFakeMigrator(t, cfg, fake).Run(); err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+
+	if result.Deleted != 2 || result.Created != 1 || result.Updated != 1 {
+		t.Errorf("Unexpected dry-run result: %+v", result)
+	}
+	for _, want := range []string{
+		"[DRY-RUN] Would update variable: LOG_LEVEL (acme/app)",
+		"[DRY-RUN] Would delete variable: EXTRA (acme/app, --prune)",
+		"[DRY-RUN] Would delete variable: LEGACY_TOKEN (acme/app, --prune)",
+		"Deleted: 2 (not in the file; --prune)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, out)
+		}
+	}
+	if writes := writeCount(fake); writes != 0 {
+		t.Errorf("Dry run made %d write(s)", writes)
+	}
+}
+
+func TestImport_PruneConfirmation(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantDeleted int
+	}{
+		{name: "scope typed", input: "acme/app\n", wantDeleted: 2},
+		{name: "other answer", input: "y\n", wantDeleted: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := importTarget()
+			cfg := importConfig(t, importDump())
+			cfg.Prune = true
+
+			var prompt bytes.Buffer
+			m := newFakeMigrator(t, cfg, fake)
+			m.input = strings.NewReader(tt.input)
+			m.output = &prompt
+			var result *types.MigrationResult
+			captureStdout(t, func() {
+				var err error
+				if result, err = m.Run(); err != nil {
+					t.Errorf("Run() unexpected error: %v", err)
+				}
+			})
+
+			if !strings.Contains(prompt.String(), "Delete 2 variable(s) from acme/app. Type acme/app to confirm") {
+				t.Errorf("Unexpected prompt %q", prompt.String())
+			}
+			if result.Deleted != tt.wantDeleted || result.Created != 1 || result.Updated != 1 {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+			if _, ok := fake.getVar(repoVarsPath("acme", "app"), "EXTRA"); ok != (tt.wantDeleted == 0) {
+				t.Errorf("EXTRA kept = %v, want %v", ok, tt.wantDeleted == 0)
+			}
+		})
+	}
+}
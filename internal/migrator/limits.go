@@ -0,0 +1,65 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// GitHub's documented limits for Actions variables: the size of one value
+// and the number of variables per organization, repository, and environment.
+const (
+	maxVariableValueSize = 48 * 1024
+	maxOrgVariables      = 1000
+	maxRepoVariables     = 500
+	maxEnvVariables      = 100
+)
+
+// checkLimits is the preflight for a scope. Before anything is written to
+// it, every target name is validated and every target value is checked
+// against the size limit, and all violations are returned as one error.
+// Variables with a reserved name (the GITHUB_ prefix or a leading digit) are
+// left out with a warning and counted as skipped, unless StrictNames makes
+// them violations too; the remaining variables are returned. It also warns
+// when more variables than maxVars would be written, since GitHub rejects
+// the writes beyond the limit. It does nothing when SkipLimitChecks is set.
+func (m *Migrator) checkLimits(scope string, vars []types.Variable, maxVars int, result *types.MigrationResult) ([]types.Variable, error) {
+	if m.config.SkipLimitChecks {
+		return vars, nil
+	}
+
+	var violations []string
+	kept := make([]types.Variable, 0, len(vars))
+	for _, v := range vars {
+		// targetVariable only validates renamed variables, so the name is
+		// checked here as well
+		target, _ := m.targetVariable(v)
+		label := nameLabel(v.Name, target.Name)
+		if reason := reservedNameReason(target.Name); reason != "" && !m.config.StrictNames {
+			logger.Warning("Variable '%s' skipped: %s (--strict-names to fail instead)", label, reason)
+			recordSkipped(scope, v.Name, reason, result)
+			result.Add(&result.ReservedNames, 1)
+			continue
+		}
+		kept = append(kept, v)
+
+		if err := validateVariableName(target.Name); err != nil {
+			violations = append(violations, fmt.Sprintf("%s: %v", label, err))
+		}
+		if size := len(target.Value); size > maxVariableValueSize {
+			violations = append(violations, fmt.Sprintf("%s: value is %d bytes, over the %d-byte limit", label, size, maxVariableValueSize))
+		}
+	}
+	if len(kept) > maxVars {
+		logger.Warning("%d variable(s) would be written to %s, more than the %d GitHub allows; writes beyond the limit will fail",
+			len(kept), scope, maxVars)
+	}
+	if len(violations) == 0 {
+		return kept, nil
+	}
+
+	return kept, fmt.Errorf("%d problem(s) with variables for %s would be rejected by GitHub (--skip-limit-checks to migrate anyway):\n  %s",
+		len(violations), scope, strings.Join(violations, "\n  "))
+}
@@ -0,0 +1,227 @@
+package migrator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func TestCheckLimits(t *testing.T) {
+	oversized := strings.Repeat("x", maxVariableValueSize+1)
+
+	tests := []struct {
+		name         string
+		cfg          *types.MigrationConfig
+		vars         []types.Variable
+		wantProblems []string
+	}{
+		{
+			name: "within limits",
+			cfg:  &types.MigrationConfig{},
+			vars: []types.Variable{{Name: "REGION", Value: "eu"}, {Name: "MAX", Value: strings.Repeat("x", maxVariableValueSize)}},
+		},
+		{
+			name:         "oversized value",
+			cfg:          &types.MigrationConfig{},
+			vars:         []types.Variable{{Name: "CERT", Value: oversized}},
+			wantProblems: []string{"CERT: value is 49153 bytes, over the 49152-byte limit"},
+		},
+		{
+			name:         "prefix starting with a digit",
+			cfg:          &types.MigrationConfig{TargetPrefix: "1_", StrictNames: true},
+			vars:         []types.Variable{{Name: "REGION", Value: "eu"}},
+			wantProblems: []string{"REGION → 1_REGION: name '1_REGION' must not start with a number"},
+		},
+		{
+			name:         "reserved prefix",
+			cfg:          &types.MigrationConfig{TargetPrefix: "GITHUB_", StrictNames: true},
+			vars:         []types.Variable{{Name: "REGION", Value: "eu"}},
+			wantProblems: []string{"REGION → GITHUB_REGION: name 'GITHUB_REGION' must not start with the reserved GITHUB_ prefix"},
+		},
+		{
+			name: "all violations together",
+			cfg:  &types.MigrationConfig{TargetSuffix: "-V2"},
+			vars: []types.Variable{{Name: "A", Value: "ok"}, {Name: "B", Value: oversized}},
+			wantProblems: []string{
+				"A → A-V2: name 'A-V2' contains invalid character '-'",
+				"B → B-V2: name 'B-V2' contains invalid character '-'",
+				"B → B-V2: value is 49153 bytes",
+			},
+		},
+		{
+			name: "checks skipped",
+			cfg:  &types.MigrationConfig{TargetPrefix: "GITHUB_", SkipLimitChecks: true},
+			vars: []types.Variable{{Name: "CERT", Value: oversized}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{config: tt.cfg}
+			_, err := m.checkLimits(scopeRepo, tt.vars, maxRepoVariables, &types.MigrationResult{})
+			if len(tt.wantProblems) == 0 {
+				if err != nil {
+					t.Fatalf("checkLimits() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("checkLimits() expected an error")
+			}
+			msg := err.Error()
+			if !strings.Contains(msg, "for repository") || !strings.Contains(msg, "--skip-limit-checks") {
+				t.Errorf("Error should name the scope and the flag, got: %s", msg)
+			}
+			for _, want := range tt.wantProblems {
+				if !strings.Contains(msg, want) {
+					t.Errorf("Error should contain %q, got: %s", want, msg)
+				}
+			}
+		})
+	}
+}
+
+// TestCheckLimits_AggregateMessage verifies the layout of the error: a count
+// and one indented line per violation
+func TestCheckLimits_AggregateMessage(t *testing.T) {
+	m := &Migrator{config: &types.MigrationConfig{TargetPrefix: "9", StrictNames: true}}
+	_, err := m.checkLimits(envScope("prod"), []types.Variable{{Name: "A"}, {Name: "B"}}, maxEnvVariables, &types.MigrationResult{})
+	if err == nil {
+		t.Fatal("checkLimits() expected an error")
+	}
+
+	want := "2 problem(s) with variables for env:prod would be rejected by GitHub (--skip-limit-checks to migrate anyway):\n" +
+		"  A → 9A: name '9A' must not start with a number\n" +
+		"  B → 9B: name '9B' must not start with a number"
+	if err.Error() != want {
+		t.Errorf("Error =\n%s\nwant\n%s", err, want)
+	}
+}
+
+// TestCheckLimits_ReservedNames verifies that reserved names are skipped and
+// counted by default and fail the check with StrictNames
+func TestCheckLimits_ReservedNames(t *testing.T) {
+	vars := []types.Variable{
+		{Name: "REGION"},
+		{Name: "GITHUB_TOKEN_URL"},
+		{Name: "github_api"},
+		{Name: "2FA_MODE"},
+		{Name: "API_V2"},
+	}
+
+	t.Run("skipped by default", func(t *testing.T) {
+		m := &Migrator{config: &types.MigrationConfig{}}
+		result := &types.MigrationResult{}
+		kept, err := m.checkLimits(scopeRepo, vars, maxRepoVariables, result)
+		if err != nil {
+			t.Fatalf("checkLimits() unexpected error: %v", err)
+		}
+
+		var names []string
+		for _, v := range kept {
+			names = append(names, v.Name)
+		}
+		if want := []string{"REGION", "API_V2"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("Kept %v, want %v", names, want)
+		}
+		if result.ReservedNames != 3 || result.Skipped != 3 {
+			t.Errorf("ReservedNames=%d Skipped=%d, want 3 and 3", result.ReservedNames, result.Skipped)
+		}
+		want := []string{
+			"repository GITHUB_TOKEN_URL skipped",
+			"repository github_api skipped",
+			"repository 2FA_MODE skipped",
+		}
+		if got := detailLines(result.Details); !reflect.DeepEqual(got, want) {
+			t.Errorf("Details = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		m := &Migrator{config: &types.MigrationConfig{StrictNames: true}}
+		result := &types.MigrationResult{}
+		_, err := m.checkLimits(scopeRepo, vars, maxRepoVariables, result)
+		if err == nil {
+			t.Fatal("checkLimits() with StrictNames expected an error")
+		}
+		for _, name := range []string{"GITHUB_TOKEN_URL", "github_api", "2FA_MODE"} {
+			if !strings.Contains(err.Error(), name+": name") {
+				t.Errorf("Error should list %s, got: %v", name, err)
+			}
+		}
+		if !strings.HasPrefix(err.Error(), "3 problem(s)") {
+			t.Errorf("Expected all three names in one error, got: %v", err)
+		}
+		if result.ReservedNames != 0 || result.Skipped != 0 {
+			t.Errorf("Nothing may be skipped with StrictNames, got %+v", result)
+		}
+	})
+}
+
+// TestMigrateRepoToRepo_ReservedNames verifies that a reserved source name
+// is left out of the migration while the others are written
+func TestMigrateRepoToRepo_ReservedNames(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "REGION", Value: "eu"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "GITHUB_ENTERPRISE_URL", Value: "https://ghes"})
+
+	cfg := repoToRepoConfig()
+	cfg.SkipEnvs = true
+	var result *types.MigrationResult
+	out := captureStdout(t, func() {
+		var err error
+		result, err = newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+
+	if result == nil || result.Created != 1 || result.ReservedNames != 1 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "app"), "GITHUB_ENTERPRISE_URL"); ok {
+		t.Error("A reserved name must not be written")
+	}
+	if !strings.Contains(out, "Reserved names: 1 (included in Skipped)") {
+		t.Errorf("Expected the summary to count reserved names, got:\n%s", out)
+	}
+}
+
+// TestMigrateRepoToRepo_LimitChecks verifies that a violation stops the
+// migration before any variable is written, unless the checks are skipped
+func TestMigrateRepoToRepo_LimitChecks(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		fake := newFakeGitHub()
+		fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "REGION", Value: "eu"})
+		fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "CERT", Value: strings.Repeat("x", maxVariableValueSize+1)})
+
+		cfg := repoToRepoConfig()
+		cfg.SkipEnvs = true
+		cfg.SkipLimitChecks = skip
+		result, err := newFakeMigrator(t, cfg, fake).Run()
+
+		writes := 0
+		for _, call := range fake.calls {
+			if strings.HasPrefix(call, "POST ") || strings.HasPrefix(call, "PATCH ") {
+				writes++
+			}
+		}
+		if !skip {
+			if err == nil || !strings.Contains(err.Error(), "CERT: value is") {
+				t.Errorf("Expected a limit error for CERT, got: %v", err)
+			}
+			if writes != 0 {
+				t.Errorf("Expected no writes after a failed preflight, got %d", writes)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Run() with --skip-limit-checks unexpected error: %v", err)
+		}
+		if result.Created != 2 {
+			t.Errorf("Expected both variables to be written, got %+v", result)
+		}
+	}
+}
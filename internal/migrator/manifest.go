@@ -0,0 +1,393 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// applyManifest converges every scope declared by the manifest on its
+// declared variables: missing ones are created, differing ones are updated
+// under the conflict strategy, and with Prune, undeclared ones are deleted.
+// Applying the same manifest again changes nothing.
+func (m *Migrator) applyManifest() (*types.MigrationResult, error) {
+	result := m.newResult()
+	m.targetClient.WaitForRateLimit()
+
+	for _, scope := range m.config.Desired {
+		if m.stopped() {
+			break
+		}
+		if err := m.applyManifestScope(scope, result); err != nil {
+			logger.Error("Failed to apply %s: %v", scope.Label(), err)
+			m.addError(result, fmt.Errorf("%s: %w", scope.Label(), err))
+		}
+	}
+	return result, nil
+}
+
+// applyManifestScope converges one declared scope
+func (m *Migrator) applyManifestScope(scope types.DesiredScope, result *types.MigrationResult) error {
+	label := scope.Label()
+	wanted := m.filterDesired(scope.Variables, result)
+	logger.Info("Applying %d variable(s) to %s", len(wanted), label)
+	result.Add(&result.Selected, len(wanted))
+
+	current, err := m.manifestCurrent(scope, len(wanted) > 0)
+	if err != nil {
+		return err
+	}
+	currentByName := make(map[string]types.Variable, len(current))
+	for _, v := range current {
+		currentByName[strings.ToUpper(v.Name)] = v
+	}
+
+	declared := make(map[string]bool, len(scope.Variables))
+	for _, want := range scope.Variables {
+		declared[strings.ToUpper(want.Name)] = true
+	}
+	for _, want := range wanted {
+		if m.stopped() {
+			return nil
+		}
+		var existing *types.Variable
+		if got, ok := currentByName[strings.ToUpper(want.Name)]; ok {
+			existing = &got
+		}
+		if err := m.applyManifestVariable(scope, want, existing, result); err != nil {
+			logger.VariableError(want.Name, "Failed to apply variable '%s' (%s): %v", want.Name, label, err)
+			recordFailed(label, want.Name, err, result)
+			m.addError(result, fmt.Errorf("%s variable '%s': %w", label, want.Name, err))
+		}
+	}
+
+	if !m.config.Prune {
+		return nil
+	}
+	var extra []string
+	for key, v := range currentByName {
+		if !declared[key] && m.pruneSelected(v.Name) {
+			extra = append(extra, v.Name)
+		}
+	}
+	sort.Strings(extra)
+	if ok, err := m.confirmPrune(label, extra); err != nil || !ok {
+		return err
+	}
+	for _, name := range extra {
+		if m.stopped() {
+			return nil
+		}
+		if err := m.deleteVariable(scope, name, ", --prune", result); err != nil {
+			logger.VariableError(name, "Failed to delete variable '%s' (%s): %v", name, label, err)
+			recordFailed(label, name, err, result)
+			m.addError(result, fmt.Errorf("%s variable '%s': %w", label, name, err))
+		}
+	}
+	return nil
+}
+
+// filterDesired applies the --vars selection and the name filters of an
+// import to the variables declared for a scope. A manifest is applied
+// without filters, so all of them are kept.
+func (m *Migrator) filterDesired(vars []types.DesiredVariable, result *types.MigrationResult) []types.DesiredVariable {
+	named := make([]types.Variable, 0, len(vars))
+	for _, v := range vars {
+		named = append(named, types.Variable{Name: v.Name})
+	}
+	keep := make(map[string]bool, len(vars))
+	for _, v := range m.filterVariables(named, result) {
+		keep[v.Name] = true
+	}
+
+	kept := make([]types.DesiredVariable, 0, len(keep))
+	for _, v := range vars {
+		if keep[v.Name] {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// pruneSelected reports whether --prune may delete an undeclared target
+// variable: the --vars selection and the name filters of an import also
+// limit what is pruned, so variables they leave out are never touched
+func (m *Migrator) pruneSelected(name string) bool {
+	if m.requestedVars != nil && !m.requestedVars[strings.ToUpper(name)] {
+		return false
+	}
+	return matchesNameFilters(name, m.config.Include, m.config.Exclude, m.nameRegex)
+}
+
+// confirmPrune lists the variables an import prunes from the scope label
+// and asks to type label before they are deleted. Nothing is asked in
+// dry-run mode, with Yes, or for apply --manifest, whose manifest declares
+// the whole scope. A mismatch leaves the scope's variables alone.
+func (m *Migrator) confirmPrune(label string, names []string) (bool, error) {
+	if len(names) == 0 || m.config.Mode != types.ModeImport || m.config.DryRun || m.config.Yes {
+		return true, nil
+	}
+	logger.Info("%d variable(s) of %s are not in the file and will be deleted (--prune):", len(names), label)
+	for _, name := range names {
+		logger.Plain("  - %s", name)
+	}
+	ok, err := m.confirmTyped(fmt.Sprintf("Delete %d variable(s) from %s", len(names), label), label)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		logger.Warning("Confirmation did not match %s; none of its variables were deleted", label)
+	}
+	return ok, nil
+}
+
+// applyManifestVariable creates or updates one declared variable; existing
+// is its current state in the target, or nil when it does not exist
+func (m *Migrator) applyManifestVariable(scope types.DesiredScope, want types.DesiredVariable, existing *types.Variable, result *types.MigrationResult) error {
+	label := scope.Label()
+	target, err := m.manifestVariable(scope, want)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		if m.config.DryRun {
+			logger.Detail("[DRY-RUN] Would create variable: %s (%s)", want.Name, label)
+			m.recordCreated(label, target, result)
+			return nil
+		}
+		if ok, err := m.confirmWrite("Create", label, want.Name, want.Name+" ("+label+")", result); err != nil || !ok {
+			return err
+		}
+		if err := m.writeManifestVariable(scope, target, false); err != nil {
+			return fmt.Errorf("failed to create: %w", err)
+		}
+		logger.DetailSuccess("Created variable: %s (%s)", want.Name, label)
+		m.recordCreated(label, target, result)
+		return nil
+	}
+
+	if sameState(*existing, target) && !m.config.AlwaysWrite {
+		logger.Detail("Variable '%s' (%s) is unchanged in target, update skipped", want.Name, label)
+		recordUnchanged(label, want.Name, result)
+		return nil
+	}
+
+	overwrite, err := m.resolveConflict("Variable", label, want.Name, want.Name+" ("+label+")", result)
+	if err != nil || !overwrite {
+		return err
+	}
+	if m.config.DryRun {
+		logger.Detail("[DRY-RUN] Would update variable: %s (%s)%s", want.Name, label, m.valueChange(target, existing))
+		m.recordUpdated(label, target, result)
+		return nil
+	}
+	if ok, err := m.confirmWrite("Update", label, want.Name, want.Name+" ("+label+")", result); err != nil || !ok {
+		return err
+	}
+	if err := m.writeManifestVariable(scope, target, true); err != nil {
+		return fmt.Errorf("failed to update: %w", err)
+	}
+	logger.DetailSuccess("Updated variable: %s (%s)", want.Name, label)
+	m.recordUpdated(label, target, result)
+	return nil
+}
+
+// deleteVariable deletes (or, in dry-run mode, reports) a target variable,
+// e.g. one the manifest does not declare; note is appended to the scope in
+// log messages
+func (m *Migrator) deleteVariable(scope types.DesiredScope, name, note string, result *types.MigrationResult) error {
+	label := scope.Label()
+	if m.config.DryRun {
+		logger.Detail("[DRY-RUN] Would delete variable: %s (%s%s)", name, label, note)
+		recordDeleted(label, name, result)
+		return nil
+	}
+	if ok, err := m.confirmWrite("Delete", label, name, name+" ("+label+")", result); err != nil || !ok {
+		return err
+	}
+
+	var err error
+	switch {
+	case scope.Org != "":
+		err = m.targetClient.DeleteOrgVariable(scope.Org, name)
+	case scope.Environment != "":
+		err = m.targetClient.DeleteEnvVariable(scope.Owner, scope.Repo, scope.Environment, name)
+	default:
+		err = m.targetClient.DeleteRepoVariable(scope.Owner, scope.Repo, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+	logger.DetailSuccess("Deleted variable: %s (%s%s)", name, label, note)
+	recordDeleted(label, name, result)
+	return nil
+}
+
+// recordDeleted counts a target variable deleted (or that would be deleted
+// in dry-run mode) by --prune or the delete command
+func recordDeleted(scope, name string, result *types.MigrationResult) {
+	result.IncDeleted()
+	result.AddDetail(types.VariableResult{Scope: scope, Name: name, Action: types.ActionDeleted})
+}
+
+// manifestVariable converts a declared variable to the variable written to
+// the target, resolving the selected repositories of an organization
+// variable to their IDs
+func (m *Migrator) manifestVariable(scope types.DesiredScope, want types.DesiredVariable) (types.Variable, error) {
+	v := types.Variable{Name: want.Name, Value: want.Value, Visibility: want.Visibility}
+	if want.Visibility != "selected" {
+		return v, nil
+	}
+	v.SelectedRepositoryIDs = []int64{}
+	for _, name := range want.SelectedRepositories {
+		repo, err := m.targetClient.GetRepo(scope.Org, name)
+		if err != nil {
+			return v, fmt.Errorf("selected repository %s/%s: %w", scope.Org, name, err)
+		}
+		v.SelectedRepositoryIDs = append(v.SelectedRepositoryIDs, repo.ID)
+	}
+	return v, nil
+}
+
+// writeManifestVariable creates or updates a variable in a declared scope
+func (m *Migrator) writeManifestVariable(scope types.DesiredScope, v types.Variable, update bool) error {
+	switch {
+	case scope.Org != "" && update:
+		return m.targetClient.UpdateOrgVariable(scope.Org, v)
+	case scope.Org != "":
+		return m.targetClient.CreateOrgVariable(scope.Org, v)
+	case scope.Environment != "" && update:
+		return m.targetClient.UpdateEnvVariable(scope.Owner, scope.Repo, scope.Environment, v)
+	case scope.Environment != "":
+		return m.targetClient.CreateEnvVariable(scope.Owner, scope.Repo, scope.Environment, v)
+	case update:
+		return m.targetClient.UpdateRepoVariable(scope.Owner, scope.Repo, v)
+	default:
+		return m.targetClient.CreateRepoVariable(scope.Owner, scope.Repo, v)
+	}
+}
+
+// manifestCurrent lists the current variables of a declared scope. A
+// missing environment has none; with create set, which the caller does when
+// there are variables to write to it, it is created (or, in dry-run mode,
+// reported).
+func (m *Migrator) manifestCurrent(scope types.DesiredScope, create bool) ([]types.Variable, error) {
+	switch {
+	case scope.Org != "":
+		return listOrgVariablesWithSelection(m.targetClient, scope.Org)
+	case scope.Environment == "":
+		return m.targetClient.ListRepoVariables(scope.Owner, scope.Repo)
+	}
+
+	if _, err := m.targetClient.GetEnvironment(scope.Owner, scope.Repo, scope.Environment); err != nil {
+		if !create {
+			return nil, nil
+		}
+		if m.config.DryRun {
+			logger.Info("[DRY-RUN] Would create environment: %s (%s/%s)", scope.Environment, scope.Owner, scope.Repo)
+			return nil, nil
+		}
+		if err := m.targetClient.CreateEnvironment(scope.Owner, scope.Repo, scope.Environment); err != nil {
+			return nil, fmt.Errorf("failed to create environment: %w", err)
+		}
+		logger.Success("Created environment: %s (%s/%s)", scope.Environment, scope.Owner, scope.Repo)
+		return nil, nil
+	}
+	return m.targetClient.ListEnvVariables(scope.Owner, scope.Repo, scope.Environment)
+}
+
+// diffManifest compares every declared scope with the target. Target
+// variables the manifest does not declare are reported as target-only;
+// they are the ones --prune would delete.
+func (m *Migrator) diffManifest() (*types.DiffResult, error) {
+	diff := &types.DiffResult{}
+	for _, scope := range m.config.Desired {
+		current, err := m.manifestCurrent(scope, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list target variables of %s: %w", scope.Label(), err)
+		}
+		declared := make([]types.Variable, 0, len(scope.Variables))
+		for _, v := range scope.Variables {
+			declared = append(declared, types.Variable{Name: v.Name, Value: v.Value, Visibility: v.Visibility})
+		}
+		diff.Entries = append(diff.Entries, m.diffScope(scope.Label(), declared, current)...)
+	}
+	return diff, nil
+}
+
+// Export reads the current variables of an organization, or of a
+// repository of it and its environments when repo is set, as the scopes of
+// a manifest. Applying a manifest built from them changes nothing.
+func Export(c *client.Client, org, repo string) ([]types.DesiredScope, error) {
+	if c == nil {
+		return nil, fmt.Errorf("client cannot be nil")
+	}
+	c.WaitForRateLimit()
+
+	if repo == "" {
+		vars, err := c.ListOrgVariables(org)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization variables: %w", err)
+		}
+		scope := types.DesiredScope{Org: org}
+		for _, v := range vars {
+			dv := types.DesiredVariable{Name: v.Name, Value: v.Value, Visibility: v.Visibility}
+			if v.Visibility == "selected" {
+				if dv.SelectedRepositories, err = selectedRepoNames(c, org, v.Name); err != nil {
+					return nil, err
+				}
+			}
+			scope.Variables = append(scope.Variables, dv)
+		}
+		return []types.DesiredScope{scope}, nil
+	}
+
+	vars, err := c.ListRepoVariables(org, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository variables: %w", err)
+	}
+	scopes := []types.DesiredScope{{Owner: org, Repo: repo, Variables: desiredVariables(vars)}}
+
+	envs, err := c.ListEnvironments(org, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environments: %w", err)
+	}
+	for _, env := range envs {
+		vars, err := c.ListEnvVariables(org, repo, env.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list variables of environment %s: %w", env.Name, err)
+		}
+		scopes = append(scopes, types.DesiredScope{Owner: org, Repo: repo, Environment: env.Name, Variables: desiredVariables(vars)})
+	}
+	return scopes, nil
+}
+
+// desiredVariables converts repository or environment variables, which
+// have no visibility
+func desiredVariables(vars []types.Variable) []types.DesiredVariable {
+	var out []types.DesiredVariable
+	for _, v := range vars {
+		out = append(out, types.DesiredVariable{Name: v.Name, Value: v.Value})
+	}
+	return out
+}
+
+// selectedRepoNames returns the sorted names of the repositories an
+// organization variable with selected visibility is visible to, or nil
+func selectedRepoNames(c *client.Client, org, name string) ([]string, error) {
+	repos, err := c.ListOrgVariableSelectedRepos(org, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list selected repositories for '%s': %w", name, err)
+	}
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
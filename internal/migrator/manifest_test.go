@@ -0,0 +1,255 @@
+package migrator
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/manifest"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// desiredScopes declares an organization, a repository, and one of its
+// environments
+func desiredScopes() []types.DesiredScope {
+	return []types.DesiredScope{
+		{Org: "acme", Variables: []types.DesiredVariable{
+			{Name: "REGION", Value: "eu", Visibility: "private"},
+		}},
+		{Owner: "acme", Repo: "app", Variables: []types.DesiredVariable{
+			{Name: "LOG_LEVEL", Value: "info"},
+			{Name: "TIMEOUT", Value: "30"},
+		}},
+		{Owner: "acme", Repo: "app", Environment: "production", Variables: []types.DesiredVariable{
+			{Name: "URL", Value: "https://app.example.com"},
+		}},
+	}
+}
+
+// manifestConfig returns the configuration of a manifest apply
+func manifestConfig(scopes []types.DesiredScope) *types.MigrationConfig {
+	return &types.MigrationConfig{Mode: types.ModeManifest, Manifest: "vars.yaml", Desired: scopes}
+}
+
+// runManifest applies cfg to fake and fails the test on a run error
+func runManifest(t *testing.T, cfg *types.MigrationConfig, fake *fakeGitHub) *types.MigrationResult {
+	t.Helper()
+	var result *types.MigrationResult
+	captureStdout(t, func() {
+		var err error
+		result, err = newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+	return result
+}
+
+// writeCount counts create, update, and delete calls
+func writeCount(fake *fakeGitHub) int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	n := 0
+	for _, call := range fake.calls {
+		if strings.HasPrefix(call, "POST ") || strings.HasPrefix(call, "PATCH ") || strings.HasPrefix(call, "DELETE ") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestApplyManifest_Create(t *testing.T) {
+	fake := newFakeGitHub()
+	result := runManifest(t, manifestConfig(desiredScopes()), fake)
+
+	if result.Created != 4 || result.Updated != 0 || result.HasErrors() {
+		t.Fatalf("Expected 4 creates, got %+v (errors: %v)", result, result.Errors)
+	}
+	if v, ok := fake.getVar(orgVarsPath("acme"), "REGION"); !ok || v.Value != "eu" || v.Visibility != "private" {
+		t.Errorf("Organization variable not created as declared: %+v", v)
+	}
+	if v, ok := fake.getVar(envVarsPath("acme", "app", "production"), "URL"); !ok || v.Value != "https://app.example.com" {
+		t.Errorf("Environment variable not created: %+v", v)
+	}
+	if !fake.envs["acme/app"]["production"] {
+		t.Error("The missing environment should have been created")
+	}
+}
+
+func TestApplyManifest_UpdateAndConverge(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("acme"), types.Variable{Name: "REGION", Value: "eu", Visibility: "all"})
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LOG_LEVEL", Value: "debug"})
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "TIMEOUT", Value: "30"})
+	fake.addEnv("acme", "app", "production")
+	fake.setVar(envVarsPath("acme", "app", "production"), types.Variable{Name: "URL", Value: "https://app.example.com"})
+
+	result := runManifest(t, manifestConfig(desiredScopes()), fake)
+	if result.Created != 0 || result.Updated != 2 || result.Unchanged != 2 {
+		t.Fatalf("Expected the visibility and value changes to be updated, got %+v", result)
+	}
+	if v, _ := fake.getVar(orgVarsPath("acme"), "REGION"); v.Visibility != "private" {
+		t.Errorf("Visibility not updated: %+v", v)
+	}
+	if v, _ := fake.getVar(repoVarsPath("acme", "app"), "LOG_LEVEL"); v.Value != "info" {
+		t.Errorf("Value not updated: %+v", v)
+	}
+
+	// Applying again is a no-op
+	before := writeCount(fake)
+	again := runManifest(t, manifestConfig(desiredScopes()), fake)
+	if again.Created+again.Updated+again.Deleted != 0 || again.Unchanged != 4 {
+		t.Errorf("Second apply should change nothing, got %+v", again)
+	}
+	if writes := writeCount(fake) - before; writes != 0 {
+		t.Errorf("Second apply made %d write(s)", writes)
+	}
+}
+
+func TestApplyManifest_Prune(t *testing.T) {
+	for _, prune := range []bool{false, true} {
+		fake := newFakeGitHub()
+		fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LOG_LEVEL", Value: "info"})
+		fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "TIMEOUT", Value: "30"})
+		fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LEGACY", Value: "x"})
+		fake.setVar(repoVarsPath("acme", "other"), types.Variable{Name: "UNDECLARED_SCOPE", Value: "y"})
+
+		cfg := manifestConfig(desiredScopes()[1:2])
+		cfg.Prune = prune
+		result := runManifest(t, cfg, fake)
+
+		_, kept := fake.getVar(repoVarsPath("acme", "app"), "LEGACY")
+		if kept == prune {
+			t.Errorf("prune=%v: LEGACY kept=%v", prune, kept)
+		}
+		if _, ok := fake.getVar(repoVarsPath("acme", "other"), "UNDECLARED_SCOPE"); !ok {
+			t.Errorf("prune=%v: a scope the manifest does not declare must be left alone", prune)
+		}
+		wantDeleted := 0
+		if prune {
+			wantDeleted = 1
+			want := types.VariableResult{Scope: "acme/app", Name: "LEGACY", Action: types.ActionDeleted}
+			if d := result.Details[len(result.Details)-1]; !reflect.DeepEqual(d, want) {
+				t.Errorf("Last detail = %+v, want %+v", d, want)
+			}
+		}
+		if result.Deleted != wantDeleted || result.Unchanged != 2 {
+			t.Errorf("prune=%v: unexpected result %+v", prune, result)
+		}
+	}
+}
+
+func TestApplyManifest_DryRun(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LOG_LEVEL", Value: "debug"})
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LEGACY", Value: "x"})
+
+	cfg := manifestConfig(desiredScopes())
+	cfg.DryRun = true
+	cfg.Prune = true
+	result := runManifest(t, cfg, fake)
+
+	if result.Created != 3 || result.Updated != 1 || result.Deleted != 1 {
+		t.Errorf("Unexpected dry-run result: %+v", result)
+	}
+	if writes := writeCount(fake); writes != 0 {
+		t.Errorf("Dry run made %d write(s)", writes)
+	}
+	if fake.envs["acme/app"]["production"] {
+		t.Error("Dry run must not create environments")
+	}
+}
+
+func TestApplyManifest_ConflictSkip(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LOG_LEVEL", Value: "debug"})
+
+	cfg := manifestConfig(desiredScopes()[1:2])
+	cfg.OnConflict = types.ConflictSkip
+	result := runManifest(t, cfg, fake)
+
+	if result.Created != 1 || result.Skipped != 1 || result.Conflicts != 1 {
+		t.Errorf("Expected the differing variable to be skipped, got %+v", result)
+	}
+	if v, _ := fake.getVar(repoVarsPath("acme", "app"), "LOG_LEVEL"); v.Value != "debug" {
+		t.Errorf("Skipped variable was overwritten: %+v", v)
+	}
+}
+
+func TestDiffManifest(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LOG_LEVEL", Value: "debug"})
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "TIMEOUT", Value: "30"})
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LEGACY", Value: "x"})
+
+	var diff *types.DiffResult
+	captureStdout(t, func() {
+		var err error
+		diff, err = newFakeMigrator(t, manifestConfig(desiredScopes()[1:]), fake).Diff()
+		if err != nil {
+			t.Errorf("Diff() unexpected error: %v", err)
+		}
+	})
+
+	got := map[types.DiffStatus]int{}
+	for _, e := range diff.Entries {
+		got[e.Status]++
+	}
+	want := map[types.DiffStatus]int{types.DiffAdd: 1, types.DiffUpdate: 1, types.DiffUnchanged: 1, types.DiffTargetOnly: 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff statuses = %v, want %v", got, want)
+	}
+}
+
+// TestExport_RoundTrip verifies that a manifest written from the current
+// variables applies to the same target without changes, even with pruning
+func TestExport_RoundTrip(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("acme"), types.Variable{Name: "REGION", Value: "eu", Visibility: "private"})
+	fake.setVar(orgVarsPath("acme"), types.Variable{Name: "SHARED", Value: "yes", Visibility: "all"})
+	fake.setVar(repoVarsPath("acme", "app"), types.Variable{Name: "LOG_LEVEL", Value: "info"})
+	fake.addEnv("acme", "app", "production")
+	fake.addEnv("acme", "app", "staging")
+	fake.setVar(envVarsPath("acme", "app", "production"), types.Variable{Name: "URL", Value: "multi\nline: value"})
+
+	c, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scopes []types.DesiredScope
+	for _, repo := range []string{"", "app"} {
+		exported, err := Export(c, "acme", repo)
+		if err != nil {
+			t.Fatalf("Export(%q) unexpected error: %v", repo, err)
+		}
+		scopes = append(scopes, exported...)
+	}
+	if len(scopes) != 4 {
+		t.Fatalf("Expected organization, repository, and 2 environment scopes, got %+v", scopes)
+	}
+
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := manifest.Save(path, manifest.FromDesired(scopes)); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	loaded, err := manifest.Load(path)
+	if err != nil {
+		t.Fatalf("Load() of an exported manifest: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Desired(), scopes) {
+		t.Errorf("Round trip mismatch:\n got  %+v\n want %+v", loaded.Desired(), scopes)
+	}
+
+	before := writeCount(fake)
+	cfg := manifestConfig(loaded.Desired())
+	cfg.Prune = true
+	result := runManifest(t, cfg, fake)
+	if result.Created+result.Updated+result.Deleted != 0 || result.Unchanged != 4 {
+		t.Errorf("Applying an exported manifest should change nothing, got %+v", result)
+	}
+	if writes := writeCount(fake) - before; writes != 0 {
+		t.Errorf("Applying an exported manifest made %d write(s)", writes)
+	}
+}
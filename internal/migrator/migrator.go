@@ -1,7 +1,14 @@
 package migrator
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
 
 	"github.com/renan-alm/gh-vars-migrator/internal/client"
 	"github.com/renan-alm/gh-vars-migrator/internal/config"
@@ -14,6 +21,64 @@ type Migrator struct {
 	sourceClient *client.Client
 	targetClient *client.Client
 	config       *types.MigrationConfig
+	nameRegex    *regexp.Regexp
+	nameMap      map[string]string
+
+	// envMap and repoMap map upper-cased source environment and repository
+	// names to target ones.
+	envMap  map[string]string
+	repoMap map[string]string
+
+	// valueOverrides maps upper-cased source names to replacement values.
+	valueOverrides map[string]string
+	replacements   []types.Replacement
+
+	// requestedVars and foundVars track --vars selection by upper-cased name.
+	requestedVars map[string]bool
+	foundVars     map[string]bool
+
+	// usedVars holds the upper-cased names referenced by the source
+	// workflows for --skip-unused, loaded on first use.
+	usedVars map[string]bool
+
+	// written holds the variables written to the target per scope label,
+	// recorded for --verify.
+	written map[string][]types.Variable
+
+	// input and output carry interactive prompts (os.Stdin and os.Stderr
+	// when nil); promptIn buffers input. conflictAnswer remembers an "all"
+	// or "quit" given to an --on-conflict=prompt question; approveAll and
+	// aborted do the same for --interactive.
+	input          io.Reader
+	output         io.Writer
+	promptIn       *bufio.Reader
+	conflictAnswer string
+	approveAll     bool
+	aborted        bool
+
+	// planned holds the plan being applied or the failed variables being
+	// retried, if any; planScope prefixes the scopes of a repository of a
+	// multi-repository run to match them.
+	planned   *plannedWrites
+	planScope string
+
+	// errors stops the run once --max-errors (or with --fail-fast, one)
+	// errors have been recorded.
+	errors *errorLimit
+	// budget stops the run once --max-api-calls requests have been sent.
+	budget *apiBudget
+	// interrupted is set by Interrupt, possibly from another goroutine
+	interrupted *atomic.Bool
+	// refused is set when the target token was refused part way through a
+	// run that stops there; its partial result is still summarized
+	refused bool
+	// live is the result of the run in progress, read by Partial
+	live atomic.Pointer[types.MigrationResult]
+
+	// fanOutRepoList caches the resolved fan-out repositories; fanOutRepo is
+	// the repository currently being written to.
+	fanOutRepoList []string
+	fanOutRepo     string
 }
 
 // New creates a new Migrator instance with separate source and target clients
@@ -31,19 +96,101 @@ func New(cfg *types.MigrationConfig, sourceClient, targetClient *client.Client)
 		return nil, fmt.Errorf("target client cannot be nil")
 	}
 
-	return &Migrator{
+	m := &Migrator{
 		sourceClient: sourceClient,
 		targetClient: targetClient,
 		config:       cfg,
-	}, nil
+	}
+	if cfg.FilterRegex != "" {
+		// Already validated above, so compilation cannot fail here.
+		m.nameRegex = regexp.MustCompile(cfg.FilterRegex)
+	}
+	m.nameMap = newNameMap(cfg.NameMap)
+	m.envMap = newNameMap(cfg.EnvMap)
+	m.repoMap = newNameMap(cfg.RepoMap)
+	m.valueOverrides = newValueOverrides(cfg.ValueOverrides)
+	m.replacements = newReplacements(cfg)
+	m.errors = newErrorLimit(cfg.FailFast, cfg.MaxErrors)
+	m.budget = newAPIBudget(cfg.MaxAPICalls, sourceClient, targetClient)
+	m.interrupted = new(atomic.Bool)
+	m.planned = newPlannedWrites(cfg.Plan)
+	if cfg.Retry != nil {
+		m.planned = newRetryWrites(cfg.Retry)
+	}
+	m.requestedVars = newNameSet(cfg.Vars)
+	if m.requestedVars != nil {
+		m.foundVars = make(map[string]bool, len(m.requestedVars))
+	}
+
+	return m, nil
 }
 
-// Run executes the migration based on the configuration
+// Run executes the migration based on the configuration. The result
+// records the duration of the run and the API calls made by each client.
 func (m *Migrator) Run() (*types.MigrationResult, error) {
 	logger.Info("Starting migration: %s", config.GetDescription(m.config))
+	started := time.Now()
+	sourceCalls, targetCalls := m.sourceClient.APICalls(), m.targetClient.APICalls()
 
 	if m.config.DryRun {
 		logger.Warning("Running in DRY-RUN mode - no changes will be made")
+		if m.config.Interactive {
+			logger.Info("Interactive approval is not needed in dry-run mode; no prompts will be shown")
+		}
+	}
+
+	var result *types.MigrationResult
+	var missing []string
+	var err error
+	if len(m.config.Targets) > 0 {
+		result, missing = m.runTargets()
+	} else {
+		result, missing, err = m.run()
+		if err == nil && m.config.Deep && !m.stopped() {
+			missing = m.migrateDeep(result, missing)
+		}
+	}
+	if result != nil {
+		result.Interrupted = m.interrupted.Load()
+		result.ErrorLimitReached = m.config.MaxErrors > 0 && m.errors.exceeded()
+		result.BudgetExhausted = m.budget.spent()
+		result.Duration = time.Since(started)
+		result.SourceAPICalls = m.sourceClient.APICalls().Since(sourceCalls)
+		result.TargetAPICalls = m.targetClient.APICalls().Since(targetCalls)
+	}
+	if err != nil {
+		if m.refused && result != nil {
+			m.printSummary(result, nil)
+		}
+		return result, err
+	}
+
+	if result.Interrupted {
+		logger.Warning("Migration interrupted; remaining variables were not processed")
+	}
+	if m.planned != nil && !m.stopped() && !result.Aborted {
+		m.checkPlanReached(result)
+	}
+	if len(missing) > 0 && !result.Aborted && !result.Interrupted && !m.errors.exceeded() && !m.budget.spent() {
+		result.AddError(fmt.Errorf("requested variable(s) not found in source: %s", strings.Join(missing, ", ")))
+	}
+	if m.config.FailIfEmpty && result.Selected == 0 && !result.Aborted && !result.Interrupted && !m.errors.exceeded() && !m.budget.spent() {
+		result.Empty = true
+	}
+
+	m.printSummary(result, missing)
+	return result, nil
+}
+
+// run performs the migration and verification for the configured target
+// and returns the result together with the requested variables that were
+// not found in the source, which the caller reports
+func (m *Migrator) run() (*types.MigrationResult, []string, error) {
+	if m.config.ConflictStrategy() == types.ConflictFail {
+		result := &types.MigrationResult{}
+		if err := m.checkConflicts(result); err != nil {
+			return result, nil, err
+		}
 	}
 
 	var result *types.MigrationResult
@@ -54,24 +201,285 @@ func (m *Migrator) Run() (*types.MigrationResult, error) {
 		result, err = m.migrateRepoToRepo()
 	case types.ModeOrgToOrg:
 		result, err = m.migrateOrgToOrg()
+	case types.ModeOrgToRepo:
+		result, err = m.migrateOrgToRepo()
+	case types.ModeRepoToOrg:
+		result, err = m.migrateRepoToOrg()
+	case types.ModeFanOut:
+		result, err = m.migrateFanOut()
+	case types.ModeManifest, types.ModeImport:
+		result, err = m.applyManifest()
+	case types.ModeDelete:
+		result, err = m.deleteVariables()
+	case types.ModeCopy:
+		result, err = m.copyVariable()
 	default:
-		return nil, fmt.Errorf("unsupported migration mode: %s", m.config.Mode)
+		return nil, nil, fmt.Errorf("unsupported migration mode: %s", m.config.Mode)
 	}
 
 	if err != nil {
-		return result, err
+		return result, nil, err
+	}
+
+	if m.aborted {
+		result.Aborted = true
+		logger.Warning("Migration stopped at user request; remaining variables were not processed")
+	}
+	if m.errors.exceeded() {
+		logger.Warning("Migration stopped after %s; remaining variables were not processed", m.errorLimitLabel())
+	}
+	if m.budget.spent() {
+		logger.Warning("Migration stopped after %d API call(s) (--max-api-calls); remaining variables were not processed", m.budget.used())
 	}
 
-	// Print summary
+	if m.config.CheckUsage && !m.interrupted.Load() && !m.budget.exhausted() {
+		m.checkUsage(result)
+	}
+	if m.config.Verify && !m.interrupted.Load() && !m.budget.exhausted() {
+		if m.config.DryRun {
+			logger.Info("Skipping verification in dry-run mode")
+		} else {
+			m.verify(result)
+		}
+	}
+
+	return result, m.missingVars(), nil
+}
+
+// addError records err in result and counts it against --max-errors. Once
+// the limit is reached the migration stops, which the loops check through
+// stopped.
+func (m *Migrator) addError(result *types.MigrationResult, err error) {
+	result.AddError(err)
+	if m.errors.add() {
+		logger.Error("Reached %s; stopping the migration", m.errorLimitLabel())
+	}
+}
+
+// errorLimitLabel describes the error limit for log messages
+func (m *Migrator) errorLimitLabel() string {
+	if m.config.FailFast {
+		return "the first error (--fail-fast)"
+	}
+	return fmt.Sprintf("%d error(s) (--max-errors)", m.config.MaxErrors)
+}
+
+// stopped reports whether the remaining variables are to be left alone,
+// after a quit at a prompt, once the error limit or the API call budget is
+// reached, or after an interrupt
+func (m *Migrator) stopped() bool {
+	return m.aborted || m.errors.exceeded() || m.interrupted.Load() || m.budget.exhausted()
+}
+
+// Interrupt stops a running migration before its next variable, so that Run
+// returns the partial result with Interrupted set. The write in progress is
+// finished. It is safe to call from another goroutine, e.g. a signal
+// handler.
+func (m *Migrator) Interrupt() {
+	m.interrupted.Store(true)
+}
+
+// Partial returns a copy of what the run in progress has recorded so far,
+// or an empty result before it has started. It is safe to call from
+// another goroutine, e.g. to report on a run that does not stop in time
+// after Interrupt.
+func (m *Migrator) Partial() *types.MigrationResult {
+	if result := m.live.Load(); result != nil {
+		return result.Snapshot()
+	}
+	return &types.MigrationResult{}
+}
+
+// newResult creates the result of the run and publishes it for Partial
+func (m *Migrator) newResult() *types.MigrationResult {
+	result := &types.MigrationResult{}
+	m.live.Store(result)
+	return result
+}
+
+// printSummary prints the counts, the per-repository breakdown, and the
+// errors of a finished migration
+func (m *Migrator) printSummary(result *types.MigrationResult, missing []string) {
 	logger.PrintSummary(result.Created, result.Updated, result.Skipped, len(result.Errors))
+	if result.Interrupted {
+		logger.Warning("Interrupted: the counts cover only the variables processed before the interrupt")
+	}
+	if m.config.SkipRepoVars {
+		logger.Info("Repository variables: not migrated (environments only)")
+	}
+	if result.Unchanged > 0 {
+		logger.Info("Unchanged: %d (already identical in target)", result.Unchanged)
+	}
+	switch {
+	case m.config.Mode == types.ModeDelete && m.refused:
+		logger.Info("Deleted before the refusal: %d", result.Deleted)
+		for _, d := range result.Details {
+			if d.Action == types.ActionDeleted {
+				logger.Plain("  - %s (%s)", d.Name, d.Scope)
+			}
+		}
+	case m.config.Mode == types.ModeDelete:
+		logger.Info("Deleted: %d", result.Deleted)
+	case m.config.Prune && m.config.Mode == types.ModeImport:
+		logger.Info("Deleted: %d (not in the file; --prune)", result.Deleted)
+	case m.config.Prune:
+		logger.Info("Deleted: %d (not in manifest; --prune)", result.Deleted)
+	}
+	if result.Conflicts > 0 {
+		logger.Info("Conflicts: %d (on-conflict=%s; overwritten: %d, skipped: %d)",
+			result.Conflicts, m.config.ConflictStrategy(), result.Updated, result.Skipped)
+	}
+	if result.Declined > 0 {
+		logger.Info("Declined: %d (included in Skipped)", result.Declined)
+	}
+	if result.ReservedNames > 0 {
+		logger.Info("Reserved names: %d (included in Skipped)", result.ReservedNames)
+	}
+	if result.Filtered > 0 {
+		logger.Info("Filtered: %d", result.Filtered)
+	}
+	if result.UnchangedSince > 0 {
+		logger.Info("Unchanged since %s: %d (--since)", m.config.Since.Format(time.RFC3339), result.UnchangedSince)
+	}
+	if result.Overridden > 0 {
+		logger.Info("Values overridden: %d", result.Overridden)
+	}
+	if result.Rewritten > 0 {
+		logger.Info("Values rewritten: %d", result.Rewritten)
+	}
+	if result.SelectedFallbacks > 0 {
+		logger.Info("Selected without matching repositories: %d (selected-fallback=%s)",
+			result.SelectedFallbacks, m.config.SelectedFallbackOrDefault())
+	}
+	if len(result.Unused) > 0 {
+		logger.Info("Unused: %d (not referenced by source workflows; --skip-unused)", len(result.Unused))
+	}
+	if m.config.CheckUsage {
+		logger.Info("Workflow references not in target: %d (--check-usage)", result.UnresolvedRefs)
+	}
+	if result.Empty {
+		if filters := m.activeFilters(); len(filters) > 0 {
+			logger.Error("No source variables left to migrate after filtering (--fail-if-empty); active filters: %s", strings.Join(filters, ", "))
+		} else {
+			logger.Error("No source variables to migrate (--fail-if-empty); no filters were active, so the source has none")
+		}
+	}
+	if m.config.Verify && !m.config.DryRun {
+		logger.Info("Verified: %d", result.Verified)
+		logger.Info("Mismatched: %d", result.Mismatched)
+	}
+	if len(m.config.Envs) > 0 || len(result.FilteredEnvs) > 0 {
+		logger.Info("Environments migrated: %d (%s)", len(result.Environments), envList(result.Environments))
+		logger.Info("Environments skipped by filter: %d (%s)", len(result.FilteredEnvs), envList(result.FilteredEnvs))
+	}
+	if m.requestedVars != nil {
+		logger.Info("Requested: %d, Found: %d, Migrated: %d",
+			len(m.requestedVars), len(m.requestedVars)-len(missing), result.Created+result.Updated)
+	}
+	if scopes := result.Scopes(); len(scopes) > 0 {
+		logger.Plain("\nPer-scope results:")
+		for _, line := range strings.Split(formatScopeTable(scopes), "\n") {
+			logger.Plain("  %s", line)
+		}
+	}
+	if len(result.Repos) > 0 {
+		printRepoTable(result.Repos)
+	}
+	printDetailTable(result.Details)
+	logger.Info("Duration: %s, Source API calls: %d, Target API calls: %d",
+		formatDuration(result.Duration), result.SourceAPICalls.Total(), result.TargetAPICalls.Total())
 
 	// Print errors if any
 	if result.HasErrors() {
-		logger.Error("\nEncountered %d error(s) during migration:", len(result.Errors))
+		logger.SummaryError("\nEncountered %d error(s) during migration:", len(result.Errors))
 		for i, err := range result.Errors {
-			logger.Error("  %d. %v", i+1, err)
+			logger.SummaryError("  %d. %v", i+1, err)
 		}
 	}
+}
 
-	return result, nil
+// formatDuration rounds a run duration for the summary: to the millisecond
+// below a second, otherwise to the second
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// envList formats environment names for the summary
+func envList(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// formatScopeTable renders the per-scope counts as an aligned table with a
+// totals row
+func formatScopeTable(scopes []types.ScopeResult) string {
+	var buf strings.Builder
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCOPE\tCREATED\tUPDATED\tSKIPPED\tERRORS")
+	var total types.ScopeResult
+	for _, s := range scopes {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", s.Scope, s.Created, s.Updated, s.Skipped, s.Errors)
+		total.Created += s.Created
+		total.Updated += s.Updated
+		total.Skipped += s.Skipped
+		total.Errors += s.Errors
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%d\t%d\t%d\n", total.Created, total.Updated, total.Skipped, total.Errors)
+	w.Flush()
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// printDetailTable prints the variables that were skipped or failed, with
+// their scope and reason. Nothing is printed when every variable succeeded.
+func printDetailTable(details []types.VariableResult) {
+	var buf strings.Builder
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCOPE\tVARIABLE\tACTION\tREASON")
+	rows := 0
+	for _, d := range details {
+		if d.Action != types.ActionSkipped && d.Action != types.ActionFailed {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Scope, d.Name, d.Action, d.Reason)
+		rows++
+	}
+	if rows == 0 {
+		return
+	}
+	w.Flush()
+
+	logger.Plain("\nVariables not migrated:")
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		logger.Plain("  %s", line)
+	}
+}
+
+// printRepoTable prints the per-repository breakdown as an aligned table
+func printRepoTable(repos []types.RepoResult) {
+	var buf strings.Builder
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tCREATED\tUPDATED\tSKIPPED\tERRORS\tSTATUS")
+	for _, r := range repos {
+		status := "ok"
+		switch {
+		case r.Note != "":
+			status = r.Note
+		case r.Failed:
+			status = "failed"
+		case r.Errors > 0:
+			status = "partial"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n", r.Repo, r.Created, r.Updated, r.Skipped, r.Errors, status)
+	}
+	w.Flush()
+
+	logger.Plain("\nPer-repository results:")
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		logger.Plain("  %s", line)
+	}
 }
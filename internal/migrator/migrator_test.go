@@ -1,9 +1,13 @@
 package migrator
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
 	"github.com/renan-alm/gh-vars-migrator/internal/types"
 )
 
@@ -262,3 +266,181 @@ func TestErrorAccumulation(t *testing.T) {
 		t.Error("Expected result to have errors")
 	}
 }
+
+// checkDetails verifies that the created, updated, and skipped counters of
+// a result match its per-variable detail records
+func checkDetails(t *testing.T, result *types.MigrationResult) {
+	t.Helper()
+	counts := map[types.VariableAction]int{}
+	for _, d := range result.Details {
+		counts[d.Action]++
+	}
+	if counts[types.ActionCreated] != result.Created || counts[types.ActionUpdated] != result.Updated ||
+		counts[types.ActionUnchanged] != result.Unchanged || counts[types.ActionSkipped] != result.Skipped {
+		t.Errorf("Details %v do not match counters created=%d updated=%d unchanged=%d skipped=%d",
+			counts, result.Created, result.Updated, result.Unchanged, result.Skipped)
+	}
+}
+
+// detailLines formats detail records as "scope name action" for comparison
+func detailLines(details []types.VariableResult) []string {
+	lines := make([]string, len(details))
+	for i, d := range details {
+		lines[i] = fmt.Sprintf("%s %s %s", d.Scope, d.Name, d.Action)
+	}
+	return lines
+}
+
+func TestPrintDetailTable(t *testing.T) {
+	details := []types.VariableResult{
+		{Scope: "repository", Name: "A", Action: types.ActionCreated},
+		{Scope: "repository", Name: "B", Action: types.ActionSkipped, Reason: "already exists in target (--on-conflict=skip)"},
+		{Scope: "env:prod", Name: "C", Action: types.ActionFailed, Reason: "failed to create: boom"},
+	}
+
+	out := captureStdout(t, func() { printDetailTable(details) })
+	if !strings.Contains(out, "Variables not migrated:") || strings.Contains(out, " A ") {
+		t.Errorf("Expected only non-success entries, got:\n%s", out)
+	}
+	for _, want := range []string{"already exists in target (--on-conflict=skip)", "env:prod", "failed to create: boom"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the table, got:\n%s", want, out)
+		}
+	}
+
+	if out := captureStdout(t, func() { printDetailTable(details[:1]) }); out != "" {
+		t.Errorf("Expected no table when every variable succeeded, got:\n%s", out)
+	}
+}
+
+func TestFormatScopeTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []types.ScopeResult
+		want   string
+	}{
+		{
+			name: "multiple environments",
+			scopes: []types.ScopeResult{
+				{Scope: "repository", Created: 3, Updated: 1},
+				{Scope: "env:production", Skipped: 12, Errors: 1},
+				{Scope: "env:staging", Created: 2, Updated: 10},
+			},
+			want: "" +
+				"SCOPE           CREATED  UPDATED  SKIPPED  ERRORS\n" +
+				"repository      3        1        0        0\n" +
+				"env:production  0        0        12       1\n" +
+				"env:staging     2        10       0        0\n" +
+				"TOTAL           5        11       12       1",
+		},
+		{
+			name:   "zero environments",
+			scopes: []types.ScopeResult{{Scope: "organization", Created: 4, Skipped: 1}},
+			want: "" +
+				"SCOPE         CREATED  UPDATED  SKIPPED  ERRORS\n" +
+				"organization  4        0        1        0\n" +
+				"TOTAL         4        0        1        0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatScopeTable(tt.scopes); got != tt.want {
+				t.Errorf("formatScopeTable() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintSummary_Scopes(t *testing.T) {
+	fake := seedEnvsFake()
+	fake.addEnv("dst", "app", "production")
+	fake.setVar(envVarsPath("dst", "app", "production"), types.Variable{Name: "URL", Value: "old"})
+
+	cfg := repoToRepoConfig()
+	cfg.OnConflict = types.ConflictSkip
+	out := captureStdout(t, func() {
+		if _, err := newFakeMigrator(t, cfg, fake).Run(); err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+
+	for _, want := range []string{
+		"Per-scope results:",
+		"env:production  0        0        1        0",
+		"env:staging     1        0        0        0",
+		"TOTAL           3        0        1        0",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRun_Metrics(t *testing.T) {
+	fake := seedEnvsFake()
+
+	result, err := newFakeMigrator(t, repoToRepoConfig(), fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if result.Duration <= 0 {
+		t.Errorf("Expected a non-zero duration, got %v", result.Duration)
+	}
+	// Both clients talk to the same fake, which records every request.
+	if got := result.SourceAPICalls.Total() + result.TargetAPICalls.Total(); got != len(fake.calls) {
+		t.Errorf("Expected %d API calls in total, got source %v and target %v", len(fake.calls), result.SourceAPICalls, result.TargetAPICalls)
+	}
+	if result.SourceAPICalls["POST"] != 0 || result.SourceAPICalls["PUT"] != 0 {
+		t.Errorf("The source client must only read, got %v", result.SourceAPICalls)
+	}
+	posts := 0
+	for _, call := range fake.calls {
+		if strings.HasPrefix(call, "POST ") {
+			posts++
+		}
+	}
+	if posts == 0 || result.TargetAPICalls["POST"] != posts {
+		t.Errorf("Target POST calls = %d, want %d", result.TargetAPICalls["POST"], posts)
+	}
+}
+
+func TestRun_Quiet(t *testing.T) {
+	fake := seedEnvsFake()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "BROKEN", Value: "x"})
+	fake.failWrites["BROKEN"] = true
+
+	logger.SetLevel(logger.LevelQuiet)
+	defer logger.SetLevel(logger.LevelNormal)
+
+	oldStderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error: %v", err)
+	}
+	os.Stderr = w
+	out := captureStdout(t, func() {
+		if _, err := newFakeMigrator(t, repoToRepoConfig(), fake).Run(); err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+	_ = w.Close()
+	os.Stderr = oldStderr
+	var stderr bytes.Buffer
+	_, _ = stderr.ReadFrom(r)
+
+	for _, absent := range []string{"Created variable", "Created environment variable"} {
+		if strings.Contains(out, absent) {
+			t.Errorf("Expected no %q lines with quiet, got:\n%s", absent, out)
+		}
+	}
+	for _, want := range []string{"Fetching variables from source repository", "Migration Summary", "Created: 4"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(stderr.String(), "Failed to migrate variable 'BROKEN'") {
+		t.Errorf("Expected the failure on stderr, got:\n%s", stderr.String())
+	}
+}
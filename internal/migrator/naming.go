@@ -0,0 +1,152 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// maxVariableNameLength is a conservative cap on variable name length; the
+// GitHub API rejects names longer than this.
+const maxVariableNameLength = 255
+
+// targetVariable returns a copy of a source variable as it should be written
+// to the target: the name is transformed (name map, then prefix and suffix)
+// and the value is overridden or rewritten as configured. The resulting name
+// is validated against GitHub's naming rules.
+func (m *Migrator) targetVariable(variable types.Variable) (types.Variable, error) {
+	target := variable
+	target.Value = m.targetValue(variable)
+	name := variable.Name
+	if mapped, ok := m.nameMap[strings.ToUpper(name)]; ok {
+		name = mapped
+	}
+	target.Name = transformName(name, m.config.TargetPrefix, m.config.TargetSuffix)
+
+	if target.Name != variable.Name {
+		if err := validateVariableName(target.Name); err != nil {
+			return target, fmt.Errorf("invalid target name for '%s': %w", variable.Name, err)
+		}
+	}
+
+	return target, nil
+}
+
+// checkNameCollisions computes the target name of every variable in a scope
+// and returns an error listing any target name that more than one source
+// variable would be written to. Names are compared case-insensitively
+// because GitHub treats them that way. It performs no API calls, so it runs
+// before any writes for the scope. With AllowCollisions each collision is
+// logged instead; the sources are listed in the order they are written.
+func (m *Migrator) checkNameCollisions(vars []types.Variable) error {
+	sources := make(map[string][]string, len(vars))
+	for _, v := range vars {
+		target, err := m.targetVariable(v)
+		if err != nil {
+			// Invalid names are reported per variable during migration.
+			continue
+		}
+		key := strings.ToUpper(target.Name)
+		sources[key] = append(sources[key], v.Name)
+	}
+
+	var collisions []string
+	for targetName, names := range sources {
+		if len(names) > 1 {
+			collisions = append(collisions, fmt.Sprintf("%s ← %s", targetName, strings.Join(names, ", ")))
+		}
+	}
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	sort.Strings(collisions)
+	if m.config.AllowCollisions {
+		for _, c := range collisions {
+			logger.Warning("Target name collision %s: last writer wins (--allow-collisions)", c)
+		}
+		return nil
+	}
+	return fmt.Errorf("multiple source variables map to the same target name (--allow-collisions to let the last one win): %s", strings.Join(collisions, "; "))
+}
+
+// newNameMap builds a rename lookup keyed by upper-cased source name. It
+// returns nil when the mapping is empty.
+func newNameMap(nameMap map[string]string) map[string]string {
+	if len(nameMap) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(nameMap))
+	for src, dst := range nameMap {
+		out[strings.ToUpper(src)] = dst
+	}
+	return out
+}
+
+// targetEnvName returns the target environment for a source environment:
+// its --env-map entry, or the same name when it is not mapped
+func (m *Migrator) targetEnvName(envName string) string {
+	if target, ok := m.envMap[strings.ToUpper(envName)]; ok {
+		return target
+	}
+	return envName
+}
+
+// transformName applies a prefix and suffix to a variable name
+func transformName(name, prefix, suffix string) string {
+	return prefix + name + suffix
+}
+
+// validateVariableName checks a variable name against GitHub's rules: only
+// alphanumeric characters or underscores, not starting with a number or the
+// reserved GITHUB_ prefix, and within the length limit.
+func validateVariableName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if len(name) > maxVariableNameLength {
+		return fmt.Errorf("name '%s' exceeds %d characters", name, maxVariableNameLength)
+	}
+	if strings.HasPrefix(strings.ToUpper(name), "GITHUB_") {
+		return fmt.Errorf("name '%s' must not start with the reserved GITHUB_ prefix", name)
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		return fmt.Errorf("name '%s' must not start with a number", name)
+	}
+	for _, r := range name {
+		if !isNameChar(r) {
+			return fmt.Errorf("name '%s' contains invalid character %q", name, r)
+		}
+	}
+	return nil
+}
+
+// reservedNameReason returns why GitHub rejects a name that is otherwise
+// well-formed: the reserved GITHUB_ prefix or a leading digit. It returns ""
+// for any other name.
+func reservedNameReason(name string) string {
+	switch {
+	case strings.HasPrefix(strings.ToUpper(name), "GITHUB_"):
+		return "name starts with the reserved GITHUB_ prefix"
+	case name != "" && name[0] >= '0' && name[0] <= '9':
+		return "name starts with a number"
+	}
+	return ""
+}
+
+// isNameChar reports whether r is allowed in a variable name
+func isNameChar(r rune) bool {
+	return r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
+}
+
+// nameLabel formats a variable name for log output, showing the rename as
+// "SOURCE_NAME → TARGET_NAME" when the target name differs.
+func nameLabel(sourceName, targetName string) string {
+	if sourceName == targetName {
+		return sourceName
+	}
+	return sourceName + " → " + targetName
+}
@@ -0,0 +1,255 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// TestTargetVariable_PrefixAndSuffix verifies combined prefix and suffix
+// transformations leave the value untouched
+func TestTargetVariable_PrefixAndSuffix(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		suffix string
+		want   string
+	}{
+		{"no transform", "", "", "API_URL"},
+		{"prefix only", "NEWORG_", "", "NEWORG_API_URL"},
+		{"suffix only", "", "_V2", "API_URL_V2"},
+		{"prefix and suffix", "NEWORG_", "_V2", "NEWORG_API_URL_V2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{config: &types.MigrationConfig{TargetPrefix: tt.prefix, TargetSuffix: tt.suffix}}
+			src := types.Variable{Name: "API_URL", Value: "https://example.com", Visibility: "private"}
+
+			got, err := m.targetVariable(src)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("Expected name %q, got %q", tt.want, got.Name)
+			}
+			if got.Value != src.Value || got.Visibility != src.Visibility {
+				t.Errorf("Expected value and visibility to be preserved, got %+v", got)
+			}
+			if src.Name != "API_URL" {
+				t.Errorf("Source variable must not be modified, got %q", src.Name)
+			}
+		})
+	}
+}
+
+// TestTargetVariable_LimitViolations verifies that transformed names breaking
+// GitHub's rules are rejected
+func TestTargetVariable_LimitViolations(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		suffix  string
+		varName string
+	}{
+		{"too long", strings.Repeat("P", maxVariableNameLength), "", "X"},
+		{"reserved prefix", "GITHUB_", "", "TOKEN"},
+		{"reserved prefix lowercase", "github_", "", "TOKEN"},
+		{"leading number", "1_", "", "VAR"},
+		{"invalid character", "NEW-", "", "VAR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{config: &types.MigrationConfig{TargetPrefix: tt.prefix, TargetSuffix: tt.suffix}}
+			if _, err := m.targetVariable(types.Variable{Name: tt.varName}); err == nil {
+				t.Error("Expected error for invalid target name, got nil")
+			}
+		})
+	}
+}
+
+// TestValidateVariableName verifies GitHub's naming rules
+func TestValidateVariableName(t *testing.T) {
+	tests := []struct {
+		name    string
+		varName string
+		wantErr bool
+	}{
+		{"valid upper", "API_URL", false},
+		{"valid lower", "api_url", false},
+		{"valid with digits", "V2_URL", false},
+		{"valid leading underscore", "_PRIVATE", false},
+		{"max length", strings.Repeat("A", maxVariableNameLength), false},
+		{"empty", "", true},
+		{"over max length", strings.Repeat("A", maxVariableNameLength+1), true},
+		{"reserved prefix", "GITHUB_SHA", true},
+		{"leading number", "1VAR", true},
+		{"space", "MY VAR", true},
+		{"dash", "MY-VAR", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateVariableName(tt.varName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateVariableName(%q) error = %v, wantErr %v", tt.varName, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestNameLabel verifies rename formatting in log output
+func TestNameLabel(t *testing.T) {
+	if got := nameLabel("API_URL", "API_URL"); got != "API_URL" {
+		t.Errorf("Expected unchanged label, got %q", got)
+	}
+	if got := nameLabel("API_URL", "NEWORG_API_URL"); got != "API_URL → NEWORG_API_URL" {
+		t.Errorf("Expected rename label, got %q", got)
+	}
+}
+
+// TestTargetVariable_NameMap verifies renames are applied case-insensitively
+// before the prefix and suffix, and unmapped names pass through
+func TestTargetVariable_NameMap(t *testing.T) {
+	m := &Migrator{
+		config:  &types.MigrationConfig{TargetPrefix: "NEW_"},
+		nameMap: newNameMap(map[string]string{"old_db_host": "DATABASE_HOST"}),
+	}
+
+	got, err := m.targetVariable(types.Variable{Name: "OLD_DB_HOST", Value: "db.internal"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.Name != "NEW_DATABASE_HOST" || got.Value != "db.internal" {
+		t.Errorf("Unexpected mapped variable: %+v", got)
+	}
+
+	got, err = m.targetVariable(types.Variable{Name: "REGION"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.Name != "NEW_REGION" {
+		t.Errorf("Expected unmapped name to pass through, got %q", got.Name)
+	}
+}
+
+// TestCheckNameCollisions verifies that two source variables landing on the
+// same target name are detected before any writes
+func TestCheckNameCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		nameMap map[string]string
+		prefix  string
+		vars    []string
+		wantErr string
+	}{
+		{name: "no mapping", vars: []string{"A", "B"}},
+		{name: "distinct renames", nameMap: map[string]string{"A": "X", "B": "Y"}, vars: []string{"A", "B"}},
+		{name: "rename onto passthrough name", nameMap: map[string]string{"A": "B"}, vars: []string{"A", "B"}, wantErr: "B ← A, B"},
+		{name: "rename onto passthrough name differing in case", nameMap: map[string]string{"A": "b"}, vars: []string{"A", "B"}, wantErr: "B ← A, B"},
+		{name: "rename onto absent name", nameMap: map[string]string{"A": "B"}, vars: []string{"A", "C"}},
+		{name: "prefix with names differing in case", prefix: "NEW_", vars: []string{"Api_Key", "API_KEY", "REGION"}, wantErr: "NEW_API_KEY ← Api_Key, API_KEY"},
+		{name: "prefix with rename onto prefixed name", nameMap: map[string]string{"OLD": "KEY"}, prefix: "NEW_", vars: []string{"OLD", "key"}, wantErr: "NEW_KEY ← OLD, key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, allow := range []bool{false, true} {
+				m := &Migrator{
+					config:  &types.MigrationConfig{TargetPrefix: tt.prefix, AllowCollisions: allow},
+					nameMap: newNameMap(tt.nameMap),
+				}
+				vars := make([]types.Variable, len(tt.vars))
+				for i, n := range tt.vars {
+					vars[i] = types.Variable{Name: n}
+				}
+
+				err := m.checkNameCollisions(vars)
+				switch {
+				case allow && err != nil:
+					t.Errorf("checkNameCollisions() with AllowCollisions unexpected error: %v", err)
+				case allow:
+				case tt.wantErr == "" && err != nil:
+					t.Errorf("checkNameCollisions() unexpected error: %v", err)
+				case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+					t.Errorf("checkNameCollisions() error = %v, want it to list %q", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+// TestMigrateRepoToRepo_PrefixCollision verifies that a collision on the
+// final, prefixed names stops the migration before any write, and that with
+// AllowCollisions the last source variable wins. GitHub, like the fake,
+// cannot hold two names differing only in case, so the collision comes from
+// a rename.
+func TestMigrateRepoToRepo_PrefixCollision(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		fake := newFakeGitHub()
+		fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "API_KEY", Value: "first"})
+		fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "OLD_KEY", Value: "second"})
+
+		cfg := repoToRepoConfig()
+		cfg.SkipEnvs = true
+		cfg.NameMap = map[string]string{"OLD_KEY": "api_key"}
+		cfg.TargetPrefix = "NEW_"
+		cfg.AllowCollisions = allow
+		var result *types.MigrationResult
+		var err error
+		out := captureStdout(t, func() {
+			result, err = newFakeMigrator(t, cfg, fake).Run()
+		})
+
+		if !allow {
+			if err == nil || !strings.Contains(err.Error(), "NEW_API_KEY ← API_KEY, OLD_KEY") {
+				t.Errorf("Expected a collision error listing both sources, got: %v", err)
+			}
+			if len(fake.vars[repoVarsPath("dst", "app")]) != 0 {
+				t.Error("Nothing may be written after a collision")
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Run() with AllowCollisions unexpected error: %v", err)
+		}
+		if !strings.Contains(out, "Target name collision NEW_API_KEY ← API_KEY, OLD_KEY: last writer wins") {
+			t.Errorf("Expected the collision to be logged, got:\n%s", out)
+		}
+		if v, _ := fake.getVar(repoVarsPath("dst", "app"), "NEW_API_KEY"); v.Value != "second" {
+			t.Errorf("Expected the last source variable to win, got %q", v.Value)
+		}
+		if result.Created != 1 || result.Updated != 1 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	}
+}
+
+// TestNameMap_FiltersUseSourceNames verifies that include/exclude filters are
+// evaluated against source names, not mapped target names
+func TestNameMap_FiltersUseSourceNames(t *testing.T) {
+	m := &Migrator{
+		config: &types.MigrationConfig{
+			Include: []string{"OLD_*"},
+			Exclude: []string{"DATABASE_*"},
+		},
+		nameMap: newNameMap(map[string]string{"OLD_DB_HOST": "DATABASE_HOST"}),
+	}
+	result := &types.MigrationResult{}
+
+	kept := m.filterVariables([]types.Variable{{Name: "OLD_DB_HOST"}, {Name: "DATABASE_PORT"}}, result)
+
+	if len(kept) != 1 || kept[0].Name != "OLD_DB_HOST" {
+		t.Fatalf("Expected only OLD_DB_HOST to pass the filters, got %v", kept)
+	}
+	target, err := m.targetVariable(kept[0])
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if target.Name != "DATABASE_HOST" {
+		t.Errorf("Expected mapped name DATABASE_HOST, got %q", target.Name)
+	}
+}
@@ -0,0 +1,74 @@
+package migrator
+
+import (
+	"fmt"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// migrateOrgToRepo copies organization variables into a repository as
+// repository variables
+func (m *Migrator) migrateOrgToRepo() (*types.MigrationResult, error) {
+	result := m.newResult()
+
+	// Check rate limit before starting the API-intensive migration
+	m.sourceClient.WaitForRateLimit()
+
+	logger.Info("Fetching variables from source organization: %s", m.config.SourceOrg)
+
+	sourceVars, err := m.sourceClient.ListOrgVariables(m.config.SourceOrg)
+	if err != nil {
+		return result, fmt.Errorf("failed to list source organization variables: %w", err)
+	}
+
+	logger.Info("Found %d variable(s) in source organization", len(sourceVars))
+
+	sourceVars = m.filterVariables(sourceVars, result)
+	result.Add(&result.Selected, len(sourceVars))
+	if err := m.checkNameCollisions(sourceVars); err != nil {
+		return result, err
+	}
+	sourceVars, err = m.checkLimits(scopeRepo, sourceVars, maxRepoVariables, result)
+	if err != nil {
+		return result, err
+	}
+
+	for _, variable := range sourceVars {
+		if variable.Visibility == "selected" {
+			m.warnIfNoSelectedRepos(variable.Name)
+		}
+	}
+
+	// Visibility does not exist at repository level, so it is dropped
+	if err := m.migrateRepoVariables(orgToRepoVariables(sourceVars), result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// warnIfNoSelectedRepos warns when a "selected"-visibility organization
+// variable is not shared with any repository. Its value is copied anyway.
+func (m *Migrator) warnIfNoSelectedRepos(varName string) {
+	repos, err := m.sourceClient.ListOrgVariableSelectedRepos(m.config.SourceOrg, varName)
+	if err != nil {
+		logger.Warning("Failed to list selected repositories for variable '%s': %v", varName, err)
+		return
+	}
+	if len(repos) == 0 {
+		logger.Warning("Variable '%s' has 'selected' visibility but no repositories are selected in the source organization; copying its value anyway", varName)
+	}
+}
+
+// orgToRepoVariables converts organization variables to repository
+// variables by dropping their visibility and repository selection
+func orgToRepoVariables(vars []types.Variable) []types.Variable {
+	out := make([]types.Variable, len(vars))
+	for i, v := range vars {
+		v.Visibility = ""
+		v.SelectedRepositoryIDs = nil
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func orgToRepoConfig() *types.MigrationConfig {
+	return &types.MigrationConfig{
+		Mode:        types.ModeOrgToRepo,
+		SourceOrg:   "src",
+		TargetOwner: "dst",
+		TargetRepo:  "app",
+	}
+}
+
+func TestOrgToRepoVariables(t *testing.T) {
+	in := []types.Variable{
+		{Name: "A", Value: "1", Visibility: "all"},
+		{Name: "B", Value: "2", Visibility: "selected", SelectedRepositoryIDs: []int64{1, 2}},
+	}
+	want := []types.Variable{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}
+
+	if got := orgToRepoVariables(in); !reflect.DeepEqual(got, want) {
+		t.Errorf("orgToRepoVariables() = %+v, want %+v", got, want)
+	}
+	if in[1].Visibility != "selected" {
+		t.Error("orgToRepoVariables() must not modify its input")
+	}
+}
+
+func TestMigrateOrgToRepo(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "A", Value: "1", Visibility: "all"})
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "B", Value: "2", Visibility: "selected"})
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "SKIP_ME", Value: "3", Visibility: "private"})
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "A", Value: "old"})
+
+	cfg := orgToRepoConfig()
+	cfg.Exclude = []string{"SKIP_*"}
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 1 || result.Updated != 1 || result.Filtered != 1 || result.HasErrors() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	a, _ := fake.getVar(repoVarsPath("dst", "app"), "A")
+	b, ok := fake.getVar(repoVarsPath("dst", "app"), "B")
+	if a.Value != "1" || !ok || b.Value != "2" || b.Visibility != "" {
+		t.Errorf("Unexpected target variables: A=%+v B=%+v", a, b)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "app"), "SKIP_ME"); ok {
+		t.Error("Excluded variable must not be copied")
+	}
+	// B has no selected repositories, so the selection was checked.
+	if got := fake.countCalls("GET orgs/src/actions/variables/B/repositories"); got != 1 {
+		t.Errorf("Expected the selection of B to be checked once, got %d", got)
+	}
+}
+
+func TestMigrateOrgToRepo_DryRunAndSkip(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "A", Value: "1", Visibility: "all"})
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "B", Value: "2", Visibility: "all"})
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "A", Value: "old"})
+
+	cfg := orgToRepoConfig()
+	cfg.DryRun = true
+	cfg.OnConflict = types.ConflictSkip
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 1 || result.Skipped != 1 || result.Updated != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "app"), "B"); ok {
+		t.Error("Dry-run must not create variables")
+	}
+}
+
+func TestDiffOrgToRepo(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "A", Value: "1", Visibility: "private"})
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "A", Value: "1"})
+
+	diff, err := newFakeMigrator(t, orgToRepoConfig(), fake).Diff()
+	if err != nil {
+		t.Fatalf("Diff() unexpected error: %v", err)
+	}
+	if diff.HasDifferences() {
+		t.Errorf("Expected no differences once visibility is dropped, got %+v", diff.Entries)
+	}
+}
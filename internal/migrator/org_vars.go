@@ -2,6 +2,7 @@ package migrator
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/renan-alm/gh-vars-migrator/internal/logger"
 	"github.com/renan-alm/gh-vars-migrator/internal/types"
@@ -9,7 +10,7 @@ import (
 
 // migrateOrgToOrg handles organization-to-organization variable migration
 func (m *Migrator) migrateOrgToOrg() (*types.MigrationResult, error) {
-	result := &types.MigrationResult{}
+	result := m.newResult()
 
 	// Check rate limit before starting the API-intensive migration
 	m.sourceClient.WaitForRateLimit()
@@ -24,40 +25,115 @@ func (m *Migrator) migrateOrgToOrg() (*types.MigrationResult, error) {
 
 	logger.Info("Found %d variable(s) in source organization", len(sourceVars))
 
-	// Migrate each variable, preserving source visibility
+	sourceVars = m.filterVariables(sourceVars, result)
+	result.Add(&result.Selected, len(sourceVars))
+	if err := m.checkNameCollisions(sourceVars); err != nil {
+		return result, err
+	}
+	sourceVars, err = m.checkLimits(scopeOrg, sourceVars, maxOrgVariables, result)
+	if err != nil {
+		return result, err
+	}
+
+	if m.config.Visibility != "" {
+		logger.Info("Overriding source visibility with '%s' (--visibility)", m.config.Visibility)
+	}
+
+	// Migrate each variable, preserving source visibility unless overridden
 	for _, variable := range sourceVars {
-		if variable.Visibility == "" {
-			variable.Visibility = "all"
+		if m.stopped() {
+			break
 		}
+		sourceVisibility := variable.Visibility
+		variable.Visibility = m.orgVisibility(variable)
 
 		// For "selected" visibility, resolve the repository selection from source
 		// and match by name in the target organisation.
 		if variable.Visibility == "selected" {
-			selectedIDs, err := m.resolveSelectedRepos(variable.Name)
-			if err != nil {
-				logger.Warning("Failed to resolve selected repositories for variable '%s': %v; migrating with empty repository list", variable.Name, err)
+			var selectedIDs []int64
+			if sourceVisibility == "selected" {
+				var err error
+				selectedIDs, err = m.resolveSelectedRepos(variable.Name)
+				if err != nil {
+					logger.Warning("Failed to resolve selected repositories for variable '%s': %v; migrating with empty repository list", variable.Name, err)
+				}
 			}
 			variable.SelectedRepositoryIDs = selectedIDs
 
+			if len(selectedIDs) == 0 && m.config.Visibility == "selected" && m.config.SelectedFallback == "" {
+				err := fmt.Errorf("--visibility selected needs repositories to share the variable with, but none could be resolved in target organization '%s'; "+
+					"use --visibility all or private for it, choose a --selected-fallback, or leave it out with --exclude", m.config.TargetOrg)
+				logger.VariableError(variable.Name, "Failed to migrate variable '%s': %v", variable.Name, err)
+				recordFailed(scopeOrg, variable.Name, err, result)
+				m.addError(result, fmt.Errorf("variable '%s': %w", variable.Name, err))
+				continue
+			}
 			if len(selectedIDs) == 0 {
-				logger.Warning("Variable '%s' has 'selected' visibility but no matching repositories were found in target organization '%s'; it will be created with zero selected repositories", variable.Name, m.config.TargetOrg)
+				if !m.applySelectedFallback(&variable, result) {
+					continue
+				}
 			} else {
-				logger.Info("Variable '%s': matched %d repository(ies) by name in target organization", variable.Name, len(selectedIDs))
+				logger.Debug("Variable '%s': matched %d repository(ies) by name in target organization", variable.Name, len(selectedIDs))
 			}
 		}
 
 		if err := m.migrateOrgVariable(variable, result); err != nil {
-			logger.Error("Failed to migrate variable '%s': %v", variable.Name, err)
-			result.AddError(fmt.Errorf("variable '%s': %w", variable.Name, err))
+			logger.VariableError(variable.Name, "Failed to migrate variable '%s': %v", variable.Name, err)
+			recordFailed(scopeOrg, variable.Name, err, result)
+			m.addError(result, fmt.Errorf("variable '%s': %w", variable.Name, err))
 		}
 	}
 
 	return result, nil
 }
 
+// orgVisibility returns the visibility an organization variable gets in the
+// target: the --visibility override, else the source visibility ("all" when
+// unset)
+func (m *Migrator) orgVisibility(v types.Variable) string {
+	switch {
+	case m.config.Visibility != "":
+		return m.config.Visibility
+	case v.Visibility == "":
+		return "all"
+	default:
+		return v.Visibility
+	}
+}
+
+// applySelectedFallback handles a "selected" variable that matched no
+// repository in the target organization according to --selected-fallback.
+// It returns false when the variable is skipped.
+func (m *Migrator) applySelectedFallback(variable *types.Variable, result *types.MigrationResult) bool {
+	prefix := ""
+	if m.config.DryRun {
+		prefix = "[DRY-RUN] "
+	}
+	fallback := m.config.SelectedFallbackOrDefault()
+	result.Add(&result.SelectedFallbacks, 1)
+
+	switch fallback {
+	case types.FallbackSkip:
+		logger.Warning("%sSkipping variable '%s': 'selected' visibility but no matching repositories in target organization '%s' (--selected-fallback skip)",
+			prefix, variable.Name, m.config.TargetOrg)
+		recordSkipped(scopeOrg, variable.Name, "no matching repositories in target (--selected-fallback skip)", result)
+		return false
+	case types.FallbackPrivate, types.FallbackAll:
+		logger.Warning("%sVariable '%s' has 'selected' visibility but no matching repositories in target organization '%s'; using '%s' visibility instead (--selected-fallback %s)",
+			prefix, variable.Name, m.config.TargetOrg, fallback, fallback)
+		variable.Visibility = string(fallback)
+		variable.SelectedRepositoryIDs = nil
+	default:
+		logger.Warning("%sVariable '%s' has 'selected' visibility but no matching repositories were found in target organization '%s'; it will be created with zero selected repositories (--selected-fallback empty)",
+			prefix, variable.Name, m.config.TargetOrg)
+	}
+	return true
+}
+
 // resolveSelectedRepos fetches the selected repositories for a source variable
-// and looks up repositories with matching names in the target organisation.
-// Returns the target repository IDs for any names that match.
+// and looks up the corresponding repositories in the target organisation:
+// the --repo-map entry when there is one, else the same name. Returns the
+// target repository IDs for any that match.
 func (m *Migrator) resolveSelectedRepos(varName string) ([]int64, error) {
 	sourceRepos, err := m.sourceClient.ListOrgVariableSelectedRepos(m.config.SourceOrg, varName)
 	if err != nil {
@@ -70,12 +146,23 @@ func (m *Migrator) resolveSelectedRepos(varName string) ([]int64, error) {
 
 	var targetIDs []int64
 	for _, srcRepo := range sourceRepos {
-		targetRepo, err := m.targetClient.GetRepo(m.config.TargetOrg, srcRepo.Name)
+		name := srcRepo.Name
+		mapped, ok := m.repoMap[strings.ToUpper(srcRepo.Name)]
+		if ok {
+			logger.Debug("Repository '%s' mapped to '%s' (--repo-map)", srcRepo.Name, mapped)
+			name = mapped
+		}
+
+		targetRepo, err := m.targetClient.GetRepo(m.config.TargetOrg, name)
 		if err != nil {
-			logger.Debug("Repository '%s' not found in target organization '%s': %v", srcRepo.Name, m.config.TargetOrg, err)
+			if ok {
+				logger.Warning("Repository '%s' (mapped from '%s') not found in target organization '%s'", name, srcRepo.Name, m.config.TargetOrg)
+			} else {
+				logger.Debug("Repository '%s' not found in target organization '%s': %v", name, m.config.TargetOrg, err)
+			}
 			continue
 		}
-		logger.Debug("Matched repository '%s' (source ID %d -> target ID %d)", srcRepo.Name, srcRepo.ID, targetRepo.ID)
+		logger.Debug("Matched repository '%s' as '%s' (source ID %d -> target ID %d)", srcRepo.Name, name, srcRepo.ID, targetRepo.ID)
 		targetIDs = append(targetIDs, targetRepo.ID)
 	}
 
@@ -84,45 +171,70 @@ func (m *Migrator) resolveSelectedRepos(varName string) ([]int64, error) {
 
 // migrateOrgVariable migrates a single organization variable
 func (m *Migrator) migrateOrgVariable(variable types.Variable, result *types.MigrationResult) error {
+	if ok, err := m.checkPlan(scopeOrg, variable, result); err != nil || !ok {
+		return err
+	}
+	target, err := m.targetVariable(variable)
+	if err != nil {
+		return err
+	}
+	label := nameLabel(variable.Name, target.Name)
+	note := m.valueNote(variable)
+
 	// Check if variable exists in target using target client
-	existingVar, err := m.targetClient.GetOrgVariable(m.config.TargetOrg, variable.Name)
+	existingVar, err := m.targetClient.GetOrgVariable(m.config.TargetOrg, target.Name)
 
 	if err == nil && existingVar != nil {
-		// Variable exists in target
-		if m.config.SkipOverwrite {
-			logger.Warning("Variable '%s' already exists in target, overwrite skipped (--skip-overwrite)", variable.Name)
-			result.Skipped++
+		if m.isUnchanged(target, existingVar) {
+			logger.Detail("Variable '%s' is unchanged in target, update skipped", label)
+			recordUnchanged(scopeOrg, variable.Name, result)
 			return nil
 		}
 
+		// Variable exists in target
+		overwrite, err := m.resolveConflict("Variable", scopeOrg, variable.Name, label, result)
+		if err != nil || !overwrite {
+			return err
+		}
+
 		// Update existing variable using target client
 		if m.config.DryRun {
-			logger.Info("[DRY-RUN] Would update variable: %s", variable.Name)
-			result.Updated++
+			logger.Detail("[DRY-RUN] Would update variable: %s%s%s", label, m.valueChange(target, existingVar), note)
+			m.recordUpdated(scopeOrg, variable, result)
 			return nil
 		}
 
-		if err := m.targetClient.UpdateOrgVariable(m.config.TargetOrg, variable); err != nil {
+		if ok, err := m.confirmWrite("Update", scopeOrg, variable.Name, label, result); err != nil || !ok {
+			return err
+		}
+
+		if err := m.targetClient.UpdateOrgVariable(m.config.TargetOrg, target); err != nil {
 			return fmt.Errorf("failed to update: %w", err)
 		}
 
-		logger.Success("Updated variable: %s", variable.Name)
-		result.Updated++
+		logger.DetailSuccess("Updated variable: %s%s", label, note)
+		m.recordWritten(scopeOrg, target)
+		m.recordUpdated(scopeOrg, variable, result)
 		return nil
 	}
 
 	// Create new variable using target client
 	if m.config.DryRun {
-		logger.Info("[DRY-RUN] Would create variable: %s", variable.Name)
-		result.Created++
+		logger.Detail("[DRY-RUN] Would create variable: %s%s", label, note)
+		m.recordCreated(scopeOrg, variable, result)
 		return nil
 	}
 
-	if err := m.targetClient.CreateOrgVariable(m.config.TargetOrg, variable); err != nil {
+	if ok, err := m.confirmWrite("Create", scopeOrg, variable.Name, label, result); err != nil || !ok {
+		return err
+	}
+
+	if err := m.targetClient.CreateOrgVariable(m.config.TargetOrg, target); err != nil {
 		return fmt.Errorf("failed to create: %w", err)
 	}
 
-	logger.Success("Created variable: %s", variable.Name)
-	result.Created++
+	logger.DetailSuccess("Created variable: %s%s", label, note)
+	m.recordWritten(scopeOrg, target)
+	m.recordCreated(scopeOrg, variable, result)
 	return nil
 }
@@ -0,0 +1,199 @@
+package migrator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func orgToOrgConfig() *types.MigrationConfig {
+	return &types.MigrationConfig{
+		Mode:      types.ModeOrgToOrg,
+		SourceOrg: "src",
+		TargetOrg: "dst",
+	}
+}
+
+// seedOrgFake returns a fake with the source organization variables A (all),
+// B (private), and C (selected, shared with api and web). Only api exists
+// in the target organization.
+func seedOrgFake() *fakeGitHub {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "A", Value: "1", Visibility: "all"})
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "B", Value: "2", Visibility: "private"})
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "C", Value: "3", Visibility: "selected"})
+	fake.selected["src/C"] = []types.Repository{
+		{ID: fake.addRepo("src", "api"), Name: "api"},
+		{ID: fake.addRepo("src", "web"), Name: "web"},
+	}
+	fake.addRepo("dst", "api")
+	return fake
+}
+
+func TestMigrateOrgToOrg_VisibilityOverride(t *testing.T) {
+	tests := []struct {
+		visibility string
+		want       map[string]string
+		wantErrs   int
+	}{
+		{visibility: "", want: map[string]string{"A": "all", "B": "private", "C": "selected"}},
+		{visibility: "all", want: map[string]string{"A": "all", "B": "all", "C": "all"}},
+		{visibility: "private", want: map[string]string{"A": "private", "B": "private", "C": "private"}},
+		// A and B have no repositories to share with, so only C is migrated.
+		{visibility: "selected", want: map[string]string{"C": "selected"}, wantErrs: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run("visibility "+tt.visibility, func(t *testing.T) {
+			fake := seedOrgFake()
+			cfg := orgToOrgConfig()
+			cfg.Visibility = tt.visibility
+
+			result, err := newFakeMigrator(t, cfg, fake).Run()
+			if err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			if len(result.Errors) != tt.wantErrs {
+				t.Errorf("Expected %d error(s), got %v", tt.wantErrs, result.Errors)
+			}
+
+			got := map[string]string{}
+			for _, v := range fake.vars[orgVarsPath("dst")] {
+				got[v.Name] = v.Visibility
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Target visibilities = %v, want %v", got, tt.want)
+			}
+			if v, ok := fake.getVar(orgVarsPath("dst"), "C"); ok && v.Visibility == "selected" {
+				if !reflect.DeepEqual(v.SelectedRepositoryIDs, []int64{3}) {
+					t.Errorf("Expected C to be shared with the target api repository, got %v", v.SelectedRepositoryIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateOrgToOrg_SelectedWithoutRepos(t *testing.T) {
+	fake := seedOrgFake()
+	fake.selected["src/C"] = []types.Repository{{ID: 2, Name: "web"}}
+
+	cfg := orgToOrgConfig()
+	cfg.Visibility = "selected"
+	cfg.Vars = []string{"C"}
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Error(), "use --visibility all or private") {
+		t.Fatalf("Expected an error with guidance, got %v", result.Errors)
+	}
+	if _, ok := fake.getVar(orgVarsPath("dst"), "C"); ok {
+		t.Error("A variable without resolvable repositories must not be created")
+	}
+}
+
+func TestMigrateOrgToOrg_SelectedFallback(t *testing.T) {
+	tests := []struct {
+		fallback       types.SelectedFallback
+		wantVisibility string // empty when C must not be created
+		wantSkipped    int
+		wantOutput     string
+	}{
+		{fallback: "", wantVisibility: "selected", wantOutput: "zero selected repositories (--selected-fallback empty)"},
+		{fallback: types.FallbackEmpty, wantVisibility: "selected", wantOutput: "zero selected repositories (--selected-fallback empty)"},
+		{fallback: types.FallbackPrivate, wantVisibility: "private", wantOutput: "using 'private' visibility instead"},
+		{fallback: types.FallbackAll, wantVisibility: "all", wantOutput: "using 'all' visibility instead"},
+		{fallback: types.FallbackSkip, wantSkipped: 1, wantOutput: "Skipping variable 'C'"},
+	}
+
+	for _, tt := range tests {
+		t.Run("fallback "+string(tt.fallback), func(t *testing.T) {
+			fake := seedOrgFake()
+			fake.selected["src/C"] = []types.Repository{{ID: 2, Name: "web"}}
+
+			cfg := orgToOrgConfig()
+			cfg.SelectedFallback = tt.fallback
+			var result *types.MigrationResult
+			out := captureStdout(t, func() {
+				var err error
+				result, err = newFakeMigrator(t, cfg, fake).Run()
+				if err != nil {
+					t.Errorf("Run() unexpected error: %v", err)
+				}
+			})
+			if result == nil || result.HasErrors() {
+				t.Fatalf("Unexpected result: %+v", result)
+			}
+			if result.Skipped != tt.wantSkipped || result.SelectedFallbacks != 1 {
+				t.Errorf("Skipped = %d, SelectedFallbacks = %d, want %d and 1", result.Skipped, result.SelectedFallbacks, tt.wantSkipped)
+			}
+			checkDetails(t, result)
+			if !strings.Contains(out, tt.wantOutput) {
+				t.Errorf("Expected output to contain %q, got:\n%s", tt.wantOutput, out)
+			}
+			if !strings.Contains(out, "selected-fallback="+string(cfg.SelectedFallbackOrDefault())) {
+				t.Errorf("Expected the summary to report the fallback, got:\n%s", out)
+			}
+
+			v, ok := fake.getVar(orgVarsPath("dst"), "C")
+			if tt.wantVisibility == "" {
+				if ok {
+					t.Error("A skipped variable must not be created")
+				}
+				return
+			}
+			if !ok || v.Visibility != tt.wantVisibility || len(v.SelectedRepositoryIDs) != 0 {
+				t.Errorf("Expected C with %s visibility and no repositories, got %+v (found %v)", tt.wantVisibility, v, ok)
+			}
+		})
+	}
+}
+
+func TestMigrateOrgToOrg_SelectedFallbackDryRun(t *testing.T) {
+	fake := seedOrgFake()
+	fake.selected["src/C"] = []types.Repository{{ID: 2, Name: "web"}}
+
+	cfg := orgToOrgConfig()
+	cfg.DryRun = true
+	cfg.SelectedFallback = types.FallbackPrivate
+	out := captureStdout(t, func() {
+		if _, err := newFakeMigrator(t, cfg, fake).Run(); err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "[DRY-RUN] Variable 'C' has 'selected' visibility") {
+		t.Errorf("Expected dry-run output to show the fallback, got:\n%s", out)
+	}
+	if len(fake.vars[orgVarsPath("dst")]) != 0 {
+		t.Error("Dry run must not write any variable")
+	}
+}
+
+func TestResolveSelectedRepos_RepoMap(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.selected["src/C"] = []types.Repository{
+		{ID: fake.addRepo("src", "api"), Name: "api"},
+		{ID: fake.addRepo("src", "web"), Name: "web"},
+		{ID: fake.addRepo("src", "legacy"), Name: "legacy"},
+		{ID: fake.addRepo("src", "tools"), Name: "tools"},
+	}
+	platformAPI := fake.addRepo("dst", "platform-api")
+	web := fake.addRepo("dst", "web")
+
+	cfg := orgToOrgConfig()
+	// api was renamed; web kept its name; legacy and tools (mapped to a
+	// repository that does not exist) have no counterpart.
+	cfg.RepoMap = map[string]string{"API": "platform-api", "tools": "dev-tools"}
+	ids, err := newFakeMigrator(t, cfg, fake).resolveSelectedRepos("C")
+	if err != nil {
+		t.Fatalf("resolveSelectedRepos() unexpected error: %v", err)
+	}
+
+	if want := []int64{platformAPI, web}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("resolveSelectedRepos() = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,119 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/plan"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// errDrifted prefixes the errors of planned variables whose source changed
+// after the plan was made
+const errDrifted = "drifted since plan"
+
+// plannedWrites holds the actions of the plan being applied, or the failed
+// variables being retried, keyed by scope and upper-cased name, and records
+// which of them the run reached. It is shared by the Migrators of a
+// multi-repository run.
+type plannedWrites struct {
+	actions map[string]types.PlannedAction
+	order   []string
+	reached map[string]bool
+	// retry is set for --retry-failed, whose variables have no planned
+	// value to check for drift
+	retry bool
+}
+
+// newPlannedWrites indexes the planned actions; it returns nil without a
+// plan
+func newPlannedWrites(actions []types.PlannedAction) *plannedWrites {
+	if actions == nil {
+		return nil
+	}
+	p := &plannedWrites{
+		actions: make(map[string]types.PlannedAction, len(actions)),
+		reached: make(map[string]bool, len(actions)),
+	}
+	for _, a := range actions {
+		key := planKey(a.Scope, a.Name)
+		if _, ok := p.actions[key]; !ok {
+			p.order = append(p.order, key)
+		}
+		p.actions[key] = a
+	}
+	return p
+}
+
+// newRetryWrites indexes the failed variables of a previous run; it returns
+// nil without any
+func newRetryWrites(refs []types.VariableRef) *plannedWrites {
+	if refs == nil {
+		return nil
+	}
+	actions := make([]types.PlannedAction, 0, len(refs))
+	for _, r := range refs {
+		actions = append(actions, types.PlannedAction{Scope: r.Scope, Name: r.Name})
+	}
+	p := newPlannedWrites(actions)
+	p.retry = true
+	return p
+}
+
+func planKey(scope, name string) string {
+	return scope + "\x00" + strings.ToUpper(name)
+}
+
+// checkPlan decides whether a source variable may be written to scope when
+// a plan is applied or failed variables are retried. Variables outside the
+// plan are left alone and counted as Filtered; a planned variable whose
+// source value no longer has the planned hash fails as drifted. Without a
+// plan every variable may be written.
+func (m *Migrator) checkPlan(scope string, variable types.Variable, result *types.MigrationResult) (bool, error) {
+	if m.planned == nil {
+		return true, nil
+	}
+
+	key := planKey(m.planScope+scope, variable.Name)
+	action, ok := m.planned.actions[key]
+	if !ok {
+		logger.Debug("Variable '%s' (%s) is not in the %s", variable.Name, scope, m.planned.source())
+		result.Add(&result.Filtered, 1)
+		return false, nil
+	}
+	m.planned.reached[key] = true
+
+	if !m.planned.retry && plan.HashValue(variable.Value) != action.ValueHash {
+		return false, fmt.Errorf("%s: source value changed", errDrifted)
+	}
+	return true, nil
+}
+
+// checkPlanReached fails every planned action the run did not reach, which
+// happens when the variable, its environment, or its repository is gone
+// from the source
+func (m *Migrator) checkPlanReached(result *types.MigrationResult) {
+	for _, key := range m.planned.order {
+		if m.planned.reached[key] {
+			continue
+		}
+		a := m.planned.actions[key]
+		err := fmt.Errorf("%s: no longer in source", errDrifted)
+		if m.planned.retry {
+			err = errors.New("no longer in source")
+		}
+		logger.VariableError(a.Name, "Failed to migrate variable '%s' (%s) from the %s: %v", a.Name, a.Scope, m.planned.source(), err)
+		recordFailed(a.Scope, a.Name, err, result)
+		result.AddError(fmt.Errorf("%s variable '%s': %w", a.Scope, a.Name, err))
+	}
+}
+
+// source names where the variables come from in log lines
+func (p *plannedWrites) source() string {
+	if p.retry {
+		return "retry list"
+	}
+	return "plan"
+}
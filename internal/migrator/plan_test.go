@@ -0,0 +1,228 @@
+package migrator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/plan"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// planFake seeds a source with a repository variable and an environment
+// variable, and a target where REGION already has an older value
+func planFake() *fakeGitHub {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "REGION", Value: "eu"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "TIER", Value: "gold"})
+	fake.addEnv("src", "app", "prod")
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "URL", Value: "https://prod"})
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "REGION", Value: "us"})
+	return fake
+}
+
+// makePlan runs a dry run of cfg against fake and returns its plan
+func makePlan(t *testing.T, cfg *types.MigrationConfig, fake *fakeGitHub) *plan.Plan {
+	t.Helper()
+	dry := *cfg
+	dry.DryRun = true
+	var result *types.MigrationResult
+	captureStdout(t, func() {
+		var err error
+		result, err = newFakeMigrator(t, &dry, fake).Run()
+		if err != nil {
+			t.Fatalf("Dry run unexpected error: %v", err)
+		}
+	})
+	return plan.New(&dry, result, false, time.Now())
+}
+
+// applyPlan runs cfg against fake with the actions of p
+func applyPlan(t *testing.T, cfg *types.MigrationConfig, fake *fakeGitHub, p *plan.Plan) *types.MigrationResult {
+	t.Helper()
+	if err := p.Check(cfg); err != nil {
+		t.Fatalf("Check() unexpected error: %v", err)
+	}
+	cfg.Plan = p.Actions
+	var result *types.MigrationResult
+	captureStdout(t, func() {
+		var err error
+		result, err = newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+	})
+	return result
+}
+
+// TestApplyPlan_RoundTrip verifies that a plan made by a dry run applies
+// exactly the writes the dry run reported
+func TestApplyPlan_RoundTrip(t *testing.T) {
+	fake := planFake()
+	cfg := repoToRepoConfig()
+	p := makePlan(t, cfg, fake)
+
+	want := []string{"repository REGION updated", "repository TIER created", "env:prod URL created"}
+	var got []string
+	for _, a := range p.Actions {
+		got = append(got, a.Scope+" "+a.Name+" "+string(a.Action))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Plan actions = %v, want %v", got, want)
+	}
+
+	result := applyPlan(t, cfg, fake, p)
+	if result.Created != 2 || result.Updated != 1 || len(result.Errors) != 0 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if v, _ := fake.getVar(repoVarsPath("dst", "app"), "REGION"); v.Value != "eu" {
+		t.Errorf("REGION = %q, want eu", v.Value)
+	}
+	if v, _ := fake.getVar(envVarsPath("dst", "app", "prod"), "URL"); v.Value != "https://prod" {
+		t.Errorf("URL = %q, want https://prod", v.Value)
+	}
+}
+
+// TestApplyPlan_Drift verifies that planned variables whose source value
+// changed or disappeared fail as drifted while the rest are applied
+func TestApplyPlan_Drift(t *testing.T) {
+	fake := planFake()
+	cfg := repoToRepoConfig()
+	p := makePlan(t, cfg, fake)
+
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "REGION", Value: "ap"})
+	delete(fake.vars[envVarsPath("src", "app", "prod")], "URL")
+
+	result := applyPlan(t, cfg, fake, p)
+	if result.Created != 1 || result.Updated != 0 || len(result.Errors) != 2 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	want := []string{
+		"repository REGION failed",
+		"repository TIER created",
+		"env:prod URL failed",
+	}
+	if got := detailLines(result.Details); !reflect.DeepEqual(got, want) {
+		t.Errorf("Details = %v, want %v", got, want)
+	}
+	for _, err := range result.Errors {
+		if !strings.Contains(err.Error(), "drifted since plan") {
+			t.Errorf("Expected a drift error, got: %v", err)
+		}
+	}
+	if !strings.Contains(result.Errors[0].Error(), "source value changed") || !strings.Contains(result.Errors[1].Error(), "no longer in source") {
+		t.Errorf("Unexpected drift reasons: %v", result.Errors)
+	}
+	if v, _ := fake.getVar(repoVarsPath("dst", "app"), "REGION"); v.Value != "us" {
+		t.Errorf("A drifted variable must not be written, REGION = %q", v.Value)
+	}
+}
+
+// TestApplyPlan_OnlyPlanned verifies that variables that appeared in the
+// source after the plan was made are left alone
+func TestApplyPlan_OnlyPlanned(t *testing.T) {
+	fake := planFake()
+	cfg := repoToRepoConfig()
+	p := makePlan(t, cfg, fake)
+
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "LATE", Value: "x"})
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "LATE", Value: "y"})
+
+	result := applyPlan(t, cfg, fake, p)
+	if result.Created != 2 || result.Updated != 1 || result.Filtered != 2 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "app"), "LATE"); ok {
+		t.Error("A variable outside the plan must not be written")
+	}
+	if _, ok := fake.getVar(envVarsPath("dst", "app", "prod"), "LATE"); ok {
+		t.Error("An environment variable outside the plan must not be written")
+	}
+}
+
+// TestApplyPlan_TargetMismatch verifies that a plan is rejected for another
+// target
+func TestApplyPlan_TargetMismatch(t *testing.T) {
+	fake := planFake()
+	p := makePlan(t, repoToRepoConfig(), fake)
+
+	cfg := repoToRepoConfig()
+	cfg.TargetRepo = "other"
+	err := p.Check(cfg)
+	if err == nil || !strings.Contains(err.Error(), "plan was made for target dst/app, not dst/other") {
+		t.Errorf("Check() error = %v, want a target mismatch", err)
+	}
+}
+
+// TestRetryFailed verifies that a retry writes only the variables recorded
+// as failed, each in the scope it failed in
+func TestRetryFailed(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   *types.MigrationConfig
+		seed  func() *fakeGitHub
+		retry []types.VariableRef
+		want  []string
+	}{
+		{
+			name:  "repository variable",
+			cfg:   repoToRepoConfig(),
+			seed:  planFake,
+			retry: []types.VariableRef{{Scope: "repository", Name: "TIER"}},
+			want:  []string{"repository TIER created"},
+		},
+		{
+			name:  "environment variable",
+			cfg:   repoToRepoConfig(),
+			seed:  planFake,
+			retry: []types.VariableRef{{Scope: "env:prod", Name: "url"}},
+			want:  []string{"env:prod URL created"},
+		},
+		{
+			name:  "organization variable",
+			cfg:   orgToOrgConfig(),
+			seed:  seedOrgFake,
+			retry: []types.VariableRef{{Scope: "organization", Name: "B"}},
+			want:  []string{"organization B created"},
+		},
+		{
+			name:  "repository of many targets",
+			cfg:   targetsConfig("acme/api", "acme/web"),
+			seed:  seedTemplateFake,
+			retry: []types.VariableRef{{Scope: "acme/web:env:prod", Name: "E"}, {Scope: "acme/api:repository", Name: "A"}},
+			want:  []string{"acme/api:repository A created", "acme/web:env:prod E created"},
+		},
+		{
+			name:  "variable gone from the source",
+			cfg:   repoToRepoConfig(),
+			seed:  planFake,
+			retry: []types.VariableRef{{Scope: "repository", Name: "GONE"}},
+			want:  []string{"repository GONE failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tt.seed()
+			tt.cfg.Retry = tt.retry
+			var result *types.MigrationResult
+			captureStdout(t, func() {
+				var err error
+				result, err = newFakeMigrator(t, tt.cfg, fake).Run()
+				if err != nil {
+					t.Fatalf("Run() unexpected error: %v", err)
+				}
+			})
+
+			if got := detailLines(result.Details); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Details = %v, want %v", got, tt.want)
+			}
+			for _, err := range result.Errors {
+				if msg := err.Error(); !strings.Contains(msg, "no longer in source") || strings.Contains(msg, "drifted") {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package migrator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// Normalized answers to interactive prompts
+const (
+	answerYes  = "y"
+	answerNo   = "n"
+	answerAll  = "a"
+	answerQuit = "q"
+)
+
+// ask prints question with the yes/no/all/quit choices and reads answers
+// until a valid one is given. End of input is treated as "quit" so a closed
+// stdin never hangs the run.
+func (m *Migrator) ask(question string) (string, error) {
+	out := m.promptOut()
+	for {
+		fmt.Fprintf(out, "%s [y]es/[n]o/[a]ll/[q]uit: ", question)
+		line, err := m.promptReader().ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read answer: %w", err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return answerYes, nil
+		case "n", "no":
+			return answerNo, nil
+		case "a", "all":
+			return answerAll, nil
+		case "q", "quit":
+			return answerQuit, nil
+		}
+
+		if err == io.EOF {
+			fmt.Fprintln(out)
+			return answerQuit, nil
+		}
+	}
+}
+
+// promptReader returns the buffered reader of prompt answers
+func (m *Migrator) promptReader() *bufio.Reader {
+	if m.promptIn == nil {
+		var in io.Reader = os.Stdin
+		if m.input != nil {
+			in = m.input
+		}
+		m.promptIn = bufio.NewReader(in)
+	}
+	return m.promptIn
+}
+
+// promptOut returns where prompts are written
+func (m *Migrator) promptOut() io.Writer {
+	if m.output != nil {
+		return m.output
+	}
+	return os.Stderr
+}
+
+// confirmTyped asks the user to type expected to go ahead with action, and
+// reports whether they did. Anything else, including end of input, declines.
+func (m *Migrator) confirmTyped(action, expected string) (bool, error) {
+	fmt.Fprintf(m.promptOut(), "%s. Type %s to confirm: ", action, expected)
+	line, err := m.promptReader().ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read answer: %w", err)
+	}
+	if err == io.EOF {
+		fmt.Fprintln(m.promptOut())
+	}
+	return strings.TrimSpace(line) == expected, nil
+}
+
+// confirmWrite asks for approval before a variable is created or updated
+// when --interactive is set, and reports whether to go ahead. A declined
+// variable is counted as Skipped and Declined under scope and name; "all"
+// approves every remaining variable and "quit" declines this one and stops
+// the run.
+func (m *Migrator) confirmWrite(action, scope, name, label string, result *types.MigrationResult) (bool, error) {
+	if !m.config.Interactive || m.approveAll {
+		return true, nil
+	}
+
+	answer, err := m.ask(fmt.Sprintf("%s %s = %s?", action, label, maskedValue))
+	if err != nil {
+		return false, err
+	}
+
+	switch answer {
+	case answerYes:
+		return true, nil
+	case answerAll:
+		m.approveAll = true
+		return true, nil
+	case answerQuit:
+		m.aborted = true
+	}
+
+	logger.Warning("Variable '%s' skipped: declined interactively", label)
+	recordSkipped(scope, name, "declined interactively", result)
+	result.Add(&result.Declined, 1)
+	return false, nil
+}
@@ -0,0 +1,131 @@
+package migrator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func TestAsk(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		prompts int
+	}{
+		{name: "yes", input: "y\n", want: answerYes, prompts: 1},
+		{name: "long no", input: "No\n", want: answerNo, prompts: 1},
+		{name: "all", input: "all\n", want: answerAll, prompts: 1},
+		{name: "quit", input: "q\n", want: answerQuit, prompts: 1},
+		{name: "invalid then yes", input: "x\n\ny\n", want: answerYes, prompts: 3},
+		{name: "end of input", input: "", want: answerQuit, prompts: 1},
+		{name: "answer without newline", input: "y", want: answerYes, prompts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			m := &Migrator{config: &types.MigrationConfig{}, input: strings.NewReader(tt.input), output: &out}
+
+			got, err := m.ask("Proceed?")
+			if err != nil {
+				t.Fatalf("ask() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ask() = %q, want %q", got, tt.want)
+			}
+			if n := strings.Count(out.String(), "Proceed? [y]es/[n]o/[a]ll/[q]uit: "); n != tt.prompts {
+				t.Errorf("Expected %d prompt(s), got %d: %q", tt.prompts, n, out.String())
+			}
+		})
+	}
+}
+
+func interactiveConfig() *types.MigrationConfig {
+	cfg := conflictConfig("")
+	cfg.Interactive = true
+	return cfg
+}
+
+func TestInteractive(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantCreated  int
+		wantUpdated  int
+		wantDeclined int
+		wantAborted  bool
+		wantValues   string
+	}{
+		// Variables are processed in source order: A (update), B (update), C (create).
+		{name: "approve all individually", input: "y\ny\ny\n", wantCreated: 1, wantUpdated: 2, wantValues: "new-a,new-b,new-c"},
+		{name: "decline one", input: "y\nn\ny\n", wantCreated: 1, wantUpdated: 1, wantDeclined: 1, wantValues: "new-a,old-b,new-c"},
+		{name: "all stops prompting", input: "a\n", wantCreated: 1, wantUpdated: 2, wantValues: "new-a,new-b,new-c"},
+		{name: "quit aborts", input: "y\nq\ny\n", wantUpdated: 1, wantDeclined: 1, wantAborted: true, wantValues: "new-a,old-b,"},
+		{name: "closed input aborts", input: "", wantDeclined: 1, wantAborted: true, wantValues: "old-a,old-b,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := seedConflictFake()
+			m := newFakeMigrator(t, interactiveConfig(), fake)
+			var out bytes.Buffer
+			m.input, m.output = strings.NewReader(tt.input), &out
+
+			result, err := m.Run()
+			if err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			if result.Created != tt.wantCreated || result.Updated != tt.wantUpdated ||
+				result.Declined != tt.wantDeclined || result.Skipped != tt.wantDeclined || result.Aborted != tt.wantAborted {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+			checkDetails(t, result)
+			for _, d := range result.Details {
+				if d.Action == types.ActionSkipped && d.Reason != "declined interactively" {
+					t.Errorf("Unexpected skip reason: %+v", d)
+				}
+			}
+			if got := targetValues(fake); got != tt.wantValues {
+				t.Errorf("Target values = %s, want %s", got, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestInteractive_PromptShowsActionAndMasksValue(t *testing.T) {
+	fake := seedConflictFake()
+	m := newFakeMigrator(t, interactiveConfig(), fake)
+	var out bytes.Buffer
+	m.input, m.output = strings.NewReader("a\n"), &out
+
+	if _, err := m.Run(); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	prompt := out.String()
+	if !strings.Contains(prompt, "Update A = "+maskedValue+"?") {
+		t.Errorf("Expected prompt to show the action, name, and masked value, got %q", prompt)
+	}
+	if strings.Contains(prompt, "new-a") {
+		t.Errorf("Prompt must not reveal the value: %q", prompt)
+	}
+}
+
+func TestInteractive_DryRunDoesNotPrompt(t *testing.T) {
+	fake := seedConflictFake()
+	cfg := interactiveConfig()
+	cfg.DryRun = true
+	m := newFakeMigrator(t, cfg, fake)
+	var out bytes.Buffer
+	m.input, m.output = strings.NewReader(""), &out
+
+	result, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if out.Len() != 0 || result.Created != 1 || result.Updated != 2 {
+		t.Errorf("Expected no prompts in dry-run, got output %q and result %+v", out.String(), result)
+	}
+}
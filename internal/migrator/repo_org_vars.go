@@ -0,0 +1,71 @@
+package migrator
+
+import (
+	"fmt"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// migrateRepoToOrg promotes repository variables to organization variables
+// with the configured visibility. Environment variables are not promoted.
+func (m *Migrator) migrateRepoToOrg() (*types.MigrationResult, error) {
+	result := m.newResult()
+
+	// Check rate limit before starting the API-intensive migration
+	m.sourceClient.WaitForRateLimit()
+
+	logger.Info("Fetching variables from source repository: %s/%s", m.config.SourceOwner, m.config.SourceRepo)
+
+	sourceVars, err := m.sourceClient.ListRepoVariables(m.config.SourceOwner, m.config.SourceRepo)
+	if err != nil {
+		return result, fmt.Errorf("failed to list source repository variables: %w", err)
+	}
+
+	logger.Info("Found %d variable(s) in source repository", len(sourceVars))
+
+	sourceVars = m.filterVariables(sourceVars, result)
+	result.Add(&result.Selected, len(sourceVars))
+	if err := m.checkNameCollisions(sourceVars); err != nil {
+		return result, err
+	}
+	sourceVars, err = m.checkLimits(scopeOrg, sourceVars, maxOrgVariables, result)
+	if err != nil {
+		return result, err
+	}
+
+	logger.Info("Promoting to organization %s with '%s' visibility", m.config.TargetOrg, m.promotionVisibility())
+
+	for _, variable := range m.repoToOrgVariables(sourceVars) {
+		if m.stopped() {
+			break
+		}
+		if err := m.migrateOrgVariable(variable, result); err != nil {
+			logger.VariableError(variable.Name, "Failed to migrate variable '%s': %v", variable.Name, err)
+			recordFailed(scopeOrg, variable.Name, err, result)
+			m.addError(result, fmt.Errorf("variable '%s': %w", variable.Name, err))
+		}
+	}
+
+	return result, nil
+}
+
+// promotionVisibility returns the visibility given to promoted variables
+func (m *Migrator) promotionVisibility() string {
+	if m.config.TargetVisibility == "" {
+		return "all"
+	}
+	return m.config.TargetVisibility
+}
+
+// repoToOrgVariables converts repository variables to organization
+// variables with the promotion visibility
+func (m *Migrator) repoToOrgVariables(vars []types.Variable) []types.Variable {
+	out := make([]types.Variable, len(vars))
+	for i, v := range vars {
+		v.Visibility = m.promotionVisibility()
+		v.SelectedRepositoryIDs = nil
+		out[i] = v
+	}
+	return out
+}
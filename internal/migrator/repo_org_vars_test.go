@@ -0,0 +1,103 @@
+package migrator
+
+import (
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func repoToOrgConfig() *types.MigrationConfig {
+	return &types.MigrationConfig{
+		Mode:        types.ModeRepoToOrg,
+		SourceOwner: "src",
+		SourceRepo:  "app",
+		TargetOrg:   "dst",
+	}
+}
+
+func TestMigrateRepoToOrg(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "A", Value: "1"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "B", Value: "2"})
+	fake.setVar(orgVarsPath("dst"), types.Variable{Name: "A", Value: "old", Visibility: "private"})
+	fake.addEnv("src", "app", "prod")
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "E", Value: "3"})
+
+	result, err := newFakeMigrator(t, repoToOrgConfig(), fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 1 || result.Updated != 1 || result.HasErrors() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	for name, want := range map[string]string{"A": "1", "B": "2"} {
+		v, ok := fake.getVar(orgVarsPath("dst"), name)
+		if !ok || v.Value != want || v.Visibility != "all" {
+			t.Errorf("Expected %s=%q with 'all' visibility, got %+v (found %v)", name, want, v, ok)
+		}
+	}
+	if _, ok := fake.getVar(orgVarsPath("dst"), "E"); ok {
+		t.Error("Environment variables must not be promoted")
+	}
+}
+
+func TestMigrateRepoToOrg_Visibility(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "A", Value: "1"})
+
+	cfg := repoToOrgConfig()
+	cfg.TargetVisibility = "private"
+	if _, err := newFakeMigrator(t, cfg, fake).Run(); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if v, _ := fake.getVar(orgVarsPath("dst"), "A"); v.Visibility != "private" {
+		t.Errorf("Expected 'private' visibility, got %q", v.Visibility)
+	}
+}
+
+func TestMigrateRepoToOrg_DryRunAndConflicts(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "A", Value: "1"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "B", Value: "2"})
+	fake.setVar(orgVarsPath("dst"), types.Variable{Name: "A", Value: "old", Visibility: "all"})
+
+	cfg := repoToOrgConfig()
+	cfg.DryRun = true
+	cfg.OnConflict = types.ConflictSkip
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 1 || result.Skipped != 1 || result.Updated != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if _, ok := fake.getVar(orgVarsPath("dst"), "B"); ok {
+		t.Error("Dry run must not create variables")
+	}
+
+	cfg = repoToOrgConfig()
+	cfg.OnConflict = types.ConflictFail
+	if _, err := newFakeMigrator(t, cfg, fake).Run(); err == nil {
+		t.Error("Expected --on-conflict=fail to reject the existing variable A")
+	}
+	if _, ok := fake.getVar(orgVarsPath("dst"), "B"); ok {
+		t.Error("A failed conflict check must not write anything")
+	}
+}
+
+func TestDiffRepoToOrg(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "A", Value: "1"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "B", Value: "2"})
+	fake.setVar(orgVarsPath("dst"), types.Variable{Name: "A", Value: "1", Visibility: "all"})
+	fake.setVar(orgVarsPath("dst"), types.Variable{Name: "B", Value: "2", Visibility: "private"})
+
+	diff, err := newFakeMigrator(t, repoToOrgConfig(), fake).Diff()
+	if err != nil {
+		t.Fatalf("Diff() unexpected error: %v", err)
+	}
+	if diff.Count(types.DiffUnchanged) != 1 || diff.Count(types.DiffUpdate) != 1 {
+		t.Errorf("Unexpected diff: %+v", diff.Entries)
+	}
+}
@@ -2,6 +2,7 @@ package migrator
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/renan-alm/gh-vars-migrator/internal/logger"
 	"github.com/renan-alm/gh-vars-migrator/internal/types"
@@ -9,65 +10,150 @@ import (
 
 // migrateRepoToRepo handles repository-to-repository variable migration
 func (m *Migrator) migrateRepoToRepo() (*types.MigrationResult, error) {
-	result := &types.MigrationResult{}
+	result := m.newResult()
 
 	// Check rate limit before starting the API-intensive migration
 	m.sourceClient.WaitForRateLimit()
 
-	logger.Info("Fetching variables from source repository: %s/%s", m.config.SourceOwner, m.config.SourceRepo)
+	if err := m.checkArchived(); err != nil {
+		return result, err
+	}
+	if err := m.loadUsedVars(); err != nil {
+		return result, err
+	}
 
-	// Get source repository variables using source client
-	sourceVars, err := m.sourceClient.ListRepoVariables(m.config.SourceOwner, m.config.SourceRepo)
-	if err != nil {
-		return result, fmt.Errorf("failed to list source repository variables: %w", err)
+	var sourceVars []types.Variable
+	var err error
+	if !m.config.SkipRepoVars {
+		logger.Info("Fetching variables from source repository: %s/%s", m.config.SourceOwner, m.config.SourceRepo)
+
+		// Get source repository variables using source client
+		sourceVars, err = m.sourceClient.ListRepoVariables(m.config.SourceOwner, m.config.SourceRepo)
+		if err != nil {
+			return result, fmt.Errorf("failed to list source repository variables: %w", err)
+		}
+
+		logger.Info("Found %d variable(s) in source repository", len(sourceVars))
+
+		sourceVars = m.filterVariables(sourceVars, result)
+		sourceVars = m.skipUnused(scopeRepo, sourceVars, result)
+		result.Add(&result.Selected, len(sourceVars))
+		if err := m.checkNameCollisions(sourceVars); err != nil {
+			return result, err
+		}
+		sourceVars, err = m.checkLimits(scopeRepo, sourceVars, maxRepoVariables, result)
+		if err != nil {
+			return result, err
+		}
 	}
 
-	logger.Info("Found %d variable(s) in source repository", len(sourceVars))
+	// Discover environments before writing anything, so that an unknown
+	// --envs name stops the migration up front
+	var environments []types.Environment
+	var envErr error
+	if !m.config.SkipEnvs {
+		environments, envErr = m.discoverEnvironments()
+		if envErr == nil {
+			if environments, err = m.selectEnvironments(environments, result); err != nil {
+				return result, err
+			}
+		} else if m.config.SkipRepoVars {
+			// Environments are all there is to migrate.
+			return result, envErr
+		}
+	}
 
 	// Migrate repository-level variables
-	if err := m.migrateRepoVariables(sourceVars, result); err != nil {
+	if m.config.SkipRepoVars {
+		logger.Info("Skipping repository-level variables (environments only)")
+	} else if err := m.migrateRepoVariables(sourceVars, result); err != nil {
 		return result, err
 	}
 
 	// Migrate environment variables if not skipped
-	if !m.config.SkipEnvs {
-		if err := m.migrateAllEnvironments(result); err != nil {
-			logger.Warning("Failed to migrate environments: %v", err)
-			result.AddError(fmt.Errorf("environment migration failed: %w", err))
-		}
-	} else {
+	switch {
+	case m.config.SkipEnvs:
 		logger.Info("Skipping environment variable migration (--skip-envs)")
+	case m.stopped():
+		logger.Info("Skipping environment variable migration after the run was stopped")
+	case envErr != nil:
+		logger.Warning("Failed to migrate environments: %v", envErr)
+		m.addError(result, fmt.Errorf("environment migration failed: %w", envErr))
+	default:
+		m.migrateAllEnvironments(environments, result)
 	}
 
 	return result, nil
 }
 
-// migrateAllEnvironments discovers all environments from source repo and migrates them
-func (m *Migrator) migrateAllEnvironments(result *types.MigrationResult) error {
+// checkArchived fails when the target repository is archived, since GitHub
+// rejects every write to it, and warns when the source repository is. A
+// repository that cannot be read is left for the later calls to report.
+func (m *Migrator) checkArchived() error {
+	target, err := m.targetClient.GetRepo(m.config.TargetOwner, m.config.TargetRepo)
+	if err != nil {
+		logger.Debug("Could not check whether %s/%s is archived: %v", m.config.TargetOwner, m.config.TargetRepo, err)
+	} else if target.Archived {
+		return fmt.Errorf("target repository %s/%s is archived; unarchive it or choose another target", m.config.TargetOwner, m.config.TargetRepo)
+	}
+
+	source, err := m.sourceClient.GetRepo(m.config.SourceOwner, m.config.SourceRepo)
+	if err != nil {
+		logger.Debug("Could not check whether %s/%s is archived: %v", m.config.SourceOwner, m.config.SourceRepo, err)
+	} else if source.Archived {
+		logger.Warning("Source repository %s/%s is archived; migrating its variables anyway", m.config.SourceOwner, m.config.SourceRepo)
+	}
+	return nil
+}
+
+// discoverEnvironments lists the environments of the source repository
+func (m *Migrator) discoverEnvironments() ([]types.Environment, error) {
 	logger.Info("Discovering environments from source repository: %s/%s", m.config.SourceOwner, m.config.SourceRepo)
 
 	// List all environments from source repository using source client
 	environments, err := m.sourceClient.ListEnvironments(m.config.SourceOwner, m.config.SourceRepo)
 	if err != nil {
-		return fmt.Errorf("failed to list environments: %w", err)
+		return nil, fmt.Errorf("failed to list environments: %w", err)
 	}
+	return environments, nil
+}
 
+// SourceEnvironments lists the source environments a repository migration
+// would migrate after --skip-envs, --envs, and --exclude-envs, without
+// reading their variables
+func (m *Migrator) SourceEnvironments() ([]types.Environment, error) {
+	if m.config.SkipEnvs {
+		return nil, nil
+	}
+	environments, err := m.discoverEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	return m.selectEnvironments(environments, &types.MigrationResult{})
+}
+
+// migrateAllEnvironments migrates the given source environments in turn
+func (m *Migrator) migrateAllEnvironments(environments []types.Environment, result *types.MigrationResult) {
 	if len(environments) == 0 {
-		logger.Info("No environments found in source repository")
-		return nil
+		logger.Info("No environments to migrate in source repository")
+		return
 	}
 
 	logger.Info("Found %d environment(s): %v", len(environments), getEnvNames(environments))
+	m.warnEnvCollisions(environments)
 
 	// Migrate each environment
 	for _, env := range environments {
+		if m.stopped() {
+			break
+		}
 		if err := m.migrateEnvironment(env.Name, result); err != nil {
 			logger.Error("Failed to migrate environment '%s': %v", env.Name, err)
-			result.AddError(fmt.Errorf("environment '%s': %w", env.Name, err))
+			m.addError(result, fmt.Errorf("environment '%s': %w", env.Name, err))
+			continue
 		}
+		result.AddEnvironment(env.Name)
 	}
-
-	return nil
 }
 
 // getEnvNames extracts environment names for logging
@@ -79,12 +165,46 @@ func getEnvNames(envs []types.Environment) []string {
 	return names
 }
 
-// migrateEnvironment migrates a single environment and its variables
+// warnEnvCollisions warns when --env-map sends several source environments
+// to the same target environment, where same-named variables overwrite each
+// other
+func (m *Migrator) warnEnvCollisions(environments []types.Environment) {
+	if m.envMap == nil {
+		return
+	}
+	sources := make(map[string][]string)
+	var targets []string
+	for _, env := range environments {
+		target := m.targetEnvName(env.Name)
+		key := strings.ToUpper(target)
+		if sources[key] == nil {
+			targets = append(targets, target)
+		}
+		sources[key] = append(sources[key], env.Name)
+	}
+	for _, target := range targets {
+		if names := sources[strings.ToUpper(target)]; len(names) > 1 {
+			logger.Warning("Environments %s all map to target environment '%s'; variables with the same name will overwrite each other",
+				strings.Join(names, ", "), target)
+		}
+	}
+}
+
+// migrateEnvironment migrates a single environment and its variables into
+// the target environment chosen by --env-map
 func (m *Migrator) migrateEnvironment(envName string, result *types.MigrationResult) error {
-	logger.Info("Migrating environment: %s", envName)
+	targetEnv := m.targetEnvName(envName)
+	switch {
+	case targetEnv == envName:
+		logger.Info("Migrating environment: %s", envName)
+	case m.config.DryRun:
+		logger.Info("[DRY-RUN] Would migrate env %s → %s", envName, targetEnv)
+	default:
+		logger.Info("Migrating env %s → %s", envName, targetEnv)
+	}
 
 	// Check if environment exists in target, create if not
-	if err := m.ensureEnvironmentExists(envName); err != nil {
+	if err := m.ensureEnvironmentExists(envName, targetEnv); err != nil {
 		return fmt.Errorf("failed to ensure environment exists: %w", err)
 	}
 
@@ -96,26 +216,49 @@ func (m *Migrator) migrateEnvironment(envName string, result *types.MigrationRes
 
 	logger.Info("Found %d variable(s) in environment '%s'", len(sourceEnvVars), envName)
 
+	sourceEnvVars = m.filterVariables(sourceEnvVars, result)
+	sourceEnvVars = m.skipUnused(envScope(targetEnv), sourceEnvVars, result)
+	result.Add(&result.Selected, len(sourceEnvVars))
+	if err := m.checkNameCollisions(sourceEnvVars); err != nil {
+		return err
+	}
+	sourceEnvVars, err = m.checkLimits(envScope(targetEnv), sourceEnvVars, maxEnvVariables, result)
+	if err != nil {
+		return err
+	}
+
 	// Migrate each variable in this environment
 	for _, variable := range sourceEnvVars {
-		if err := m.migrateEnvVariable(envName, variable, result); err != nil {
-			logger.Error("Failed to migrate environment variable '%s': %v", variable.Name, err)
-			result.AddError(fmt.Errorf("env '%s' variable '%s': %w", envName, variable.Name, err))
+		if m.stopped() {
+			break
+		}
+		if err := m.migrateEnvVariable(targetEnv, variable, result); err != nil {
+			logger.VariableError(variable.Name, "Failed to migrate environment variable '%s': %v", variable.Name, err)
+			recordFailed(envScope(targetEnv), variable.Name, err, result)
+			m.addError(result, fmt.Errorf("env '%s' variable '%s': %w", envName, variable.Name, err))
 		}
 	}
 
 	return nil
 }
 
-// ensureEnvironmentExists creates the environment in the target repo if it doesn't exist
-func (m *Migrator) ensureEnvironmentExists(envName string) error {
+// ensureEnvironmentExists creates the environment in the target repo if it
+// doesn't exist. With CopyEnvProtection it is created with the protection
+// settings of the source environment, and with UpdateEnvSettings those are
+// also applied to an environment that already exists.
+func (m *Migrator) ensureEnvironmentExists(sourceEnv, envName string) error {
 	// Check if environment already exists in target using target client
 	_, err := m.targetClient.GetEnvironment(m.config.TargetOwner, m.config.TargetRepo, envName)
-	if err == nil {
+	exists := err == nil
+	if exists && !m.config.UpdateEnvSettings {
 		logger.Debug("Environment '%s' already exists in target repository", envName)
 		return nil
 	}
 
+	if m.config.CopyEnvProtection {
+		return m.writeEnvironmentSettings(sourceEnv, envName, exists)
+	}
+
 	// Environment doesn't exist, create it
 	if m.config.DryRun {
 		logger.Info("[DRY-RUN] Would create environment: %s", envName)
@@ -134,9 +277,13 @@ func (m *Migrator) ensureEnvironmentExists(envName string) error {
 // migrateRepoVariables migrates repository-level variables
 func (m *Migrator) migrateRepoVariables(sourceVars []types.Variable, result *types.MigrationResult) error {
 	for _, variable := range sourceVars {
+		if m.stopped() {
+			break
+		}
 		if err := m.migrateRepoVariable(variable, result); err != nil {
-			logger.Error("Failed to migrate variable '%s': %v", variable.Name, err)
-			result.AddError(fmt.Errorf("variable '%s': %w", variable.Name, err))
+			logger.VariableError(variable.Name, "Failed to migrate variable '%s': %v", variable.Name, err)
+			recordFailed(m.currentRepoScope(), variable.Name, err, result)
+			m.addError(result, fmt.Errorf("variable '%s': %w", variable.Name, err))
 		}
 	}
 	return nil
@@ -144,90 +291,144 @@ func (m *Migrator) migrateRepoVariables(sourceVars []types.Variable, result *typ
 
 // migrateRepoVariable migrates a single repository variable
 func (m *Migrator) migrateRepoVariable(variable types.Variable, result *types.MigrationResult) error {
+	if ok, err := m.checkPlan(m.currentRepoScope(), variable, result); err != nil || !ok {
+		return err
+	}
+	target, err := m.targetVariable(variable)
+	if err != nil {
+		return err
+	}
+	label := nameLabel(variable.Name, target.Name)
+	note := m.valueNote(variable)
+	owner, repo := m.targetRepository()
+	where := m.fanOutNote()
+	scope := m.currentRepoScope()
+
 	// Check if variable exists in target using target client
-	existingVar, err := m.targetClient.GetRepoVariable(m.config.TargetOwner, m.config.TargetRepo, variable.Name)
+	existingVar, err := m.targetClient.GetRepoVariable(owner, repo, target.Name)
 
 	if err == nil && existingVar != nil {
-		// Variable exists in target
-		if m.config.SkipOverwrite {
-			logger.Warning("Variable '%s' already exists in target, overwrite skipped (--skip-overwrite)", variable.Name)
-			result.Skipped++
+		if m.isUnchanged(target, existingVar) {
+			logger.Detail("Variable '%s'%s is unchanged in target, update skipped", label, where)
+			recordUnchanged(scope, variable.Name, result)
 			return nil
 		}
 
+		// Variable exists in target
+		overwrite, err := m.resolveConflict("Variable", scope, variable.Name, label, result)
+		if err != nil || !overwrite {
+			return err
+		}
+
 		// Update existing variable using target client
 		if m.config.DryRun {
-			logger.Info("[DRY-RUN] Would update variable: %s", variable.Name)
-			result.Updated++
+			logger.Detail("[DRY-RUN] Would update variable: %s%s%s%s", label, where, m.valueChange(target, existingVar), note)
+			m.recordUpdated(scope, variable, result)
 			return nil
 		}
 
-		if err := m.targetClient.UpdateRepoVariable(m.config.TargetOwner, m.config.TargetRepo, variable); err != nil {
+		if ok, err := m.confirmWrite("Update", scope, variable.Name, label+where, result); err != nil || !ok {
+			return err
+		}
+
+		if err := m.targetClient.UpdateRepoVariable(owner, repo, target); err != nil {
 			return fmt.Errorf("failed to update: %w", err)
 		}
 
-		logger.Success("Updated variable: %s", variable.Name)
-		result.Updated++
+		logger.DetailSuccess("Updated variable: %s%s%s", label, where, note)
+		m.recordWritten(scope, target)
+		m.recordUpdated(scope, variable, result)
 		return nil
 	}
 
 	// Create new variable using target client
 	if m.config.DryRun {
-		logger.Info("[DRY-RUN] Would create variable: %s", variable.Name)
-		result.Created++
+		logger.Detail("[DRY-RUN] Would create variable: %s%s%s", label, where, note)
+		m.recordCreated(scope, variable, result)
 		return nil
 	}
 
-	if err := m.targetClient.CreateRepoVariable(m.config.TargetOwner, m.config.TargetRepo, variable); err != nil {
+	if ok, err := m.confirmWrite("Create", scope, variable.Name, label+where, result); err != nil || !ok {
+		return err
+	}
+
+	if err := m.targetClient.CreateRepoVariable(owner, repo, target); err != nil {
 		return fmt.Errorf("failed to create: %w", err)
 	}
 
-	logger.Success("Created variable: %s", variable.Name)
-	result.Created++
+	logger.DetailSuccess("Created variable: %s%s%s", label, where, note)
+	m.recordWritten(scope, target)
+	m.recordCreated(scope, variable, result)
 	return nil
 }
 
-// migrateEnvVariable migrates a single environment variable
+// migrateEnvVariable migrates a single environment variable into the
+// target environment envName
 func (m *Migrator) migrateEnvVariable(envName string, variable types.Variable, result *types.MigrationResult) error {
+	if ok, err := m.checkPlan(envScope(envName), variable, result); err != nil || !ok {
+		return err
+	}
+	target, err := m.targetVariable(variable)
+	if err != nil {
+		return err
+	}
+	label := nameLabel(variable.Name, target.Name)
+	note := m.valueNote(variable)
+
 	// Check if variable exists in target environment using target client
-	existingVar, err := m.targetClient.GetEnvVariable(m.config.TargetOwner, m.config.TargetRepo, envName, variable.Name)
+	existingVar, err := m.targetClient.GetEnvVariable(m.config.TargetOwner, m.config.TargetRepo, envName, target.Name)
 
 	if err == nil && existingVar != nil {
-		// Variable exists in target environment
-		if m.config.SkipOverwrite {
-			logger.Warning("Environment variable '%s' already exists in target, overwrite skipped (--skip-overwrite)", variable.Name)
-			result.Skipped++
+		if m.isUnchanged(target, existingVar) {
+			logger.Detail("Environment variable '%s' (env: %s) is unchanged in target, update skipped", label, envName)
+			recordUnchanged(envScope(envName), variable.Name, result)
 			return nil
 		}
 
+		// Variable exists in target environment
+		overwrite, err := m.resolveConflict("Environment variable", envScope(envName), variable.Name, label, result)
+		if err != nil || !overwrite {
+			return err
+		}
+
 		// Update existing variable using target client
 		if m.config.DryRun {
-			logger.Info("[DRY-RUN] Would update environment variable: %s (env: %s)", variable.Name, envName)
-			result.Updated++
+			logger.Detail("[DRY-RUN] Would update environment variable: %s (env: %s)%s%s", label, envName, m.valueChange(target, existingVar), note)
+			m.recordUpdated(envScope(envName), variable, result)
 			return nil
 		}
 
-		if err := m.targetClient.UpdateEnvVariable(m.config.TargetOwner, m.config.TargetRepo, envName, variable); err != nil {
+		if ok, err := m.confirmWrite("Update", envScope(envName), variable.Name, label+" (env: "+envName+")", result); err != nil || !ok {
+			return err
+		}
+
+		if err := m.targetClient.UpdateEnvVariable(m.config.TargetOwner, m.config.TargetRepo, envName, target); err != nil {
 			return fmt.Errorf("failed to update: %w", err)
 		}
 
-		logger.Success("Updated environment variable: %s (env: %s)", variable.Name, envName)
-		result.Updated++
+		logger.DetailSuccess("Updated environment variable: %s (env: %s)%s", label, envName, note)
+		m.recordWritten(envScope(envName), target)
+		m.recordUpdated(envScope(envName), variable, result)
 		return nil
 	}
 
 	// Create new environment variable using target client
 	if m.config.DryRun {
-		logger.Info("[DRY-RUN] Would create environment variable: %s (env: %s)", variable.Name, envName)
-		result.Created++
+		logger.Detail("[DRY-RUN] Would create environment variable: %s (env: %s)%s", label, envName, note)
+		m.recordCreated(envScope(envName), variable, result)
 		return nil
 	}
 
-	if err := m.targetClient.CreateEnvVariable(m.config.TargetOwner, m.config.TargetRepo, envName, variable); err != nil {
+	if ok, err := m.confirmWrite("Create", envScope(envName), variable.Name, label+" (env: "+envName+")", result); err != nil || !ok {
+		return err
+	}
+
+	if err := m.targetClient.CreateEnvVariable(m.config.TargetOwner, m.config.TargetRepo, envName, target); err != nil {
 		return fmt.Errorf("failed to create: %w", err)
 	}
 
-	logger.Success("Created environment variable: %s (env: %s)", variable.Name, envName)
-	result.Created++
+	logger.DetailSuccess("Created environment variable: %s (env: %s)%s", label, envName, note)
+	m.recordWritten(envScope(envName), target)
+	m.recordCreated(envScope(envName), variable, result)
 	return nil
 }
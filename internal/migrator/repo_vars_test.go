@@ -0,0 +1,647 @@
+package migrator
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func repoToRepoConfig() *types.MigrationConfig {
+	return &types.MigrationConfig{
+		Mode:        types.ModeRepoToRepo,
+		SourceOwner: "src",
+		SourceRepo:  "app",
+		TargetOwner: "dst",
+		TargetRepo:  "app",
+	}
+}
+
+// seedEnvsFake returns a fake whose source repository has one repository
+// variable and the environments production, staging, and pr-1, each with a
+// URL variable
+func seedEnvsFake() *fakeGitHub {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "REGION", Value: "eu"})
+	for _, env := range []string{"production", "staging", "pr-1"} {
+		fake.addEnv("src", "app", env)
+		fake.setVar(envVarsPath("src", "app", env), types.Variable{Name: "URL", Value: "https://" + env})
+	}
+	return fake
+}
+
+func TestMigrateRepoToRepo_Envs(t *testing.T) {
+	fake := seedEnvsFake()
+
+	cfg := repoToRepoConfig()
+	cfg.Envs = []string{"Production", "staging"}
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 3 || result.HasErrors() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if want := []string{"production", "staging"}; !reflect.DeepEqual(result.Environments, want) {
+		t.Errorf("Environments = %v, want %v", result.Environments, want)
+	}
+	if want := []string{"pr-1"}; !reflect.DeepEqual(result.FilteredEnvs, want) {
+		t.Errorf("FilteredEnvs = %v, want %v", result.FilteredEnvs, want)
+	}
+
+	if fake.envs["dst/app"]["pr-1"] {
+		t.Error("Filtered environment must not be created in the target")
+	}
+	if _, ok := fake.getVar(envVarsPath("dst", "app", "staging"), "URL"); !ok {
+		t.Error("Expected staging variable to be migrated")
+	}
+}
+
+func TestMigrateRepoToRepo_UnknownEnvs(t *testing.T) {
+	fake := seedEnvsFake()
+
+	cfg := repoToRepoConfig()
+	cfg.Envs = []string{"production", "qa", "demo"}
+	_, err := newFakeMigrator(t, cfg, fake).Run()
+	if err == nil {
+		t.Fatal("Run() expected an error for unknown environments")
+	}
+	if !strings.Contains(err.Error(), "qa, demo") {
+		t.Errorf("Error should name the unknown environments, got: %v", err)
+	}
+
+	for _, call := range fake.calls {
+		if !strings.HasPrefix(call, "GET ") {
+			t.Errorf("Nothing must be written when an environment is unknown, got %s", call)
+		}
+	}
+}
+
+func TestMigrateRepoToRepo_ExcludeEnvs(t *testing.T) {
+	fake := seedEnvsFake()
+	fake.addEnv("src", "app", "preview-2")
+	fake.setVar(envVarsPath("src", "app", "preview-2"), types.Variable{Name: "URL", Value: "https://preview"})
+
+	cfg := repoToRepoConfig()
+	// pr-1 matches both of the first two patterns.
+	cfg.ExcludeEnvs = []string{"pr-*", "*-1", "PREVIEW-*"}
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 3 || result.HasErrors() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if want := []string{"pr-1", "preview-2"}; !reflect.DeepEqual(result.FilteredEnvs, want) {
+		t.Errorf("FilteredEnvs = %v, want %v", result.FilteredEnvs, want)
+	}
+	if want := []string{"production", "staging"}; !reflect.DeepEqual(result.Environments, want) {
+		t.Errorf("Environments = %v, want %v", result.Environments, want)
+	}
+
+	for _, env := range []string{"pr-1", "preview-2"} {
+		if fake.envs["dst/app"][env] {
+			t.Errorf("Excluded environment %s must not be created in the target", env)
+		}
+	}
+}
+
+func TestMigrateRepoToRepo_SingleEnv(t *testing.T) {
+	fake := seedEnvsFake()
+
+	cfg := repoToRepoConfig()
+	cfg.Envs = []string{"production"}
+	cfg.SkipRepoVars = true
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 1 || result.HasErrors() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if _, ok := fake.getVar(envVarsPath("dst", "app", "production"), "URL"); !ok {
+		t.Error("Expected the production variable to be migrated")
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "app"), "REGION"); ok {
+		t.Error("Repository-level variables must not be migrated")
+	}
+	if n := fake.countCalls("GET repos/src/app/actions/variables"); n != 0 {
+		t.Errorf("Source repository variables must not be read, got %d call(s)", n)
+	}
+	if envs := fake.envs["dst/app"]; len(envs) != 1 {
+		t.Errorf("Expected only production in the target, got %v", envs)
+	}
+}
+
+// TestMigrateRepoToRepo_EnvsOnly verifies that every selected environment
+// is migrated while repository-level variables are neither read nor
+// written, and that the summary says so
+func TestMigrateRepoToRepo_EnvsOnly(t *testing.T) {
+	fake := seedEnvsFake()
+
+	cfg := repoToRepoConfig()
+	cfg.SkipRepoVars = true
+	cfg.ExcludeEnvs = []string{"pr-*"}
+	var result *types.MigrationResult
+	out := captureStdout(t, func() {
+		var err error
+		result, err = newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+	if result == nil || result.Created != 2 || result.HasErrors() {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	if want := []string{"production", "staging"}; !reflect.DeepEqual(result.Environments, want) {
+		t.Errorf("Environments = %v, want %v", result.Environments, want)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "app"), "REGION"); ok {
+		t.Error("Repository-level variables must not be migrated")
+	}
+	if n := fake.countCalls("GET repos/src/app/actions/variables"); n != 0 {
+		t.Errorf("Source repository variables must not be read, got %d call(s)", n)
+	}
+	if !strings.Contains(out, "Repository variables: not migrated (environments only)") {
+		t.Errorf("Expected the summary to note the skipped repository variables, got:\n%s", out)
+	}
+}
+
+// TestMigrateRepoToRepo_EnvsOnlyAcrossHosts verifies that an
+// environment-only migration between two hosts reads only through the
+// source client and writes only through the target client
+func TestMigrateRepoToRepo_EnvsOnlyAcrossHosts(t *testing.T) {
+	source := seedEnvsFake()
+	target := newFakeGitHub()
+
+	sourceClient, err := client.NewWithTransport("source-token", "github.source.example", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetClient, err := client.NewWithTransport("target-token", "github.com", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := repoToRepoConfig()
+	cfg.SkipRepoVars = true
+	cfg.Envs = []string{"production"}
+	m, err := New(cfg, sourceClient, targetClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 1 || result.HasErrors() {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	if _, ok := target.getVar(envVarsPath("dst", "app", "production"), "URL"); !ok {
+		t.Error("Expected the production variable to be written to the target host")
+	}
+	for _, call := range source.calls {
+		if !strings.HasPrefix(call, "GET ") {
+			t.Errorf("Only reads may go to the source host, got %s", call)
+		}
+		if strings.Contains(call, "repos/dst/") {
+			t.Errorf("The target repository must not be requested from the source host, got %s", call)
+		}
+	}
+	for _, call := range target.calls {
+		if strings.Contains(call, "repos/src/") {
+			t.Errorf("The source repository must not be requested from the target host, got %s", call)
+		}
+	}
+}
+
+func TestDiffRepoToRepo_Envs(t *testing.T) {
+	fake := seedEnvsFake()
+
+	cfg := repoToRepoConfig()
+	cfg.Envs = []string{"staging"}
+	diff, err := newFakeMigrator(t, cfg, fake).Diff()
+	if err != nil {
+		t.Fatalf("Diff() unexpected error: %v", err)
+	}
+
+	var scopes []string
+	for _, e := range diff.Entries {
+		scopes = append(scopes, e.Scope)
+	}
+	if want := []string{scopeRepo, envScope("staging")}; !reflect.DeepEqual(scopes, want) {
+		t.Errorf("Diff scopes = %v, want %v", scopes, want)
+	}
+}
+
+// captureStdout returns what f writes to standard output
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error: %v", err)
+	}
+	os.Stdout = w
+
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		done <- buf.String()
+	}()
+
+	f()
+	_ = w.Close()
+	os.Stdout = old
+	return <-done
+}
+
+func TestMigrateRepoToRepo_EnvMapExistingTarget(t *testing.T) {
+	fake := seedEnvsFake()
+	fake.addEnv("dst", "app", "live")
+	fake.setVar(envVarsPath("dst", "app", "live"), types.Variable{Name: "URL", Value: "old"})
+
+	cfg := repoToRepoConfig()
+	cfg.Envs = []string{"production"}
+	cfg.EnvMap = map[string]string{"PRODUCTION": "live"}
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 1 || result.Updated != 1 || result.HasErrors() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if v, ok := fake.getVar(envVarsPath("dst", "app", "live"), "URL"); !ok || v.Value != "https://production" {
+		t.Errorf("Expected URL updated in mapped environment, got %+v (found %v)", v, ok)
+	}
+	if fake.envs["dst/app"]["production"] {
+		t.Error("Source environment name must not be created when it is mapped")
+	}
+	if n := fake.countCalls("PUT repos/dst/app/environments/live"); n != 0 {
+		t.Errorf("Existing target environment must not be recreated, got %d call(s)", n)
+	}
+}
+
+func TestMigrateRepoToRepo_EnvMapManyToOne(t *testing.T) {
+	fake := seedEnvsFake()
+	fake.setVar(envVarsPath("src", "app", "staging"), types.Variable{Name: "STAGE_ONLY", Value: "s"})
+
+	cfg := repoToRepoConfig()
+	cfg.ExcludeEnvs = []string{"pr-*"}
+	cfg.EnvMap = map[string]string{"production": "shared", "staging": "shared"}
+	var result *types.MigrationResult
+	out := captureStdout(t, func() {
+		var err error
+		result, err = newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+	if result == nil || result.HasErrors() {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	if !strings.Contains(out, "Environments production, staging all map to target environment 'shared'") {
+		t.Errorf("Expected a collision warning, got:\n%s", out)
+	}
+	if !fake.envs["dst/app"]["shared"] {
+		t.Error("Expected the mapped environment to be created")
+	}
+	for _, name := range []string{"URL", "STAGE_ONLY"} {
+		if _, ok := fake.getVar(envVarsPath("dst", "app", "shared"), name); !ok {
+			t.Errorf("Expected %s in the shared environment", name)
+		}
+	}
+}
+
+func TestMigrateRepoToRepo_EnvMapDryRun(t *testing.T) {
+	fake := seedEnvsFake()
+
+	cfg := repoToRepoConfig()
+	cfg.DryRun = true
+	cfg.Envs = []string{"staging"}
+	cfg.EnvMap = map[string]string{"staging": "stage-eu"}
+	out := captureStdout(t, func() {
+		if _, err := newFakeMigrator(t, cfg, fake).Run(); err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "env staging → stage-eu") {
+		t.Errorf("Expected dry-run output to show the rename, got:\n%s", out)
+	}
+	if fake.envs["dst/app"]["stage-eu"] {
+		t.Error("Dry run must not create the mapped environment")
+	}
+}
+
+func TestMigrateRepoToRepo_DryRunValueChanges(t *testing.T) {
+	fake := seedEnvsFake()
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "REGION", Value: "us-east"})
+	fake.addEnv("dst", "app", "staging")
+	fake.setVar(envVarsPath("dst", "app", "staging"), types.Variable{Name: "URL", Value: "https://staging"})
+
+	for _, showValues := range []bool{false, true} {
+		cfg := repoToRepoConfig()
+		cfg.DryRun = true
+		cfg.ShowValues = showValues
+		cfg.Envs = []string{"staging"}
+		out := captureStdout(t, func() {
+			if _, err := newFakeMigrator(t, cfg, fake).Run(); err != nil {
+				t.Errorf("Run() unexpected error: %v", err)
+			}
+		})
+
+		wantRegion := "Would update variable: REGION: value differs (source 2 chars, target 7 chars)"
+		if showValues {
+			wantRegion = `Would update variable: REGION: "us-east" → "eu"`
+		}
+		if !strings.Contains(out, wantRegion) {
+			t.Errorf("showValues=%v: expected %q, got:\n%s", showValues, wantRegion, out)
+		}
+		if !strings.Contains(out, "Environment variable 'URL' (env: staging) is unchanged in target, update skipped") {
+			t.Errorf("showValues=%v: expected identical value to be skipped as unchanged, got:\n%s", showValues, out)
+		}
+		if !showValues && strings.Contains(out, "us-east") {
+			t.Errorf("Values must be masked without ShowValues, got:\n%s", out)
+		}
+	}
+}
+
+// TestMigrateRepoToRepo_Unchanged verifies that only variables whose target
+// value differs are updated, unless AlwaysWrite is set
+func TestMigrateRepoToRepo_Unchanged(t *testing.T) {
+	for _, alwaysWrite := range []bool{false, true} {
+		fake := newFakeGitHub()
+		for _, v := range []types.Variable{{Name: "SAME", Value: "eu"}, {Name: "DIFFERENT", Value: "eu"}, {Name: "SPACE", Value: "eu "}} {
+			fake.setVar(repoVarsPath("src", "app"), v)
+		}
+		for _, v := range []types.Variable{{Name: "SAME", Value: "eu"}, {Name: "DIFFERENT", Value: "us"}, {Name: "SPACE", Value: "eu"}} {
+			fake.setVar(repoVarsPath("dst", "app"), v)
+		}
+
+		cfg := repoToRepoConfig()
+		cfg.SkipEnvs = true
+		cfg.AlwaysWrite = alwaysWrite
+		result, err := newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Fatalf("alwaysWrite=%v: Run() unexpected error: %v", alwaysWrite, err)
+		}
+		checkDetails(t, result)
+
+		patches := 0
+		for _, call := range fake.calls {
+			if strings.HasPrefix(call, "PATCH ") {
+				patches++
+			}
+		}
+		wantUpdated, wantUnchanged := 2, 1
+		if alwaysWrite {
+			wantUpdated, wantUnchanged = 3, 0
+		}
+		if result.Updated != wantUpdated || result.Unchanged != wantUnchanged || patches != wantUpdated {
+			t.Errorf("alwaysWrite=%v: Updated=%d Unchanged=%d PATCHes=%d, want %d, %d, %d",
+				alwaysWrite, result.Updated, result.Unchanged, patches, wantUpdated, wantUnchanged, wantUpdated)
+		}
+		if result.Conflicts != wantUpdated {
+			t.Errorf("alwaysWrite=%v: Conflicts=%d, unchanged variables must not count as conflicts", alwaysWrite, result.Conflicts)
+		}
+		if v, _ := fake.getVar(repoVarsPath("dst", "app"), "SPACE"); v.Value != "eu " {
+			t.Errorf("alwaysWrite=%v: a value differing in whitespace must be written, got %q", alwaysWrite, v.Value)
+		}
+	}
+}
+
+// TestMigrateRepoToRepo_FailFast verifies that a failed environment variable
+// is recorded and the run moves on by default, and that --fail-fast leaves
+// the remaining environments alone
+func TestMigrateRepoToRepo_FailFast(t *testing.T) {
+	for _, failFast := range []bool{false, true} {
+		fake := seedEnvsFake()
+		fake.failWrites["URL"] = true
+
+		cfg := repoToRepoConfig()
+		cfg.FailFast = failFast
+		result, err := newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		wantErrors := 3
+		if failFast {
+			wantErrors = 1
+		}
+		if result.Created != 1 || len(result.Errors) != wantErrors {
+			t.Errorf("FailFast=%v: expected 1 created and %d error(s), got %+v", failFast, wantErrors, result)
+		}
+		if result.Aborted {
+			t.Errorf("FailFast=%v: an error must not mark the run as aborted", failFast)
+		}
+
+		envWrites := 0
+		for _, call := range fake.calls {
+			if strings.HasPrefix(call, "POST ") && strings.Contains(call, "/environments/") && strings.HasSuffix(call, "/variables") {
+				envWrites++
+			}
+		}
+		if envWrites != wantErrors {
+			t.Errorf("FailFast=%v: expected %d environment variable write(s), got %d in %v", failFast, wantErrors, envWrites, fake.calls)
+		}
+	}
+}
+
+// TestMigrateRepoToRepo_MaxErrors verifies that the run stops once the
+// error threshold is reached, and runs to the end when it is not
+func TestMigrateRepoToRepo_MaxErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxErrors   int
+		failFast    bool
+		wantErrors  int
+		wantReached bool
+	}{
+		{name: "not reached", maxErrors: 4, wantErrors: 3},
+		{name: "exactly reached", maxErrors: 3, wantErrors: 3, wantReached: true},
+		{name: "reached early", maxErrors: 2, wantErrors: 2, wantReached: true},
+		{name: "fail fast is a limit of one", failFast: true, wantErrors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := seedEnvsFake()
+			fake.failWrites["URL"] = true
+
+			cfg := repoToRepoConfig()
+			cfg.MaxErrors = tt.maxErrors
+			cfg.FailFast = tt.failFast
+			result, err := newFakeMigrator(t, cfg, fake).Run()
+			if err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+
+			if len(result.Errors) != tt.wantErrors {
+				t.Errorf("Expected %d error(s), got %v", tt.wantErrors, result.Errors)
+			}
+			if result.ErrorLimitReached != tt.wantReached {
+				t.Errorf("ErrorLimitReached = %v, want %v", result.ErrorLimitReached, tt.wantReached)
+			}
+		})
+	}
+}
+
+// TestMigrateRepoToRepo_Since verifies that variables not updated since the
+// cutoff are left alone in every scope and counted separately
+func TestMigrateRepoToRepo_Since(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "FRESH", Value: "new", UpdatedAt: "2026-05-02T00:00:00Z"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "STALE", Value: "old", UpdatedAt: "2026-04-01T00:00:00Z"})
+	fake.addEnv("src", "app", "prod")
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "URL", Value: "https://prod", UpdatedAt: "2026-04-15T00:00:00Z"})
+
+	cfg := repoToRepoConfig()
+	cfg.Since = time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	var result *types.MigrationResult
+	out := captureStdout(t, func() {
+		var err error
+		result, err = newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+
+	if result == nil || result.Created != 1 || result.UnchangedSince != 2 || result.Filtered != 0 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "app"), "STALE"); ok {
+		t.Error("A variable not updated since the cutoff must not be written")
+	}
+	if !strings.Contains(out, "Unchanged since 2026-05-01T00:00:00Z: 2 (--since)") {
+		t.Errorf("Expected the summary to count variables left out by --since, got:\n%s", out)
+	}
+}
+
+// TestMigrateRepoToRepo_Interrupt verifies that an interrupt from another
+// goroutine, as sent by the signal handler, stops the run after the write in
+// progress and that the partial result and summary are still produced
+func TestMigrateRepoToRepo_Interrupt(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  func() *types.MigrationConfig
+		seed func() *fakeGitHub
+	}{
+		{name: "single target", cfg: repoToRepoConfig, seed: seedEnvsFake},
+		{name: "many targets", cfg: func() *types.MigrationConfig { return targetsConfig("acme/api", "acme/web") }, seed: seedTemplateFake},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tt.seed()
+			m := newFakeMigrator(t, tt.cfg(), fake)
+
+			signals := make(chan struct{})
+			handled := make(chan struct{})
+			go func() {
+				<-signals
+				m.Interrupt()
+				close(handled)
+			}()
+			var once sync.Once
+			fake.afterCall = func(call string) {
+				if strings.HasPrefix(call, "POST ") {
+					once.Do(func() {
+						close(signals)
+						<-handled
+					})
+				}
+			}
+
+			var result *types.MigrationResult
+			out := captureStdout(t, func() {
+				var err error
+				result, err = m.Run()
+				if err != nil {
+					t.Errorf("Run() unexpected error: %v", err)
+				}
+			})
+
+			if result == nil || !result.Interrupted || result.Created != 1 || len(result.Details) != 1 {
+				t.Fatalf("Expected a partial result with one created variable, got %+v", result)
+			}
+			writes := 0
+			for _, call := range fake.calls {
+				if strings.HasPrefix(call, "POST ") && strings.HasSuffix(call, "/variables") {
+					writes++
+				}
+			}
+			if writes != 1 {
+				t.Errorf("Expected no writes after the interrupt, got %d in %v", writes, fake.calls)
+			}
+			if !strings.Contains(out, "Interrupted: the counts cover only the variables processed before the interrupt") {
+				t.Errorf("Expected the partial summary, got:\n%s", out)
+			}
+		})
+	}
+}
+
+// TestMigrateRepoToRepo_ArchivedTarget verifies that an archived target
+// stops the migration before any source data is read, in the full and the
+// single-environment modes
+func TestMigrateRepoToRepo_ArchivedTarget(t *testing.T) {
+	for _, envOnly := range []bool{false, true} {
+		fake := seedEnvsFake()
+		fake.addRepo("dst", "app")
+		fake.archived["dst/app"] = true
+
+		cfg := repoToRepoConfig()
+		if envOnly {
+			cfg.Envs = []string{"production"}
+			cfg.SkipRepoVars = true
+		}
+		_, err := newFakeMigrator(t, cfg, fake).Run()
+		want := "target repository dst/app is archived; unarchive it or choose another target"
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("envOnly=%v: expected %q, got: %v", envOnly, want, err)
+		}
+		for _, call := range fake.calls {
+			if strings.Contains(call, "/variables") || strings.Contains(call, "/environments") {
+				t.Errorf("envOnly=%v: nothing may be read after the check, got %s", envOnly, call)
+			}
+		}
+	}
+}
+
+// TestMigrateRepoToRepo_ArchivedSource verifies that an archived source is
+// only a warning
+func TestMigrateRepoToRepo_ArchivedSource(t *testing.T) {
+	fake := seedEnvsFake()
+	fake.addRepo("src", "app")
+	fake.archived["src/app"] = true
+
+	cfg := repoToRepoConfig()
+	cfg.SkipEnvs = true
+	var result *types.MigrationResult
+	out := captureStdout(t, func() {
+		var err error
+		result, err = newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+	if result == nil || result.Created != 1 {
+		t.Fatalf("Expected the variable of the archived source to be migrated, got %+v", result)
+	}
+	if !strings.Contains(out, "Source repository src/app is archived; migrating its variables anyway") {
+		t.Errorf("Expected a warning about the archived source, got:\n%s", out)
+	}
+}
@@ -0,0 +1,231 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/snapshot"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// Rollback restores the target described by a snapshot to its captured
+// state: variables deleted since are recreated, changed values and
+// visibilities are put back, and variables that did not exist at snapshot
+// time are deleted. Environments created since the snapshot are left in
+// place, emptied of variables. In dry-run mode the actions are only logged.
+func Rollback(targetClient *client.Client, snap *snapshot.Snapshot, dryRun bool) (*types.RollbackResult, error) {
+	if targetClient == nil {
+		return nil, fmt.Errorf("target client cannot be nil")
+	}
+	if err := snap.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	r := &rollback{client: targetClient, snap: snap, dryRun: dryRun, result: &types.RollbackResult{}}
+
+	logger.Info("Rolling back %s to snapshot taken at %s", r.targetLabel(), snap.CreatedAt.Format("2006-01-02T15:04:05Z07:00"))
+	if dryRun {
+		logger.Warning("Running in DRY-RUN mode - no changes will be made")
+	}
+
+	targetClient.WaitForRateLimit()
+
+	for _, scope := range snap.Scopes {
+		if err := r.restoreScope(scope); err != nil {
+			logger.Error("Failed to roll back %s: %v", scope.Label(), err)
+			r.result.AddError(fmt.Errorf("%s: %w", scope.Label(), err))
+		}
+	}
+
+	printRollbackSummary(r.result)
+	return r.result, nil
+}
+
+// rollback carries the state of a single Rollback call
+type rollback struct {
+	client *client.Client
+	snap   *snapshot.Snapshot
+	dryRun bool
+	result *types.RollbackResult
+}
+
+// targetLabel describes the snapshot target for log output
+func (r *rollback) targetLabel() string {
+	if r.snap.Mode.TargetsOrg() {
+		return "organization " + r.snap.TargetOrg
+	}
+	return "repository " + r.snap.TargetOwner + "/" + r.snap.TargetRepo
+}
+
+// restoreScope brings one scope back to its snapshot state
+func (r *rollback) restoreScope(scope snapshot.Scope) error {
+	current, err := r.currentVariables(scope)
+	if err != nil {
+		return err
+	}
+
+	currentByName := make(map[string]types.Variable, len(current))
+	for _, v := range current {
+		currentByName[strings.ToUpper(v.Name)] = v
+	}
+	wanted := make(map[string]bool, len(scope.Variables))
+
+	for _, want := range scope.Variables {
+		key := strings.ToUpper(want.Name)
+		wanted[key] = true
+
+		got, ok := currentByName[key]
+		switch {
+		case !ok:
+			r.apply(scope, "recreate", want.Name, func() error { return r.create(scope, want) }, &r.result.Recreated)
+		case !sameState(got, want):
+			r.apply(scope, "restore", want.Name, func() error { return r.update(scope, want) }, &r.result.Restored)
+		default:
+			r.result.Unchanged++
+		}
+	}
+
+	var extra []string
+	for key, v := range currentByName {
+		if !wanted[key] {
+			extra = append(extra, v.Name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		r.apply(scope, "delete", name, func() error { return r.delete(scope, name) }, &r.result.Deleted)
+	}
+
+	return nil
+}
+
+// apply performs (or, in dry-run mode, logs) one restore action and counts
+// it on success
+func (r *rollback) apply(scope snapshot.Scope, action, name string, fn func() error, counter *int) {
+	if r.dryRun {
+		logger.Detail("[DRY-RUN] Would %s variable: %s (%s)", action, name, scope.Label())
+		*counter++
+		return
+	}
+
+	if err := fn(); err != nil {
+		logger.VariableError(name, "Failed to %s variable '%s' (%s): %v", action, name, scope.Label(), err)
+		r.result.AddError(fmt.Errorf("%s variable '%s': %w", scope.Label(), name, err))
+		return
+	}
+
+	logger.DetailSuccess("Rolled back variable: %s (%s, %s)", name, scope.Label(), action)
+	*counter++
+}
+
+// currentVariables lists the current target variables of a scope. An
+// environment that no longer exists is recreated when the snapshot holds
+// variables for it.
+func (r *rollback) currentVariables(scope snapshot.Scope) ([]types.Variable, error) {
+	switch scope.Kind {
+	case snapshot.KindOrg:
+		return listOrgVariablesWithSelection(r.client, r.snap.TargetOrg)
+	case snapshot.KindRepo:
+		return r.client.ListRepoVariables(r.snap.TargetOwner, r.snap.TargetRepo)
+	}
+
+	if _, err := r.client.GetEnvironment(r.snap.TargetOwner, r.snap.TargetRepo, scope.Environment); err != nil {
+		if len(scope.Variables) == 0 {
+			logger.Debug("Environment '%s' does not exist in target; nothing to roll back", scope.Environment)
+			return nil, nil
+		}
+		if r.dryRun {
+			logger.Info("[DRY-RUN] Would create environment: %s", scope.Environment)
+			return nil, nil
+		}
+		if err := r.client.CreateEnvironment(r.snap.TargetOwner, r.snap.TargetRepo, scope.Environment); err != nil {
+			return nil, fmt.Errorf("failed to recreate environment: %w", err)
+		}
+		logger.Success("Created environment: %s", scope.Environment)
+		return nil, nil
+	}
+	return r.client.ListEnvVariables(r.snap.TargetOwner, r.snap.TargetRepo, scope.Environment)
+}
+
+// create writes a snapshot variable that is missing from the target
+func (r *rollback) create(scope snapshot.Scope, v types.Variable) error {
+	switch scope.Kind {
+	case snapshot.KindOrg:
+		return r.client.CreateOrgVariable(r.snap.TargetOrg, v)
+	case snapshot.KindRepo:
+		return r.client.CreateRepoVariable(r.snap.TargetOwner, r.snap.TargetRepo, v)
+	default:
+		return r.client.CreateEnvVariable(r.snap.TargetOwner, r.snap.TargetRepo, scope.Environment, v)
+	}
+}
+
+// update puts a snapshot variable's value and visibility back
+func (r *rollback) update(scope snapshot.Scope, v types.Variable) error {
+	switch scope.Kind {
+	case snapshot.KindOrg:
+		return r.client.UpdateOrgVariable(r.snap.TargetOrg, v)
+	case snapshot.KindRepo:
+		return r.client.UpdateRepoVariable(r.snap.TargetOwner, r.snap.TargetRepo, v)
+	default:
+		return r.client.UpdateEnvVariable(r.snap.TargetOwner, r.snap.TargetRepo, scope.Environment, v)
+	}
+}
+
+// delete removes a variable that did not exist at snapshot time
+func (r *rollback) delete(scope snapshot.Scope, name string) error {
+	switch scope.Kind {
+	case snapshot.KindOrg:
+		return r.client.DeleteOrgVariable(r.snap.TargetOrg, name)
+	case snapshot.KindRepo:
+		return r.client.DeleteRepoVariable(r.snap.TargetOwner, r.snap.TargetRepo, name)
+	default:
+		return r.client.DeleteEnvVariable(r.snap.TargetOwner, r.snap.TargetRepo, scope.Environment, name)
+	}
+}
+
+// sameState reports whether a current target variable matches its snapshot:
+// same value, visibility, and (for "selected" visibility) repository IDs
+func sameState(current, want types.Variable) bool {
+	if current.Value != want.Value || !sameVisibility(current, want) {
+		return false
+	}
+	if want.Visibility != "selected" {
+		return true
+	}
+	return sameIDs(current.SelectedRepositoryIDs, want.SelectedRepositoryIDs)
+}
+
+// sameIDs reports whether two ID lists hold the same IDs in any order
+func sameIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sa := append([]int64(nil), a...)
+	sb := append([]int64(nil), b...)
+	sort.Slice(sa, func(i, j int) bool { return sa[i] < sa[j] })
+	sort.Slice(sb, func(i, j int) bool { return sb[i] < sb[j] })
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// printRollbackSummary prints the counts of restore actions
+func printRollbackSummary(result *types.RollbackResult) {
+	logger.Plain("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	logger.Plain("Rollback Summary")
+	logger.Plain("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	logger.Plain("Recreated: %d  Restored: %d  Deleted: %d  Unchanged: %d",
+		result.Recreated, result.Restored, result.Deleted, result.Unchanged)
+	if result.HasErrors() {
+		logger.SummaryError("\nEncountered %d error(s) during rollback:", len(result.Errors))
+		for i, err := range result.Errors {
+			logger.SummaryError("  %d. %v", i+1, err)
+		}
+	}
+}
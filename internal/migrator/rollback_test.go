@@ -0,0 +1,222 @@
+package migrator
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/snapshot"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// seedRollbackFake sets up a source repository with an environment and a
+// target that already holds some of the variables
+func seedRollbackFake() *fakeGitHub {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "A", Value: "new-a"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "B", Value: "new-b"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "C", Value: "new-c"})
+	fake.addEnv("src", "app", "prod")
+	fake.addEnv("src", "app", "staging")
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "E", Value: "new-e"})
+	fake.setVar(envVarsPath("src", "app", "staging"), types.Variable{Name: "S", Value: "new-s"})
+
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "A", Value: "old-a"})
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "KEEP", Value: "keep"})
+	fake.addEnv("dst", "app", "prod")
+	fake.setVar(envVarsPath("dst", "app", "prod"), types.Variable{Name: "E", Value: "old-e"})
+	return fake
+}
+
+// targetState returns the fake's target collections for comparison
+func targetState(fake *fakeGitHub) map[string]map[string]types.Variable {
+	state := map[string]map[string]types.Variable{}
+	for _, path := range []string{
+		repoVarsPath("dst", "app"),
+		envVarsPath("dst", "app", "prod"),
+		envVarsPath("dst", "app", "staging"),
+	} {
+		fake.mu.Lock()
+		vars := map[string]types.Variable{}
+		for k, v := range fake.vars[path] {
+			vars[k] = v
+		}
+		fake.mu.Unlock()
+		state[path] = vars
+	}
+	return state
+}
+
+func TestSnapshot_CapturesTargetScopes(t *testing.T) {
+	fake := seedRollbackFake()
+	m := newFakeMigrator(t, repoVerifyConfig(), fake)
+
+	snap, err := m.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() unexpected error: %v", err)
+	}
+
+	if snap.TargetOwner != "dst" || snap.TargetRepo != "app" || snap.Mode != types.ModeRepoToRepo {
+		t.Errorf("Unexpected snapshot target: %+v", snap)
+	}
+	if len(snap.Scopes) != 3 {
+		t.Fatalf("Expected repository and 2 environment scopes, got %d", len(snap.Scopes))
+	}
+	if got := len(snap.Scopes[0].Variables); snap.Scopes[0].Kind != snapshot.KindRepo || got != 2 {
+		t.Errorf("Expected repository scope with 2 variables, got %+v", snap.Scopes[0])
+	}
+	if s := snap.Scopes[1]; s.Environment != "prod" || s.EnvironmentMissing || len(s.Variables) != 1 {
+		t.Errorf("Unexpected prod scope: %+v", s)
+	}
+	if s := snap.Scopes[2]; s.Environment != "staging" || !s.EnvironmentMissing {
+		t.Errorf("Expected staging to be recorded as missing, got %+v", s)
+	}
+}
+
+func TestSnapshot_EnvMap(t *testing.T) {
+	fake := seedRollbackFake()
+	cfg := repoVerifyConfig()
+	cfg.EnvMap = map[string]string{"prod": "live", "staging": "live"}
+
+	snap, err := newFakeMigrator(t, cfg, fake).Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() unexpected error: %v", err)
+	}
+
+	if len(snap.Scopes) != 2 {
+		t.Fatalf("Expected repository and one mapped environment scope, got %+v", snap.Scopes)
+	}
+	if s := snap.Scopes[1]; s.Environment != "live" || !s.EnvironmentMissing {
+		t.Errorf("Expected the mapped environment live to be recorded as missing, got %+v", s)
+	}
+}
+
+func TestRollback_PartiallyAppliedMigration(t *testing.T) {
+	fake := seedRollbackFake()
+	before := targetState(fake)
+
+	cfg := repoVerifyConfig()
+	cfg.Verify = false
+	m := newFakeMigrator(t, cfg, fake)
+
+	snap, err := m.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() unexpected error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := snapshot.Save(path, snap); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	// Fail one write so the migration is only partially applied.
+	fake.failWrites["C"] = true
+	result, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if !result.HasErrors() {
+		t.Fatal("Expected the migration to report the injected failure")
+	}
+	if reflect.DeepEqual(targetState(fake), before) {
+		t.Fatal("Expected the migration to change the target")
+	}
+
+	// Someone also deletes a pre-existing variable after the migration.
+	fake.mu.Lock()
+	delete(fake.vars[repoVarsPath("dst", "app")], "KEEP")
+	fake.mu.Unlock()
+
+	loaded, err := snapshot.Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	targetClient, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb, err := Rollback(targetClient, loaded, false)
+	if err != nil {
+		t.Fatalf("Rollback() unexpected error: %v", err)
+	}
+	if rb.HasErrors() {
+		t.Fatalf("Rollback() reported errors: %v", rb.Errors)
+	}
+
+	// A and E restored, KEEP recreated, B and S deleted.
+	if rb.Restored != 2 || rb.Recreated != 1 || rb.Deleted != 2 {
+		t.Errorf("Unexpected rollback counts: %+v", rb)
+	}
+	if got := targetState(fake); !reflect.DeepEqual(got, before) {
+		t.Errorf("Target not restored:\n got  %+v\n want %+v", got, before)
+	}
+}
+
+func TestRollback_DryRun(t *testing.T) {
+	fake := seedRollbackFake()
+	cfg := repoVerifyConfig()
+	cfg.Verify = false
+	m := newFakeMigrator(t, cfg, fake)
+
+	snap, err := m.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() unexpected error: %v", err)
+	}
+	if _, err := m.Run(); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	after := targetState(fake)
+
+	targetClient, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb, err := Rollback(targetClient, snap, true)
+	if err != nil {
+		t.Fatalf("Rollback() unexpected error: %v", err)
+	}
+	if rb.Restored != 2 || rb.Deleted != 3 || rb.Unchanged != 1 {
+		t.Errorf("Unexpected dry-run counts: %+v", rb)
+	}
+	if got := targetState(fake); !reflect.DeepEqual(got, after) {
+		t.Error("Dry-run rollback must not change the target")
+	}
+}
+
+func TestRollback_OrgVisibility(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("dst"), types.Variable{Name: "A", Value: "1", Visibility: "private"})
+
+	snap := &snapshot.Snapshot{
+		Version:   snapshot.Version,
+		Mode:      types.ModeOrgToOrg,
+		TargetOrg: "dst",
+		Scopes: []snapshot.Scope{{Kind: snapshot.KindOrg, Variables: []types.Variable{
+			{Name: "A", Value: "1", Visibility: "all"},
+		}}},
+	}
+
+	targetClient, err := client.NewWithTransport("test-token", "github.com", fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb, err := Rollback(targetClient, snap, false)
+	if err != nil {
+		t.Fatalf("Rollback() unexpected error: %v", err)
+	}
+	if rb.Restored != 1 {
+		t.Errorf("Expected visibility change to be restored, got %+v", rb)
+	}
+	if v, _ := fake.getVar(orgVarsPath("dst"), "A"); v.Visibility != "all" {
+		t.Errorf("Expected visibility 'all', got %q", v.Visibility)
+	}
+}
+
+func TestSameIDs(t *testing.T) {
+	if !sameIDs([]int64{1, 2}, []int64{2, 1}) {
+		t.Error("Expected order-insensitive match")
+	}
+	if sameIDs([]int64{1}, []int64{1, 2}) || sameIDs([]int64{1, 3}, []int64{1, 2}) {
+		t.Error("Expected different ID lists not to match")
+	}
+}
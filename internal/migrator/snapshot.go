@@ -0,0 +1,125 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/client"
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/snapshot"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// Snapshot captures the current state of every target scope the configured
+// migration may write to: the organization, or the repository and (for
+// repo-to-repo) the target environment of each source environment, after
+// --env-map renames. Whole scopes are captured regardless of filters so a
+// rollback restores them exactly.
+func (m *Migrator) Snapshot() (*snapshot.Snapshot, error) {
+	snap := &snapshot.Snapshot{
+		Version:   snapshot.Version,
+		CreatedAt: time.Now().UTC(),
+		Mode:      m.config.Mode,
+	}
+
+	switch m.config.Mode {
+	case types.ModeOrgToOrg, types.ModeRepoToOrg:
+		if m.config.Deep {
+			return nil, fmt.Errorf("snapshots are not supported in deep mode")
+		}
+		snap.TargetOrg = m.config.TargetOrg
+		vars, err := listOrgVariablesWithSelection(m.targetClient, m.config.TargetOrg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot target organization variables: %w", err)
+		}
+		snap.Scopes = append(snap.Scopes, snapshot.Scope{Kind: snapshot.KindOrg, Variables: vars})
+
+	case types.ModeRepoToRepo, types.ModeOrgToRepo:
+		if len(m.config.Targets) > 0 {
+			return nil, fmt.Errorf("snapshots are not supported with multiple targets")
+		}
+		snap.TargetOwner = m.config.TargetOwner
+		snap.TargetRepo = m.config.TargetRepo
+		vars, err := m.targetClient.ListRepoVariables(m.config.TargetOwner, m.config.TargetRepo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot target repository variables: %w", err)
+		}
+		snap.Scopes = append(snap.Scopes, snapshot.Scope{Kind: snapshot.KindRepo, Variables: vars})
+
+		if m.config.Mode == types.ModeRepoToRepo && !m.config.SkipEnvs {
+			environments, err := m.sourceClient.ListEnvironments(m.config.SourceOwner, m.config.SourceRepo)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list environments: %w", err)
+			}
+			seen := make(map[string]bool, len(environments))
+			for _, env := range environments {
+				// Capture the environment the migration writes to.
+				targetEnv := m.targetEnvName(env.Name)
+				if seen[strings.ToUpper(targetEnv)] {
+					continue
+				}
+				seen[strings.ToUpper(targetEnv)] = true
+
+				scope, err := m.snapshotEnvironment(targetEnv)
+				if err != nil {
+					return nil, err
+				}
+				snap.Scopes = append(snap.Scopes, scope)
+			}
+		}
+
+	case types.ModeFanOut:
+		return nil, fmt.Errorf("snapshots are not supported in fan-out mode")
+
+	default:
+		return nil, fmt.Errorf("unsupported migration mode: %s", m.config.Mode)
+	}
+
+	return snap, nil
+}
+
+// snapshotEnvironment captures the target variables of one environment,
+// recording whether the environment exists yet
+func (m *Migrator) snapshotEnvironment(envName string) (snapshot.Scope, error) {
+	scope := snapshot.Scope{Kind: snapshot.KindEnv, Environment: envName}
+
+	if _, err := m.targetClient.GetEnvironment(m.config.TargetOwner, m.config.TargetRepo, envName); err != nil {
+		logger.Debug("Environment '%s' does not exist in target repository", envName)
+		scope.EnvironmentMissing = true
+		scope.Variables = []types.Variable{}
+		return scope, nil
+	}
+
+	vars, err := m.targetClient.ListEnvVariables(m.config.TargetOwner, m.config.TargetRepo, envName)
+	if err != nil {
+		return scope, fmt.Errorf("failed to snapshot target variables for environment '%s': %w", envName, err)
+	}
+	scope.Variables = vars
+	return scope, nil
+}
+
+// listOrgVariablesWithSelection lists organization variables and fills in
+// the repository IDs of those with "selected" visibility
+func listOrgVariablesWithSelection(c *client.Client, org string) ([]types.Variable, error) {
+	vars, err := c.ListOrgVariables(org)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range vars {
+		if v.Visibility != "selected" {
+			continue
+		}
+		repos, err := c.ListOrgVariableSelectedRepos(org, v.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list selected repositories for '%s': %w", v.Name, err)
+		}
+		ids := make([]int64, len(repos))
+		for j, r := range repos {
+			ids[j] = r.ID
+		}
+		vars[i].SelectedRepositoryIDs = ids
+	}
+	return vars, nil
+}
@@ -0,0 +1,171 @@
+package migrator
+
+import (
+	"fmt"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// repoRun is one repository-to-repository migration of a run that covers
+// many repositories
+type repoRun struct {
+	label string
+	cfg   *types.MigrationConfig
+}
+
+// runTargets migrates the source repository into every configured target
+// in turn
+func (m *Migrator) runTargets() (*types.MigrationResult, []string) {
+	runs := make([]repoRun, len(m.config.Targets))
+	for i, target := range m.config.Targets {
+		cfg := *m.config
+		cfg.Targets = nil
+		cfg.TargetOwner = target.Owner
+		cfg.TargetRepo = target.Repo
+		runs[i] = repoRun{label: target.String(), cfg: &cfg}
+	}
+
+	result := m.newResult()
+	missing, _ := m.runRepos(runs, result)
+	return result, missing
+}
+
+// runRepos runs each repository migration in turn with its own Migrator, so
+// a failing repository is recorded and the run moves on to the next one,
+// unless --fail-fast or --max-errors stops the run.
+// Counts are added to result with a per-repository breakdown in Repos;
+// errors and detail scopes are prefixed with the run's label. It returns the requested
+// variables missing from every completed run's source, and whether any run
+// completed.
+func (m *Migrator) runRepos(runs []repoRun, result *types.MigrationResult) ([]string, bool) {
+	var missing []string
+	completed := false
+
+	for i, r := range runs {
+		if result.Aborted || m.stopped() {
+			break
+		}
+		logger.Info("Repository %s (%d/%d)", r.label, i+1, len(runs))
+
+		child, err := m.child(r.cfg)
+		var repoResult *types.MigrationResult
+		if err == nil {
+			child.planScope = r.label + ":"
+			var repoMissing []string
+			repoResult, repoMissing, err = child.run()
+			if err == nil {
+				if completed {
+					missing = intersectNames(missing, repoMissing)
+				} else {
+					missing = repoMissing
+				}
+				completed = true
+			}
+			m.promptIn, m.approveAll, m.conflictAnswer = child.promptIn, child.approveAll, child.conflictAnswer
+			m.usedVars = child.usedVars
+		}
+		if repoResult == nil {
+			repoResult = &types.MigrationResult{}
+		}
+		if err != nil {
+			logger.Error("Migration to %s failed: %v", r.label, err)
+			m.addError(repoResult, err)
+		}
+
+		result.AddCounts(repoResult)
+		for _, e := range repoResult.Errors {
+			result.AddError(fmt.Errorf("%s: %w", r.label, e))
+		}
+		for _, d := range repoResult.Details {
+			d.Scope = r.label + ":" + d.Scope
+			result.AddDetail(d)
+		}
+		for _, u := range repoResult.Unused {
+			u.Scope = r.label + ":" + u.Scope
+			result.AddUnused(u)
+		}
+		for _, env := range repoResult.Environments {
+			result.AddEnvironment(r.label + ":" + env)
+		}
+		for _, env := range repoResult.FilteredEnvs {
+			result.AddFilteredEnv(r.label + ":" + env)
+		}
+		result.Aborted = repoResult.Aborted
+		result.AddRepo(newRepoResult(r.label, repoResult, err != nil))
+	}
+
+	return missing, completed
+}
+
+// diffTargets compares the source repository with every configured target.
+// Scopes are prefixed with the target, e.g. "acme/api:env:prod".
+func (m *Migrator) diffTargets() (*types.DiffResult, error) {
+	diff := &types.DiffResult{}
+	for _, target := range m.config.Targets {
+		cfg := *m.config
+		cfg.Targets = nil
+		cfg.TargetOwner = target.Owner
+		cfg.TargetRepo = target.Repo
+
+		child, err := m.child(&cfg)
+		if err != nil {
+			return nil, err
+		}
+		targetDiff, err := child.compare()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target, err)
+		}
+		for _, e := range targetDiff.Entries {
+			e.Scope = target.String() + ":" + e.Scope
+			diff.Entries = append(diff.Entries, e)
+		}
+	}
+	return diff, nil
+}
+
+// child returns a Migrator for one repository of a multi-repository run. It
+// shares the clients, the interactive prompt state, the error limit, the
+// interrupt, the plan being applied, and the names used by the source
+// workflows with m.
+func (m *Migrator) child(cfg *types.MigrationConfig) (*Migrator, error) {
+	child, err := New(cfg, m.sourceClient, m.targetClient)
+	if err != nil {
+		return nil, err
+	}
+	child.input, child.output, child.promptIn = m.input, m.output, m.promptIn
+	child.approveAll, child.conflictAnswer = m.approveAll, m.conflictAnswer
+	child.errors, child.interrupted, child.budget = m.errors, m.interrupted, m.budget
+	child.planned = m.planned
+	child.usedVars = m.usedVars
+	return child, nil
+}
+
+// newRepoResult builds the per-repository breakdown entry from the result
+// of one repository. A repository counts as failed when it could not be
+// processed at all, or when it had errors and nothing was written.
+func newRepoResult(repo string, result *types.MigrationResult, fatal bool) types.RepoResult {
+	return types.RepoResult{
+		Repo:    repo,
+		Created: result.Created,
+		Updated: result.Updated,
+		Skipped: result.Skipped,
+		Errors:  len(result.Errors),
+		Failed:  fatal || (result.HasErrors() && result.Created+result.Updated == 0),
+	}
+}
+
+// intersectNames returns the names present in both lists, in the order of a
+func intersectNames(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, name := range b {
+		inB[name] = true
+	}
+	var out []string
+	for _, name := range a {
+		if inB[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
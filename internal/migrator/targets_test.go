@@ -0,0 +1,147 @@
+package migrator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func targetsConfig(targets ...string) *types.MigrationConfig {
+	cfg := &types.MigrationConfig{
+		Mode:        types.ModeRepoToRepo,
+		SourceOwner: "acme",
+		SourceRepo:  "template",
+	}
+	for _, t := range targets {
+		owner, repo, _ := strings.Cut(t, "/")
+		cfg.Targets = append(cfg.Targets, types.RepoRef{Owner: owner, Repo: repo})
+	}
+	return cfg
+}
+
+// seedTemplateFake returns a fake whose template repository has two
+// variables and a prod environment with one variable
+func seedTemplateFake() *fakeGitHub {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("acme", "template"), types.Variable{Name: "A", Value: "1"})
+	fake.setVar(repoVarsPath("acme", "template"), types.Variable{Name: "B", Value: "2"})
+	fake.addEnv("acme", "template", "prod")
+	fake.setVar(envVarsPath("acme", "template", "prod"), types.Variable{Name: "E", Value: "3"})
+	return fake
+}
+
+func TestRunTargets(t *testing.T) {
+	fake := seedTemplateFake()
+	fake.setVar(repoVarsPath("acme", "web"), types.Variable{Name: "A", Value: "old"})
+
+	result, err := newFakeMigrator(t, targetsConfig("acme/api", "other/web"), fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 6 || result.HasErrors() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	want := []types.RepoResult{
+		{Repo: "acme/api", Created: 3},
+		{Repo: "other/web", Created: 3},
+	}
+	if !reflect.DeepEqual(result.Repos, want) {
+		t.Errorf("Repos = %+v, want %+v", result.Repos, want)
+	}
+	for _, target := range [][2]string{{"acme", "api"}, {"other", "web"}} {
+		if v, ok := fake.getVar(envVarsPath(target[0], target[1], "prod"), "E"); !ok || v.Value != "3" {
+			t.Errorf("Expected environment variable E in %s/%s, got %+v (found %v)", target[0], target[1], v, ok)
+		}
+	}
+	// acme/web is a different repository from other/web and must be untouched.
+	if v, _ := fake.getVar(repoVarsPath("acme", "web"), "A"); v.Value != "old" {
+		t.Errorf("Expected acme/web to be untouched, got %q", v.Value)
+	}
+}
+
+func TestRunTargets_ErrorIsolation(t *testing.T) {
+	fake := seedTemplateFake()
+	fake.setVar(repoVarsPath("acme", "web"), types.Variable{Name: "A", Value: "old"})
+
+	cfg := targetsConfig("acme/api", "acme/web", "acme/worker")
+	cfg.SkipEnvs = true
+	cfg.OnConflict = types.ConflictFail
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	want := []types.RepoResult{
+		{Repo: "acme/api", Created: 2},
+		{Repo: "acme/web", Errors: 1, Failed: true},
+		{Repo: "acme/worker", Created: 2},
+	}
+	if !reflect.DeepEqual(result.Repos, want) {
+		t.Errorf("Repos = %+v, want %+v", result.Repos, want)
+	}
+	if len(result.Errors) != 1 || !strings.HasPrefix(result.Errors[0].Error(), "acme/web: ") {
+		t.Errorf("Expected one error prefixed with the failing target, got %v", result.Errors)
+	}
+	if _, ok := fake.getVar(repoVarsPath("acme", "worker"), "B"); !ok {
+		t.Error("Expected the target after the failing one to be migrated")
+	}
+}
+
+func TestRunTargets_PartialFailure(t *testing.T) {
+	fake := seedTemplateFake()
+	fake.failWrites["B"] = true
+
+	cfg := targetsConfig("acme/api", "acme/web")
+	cfg.SkipEnvs = true
+	cfg.Verify = true
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	want := []types.RepoResult{
+		{Repo: "acme/api", Created: 1, Errors: 1},
+		{Repo: "acme/web", Created: 1, Errors: 1},
+	}
+	if !reflect.DeepEqual(result.Repos, want) {
+		t.Errorf("Repos = %+v, want %+v", result.Repos, want)
+	}
+	if result.Created != 2 || result.Verified != 2 || len(result.Errors) != 2 {
+		t.Errorf("Unexpected aggregated result: %+v", result)
+	}
+	wantDetails := []string{
+		"acme/api:repository A created",
+		"acme/api:repository B failed",
+		"acme/web:repository A created",
+		"acme/web:repository B failed",
+	}
+	if got := detailLines(result.Details); !reflect.DeepEqual(got, wantDetails) {
+		t.Errorf("Details = %v, want %v", got, wantDetails)
+	}
+	if reason := result.Details[1].Reason; !strings.HasPrefix(reason, "failed to create") {
+		t.Errorf("Expected the failure reason to hold the error, got %q", reason)
+	}
+}
+
+func TestRunTargets_Diff(t *testing.T) {
+	fake := seedTemplateFake()
+	fake.setVar(repoVarsPath("acme", "web"), types.Variable{Name: "A", Value: "1"})
+
+	cfg := targetsConfig("acme/api", "acme/web")
+	cfg.SkipEnvs = true
+	diff, err := newFakeMigrator(t, cfg, fake).Diff()
+	if err != nil {
+		t.Fatalf("Diff() unexpected error: %v", err)
+	}
+	if diff.Count(types.DiffAdd) != 3 || diff.Count(types.DiffUnchanged) != 1 {
+		t.Errorf("Unexpected diff: %+v", diff.Entries)
+	}
+	for _, e := range diff.Entries {
+		if e.Scope != "acme/api:repository" && e.Scope != "acme/web:repository" {
+			t.Errorf("Unexpected scope %q", e.Scope)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+	"github.com/renan-alm/gh-vars-migrator/internal/workflows"
+)
+
+// checkUsage is the --check-usage step of the repository modes. It scans
+// the target repository's workflow files for vars.NAME references and warns
+// about every name that the migration did not write and that the target
+// does not already have as a repository, environment, or organization
+// variable. Such names are counted in UnresolvedRefs. The step only warns:
+// failures to read the workflows or the target variables are logged and
+// the check is skipped.
+func (m *Migrator) checkUsage(result *types.MigrationResult) {
+	owner, repo := m.config.TargetOwner, m.config.TargetRepo
+	files, err := m.targetClient.ListWorkflowFiles(owner, repo)
+	if err != nil {
+		logger.Warning("Skipping the workflow usage check: %v", err)
+		return
+	}
+	if len(files) == 0 {
+		logger.Info("No workflow files in %s/%s; nothing to check for --check-usage", owner, repo)
+		return
+	}
+
+	known, err := m.targetVariableNames()
+	if err != nil {
+		logger.Warning("Skipping the workflow usage check: %v", err)
+		return
+	}
+	for _, d := range result.Details {
+		if d.Action == types.ActionCreated || d.Action == types.ActionUpdated || d.Action == types.ActionUnchanged {
+			target, _ := m.targetVariable(types.Variable{Name: d.Name})
+			known[strings.ToUpper(target.Name)] = true
+		}
+	}
+
+	usedIn := map[string][]string{}
+	for _, f := range files {
+		for _, name := range workflows.References(f.Content) {
+			usedIn[name] = append(usedIn[name], f.Path)
+		}
+	}
+	var unresolved []string
+	for name := range usedIn {
+		if !known[name] {
+			unresolved = append(unresolved, name)
+		}
+	}
+	sort.Strings(unresolved)
+
+	for _, name := range unresolved {
+		logger.Warning("vars.%s is referenced by %s but is neither migrated nor set in %s/%s",
+			name, strings.Join(usedIn[name], ", "), owner, repo)
+	}
+	if len(unresolved) == 0 {
+		logger.Success("Every variable referenced by the %d workflow file(s) of %s/%s is set", len(files), owner, repo)
+	}
+	result.Add(&result.UnresolvedRefs, len(unresolved))
+}
+
+// targetVariableNames returns the upper-cased names of the variables of the
+// target repository, its environments, and its owner's organization. The
+// organization variables are left out when they cannot be listed, e.g. for
+// a repository owned by a user.
+func (m *Migrator) targetVariableNames() (map[string]bool, error) {
+	owner, repo := m.config.TargetOwner, m.config.TargetRepo
+	names := map[string]bool{}
+	add := func(vars []types.Variable) {
+		for _, v := range vars {
+			names[strings.ToUpper(v.Name)] = true
+		}
+	}
+
+	vars, err := m.targetClient.ListRepoVariables(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	add(vars)
+
+	envs, err := m.targetClient.ListEnvironments(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, env := range envs {
+		vars, err := m.targetClient.ListEnvVariables(owner, repo, env.Name)
+		if err != nil {
+			return nil, err
+		}
+		add(vars)
+	}
+
+	if vars, err := m.targetClient.ListOrgVariables(owner); err == nil {
+		add(vars)
+	} else {
+		logger.Debug("Organization variables of %s are not included in the usage check: %v", owner, err)
+	}
+	return names, nil
+}
+
+// loadUsedVars reads the workflow files of the source repository for
+// --skip-unused and records the variable names they reference. It does
+// nothing without SkipUnused or when the names are already loaded. A source
+// repository without workflow files is an error, since every variable would
+// be left out.
+func (m *Migrator) loadUsedVars() error {
+	if !m.config.SkipUnused || m.usedVars != nil {
+		return nil
+	}
+	owner, repo := m.config.SourceOwner, m.config.SourceRepo
+	files, err := m.sourceClient.ListWorkflowFiles(owner, repo)
+	if err != nil {
+		return fmt.Errorf("failed to read the source workflows for --skip-unused: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no workflow files in source repository %s/%s; --skip-unused would leave out every variable", owner, repo)
+	}
+
+	m.usedVars = map[string]bool{}
+	for _, f := range files {
+		for _, name := range workflows.References(f.Content) {
+			m.usedVars[name] = true
+		}
+	}
+	logger.Info("Found %d variable name(s) referenced by %d workflow file(s) of %s/%s", len(m.usedVars), len(files), owner, repo)
+	return nil
+}
+
+// skipUnused leaves out the variables of scope that no source workflow
+// references and records them in result.Unused. Workflows cannot tell a
+// repository variable from an environment one, so a name is kept in every
+// scope once any workflow references it.
+func (m *Migrator) skipUnused(scope string, vars []types.Variable, result *types.MigrationResult) []types.Variable {
+	if m.usedVars == nil {
+		return vars
+	}
+
+	kept := make([]types.Variable, 0, len(vars))
+	unused := 0
+	for _, v := range vars {
+		if !m.usedVars[strings.ToUpper(v.Name)] {
+			logger.Debug("Variable '%s' is not referenced by any source workflow", v.Name)
+			result.AddUnused(types.VariableRef{Scope: scope, Name: v.Name})
+			unused++
+			continue
+		}
+		kept = append(kept, v)
+	}
+	if unused > 0 {
+		logger.Info("Left out %d variable(s) of %s not referenced by any source workflow (--skip-unused)", unused, scope)
+	}
+	return kept
+}
@@ -0,0 +1,213 @@
+package migrator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+const usageWorkflow = `name: Deploy
+on: push
+env:
+  REGION: ${{ vars.REGION }}
+jobs:
+  deploy:
+    if: vars.ENABLED == 'true'
+    environment: prod
+    runs-on: ubuntu-latest
+    steps:
+      - uses: acme/deploy@v1
+        with:
+          url: ${{ vars.URL }}
+          key-id: ${{ vars['DEPLOY_KEY_ID'] }}
+      - run: echo "${{ vars.ORG_WIDE }} ${{ vars.Missing }}"
+`
+
+// seedUsageFake returns a fake whose source repository has REGION,
+// DEPLOY_KEY_ID, and a prod environment with URL, and whose target
+// repository has ENABLED, an organization variable ORG_WIDE, and a workflow
+// referencing all of them and MISSING
+func seedUsageFake() *fakeGitHub {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "REGION", Value: "eu"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "DEPLOY_KEY_ID", Value: "k1"})
+	fake.addEnv("src", "app", "prod")
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "URL", Value: "https://prod"})
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "ENABLED", Value: "true"})
+	fake.setVar(orgVarsPath("dst"), types.Variable{Name: "ORG_WIDE", Value: "x", Visibility: "all"})
+	fake.addWorkflow("dst", "app", "deploy.yml", usageWorkflow)
+	return fake
+}
+
+func TestCheckUsage(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		fake := seedUsageFake()
+		cfg := repoToRepoConfig()
+		cfg.CheckUsage = true
+		cfg.DryRun = dryRun
+		cfg.Exclude = []string{"DEPLOY_*"}
+
+		var result *types.MigrationResult
+		out := captureStdout(t, func() {
+			var err error
+			result, err = newFakeMigrator(t, cfg, fake).Run()
+			if err != nil {
+				t.Errorf("Run() unexpected error: %v", err)
+			}
+		})
+
+		if result == nil || result.UnresolvedRefs != 2 || result.HasErrors() {
+			t.Fatalf("DryRun=%v: expected 2 unresolved references and no errors, got %+v", dryRun, result)
+		}
+		for _, want := range []string{
+			"vars.DEPLOY_KEY_ID is referenced by .github/workflows/deploy.yml but is neither migrated nor set in dst/app",
+			"vars.MISSING is referenced by .github/workflows/deploy.yml",
+			"Workflow references not in target: 2 (--check-usage)",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("DryRun=%v: expected %q in output:\n%s", dryRun, want, out)
+			}
+		}
+		for _, name := range []string{"REGION", "URL", "ENABLED", "ORG_WIDE"} {
+			if strings.Contains(out, "vars."+name+" is referenced") {
+				t.Errorf("DryRun=%v: %s is migrated or set in the target and must not be reported", dryRun, name)
+			}
+		}
+	}
+}
+
+// TestCheckUsage_RenamedAndNoWorkflows verifies that renamed variables are
+// matched by their target name, and that a target without workflows is not
+// an error
+func TestCheckUsage_RenamedAndNoWorkflows(t *testing.T) {
+	fake := seedUsageFake()
+	fake.addWorkflow("dst", "app", "deploy.yml", "x: ${{ vars.NEW_REGION }} ${{ vars.REGION }}")
+	cfg := repoToRepoConfig()
+	cfg.CheckUsage = true
+	cfg.SkipEnvs = true
+	cfg.Vars = []string{"REGION"}
+	cfg.TargetPrefix = "NEW_"
+	var result *types.MigrationResult
+	captureStdout(t, func() {
+		result, _ = newFakeMigrator(t, cfg, fake).Run()
+	})
+	if result.UnresolvedRefs != 1 {
+		t.Errorf("Expected only the old name REGION to be unresolved, got %d", result.UnresolvedRefs)
+	}
+
+	fake = newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "REGION", Value: "eu"})
+	cfg = repoToRepoConfig()
+	cfg.CheckUsage = true
+	out := captureStdout(t, func() {
+		result, _ = newFakeMigrator(t, cfg, fake).Run()
+	})
+	if result.UnresolvedRefs != 0 || result.HasErrors() {
+		t.Errorf("Unexpected result without workflows: %+v", result)
+	}
+	if !strings.Contains(out, "No workflow files in dst/app") {
+		t.Errorf("Expected a note about the missing workflows, got:\n%s", out)
+	}
+}
+
+const sourceWorkflow = `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make REGION=${{ vars.REGION }}
+      # ${{ vars.DEAD }} was removed with the old deploy job
+  deploy:
+    environment: prod
+    runs-on: ubuntu-latest
+    steps:
+      - run: ./deploy.sh "${{ vars['url'] }}"
+`
+
+// TestSkipUnused verifies that only the variables referenced by the source
+// workflows are migrated, in the repository and in every environment, and
+// that the others are listed as unused
+func TestSkipUnused(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "REGION", Value: "eu"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "DEAD", Value: "x"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "URL", Value: "https://default"})
+	fake.addEnv("src", "app", "prod")
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "URL", Value: "https://prod"})
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "OLD_URL", Value: "https://old"})
+	fake.addWorkflow("src", "app", "ci.yml", sourceWorkflow)
+
+	cfg := repoToRepoConfig()
+	cfg.SkipUnused = true
+	var result *types.MigrationResult
+	out := captureStdout(t, func() {
+		var err error
+		result, err = newFakeMigrator(t, cfg, fake).Run()
+		if err != nil {
+			t.Errorf("Run() unexpected error: %v", err)
+		}
+	})
+
+	if result == nil || result.Created != 3 || result.Filtered != 0 || result.HasErrors() {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	want := []types.VariableRef{{Scope: "repository", Name: "DEAD"}, {Scope: "env:prod", Name: "OLD_URL"}}
+	if !reflect.DeepEqual(result.Unused, want) {
+		t.Errorf("Unused = %v, want %v", result.Unused, want)
+	}
+	for _, name := range []string{"DEAD", "OLD_URL"} {
+		if _, ok := fake.getVar(repoVarsPath("dst", "app"), name); ok {
+			t.Errorf("%s is unused and must not be written", name)
+		}
+	}
+	if _, ok := fake.getVar(envVarsPath("dst", "app", "prod"), "OLD_URL"); ok {
+		t.Error("OLD_URL is unused and must not be written")
+	}
+	if !strings.Contains(out, "Unused: 2 (not referenced by source workflows; --skip-unused)") {
+		t.Errorf("Expected the unused count in the summary, got:\n%s", out)
+	}
+}
+
+// TestSkipUnused_Targets verifies that the source workflows are read once
+// for all targets and that the unused variables are listed per target
+func TestSkipUnused_Targets(t *testing.T) {
+	fake := seedTemplateFake()
+	fake.addWorkflow("acme", "template", "ci.yml", "run: echo ${{ vars.A }} ${{ vars.E }}")
+
+	cfg := targetsConfig("acme/api", "acme/web")
+	cfg.SkipUnused = true
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Created != 4 {
+		t.Errorf("Expected A and E in both targets, got %+v", result)
+	}
+	want := []types.VariableRef{{Scope: "acme/api:repository", Name: "B"}, {Scope: "acme/web:repository", Name: "B"}}
+	if !reflect.DeepEqual(result.Unused, want) {
+		t.Errorf("Unused = %v, want %v", result.Unused, want)
+	}
+	if n := fake.countCalls("GET repos/acme/template/contents/.github/workflows"); n != 1 {
+		t.Errorf("Expected the source workflows to be listed once, got %d", n)
+	}
+}
+
+// TestSkipUnused_NoWorkflows verifies that a source repository without
+// workflows stops the migration before anything is written
+func TestSkipUnused_NoWorkflows(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "REGION", Value: "eu"})
+
+	cfg := repoToRepoConfig()
+	cfg.SkipUnused = true
+	_, err := newFakeMigrator(t, cfg, fake).Run()
+	if err == nil || !strings.Contains(err.Error(), "no workflow files in source repository src/app") {
+		t.Fatalf("Expected an error about the missing workflows, got: %v", err)
+	}
+	if _, ok := fake.getVar(repoVarsPath("dst", "app"), "REGION"); ok {
+		t.Error("Nothing may be written without source workflows")
+	}
+}
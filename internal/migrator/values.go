@@ -0,0 +1,240 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/plan"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// maskedValue is shown in place of variable values in log output
+const maskedValue = "********"
+
+// newValueOverrides builds a value override lookup keyed by upper-cased
+// variable name. It returns nil when there are no overrides.
+func newValueOverrides(overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(overrides))
+	for name, value := range overrides {
+		out[strings.ToUpper(name)] = value
+	}
+	return out
+}
+
+// valueOverride returns the replacement value configured for a source
+// variable name, if any.
+func (m *Migrator) valueOverride(sourceName string) (string, bool) {
+	value, ok := m.valueOverrides[strings.ToUpper(sourceName)]
+	return value, ok
+}
+
+// targetValue returns the value to write to the target for a source
+// variable. An override wins outright; otherwise the configured
+// substitutions are applied to the source value.
+func (m *Migrator) targetValue(variable types.Variable) string {
+	if value, ok := m.valueOverride(variable.Name); ok {
+		return value
+	}
+	return rewriteValue(variable.Value, m.replacements, m.config.ReplaceWordBoundaries)
+}
+
+// isRewritten reports whether the substitutions change a source variable's
+// value. Overridden variables are never rewritten.
+func (m *Migrator) isRewritten(variable types.Variable) bool {
+	if _, ok := m.valueOverride(variable.Name); ok {
+		return false
+	}
+	return len(m.replacements) > 0 && m.targetValue(variable) != variable.Value
+}
+
+// valueNote describes how the target value differs from the source value for
+// log output. The value itself is always masked.
+func (m *Migrator) valueNote(variable types.Variable) string {
+	if _, ok := m.valueOverride(variable.Name); ok {
+		return " (value overridden: " + maskedValue + ")"
+	}
+	if m.isRewritten(variable) {
+		return " (value rewritten: " + maskedValue + ")"
+	}
+	return ""
+}
+
+// valueChange describes for a dry-run update how the variable that would be
+// written compares with the existing target variable. Values are masked,
+// showing only their lengths, unless ShowValues is set.
+func (m *Migrator) valueChange(target types.Variable, existing *types.Variable) string {
+	visibility := ""
+	if existing.Visibility != "" && target.Visibility != "" && existing.Visibility != target.Visibility {
+		visibility = fmt.Sprintf("visibility %s → %s", existing.Visibility, target.Visibility)
+	}
+
+	switch {
+	case target.Value == existing.Value && visibility == "":
+		return ": no change"
+	case target.Value == existing.Value:
+		return ": value unchanged, " + visibility
+	}
+
+	change := fmt.Sprintf("value differs (source %d chars, target %d chars)",
+		utf8.RuneCountInString(target.Value), utf8.RuneCountInString(existing.Value))
+	if m.config.ShowValues {
+		change = fmt.Sprintf("%q → %q", existing.Value, target.Value)
+	}
+	if visibility != "" {
+		change += ", " + visibility
+	}
+	return ": " + change
+}
+
+// isUnchanged reports whether an existing target variable already holds the
+// value and visibility that would be written, so that updating it would
+// change nothing. Only conflict strategies that overwrite are affected, and
+// variables with "selected" visibility are always written because their
+// repository selection is not compared. AlwaysWrite turns the check off.
+func (m *Migrator) isUnchanged(target types.Variable, existing *types.Variable) bool {
+	if m.config.AlwaysWrite || target.Visibility == "selected" {
+		return false
+	}
+	if strategy := m.config.ConflictStrategy(); strategy != types.ConflictOverwrite && strategy != types.ConflictPrompt {
+		return false
+	}
+	return target.Value == existing.Value && target.Visibility == existing.Visibility
+}
+
+// recordCreated counts a variable created (or that would be created in
+// dry-run mode) in the target scope.
+func (m *Migrator) recordCreated(scope string, variable types.Variable, result *types.MigrationResult) {
+	result.IncCreated()
+	result.AddDetail(types.VariableResult{Scope: scope, Name: variable.Name, Action: types.ActionCreated, Value: m.targetValue(variable), ValueHash: plan.HashValue(variable.Value)})
+	m.recordValueChanges(variable, result)
+}
+
+// recordUpdated counts a variable updated (or that would be updated in
+// dry-run mode) in the target scope.
+func (m *Migrator) recordUpdated(scope string, variable types.Variable, result *types.MigrationResult) {
+	result.IncUpdated()
+	result.AddDetail(types.VariableResult{Scope: scope, Name: variable.Name, Action: types.ActionUpdated, Value: m.targetValue(variable), ValueHash: plan.HashValue(variable.Value)})
+	m.recordValueChanges(variable, result)
+}
+
+// recordUnchanged counts an existing variable of the target scope that
+// already held the value to be written.
+func recordUnchanged(scope, name string, result *types.MigrationResult) {
+	result.IncUnchanged()
+	result.AddDetail(types.VariableResult{Scope: scope, Name: name, Action: types.ActionUnchanged})
+}
+
+// recordSkipped counts a variable that was left untouched in the target
+// scope, with the reason it was skipped.
+func recordSkipped(scope, name, reason string, result *types.MigrationResult) {
+	result.IncSkipped()
+	result.AddDetail(types.VariableResult{Scope: scope, Name: name, Action: types.ActionSkipped, Reason: reason})
+}
+
+// recordFailed records a variable that could not be migrated to the target
+// scope. The error itself is added to the result by the caller.
+func recordFailed(scope, name string, err error, result *types.MigrationResult) {
+	result.AddDetail(types.VariableResult{Scope: scope, Name: name, Action: types.ActionFailed, Reason: err.Error()})
+}
+
+// recordValueChanges counts value transformations applied to a variable that
+// was written to the target.
+func (m *Migrator) recordValueChanges(variable types.Variable, result *types.MigrationResult) {
+	if _, ok := m.valueOverride(variable.Name); ok {
+		result.Add(&result.Overridden, 1)
+		return
+	}
+	if m.isRewritten(variable) {
+		result.Add(&result.Rewritten, 1)
+	}
+}
+
+// newReplacements builds the list of value substitutions from the
+// configuration: the org/repo rewrites enabled by RewriteValues followed by
+// the custom replacements. Pairs with an empty or unchanged old string are
+// dropped, and the list is sorted longest-first so that overlapping
+// replacements prefer the most specific match.
+func newReplacements(cfg *types.MigrationConfig) []types.Replacement {
+	var pairs []types.Replacement
+	if cfg.RewriteValues {
+		switch cfg.Mode {
+		case types.ModeRepoToRepo:
+			pairs = append(pairs,
+				types.Replacement{Old: cfg.SourceOwner + "/" + cfg.SourceRepo, New: cfg.TargetOwner + "/" + cfg.TargetRepo},
+				types.Replacement{Old: cfg.SourceOwner, New: cfg.TargetOwner},
+				types.Replacement{Old: cfg.SourceRepo, New: cfg.TargetRepo},
+			)
+		case types.ModeOrgToOrg, types.ModeFanOut:
+			pairs = append(pairs, types.Replacement{Old: cfg.SourceOrg, New: cfg.TargetOrg})
+		case types.ModeOrgToRepo:
+			pairs = append(pairs, types.Replacement{Old: cfg.SourceOrg, New: cfg.TargetOwner})
+		case types.ModeRepoToOrg:
+			pairs = append(pairs, types.Replacement{Old: cfg.SourceOwner, New: cfg.TargetOrg})
+		}
+	}
+	pairs = append(pairs, cfg.Replacements...)
+
+	out := make([]types.Replacement, 0, len(pairs))
+	seen := make(map[string]bool, len(pairs))
+	for _, p := range pairs {
+		if p.Old == "" || p.Old == p.New || seen[p.Old] {
+			continue
+		}
+		seen[p.Old] = true
+		out = append(out, p)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return len(out[i].Old) > len(out[j].Old)
+	})
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// rewriteValue applies the substitutions to value in a single left-to-right
+// pass. At each position the first (longest) matching old string wins and
+// replaced text is never rescanned, so replacements cannot cascade. Matching
+// is a plain, case-sensitive substring match unless wordBoundaries is set, in
+// which case a match must not be preceded or followed by a letter, digit, or
+// underscore.
+func rewriteValue(value string, pairs []types.Replacement, wordBoundaries bool) string {
+	if len(pairs) == 0 || value == "" {
+		return value
+	}
+
+	var b strings.Builder
+	i := 0
+	for i < len(value) {
+		matched := false
+		for _, p := range pairs {
+			if !strings.HasPrefix(value[i:], p.Old) {
+				continue
+			}
+			end := i + len(p.Old)
+			if wordBoundaries && (i > 0 && isWordByte(value[i-1]) || end < len(value) && isWordByte(value[end])) {
+				continue
+			}
+			b.WriteString(p.New)
+			i = end
+			matched = true
+			break
+		}
+		if !matched {
+			b.WriteByte(value[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// isWordByte reports whether c is a letter, digit, or underscore
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,320 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// TestTargetVariable_ValueOverride verifies that an override takes precedence
+// over the source value and is matched case-insensitively
+func TestTargetVariable_ValueOverride(t *testing.T) {
+	m := &Migrator{
+		config:         &types.MigrationConfig{},
+		valueOverrides: newValueOverrides(map[string]string{"registry_url": "ghcr.io/target"}),
+	}
+
+	got, err := m.targetVariable(types.Variable{Name: "REGISTRY_URL", Value: "ghcr.io/source"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.Value != "ghcr.io/target" {
+		t.Errorf("Expected override value, got %q", got.Value)
+	}
+
+	got, err = m.targetVariable(types.Variable{Name: "OTHER", Value: "unchanged"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.Value != "unchanged" {
+		t.Errorf("Expected source value for non-overridden variable, got %q", got.Value)
+	}
+}
+
+// TestTargetVariable_EmptyOverride verifies that an empty override value still
+// replaces the source value
+func TestTargetVariable_EmptyOverride(t *testing.T) {
+	m := &Migrator{
+		config:         &types.MigrationConfig{},
+		valueOverrides: newValueOverrides(map[string]string{"FEATURE_FLAG": ""}),
+	}
+
+	got, err := m.targetVariable(types.Variable{Name: "FEATURE_FLAG", Value: "on"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.Value != "" {
+		t.Errorf("Expected empty override value, got %q", got.Value)
+	}
+}
+
+// TestRecordWritten_CountsOverrides verifies that written variables with an
+// override are counted in the result
+func TestRecordWritten_CountsOverrides(t *testing.T) {
+	m := &Migrator{
+		config:         &types.MigrationConfig{},
+		valueOverrides: newValueOverrides(map[string]string{"A": "x"}),
+	}
+	result := &types.MigrationResult{}
+
+	m.recordCreated(scopeOrg, types.Variable{Name: "A"}, result)
+	m.recordUpdated(scopeOrg, types.Variable{Name: "B"}, result)
+
+	if result.Created != 1 || result.Updated != 1 {
+		t.Errorf("Expected 1 created and 1 updated, got %d and %d", result.Created, result.Updated)
+	}
+	if result.Overridden != 1 {
+		t.Errorf("Expected Overridden 1, got %d", result.Overridden)
+	}
+}
+
+// TestValueNote_Masked verifies that override notes never reveal the value
+func TestValueNote_Masked(t *testing.T) {
+	m := &Migrator{
+		config:         &types.MigrationConfig{},
+		valueOverrides: newValueOverrides(map[string]string{"SECRETISH": "super-sensitive"}),
+	}
+
+	note := m.valueNote(types.Variable{Name: "SECRETISH", Value: "old"})
+	if !strings.Contains(note, maskedValue) || strings.Contains(note, "super-sensitive") {
+		t.Errorf("Expected masked note, got %q", note)
+	}
+	if note := m.valueNote(types.Variable{Name: "OTHER"}); note != "" {
+		t.Errorf("Expected no note for non-overridden variable, got %q", note)
+	}
+}
+
+// TestRewriteValue verifies substring substitution semantics
+func TestRewriteValue(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          string
+		pairs          []types.Replacement
+		wordBoundaries bool
+		want           string
+	}{
+		{
+			name:  "org in URL",
+			value: "https://github.com/oldorg/oldrepo",
+			pairs: []types.Replacement{{Old: "oldorg", New: "neworg"}},
+			want:  "https://github.com/neworg/oldrepo",
+		},
+		{
+			name:  "package scope",
+			value: "@oldorg/ui",
+			pairs: []types.Replacement{{Old: "oldorg", New: "neworg"}},
+			want:  "@neworg/ui",
+		},
+		{
+			name:  "every occurrence",
+			value: "acme,acme",
+			pairs: []types.Replacement{{Old: "acme", New: "corp"}},
+			want:  "corp,corp",
+		},
+		{
+			name:  "substring of another word is replaced by default",
+			value: "myacme acme-tools",
+			pairs: []types.Replacement{{Old: "acme", New: "corp"}},
+			want:  "mycorp corp-tools",
+		},
+		{
+			name:           "word boundaries skip embedded matches",
+			value:          "myacme acme-tools acme_x acme",
+			pairs:          []types.Replacement{{Old: "acme", New: "corp"}},
+			wordBoundaries: true,
+			want:           "myacme corp-tools acme_x corp",
+		},
+		{
+			name:  "overlapping replacements prefer longest",
+			value: "acme-infra and acme",
+			pairs: newReplacements(&types.MigrationConfig{Replacements: []types.Replacement{
+				{Old: "acme", New: "corp"},
+				{Old: "acme-infra", New: "platform"},
+			}}),
+			want: "platform and corp",
+		},
+		{
+			name:  "replacements do not cascade",
+			value: "a b",
+			pairs: []types.Replacement{{Old: "a", New: "b"}, {Old: "b", New: "c"}},
+			want:  "b c",
+		},
+		{
+			name:  "case-sensitive",
+			value: "ACME acme",
+			pairs: []types.Replacement{{Old: "acme", New: "corp"}},
+			want:  "ACME corp",
+		},
+		{
+			name:  "no pairs",
+			value: "unchanged",
+			want:  "unchanged",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteValue(tt.value, tt.pairs, tt.wordBoundaries); got != tt.want {
+				t.Errorf("rewriteValue(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewReplacements verifies org/repo rewrites are derived from the mode
+func TestNewReplacements(t *testing.T) {
+	repoCfg := &types.MigrationConfig{
+		Mode:          types.ModeRepoToRepo,
+		SourceOwner:   "oldorg",
+		SourceRepo:    "oldrepo",
+		TargetOwner:   "neworg",
+		TargetRepo:    "newrepo",
+		RewriteValues: true,
+	}
+	got := rewriteValue("https://github.com/oldorg/oldrepo.git", newReplacements(repoCfg), false)
+	if got != "https://github.com/neworg/newrepo.git" {
+		t.Errorf("Unexpected repo rewrite: %q", got)
+	}
+
+	orgCfg := &types.MigrationConfig{
+		Mode:          types.ModeOrgToOrg,
+		SourceOrg:     "oldorg",
+		TargetOrg:     "neworg",
+		RewriteValues: true,
+		Replacements:  []types.Replacement{{Old: "eu-1", New: "us-2"}},
+	}
+	got = rewriteValue("@oldorg/app@eu-1", newReplacements(orgCfg), false)
+	if got != "@neworg/app@us-2" {
+		t.Errorf("Unexpected org rewrite: %q", got)
+	}
+
+	if pairs := newReplacements(&types.MigrationConfig{Mode: types.ModeOrgToOrg, SourceOrg: "a", TargetOrg: "b"}); pairs != nil {
+		t.Errorf("Expected no replacements without --rewrite-values, got %v", pairs)
+	}
+}
+
+// TestRecordValueChanges_Rewritten verifies rewritten values are counted and
+// overrides are not rewritten
+func TestRecordValueChanges_Rewritten(t *testing.T) {
+	m := &Migrator{
+		config:         &types.MigrationConfig{},
+		valueOverrides: newValueOverrides(map[string]string{"PINNED": "oldorg-literal"}),
+		replacements:   []types.Replacement{{Old: "oldorg", New: "neworg"}},
+	}
+	result := &types.MigrationResult{}
+
+	m.recordCreated(scopeOrg, types.Variable{Name: "URL", Value: "https://github.com/oldorg"}, result)
+	m.recordCreated(scopeOrg, types.Variable{Name: "PLAIN", Value: "nothing to see"}, result)
+	m.recordCreated(scopeOrg, types.Variable{Name: "PINNED", Value: "oldorg"}, result)
+
+	if result.Rewritten != 1 {
+		t.Errorf("Expected Rewritten 1, got %d", result.Rewritten)
+	}
+	if result.Overridden != 1 {
+		t.Errorf("Expected Overridden 1, got %d", result.Overridden)
+	}
+	if v := m.targetValue(types.Variable{Name: "PINNED", Value: "oldorg"}); v != "oldorg-literal" {
+		t.Errorf("Expected override value to be written untouched, got %q", v)
+	}
+	if note := m.valueNote(types.Variable{Name: "URL", Value: "https://github.com/oldorg"}); !strings.Contains(note, "rewritten") || strings.Contains(note, "neworg") {
+		t.Errorf("Expected masked rewrite note, got %q", note)
+	}
+}
+
+func TestValueChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		showValues bool
+		target     types.Variable
+		existing   types.Variable
+		want       string
+	}{
+		{
+			name:     "masked",
+			target:   types.Variable{Name: "URL", Value: "https://new.io"},
+			existing: types.Variable{Name: "URL", Value: "https://x"},
+			want:     ": value differs (source 14 chars, target 9 chars)",
+		},
+		{
+			name:       "show values",
+			showValues: true,
+			target:     types.Variable{Name: "URL", Value: "https://new.io"},
+			existing:   types.Variable{Name: "URL", Value: "https://x"},
+			want:       `: "https://x" → "https://new.io"`,
+		},
+		{
+			name:     "identical",
+			target:   types.Variable{Name: "URL", Value: "same"},
+			existing: types.Variable{Name: "URL", Value: "same"},
+			want:     ": no change",
+		},
+		{
+			name:     "visibility only",
+			target:   types.Variable{Name: "URL", Value: "same", Visibility: "private"},
+			existing: types.Variable{Name: "URL", Value: "same", Visibility: "all"},
+			want:     ": value unchanged, visibility all → private",
+		},
+		{
+			name:     "value and visibility",
+			target:   types.Variable{Name: "URL", Value: "née", Visibility: "private"},
+			existing: types.Variable{Name: "URL", Value: "ne", Visibility: "all"},
+			want:     ": value differs (source 3 chars, target 2 chars), visibility all → private",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{config: &types.MigrationConfig{ShowValues: tt.showValues}}
+			if got := m.valueChange(tt.target, &tt.existing); got != tt.want {
+				t.Errorf("valueChange() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUnchanged(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      types.MigrationConfig
+		target   types.Variable
+		existing types.Variable
+		want     bool
+	}{
+		{name: "identical", target: types.Variable{Value: "eu"}, existing: types.Variable{Value: "eu"}, want: true},
+		{name: "differing", target: types.Variable{Value: "eu"}, existing: types.Variable{Value: "us"}, want: false},
+		{name: "trailing whitespace", target: types.Variable{Value: "eu "}, existing: types.Variable{Value: "eu"}, want: false},
+		{name: "trailing newline", target: types.Variable{Value: "eu"}, existing: types.Variable{Value: "eu\n"}, want: false},
+		{name: "both empty", target: types.Variable{}, existing: types.Variable{}, want: true},
+		{name: "always write", cfg: types.MigrationConfig{AlwaysWrite: true}, target: types.Variable{Value: "eu"}, existing: types.Variable{Value: "eu"}, want: false},
+		{
+			name:     "same value, different visibility",
+			target:   types.Variable{Value: "eu", Visibility: "private"},
+			existing: types.Variable{Value: "eu", Visibility: "all"},
+			want:     false,
+		},
+		{
+			name:     "same value and visibility",
+			target:   types.Variable{Value: "eu", Visibility: "private"},
+			existing: types.Variable{Value: "eu", Visibility: "private"},
+			want:     true,
+		},
+		{
+			name:     "selected visibility",
+			target:   types.Variable{Value: "eu", Visibility: "selected", SelectedRepositoryIDs: []int64{1}},
+			existing: types.Variable{Value: "eu", Visibility: "selected"},
+			want:     false,
+		},
+		{name: "prompt strategy", cfg: types.MigrationConfig{OnConflict: types.ConflictPrompt}, target: types.Variable{Value: "eu"}, existing: types.Variable{Value: "eu"}, want: true},
+		{name: "skip strategy", cfg: types.MigrationConfig{OnConflict: types.ConflictSkip}, target: types.Variable{Value: "eu"}, existing: types.Variable{Value: "eu"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{config: &tt.cfg}
+			if got := m.isUnchanged(tt.target, &tt.existing); got != tt.want {
+				t.Errorf("isUnchanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/logger"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// recordWritten remembers a variable written to the target so it can be
+// checked by --verify. Nothing is recorded when verification is disabled.
+func (m *Migrator) recordWritten(scope string, target types.Variable) {
+	if !m.config.Verify {
+		return
+	}
+	if m.written == nil {
+		m.written = make(map[string][]types.Variable)
+	}
+	m.written[scope] = append(m.written[scope], target)
+}
+
+// verify re-reads every scope that was written to, using a single list call
+// per scope, and compares each written variable's name and value with the
+// target. Matches are counted in result.Verified; missing variables and
+// stale values are counted in result.Mismatched and recorded as errors.
+func (m *Migrator) verify(result *types.MigrationResult) {
+	if len(m.written) == 0 {
+		return
+	}
+
+	logger.Info("Verifying target state")
+
+	scopes := make([]string, 0, len(m.written))
+	for scope := range m.written {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	for _, scope := range scopes {
+		written := m.written[scope]
+
+		targetVars, err := m.listTargetScope(scope)
+		if err != nil {
+			result.Add(&result.Mismatched, len(written))
+			result.AddError(fmt.Errorf("verification of %s failed: %w", scope, err))
+			continue
+		}
+
+		actual := make(map[string]types.Variable, len(targetVars))
+		for _, v := range targetVars {
+			actual[strings.ToUpper(v.Name)] = v
+		}
+
+		for _, want := range written {
+			got, ok := actual[strings.ToUpper(want.Name)]
+			switch {
+			case !ok:
+				result.Add(&result.Mismatched, 1)
+				result.AddError(fmt.Errorf("verification failed: variable '%s' not found in target %s", want.Name, scope))
+			case got.Value != want.Value:
+				result.Add(&result.Mismatched, 1)
+				result.AddError(fmt.Errorf("verification failed: variable '%s' in target %s has a different value than was written", want.Name, scope))
+			default:
+				result.Add(&result.Verified, 1)
+				logger.Debug("Verified variable '%s' in %s", want.Name, scope)
+			}
+		}
+	}
+}
+
+// listTargetScope lists the target variables for a scope label
+func (m *Migrator) listTargetScope(scope string) ([]types.Variable, error) {
+	switch {
+	case scope == scopeOrg:
+		return m.targetClient.ListOrgVariables(m.config.TargetOrg)
+	case scope == scopeRepo:
+		return m.targetClient.ListRepoVariables(m.config.TargetOwner, m.config.TargetRepo)
+	case strings.HasPrefix(scope, repoScope("")):
+		return m.targetClient.ListRepoVariables(m.config.TargetOrg, strings.TrimPrefix(scope, repoScope("")))
+	case strings.HasPrefix(scope, envScope("")):
+		envName := strings.TrimPrefix(scope, envScope(""))
+		return m.targetClient.ListEnvVariables(m.config.TargetOwner, m.config.TargetRepo, envName)
+	default:
+		return nil, fmt.Errorf("unknown scope: %s", scope)
+	}
+}
@@ -0,0 +1,105 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func repoVerifyConfig() *types.MigrationConfig {
+	return &types.MigrationConfig{
+		Mode:        types.ModeRepoToRepo,
+		SourceOwner: "src",
+		SourceRepo:  "app",
+		TargetOwner: "dst",
+		TargetRepo:  "app",
+		Verify:      true,
+	}
+}
+
+func TestVerify_AllMatch(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "A", Value: "1"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "B", Value: "2"})
+	fake.setVar(repoVarsPath("dst", "app"), types.Variable{Name: "B", Value: "old"})
+	fake.addEnv("src", "app", "prod")
+	fake.setVar(envVarsPath("src", "app", "prod"), types.Variable{Name: "E", Value: "3"})
+
+	result, err := newFakeMigrator(t, repoVerifyConfig(), fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Verified != 3 || result.Mismatched != 0 || result.HasErrors() {
+		t.Errorf("Expected 3 verified and no mismatches, got %+v", result)
+	}
+
+	// One list call per scope for verification, on top of the migration's own.
+	if got := fake.countCalls("GET " + repoVarsPath("dst", "app")); got != 1 {
+		t.Errorf("Expected 1 list call for target repository variables, got %d", got)
+	}
+	if got := fake.countCalls("GET " + envVarsPath("dst", "app", "prod")); got != 1 {
+		t.Errorf("Expected 1 list call for target environment variables, got %d", got)
+	}
+}
+
+func TestVerify_StaleValue(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "A", Value: "new"})
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "B", Value: "2"})
+	fake.staleValues[repoVarsPath("dst", "app")+"/A"] = "stale"
+
+	cfg := repoVerifyConfig()
+	cfg.SkipEnvs = true
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Verified != 1 || result.Mismatched != 1 {
+		t.Errorf("Expected 1 verified and 1 mismatched, got %+v", result)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Error(), "'A'") {
+		t.Fatalf("Expected a single mismatch error for A, got %v", result.Errors)
+	}
+	if strings.Contains(result.Errors[0].Error(), "stale") || strings.Contains(result.Errors[0].Error(), "new") {
+		t.Errorf("Mismatch error must not reveal values: %v", result.Errors[0])
+	}
+}
+
+func TestVerify_UsesTargetNames(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(orgVarsPath("src"), types.Variable{Name: "A", Value: "1", Visibility: "all"})
+
+	result, err := newFakeMigrator(t, &types.MigrationConfig{
+		Mode:         types.ModeOrgToOrg,
+		SourceOrg:    "src",
+		TargetOrg:    "dst",
+		TargetPrefix: "NEW_",
+		Verify:       true,
+	}, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Verified != 1 || result.Mismatched != 0 {
+		t.Errorf("Expected NEW_A to verify, got %+v", result)
+	}
+}
+
+func TestVerify_SkippedInDryRun(t *testing.T) {
+	fake := newFakeGitHub()
+	fake.setVar(repoVarsPath("src", "app"), types.Variable{Name: "A", Value: "1"})
+
+	cfg := repoVerifyConfig()
+	cfg.SkipEnvs = true
+	cfg.DryRun = true
+	result, err := newFakeMigrator(t, cfg, fake).Run()
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.Verified != 0 || result.Mismatched != 0 || result.HasErrors() {
+		t.Errorf("Expected no verification in dry-run, got %+v", result)
+	}
+	if got := fake.countCalls("GET " + repoVarsPath("dst", "app")); got != 0 {
+		t.Errorf("Expected no target list calls in dry-run, got %d", got)
+	}
+}
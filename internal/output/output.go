@@ -0,0 +1,54 @@
+// Package output writes the machine-readable output the commands produce
+// with --output json and --output csv.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Formats of the --output flag. Table is the human-readable output of each
+// command; JSON and CSV leave standard output to the payload alone.
+const (
+	Table = "table"
+	JSON  = "json"
+	CSV   = "csv"
+)
+
+// Formats lists the --output formats in the order they are documented
+var Formats = []string{Table, JSON, CSV}
+
+// Valid reports whether format is one of Formats
+func Valid(format string) bool {
+	for _, f := range Formats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJSON writes v to w as indented JSON followed by a newline
+func WriteJSON(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding output: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// WriteCSV writes the header and rows to w as CSV. The header is written
+// even without rows, so the columns are always known.
+func WriteCSV(w io.Writer, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
+	}
+	return nil
+}
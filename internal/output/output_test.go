@@ -0,0 +1,46 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	for _, f := range []string{"table", "json", "csv"} {
+		if !Valid(f) {
+			t.Errorf("Valid(%q) = false, want true", f)
+		}
+	}
+	for _, f := range []string{"", "JSON", "yaml"} {
+		if Valid(f) {
+			t.Errorf("Valid(%q) = true, want false", f)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b strings.Builder
+	if err := WriteJSON(&b, map[string]int{"created": 2}); err != nil {
+		t.Fatalf("WriteJSON() unexpected error: %v", err)
+	}
+	if want := "{\n  \"created\": 2\n}\n"; b.String() != want {
+		t.Errorf("WriteJSON() wrote %q, want %q", b.String(), want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b strings.Builder
+	err := WriteCSV(&b, []string{"name", "value"}, [][]string{{"API_URL", "https://a,b"}, {"QUOTE", `say "hi"`}})
+	if err != nil {
+		t.Fatalf("WriteCSV() unexpected error: %v", err)
+	}
+	want := "name,value\nAPI_URL,\"https://a,b\"\nQUOTE,\"say \"\"hi\"\"\"\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV() wrote %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := WriteCSV(&b, []string{"name"}, nil); err != nil || b.String() != "name\n" {
+		t.Errorf("WriteCSV() without rows wrote %q, %v", b.String(), err)
+	}
+}
@@ -0,0 +1,199 @@
+// Package plan saves and loads the writes a dry run intends to make, so that
+// exactly those writes can be applied after review.
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// Version is the plan file format version written by Save
+const Version = 1
+
+// Plan is the list of creates and updates found by a dry run, together with
+// the migration it was made for
+type Plan struct {
+	Version   int                 `json:"version"`
+	CreatedAt time.Time           `json:"created_at"`
+	Mode      types.MigrationMode `json:"mode"`
+	Source    string              `json:"source"`
+	Target    string              `json:"target,omitempty"`
+	Targets   []string            `json:"targets,omitempty"`
+
+	// Fingerprint identifies the migration options that decide what is
+	// written where; see Fingerprint.
+	Fingerprint string `json:"fingerprint"`
+	// ValuesIncluded records whether the actions carry target values
+	ValuesIncluded bool `json:"values_included"`
+
+	Actions []types.PlannedAction `json:"actions"`
+}
+
+// New builds the plan of a dry run from its result: one action per variable
+// that would be created or updated. Values are only kept when
+// includeValues is set.
+func New(cfg *types.MigrationConfig, result *types.MigrationResult, includeValues bool, createdAt time.Time) *Plan {
+	source, target := config.Endpoints(cfg)
+	p := &Plan{
+		Version:        Version,
+		CreatedAt:      createdAt.UTC(),
+		Mode:           cfg.Mode,
+		Source:         source,
+		Target:         target,
+		Targets:        targetList(cfg),
+		Fingerprint:    Fingerprint(cfg),
+		ValuesIncluded: includeValues,
+		Actions:        []types.PlannedAction{},
+	}
+	for _, d := range result.Details {
+		if d.Action != types.ActionCreated && d.Action != types.ActionUpdated {
+			continue
+		}
+		a := types.PlannedAction{Scope: d.Scope, Name: d.Name, Action: d.Action, ValueHash: d.ValueHash}
+		if includeValues {
+			a.Value = d.Value
+		}
+		p.Actions = append(p.Actions, a)
+	}
+	return p
+}
+
+// HashValue returns the hash recorded in a plan for a source value
+func HashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Fingerprint hashes the parts of cfg that decide which target scopes are
+// written and what is written to them: the mode, source, and targets, and
+// the name, value, environment, and visibility transformations. Filters,
+// conflict handling, and run options such as --dry-run are left out.
+func Fingerprint(cfg *types.MigrationConfig) string {
+	// Only strings, bools, slices, and string maps are encoded, which
+	// cannot fail; maps are encoded with sorted keys.
+	data, _ := json.Marshal(struct {
+		Mode                  types.MigrationMode
+		SourceOrg             string
+		SourceOwner           string
+		SourceRepo            string
+		TargetOrg             string
+		TargetOwner           string
+		TargetRepo            string
+		Targets               []string
+		TargetRepos           []string
+		AllRepos              bool
+		Deep                  bool
+		NameMap               map[string]string
+		TargetPrefix          string
+		TargetSuffix          string
+		ValueOverrides        map[string]string
+		RewriteValues         bool
+		Replacements          []types.Replacement
+		ReplaceWordBoundaries bool
+		EnvMap                map[string]string
+		RepoMap               map[string]string
+		Visibility            string
+		TargetVisibility      string
+		SelectedFallback      types.SelectedFallback
+	}{
+		cfg.Mode, cfg.SourceOrg, cfg.SourceOwner, cfg.SourceRepo,
+		cfg.TargetOrg, cfg.TargetOwner, cfg.TargetRepo, targetList(cfg), cfg.TargetRepos, cfg.AllRepos, cfg.Deep,
+		cfg.NameMap, cfg.TargetPrefix, cfg.TargetSuffix,
+		cfg.ValueOverrides, cfg.RewriteValues, cfg.Replacements, cfg.ReplaceWordBoundaries,
+		cfg.EnvMap, cfg.RepoMap, cfg.Visibility, cfg.TargetVisibility, cfg.SelectedFallback,
+	})
+	return HashValue(string(data))
+}
+
+// targetList returns the configured target repositories as owner/repo
+func targetList(cfg *types.MigrationConfig) []string {
+	var targets []string
+	for _, t := range cfg.Targets {
+		targets = append(targets, t.String())
+	}
+	return targets
+}
+
+// Check reports why the plan cannot be applied with cfg: a different mode,
+// source, or target, or different migration options
+func (p *Plan) Check(cfg *types.MigrationConfig) error {
+	source, target := config.Endpoints(cfg)
+	switch {
+	case p.Mode != cfg.Mode:
+		return fmt.Errorf("plan was made for a %s migration, not %s", p.Mode, cfg.Mode)
+	case p.Source != source:
+		return fmt.Errorf("plan was made for source %s, not %s", p.Source, source)
+	case p.Target != target || !slices.Equal(p.Targets, targetList(cfg)):
+		return fmt.Errorf("plan was made for target %s, not %s", describeTarget(p.Target, p.Targets), describeTarget(target, targetList(cfg)))
+	case p.Fingerprint != Fingerprint(cfg):
+		return fmt.Errorf("plan was made with different migration options (names, values, environments, or visibility); run the dry run with --plan-out again")
+	}
+	return nil
+}
+
+// describeTarget names the target of a plan or configuration for error
+// messages
+func describeTarget(target string, targets []string) string {
+	if len(targets) > 0 {
+		return strings.Join(targets, ", ")
+	}
+	return target
+}
+
+// Save writes the plan to path as indented JSON. The file is created with
+// owner-only permissions because it may contain variable values.
+func Save(path string, p *Plan) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	return nil
+}
+
+// Load reads and validates the plan at path
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: invalid plan: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Validate checks that the plan is complete enough to apply
+func (p *Plan) Validate() error {
+	if p.Version != Version {
+		return fmt.Errorf("unsupported plan version %d (expected %d)", p.Version, Version)
+	}
+	if p.Mode == "" || p.Source == "" || p.Fingerprint == "" {
+		return fmt.Errorf("plan is missing its mode, source, or fingerprint")
+	}
+	for i, a := range p.Actions {
+		if a.Scope == "" || a.Name == "" || a.ValueHash == "" {
+			return fmt.Errorf("action %d: scope, name, and value_sha256 are required", i+1)
+		}
+		if a.Action != types.ActionCreated && a.Action != types.ActionUpdated {
+			return fmt.Errorf("action %d: unknown action %q", i+1, a.Action)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func repoConfig() *types.MigrationConfig {
+	return &types.MigrationConfig{
+		Mode:        types.ModeRepoToRepo,
+		SourceOwner: "src",
+		SourceRepo:  "app",
+		TargetOwner: "dst",
+		TargetRepo:  "app",
+		DryRun:      true,
+	}
+}
+
+func sampleResult() *types.MigrationResult {
+	result := &types.MigrationResult{}
+	result.AddDetail(types.VariableResult{Scope: "repository", Name: "A", Action: types.ActionCreated, Value: "new-a", ValueHash: HashValue("a")})
+	result.AddDetail(types.VariableResult{Scope: "repository", Name: "B", Action: types.ActionSkipped, Reason: "already exists in target"})
+	result.AddDetail(types.VariableResult{Scope: "env:prod", Name: "C", Action: types.ActionUpdated, Value: "c", ValueHash: HashValue("c")})
+	result.AddDetail(types.VariableResult{Scope: "env:prod", Name: "D", Action: types.ActionUnchanged})
+	return result
+}
+
+func TestNew(t *testing.T) {
+	for _, include := range []bool{false, true} {
+		p := New(repoConfig(), sampleResult(), include, time.Now())
+
+		want := []types.PlannedAction{
+			{Scope: "repository", Name: "A", Action: types.ActionCreated, ValueHash: HashValue("a")},
+			{Scope: "env:prod", Name: "C", Action: types.ActionUpdated, ValueHash: HashValue("c")},
+		}
+		if include {
+			want[0].Value, want[1].Value = "new-a", "c"
+		}
+		if !reflect.DeepEqual(p.Actions, want) {
+			t.Errorf("include=%v: Actions = %+v, want %+v", include, p.Actions, want)
+		}
+		if p.Source != "src/app" || p.Target != "dst/app" || p.ValuesIncluded != include {
+			t.Errorf("Unexpected plan header: %+v", p)
+		}
+	}
+}
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	p := New(repoConfig(), sampleResult(), true, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := Save(path, p); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected file mode 0600, got %o", perm)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("Round trip mismatch:\n got  %+v\n want %+v", got, p)
+	}
+	if err := got.Check(repoConfig()); err != nil {
+		t.Errorf("Check() of the loaded plan with its own config: %v", err)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"invalid json", `{`, "invalid plan"},
+		{"wrong version", `{"version": 2, "mode": "repo-to-repo", "source": "a/b", "fingerprint": "x"}`, "unsupported plan version"},
+		{"missing fingerprint", `{"version": 1, "mode": "repo-to-repo", "source": "a/b"}`, "fingerprint"},
+		{"action without hash", `{"version": 1, "mode": "repo-to-repo", "source": "a/b", "fingerprint": "x", "actions": [{"scope": "repository", "name": "A", "action": "created"}]}`, "action 1"},
+		{"unknown action", `{"version": 1, "mode": "repo-to-repo", "source": "a/b", "fingerprint": "x", "actions": [{"scope": "repository", "name": "A", "action": "deleted", "value_sha256": "y"}]}`, "unknown action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "plan.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p := New(repoConfig(), sampleResult(), false, time.Now())
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *types.MigrationConfig)
+		wantErr string
+	}{
+		{name: "same configuration", modify: func(cfg *types.MigrationConfig) {}},
+		{name: "apply without dry run", modify: func(cfg *types.MigrationConfig) { cfg.DryRun = false }},
+		{name: "filters may differ", modify: func(cfg *types.MigrationConfig) { cfg.Include = []string{"A*"} }},
+		{name: "different target", modify: func(cfg *types.MigrationConfig) { cfg.TargetOwner = "other" }, wantErr: "plan was made for target dst/app, not other/app"},
+		{name: "different source", modify: func(cfg *types.MigrationConfig) { cfg.SourceRepo = "web" }, wantErr: "plan was made for source src/app"},
+		{name: "many targets", modify: func(cfg *types.MigrationConfig) {
+			cfg.Targets = []types.RepoRef{{Owner: "dst", Repo: "app"}}
+		}, wantErr: "plan was made for target"},
+		{name: "different mode", modify: func(cfg *types.MigrationConfig) {
+			cfg.Mode, cfg.SourceOrg, cfg.TargetOrg = types.ModeOrgToOrg, "src", "dst"
+		}, wantErr: "org-to-org"},
+		{name: "different prefix", modify: func(cfg *types.MigrationConfig) { cfg.TargetPrefix = "NEW_" }, wantErr: "different migration options"},
+		{name: "different env map", modify: func(cfg *types.MigrationConfig) { cfg.EnvMap = map[string]string{"PROD": "production"} }, wantErr: "different migration options"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := repoConfig()
+			tt.modify(cfg)
+			err := p.Check(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashValue(t *testing.T) {
+	if HashValue("a") == HashValue("b") {
+		t.Error("Different values must hash differently")
+	}
+	if got := HashValue(""); got != "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("HashValue(\"\") = %s", got)
+	}
+}
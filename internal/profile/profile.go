@@ -0,0 +1,168 @@
+// Package profile loads named connection profiles from the configuration
+// file. A profile bundles the organizations, repositories, hostnames, and
+// token environment variables of both sides of a recurring migration so
+// that they can be selected with --profile instead of repeated as flags.
+package profile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of the configuration file
+type Config struct {
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// path is the file the configuration was loaded from
+	path string
+}
+
+// Profile is one named connection. Tokens are never stored in the file;
+// SourceTokenEnv and TargetTokenEnv name the environment variables that
+// hold them.
+type Profile struct {
+	SourceOrg      string `yaml:"source_org,omitempty"`
+	SourceRepo     string `yaml:"source_repo,omitempty"`
+	SourceHostname string `yaml:"source_hostname,omitempty"`
+	SourceTokenEnv string `yaml:"source_token_env,omitempty"`
+	TargetOrg      string `yaml:"target_org,omitempty"`
+	TargetRepo     string `yaml:"target_repo,omitempty"`
+	TargetHostname string `yaml:"target_hostname,omitempty"`
+	TargetTokenEnv string `yaml:"target_token_env,omitempty"`
+}
+
+// envNamePattern matches the names accepted for the token variables
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// DefaultPath returns the configuration file used without --config:
+// gh-vars-migrator/config.yaml under $XDG_CONFIG_HOME, or under ~/.config
+// when that is not set.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("locating the configuration file: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "gh-vars-migrator", "config.yaml"), nil
+}
+
+// Load reads and validates the configuration file at path. Errors name the
+// file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading configuration file: %w", err)
+	}
+
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.path = path
+	return c, nil
+}
+
+// Parse decodes and validates configuration data. Unknown keys are
+// rejected, which also keeps tokens out of the file.
+func Parse(data []byte) (*Config, error) {
+	var c Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("configuration file is empty")
+		}
+		return nil, fmt.Errorf("invalid configuration: %s", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// validate checks the profile names and token variable names
+func (c *Config) validate() error {
+	for _, name := range c.Names() {
+		if name == "" {
+			return fmt.Errorf("profile with an empty name")
+		}
+		p := c.Profiles[name]
+		for _, env := range []struct{ key, value string }{
+			{"source_token_env", p.SourceTokenEnv},
+			{"target_token_env", p.TargetTokenEnv},
+		} {
+			if env.value != "" && !envNamePattern.MatchString(env.value) {
+				return fmt.Errorf("profile %s: %s %q is not an environment variable name", name, env.key, env.value)
+			}
+		}
+	}
+	return nil
+}
+
+// Put adds the profile called name, replacing any profile of that name
+func (c *Config) Put(name string, p Profile) {
+	if c.Profiles == nil {
+		c.Profiles = map[string]Profile{}
+	}
+	c.Profiles[name] = p
+}
+
+// Save validates the configuration and writes it to path, readable only by
+// its owner, creating the directory when needed. Comments of the file it
+// replaces are not kept.
+func (c *Config) Save(path string) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("encoding configuration: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("writing configuration file: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing configuration file: %w", err)
+	}
+	c.path = path
+	return nil
+}
+
+// Names returns the names of the profiles, sorted
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the profile called name. The error of an unknown name lists
+// the profiles that are defined.
+func (c *Config) Get(name string) (Profile, error) {
+	if p, ok := c.Profiles[name]; ok {
+		return p, nil
+	}
+	defined := "none"
+	if names := c.Names(); len(names) > 0 {
+		defined = strings.Join(names, ", ")
+	}
+	if c.path == "" {
+		return Profile{}, fmt.Errorf("profile %q not found (defined: %s)", name, defined)
+	}
+	return Profile{}, fmt.Errorf("profile %q not found in %s (defined: %s)", name, c.path, defined)
+}
@@ -0,0 +1,135 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `profiles:
+  ghes-to-cloud:
+    source_org: acme-legacy
+    source_hostname: github.acme.internal
+    source_token_env: GHES_TOKEN
+    target_org: acme
+    target_token_env: CLOUD_TOKEN
+  app:
+    source_org: acme
+    source_repo: app
+    target_org: acme-new
+    target_repo: app
+`
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	if got := c.Names(); !reflect.DeepEqual(got, []string{"app", "ghes-to-cloud"}) {
+		t.Errorf("Names() = %v", got)
+	}
+	p, err := c.Get("ghes-to-cloud")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	want := Profile{
+		SourceOrg: "acme-legacy", SourceHostname: "github.acme.internal", SourceTokenEnv: "GHES_TOKEN",
+		TargetOrg: "acme", TargetTokenEnv: "CLOUD_TOKEN",
+	}
+	if p != want {
+		t.Errorf("Get() = %+v, want %+v", p, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty", "", "configuration file is empty"},
+		{"malformed", "profiles:\n  app: [\n", "invalid configuration"},
+		{"unknown key", "profiles:\n  app:\n    source_org: acme\n    source_pat: ghp_x\n", "field source_pat not found"},
+		{"unknown top-level key", "default: app\n", "field default not found"},
+		{"bad token env", "profiles:\n  app:\n    source_token_env: ghp_abc-123\n", `profile app: source_token_env "ghp_abc-123"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_ErrorsNameFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("profiles: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Load() error = %v, want it to name %s", err, path)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected error for missing file, got nil")
+	}
+}
+
+func TestGet_Unknown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(sample), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	_, err = c.Get("prod")
+	want := `profile "prod" not found in ` + path + ` (defined: app, ghes-to-cloud)`
+	if err == nil || err.Error() != want {
+		t.Errorf("Get() error = %v, want %q", err, want)
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	got, err := DefaultPath()
+	if err != nil {
+		t.Fatalf("DefaultPath() unexpected error: %v", err)
+	}
+	if want := filepath.Join("/tmp/xdg", "gh-vars-migrator", "config.yaml"); got != want {
+		t.Errorf("DefaultPath() = %q, want %q", got, want)
+	}
+}
+
+func TestSave_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gh-vars-migrator", "config.yaml")
+	c := &Config{}
+	c.Put("app", Profile{SourceOrg: "acme", SourceRepo: "app", TargetOrg: "acme-new", TargetRepo: "app", TargetTokenEnv: "CLOUD_TOKEN"})
+	if err := c.Save(path); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Save() wrote %v, %v; want mode 0600", info, err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Profiles, c.Profiles) {
+		t.Errorf("Load() = %+v, want %+v", loaded.Profiles, c.Profiles)
+	}
+
+	c.Put("bad", Profile{SourceTokenEnv: "not a name"})
+	if err := c.Save(path); err == nil || !strings.Contains(err.Error(), "profile bad") {
+		t.Errorf("Save() error = %v, want the invalid profile named", err)
+	}
+}
@@ -0,0 +1,322 @@
+// Package report writes a machine-readable JSON record of a migration run,
+// suitable as an audit artifact.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/config"
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// SchemaVersion is the report format version written by Save. It is
+// increased whenever a field is removed or changes meaning.
+const SchemaVersion = 1
+
+// Report is the record of one migration run
+type Report struct {
+	SchemaVersion int       `json:"schema_version"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+
+	Config Config `json:"config"`
+
+	// Interrupted is set when the run was stopped by a signal before it
+	// finished; the counts and variables are then incomplete
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Aborted is set when the run was stopped at an interactive prompt
+	Aborted bool `json:"aborted,omitempty"`
+	// ErrorLimitReached is set when the run was stopped by --max-errors
+	ErrorLimitReached bool `json:"error_limit_reached,omitempty"`
+	// BudgetExhausted is set when the run was stopped by --max-api-calls
+	BudgetExhausted bool `json:"budget_exhausted,omitempty"`
+	// Empty is set when --fail-if-empty found no source variable to migrate
+	Empty bool `json:"empty,omitempty"`
+
+	Summary   Summary    `json:"summary"`
+	Metrics   Metrics    `json:"metrics"`
+	Scopes    []Scope    `json:"scopes"`
+	Variables []Variable `json:"variables"`
+	Errors    []string   `json:"errors"`
+
+	// Unused lists the source variables left out by --skip-unused
+	Unused []UnusedVariable `json:"unused,omitempty"`
+}
+
+// Config summarizes the configuration the run was started with
+type Config struct {
+	Mode    types.MigrationMode `json:"mode"`
+	Source  string              `json:"source"`
+	Target  string              `json:"target,omitempty"`
+	Targets []string            `json:"targets,omitempty"`
+	DryRun  bool                `json:"dry_run"`
+	// OnConflict is the effective conflict strategy ("overwrite" replaces
+	// existing target variables)
+	OnConflict types.ConflictStrategy `json:"on_conflict"`
+	Deep       bool                   `json:"deep,omitempty"`
+	// EnvironmentsOnly records that repository-level variables were left
+	// out on purpose, with --env or --envs-only
+	EnvironmentsOnly bool `json:"environments_only,omitempty"`
+	// Prune records that apply --manifest or import deleted undeclared
+	// variables
+	Prune bool `json:"prune,omitempty"`
+	// ValuesIncluded records whether variable values were written to the
+	// report
+	ValuesIncluded bool `json:"values_included"`
+}
+
+// Summary holds the totals of the run
+type Summary struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped"`
+	Failed    int `json:"failed"`
+	Filtered  int `json:"filtered"`
+	Conflicts int `json:"conflicts"`
+	Errors    int `json:"errors"`
+
+	// UnchangedSince counts source variables left out by --since
+	UnchangedSince int `json:"unchanged_since"`
+	// Unused counts source variables left out by --skip-unused
+	Unused int `json:"unused"`
+	// Deleted counts target variables removed by --prune of apply
+	// --manifest and import, and by the delete command
+	Deleted int `json:"deleted,omitempty"`
+}
+
+// Metrics holds the duration of the run and the API requests it made
+type Metrics struct {
+	DurationSeconds float64  `json:"duration_seconds"`
+	SourceAPICalls  APICalls `json:"source_api_calls"`
+	TargetAPICalls  APICalls `json:"target_api_calls"`
+}
+
+// APICalls counts the requests made by one client
+type APICalls struct {
+	Total    int            `json:"total"`
+	ByMethod map[string]int `json:"by_method"`
+}
+
+// newAPICalls converts the per-method counts of a client
+func newAPICalls(calls types.APICalls) APICalls {
+	byMethod := map[string]int{}
+	for method, n := range calls {
+		byMethod[method] = n
+	}
+	return APICalls{Total: calls.Total(), ByMethod: byMethod}
+}
+
+// Scope breaks the outcomes down per target scope: the organization, the
+// repository, and each environment, e.g. "env:prod" or "api:env:prod"
+type Scope struct {
+	Scope     string `json:"scope"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Skipped   int    `json:"skipped"`
+	Failed    int    `json:"failed"`
+	Deleted   int    `json:"deleted,omitempty"`
+}
+
+// Variable is the outcome of one variable
+type Variable struct {
+	Scope  string               `json:"scope"`
+	Name   string               `json:"name"`
+	Action types.VariableAction `json:"action"`
+	Reason string               `json:"reason,omitempty"`
+	Value  *string              `json:"value,omitempty"`
+}
+
+// UnusedVariable is a source variable that no source workflow references
+type UnusedVariable struct {
+	Scope string `json:"scope"`
+	Name  string `json:"name"`
+}
+
+// New starts a report for a run of cfg that started at startedAt. Values
+// are only recorded when includeValues is set.
+func New(cfg *types.MigrationConfig, startedAt time.Time, includeValues bool) *Report {
+	source, target := config.Endpoints(cfg)
+	r := &Report{
+		SchemaVersion: SchemaVersion,
+		StartedAt:     startedAt.UTC(),
+		Config: Config{
+			Mode:             cfg.Mode,
+			Source:           source,
+			Target:           target,
+			DryRun:           cfg.DryRun,
+			OnConflict:       cfg.ConflictStrategy(),
+			Deep:             cfg.Deep,
+			EnvironmentsOnly: cfg.SkipRepoVars,
+			Prune:            cfg.Prune,
+			ValuesIncluded:   includeValues,
+		},
+		Metrics: Metrics{
+			SourceAPICalls: newAPICalls(nil),
+			TargetAPICalls: newAPICalls(nil),
+		},
+		Scopes:    []Scope{},
+		Variables: []Variable{},
+		Errors:    []string{},
+	}
+	for _, t := range cfg.Targets {
+		r.Config.Targets = append(r.Config.Targets, t.String())
+	}
+	return r
+}
+
+// Finish completes the report with the result of the run and the error
+// that ended it, if any. result may be nil when the run failed before
+// producing one.
+func (r *Report) Finish(result *types.MigrationResult, runErr error, finishedAt time.Time) {
+	r.FinishedAt = finishedAt.UTC()
+	r.Metrics.DurationSeconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+
+	if result != nil {
+		r.Aborted = result.Aborted
+		r.Interrupted = r.Interrupted || result.Interrupted
+		r.ErrorLimitReached = result.ErrorLimitReached
+		r.BudgetExhausted = result.BudgetExhausted
+		r.Empty = result.Empty
+		if result.Duration > 0 {
+			r.Metrics.DurationSeconds = result.Duration.Seconds()
+		}
+		r.Metrics.SourceAPICalls = newAPICalls(result.SourceAPICalls)
+		r.Metrics.TargetAPICalls = newAPICalls(result.TargetAPICalls)
+		r.Summary = Summary{
+			Created:        result.Created,
+			Updated:        result.Updated,
+			Unchanged:      result.Unchanged,
+			Skipped:        result.Skipped,
+			Filtered:       result.Filtered,
+			UnchangedSince: result.UnchangedSince,
+			Unused:         len(result.Unused),
+			Conflicts:      result.Conflicts,
+			Deleted:        result.Deleted,
+		}
+		for _, s := range result.Scopes() {
+			r.Scopes = append(r.Scopes, Scope{Scope: s.Scope, Created: s.Created, Updated: s.Updated, Unchanged: s.Unchanged, Skipped: s.Skipped, Failed: s.Errors, Deleted: s.Deleted})
+			r.Summary.Failed += s.Errors
+		}
+		for _, d := range result.Details {
+			r.Variables = append(r.Variables, r.variable(d))
+		}
+		for _, u := range result.Unused {
+			r.Unused = append(r.Unused, UnusedVariable{Scope: u.Scope, Name: u.Name})
+		}
+		for _, err := range result.Errors {
+			r.Errors = append(r.Errors, err.Error())
+		}
+	}
+	if runErr != nil {
+		r.Errors = append(r.Errors, runErr.Error())
+	}
+	r.Summary.Errors = len(r.Errors)
+}
+
+// variable converts a detail record, dropping the value unless values are
+// included
+func (r *Report) variable(d types.VariableResult) Variable {
+	v := Variable{Scope: d.Scope, Name: d.Name, Action: d.Action, Reason: d.Reason}
+	if r.Config.ValuesIncluded && (d.Action == types.ActionCreated || d.Action == types.ActionUpdated) {
+		value := d.Value
+		v.Value = &value
+	}
+	return v
+}
+
+// Save writes the report to path as indented JSON. The file is created with
+// owner-only permissions because it may contain variable values.
+func Save(path string, r *Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
+
+// DefaultLastPath returns the file the report of the most recent run is
+// kept in when no other is configured: gh-vars-migrator/last-report.json
+// under the user cache directory.
+func DefaultLastPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locating the cache directory: %w", err)
+	}
+	return filepath.Join(dir, "gh-vars-migrator", "last-report.json"), nil
+}
+
+// Load reads the report at path, e.g. to retry its failed variables
+func Load(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading report: %w", err)
+	}
+
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: invalid report: %w", path, err)
+	}
+	if r.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("%s: unsupported report schema version %d (expected %d)", path, r.SchemaVersion, SchemaVersion)
+	}
+	if r.Config.Mode == "" || r.Config.Source == "" {
+		return nil, fmt.Errorf("%s: report is missing its mode or source", path)
+	}
+	return &r, nil
+}
+
+// Failed returns the variables the run recorded as failed, in report order
+func (r *Report) Failed() []types.VariableRef {
+	failed := []types.VariableRef{}
+	for _, v := range r.Variables {
+		if v.Action == types.ActionFailed {
+			failed = append(failed, types.VariableRef{Scope: v.Scope, Name: v.Name})
+		}
+	}
+	return failed
+}
+
+// Check reports why the failures of the run cannot be retried with cfg: a
+// different mode, source, or target, or a deep migration run without --deep
+// or the other way around
+func (r *Report) Check(cfg *types.MigrationConfig) error {
+	source, target := config.Endpoints(cfg)
+	var targets []string
+	for _, t := range cfg.Targets {
+		targets = append(targets, t.String())
+	}
+
+	switch {
+	case r.Config.Mode != cfg.Mode:
+		return fmt.Errorf("report was made for a %s migration, not %s", r.Config.Mode, cfg.Mode)
+	case r.Config.Source != source:
+		return fmt.Errorf("report was made for source %s, not %s", r.Config.Source, source)
+	case r.Config.Target != target || !slices.Equal(r.Config.Targets, targets):
+		return fmt.Errorf("report was made for target %s, not %s", describeTarget(r.Config.Target, r.Config.Targets), describeTarget(target, targets))
+	case r.Config.Deep && !cfg.Deep:
+		return fmt.Errorf("report was made for a --deep migration; pass --deep to retry it")
+	case !r.Config.Deep && cfg.Deep:
+		return fmt.Errorf("report was made without --deep")
+	}
+	return nil
+}
+
+// describeTarget names the target of a report or configuration for error
+// messages
+func describeTarget(target string, targets []string) string {
+	if len(targets) > 0 {
+		return strings.Join(targets, ", ")
+	}
+	return target
+}
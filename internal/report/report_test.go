@@ -0,0 +1,334 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func sampleResult() *types.MigrationResult {
+	result := &types.MigrationResult{Created: 1, Updated: 1, Skipped: 1, Conflicts: 2}
+	result.AddDetail(types.VariableResult{Scope: "repository", Name: "A", Action: types.ActionCreated, Value: "secret-a"})
+	result.AddDetail(types.VariableResult{Scope: "repository", Name: "B", Action: types.ActionSkipped, Reason: "already exists in target (--on-conflict=skip)"})
+	result.AddDetail(types.VariableResult{Scope: "env:prod", Name: "C", Action: types.ActionUpdated, Value: "secret-c"})
+	result.AddDetail(types.VariableResult{Scope: "env:prod", Name: "D", Action: types.ActionFailed, Reason: "failed to create: boom"})
+	result.AddError(errors.New("env 'prod' variable 'D': failed to create: boom"))
+	return result
+}
+
+func sampleConfig() *types.MigrationConfig {
+	return &types.MigrationConfig{
+		Mode:        types.ModeRepoToRepo,
+		SourceOwner: "src",
+		SourceRepo:  "app",
+		TargetOwner: "dst",
+		TargetRepo:  "app",
+		DryRun:      true,
+	}
+}
+
+// writeAndRead saves the report and decodes the file into a generic map, the
+// way external tooling would read it
+func writeAndRead(t *testing.T, r *Report) map[string]any {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := Save(path, r); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Report permissions = %o, want 600", perm)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestReport_RequiredFields(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := New(sampleConfig(), started, false)
+	r.Finish(sampleResult(), nil, started.Add(time.Minute))
+	doc := writeAndRead(t, r)
+
+	for _, field := range []string{"schema_version", "started_at", "finished_at", "config", "summary", "metrics", "scopes", "variables", "errors"} {
+		if _, ok := doc[field]; !ok {
+			t.Errorf("Report is missing required field %q", field)
+		}
+	}
+	if v := doc["schema_version"]; v != float64(SchemaVersion) {
+		t.Errorf("schema_version = %v, want %d", v, SchemaVersion)
+	}
+	if v := doc["finished_at"]; v != "2026-01-02T03:05:05Z" {
+		t.Errorf("finished_at = %v", v)
+	}
+
+	cfg := doc["config"].(map[string]any)
+	want := map[string]any{
+		"mode": "repo-to-repo", "source": "src/app", "target": "dst/app",
+		"dry_run": true, "on_conflict": "overwrite", "values_included": false,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config = %v, want %v", cfg, want)
+	}
+
+	summary := doc["summary"].(map[string]any)
+	if summary["created"] != 1.0 || summary["failed"] != 1.0 || summary["errors"] != 1.0 || summary["conflicts"] != 2.0 {
+		t.Errorf("Unexpected summary: %v", summary)
+	}
+	if n := len(doc["variables"].([]any)); n != 4 {
+		t.Errorf("Expected 4 variables, got %d", n)
+	}
+}
+
+func TestReport_Metrics(t *testing.T) {
+	result := sampleResult()
+	result.Duration = 1500 * time.Millisecond
+	result.SourceAPICalls = types.APICalls{"GET": 3}
+	result.TargetAPICalls = types.APICalls{"GET": 4, "POST": 1, "PATCH": 2}
+
+	started := time.Now()
+	r := New(sampleConfig(), started, false)
+	r.Finish(result, nil, started.Add(time.Minute))
+	doc := writeAndRead(t, r)
+
+	metrics := doc["metrics"].(map[string]any)
+	if metrics["duration_seconds"] != 1.5 {
+		t.Errorf("duration_seconds = %v, want 1.5", metrics["duration_seconds"])
+	}
+	target := metrics["target_api_calls"].(map[string]any)
+	if target["total"] != 7.0 || target["by_method"].(map[string]any)["PATCH"] != 2.0 {
+		t.Errorf("Unexpected target API calls: %v", target)
+	}
+	if source := metrics["source_api_calls"].(map[string]any); source["total"] != 3.0 {
+		t.Errorf("Unexpected source API calls: %v", source)
+	}
+}
+
+func TestReport_Values(t *testing.T) {
+	for _, include := range []bool{false, true} {
+		r := New(sampleConfig(), time.Now(), include)
+		r.Finish(sampleResult(), nil, time.Now())
+		doc := writeAndRead(t, r)
+
+		for _, v := range doc["variables"].([]any) {
+			variable := v.(map[string]any)
+			value, ok := variable["value"]
+			written := variable["action"] == "created" || variable["action"] == "updated"
+			switch {
+			case !include && ok:
+				t.Errorf("Values must be omitted by default, got %v", variable)
+			case include && written && (!ok || value == ""):
+				t.Errorf("Expected the value of %v with values included", variable)
+			case include && !written && ok:
+				t.Errorf("Skipped and failed variables carry no value, got %v", variable)
+			}
+		}
+	}
+}
+
+func TestReport_Scopes(t *testing.T) {
+	r := New(sampleConfig(), time.Now(), false)
+	r.Finish(sampleResult(), nil, time.Now())
+
+	want := []Scope{
+		{Scope: "repository", Created: 1, Skipped: 1},
+		{Scope: "env:prod", Updated: 1, Failed: 1},
+	}
+	if !reflect.DeepEqual(r.Scopes, want) {
+		t.Errorf("Scopes = %+v, want %+v", r.Scopes, want)
+	}
+}
+
+func TestReport_RunError(t *testing.T) {
+	r := New(sampleConfig(), time.Now(), false)
+	r.Finish(nil, errors.New("failed to list source repository variables"), time.Now())
+	doc := writeAndRead(t, r)
+
+	errs := doc["errors"].([]any)
+	if len(errs) != 1 || errs[0] != "failed to list source repository variables" {
+		t.Errorf("errors = %v", errs)
+	}
+	if vars := doc["variables"].([]any); len(vars) != 0 {
+		t.Errorf("Expected an empty variable list, got %v", vars)
+	}
+}
+
+func TestReport_Unchanged(t *testing.T) {
+	result := &types.MigrationResult{Updated: 1, Unchanged: 1}
+	result.AddDetail(types.VariableResult{Scope: "repository", Name: "A", Action: types.ActionUpdated, Value: "new"})
+	result.AddDetail(types.VariableResult{Scope: "repository", Name: "B", Action: types.ActionUnchanged})
+
+	r := New(sampleConfig(), time.Now(), true)
+	r.Finish(result, nil, time.Now())
+	doc := writeAndRead(t, r)
+
+	if summary := doc["summary"].(map[string]any); summary["unchanged"] != 1.0 {
+		t.Errorf("summary.unchanged = %v, want 1", summary["unchanged"])
+	}
+	if scope := doc["scopes"].([]any)[0].(map[string]any); scope["unchanged"] != 1.0 || scope["updated"] != 1.0 {
+		t.Errorf("Unexpected scope counts: %v", scope)
+	}
+	variable := doc["variables"].([]any)[1].(map[string]any)
+	if variable["action"] != "unchanged" {
+		t.Errorf("action = %v, want unchanged", variable["action"])
+	}
+	if _, ok := variable["value"]; ok {
+		t.Errorf("Unchanged variables carry no value, got %v", variable)
+	}
+}
+
+func TestLoad_Failed(t *testing.T) {
+	cfg := sampleConfig()
+	r := New(cfg, time.Now(), false)
+	result := sampleResult()
+	result.AddDetail(types.VariableResult{Scope: "organization", Name: "E", Action: types.ActionFailed, Reason: "failed to update: boom"})
+	result.AddDetail(types.VariableResult{Scope: "acme/api:env:prod", Name: "F", Action: types.ActionFailed, Reason: "failed to update: boom"})
+	r.Finish(result, nil, time.Now())
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := Save(path, r); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	want := []types.VariableRef{
+		{Scope: "env:prod", Name: "D"},
+		{Scope: "organization", Name: "E"},
+		{Scope: "acme/api:env:prod", Name: "F"},
+	}
+	if got := loaded.Failed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Failed() = %+v, want %+v", got, want)
+	}
+	if err := loaded.Check(cfg); err != nil {
+		t.Errorf("Check() with the run's own config: %v", err)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"invalid json", `[`, "invalid report"},
+		{"unsupported version", `{"schema_version": 9, "config": {"mode": "org-to-org", "source": "a"}}`, "unsupported report schema version 9"},
+		{"missing mode", `{"schema_version": 1, "config": {"source": "a"}}`, "missing its mode or source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "report.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReport_Check(t *testing.T) {
+	r := New(sampleConfig(), time.Now(), false)
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *types.MigrationConfig)
+		wantErr string
+	}{
+		{name: "same source and target", modify: func(cfg *types.MigrationConfig) {}},
+		{name: "real run after a dry run", modify: func(cfg *types.MigrationConfig) { cfg.DryRun = false }},
+		{name: "different target", modify: func(cfg *types.MigrationConfig) { cfg.TargetRepo = "web" }, wantErr: "report was made for target dst/app, not dst/web"},
+		{name: "different source", modify: func(cfg *types.MigrationConfig) { cfg.SourceOwner = "other" }, wantErr: "report was made for source src/app, not other/app"},
+		{name: "different mode", modify: func(cfg *types.MigrationConfig) {
+			cfg.Mode, cfg.SourceOrg, cfg.TargetOrg = types.ModeOrgToOrg, "src", "dst"
+		}, wantErr: "report was made for a repo-to-repo migration"},
+		{name: "deep", modify: func(cfg *types.MigrationConfig) { cfg.Deep = true }, wantErr: "without --deep"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := sampleConfig()
+			tt.modify(cfg)
+			err := r.Check(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestReport_Interrupted verifies that the partial result of an interrupted
+// run is recorded and marked as interrupted
+func TestReport_Interrupted(t *testing.T) {
+	result := &types.MigrationResult{Created: 1, Interrupted: true}
+	result.AddDetail(types.VariableResult{Scope: "repository", Name: "A", Action: types.ActionCreated})
+
+	r := New(sampleConfig(), time.Now(), false)
+	r.Finish(result, nil, time.Now())
+	doc := writeAndRead(t, r)
+
+	if doc["interrupted"] != true {
+		t.Errorf("interrupted = %v, want true", doc["interrupted"])
+	}
+	if summary := doc["summary"].(map[string]any); summary["created"] != 1.0 {
+		t.Errorf("Expected the partial counts, got %v", summary)
+	}
+	if vars := doc["variables"].([]any); len(vars) != 1 {
+		t.Errorf("Expected the partial variable list, got %v", vars)
+	}
+}
+
+func TestReport_Unused(t *testing.T) {
+	result := &types.MigrationResult{Created: 1}
+	result.AddDetail(types.VariableResult{Scope: "repository", Name: "A", Action: types.ActionCreated})
+	result.Unused = []types.VariableRef{{Scope: "repository", Name: "OLD"}, {Scope: "env:prod", Name: "LEGACY"}}
+
+	r := New(sampleConfig(), time.Now(), false)
+	r.Finish(result, nil, time.Now())
+	doc := writeAndRead(t, r)
+
+	if summary := doc["summary"].(map[string]any); summary["unused"] != 2.0 {
+		t.Errorf("summary.unused = %v, want 2", summary["unused"])
+	}
+	want := []any{
+		map[string]any{"scope": "repository", "name": "OLD"},
+		map[string]any{"scope": "env:prod", "name": "LEGACY"},
+	}
+	if !reflect.DeepEqual(doc["unused"], want) {
+		t.Errorf("unused = %v, want %v", doc["unused"], want)
+	}
+
+	r = New(sampleConfig(), time.Now(), false)
+	r.Finish(sampleResult(), nil, time.Now())
+	if _, ok := writeAndRead(t, r)["unused"]; ok {
+		t.Error("The unused list must be left out when nothing was skipped as unused")
+	}
+}
@@ -0,0 +1,130 @@
+// Package snapshot saves and loads the state of target variables captured
+// before a migration so that the target can later be rolled back.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+// Version is the snapshot file format version written by Save
+const Version = 1
+
+// Scope kinds stored in a snapshot
+const (
+	KindOrg  = "organization"
+	KindRepo = "repository"
+	KindEnv  = "environment"
+)
+
+// Snapshot is the captured state of every target scope a migration may
+// write to. It includes variable values, so files should be kept private.
+type Snapshot struct {
+	Version   int                 `json:"version"`
+	CreatedAt time.Time           `json:"created_at"`
+	Mode      types.MigrationMode `json:"mode"`
+
+	// Target location: TargetOrg when the mode targets an organization,
+	// otherwise TargetOwner and TargetRepo.
+	TargetOrg   string `json:"target_org,omitempty"`
+	TargetOwner string `json:"target_owner,omitempty"`
+	TargetRepo  string `json:"target_repo,omitempty"`
+
+	Scopes []Scope `json:"scopes"`
+}
+
+// Scope holds the variables of one target scope at snapshot time
+type Scope struct {
+	Kind        string `json:"kind"`
+	Environment string `json:"environment,omitempty"`
+	// EnvironmentMissing records that the environment did not exist in the
+	// target when the snapshot was taken.
+	EnvironmentMissing bool             `json:"environment_missing,omitempty"`
+	Variables          []types.Variable `json:"variables"`
+}
+
+// Label returns a short human-readable name for the scope, e.g.
+// "repository" or "env:production".
+func (s Scope) Label() string {
+	if s.Kind == KindEnv {
+		return "env:" + s.Environment
+	}
+	return s.Kind
+}
+
+// Save writes the snapshot to path as indented JSON. The file is created
+// with owner-only permissions because it contains variable values.
+func Save(path string, snap *Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load reads and validates the snapshot at path
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("%s: invalid snapshot: %w", path, err)
+	}
+	if err := snap.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &snap, nil
+}
+
+// Validate checks that the snapshot is complete enough to roll back from
+func (s *Snapshot) Validate() error {
+	if s.Version != Version {
+		return fmt.Errorf("unsupported snapshot version %d (expected %d)", s.Version, Version)
+	}
+
+	switch s.Mode {
+	case types.ModeOrgToOrg, types.ModeRepoToOrg:
+		if s.TargetOrg == "" {
+			return fmt.Errorf("snapshot is missing the target organization")
+		}
+	case types.ModeRepoToRepo, types.ModeOrgToRepo:
+		if s.TargetOwner == "" || s.TargetRepo == "" {
+			return fmt.Errorf("snapshot is missing the target repository")
+		}
+	default:
+		return fmt.Errorf("snapshot has unsupported mode %q", s.Mode)
+	}
+
+	for i, scope := range s.Scopes {
+		switch scope.Kind {
+		case KindOrg:
+			if !s.Mode.TargetsOrg() {
+				return fmt.Errorf("scope %d: organization scope in a %s snapshot", i+1, s.Mode)
+			}
+		case KindRepo:
+			if s.Mode.TargetsOrg() {
+				return fmt.Errorf("scope %d: repository scope in a %s snapshot", i+1, s.Mode)
+			}
+		case KindEnv:
+			if s.Mode != types.ModeRepoToRepo {
+				return fmt.Errorf("scope %d: environment scope in a %s snapshot", i+1, s.Mode)
+			}
+			if scope.Environment == "" {
+				return fmt.Errorf("scope %d: environment scope without a name", i+1)
+			}
+		default:
+			return fmt.Errorf("scope %d: unknown kind %q", i+1, scope.Kind)
+		}
+	}
+	return nil
+}
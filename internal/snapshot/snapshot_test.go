@@ -0,0 +1,117 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/types"
+)
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	snap := &Snapshot{
+		Version:     Version,
+		CreatedAt:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Mode:        types.ModeRepoToRepo,
+		TargetOwner: "acme",
+		TargetRepo:  "app",
+		Scopes: []Scope{
+			{Kind: KindRepo, Variables: []types.Variable{{Name: "A", Value: "1"}}},
+			{Kind: KindEnv, Environment: "prod", Variables: []types.Variable{{Name: "B", Value: "line1\nline2"}}},
+			{Kind: KindEnv, Environment: "new", EnvironmentMissing: true, Variables: []types.Variable{}},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := Save(path, snap); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() unexpected error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected file mode 0600, got %o", perm)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, snap) {
+		t.Errorf("Round trip mismatch:\n got  %+v\n want %+v", got, snap)
+	}
+}
+
+func TestSaveLoad_OrgSelectedVisibility(t *testing.T) {
+	snap := &Snapshot{
+		Version:   Version,
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Mode:      types.ModeOrgToOrg,
+		TargetOrg: "acme",
+		Scopes: []Scope{{Kind: KindOrg, Variables: []types.Variable{
+			{Name: "A", Value: "1", Visibility: "selected", SelectedRepositoryIDs: []int64{3, 7}},
+		}}},
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := Save(path, snap); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got.Scopes[0].Variables, snap.Scopes[0].Variables) {
+		t.Errorf("Expected selected repository IDs to survive, got %+v", got.Scopes[0].Variables)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"invalid json", `{`, "invalid snapshot"},
+		{"wrong version", `{"version": 99, "mode": "org-to-org", "target_org": "a"}`, "unsupported snapshot version"},
+		{"unknown mode", `{"version": 1, "mode": "other"}`, "unsupported mode"},
+		{"missing org", `{"version": 1, "mode": "org-to-org"}`, "target organization"},
+		{"missing repo", `{"version": 1, "mode": "repo-to-repo", "target_owner": "a"}`, "target repository"},
+		{"env without name", `{"version": 1, "mode": "repo-to-repo", "target_owner": "a", "target_repo": "b", "scopes": [{"kind": "environment"}]}`, "without a name"},
+		{"org scope in repo snapshot", `{"version": 1, "mode": "repo-to-repo", "target_owner": "a", "target_repo": "b", "scopes": [{"kind": "organization"}]}`, "organization scope"},
+		{"unknown kind", `{"version": 1, "mode": "org-to-org", "target_org": "a", "scopes": [{"kind": "team"}]}`, "unknown kind"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "nope.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestScopeLabel(t *testing.T) {
+	if got := (Scope{Kind: KindRepo}).Label(); got != "repository" {
+		t.Errorf("Label() = %q, want repository", got)
+	}
+	if got := (Scope{Kind: KindEnv, Environment: "prod"}).Label(); got != "env:prod" {
+		t.Errorf("Label() = %q, want env:prod", got)
+	}
+}
@@ -0,0 +1,283 @@
+// Package terraform renders exported variables as resources of the
+// Terraform GitHub provider: github_actions_organization_variable,
+// github_actions_variable, and github_actions_environment_variable blocks.
+// Values are references to input variables unless they are inlined, and
+// the output is sorted so that the same variables always render the same.
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/renan-alm/gh-vars-migrator/internal/dump"
+)
+
+// Format is the export --format value that selects Terraform output
+const Format = "terraform"
+
+// Options control how a dump is rendered
+type Options struct {
+	// ModulePrefix is prepended to every resource, data source, and input
+	// variable name, so that the output can sit next to other resources
+	ModulePrefix string
+	// InlineValues writes the values into the resources instead of
+	// references to input variables
+	InlineValues bool
+}
+
+// Resource types of the GitHub provider
+const (
+	orgVariable  = "github_actions_organization_variable"
+	repoVariable = "github_actions_variable"
+	envVariable  = "github_actions_environment_variable"
+)
+
+// block is one rendered block: its type, labels, and attributes in order
+type block struct {
+	kind   string
+	labels []string
+	attrs  [][2]string
+}
+
+// renderer collects the blocks of one dump and keeps the names it has
+// given out unique per block type
+type renderer struct {
+	opts   Options
+	used   map[string]bool
+	inputs []block
+	data   []block
+	body   []block
+	repos  map[string]string
+}
+
+// Write renders d to w as HCL. Organization variables become
+// github_actions_organization_variable resources, those of a repository
+// github_actions_variable, and those of an environment
+// github_actions_environment_variable; the selected repositories of an
+// organization variable are looked up with github_repository data sources.
+func Write(w io.Writer, d *dump.Dump, opts Options) error {
+	r := &renderer{opts: opts, used: map[string]bool{}, repos: map[string]string{}}
+
+	switch {
+	case d.Repo == "":
+		for _, v := range sorted(d.Variables) {
+			r.orgVariable(d.Org, v)
+		}
+		for _, repo := range sortedRepos(d.Repositories) {
+			r.repoVariables(repo.Name, repo.Name+"_", repo.Variables, repo.Environments)
+		}
+	case d.Environment != "":
+		for _, v := range sorted(d.Variables) {
+			r.envVariable(d.Repo, d.Environment, d.Environment+"_", v)
+		}
+	default:
+		r.repoVariables(d.Repo, "", d.Variables, d.Environments)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by gh-vars-migrator from %s\n", label(d))
+	for _, blocks := range [][]block{r.inputs, r.data, r.body} {
+		for _, blk := range blocks {
+			b.WriteString("\n")
+			writeBlock(&b, blk)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// label names the rendered scope in the header comment
+func label(d *dump.Dump) string {
+	switch {
+	case d.Repo == "":
+		return "organization " + d.Org
+	case d.Environment != "":
+		return d.Org + "/" + d.Repo + " environment " + d.Environment
+	}
+	return d.Org + "/" + d.Repo
+}
+
+// repoVariables adds the variables of a repository and of its
+// environments; prefix starts the names of their resources
+func (r *renderer) repoVariables(repo, prefix string, vars []dump.Variable, envs []dump.Environment) {
+	for _, v := range sorted(vars) {
+		name := r.name("resource", prefix+v.Name)
+		r.body = append(r.body, block{kind: "resource", labels: []string{repoVariable, name}, attrs: [][2]string{
+			{"repository", quote(repo)},
+			{"variable_name", quote(v.Name)},
+			{"value", r.value(name, v.Value)},
+		}})
+	}
+	envs = append([]dump.Environment(nil), envs...)
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+	for _, env := range envs {
+		for _, v := range sorted(env.Variables) {
+			r.envVariable(repo, env.Name, prefix+env.Name+"_", v)
+		}
+	}
+}
+
+// envVariable adds one environment variable
+func (r *renderer) envVariable(repo, env, prefix string, v dump.Variable) {
+	name := r.name("resource", prefix+v.Name)
+	r.body = append(r.body, block{kind: "resource", labels: []string{envVariable, name}, attrs: [][2]string{
+		{"repository", quote(repo)},
+		{"environment", quote(env)},
+		{"variable_name", quote(v.Name)},
+		{"value", r.value(name, v.Value)},
+	}})
+}
+
+// orgVariable adds one organization variable, with the data sources of its
+// selected repositories
+func (r *renderer) orgVariable(org string, v dump.Variable) {
+	name := r.name("resource", v.Name)
+	visibility := v.Visibility
+	if visibility == "" {
+		visibility = "all"
+	}
+	attrs := [][2]string{
+		{"variable_name", quote(v.Name)},
+		{"visibility", quote(visibility)},
+		{"value", r.value(name, v.Value)},
+	}
+	if visibility == "selected" {
+		repos := append([]string(nil), v.SelectedRepositories...)
+		sort.Strings(repos)
+		refs := make([]string, 0, len(repos))
+		for _, repo := range repos {
+			refs = append(refs, "data.github_repository."+r.repoData(org, repo)+".repo_id")
+		}
+		attrs = append(attrs, [2]string{"selected_repository_ids", "[" + strings.Join(refs, ", ") + "]"})
+	}
+	r.body = append(r.body, block{kind: "resource", labels: []string{orgVariable, name}, attrs: attrs})
+}
+
+// repoData returns the name of the github_repository data source of a
+// repository, adding it the first time
+func (r *renderer) repoData(org, repo string) string {
+	if name, ok := r.repos[repo]; ok {
+		return name
+	}
+	name := r.name("github_repository", repo)
+	r.repos[repo] = name
+	r.data = append(r.data, block{kind: "data", labels: []string{"github_repository", name}, attrs: [][2]string{
+		{"full_name", quote(org + "/" + repo)},
+	}})
+	return name
+}
+
+// value returns the value expression of the resource called name: the
+// quoted value, or a reference to an input variable of the same name,
+// which is declared on the way
+func (r *renderer) value(name, value string) string {
+	if r.opts.InlineValues {
+		return quote(value)
+	}
+	r.inputs = append(r.inputs, block{kind: "variable", labels: []string{name}, attrs: [][2]string{
+		{"type", "string"},
+	}})
+	return "var." + name
+}
+
+// name turns s into a Terraform identifier with the module prefix, unique
+// within kind: lowercase letters, digits, and underscores, starting with a
+// letter or underscore, and suffixed with _2, _3, ... when another name
+// already sanitized to the same. Resources share one kind, so that their
+// input variables, which take their names, are unique as well.
+func (r *renderer) name(kind, s string) string {
+	base := sanitize(r.opts.ModulePrefix, s)
+	name := base
+	for n := 2; r.used[kind+"."+name]; n++ {
+		name = fmt.Sprintf("%s_%d", base, n)
+	}
+	r.used[kind+"."+name] = true
+	return name
+}
+
+// sanitize returns prefix and s joined by an underscore as a Terraform
+// identifier: lowercase, with every character other than a letter, digit,
+// or underscore replaced by an underscore, and a leading underscore added
+// when it would start with a digit
+func sanitize(prefix, s string) string {
+	if prefix != "" {
+		s = prefix + "_" + s
+	}
+	var b strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// quote returns s as an HCL string literal: quotes, backslashes, and
+// control characters are escaped, and "${" and "%{" are doubled so that
+// they are not read as template sequences
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\u%04x`, c)
+		case (c == '$' || c == '%') && strings.HasPrefix(s[i+1:], "{"):
+			b.WriteRune(c)
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// writeBlock writes a block with its attributes aligned as terraform fmt
+// aligns them
+func writeBlock(b *strings.Builder, blk block) {
+	b.WriteString(blk.kind)
+	for _, l := range blk.labels {
+		b.WriteString(" " + quote(l))
+	}
+	b.WriteString(" {\n")
+	width := 0
+	for _, a := range blk.attrs {
+		width = max(width, len(a[0]))
+	}
+	for _, a := range blk.attrs {
+		fmt.Fprintf(b, "  %-*s = %s\n", width, a[0], a[1])
+	}
+	b.WriteString("}\n")
+}
+
+// sorted returns vars sorted by name
+func sorted(vars []dump.Variable) []dump.Variable {
+	out := append([]dump.Variable(nil), vars...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// sortedRepos returns repos sorted by name
+func sortedRepos(repos []dump.Repository) []dump.Repository {
+	out := append([]dump.Repository(nil), repos...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
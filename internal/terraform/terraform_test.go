@@ -0,0 +1,262 @@
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/renan-alm/gh-vars-migrator/internal/dump"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// hclBlock is a top-level block read by parseHCL. Attrs holds the value of
+// literal string attributes and the source text of other expressions.
+type hclBlock struct {
+	kind   string
+	labels []string
+	attrs  map[string]string
+}
+
+// parseHCL parses src with the HCL native syntax parser and also checks what
+// Terraform itself would reject on top of it: top-level block names declared
+// twice, and references to undeclared input variables or data sources.
+func parseHCL(src string) ([]hclBlock, error) {
+	file, diags := hclsyntax.ParseConfig([]byte(src), "main.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body := file.Body.(*hclsyntax.Body)
+	if len(body.Attributes) > 0 {
+		return nil, fmt.Errorf("unexpected top-level attributes")
+	}
+
+	var blocks []hclBlock
+	declared := map[string]bool{}
+	var refs []hcl.Traversal
+	for _, b := range body.Blocks {
+		blk := hclBlock{kind: b.Type, labels: b.Labels, attrs: map[string]string{}}
+		for name, attr := range b.Body.Attributes {
+			refs = append(refs, attr.Expr.Variables()...)
+			blk.attrs[name] = string(attr.Expr.Range().SliceBytes([]byte(src)))
+			if v, diags := attr.Expr.Value(nil); !diags.HasErrors() && v.Type() == cty.String {
+				blk.attrs[name] = v.AsString()
+			}
+		}
+		key := blk.kind + "." + strings.Join(blk.labels, ".")
+		if declared[key] {
+			return nil, fmt.Errorf("%s is declared more than once", key)
+		}
+		declared[key] = true
+		blocks = append(blocks, blk)
+	}
+
+	for _, ref := range refs {
+		parts := []string{ref.RootName()}
+		for _, step := range ref[1:] {
+			if attr, ok := step.(hcl.TraverseAttr); ok {
+				parts = append(parts, attr.Name)
+			}
+		}
+		switch {
+		// The type of an input variable is a bare keyword
+		case len(parts) == 1 && parts[0] == "string":
+		case parts[0] == "var" && len(parts) == 2 && declared["variable."+parts[1]]:
+		case parts[0] == "data" && len(parts) == 4 && declared["data."+parts[1]+"."+parts[2]]:
+		default:
+			return nil, fmt.Errorf("reference to undeclared %s", strings.Join(parts, "."))
+		}
+	}
+	return blocks, nil
+}
+
+func render(t *testing.T, d *dump.Dump, opts Options) (string, []hclBlock) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, d, opts); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	blocks, err := parseHCL(buf.String())
+	if err != nil {
+		t.Fatalf("Write() output is not valid HCL: %v\n%s", err, buf.String())
+	}
+	if formatted := hclwrite.Format(buf.Bytes()); !bytes.Equal(formatted, buf.Bytes()) {
+		t.Errorf("Write() output is not in canonical format; terraform fmt would write:\n%s", formatted)
+	}
+	return buf.String(), blocks
+}
+
+// find returns the block of the given kind and labels
+func find(t *testing.T, blocks []hclBlock, kind string, labels ...string) hclBlock {
+	t.Helper()
+	for _, b := range blocks {
+		if b.kind == kind && strings.Join(b.labels, ".") == strings.Join(labels, ".") {
+			return b
+		}
+	}
+	t.Fatalf("No %s %v block", kind, labels)
+	return hclBlock{}
+}
+
+func TestWrite_Repository(t *testing.T) {
+	d := &dump.Dump{
+		Org:  "acme",
+		Repo: "app",
+		Variables: []dump.Variable{
+			{Name: "TIMEOUT", Value: "30"},
+			{Name: "LOG_LEVEL", Value: "info"},
+		},
+		Environments: []dump.Environment{
+			{Name: "staging", Variables: []dump.Variable{{Name: "URL", Value: "https://staging.example.com"}}},
+			{Name: "production", Variables: []dump.Variable{{Name: "URL", Value: "https://app.example.com"}}},
+		},
+	}
+
+	want := `# Generated by gh-vars-migrator from acme/app
+
+variable "log_level" {
+  type = string
+}
+
+variable "timeout" {
+  type = string
+}
+
+variable "production_url" {
+  type = string
+}
+
+variable "staging_url" {
+  type = string
+}
+
+resource "github_actions_variable" "log_level" {
+  repository    = "app"
+  variable_name = "LOG_LEVEL"
+  value         = var.log_level
+}
+
+resource "github_actions_variable" "timeout" {
+  repository    = "app"
+  variable_name = "TIMEOUT"
+  value         = var.timeout
+}
+
+resource "github_actions_environment_variable" "production_url" {
+  repository    = "app"
+  environment   = "production"
+  variable_name = "URL"
+  value         = var.production_url
+}
+
+resource "github_actions_environment_variable" "staging_url" {
+  repository    = "app"
+  environment   = "staging"
+  variable_name = "URL"
+  value         = var.staging_url
+}
+`
+	got, _ := render(t, d, Options{})
+	if got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+
+	// The order of the input does not change the output
+	d.Variables[0], d.Variables[1] = d.Variables[1], d.Variables[0]
+	d.Environments[0], d.Environments[1] = d.Environments[1], d.Environments[0]
+	if again, _ := render(t, d, Options{}); again != got {
+		t.Errorf("Write() is not deterministic:\n%s\nthen\n%s", got, again)
+	}
+}
+
+func TestWrite_Organization(t *testing.T) {
+	tricky := "say \"hi\" to ${USER} at 100%{x}\n\tand \\ $5\x01"
+	d := &dump.Dump{
+		Org: "acme",
+		Variables: []dump.Variable{
+			{Name: "REGION", Value: "eu"},
+			{Name: "SHARED", Value: tricky, Visibility: "selected", SelectedRepositories: []string{"web", "app"}},
+			{Name: "PRIVATE_ONLY", Value: "p", Visibility: "private"},
+		},
+		Repositories: []dump.Repository{
+			{Name: "app.v2", Variables: []dump.Variable{{Name: "A", Value: "a"}}},
+		},
+	}
+
+	_, blocks := render(t, d, Options{ModulePrefix: "Platform-Team", InlineValues: true})
+
+	shared := find(t, blocks, "resource", orgVariable, "platform_team_shared")
+	if shared.attrs["value"] != tricky {
+		t.Errorf("value = %q, want %q", shared.attrs["value"], tricky)
+	}
+	if got, want := shared.attrs["selected_repository_ids"], "[data.github_repository.platform_team_app.repo_id, data.github_repository.platform_team_web.repo_id]"; got != want {
+		t.Errorf("selected_repository_ids = %s, want %s", got, want)
+	}
+	if got := find(t, blocks, "data", "github_repository", "platform_team_web").attrs["full_name"]; got != "acme/web" {
+		t.Errorf("full_name = %q, want acme/web", got)
+	}
+	region := find(t, blocks, "resource", orgVariable, "platform_team_region")
+	if region.attrs["visibility"] != "all" || region.attrs["variable_name"] != "REGION" {
+		t.Errorf("Unexpected REGION resource: %+v", region.attrs)
+	}
+	if got := find(t, blocks, "resource", orgVariable, "platform_team_private_only").attrs["visibility"]; got != "private" {
+		t.Errorf("visibility = %q, want private", got)
+	}
+	if got := find(t, blocks, "resource", repoVariable, "platform_team_app_v2_a").attrs["repository"]; got != "app.v2" {
+		t.Errorf("repository = %q, want app.v2", got)
+	}
+	for _, b := range blocks {
+		if b.kind == "variable" {
+			t.Errorf("Expected no input variables with inlined values, got %v", b.labels)
+		}
+	}
+}
+
+func TestWrite_UniqueNames(t *testing.T) {
+	d := &dump.Dump{
+		Org:  "acme",
+		Repo: "app",
+		Environments: []dump.Environment{
+			{Name: "prod-1", Variables: []dump.Variable{{Name: "URL", Value: "a"}}},
+			{Name: "prod_1", Variables: []dump.Variable{{Name: "URL", Value: "b"}}},
+			{Name: "1st", Variables: []dump.Variable{{Name: "URL", Value: "c"}}},
+		},
+	}
+
+	_, blocks := render(t, d, Options{})
+	for name, env := range map[string]string{"_1st_url": "1st", "prod_1_url": "prod-1", "prod_1_url_2": "prod_1"} {
+		if got := find(t, blocks, "resource", envVariable, name).attrs["environment"]; got != env {
+			t.Errorf("%s environment = %q, want %q", name, got, env)
+		}
+		find(t, blocks, "variable", name)
+	}
+}
+
+func TestWrite_Environment(t *testing.T) {
+	d := &dump.Dump{Org: "acme", Repo: "app", Environment: "production", Variables: []dump.Variable{{Name: "URL", Value: "x"}}}
+	out, blocks := render(t, d, Options{})
+	if !strings.HasPrefix(out, "# Generated by gh-vars-migrator from acme/app environment production\n") {
+		t.Errorf("Unexpected header:\n%s", out)
+	}
+	res := find(t, blocks, "resource", envVariable, "production_url")
+	if res.attrs["environment"] != "production" || res.attrs["value"] != "var.production_url" {
+		t.Errorf("Unexpected resource: %+v", res.attrs)
+	}
+}
+
+func TestParseHCL_Rejects(t *testing.T) {
+	for _, src := range []string{
+		"resource \"a\" \"b\" {\n  value = \"${x}\"\n}\n",
+		"resource \"a\" \"b\" {\n  value = var.missing\n}\n",
+		"resource \"a\" \"b\" {\n  x = \"1\"\n  x = \"2\"\n}\n",
+		"resource \"a\" \"b\" {\n  x = \"\\x01\"\n}\n",
+		"resource \"a\" \"b\" {\n}\nresource \"a\" \"b\" {\n}\n",
+	} {
+		if _, err := parseHCL(src); err == nil {
+			t.Errorf("parseHCL() accepted:\n%s", src)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"errors"
+	"sync"
 	"time"
 )
 
@@ -34,8 +35,38 @@ type Variable struct {
 
 // Repository represents a GitHub repository
 type Repository struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Archived bool   `json:"archived,omitempty"`
+
+	// Permissions are those of the authenticated user; they are only
+	// returned when a single repository is fetched
+	Permissions *RepoPermissions `json:"permissions,omitempty"`
+}
+
+// RepoPermissions are the access levels the authenticated user has on a
+// repository
+type RepoPermissions struct {
+	Admin bool `json:"admin"`
+	Push  bool `json:"push"`
+	Pull  bool `json:"pull"`
+}
+
+// Organization is a GitHub organization
+type Organization struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+}
+
+// RepoRef identifies a repository by owner and name
+type RepoRef struct {
+	Owner string
+	Repo  string
+}
+
+// String returns the repository as owner/name
+func (r RepoRef) String() string {
+	return r.Owner + "/" + r.Repo
 }
 
 // Environment represents a GitHub repository environment
@@ -44,6 +75,88 @@ type Environment struct {
 	Name      string `json:"name"`
 	CreatedAt string `json:"created_at,omitempty"`
 	UpdatedAt string `json:"updated_at,omitempty"`
+
+	// ProtectionRules and DeploymentBranchPolicy are only returned when a
+	// single environment is fetched; a nil DeploymentBranchPolicy allows
+	// deployments from every branch
+	ProtectionRules        []ProtectionRule        `json:"protection_rules,omitempty"`
+	DeploymentBranchPolicy *DeploymentBranchPolicy `json:"deployment_branch_policy,omitempty"`
+}
+
+// DeploymentBranchPolicy restricts the branches that can deploy to an
+// environment: either the protected branches, or the branches and tags
+// matching the environment's custom branch policies
+type DeploymentBranchPolicy struct {
+	ProtectedBranches    bool `json:"protected_branches"`
+	CustomBranchPolicies bool `json:"custom_branch_policies"`
+}
+
+// BranchPolicy is one custom deployment branch policy of an environment: a
+// branch or tag name pattern. Type is "branch" or "tag"; GitHub assumes
+// "branch" when it is empty.
+type BranchPolicy struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// ProtectionRule is one protection rule of an environment: a wait timer,
+// required reviewers, or a branch policy, depending on Type
+type ProtectionRule struct {
+	Type              string                `json:"type"`
+	WaitTimer         int                   `json:"wait_timer,omitempty"`
+	PreventSelfReview bool                  `json:"prevent_self_review,omitempty"`
+	Reviewers         []EnvironmentReviewer `json:"reviewers,omitempty"`
+}
+
+// Protection rule types returned by the environments API
+const (
+	RuleWaitTimer         = "wait_timer"
+	RuleRequiredReviewers = "required_reviewers"
+)
+
+// Reviewer types of an environment's required reviewers
+const (
+	ReviewerUser = "User"
+	ReviewerTeam = "Team"
+)
+
+// EnvironmentReviewer is a user or team whose approval an environment
+// requires. Login is set for users and Slug for teams.
+type EnvironmentReviewer struct {
+	Type     string `json:"type"`
+	Reviewer struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login,omitempty"`
+		Slug  string `json:"slug,omitempty"`
+	} `json:"reviewer"`
+}
+
+// EnvironmentSettings is the body that creates or updates an environment
+// with its protection settings
+type EnvironmentSettings struct {
+	WaitTimer              int                     `json:"wait_timer"`
+	PreventSelfReview      bool                    `json:"prevent_self_review"`
+	Reviewers              []ReviewerRef           `json:"reviewers"`
+	DeploymentBranchPolicy *DeploymentBranchPolicy `json:"deployment_branch_policy"`
+}
+
+// ReviewerRef identifies a required reviewer by type and ID when an
+// environment is written
+type ReviewerRef struct {
+	Type string `json:"type"`
+	ID   int64  `json:"id"`
+}
+
+// WorkflowFile is a GitHub Actions workflow file of a repository
+type WorkflowFile struct {
+	Path    string
+	Content string
+}
+
+// Replacement is a substring substitution applied to variable values
+type Replacement struct {
+	Old string
+	New string
 }
 
 // MigrationMode defines the type of migration to perform
@@ -52,6 +165,58 @@ type MigrationMode string
 const (
 	ModeRepoToRepo MigrationMode = "repo-to-repo"
 	ModeOrgToOrg   MigrationMode = "org-to-org"
+	ModeOrgToRepo  MigrationMode = "org-to-repo"
+	ModeRepoToOrg  MigrationMode = "repo-to-org"
+	ModeFanOut     MigrationMode = "fan-out"
+	// ModeManifest converges the target scopes named in a manifest on the
+	// variables it declares; there is no source
+	ModeManifest MigrationMode = "manifest"
+	// ModeImport creates and updates the variables of a file written by the
+	// export command in one target scope, and in the environments of a
+	// target repository; there is no source
+	ModeImport MigrationMode = "import"
+	// ModeDelete deletes the selected variables of one target scope; there
+	// is no source
+	ModeDelete MigrationMode = "delete"
+	// ModeCopy copies one variable of a source scope to one target scope
+	ModeCopy MigrationMode = "copy"
+)
+
+// TargetsOrg reports whether the mode writes organization variables rather
+// than repository variables
+func (m MigrationMode) TargetsOrg() bool {
+	return m == ModeOrgToOrg || m == ModeRepoToOrg
+}
+
+// ConflictStrategy controls what happens when a variable already exists in
+// the target
+type ConflictStrategy string
+
+const (
+	// ConflictOverwrite updates the existing variable (the default)
+	ConflictOverwrite ConflictStrategy = "overwrite"
+	// ConflictSkip leaves the existing variable untouched
+	ConflictSkip ConflictStrategy = "skip"
+	// ConflictFail aborts the run before any writes if a conflict exists
+	ConflictFail ConflictStrategy = "fail"
+	// ConflictPrompt asks interactively for every conflicting variable
+	ConflictPrompt ConflictStrategy = "prompt"
+)
+
+// SelectedFallback controls what happens to a "selected" organization
+// variable when none of its repositories exist in the target organization
+type SelectedFallback string
+
+const (
+	// FallbackEmpty creates the variable with zero selected repositories
+	// (the default)
+	FallbackEmpty SelectedFallback = "empty"
+	// FallbackPrivate creates the variable with "private" visibility
+	FallbackPrivate SelectedFallback = "private"
+	// FallbackAll creates the variable with "all" visibility
+	FallbackAll SelectedFallback = "all"
+	// FallbackSkip leaves the variable out and counts it as skipped
+	FallbackSkip SelectedFallback = "skip"
 )
 
 // MigrationConfig holds the configuration for a migration
@@ -68,33 +233,646 @@ type MigrationConfig struct {
 	TargetRepo  string
 	TargetOrg   string
 
-	// Environment variables settings
-	SkipEnvs bool
+	// Target name transformation applied to every migrated variable name.
+	// NameMap renames individual variables (source name → target name,
+	// case-insensitive); the prefix and suffix are applied afterwards.
+	NameMap      map[string]string
+	TargetPrefix string
+	TargetSuffix string
+
+	// ValueOverrides replaces the value written to the target for the named
+	// source variables (case-insensitive).
+	ValueOverrides map[string]string
+
+	// Value rewriting: RewriteValues substitutes source org/repo names with
+	// the target ones inside values, and Replacements adds custom plain
+	// substring substitutions. ReplaceWordBoundaries restricts matches to
+	// whole words. Overridden values are never rewritten.
+	RewriteValues         bool
+	Replacements          []Replacement
+	ReplaceWordBoundaries bool
+
+	// Environment variables settings. Envs restricts a repo-to-repo
+	// migration to the named source environments (case-insensitive); empty
+	// means every environment. ExcludeEnvs holds glob patterns of source
+	// environments to leave out instead.
+	SkipEnvs    bool
+	Envs        []string
+	ExcludeEnvs []string
+
+	// SkipRepoVars leaves the repository-level variables of a repo-to-repo
+	// migration alone, so that only environments are migrated
+	SkipRepoVars bool
+
+	// EnvMap renames environments in the target (source name → target
+	// name, case-insensitive). Unmapped environments keep their names.
+	EnvMap map[string]string
+
+	// Vars restricts the migration to exactly these variable names
+	// (case-insensitive). Empty means all variables.
+	Vars []string
+
+	// Variable name filters (case-insensitive shell-style globs).
+	// Exclude patterns take precedence over include patterns.
+	Include []string
+	Exclude []string
+
+	// FilterRegex is an optional regular expression that variable names
+	// must match. It is applied after the include/exclude globs.
+	FilterRegex string
+
+	// Since, when set, leaves out source variables whose updated_at is
+	// before it. Variables without a usable updated_at are kept.
+	Since time.Time
 
 	// Options
 	DryRun        bool
 	SkipOverwrite bool
+	ShowValues    bool
+
+	// Deep extends an org-to-org migration to the repository and
+	// environment variables of every repository that exists, by name, in
+	// both organizations. ExcludeRepos holds glob patterns of source
+	// repositories to leave out.
+	Deep         bool
+	ExcludeRepos []string
+
+	// Targets lists the target repositories of a repo-to-repo migration
+	// into many repositories. When set, TargetOwner and TargetRepo are unused
+	// and the migration runs once per target.
+	Targets []RepoRef
+
+	// TargetRepos lists the target organization repositories that fan-out
+	// mode copies organization variables into; AllRepos selects every
+	// non-archived repository instead
+	TargetRepos []string
+	AllRepos    bool
+
+	// TargetVisibility is the visibility given to promoted variables in
+	// repo-to-org mode ("all" when empty)
+	TargetVisibility string
+
+	// Visibility overrides the preserved source visibility of every
+	// variable in org-to-org mode. Empty preserves the source visibility.
+	Visibility string
+
+	// RepoMap renames repositories when matching the selection of
+	// "selected" variables in org-to-org mode (source name → target name,
+	// case-insensitive). Unmapped repositories are matched by name.
+	RepoMap map[string]string
+
+	// SelectedFallback decides how a "selected" variable without any
+	// matching target repository is migrated. Empty means FallbackEmpty.
+	SelectedFallback SelectedFallback
+
+	// Interactive asks for approval before every create or update.
+	// Ignored in dry-run mode.
+	Interactive bool
+
+	// OnConflict selects the conflict strategy. Empty means overwrite, or
+	// skip when SkipOverwrite is set.
+	OnConflict ConflictStrategy
+
+	// Verify re-reads the target after the migration and compares every
+	// written variable with what was sent. Ignored in dry-run mode.
+	Verify bool
+
+	// FailFast stops the migration at the first variable, environment, or
+	// repository that fails, instead of recording the error and moving on
+	FailFast bool
+	// MaxErrors stops the migration once this many errors have been
+	// recorded; zero means no limit. FailFast implies a limit of one.
+	MaxErrors int
+	// FailIfEmpty fails the run when no source variable is left to migrate
+	// after filtering, across every scope
+	FailIfEmpty bool
+	// MaxAPICalls stops the migration once the source and target clients
+	// together have sent this many requests; zero means no limit
+	MaxAPICalls int
+
+	// SkipLimitChecks turns off the preflight check of target names and
+	// value sizes against GitHub's limits
+	SkipLimitChecks bool
+	// StrictNames makes reserved target names (GITHUB_ prefix, leading
+	// digit) fail the preflight check instead of being skipped
+	StrictNames bool
+
+	// AlwaysWrite updates existing target variables even when they already
+	// hold the value that would be written
+	AlwaysWrite bool
+
+	// AllowCollisions lets several source variables be written to the same
+	// target name, the last one winning, instead of stopping the migration
+	AllowCollisions bool
+
+	// Plan, when set, restricts the migration to these planned writes. A
+	// variable whose source value no longer matches its planned hash fails
+	// as drifted, and variables outside the plan are left alone.
+	Plan []PlannedAction
+
+	// CheckUsage scans the target repository's workflow files after the
+	// migration and warns about referenced variables it does not have
+	CheckUsage bool
+
+	// SkipUnused leaves out the source variables that no workflow file of
+	// the source repository references
+	SkipUnused bool
+
+	// CopyEnvProtection creates target environments with the wait timer,
+	// self-review setting, required reviewers, and deployment branch
+	// policies of the source environment; UpdateEnvSettings also applies
+	// them to environments that already exist
+	CopyEnvProtection bool
+	UpdateEnvSettings bool
+
+	// Retry, when set, restricts the migration to these variables, the
+	// failures of a previous run read from its report
+	Retry []VariableRef
+
+	// Manifest is the path of the manifest applied in ModeManifest, or of
+	// the file imported in ModeImport, and Desired the scopes it declares.
+	// Prune deletes the target variables of those scopes that the manifest
+	// or file does not declare.
+	Manifest string
+	Desired  []DesiredScope
+	Prune    bool
+
+	// Yes skips the confirmation that ModeDelete, and ModeImport with
+	// Prune, ask for before deleting
+	Yes bool
+
+	// CopyFrom is the scope ModeCopy reads the variable CopyName from. It is
+	// written to the only Desired scope, under CopyAs when that is set.
+	CopyFrom DesiredScope
+	CopyName string
+	CopyAs   string
+}
+
+// DesiredScope is one target scope declared by a manifest: an organization
+// (Org set), a repository (Owner and Repo set), or an environment of a
+// repository (Environment set as well)
+type DesiredScope struct {
+	Org         string
+	Owner       string
+	Repo        string
+	Environment string
+	Variables   []DesiredVariable
+}
+
+// Label returns the scope label used in results and reports, e.g.
+// "org:acme", "acme/app", or "acme/app:env:production"
+func (s DesiredScope) Label() string {
+	switch {
+	case s.Org != "":
+		return "org:" + s.Org
+	case s.Environment != "":
+		return s.Owner + "/" + s.Repo + ":env:" + s.Environment
+	default:
+		return s.Owner + "/" + s.Repo
+	}
 }
 
-// MigrationResult holds the result of a migration
+// DesiredVariable is a variable declared by a manifest. Visibility and
+// SelectedRepositories (repository names in the organization) only apply
+// to organization variables.
+type DesiredVariable struct {
+	Name                 string
+	Value                string
+	Visibility           string
+	SelectedRepositories []string
+}
+
+// MigrationResult holds the result of a migration. While the migration
+// runs, the counters and lists are updated through the methods below, which
+// are safe for concurrent use; once it has finished the fields can be read
+// directly.
 type MigrationResult struct {
+	mu sync.Mutex
+
+	Created  int
+	Updated  int
+	Skipped  int
+	Filtered int
+
+	// UnchangedSince counts source variables left out because they were not
+	// updated since MigrationConfig.Since; they are not counted as Filtered
+	UnchangedSince int
+
+	// Conflicts counts variables that already existed in the target; each
+	// one is then counted as Updated, Skipped, or an error
+	Conflicts int
+
+	// Unchanged counts existing target variables that already held the value
+	// to be written and were left alone; they are not counted as Conflicts
+	Unchanged int
+
+	// Deleted counts target variables removed by MigrationConfig.Prune
+	Deleted int
+
+	// Declined counts variables skipped because they were declined at an
+	// --interactive prompt; they are included in Skipped
+	Declined int
+	// ReservedNames counts variables skipped because their target name is
+	// reserved by GitHub; they are included in Skipped
+	ReservedNames int
+	// Aborted is set when the run was stopped early at an interactive prompt
+	Aborted bool
+	// Interrupted is set when the run was stopped early by a signal; the
+	// counts and details cover the variables processed until then
+	Interrupted bool
+	// ErrorLimitReached is set when the run was stopped by MaxErrors
+	ErrorLimitReached bool
+	// BudgetExhausted is set when the run was stopped by MaxAPICalls
+	BudgetExhausted bool
+
+	// Selected counts the source variables left to migrate after filtering,
+	// summed over every scope and target
+	Selected int
+	// Empty is set when FailIfEmpty is set and Selected is zero
+	Empty bool
+
+	// Overridden counts written variables whose value came from an override
+	Overridden int
+	// Rewritten counts written variables whose value was changed by
+	// --rewrite-values or --replace substitutions
+	Rewritten int
+
+	// Verified and Mismatched count written variables that did and did not
+	// match the target when re-read with --verify
+	Verified   int
+	Mismatched int
+
+	// SelectedFallbacks counts "selected" variables that matched no target
+	// repository and were handled by the selected fallback
+	SelectedFallbacks int
+
+	// UnresolvedRefs counts the variable names referenced by target
+	// workflows that are neither migrated nor set in the target, found by
+	// CheckUsage
+	UnresolvedRefs int
+
+	// Unused lists the source variables left out by SkipUnused; they are
+	// not counted as Filtered
+	Unused []VariableRef
+
+	// Environments lists the environments that were migrated and
+	// FilteredEnvs those left out by the environment selection
+	Environments []string
+	FilteredEnvs []string
+
+	// Repos breaks the counts down per target repository in fan-out mode
+	// and when migrating into many targets
+	Repos []RepoResult
+
+	// Duration is the wall-clock time of the run, and SourceAPICalls and
+	// TargetAPICalls the API requests each client made during it
+	Duration       time.Duration
+	SourceAPICalls APICalls
+	TargetAPICalls APICalls
+
+	// Details records the outcome of every variable that was created,
+	// updated, unchanged, skipped, or failed, in processing order. The
+	// Created, Updated, Unchanged, and Skipped counters match the number of
+	// details with that action.
+	Details []VariableResult
+
+	Errors []error
+}
+
+// VariableAction is the outcome of migrating a single variable
+type VariableAction string
+
+const (
+	ActionCreated   VariableAction = "created"
+	ActionUpdated   VariableAction = "updated"
+	ActionUnchanged VariableAction = "unchanged"
+	ActionSkipped   VariableAction = "skipped"
+	ActionFailed    VariableAction = "failed"
+	ActionDeleted   VariableAction = "deleted"
+)
+
+// VariableResult records what happened to one variable during a migration
+type VariableResult struct {
+	// Scope is the target scope: "organization", "repository",
+	// "repository:<name>" in fan-out mode, or "env:<name>". Runs that cover
+	// many repositories prefix it with the repository, e.g. "api:env:prod".
+	Scope  string
+	Name   string // source variable name
+	Action VariableAction
+	// Reason explains a skip or holds the error of a failure
+	Reason string
+	// Value is the value written (or that would be written in dry-run
+	// mode) for created and updated variables
+	Value string
+	// ValueHash is the hash of the source value of created and updated
+	// variables, recorded in --plan-out plans
+	ValueHash string
+}
+
+// VariableRef identifies a variable by the target scope and source name
+// recorded for it in a report
+type VariableRef struct {
+	Scope string
+	Name  string
+}
+
+// PlannedAction is one create or update of a plan written by --plan-out.
+// Scope and Name are those of the VariableResult it was planned from.
+type PlannedAction struct {
+	Scope  string         `json:"scope"`
+	Name   string         `json:"name"`
+	Action VariableAction `json:"action"`
+	// ValueHash is the hash of the source value at planning time
+	ValueHash string `json:"value_sha256"`
+	// Value is the target value, present only when the plan was written
+	// with values included
+	Value string `json:"value,omitempty"`
+}
+
+// RepoResult holds the counts for one target repository of a fan-out or
+// multi-target migration
+type RepoResult struct {
+	Repo    string
 	Created int
 	Updated int
 	Skipped int
-	Errors  []error
+	Errors  int
+
+	// Failed is set when nothing could be written to the repository
+	// because of errors
+	Failed bool
+
+	// Note explains why a repository was not migrated, e.g.
+	// "missing in target"
+	Note string
+}
+
+// APICalls counts the GitHub API requests made by one client, by HTTP method
+type APICalls map[string]int
+
+// Total returns the number of requests of every method
+func (c APICalls) Total() int {
+	total := 0
+	for _, n := range c {
+		total += n
+	}
+	return total
+}
+
+// Since returns the requests made after before was taken
+func (c APICalls) Since(before APICalls) APICalls {
+	out := make(APICalls, len(c))
+	for method, n := range c {
+		if d := n - before[method]; d > 0 {
+			out[method] = d
+		}
+	}
+	return out
+}
+
+// ScopeResult holds the counts of one target scope, e.g. "repository" or
+// "env:production"
+type ScopeResult struct {
+	Scope     string
+	Created   int
+	Updated   int
+	Unchanged int
+	Skipped   int
+	Deleted   int
+	Errors    int
+}
+
+// ConflictStrategy returns the effective conflict strategy, treating
+// SkipOverwrite as an alias for ConflictSkip
+func (c *MigrationConfig) ConflictStrategy() ConflictStrategy {
+	if c.OnConflict != "" {
+		return c.OnConflict
+	}
+	if c.SkipOverwrite {
+		return ConflictSkip
+	}
+	return ConflictOverwrite
+}
+
+// SelectedFallbackOrDefault returns the effective selected fallback,
+// FallbackEmpty when unset
+func (c *MigrationConfig) SelectedFallbackOrDefault() SelectedFallback {
+	if c.SelectedFallback == "" {
+		return FallbackEmpty
+	}
+	return c.SelectedFallback
+}
+
+// IncCreated counts one variable created in the target
+func (r *MigrationResult) IncCreated() { r.Add(&r.Created, 1) }
+
+// IncUpdated counts one variable updated in the target
+func (r *MigrationResult) IncUpdated() { r.Add(&r.Updated, 1) }
+
+// IncSkipped counts one variable left untouched in the target
+func (r *MigrationResult) IncSkipped() { r.Add(&r.Skipped, 1) }
+
+// IncUnchanged counts one target variable that already held the value
+func (r *MigrationResult) IncUnchanged() { r.Add(&r.Unchanged, 1) }
+
+// IncDeleted counts one target variable removed by a prune
+func (r *MigrationResult) IncDeleted() { r.Add(&r.Deleted, 1) }
+
+// Add adds n to counter, which must point to one of the result's own
+// counters, e.g. result.Add(&result.Filtered, 1)
+func (r *MigrationResult) Add(counter *int, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*counter += n
+}
+
+// AddEnvironment records a migrated environment
+func (r *MigrationResult) AddEnvironment(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Environments = append(r.Environments, name)
+}
+
+// AddFilteredEnv records an environment left out by the selection
+func (r *MigrationResult) AddFilteredEnv(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FilteredEnvs = append(r.FilteredEnvs, name)
+}
+
+// AddUnused records a variable left out by SkipUnused
+func (r *MigrationResult) AddUnused(ref VariableRef) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Unused = append(r.Unused, ref)
+}
+
+// AddRepo records the counts of one target repository
+func (r *MigrationResult) AddRepo(repo RepoResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Repos = append(r.Repos, repo)
+}
+
+// AddCounts adds the counts of other to the result. Errors, Repos,
+// Details, Aborted, and the environment lists are left alone. other must
+// not be updated concurrently.
+func (r *MigrationResult) AddCounts(other *MigrationResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Created += other.Created
+	r.Updated += other.Updated
+	r.Skipped += other.Skipped
+	r.Filtered += other.Filtered
+	r.UnchangedSince += other.UnchangedSince
+	r.Conflicts += other.Conflicts
+	r.Unchanged += other.Unchanged
+	r.Deleted += other.Deleted
+	r.Declined += other.Declined
+	r.ReservedNames += other.ReservedNames
+	r.Overridden += other.Overridden
+	r.Rewritten += other.Rewritten
+	r.Verified += other.Verified
+	r.Mismatched += other.Mismatched
+	r.SelectedFallbacks += other.SelectedFallbacks
+	r.UnresolvedRefs += other.UnresolvedRefs
+	r.Selected += other.Selected
+}
+
+// AddDetail records the outcome of a single variable
+func (r *MigrationResult) AddDetail(d VariableResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Details = append(r.Details, d)
+}
+
+// Scopes breaks Details down per target scope, in the order each scope was
+// first seen. Failed variables are counted in Errors.
+func (r *MigrationResult) Scopes() []ScopeResult {
+	var scopes []ScopeResult
+	index := map[string]int{}
+	for _, d := range r.Details {
+		i, ok := index[d.Scope]
+		if !ok {
+			i = len(scopes)
+			index[d.Scope] = i
+			scopes = append(scopes, ScopeResult{Scope: d.Scope})
+		}
+		switch d.Action {
+		case ActionCreated:
+			scopes[i].Created++
+		case ActionUpdated:
+			scopes[i].Updated++
+		case ActionUnchanged:
+			scopes[i].Unchanged++
+		case ActionSkipped:
+			scopes[i].Skipped++
+		case ActionFailed:
+			scopes[i].Errors++
+		case ActionDeleted:
+			scopes[i].Deleted++
+		}
+	}
+	return scopes
 }
 
 // AddError adds an error to the result
 func (r *MigrationResult) AddError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.Errors = append(r.Errors, err)
 }
 
 // HasErrors returns true if there are any errors
 func (r *MigrationResult) HasErrors() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return len(r.Errors) > 0
 }
 
 // Total returns the total number of variables processed
 func (r *MigrationResult) Total() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return r.Created + r.Updated + r.Skipped
 }
+
+// Snapshot returns a copy of the counts, lists, details, and errors
+// recorded so far, which is safe to read while the result is still being
+// updated. The flags, duration, and API calls set once the run has
+// finished are left out.
+func (r *MigrationResult) Snapshot() *MigrationResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := &MigrationResult{}
+	s.AddCounts(r)
+	s.Unused = append([]VariableRef(nil), r.Unused...)
+	s.Environments = append([]string(nil), r.Environments...)
+	s.FilteredEnvs = append([]string(nil), r.FilteredEnvs...)
+	s.Repos = append([]RepoResult(nil), r.Repos...)
+	s.Details = append([]VariableResult(nil), r.Details...)
+	s.Errors = append([]error(nil), r.Errors...)
+	return s
+}
+
+// RollbackResult holds the result of restoring a target from a snapshot
+type RollbackResult struct {
+	Recreated int // variables deleted since the snapshot, created again
+	Restored  int // variables whose value or visibility was put back
+	Deleted   int // variables that did not exist at snapshot time
+	Unchanged int
+
+	Errors []error
+}
+
+// AddError adds an error to the result
+func (r *RollbackResult) AddError(err error) {
+	r.Errors = append(r.Errors, err)
+}
+
+// HasErrors returns true if there are any errors
+func (r *RollbackResult) HasErrors() bool {
+	return len(r.Errors) > 0
+}
+
+// DiffStatus categorizes how a variable differs between source and target
+type DiffStatus string
+
+const (
+	DiffAdd        DiffStatus = "add"
+	DiffUpdate     DiffStatus = "update"
+	DiffUnchanged  DiffStatus = "unchanged"
+	DiffTargetOnly DiffStatus = "target-only"
+)
+
+// DiffEntry describes a single variable in a source/target comparison
+type DiffEntry struct {
+	Scope       string
+	Name        string // source name (target name for target-only entries)
+	TargetName  string
+	Status      DiffStatus
+	SourceValue string
+	TargetValue string
+}
+
+// DiffResult holds the result of comparing source and target variables
+type DiffResult struct {
+	Entries []DiffEntry
+}
+
+// Count returns the number of entries with the given status
+func (d *DiffResult) Count(status DiffStatus) int {
+	n := 0
+	for _, e := range d.Entries {
+		if e.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// HasDifferences returns true if any variable would be added, updated, or
+// exists only in the target
+func (d *DiffResult) HasDifferences() bool {
+	return len(d.Entries) > d.Count(DiffUnchanged)
+}
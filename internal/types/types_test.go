@@ -2,6 +2,8 @@ package types
 
 import (
 	"errors"
+	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -35,6 +37,132 @@ func TestMigrationResult_Total(t *testing.T) {
 	}
 }
 
+func TestMigrationResult_AddCounts(t *testing.T) {
+	result := &MigrationResult{Created: 1, Skipped: 1, Errors: []error{errors.New("kept")}}
+	result.AddCounts(&MigrationResult{
+		Created:  2,
+		Updated:  3,
+		Skipped:  1,
+		Verified: 4,
+		Errors:   []error{errors.New("not copied")},
+		Aborted:  true,
+	})
+
+	if result.Created != 3 || result.Updated != 3 || result.Skipped != 2 || result.Verified != 4 {
+		t.Errorf("Unexpected counts: %+v", result)
+	}
+	if len(result.Errors) != 1 || result.Aborted {
+		t.Errorf("AddCounts must not touch errors or the aborted flag: %+v", result)
+	}
+}
+
+func TestMigrationResult_AddDetail(t *testing.T) {
+	result := &MigrationResult{}
+	want := VariableResult{Scope: "env:prod", Name: "URL", Action: ActionSkipped, Reason: "declined interactively"}
+	result.AddDetail(want)
+	if len(result.Details) != 1 || result.Details[0] != want {
+		t.Errorf("Details = %+v, want [%+v]", result.Details, want)
+	}
+	if result.Skipped != 0 {
+		t.Error("AddDetail must not change the counters")
+	}
+}
+
+func TestMigrationResult_Snapshot(t *testing.T) {
+	result := &MigrationResult{Interrupted: true}
+	result.IncCreated()
+	result.AddDetail(VariableResult{Scope: "repository", Name: "URL", Action: ActionCreated})
+	result.AddError(errors.New("boom"))
+
+	snap := result.Snapshot()
+	result.IncCreated()
+	result.AddDetail(VariableResult{Scope: "repository", Name: "REGION", Action: ActionCreated})
+
+	if snap.Created != 1 || len(snap.Details) != 1 || len(snap.Errors) != 1 {
+		t.Errorf("Snapshot() = %+v, want the one variable recorded before it", snap)
+	}
+	if snap.Interrupted {
+		t.Error("Snapshot() must leave out the flags")
+	}
+}
+
+// TestMigrationResult_Concurrent updates one result from many goroutines;
+// run with -race to detect unsynchronized access
+func TestMigrationResult_Concurrent(t *testing.T) {
+	const workers, perWorker = 20, 50
+	result := &MigrationResult{}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				result.IncCreated()
+				result.IncUpdated()
+				result.IncSkipped()
+				result.IncUnchanged()
+				result.Add(&result.Filtered, 2)
+				result.AddDetail(VariableResult{Scope: "repository", Name: "A", Action: ActionCreated})
+				result.AddError(errors.New("boom"))
+				result.AddEnvironment("prod")
+				result.AddFilteredEnv("pr-1")
+				result.AddUnused(VariableRef{Scope: "repository", Name: "B"})
+				result.AddRepo(RepoResult{Repo: "api"})
+				result.AddCounts(&MigrationResult{Verified: 1})
+				_ = result.HasErrors()
+				_ = result.Total()
+				_ = result.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	n := workers * perWorker
+	if result.Created != n || result.Updated != n || result.Skipped != n || result.Unchanged != n || result.Verified != n {
+		t.Errorf("Unexpected counts: created=%d updated=%d skipped=%d unchanged=%d verified=%d, want %d each",
+			result.Created, result.Updated, result.Skipped, result.Unchanged, result.Verified, n)
+	}
+	if result.Filtered != 2*n {
+		t.Errorf("Filtered = %d, want %d", result.Filtered, 2*n)
+	}
+	if len(result.Details) != n || len(result.Errors) != n || len(result.Environments) != n ||
+		len(result.FilteredEnvs) != n || len(result.Unused) != n || len(result.Repos) != n {
+		t.Errorf("Lost appends: details=%d errors=%d envs=%d filtered envs=%d unused=%d repos=%d, want %d each",
+			len(result.Details), len(result.Errors), len(result.Environments),
+			len(result.FilteredEnvs), len(result.Unused), len(result.Repos), n)
+	}
+}
+
+func TestMigrationResult_Scopes(t *testing.T) {
+	result := &MigrationResult{}
+	for _, d := range []VariableResult{
+		{Scope: "repository", Action: ActionCreated},
+		{Scope: "env:prod", Action: ActionSkipped},
+		{Scope: "repository", Action: ActionUpdated},
+		{Scope: "env:prod", Action: ActionFailed},
+	} {
+		result.AddDetail(d)
+	}
+
+	want := []ScopeResult{
+		{Scope: "repository", Created: 1, Updated: 1},
+		{Scope: "env:prod", Skipped: 1, Errors: 1},
+	}
+	if got := result.Scopes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Scopes() = %+v, want %+v", got, want)
+	}
+	if got := (&MigrationResult{}).Scopes(); got != nil {
+		t.Errorf("Scopes() of an empty result = %+v, want nil", got)
+	}
+}
+
+func TestRepoRef_String(t *testing.T) {
+	if got := (RepoRef{Owner: "acme", Repo: "api"}).String(); got != "acme/api" {
+		t.Errorf("String() = %q, want %q", got, "acme/api")
+	}
+}
+
 func TestMigrationMode_Constants(t *testing.T) {
 	modes := []MigrationMode{
 		ModeRepoToRepo,
@@ -47,3 +175,23 @@ func TestMigrationMode_Constants(t *testing.T) {
 		}
 	}
 }
+
+func TestMigrationConfig_ConflictStrategy(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  MigrationConfig
+		want ConflictStrategy
+	}{
+		{name: "default", cfg: MigrationConfig{}, want: ConflictOverwrite},
+		{name: "skip-overwrite alias", cfg: MigrationConfig{SkipOverwrite: true}, want: ConflictSkip},
+		{name: "explicit strategy", cfg: MigrationConfig{OnConflict: ConflictFail}, want: ConflictFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.ConflictStrategy(); got != tt.want {
+				t.Errorf("ConflictStrategy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
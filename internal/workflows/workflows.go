@@ -0,0 +1,40 @@
+// Package workflows finds the configuration variables that GitHub Actions
+// workflow files reference.
+package workflows
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// varRefRe matches a reference to the vars context: vars.NAME, or
+// vars['NAME'] and vars["NAME"]. The context must not be a property of
+// something else, e.g. inputs.vars.NAME.
+var varRefRe = regexp.MustCompile(`(?:^|[^\w.])vars(?:\.([A-Za-z_][A-Za-z0-9_]*)|\[\s*'([^']+)'\s*\]|\[\s*"([^"]+)"\s*\])`)
+
+// References returns the upper-cased names of the variables referenced in
+// the content of a workflow file, sorted and without duplicates. References
+// are found wherever expressions are evaluated: in ${{ }} expressions of
+// env:, with:, run:, and other keys, and in if: conditions, which may omit
+// the ${{ }}. Lines that are YAML comments are ignored. Names are
+// upper-cased because GitHub treats variable names case-insensitively.
+func References(content string) []string {
+	seen := map[string]bool{}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, m := range varRefRe.FindAllStringSubmatch(line, -1) {
+			name := m[1] + m[2] + m[3]
+			seen[strings.ToUpper(strings.TrimSpace(name))] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,60 @@
+package workflows
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReferences_Fixtures(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{
+			file: "deploy.yml",
+			want: []string{"AZURE_CLIENT_ID", "AZURE_TENANT_ID", "DEPLOY_ENABLED", "DEPLOY_KEY_ID", "REGION", "REGISTRY"},
+		},
+		{file: "none.yaml", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := References(string(data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("References() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "expression", content: "x: ${{ vars.A }}", want: []string{"A"}},
+		{name: "several on one line", content: "run: echo ${{ vars.A }}-${{vars.b}}", want: []string{"A", "B"}},
+		{name: "condition without braces", content: "if: ${{ github.event_name == 'push' }} && vars.FLAG", want: []string{"FLAG"}},
+		{name: "index syntax", content: `x: ${{ vars[ 'A' ] }} ${{ vars["B"] }}`, want: []string{"A", "B"}},
+		{name: "function argument", content: "x: ${{ fromJSON(vars.MATRIX) }}", want: []string{"MATRIX"}},
+		{name: "start of line", content: "vars.A", want: []string{"A"}},
+		{name: "duplicates", content: "a: ${{ vars.A }}\nb: ${{ vars.a }}", want: []string{"A"}},
+		{name: "comment line", content: "  # ${{ vars.A }}", want: []string{}},
+		{name: "property of another context", content: "x: ${{ inputs.vars.A }} ${{ myvars.B }}", want: []string{}},
+		{name: "whole context", content: "x: ${{ toJSON(vars) }}", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := References(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("References(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}